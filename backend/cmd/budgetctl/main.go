@@ -0,0 +1,470 @@
+// Command budgetctl runs maintenance tasks against the budget database.
+//
+// Usage:
+//
+//	budgetctl repair [-dry-run] [-json]
+//	budgetctl orphans [-json]
+//	budgetctl integrity [-json]
+//	budgetctl assign-owner -email <email> [-dry-run] [-json]
+//	budgetctl encrypt-fields [-dry-run] [-json]
+//	budgetctl set-environment <name>
+//	budgetctl set-admin -email <email> [-revoke]
+//	budgetctl backup <file>
+//	budgetctl anonymize -out <file> [-json]
+//	budgetctl watch [-offline] [-existing] [-json] <folder>
+//
+// watch uploads the PDFs saved to a folder, e.g. by a scanner, to the server
+// named by BUDGET_SERVER with the access token in BUDGET_TOKEN, or processes
+// them with the configured AI provider directly with -offline, and prints the
+// extracted items.
+//
+// The database is selected with the same TURSO_* and APP_ENV environment
+// variables as the server, and FIELD_ENCRYPTION_KEY must match the server's.
+// Like the server, it also reads them from secret files and a .env file.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"budget-tracker/internal/config"
+	"budget-tracker/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := config.Load(); err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "repair":
+		if err := runRepair(os.Args[2:]); err != nil {
+			log.Fatalf("repair failed: %v", err)
+		}
+	case "orphans":
+		if err := runOrphans(os.Args[2:]); err != nil {
+			log.Fatalf("orphans failed: %v", err)
+		}
+	case "integrity":
+		if err := runIntegrity(os.Args[2:]); err != nil {
+			log.Fatalf("integrity failed: %v", err)
+		}
+	case "assign-owner":
+		if err := runAssignOwner(os.Args[2:]); err != nil {
+			log.Fatalf("assign-owner failed: %v", err)
+		}
+	case "encrypt-fields":
+		if err := runEncryptFields(os.Args[2:]); err != nil {
+			log.Fatalf("encrypt-fields failed: %v", err)
+		}
+	case "set-environment":
+		if err := runSetEnvironment(os.Args[2:]); err != nil {
+			log.Fatalf("set-environment failed: %v", err)
+		}
+	case "set-admin":
+		if err := runSetAdmin(os.Args[2:]); err != nil {
+			log.Fatalf("set-admin failed: %v", err)
+		}
+	case "backup":
+		if err := runBackup(os.Args[2:]); err != nil {
+			log.Fatalf("backup failed: %v", err)
+		}
+	case "anonymize":
+		if err := runAnonymize(os.Args[2:]); err != nil {
+			log.Fatalf("anonymize failed: %v", err)
+		}
+	case "watch":
+		if err := runWatch(os.Args[2:]); err != nil {
+			log.Fatalf("watch failed: %v", err)
+		}
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: budgetctl <command> [flags]
+
+Commands:
+  repair    recompute month/year from receipt dates and fix orphaned
+            expected expense links
+  orphans   list actual expenses linked to deleted expected expenses
+  integrity check for inconsistent data (month/year, negative totals,
+            duplicate receipt numbers, budget thresholds, orphans)
+  assign-owner
+            move data created while authentication was disabled to a user
+  encrypt-fields
+            encrypt item names, stores, audit snapshots and receipt
+            responses written before FIELD_ENCRYPTION_KEY was set
+  set-environment
+            tag the database for another environment, e.g. after copying
+            production into staging
+  set-admin grant a user the admin role for /api/admin, e.g. the first
+            admin; other admins can then be managed through the API
+  backup    copy a local database to a new file while the server runs,
+            and record it as the last backup for /status
+  anonymize write a copy of a local database with names, stores and
+            amounts scrubbed, to attach to a bug report
+  watch     process the receipts saved to a folder, e.g. by a scanner,
+            and print their items
+
+Run "budgetctl <command> -h" for command flags.`)
+}
+
+func runRepair(args []string) error {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be fixed without changing anything")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := repository.NewMaintenanceRepository(db).Repair(*dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(report)
+	}
+	printRepairReport(os.Stdout, report)
+	return nil
+}
+
+func runOrphans(args []string) error {
+	fs := flag.NewFlagSet("orphans", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := repository.NewMaintenanceRepository(db).FindOrphanedReferences()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(report)
+	}
+	for _, ref := range report.References {
+		fmt.Printf("expense %d (%s, %s): expected expense %d no longer exists\n",
+			ref.ExpenseID, ref.ItemName, ref.Source, ref.ExpectedExpenseID)
+	}
+	fmt.Printf("\n%d orphaned references", report.Count)
+	if report.Count > 0 {
+		fmt.Print(`; run "budgetctl repair" to clean them up`)
+	}
+	fmt.Println()
+	return nil
+}
+
+func runIntegrity(args []string) error {
+	fs := flag.NewFlagSet("integrity", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := repository.NewMaintenanceRepository(db).CheckIntegrity()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else if report.OK {
+		fmt.Println("no issues found")
+	} else {
+		for _, issue := range report.Issues {
+			if issue.ID != 0 {
+				fmt.Printf("%s (id %d): %s\n", issue.Check, issue.ID, issue.Description)
+			} else {
+				fmt.Printf("%s: %s\n", issue.Check, issue.Description)
+			}
+		}
+		fmt.Println()
+	}
+
+	// A non-zero exit lets scripts and CI act on the result
+	if !report.OK {
+		return fmt.Errorf("%d issues found", len(report.Issues))
+	}
+	return nil
+}
+
+func runAssignOwner(args []string) error {
+	fs := flag.NewFlagSet("assign-owner", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user to move the shared data to (required)")
+	dryRun := fs.Bool("dry-run", false, "report what would be moved without changing anything")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	user, _, err := repository.NewUserRepository(db).GetByEmail(strings.ToLower(strings.TrimSpace(*email)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", *email, err)
+	}
+
+	report, err := repository.NewMaintenanceRepository(db).AssignOwner(user.ID, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(report)
+	}
+	verb := "moved"
+	if report.DryRun {
+		verb = "would move"
+	}
+	fmt.Printf("%s to %s: %d budgets, %d expected expenses, %d actual expenses, %d notifications\n",
+		verb, user.Email, report.Budgets, report.ExpectedExpenses, report.ActualExpenses, report.Notifications)
+	if report.ReceiptNumberOffset > 0 {
+		fmt.Printf("receipt numbers shifted by %d\n", report.ReceiptNumberOffset)
+	}
+	if report.BudgetsSkipped > 0 {
+		fmt.Printf("%d budgets skipped: %s already has a budget for those months\n",
+			report.BudgetsSkipped, user.Email)
+	}
+	return nil
+}
+
+func runEncryptFields(args []string) error {
+	fs := flag.NewFlagSet("encrypt-fields", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "count the values to encrypt without changing anything")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := repository.NewMaintenanceRepository(db).EncryptFields(*dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(report)
+	}
+	verb := "encrypted"
+	if report.DryRun {
+		verb = "would encrypt"
+	}
+	for _, c := range report.Columns {
+		fmt.Printf("%s %d values in %s.%s\n", verb, c.Rows, c.Table, c.Column)
+	}
+	return nil
+}
+
+func runSetEnvironment(args []string) error {
+	fs := flag.NewFlagSet("set-environment", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: budgetctl set-environment <name>")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("the environment name is required")
+	}
+	name := strings.ToLower(fs.Arg(0))
+
+	// No migrations: the tag is what keeps them from running
+	db, err := repository.NewDB(repository.NewConfigFromEnv())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	previous, err := db.Environment()
+	if err != nil {
+		return err
+	}
+	if err := db.SetEnvironment(name); err != nil {
+		return err
+	}
+	if previous == "" {
+		fmt.Printf("tagged the database as %s\n", name)
+	} else {
+		fmt.Printf("retagged the database from %s to %s\n", previous, name)
+	}
+	return nil
+}
+
+func runSetAdmin(args []string) error {
+	fs := flag.NewFlagSet("set-admin", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user (required)")
+	revoke := fs.Bool("revoke", false, "revoke the admin role instead of granting it")
+	fs.Parse(args)
+
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users := repository.NewUserRepository(db)
+	user, _, err := users.GetByEmail(strings.ToLower(strings.TrimSpace(*email)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", *email, err)
+	}
+	if user.ParentID != nil && !*revoke {
+		return fmt.Errorf("%s is an allowance sub-account", user.Email)
+	}
+	if err := users.SetAdmin(user.ID, !*revoke); err != nil {
+		return err
+	}
+
+	if *revoke {
+		fmt.Printf("revoked the admin role of %s\n", user.Email)
+	} else {
+		fmt.Printf("granted %s the admin role\n", user.Email)
+	}
+	return nil
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: budgetctl backup <file>")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("the backup file is required")
+	}
+
+	cfg := repository.NewConfigFromEnv()
+	if cfg.Mode == repository.ModeRemote {
+		return fmt.Errorf("remote databases are backed up by Turso, see its point-in-time restore")
+	}
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	record, err := repository.NewMaintenanceRepository(db).Backup(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backed up the database to %s (%d bytes)\n", fs.Arg(0), record.SizeBytes)
+	return nil
+}
+
+func runAnonymize(args []string) error {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	out := fs.String("out", "", "the file to write the anonymized copy to")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+	if *out == "" {
+		fs.Usage()
+		return fmt.Errorf("-out is required")
+	}
+
+	cfg := repository.NewConfigFromEnv()
+	if cfg.Mode == repository.ModeRemote {
+		return fmt.Errorf("remote databases cannot be copied, anonymize a local replica or backup instead")
+	}
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := repository.NewMaintenanceRepository(db).Anonymize(*out)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(report)
+	}
+	for _, table := range report.Tables {
+		fmt.Printf("%-28s %d rows\n", table.Table, table.Rows)
+	}
+	fmt.Printf("\nwrote the anonymized copy to %s\n", report.Path)
+	return nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// openDB connects using the server's environment and brings the schema up to
+// date. Field encryption is enabled as in the server.
+func openDB() (*repository.DB, error) {
+	fieldCipher, err := repository.NewFieldCipherFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid field encryption key: %w", err)
+	}
+	repository.SetFieldCipher(fieldCipher)
+
+	db, err := repository.NewDB(repository.NewConfigFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run database migrations: %w", err)
+	}
+	return db, nil
+}
+
+func printRepairReport(w io.Writer, report *repository.RepairReport) {
+	for _, fix := range report.Fixes {
+		fmt.Fprintf(w, "expense %d: %s\n", fix.ExpenseID, fix.Description)
+	}
+
+	verb := "fixed"
+	if report.DryRun {
+		verb = "would fix"
+	}
+	fmt.Fprintf(w, "\n%s: %d month/year, %d orphans re-linked, %d orphans cleared\n",
+		verb, report.MonthYearFixed, report.OrphansRelinked, report.OrphansCleared)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"budget-tracker/internal/api/handlers"
+	"budget-tracker/internal/config"
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/services/ai"
+	"budget-tracker/internal/services/receipt"
+)
+
+// receiptTimeout bounds the processing of one receipt, like the server's
+// own limit on the AI call
+const receiptTimeout = 150 * time.Second
+
+// watchResult is what watch prints for one receipt
+type watchResult struct {
+	File   string               `json:"file"`
+	Items  []models.ReceiptItem `json:"items,omitempty"`
+	Error  string               `json:"error,omitempty"`
+	TimeMs int64                `json:"processing_time_ms"`
+}
+
+// receiptProcessor turns the PDF at path into its items
+type receiptProcessor func(ctx context.Context, path string) ([]models.ReceiptItem, error)
+
+// watchedFile is the state of a PDF seen in the watched folder
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	// stable is set when the file did not change between two scans, so the
+	// scanner has finished writing it
+	stable bool
+	// done is set once the file was processed in this state
+	done bool
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	server := fs.String("server", serverURLFromEnv(), "URL of the budget server (default BUDGET_SERVER)")
+	token := fs.String("token", config.Get("BUDGET_TOKEN"), "access token for the server (default BUDGET_TOKEN)")
+	offline := fs.Bool("offline", false, "process receipts with the AI provider directly instead of the server")
+	interval := fs.Duration("interval", 2*time.Second, "how often to look for new files")
+	existing := fs.Bool("existing", false, "also process the PDFs already in the folder")
+	asJSON := fs.Bool("json", false, "print each receipt as a line of JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: budgetctl watch [flags] <folder>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("the folder to watch is required")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+	dir := fs.Arg(0)
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+
+	var process receiptProcessor
+	if *offline {
+		provider, err := ai.NewProviderFromEnv()
+		if err != nil {
+			return fmt.Errorf("offline mode needs an AI provider: %w", err)
+		}
+		process = offlineProcessor(provider)
+		log.Printf("watching %s, processing receipts with the AI provider", dir)
+	} else {
+		process = serverProcessor(strings.TrimRight(*server, "/"), *token)
+		log.Printf("watching %s, uploading receipts to %s", dir, *server)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files := map[string]*watchedFile{}
+	// Files already there are the old scans, unless asked for
+	if !*existing {
+		if err := scanFolder(dir, files); err != nil {
+			return err
+		}
+		for _, f := range files {
+			f.done = true
+		}
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if err := scanFolder(dir, files); err != nil {
+			log.Printf("failed to read %s: %v", dir, err)
+		}
+		for _, path := range readyFiles(files) {
+			result := processFile(ctx, process, path)
+			if ctx.Err() != nil {
+				return nil
+			}
+			files[path].done = true
+			if err := printWatchResult(os.Stdout, result, *asJSON); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// serverURLFromEnv returns BUDGET_SERVER, or the local server on PORT
+func serverURLFromEnv() string {
+	if server := config.Get("BUDGET_SERVER"); server != "" {
+		return server
+	}
+	port := config.Get("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return "http://localhost:" + port
+}
+
+// scanFolder records the PDFs in dir in files and forgets the removed ones.
+// A file that changed since the last scan is processed again once it is
+// stable.
+func scanFolder(dir string, files map[string]*watchedFile) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	present := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".pdf") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the folder was read
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		present[path] = true
+		f, ok := files[path]
+		switch {
+		case !ok:
+			files[path] = &watchedFile{size: info.Size(), modTime: info.ModTime()}
+		case f.size != info.Size() || !f.modTime.Equal(info.ModTime()):
+			*f = watchedFile{size: info.Size(), modTime: info.ModTime()}
+		default:
+			f.stable = true
+		}
+	}
+
+	for path := range files {
+		if !present[path] {
+			delete(files, path)
+		}
+	}
+	return nil
+}
+
+// readyFiles returns the stable files not processed yet, by name
+func readyFiles(files map[string]*watchedFile) []string {
+	var ready []string
+	for path, f := range files {
+		if f.stable && !f.done && f.size > 0 {
+			ready = append(ready, path)
+		}
+	}
+	sort.Strings(ready)
+	return ready
+}
+
+// processFile processes the receipt at path and times it
+func processFile(ctx context.Context, process receiptProcessor, path string) *watchResult {
+	ctx, cancel := context.WithTimeout(ctx, receiptTimeout)
+	defer cancel()
+
+	start := time.Now()
+	items, err := process(ctx, path)
+	result := &watchResult{
+		File:   filepath.Base(path),
+		Items:  items,
+		TimeMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// serverProcessor uploads receipts to the server's receipt processing
+// endpoint, signed in with token when it is set
+func serverProcessor(server, token string) receiptProcessor {
+	client := &http.Client{}
+	return func(ctx context.Context, path string) ([]models.ReceiptItem, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile(handlers.FormFileKey, filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, err
+		}
+		if err := form.Close(); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+"/api/receipts/process", &body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			// Receipt errors and middleware errors both carry "error"
+			var failure models.ProcessReceiptError
+			raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+				return nil, fmt.Errorf("server responded %d: %s", resp.StatusCode, failure.Error)
+			}
+			return nil, fmt.Errorf("server responded %s", resp.Status)
+		}
+
+		var processed models.ProcessReceiptResponse
+		if err := json.NewDecoder(resp.Body).Decode(&processed); err != nil {
+			return nil, fmt.Errorf("failed to decode server response: %w", err)
+		}
+		return processed.Items, nil
+	}
+}
+
+// offlineProcessor processes receipts with provider directly. Nothing is
+// stored and, without the database, the AI gets no budget categories.
+func offlineProcessor(provider ai.ReceiptProvider) receiptProcessor {
+	pipeline := receipt.New(provider)
+	return func(ctx context.Context, path string) ([]models.ReceiptItem, error) {
+		job, err := pipeline.RunFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return job.Items, nil
+	}
+}
+
+func printWatchResult(w io.Writer, result *watchResult, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(result)
+	}
+
+	if result.Error != "" {
+		_, err := fmt.Fprintf(w, "%s: %s\n", result.File, result.Error)
+		return err
+	}
+	source := "Unknown"
+	if len(result.Items) > 0 {
+		source = result.Items[0].Source
+	}
+	fmt.Fprintf(w, "%s: %s, %d items in %.1fs\n",
+		result.File, source, len(result.Items), float64(result.TimeMs)/1000)
+	var total float64
+	for _, item := range result.Items {
+		fmt.Fprintf(w, "  %3d  %-40s %10.2f  %s\n", item.LineNo, item.ItemName, item.ItemPrice, item.Type)
+		total += item.ItemPrice
+	}
+	_, err := fmt.Fprintf(w, "       %-40s %10.2f\n\n", "total", total)
+	return err
+}
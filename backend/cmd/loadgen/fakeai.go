@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// fakeReceipts are the receipts the fake AI answers with, in the JSON shape
+// the receipt prompt asks for
+var fakeReceipts = []string{
+	`{"source":"Publix","item_count":3,"total":12.27,"tax":0.58,"items":[` +
+		`{"item_code":"MLK 2%","item_price":3.99,"item_name":"2% Milk","item_type":"weekly"},` +
+		`{"item_code":"BANANA","item_price":1.99,"item_name":"Bananas","item_type":"weekly"},` +
+		`{"item_code":"PPR TWL","item_price":5.71,"item_name":"Paper Towels","item_type":"misc"},` +
+		`{"item_code":"TAX","item_price":0.58,"item_name":"Tax","item_type":"TAX"}]}`,
+	`{"source":"Target","item_count":2,"total":27.45,"tax":1.8,"items":[` +
+		`{"item_code":"DETERG","item_price":13.99,"item_name":"Laundry Detergent","item_type":"monthly"},` +
+		`{"item_code":"SOAP","item_price":11.66,"item_name":"Hand Soap","item_type":"misc"},` +
+		`{"item_code":"TAX","item_price":1.8,"item_name":"Tax","item_type":"TAX"}]}`,
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatChoice struct {
+	Message chatMessage `json:"message"`
+}
+
+type chatResponse struct {
+	Choices []chatChoice `json:"choices"`
+}
+
+// newFakeAI returns a minimal OpenAI-compatible API that answers every chat
+// completion with one of fakeReceipts after latency
+func newFakeAI(latency time.Duration) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":[{"id":"fake"}]}`)
+	})
+	mux.HandleFunc("POST /v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+
+		resp := chatResponse{Choices: []chatChoice{{Message: chatMessage{
+			Role:    "assistant",
+			Content: fakeReceipts[rand.IntN(len(fakeReceipts))],
+		}}}}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+	return mux
+}
+
+// receiptPDF returns a one-page PDF with an uncompressed text layer, enough
+// for the server's local text extraction
+func receiptPDF() []byte {
+	content := "BT\n/F1 10 Tf\n72 720 Td\n(PUBLIX SUPER MARKETS) Tj\n0 -12 Td\n" +
+		"(MLK 2%   3.99) Tj\n0 -12 Td\n(BANANA   1.99) Tj\n0 -12 Td\n(TAX   0.58) Tj\nET"
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	fmt.Fprintf(&buf, "2 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	buf.WriteString("%%EOF")
+	return buf.Bytes()
+}
@@ -0,0 +1,97 @@
+// Command loadgen sends realistic traffic to a running budget server and
+// reports latency percentiles per endpoint.
+//
+// Usage:
+//
+//	loadgen [-url http://localhost:8080] [-duration 30s] [-concurrency 8]
+//	        [-upload-every 5s] [-token <jwt>] [-fake-ai :8091] [-p95 200ms]
+//
+// The traffic is list-heavy: expense lists, summaries and budget status for
+// random recent months, plus a receipt upload every -upload-every. Uploads
+// go through the server's AI provider; to keep them free and deterministic,
+// start loadgen with -fake-ai and point the server at it:
+//
+//	AI_PROVIDER=openai OPENAI_BASE_URL=http://localhost:8091/v1 OPENAI_MODEL=fake go run ./cmd/server
+//
+// loadgen waits for the server's /health endpoint before sending traffic, so
+// it can be started first. With -p95 it exits non-zero when the p95 latency
+// of any list or summary endpoint exceeds the given duration.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	cfg := config{}
+	flag.StringVar(&cfg.baseURL, "url", "http://localhost:8080", "base URL of the budget server")
+	flag.DurationVar(&cfg.duration, "duration", 30*time.Second, "how long to send traffic")
+	flag.IntVar(&cfg.concurrency, "concurrency", 8, "number of concurrent readers")
+	flag.DurationVar(&cfg.uploadEvery, "upload-every", 5*time.Second, "interval between receipt uploads (0 disables uploads)")
+	flag.StringVar(&cfg.token, "token", "", "bearer token, when the server has authentication enabled")
+	fakeAI := flag.String("fake-ai", "", "serve a fake OpenAI-compatible API on this address, e.g. :8091")
+	fakeAILatency := flag.Duration("fake-ai-latency", 500*time.Millisecond, "simulated model response time of the fake AI")
+	p95Budget := flag.Duration("p95", 0, "fail when a list or summary endpoint's p95 latency exceeds this (0 disables)")
+	flag.Parse()
+
+	cfg.baseURL = strings.TrimRight(cfg.baseURL, "/")
+	if cfg.concurrency < 1 {
+		log.Fatal("-concurrency must be at least 1")
+	}
+
+	if *fakeAI != "" {
+		server := &http.Server{Addr: *fakeAI, Handler: newFakeAI(*fakeAILatency)}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("fake AI failed: %v", err)
+			}
+		}()
+		defer server.Close()
+		log.Printf("Fake AI listening on %s", *fakeAI)
+	}
+
+	if err := waitForServer(cfg.baseURL, time.Minute); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Sending traffic to %s for %s with %d readers", cfg.baseURL, cfg.duration, cfg.concurrency)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.duration)
+	defer cancel()
+	stats := run(ctx, cfg)
+
+	stats.print(os.Stdout, cfg.duration)
+
+	if *p95Budget > 0 {
+		if over := stats.overBudget(*p95Budget); len(over) > 0 {
+			fmt.Fprintf(os.Stdout, "\np95 over %s: %s\n", *p95Budget, strings.Join(over, ", "))
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "\nall list and summary endpoints within p95 %s\n", *p95Budget)
+	}
+}
+
+// waitForServer polls /health until the server answers or timeout passes
+func waitForServer(baseURL string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server at %s is not healthy after %s", baseURL, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
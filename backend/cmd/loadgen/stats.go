@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// stats collects latencies and errors per endpoint
+type stats struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+}
+
+type endpointStats struct {
+	latencies []time.Duration
+	errors    int
+}
+
+func newStats() *stats {
+	return &stats{endpoints: make(map[string]*endpointStats)}
+}
+
+func (s *stats) record(name string, r result) {
+	if errors.Is(r.err, context.Canceled) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.endpoints[name]
+	if !ok {
+		e = &endpointStats{}
+		s.endpoints[name] = e
+	}
+	if r.err != nil {
+		e.errors++
+		return
+	}
+	e.latencies = append(e.latencies, r.latency)
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// print writes a table of request counts and latency percentiles
+func (s *stats) print(w io.Writer, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.endpoints))
+	for name := range s.endpoints {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "endpoint\trequests\terrors\tp50\tp95\tp99\tmax\t")
+	total := 0
+	for _, name := range names {
+		e := s.endpoints[name]
+		sorted := slices.Clone(e.latencies)
+		slices.Sort(sorted)
+		total += len(sorted) + e.errors
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n",
+			name, len(sorted)+e.errors, e.errors,
+			round(percentile(sorted, 50)), round(percentile(sorted, 95)),
+			round(percentile(sorted, 99)), round(percentile(sorted, 100)))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d requests in %s (%.1f req/s)\n", total, duration, float64(total)/duration.Seconds())
+}
+
+// overBudget returns the list and summary endpoints whose p95 exceeds budget;
+// receipt uploads are excluded because they wait on the AI provider
+func (s *stats) overBudget(budget time.Duration) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var over []string
+	for _, endpoint := range readEndpoints {
+		e, ok := s.endpoints[endpoint.name]
+		if !ok {
+			continue
+		}
+		sorted := slices.Clone(e.latencies)
+		slices.Sort(sorted)
+		if p95 := percentile(sorted, 95); p95 > budget {
+			over = append(over, fmt.Sprintf("%s (%s)", endpoint.name, round(p95)))
+		}
+	}
+	return over
+}
+
+func round(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type config struct {
+	baseURL     string
+	duration    time.Duration
+	concurrency int
+	uploadEvery time.Duration
+	token       string
+}
+
+// readEndpoint is a list or summary request; weight sets how often it is
+// picked relative to the others
+type readEndpoint struct {
+	name   string
+	weight int
+	path   func(month, year int) string
+}
+
+// readEndpoints mirror what the frontend loads when browsing months
+var readEndpoints = []readEndpoint{
+	{"list expenses by month", 35, func(m, y int) string {
+		return fmt.Sprintf("/api/actual-expenses?month=%d&year=%d", m, y)
+	}},
+	{"list expenses page", 15, func(m, y int) string {
+		return "/api/actual-expenses?limit=50&offset=0"
+	}},
+	{"expense summary", 20, func(m, y int) string {
+		return fmt.Sprintf("/api/actual-expenses/summary?month=%d&year=%d", m, y)
+	}},
+	{"budget status", 10, func(m, y int) string {
+		return fmt.Sprintf("/api/notifications/budget-status?month=%d&year=%d", m, y)
+	}},
+	{"list expected expenses", 10, func(m, y int) string {
+		return "/api/expected-expenses"
+	}},
+	{"list budgets", 10, func(m, y int) string {
+		return "/api/budgets"
+	}},
+}
+
+const uploadEndpoint = "receipt upload"
+
+// run sends traffic until ctx is done and returns the collected latencies
+func run(ctx context.Context, cfg config) *stats {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	s := newStats()
+
+	totalWeight := 0
+	for _, e := range readEndpoints {
+		totalWeight += e.weight
+	}
+
+	var wg sync.WaitGroup
+	for range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				endpoint := pickEndpoint(totalWeight)
+				month, year := randomRecentMonth()
+				req, _ := http.NewRequestWithContext(ctx, "GET", cfg.baseURL+endpoint.path(month, year), nil)
+				s.record(endpoint.name, send(client, req, cfg.token))
+			}
+		}()
+	}
+
+	if cfg.uploadEvery > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(cfg.uploadEvery)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					req, err := newUploadRequest(ctx, cfg.baseURL)
+					if err != nil {
+						s.record(uploadEndpoint, result{err: err})
+						continue
+					}
+					s.record(uploadEndpoint, send(client, req, cfg.token))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return s
+}
+
+func pickEndpoint(totalWeight int) readEndpoint {
+	n := rand.IntN(totalWeight)
+	for _, e := range readEndpoints {
+		if n < e.weight {
+			return e
+		}
+		n -= e.weight
+	}
+	return readEndpoints[0]
+}
+
+// randomRecentMonth returns one of the last 12 months, favoring the current one
+func randomRecentMonth() (int, int) {
+	back := 0
+	if rand.IntN(2) == 0 {
+		back = rand.IntN(12)
+	}
+	t := time.Now().AddDate(0, -back, 0)
+	return int(t.Month()), t.Year()
+}
+
+type result struct {
+	latency time.Duration
+	err     error
+}
+
+// send performs req and reads the whole response; requests cut off by the end
+// of the run are reported as canceled and not counted
+func send(client *http.Client, req *http.Request, token string) result {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return result{err: context.Canceled}
+		}
+		return result{err: err}
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	latency := time.Since(start)
+
+	if err != nil {
+		return result{latency: latency, err: err}
+	}
+	if resp.StatusCode >= 400 {
+		return result{latency: latency, err: fmt.Errorf("status %d", resp.StatusCode)}
+	}
+	return result{latency: latency}
+}
+
+// newUploadRequest builds a receipt upload with a small generated PDF
+func newUploadRequest(ctx context.Context, baseURL string) (*http.Request, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("document", "receipt.pdf")
+	if err != nil {
+		return nil, err
+	}
+	part.Write(receiptPDF())
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/receipts/process", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req, nil
+}
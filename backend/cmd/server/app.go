@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"budget-tracker/internal/api"
+	"budget-tracker/internal/api/handlers"
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/plugins"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"budget-tracker/internal/services/auth"
+	"budget-tracker/internal/services/demo"
+	"budget-tracker/internal/services/jobs"
+	"budget-tracker/internal/services/merchants"
+	"budget-tracker/internal/services/scheduler"
+)
+
+// services are shared by every database the server serves: the AI provider,
+// authentication, the runtime settings and the configuration of the jobs.
+// In tenant mode each household gets an app of its own on top of them.
+type services struct {
+	aiProvider     ai.ReceiptProvider
+	aiMonitor      *ai.HealthMonitor
+	enricher       ai.ExpenseEnricher
+	aiMonthlyQuota int
+	documentLimits ai.DocumentLimits
+
+	tokens            *auth.TokenIssuer
+	adminToken        string
+	google            *auth.GoogleProvider
+	accountMailer     *auth.AccountMailer
+	merchantDirectory merchants.Directory
+
+	rt      *api.Runtime
+	build   handlers.BuildInfo
+	dbMode  repository.Mode
+	version *handlers.VersionHandler
+	// capabilities are the same for every tenant
+	capabilities *handlers.CapabilitiesHandler
+
+	demoMode       bool
+	integrityCheck bool
+	jobs           jobSettings
+}
+
+// jobSettings configure the background jobs, see the *FromEnv functions
+type jobSettings struct {
+	interval          time.Duration
+	rollover          bool
+	rolloverLeadDays  int
+	paceNudge         bool
+	pacePercent       float64
+	budgetAlerts      bool
+	alertHysteresis   float64
+	expenseEnrichment bool
+}
+
+// app serves one database: the API and the background jobs working on it
+type app struct {
+	handler http.Handler
+	// sched is nil when background jobs are disabled
+	sched *scheduler.Scheduler
+}
+
+// newApp builds the API and the background jobs of db, with report endpoints
+// reading from reportDB. tenant is the household db belongs to in tenant
+// mode, and scopes its tokens and email links; it is empty otherwise.
+func newApp(db, reportDB *repository.DB, tenant string, s *services) (*app, error) {
+	// Initialize repositories
+	budgetRepo := repository.NewBudgetRepository(db)
+	expectedExpenseRepo := repository.NewExpectedExpenseRepository(db)
+	actualExpenseRepo := repository.NewActualExpenseRepository(db)
+	maintenanceRepo := repository.NewMaintenanceRepository(db)
+	metricsRepo := repository.NewMetricsRepository(db)
+	receiptHistoryRepo := repository.NewReceiptHistoryRepository(db)
+	aiUsageRepo := repository.NewAIUsageRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	importRepo := repository.NewImportRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	suggestionRepo := repository.NewExpenseSuggestionRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	allowanceRepo := repository.NewAllowanceRepository(db)
+	goalRepo := repository.NewSpendingGoalRepository(db)
+	ruleRepo := repository.NewCategoryRuleRepository(db)
+	sourceCapRepo := repository.NewSourceCapRepository(db)
+	paceRepo := repository.NewWeeklyPaceRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	// Budget and expense changes are published for the jobs and plugins
+	// reacting to them
+	bus := events.NewBus()
+	budgetRepo.PublishTo(bus)
+	actualExpenseRepo.PublishTo(bus)
+	if plugins.HasEventHandlers() {
+		bus.Subscribe(func(e events.Event) {
+			go plugins.HandleEvent(context.Background(), e)
+		})
+	}
+
+	if plugins.HasNotificationChannels() {
+		notificationRepo.OnCreate(func(n *models.Notification) {
+			// In the background so a slow channel holds up no job or request
+			go plugins.Notify(context.Background(), n)
+		})
+	}
+
+	// Read-only repositories for the report endpoints
+	reportBudgetRepo := repository.NewBudgetRepository(reportDB)
+	reportExpectedExpenseRepo := repository.NewExpectedExpenseRepository(reportDB)
+	reportActualExpenseRepo := repository.NewActualExpenseRepository(reportDB)
+	reportSettingsRepo := repository.NewSettingsRepository(reportDB)
+	reportSourceCapRepo := repository.NewSourceCapRepository(reportDB)
+
+	// The demo starts from its dataset on every boot
+	var demoSeeder *demo.Seeder
+	if s.demoMode {
+		demoSeeder = demo.NewSeeder(db)
+		if err := demoSeeder.Reset(time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to seed demo data: %w", err)
+		}
+		log.Println("Demo data seeded")
+	}
+
+	// Optionally report data inconsistencies on boot; they are logged, never fatal
+	if s.integrityCheck {
+		logIntegrityReport(maintenanceRepo)
+	}
+
+	// A household's tokens are not accepted by the others, and its emails
+	// link to its own subdomain
+	tokens, accountMailer := s.tokens, s.accountMailer
+	if tenant != "" {
+		if tokens != nil {
+			tokens = tokens.ForTenant(tenant)
+		}
+		accountMailer = accountMailer.ForTenant(tenant)
+	}
+
+	// Initialize handlers
+	budgetHandler := handlers.NewBudgetHandler(budgetRepo)
+	expectedExpenseHandler := handlers.NewExpectedExpenseHandler(expectedExpenseRepo)
+	actualExpenseHandler := handlers.NewActualExpenseHandler(
+		actualExpenseRepo,
+		budgetRepo,
+		reportActualExpenseRepo,
+	)
+	receiptHandler := handlers.NewReceiptHandler(
+		s.aiProvider,
+		expectedExpenseRepo,
+		actualExpenseRepo,
+		metricsRepo,
+		receiptHistoryRepo,
+		aiUsageRepo,
+		s.aiMonthlyQuota,
+	)
+	receiptHandler.SetDocumentLimits(s.documentLimits)
+	notificationHandler := handlers.NewNotificationHandler(
+		reportBudgetRepo,
+		reportExpectedExpenseRepo,
+		reportActualExpenseRepo,
+		reportSettingsRepo,
+		notificationRepo,
+		reportSourceCapRepo,
+	)
+	commentHandler := handlers.NewCommentHandler(commentRepo, actualExpenseRepo, notificationRepo)
+	suggestionHandler := handlers.NewSuggestionHandler(suggestionRepo, actualExpenseRepo)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	metricsHandler := handlers.NewMetricsHandler(metricsRepo)
+	settingsHandler := handlers.NewSettingsHandler(settingsRepo)
+	importHandler := handlers.NewImportHandler(importRepo)
+	exportHandler := handlers.NewExportHandler(reportActualExpenseRepo)
+	reportHandler := handlers.NewReportHandler(reportActualExpenseRepo)
+	goalHandler := handlers.NewGoalHandler(goalRepo)
+	ruleHandler := handlers.NewRuleHandler(ruleRepo)
+	sourceCapHandler := handlers.NewSourceCapHandler(sourceCapRepo)
+	authHandler := handlers.NewAuthHandler(userRepo, tokens, s.google, accountMailer, s.rt.Config().Registration)
+	s.rt.OnReload(func(cfg *api.RuntimeConfig) { authHandler.SetAllowRegistration(cfg.Registration) })
+	allowanceHandler := handlers.NewAllowanceHandler(allowanceRepo, userRepo, tokens)
+	merchantHandler := handlers.NewMerchantHandler(s.merchantDirectory)
+
+	// Background jobs; SCHEDULER_INTERVAL=0 disables them
+	var sched *scheduler.Scheduler
+	if s.jobs.interval > 0 {
+		sched = scheduler.New(s.jobs.interval)
+		add := func(job scheduler.Job, schedule string) error {
+			if err := sched.Add(job, schedule); err != nil {
+				return fmt.Errorf("failed to schedule background jobs: %w", err)
+			}
+			return nil
+		}
+		if s.jobs.rollover {
+			if err := add(
+				jobs.NewNextMonthBudgetJob(budgetRepo, settingsRepo, notificationRepo, s.jobs.rolloverLeadDays),
+				jobs.NextMonthBudgetSchedule,
+			); err != nil {
+				return nil, err
+			}
+		}
+		if err := add(
+			jobs.NewSpendingGoalJob(goalRepo, notificationRepo),
+			jobs.SpendingGoalSchedule,
+		); err != nil {
+			return nil, err
+		}
+		if err := add(
+			jobs.NewRecurringExpenseJob(expectedExpenseRepo, notificationRepo),
+			jobs.RecurringExpenseSchedule,
+		); err != nil {
+			return nil, err
+		}
+		if s.jobs.paceNudge {
+			if err := add(
+				jobs.NewWeeklyPaceJob(paceRepo, notificationRepo, s.jobs.pacePercent),
+				jobs.WeeklyPaceSchedule,
+			); err != nil {
+				return nil, err
+			}
+		}
+		if s.jobs.budgetAlerts {
+			thresholdJob := jobs.NewBudgetThresholdJob(budgetRepo, notificationRepo, s.jobs.alertHysteresis)
+			if err := add(thresholdJob, jobs.BudgetThresholdSchedule); err != nil {
+				return nil, err
+			}
+			thresholdJob.SubscribeTo(bus)
+		}
+		if s.jobs.expenseEnrichment && s.enricher != nil {
+			if err := add(
+				jobs.NewExpenseEnrichmentJob(suggestionRepo, notificationRepo, s.enricher),
+				jobs.ExpenseEnrichmentSchedule,
+			); err != nil {
+				return nil, err
+			}
+		}
+		if demoSeeder != nil {
+			if err := add(
+				jobs.NewDemoResetJob(demoSeeder),
+				jobs.DemoResetSchedule,
+			); err != nil {
+				return nil, err
+			}
+		}
+		if s.aiMonitor != nil {
+			if err := add(
+				jobs.NewAIHealthJob(s.aiMonitor, userRepo, notificationRepo),
+				jobs.AIHealthSchedule,
+			); err != nil {
+				return nil, err
+			}
+		}
+		applySavedSchedules(sched, settingsRepo)
+	}
+	adminHandler := handlers.NewAdminHandler(
+		maintenanceRepo,
+		userRepo,
+		receiptHistoryRepo,
+		sched,
+		settingsRepo,
+		s.aiMonitor,
+	)
+	healthHandler := handlers.NewHealthHandler(db, s.aiMonitor)
+	statusHandler := handlers.NewStatusHandler(s.build, s.dbMode, settingsRepo, receiptHistoryRepo)
+
+	// Create router with all handlers
+	h := &api.Handlers{
+		Budget:          budgetHandler,
+		ExpectedExpense: expectedExpenseHandler,
+		ActualExpense:   actualExpenseHandler,
+		Receipt:         receiptHandler,
+		Notification:    notificationHandler,
+		Comment:         commentHandler,
+		Suggestion:      suggestionHandler,
+		Audit:           auditHandler,
+		Allowance:       allowanceHandler,
+		Goal:            goalHandler,
+		Rule:            ruleHandler,
+		SourceCap:       sourceCapHandler,
+		Merchant:        merchantHandler,
+		Admin:           adminHandler,
+		Metrics:         metricsHandler,
+		Settings:        settingsHandler,
+		Import:          importHandler,
+		Export:          exportHandler,
+		Report:          reportHandler,
+		Health:          healthHandler,
+		Status:          statusHandler,
+		Version:         s.version,
+		Capabilities:    s.capabilities,
+		Auth:            authHandler,
+		AdminToken:      s.adminToken,
+		Admins:          userRepo,
+		Tokens:          tokens,
+		Freezes:         settingsRepo,
+		Notifications:   notificationRepo,
+		Runtime:         s.rt,
+	}
+	return &app{handler: api.NewRouter(h), sched: sched}, nil
+}
+
+// start runs the background jobs of the app until ctx is done
+func (a *app) start(ctx context.Context) {
+	if a.sched != nil {
+		a.sched.Start(ctx)
+	}
+}
+
+// wait blocks until the background jobs of the app have stopped
+func (a *app) wait() {
+	if a.sched != nil {
+		a.sched.Wait()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"budget-tracker/internal/api/handlers"
+	"log"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.builtAt=$(date -u +%FT%TZ)"
+var (
+	version = "0.0.0-dev"
+	commit  = ""
+	builtAt = ""
+)
+
+// buildInfo describes this build for /status and /api/version. Without an
+// injected commit, the VCS revision go build stamps into binaries built from
+// a checkout is used.
+func buildInfo() handlers.BuildInfo {
+	info := handlers.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuiltAt:   builtAt,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit != "" {
+		return info
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// logBuildBanner logs the build and the enabled features at startup, so a log
+// attached to a bug report says what was running
+func logBuildBanner(build handlers.BuildInfo, features map[string]bool) {
+	commit, builtAt := build.Commit, build.BuiltAt
+	if commit == "" {
+		commit = "unknown"
+	}
+	if builtAt == "" {
+		builtAt = "unknown"
+	}
+	log.Printf("Budget Tracker %s (commit %s, built %s, %s)", build.Version, commit, builtAt, build.GoVersion)
+
+	var enabled []string
+	for name, on := range features {
+		if on {
+			enabled = append(enabled, name)
+		}
+	}
+	if len(enabled) == 0 {
+		log.Println("Features: none enabled")
+		return
+	}
+	sort.Strings(enabled)
+	log.Printf("Features: %s", strings.Join(enabled, ", "))
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"budget-tracker/internal/config"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+)
+
+// credentialCheckTimeout bounds the AI provider request made by --check
+const credentialCheckTimeout = 15 * time.Second
+
+// readiness collects the results of --check
+type readiness struct {
+	w        io.Writer
+	failures int
+}
+
+func (r *readiness) ok(name, format string, args ...any) {
+	fmt.Fprintf(r.w, "[ok]   %-10s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+func (r *readiness) warn(name, format string, args ...any) {
+	fmt.Fprintf(r.w, "[warn] %-10s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+func (r *readiness) fail(name, format string, args ...any) {
+	r.failures++
+	fmt.Fprintf(r.w, "[fail] %-10s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+// runChecks validates the configuration, database, migrations and AI
+// credentials without starting the server or changing the database, prints a
+// readiness report to w and reports whether the server is ready to start
+func runChecks(w io.Writer) bool {
+	r := &readiness{w: w}
+
+	// Configuration
+	if err := config.Load(); err != nil {
+		r.fail("config", "%v", err)
+	}
+	if port := config.Get("PORT"); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			r.fail("config", "invalid PORT %q", port)
+		}
+	}
+	if interval, err := schedulerIntervalFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if interval == 0 {
+		r.ok("config", "background jobs disabled")
+	} else {
+		r.ok("config", "background jobs checked every %s", interval)
+	}
+	if leadDays, enabled, err := budgetRolloverFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if !enabled {
+		r.ok("config", "budget rollover disabled")
+	} else if leadDays == 0 {
+		r.ok("config", "budgets roll over on the first of the month")
+	} else {
+		r.ok("config", "budgets roll over %d day(s) before the end of the month", leadDays)
+	}
+	if percent, enabled, err := weeklyPaceFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if !enabled {
+		r.ok("config", "weekly pace nudges disabled")
+	} else {
+		r.ok("config", "weekly pace nudges at %g%% ahead of plan", percent)
+	}
+	if hysteresis, enabled, err := budgetAlertsFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if !enabled {
+		r.ok("config", "budget threshold alerts disabled")
+	} else {
+		r.ok("config", "budget threshold alerts, back on track %g point(s) below", hysteresis)
+	}
+	if enabled, err := expenseEnrichmentFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if enabled {
+		r.ok("config", "AI suggests names for expenses entered tersely")
+	}
+	if settings, err := tlsSettingsFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if settings == nil {
+		r.ok("config", "serving plain HTTP")
+	} else if settings.redirectPort != "" {
+		r.ok("config", "serving HTTPS, HTTP port %s redirects", settings.redirectPort)
+	} else {
+		r.ok("config", "serving HTTPS")
+	}
+	if quota, err := aiMonthlyQuotaFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if quota > 0 {
+		r.ok("config", "receipt processing limited to %d per user per month", quota)
+	}
+	if allowed, proxies, err := ipAllowlistFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if len(allowed) > 0 {
+		r.ok("config", "accepting requests from %d network(s), %d trusted proxies", len(allowed), len(proxies))
+	} else if len(proxies) > 0 {
+		r.warn("config", "TRUSTED_PROXIES has no effect without IP_ALLOWLIST")
+	}
+	if cfg, err := runtimeConfigFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if cfg.RateLimit > 0 {
+		r.ok("config", "logging at %s level, %d request(s) per minute per client", cfg.LogLevel, cfg.RateLimit)
+	} else {
+		r.ok("config", "logging at %s level, no rate limit", cfg.LogLevel)
+	}
+	tokens, err := tokenIssuerFromEnv()
+	if err != nil {
+		r.fail("config", "%v", err)
+	} else if tokens == nil {
+		r.warn("config", "JWT_SECRET not set, the API is unauthenticated")
+	} else {
+		r.ok("config", "authentication enabled")
+	}
+	switch {
+	case config.Get("ADMIN_TOKEN") != "":
+	case tokens == nil:
+		r.warn("config", "ADMIN_TOKEN not set, admin endpoints are disabled")
+	default:
+		r.ok("config", "ADMIN_TOKEN not set, admin endpoints need a user with the admin role")
+	}
+	if google, err := googleProviderFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if google != nil {
+		r.ok("config", "sign-in with Google enabled")
+	}
+	if mailer, err := accountMailerFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if mailer != nil {
+		r.ok("config", "email verification and password reset enabled")
+	}
+	if directory, err := merchantDirectoryFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if directory != nil {
+		r.ok("config", "merchant suggestions enabled (%s)", config.Get("MERCHANT_DIRECTORY"))
+	}
+	if fieldCipher, err := repository.NewFieldCipherFromEnv(); err != nil {
+		r.fail("config", "%v", err)
+	} else if fieldCipher != nil {
+		r.ok("config", "field encryption enabled")
+	}
+
+	// Database and migrations; in tenant mode every household's
+	tenants, tenantMode, err := repository.NewTenantDatabasesFromEnv()
+	switch {
+	case err != nil:
+		r.fail("config", "%v", err)
+	case !tenantMode:
+		dbConfig := repository.NewConfigFromEnv()
+		openDB := repository.NewDB
+		if demoMode, _ := strconv.ParseBool(config.Get("DEMO_MODE")); demoMode {
+			r.ok("config", "demo mode, serving sample data from %s", repository.NewDemoConfigFromEnv().LocalPath)
+			dbConfig = repository.NewDemoConfigFromEnv()
+			openDB = repository.NewDemoDB
+		}
+		r.checkDatabase("", dbConfig, openDB)
+	default:
+		if config.Get("TENANT_DOMAIN") == "" {
+			r.fail("config", "TENANTS needs TENANT_DOMAIN")
+		} else {
+			r.ok("config", "tenant mode, %d household(s) under %s", len(tenants.Tenants()), config.Get("TENANT_DOMAIN"))
+		}
+		if demoMode, _ := strconv.ParseBool(config.Get("DEMO_MODE")); demoMode {
+			r.fail("config", "DEMO_MODE cannot be combined with TENANTS")
+		}
+		if config.Get("TURSO_REPLICA_URL") != "" {
+			r.fail("config", "TURSO_REPLICA_URL cannot be combined with TENANTS")
+		}
+		for _, tenant := range tenants.Tenants() {
+			dbConfig, _ := tenants.Config(tenant)
+			r.checkDatabase(tenant+": ", dbConfig, repository.NewDB)
+		}
+	}
+
+	// Read replica for the report endpoints
+	if replicaConfig, ok := repository.NewReplicaConfigFromEnv(); ok && !tenantMode {
+		replica, err := repository.NewDB(replicaConfig)
+		if err != nil {
+			r.fail("replica", "cannot connect: %v", err)
+		} else {
+			replica.Close()
+			r.ok("replica", "connected, report endpoints read from it")
+		}
+	}
+
+	// AI provider; the server runs without one, so a missing one only warns
+	provider, err := ai.NewProviderFromEnv()
+	switch {
+	case errors.Is(err, ai.ErrAPIKeyNotSet) && config.Get("AI_PROVIDER") == "":
+		r.warn("ai", "no provider configured, receipt processing will be unavailable")
+	case err != nil:
+		r.fail("ai", "%v", err)
+	default:
+		if checker, ok := provider.(ai.CredentialChecker); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), credentialCheckTimeout)
+			err := checker.CheckCredentials(ctx)
+			cancel()
+			if err != nil {
+				r.fail("ai", "credentials rejected: %v", err)
+				break
+			}
+		}
+		if reporter, ok := provider.(ai.ModelReporter); ok {
+			if status := reporter.ModelStatus(); status.Active != status.Configured {
+				r.warn("ai", "model %s is not available, falling back to %s", status.Configured, status.Active)
+			}
+		}
+		// Credentials stay valid when the credit runs out, only a request tells
+		if checker, ok := provider.(ai.QuotaChecker); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), credentialCheckTimeout)
+			_, err := checker.CheckQuota(ctx)
+			cancel()
+			if errors.Is(err, ai.ErrQuotaExhausted) {
+				r.fail("ai", "%v", err)
+				break
+			}
+		}
+		r.ok("ai", "provider credentials verified")
+	}
+
+	if r.failures > 0 {
+		fmt.Fprintf(w, "\nnot ready: %d check(s) failed\n", r.failures)
+		return false
+	}
+	fmt.Fprintln(w, "\nready")
+	return true
+}
+
+// checkDatabase connects to the database of dbConfig and checks its
+// environment tag and pending migrations without applying them. prefix names
+// the household in tenant mode.
+func (r *readiness) checkDatabase(prefix string, dbConfig repository.Config, openDB func(repository.Config) (*repository.DB, error)) {
+	db, err := openDB(dbConfig)
+	if err != nil {
+		r.fail("database", "%scannot connect (%s mode): %v", prefix, dbConfig.Mode, err)
+		return
+	}
+	defer db.Close()
+	r.ok("database", "%sconnected (%s mode)", prefix, dbConfig.Mode)
+	if env, err := db.Environment(); err != nil {
+		r.fail("database", "%s%v", prefix, err)
+	} else if dbConfig.Environment == "" && env != "" {
+		r.warn("database", "%stagged for environment %q but APP_ENV is not set, so it is not checked", prefix, env)
+	} else if dbConfig.Environment != "" && env == "" {
+		r.ok("database", "%suntagged, migrating tags it for environment %q", prefix, dbConfig.Environment)
+	}
+
+	pending, err := db.VerifyMigrations()
+	switch {
+	case err != nil:
+		r.fail("migrations", "%s%v", prefix, err)
+	case len(pending) == 0:
+		r.ok("migrations", "%sschema is up to date", prefix)
+	default:
+		r.ok("migrations", "%s%d pending, all apply cleanly (first: %s)",
+			prefix, len(pending), pending[0].Description)
+	}
+}
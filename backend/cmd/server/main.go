@@ -2,82 +2,355 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
+	// The runtime image has no zoneinfo, which ?tz= of /api/budgets/current needs
+	_ "time/tzdata"
 
 	"budget-tracker/internal/api"
 	"budget-tracker/internal/api/handlers"
+	"budget-tracker/internal/config"
+	"budget-tracker/internal/plugins"
 	"budget-tracker/internal/repository"
 	"budget-tracker/internal/services/ai"
+	"budget-tracker/internal/services/auth"
+	"budget-tracker/internal/services/jobs"
+	"budget-tracker/internal/services/mail"
+	"budget-tracker/internal/services/merchants"
+	"budget-tracker/internal/services/scheduler"
 )
 
 func main() {
-	log.Println("Starting Budget Tracker API server...")
+	check := flag.Bool("check", false, "validate config, database, migrations and AI credentials, then exit")
+	flag.Parse()
+
+	if *check {
+		if !runChecks(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	build := buildInfo()
+	log.Printf("Starting Budget Tracker API server %s...", build.Version)
+
+	// Settings may also come from secret files and a .env file
+	if err := config.Load(); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Sensitive columns are encrypted before they reach the database
+	fieldCipher, err := repository.NewFieldCipherFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid field encryption key: %v", err)
+	}
+	if fieldCipher != nil {
+		repository.SetFieldCipher(fieldCipher)
+		log.Println("Field encryption enabled")
+	}
+
+	// Demo mode serves a public, periodically reset sample dataset from its
+	// own database, with authentication and receipt processing disabled
+	demoMode, _ := strconv.ParseBool(config.Get("DEMO_MODE"))
+
+	// Tenant mode serves several households from one process, each from a
+	// database of its own opened on its first request
+	tenants, tenantMode, err := repository.NewTenantDatabasesFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tenantDomain := strings.ToLower(strings.Trim(config.Get("TENANT_DOMAIN"), "."))
+	if tenantMode {
+		switch {
+		case demoMode:
+			log.Fatal("DEMO_MODE cannot be combined with TENANTS")
+		case config.Get("TURSO_REPLICA_URL") != "":
+			log.Fatal("TURSO_REPLICA_URL cannot be combined with TENANTS, every household has its own database")
+		case tenantDomain == "":
+			log.Fatal("TENANTS needs TENANT_DOMAIN, the domain whose subdomains are the households")
+		}
+		defer func() {
+			if err := tenants.Close(); err != nil {
+				log.Printf("Failed to close tenant databases: %v", err)
+			}
+		}()
+		log.Printf("Tenant mode: %d household(s) under %s", len(tenants.Tenants()), tenantDomain)
+	}
 
 	// Initialize database
 	dbConfig := repository.NewConfigFromEnv()
-	db, err := repository.NewDB(dbConfig)
+	var db, reportDB *repository.DB
+	if !tenantMode {
+		if demoMode {
+			log.Println("Demo mode enabled, real data is never opened")
+			dbConfig = repository.NewDemoConfigFromEnv()
+			db, err = repository.NewDemoDB(dbConfig)
+		} else {
+			db, err = repository.NewDB(dbConfig)
+		}
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+	}
+
+	if dbConfig.Environment != "" {
+		log.Printf("Environment: %s", dbConfig.Environment)
+	}
+
+	if !tenantMode {
+		// Run database migrations; a database tagged for another environment is refused
+		if err := db.RunMigrations(); err != nil {
+			log.Fatalf("Failed to run database migrations: %v", err)
+		}
+
+		// Report endpoints read from the replica when one is configured, so their
+		// queries never wait on the writer
+		reportDB = db
+		if replicaConfig, ok := repository.NewReplicaConfigFromEnv(); ok && !demoMode {
+			replica, err := repository.NewDB(replicaConfig)
+			if err != nil {
+				log.Fatalf("Failed to connect to read replica: %v", err)
+			}
+			defer replica.Close()
+			reportDB = replica
+			log.Println("Report endpoints read from the replica")
+		}
+	}
+
+	// Initialize AI provider (optional - receipt processing won't work without it)
+	aiProvider, err := ai.NewProviderFromEnv()
+	var aiMonitor *ai.HealthMonitor
+	var enricher ai.ExpenseEnricher
+	if demoMode {
+		// Visitors of a public demo must not spend the AI credits
+		aiProvider = nil
+		log.Println("Receipt processing is disabled in demo mode")
+	} else if err != nil {
+		log.Printf("Warning: AI provider not initialized: %v", err)
+		log.Println("Receipt processing will be unavailable")
+	} else {
+		log.Println("AI provider initialized successfully")
+		enricher, _ = aiProvider.(ai.ExpenseEnricher)
+		// Track whether the provider still accepts work, for /readyz
+		aiMonitor = ai.NewHealthMonitor(aiProvider)
+		aiProvider = aiMonitor
+	}
+
+	// Plugins register themselves when compiled in, see plugins.go
+	if names := plugins.Names(); len(names) > 0 {
+		log.Printf("Plugins: %s", strings.Join(names, ", "))
+	}
+
+	aiMonthlyQuota, err := aiMonthlyQuotaFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatal(err)
+	}
+	if aiMonthlyQuota > 0 {
+		log.Printf("Receipt processing limited to %d per user per month", aiMonthlyQuota)
 	}
-	defer db.Close()
 
-	// Run database migrations
-	if err := db.RunMigrations(); err != nil {
-		log.Fatalf("Failed to run database migrations: %v", err)
+	documentLimits, err := documentLimitsFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Initialize AI client (optional - receipt processing won't work without it)
-	var aiClient *ai.Client
-	aiClient, err = ai.NewClientFromEnv()
+	// Authentication is enforced only when JWT_SECRET is set
+	tokens, err := tokenIssuerFromEnv()
 	if err != nil {
-		log.Printf("Warning: AI client not initialized: %v", err)
-		log.Println("Receipt processing will be unavailable")
-		aiClient = nil
+		log.Fatal(err)
+	}
+	if demoMode {
+		// Every visitor shares the demo workspace
+		tokens = nil
+		log.Println("Authentication is disabled in demo mode")
+	} else if tokens == nil {
+		log.Println("JWT_SECRET not set, authentication is disabled")
+	}
+
+	// The admin API accepts ADMIN_TOKEN and, with authentication, admin users
+	adminToken := config.Get("ADMIN_TOKEN")
+	if adminToken == "" && tokens == nil {
+		log.Println("ADMIN_TOKEN not set, admin endpoints are disabled")
+	}
+	google, err := googleProviderFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if tenantMode && google != nil {
+		// Google redirects to a single callback URL, not one per household
+		google = nil
+		log.Println("Sign-in with Google is disabled in tenant mode")
+	}
+	accountMailer, err := accountMailerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if tokens != nil && accountMailer == nil {
+		log.Println("SMTP_HOST not set, email verification and password reset are disabled")
+	}
+	// The log level, CORS origins, rate limit and registration reload on
+	// SIGHUP and POST /api/admin/config/reload
+	rt, err := api.NewRuntime(func() (*api.RuntimeConfig, error) {
+		// Reread the .env file and secrets, e.g. a changed ConfigMap
+		if err := config.Load(); err != nil {
+			return nil, err
+		}
+		return runtimeConfigFromEnv()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	merchantDirectory, err := merchantDirectoryFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if merchantDirectory == nil {
+		log.Println("MERCHANT_DIRECTORY not set, merchant suggestions are disabled")
+	}
+
+	// Background jobs; SCHEDULER_INTERVAL=0 disables them
+	jobSettings, err := jobSettingsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if jobSettings.interval > 0 {
+		if !jobSettings.rollover {
+			log.Println("BUDGET_ROLLOVER is off, monthly budgets are not created automatically")
+		}
+		if jobSettings.expenseEnrichment && enricher == nil {
+			log.Println("EXPENSE_ENRICHMENT needs an AI provider, expense names are not enriched")
+		}
+		log.Printf("Background jobs checked every %s", jobSettings.interval)
 	} else {
-		log.Println("AI client initialized successfully")
+		log.Println("SCHEDULER_INTERVAL is 0, background jobs are disabled")
 	}
+	jobsOn := jobSettings.interval > 0
 
-	// Initialize repositories
-	budgetRepo := repository.NewBudgetRepository(db)
-	expectedExpenseRepo := repository.NewExpectedExpenseRepository(db)
-	actualExpenseRepo := repository.NewActualExpenseRepository(db)
+	// Reported by /api/version and logged, so bug reports say what was on
+	features := map[string]bool{
+		"authentication":     tokens != nil,
+		"registration":       rt.Config().Registration,
+		"google_sign_in":     google != nil,
+		"email":              accountMailer != nil,
+		"field_encryption":   fieldCipher != nil,
+		"demo_mode":          demoMode,
+		"tenants":            tenantMode,
+		"receipt_processing": aiMonitor != nil,
+		"read_replica":       reportDB != db,
+		"merchant_directory": merchantDirectory != nil,
+		"plugins":            len(plugins.Names()) > 0,
+		"background_jobs":    jobsOn,
+		"budget_rollover":    jobsOn && jobSettings.rollover,
+		"weekly_pace_nudge":  jobsOn && jobSettings.paceNudge,
+		"budget_alerts":      jobsOn && jobSettings.budgetAlerts,
+		"expense_enrichment": jobsOn && jobSettings.expenseEnrichment && enricher != nil,
+	}
+	logBuildBanner(build, features)
+	versionHandler := handlers.NewVersionHandler(build, features)
+	rt.OnReload(func(cfg *api.RuntimeConfig) { versionHandler.SetFeature("registration", cfg.Registration) })
+	capabilitiesHandler := handlers.NewCapabilitiesHandler(features, documentLimits)
 
-	// Initialize handlers
-	budgetHandler := handlers.NewBudgetHandler(budgetRepo)
-	expectedExpenseHandler := handlers.NewExpectedExpenseHandler(expectedExpenseRepo)
-	actualExpenseHandler := handlers.NewActualExpenseHandler(actualExpenseRepo)
-	receiptHandler := handlers.NewReceiptHandler(aiClient, expectedExpenseRepo, actualExpenseRepo)
-	notificationHandler := handlers.NewNotificationHandler(budgetRepo, expectedExpenseRepo, actualExpenseRepo)
+	integrityCheck, _ := strconv.ParseBool(config.Get("STARTUP_INTEGRITY_CHECK"))
+	shared := &services{
+		aiProvider:        aiProvider,
+		aiMonitor:         aiMonitor,
+		enricher:          enricher,
+		aiMonthlyQuota:    aiMonthlyQuota,
+		documentLimits:    documentLimits,
+		tokens:            tokens,
+		adminToken:        adminToken,
+		google:            google,
+		accountMailer:     accountMailer,
+		merchantDirectory: merchantDirectory,
+		rt:                rt,
+		build:             build,
+		dbMode:            dbConfig.Mode,
+		version:           versionHandler,
+		capabilities:      capabilitiesHandler,
+		demoMode:          demoMode,
+		integrityCheck:    integrityCheck,
+		jobs:              jobSettings,
+	}
+
+	// Start background jobs with the app they belong to; in tenant mode a
+	// household's jobs start when its database is opened
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var (
+		appsMu sync.Mutex
+		apps   []*app
+	)
+	var router http.Handler
+	if tenantMode {
+		router = api.NewTenantRouter(tenantDomain, tenants.Tenants(), func(tenant string) (http.Handler, error) {
+			tenantDB, err := tenants.Open(tenant)
+			if err != nil {
+				return nil, err
+			}
+			a, err := newApp(tenantDB, tenantDB, tenant, shared)
+			if err != nil {
+				return nil, err
+			}
+			appsMu.Lock()
+			defer appsMu.Unlock()
+			apps = append(apps, a)
+			a.start(jobsCtx)
+			return a.handler, nil
+		})
+	} else {
+		a, err := newApp(db, reportDB, "", shared)
+		if err != nil {
+			log.Fatal(err)
+		}
+		apps = append(apps, a)
+		a.start(jobsCtx)
+		router = a.handler
+	}
 
-	// Create router with all handlers
-	h := &api.Handlers{
-		Budget:          budgetHandler,
-		ExpectedExpense: expectedExpenseHandler,
-		ActualExpense:   actualExpenseHandler,
-		Receipt:         receiptHandler,
-		Notification:    notificationHandler,
+	// Optionally only accept requests from the configured networks
+	allowedIPs, trustedProxies, err := ipAllowlistFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(allowedIPs) > 0 {
+		log.Printf("Accepting requests from %d allowed network(s) only", len(allowedIPs))
 	}
-	router := api.NewRouter(h)
 
 	// Apply middleware
 	handler := api.Chain(
 		router,
 		api.Recovery,
-		api.Logger,
-		api.CORS(api.DefaultCORSConfig()),
+		api.RequestLogger(rt),
+		api.AllowIPs(allowedIPs, trustedProxies),
+		api.ReloadableCORS(rt, api.DefaultCORSConfig()),
+		api.RateLimit(rt, trustedProxies),
 	)
 
 	// Get port from environment variable or use default
-	port := os.Getenv("PORT")
+	port := config.Get("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	// Serve HTTPS directly when a certificate is configured
+	tlsSettings, err := tlsSettingsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + port,
@@ -88,10 +361,49 @@ func main() {
 	}
 
 	// Start server in a goroutine
+	var redirectServer *http.Server
+	if tlsSettings != nil {
+		server.TLSConfig = tlsSettings.config
+		go func() {
+			log.Printf("Server listening on port %s (HTTPS)", port)
+			// The certificate is already in TLSConfig
+			if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start server: %v", err)
+			}
+		}()
+
+		if tlsSettings.redirectPort != "" {
+			redirectServer = &http.Server{
+				Addr:         ":" + tlsSettings.redirectPort,
+				Handler:      httpsRedirect(port),
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+				IdleTimeout:  60 * time.Second,
+			}
+			go func() {
+				log.Printf("Redirecting HTTP on port %s to HTTPS", tlsSettings.redirectPort)
+				if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("Failed to start HTTP redirect server: %v", err)
+				}
+			}()
+		}
+	} else {
+		go func() {
+			log.Printf("Server listening on port %s", port)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start server: %v", err)
+			}
+		}()
+	}
+
+	// Reload the runtime settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		log.Printf("Server listening on port %s", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+		for range hup {
+			if _, err := rt.Reload(); err != nil {
+				log.Printf("Configuration reload failed, keeping the current one: %v", err)
+			}
 		}
 	}()
 
@@ -101,14 +413,311 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
+	// Stop background jobs before closing the databases
+	stopJobs()
+	appsMu.Lock()
+	for _, a := range apps {
+		a.wait()
+	}
+	appsMu.Unlock()
+
 	// Create a deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	// Attempt graceful shutdown
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			log.Printf("HTTP redirect server forced to shutdown: %v", err)
+		}
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
 	log.Println("Server exited gracefully")
 }
+
+// logIntegrityReport runs the data integrity checks and logs every issue found
+func logIntegrityReport(maintenance *repository.MaintenanceRepository) {
+	report, err := maintenance.CheckIntegrity()
+	if err != nil {
+		log.Printf("Warning: integrity check failed: %v", err)
+		return
+	}
+	if report.OK {
+		log.Println("Integrity check passed")
+		return
+	}
+
+	for _, issue := range report.Issues {
+		if issue.ID != 0 {
+			log.Printf("Integrity: %s (id %d): %s", issue.Check, issue.ID, issue.Description)
+		} else {
+			log.Printf("Integrity: %s: %s", issue.Check, issue.Description)
+		}
+	}
+	log.Printf(
+		"Warning: integrity check found %d issues; see GET /api/admin/integrity or run budgetctl integrity",
+		len(report.Issues),
+	)
+}
+
+// jobSettingsFromEnv reads the configuration of the background jobs
+func jobSettingsFromEnv() (jobSettings, error) {
+	var s jobSettings
+	var err error
+	if s.interval, err = schedulerIntervalFromEnv(); err != nil {
+		return s, err
+	}
+	if s.rolloverLeadDays, s.rollover, err = budgetRolloverFromEnv(); err != nil {
+		return s, err
+	}
+	if s.pacePercent, s.paceNudge, err = weeklyPaceFromEnv(); err != nil {
+		return s, err
+	}
+	if s.alertHysteresis, s.budgetAlerts, err = budgetAlertsFromEnv(); err != nil {
+		return s, err
+	}
+	if s.expenseEnrichment, err = expenseEnrichmentFromEnv(); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// schedulerIntervalFromEnv reads SCHEDULER_INTERVAL, how often the scheduler
+// checks for due jobs (default 1m, 0 disables background jobs)
+func schedulerIntervalFromEnv() (time.Duration, error) {
+	v := config.Get("SCHEDULER_INTERVAL")
+	if v == "" {
+		return time.Minute, nil
+	}
+	interval, err := time.ParseDuration(v)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid SCHEDULER_INTERVAL %q: expected a duration such as 30m", v)
+	}
+	return interval, nil
+}
+
+// budgetRolloverFromEnv reads BUDGET_ROLLOVER, how many days before the end
+// of the month the next month's budget is created (default 3, 0 for on the
+// first of the month); "off" disables the rollover
+func budgetRolloverFromEnv() (leadDays int, enabled bool, err error) {
+	v := config.Get("BUDGET_ROLLOVER")
+	switch {
+	case v == "":
+		return jobs.DefaultLeadDays, true, nil
+	case strings.EqualFold(v, "off"):
+		return 0, false, nil
+	}
+	leadDays, err = strconv.Atoi(v)
+	if err != nil || leadDays < 0 || leadDays > 27 {
+		return 0, false, fmt.Errorf("invalid BUDGET_ROLLOVER %q: expected a number of days from 0 to 27, or off", v)
+	}
+	return leadDays, true, nil
+}
+
+// weeklyPaceFromEnv reads WEEKLY_PACE_NUDGE, how many percent weekly spending
+// may run ahead of the prorated weekly plan before a nudge is sent, or off
+func weeklyPaceFromEnv() (percent float64, enabled bool, err error) {
+	v := config.Get("WEEKLY_PACE_NUDGE")
+	switch {
+	case v == "":
+		return jobs.DefaultPacePercent, true, nil
+	case strings.EqualFold(v, "off"):
+		return 0, false, nil
+	}
+	percent, err = strconv.ParseFloat(v, 64)
+	if err != nil || percent < 0 || percent > 1000 {
+		return 0, false, fmt.Errorf("invalid WEEKLY_PACE_NUDGE %q: expected a percentage from 0 to 1000, or off", v)
+	}
+	return percent, true, nil
+}
+
+// budgetAlertsFromEnv reads BUDGET_ALERTS, how many percentage points
+// spending must fall below a crossed budget threshold before the back on
+// track notification is sent, or off to send no threshold alerts
+func budgetAlertsFromEnv() (hysteresis float64, enabled bool, err error) {
+	v := config.Get("BUDGET_ALERTS")
+	switch {
+	case v == "":
+		return jobs.DefaultHysteresis, true, nil
+	case strings.EqualFold(v, "off"):
+		return 0, false, nil
+	}
+	hysteresis, err = strconv.ParseFloat(v, 64)
+	if err != nil || hysteresis < 0 || hysteresis > 100 {
+		return 0, false, fmt.Errorf("invalid BUDGET_ALERTS %q: expected percentage points from 0 to 100, or off", v)
+	}
+	return hysteresis, true, nil
+}
+
+// expenseEnrichmentFromEnv reads EXPENSE_ENRICHMENT, on to have the AI
+// suggest readable names for expenses entered tersely (default off)
+func expenseEnrichmentFromEnv() (bool, error) {
+	switch v := config.Get("EXPENSE_ENRICHMENT"); {
+	case v == "" || strings.EqualFold(v, "off"):
+		return false, nil
+	case strings.EqualFold(v, "on"):
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid EXPENSE_ENRICHMENT %q: expected on or off", v)
+	}
+}
+
+// ipAllowlistFromEnv reads IP_ALLOWLIST, the CIDR ranges requests are
+// accepted from (default: any), and TRUSTED_PROXIES, the reverse proxies whose
+// X-Forwarded-For header names the client
+func ipAllowlistFromEnv() (allowed, trustedProxies []netip.Prefix, err error) {
+	allowed, err = api.ParsePrefixes(config.Get("IP_ALLOWLIST"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid IP_ALLOWLIST: %w", err)
+	}
+	trustedProxies, err = api.ParsePrefixes(config.Get("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	return allowed, trustedProxies, nil
+}
+
+// runtimeConfigFromEnv reads the settings that reload without a restart:
+// LOG_LEVEL (default info), CORS_ORIGINS, the comma-separated origins the
+// browser may call the API from (default: any), RATE_LIMIT, requests per
+// minute per client (default 0, unlimited) and ALLOW_REGISTRATION
+func runtimeConfigFromEnv() (*api.RuntimeConfig, error) {
+	level, err := api.ParseLogLevel(config.Get("LOG_LEVEL"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(config.Get("CORS_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+
+	rateLimit := 0
+	if v := config.Get("RATE_LIMIT"); v != "" {
+		rateLimit, err = strconv.Atoi(v)
+		if err != nil || rateLimit < 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT %q: expected requests per minute, 0 for no limit", v)
+		}
+	}
+
+	allowRegistration, _ := strconv.ParseBool(config.Get("ALLOW_REGISTRATION"))
+	return &api.RuntimeConfig{
+		LogLevel:     level,
+		CORSOrigins:  origins,
+		RateLimit:    rateLimit,
+		Registration: allowRegistration,
+	}, nil
+}
+
+// aiMonthlyQuotaFromEnv reads AI_MONTHLY_QUOTA, how many receipts each user
+// may process per calendar month (default 0, unlimited)
+func aiMonthlyQuotaFromEnv() (int, error) {
+	v := config.Get("AI_MONTHLY_QUOTA")
+	if v == "" {
+		return 0, nil
+	}
+	quota, err := strconv.Atoi(v)
+	if err != nil || quota < 0 {
+		return 0, fmt.Errorf("invalid AI_MONTHLY_QUOTA %q: expected a number of receipts, 0 for unlimited", v)
+	}
+	return quota, nil
+}
+
+// documentLimitsFromEnv reads the receipts the deployment accepts:
+// RECEIPT_MAX_UPLOAD_MB, the largest upload in megabytes (default 10),
+// RECEIPT_ALLOWED_TYPES, a comma separated list of mime types (default
+// application/pdf), and RECEIPT_MAX_PAGES, the most pages of a PDF (default
+// 0, unlimited)
+func documentLimitsFromEnv() (ai.DocumentLimits, error) {
+	limits := ai.DefaultDocumentLimits()
+	if v := config.Get("RECEIPT_MAX_UPLOAD_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil || mb < 1 || mb > 1024 {
+			return limits, fmt.Errorf("invalid RECEIPT_MAX_UPLOAD_MB %q: expected a number of megabytes from 1 to 1024", v)
+		}
+		limits.MaxSize = int64(mb) << 20
+	}
+	if v := config.Get("RECEIPT_ALLOWED_TYPES"); v != "" {
+		limits.AllowedTypes = nil
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" && !slices.Contains(limits.AllowedTypes, t) {
+				limits.AllowedTypes = append(limits.AllowedTypes, t)
+			}
+		}
+	}
+	if v := config.Get("RECEIPT_MAX_PAGES"); v != "" {
+		pages, err := strconv.Atoi(v)
+		if err != nil || pages < 0 {
+			return limits, fmt.Errorf("invalid RECEIPT_MAX_PAGES %q: expected a number of pages, 0 for unlimited", v)
+		}
+		limits.MaxPages = pages
+	}
+	if err := limits.Validate(); err != nil {
+		return limits, fmt.Errorf("invalid receipt document limits: %w", err)
+	}
+	return limits, nil
+}
+
+// applySavedSchedules applies the cron schedules saved via the admin API; an
+// invalid or stale entry is logged and the job keeps its default schedule
+func applySavedSchedules(sched *scheduler.Scheduler, settings *repository.SettingsRepository) {
+	schedules, err := settings.GetSchedules()
+	if err != nil {
+		log.Printf("Warning: failed to load saved schedules: %v", err)
+		return
+	}
+	for name, spec := range schedules {
+		if _, err := sched.SetSchedule(name, spec); err != nil {
+			log.Printf("Warning: ignoring saved schedule for %s: %v", name, err)
+		}
+	}
+}
+
+// tokenIssuerFromEnv returns the JWT issuer configured by JWT_SECRET and
+// JWT_TTL, or nil when JWT_SECRET is not set and authentication is disabled
+func tokenIssuerFromEnv() (*auth.TokenIssuer, error) {
+	tokens, err := auth.NewTokenIssuerFromEnv()
+	if errors.Is(err, auth.ErrSecretNotSet) {
+		return nil, nil
+	}
+	return tokens, err
+}
+
+// googleProviderFromEnv returns the Google sign-in provider configured by the
+// GOOGLE_* variables, or nil when GOOGLE_CLIENT_ID is not set
+func googleProviderFromEnv() (*auth.GoogleProvider, error) {
+	google, err := auth.NewGoogleProviderFromEnv()
+	if errors.Is(err, auth.ErrGoogleNotConfigured) {
+		return nil, nil
+	}
+	return google, err
+}
+
+// merchantDirectoryFromEnv returns the merchant directory configured by the
+// MERCHANT_DIRECTORY* variables, or nil when MERCHANT_DIRECTORY is not set
+func merchantDirectoryFromEnv() (merchants.Directory, error) {
+	directory, err := merchants.NewDirectoryFromEnv()
+	if errors.Is(err, merchants.ErrDirectoryNotConfigured) {
+		return nil, nil
+	}
+	return directory, err
+}
+
+// accountMailerFromEnv returns the mailer of the email verification and
+// password reset emails configured by the SMTP_* variables, or nil when
+// SMTP_HOST is not set
+func accountMailerFromEnv() (*auth.AccountMailer, error) {
+	mailer, err := auth.NewAccountMailerFromEnv()
+	if errors.Is(err, mail.ErrNotConfigured) {
+		return nil, nil
+	}
+	return mailer, err
+}
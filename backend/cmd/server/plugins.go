@@ -0,0 +1,13 @@
+package main
+
+// Plugins compiled into the server register themselves from their init
+// functions, see internal/plugins. Enable one with a blank import here, or in
+// a file of its own with a build tag to make it optional:
+//
+//	//go:build mybank
+//
+//	package main
+//
+//	import _ "budget-tracker/plugins/mybank"
+//
+// and build with go build -tags mybank ./cmd/server.
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"budget-tracker/internal/config"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// tlsSettings is the HTTPS configuration read from the environment
+type tlsSettings struct {
+	config *tls.Config
+	// redirectPort is the plain HTTP port that redirects to HTTPS; empty
+	// when HTTP_REDIRECT_PORT is not set
+	redirectPort string
+}
+
+// tlsSettingsFromEnv loads the certificate named by TLS_CERT_FILE and
+// TLS_KEY_FILE, or returns nil when neither is set and the server speaks
+// plain HTTP. HTTP_REDIRECT_PORT additionally serves redirects to HTTPS.
+func tlsSettingsFromEnv() (*tlsSettings, error) {
+	certFile := config.Get("TLS_CERT_FILE")
+	keyFile := config.Get("TLS_KEY_FILE")
+	redirectPort := config.Get("HTTP_REDIRECT_PORT")
+
+	if certFile == "" && keyFile == "" {
+		if redirectPort != "" {
+			return nil, errors.New("HTTP_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if redirectPort != "" {
+		if n, err := strconv.Atoi(redirectPort); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid HTTP_REDIRECT_PORT %q", redirectPort)
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tlsSettings{config: newTLSConfig(cert), redirectPort: redirectPort}, nil
+}
+
+// newTLSConfig returns the server's TLS configuration: TLS 1.2 or later and,
+// for TLS 1.2, only forward-secret AEAD cipher suites. TLS 1.3 suites are
+// not configurable and all secure.
+func newTLSConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+}
+
+// httpsRedirect redirects every request to the same URL on the HTTPS port.
+// Methods other than GET and HEAD get 308 so clients resend the body.
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
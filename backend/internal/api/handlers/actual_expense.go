@@ -2,61 +2,132 @@ package handlers
 
 import (
 	"budget-tracker/internal/models"
+	"budget-tracker/internal/plugins"
 	"budget-tracker/internal/repository"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
 type ActualExpenseHandler struct {
-	repo *repository.ActualExpenseRepository
+	repo       *repository.ActualExpenseRepository
+	budgetRepo *repository.BudgetRepository
+	reportRepo *repository.ActualExpenseRepository
 }
 
-func NewActualExpenseHandler(repo *repository.ActualExpenseRepository) *ActualExpenseHandler {
-	return &ActualExpenseHandler{repo: repo}
+// NewActualExpenseHandler creates a new ActualExpenseHandler
+// reportRepo is optional; when set, the summary is read through it, e.g.
+// from a read replica. Otherwise repo is used.
+func NewActualExpenseHandler(
+	repo *repository.ActualExpenseRepository,
+	budgetRepo *repository.BudgetRepository,
+	reportRepo *repository.ActualExpenseRepository,
+) *ActualExpenseHandler {
+	if reportRepo == nil {
+		reportRepo = repo
+	}
+	return &ActualExpenseHandler{repo: repo, budgetRepo: budgetRepo, reportRepo: reportRepo}
 }
 
 type ActualExpenseListResponse struct {
 	Expenses []models.ActualExpense `json:"expenses"`
-	Total    int                    `json:"total"`
+	Page
 }
 
+// List handles GET /api/actual-expenses
+// Supports optional filters: type, year or month with year, receipt_number, from/to (YYYY-MM-DD receipt dates),
+// q (search in item name, source and item code), pending_approval, deductible and auto_generated (true/false),
+// tags (comma-separated, all must match) and limit/offset paging.
+// Total is the number of matching expenses before paging.
+// include=expected_expense embeds each expense's linked expected expense.
 func (h *ActualExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
-	// Parse query params: month, year, type
 	query := r.URL.Query()
-	monthStr := query.Get("month")
-	yearStr := query.Get("year")
-	expenseType := query.Get("type")
-
-	var expenses []models.ActualExpense
+	var filter repository.ExpenseFilter
 	var err error
 
-	// Default to current month/year if provided
-	if monthStr != "" && yearStr != "" {
-		month, _ := strconv.Atoi(monthStr)
-		year, _ := strconv.Atoi(yearStr)
-
-		if expenseType != "" && expenseType != "ALL" {
-			expenses, err = h.repo.GetByTypeAndMonthYear(
-				models.ExpenseType(strings.ToLower(expenseType)),
-				month,
-				year,
-			)
-		} else {
-			expenses, err = h.repo.GetByMonthYear(month, year)
+	// "ALL" (any case) or an empty value means no type filter
+	if expenseType := models.NormalizeExpenseType(query.Get("type")); expenseType != "" &&
+		expenseType != "all" {
+		if filter.Type, err = models.ParseExpenseType(string(expenseType)); err != nil {
+			http.Error(w, "Invalid type filter", http.StatusBadRequest)
+			return
 		}
-	} else if expenseType != "" && expenseType != "ALL" {
-		expenses, err = h.repo.GetByType(models.ExpenseType(strings.ToLower(expenseType)))
-	} else {
-		expenses, err = h.repo.GetAll()
 	}
 
+	if err := parseMonthYear(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	receiptNumber, err := parseOptionalInt(query, "receipt_number")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.ReceiptNumber = int64(receiptNumber)
+	if err := parseDateRange(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := parseSearchAndPaging(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("pending_approval"); v != "" {
+		pending, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "pending_approval must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.Pending = &pending
+	}
+	if v := query.Get("deductible"); v != "" {
+		deductible, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "deductible must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.Deductible = &deductible
+	}
+	if v := query.Get("auto_generated"); v != "" {
+		autoGenerated, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "auto_generated must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.AutoGenerated = &autoGenerated
+	}
+	if filter.Tags, err = parseTags(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	includes, err := parseIncludes(query, includeExpectedExpense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := h.repo.ForUser(requestUserID(r))
+	expenses, err := repo.List(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if includes[includeExpectedExpense] {
+		if err := repo.IncludeExpectedExpenses(expenses); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	total := len(expenses)
+	if filter.Limit > 0 || filter.Offset > 0 {
+		if total, err = repo.Count(filter); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
 	if expenses == nil {
 		expenses = []models.ActualExpense{}
@@ -64,13 +135,15 @@ func (h *ActualExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	response := ActualExpenseListResponse{
 		Expenses: expenses,
-		Total:    len(expenses),
+		Page:     newPage(w, r, len(expenses), total, filter.Limit, filter.Offset),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// Create handles POST /api/actual-expenses
+// With ?preview_budget=true nothing is saved; the budget impact is returned instead
 func (h *ActualExpenseHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateActualExpenseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -78,13 +151,30 @@ func (h *ActualExpenseHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := plugins.ProcessExpense(r.Context(), &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err := req.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	expense, err := h.repo.Create(&req)
+	if isPreviewBudget(r) {
+		h.respondBudgetPreview(w, r, []models.CreateActualExpenseRequest{req})
+		return
+	}
+
+	expense, err := h.repo.ForUser(requestUserID(r)).Create(&req)
 	if err != nil {
+		if errors.Is(err, models.ErrExpectedExpenseNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, models.ErrMonthClosed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -94,6 +184,153 @@ func (h *ActualExpenseHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(expense)
 }
 
+// CreateBulk handles POST /api/actual-expenses/bulk
+// All expenses are created in one transaction. Supports ?preview_budget=true like Create.
+func (h *ActualExpenseHandler) CreateBulk(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkCreateActualExpenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := plugins.ProcessExpenses(r.Context(), req.Expenses); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isPreviewBudget(r) {
+		h.respondBudgetPreview(w, r, req.Expenses)
+		return
+	}
+
+	expenses, err := h.repo.ForUser(requestUserID(r)).CreateBulk(req.Expenses)
+	if err != nil {
+		if errors.Is(err, models.ErrExpectedExpenseNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, models.ErrMonthClosed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ActualExpenseListResponse{
+		Expenses: expenses,
+		Page:     Page{Count: len(expenses), Total: len(expenses)},
+	})
+}
+
+// SavedReceipt is the outcome of saving one receipt: its expenses under the
+// receipt number they got, or why it was not saved
+type SavedReceipt struct {
+	ReceiptNumber int64                  `json:"receipt_number,omitempty"`
+	Expenses      []models.ActualExpense `json:"expenses,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+}
+
+type SaveReceiptsResponse struct {
+	Receipts []SavedReceipt `json:"receipts"`
+	Saved    int            `json:"saved"`
+	Failed   int            `json:"failed"`
+}
+
+// SaveReceipts handles POST /api/actual-expenses/receipts
+// Saves the receipts of a processed document, each in its own transaction
+// and, unless it has one, under its own receipt number, so a receipt that
+// fails does not hold back the others. Responds 201 when every receipt was
+// saved, 207 when some were and 400 when none were.
+func (h *ActualExpenseHandler) SaveReceipts(w http.ResponseWriter, r *http.Request) {
+	var req models.SaveReceiptsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := h.repo.ForUser(requestUserID(r))
+	response := SaveReceiptsResponse{Receipts: make([]SavedReceipt, 0, len(req.Receipts))}
+	for i := range req.Receipts {
+		saved := h.saveReceipt(r, repo, &req.Receipts[i])
+		if saved.Error != "" {
+			response.Failed++
+		} else {
+			response.Saved++
+		}
+		response.Receipts = append(response.Receipts, saved)
+	}
+
+	status := http.StatusCreated
+	switch {
+	case response.Saved == 0:
+		status = http.StatusBadRequest
+	case response.Failed > 0:
+		status = http.StatusMultiStatus
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// saveReceipt validates and saves the expenses of one receipt
+func (h *ActualExpenseHandler) saveReceipt(
+	r *http.Request,
+	repo *repository.ActualExpenseRepository,
+	receipt *models.BulkCreateActualExpenseRequest,
+) SavedReceipt {
+	if err := plugins.ProcessExpenses(r.Context(), receipt.Expenses); err != nil {
+		return SavedReceipt{Error: err.Error()}
+	}
+	if err := receipt.Validate(); err != nil {
+		return SavedReceipt{Error: err.Error()}
+	}
+	expenses, err := repo.CreateReceipt(receipt.Expenses)
+	if err != nil {
+		return SavedReceipt{Error: err.Error()}
+	}
+	return SavedReceipt{ReceiptNumber: expenses[0].ReceiptNumber, Expenses: expenses}
+}
+
+// respondBudgetPreview writes the budget impact of the pending expenses without saving them
+func (h *ActualExpenseHandler) respondBudgetPreview(
+	w http.ResponseWriter,
+	r *http.Request,
+	reqs []models.CreateActualExpenseRequest,
+) {
+	userID := requestUserID(r)
+	impacts, err := computeBudgetImpacts(h.budgetRepo.ForUser(userID), h.repo.ForUser(userID), reqs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BudgetImpactPreviewResponse{
+		Preview:      true,
+		ExpenseCount: len(reqs),
+		Impacts:      impacts,
+	})
+}
+
+// isPreviewBudget reports whether the request asks for a budget impact preview
+func isPreviewBudget(r *http.Request) bool {
+	preview, _ := strconv.ParseBool(r.URL.Query().Get("preview_budget"))
+	return preview
+}
+
+// Get handles GET /api/actual-expenses/{id}
+// Supports include=expected_expense like List.
 func (h *ActualExpenseHandler) Get(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -101,8 +338,14 @@ func (h *ActualExpenseHandler) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
 		return
 	}
+	includes, err := parseIncludes(r.URL.Query(), includeExpectedExpense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	expense, err := h.repo.GetByID(id)
+	repo := h.repo.ForUser(requestUserID(r))
+	expense, err := repo.GetByID(id)
 	if err != nil {
 		if err == models.ErrExpenseNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -111,6 +354,14 @@ func (h *ActualExpenseHandler) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if includes[includeExpectedExpense] {
+		expenses := []models.ActualExpense{*expense}
+		if err := repo.IncludeExpectedExpenses(expenses); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		expense = &expenses[0]
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(expense)
@@ -135,12 +386,20 @@ func (h *ActualExpenseHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expense, err := h.repo.Update(id, &req)
+	expense, err := h.repo.ForUser(requestUserID(r)).Update(id, &req)
 	if err != nil {
 		if err == models.ErrExpenseNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, models.ErrExpectedExpenseNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, models.ErrMonthClosed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -157,11 +416,15 @@ func (h *ActualExpenseHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
+	if err := h.repo.ForUser(requestUserID(r)).Delete(id); err != nil {
 		if err == models.ErrExpenseNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, models.ErrMonthClosed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -169,6 +432,58 @@ func (h *ActualExpenseHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// PendingApprovals handles GET /api/actual-expenses/approvals
+// Lists the expenses of other users held by the approval rule that the
+// signed-in user may approve, oldest first.
+func (h *ActualExpenseHandler) PendingApprovals(w http.ResponseWriter, r *http.Request) {
+	expenses, err := h.repo.ForUser(requestUserID(r)).PendingApprovals()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expenses)
+}
+
+// Approve handles POST /api/actual-expenses/{id}/approve
+// Counts an expense held by the approval rule in totals. The owner's
+// allowance parent or an admin approves it; owners cannot approve their own.
+func (h *ActualExpenseHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := requestUserID(r)
+	ownerID, err := h.repo.ForUser(userID).HouseholdOwner(id)
+	var expense *models.ActualExpense
+	if err == nil {
+		expense, err = h.repo.ForUser(ownerID).Approve(id, userID)
+	}
+	if err != nil {
+		if err == models.ErrExpenseNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repository.ErrSelfApproval) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, repository.ErrNotPendingApproval) || errors.Is(err, models.ErrMonthClosed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expense)
+}
+
 func (h *ActualExpenseHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	monthStr := query.Get("month")
@@ -189,7 +504,7 @@ func (h *ActualExpenseHandler) GetSummary(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	summary, err := h.repo.GetMonthlySummary(month, year)
+	summary, err := h.reportRepo.ForUser(requestUserID(r)).GetMonthlySummary(month, year)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -200,7 +515,7 @@ func (h *ActualExpenseHandler) GetSummary(w http.ResponseWriter, r *http.Request
 }
 
 func (h *ActualExpenseHandler) GetNextReceiptNumber(w http.ResponseWriter, r *http.Request) {
-	nextNumber, err := h.repo.GetNextReceiptNumber()
+	nextNumber, err := h.repo.ForUser(requestUserID(r)).GetNextReceiptNumber()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,978 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTestActualExpenseMux creates a router with the actual expense handler for testing
+func createTestActualExpenseMux(handler *ActualExpenseHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/actual-expenses", handler.List)
+	mux.HandleFunc("POST /api/actual-expenses", handler.Create)
+	mux.HandleFunc("POST /api/actual-expenses/bulk", handler.CreateBulk)
+	mux.HandleFunc("POST /api/actual-expenses/receipts", handler.SaveReceipts)
+	mux.HandleFunc("GET /api/actual-expenses/summary", handler.GetSummary)
+	mux.HandleFunc("GET /api/actual-expenses/{id}", handler.Get)
+	mux.HandleFunc("PUT /api/actual-expenses/{id}", handler.Update)
+	mux.HandleFunc("GET /api/actual-expenses/approvals", handler.PendingApprovals)
+	mux.HandleFunc("POST /api/actual-expenses/{id}/approve", handler.Approve)
+	return mux
+}
+
+// setupActualExpenseTest wires repositories and a mux against a fresh database
+func setupActualExpenseTest(
+	t *testing.T,
+) (*repository.DB, *repository.BudgetRepository, *repository.ActualExpenseRepository, *http.ServeMux) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	budgetRepo := repository.NewBudgetRepository(db)
+	actualRepo := repository.NewActualExpenseRepository(db)
+	mux := createTestActualExpenseMux(NewActualExpenseHandler(actualRepo, budgetRepo, nil))
+
+	return db, budgetRepo, actualRepo, mux
+}
+
+func testReceiptDate() *time.Time {
+	date := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+func TestActualExpenseCreate_PreviewBudget(t *testing.T) {
+	db, budgetRepo, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	if _, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: 6, Year: 2024, Amount: 100, NotificationThreshold: 0.8,
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 70,
+		ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+	}); err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+
+	reqBody := models.CreateActualExpenseRequest{
+		ItemName: "Eggs", Source: "Costco", ActualAmount: 35,
+		ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(
+		"POST",
+		"/api/actual-expenses?preview_budget=true",
+		bytes.NewReader(body),
+	)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var preview BudgetImpactPreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if !preview.Preview || len(preview.Impacts) != 1 {
+		t.Fatalf("Expected one preview impact, got %+v", preview)
+	}
+
+	impact := preview.Impacts[0]
+	if impact.Before.PercentageUsed != 70 || impact.After.PercentageUsed != 105 {
+		t.Errorf(
+			"Expected 70%% -> 105%%, got %.1f%% -> %.1f%%",
+			impact.Before.PercentageUsed,
+			impact.After.PercentageUsed,
+		)
+	}
+	if impact.After.Status != BudgetStatusOver {
+		t.Errorf("Expected status over, got %s", impact.After.Status)
+	}
+	if len(impact.ThresholdsCrossed) != 3 {
+		t.Errorf("Expected 3 thresholds crossed, got %+v", impact.ThresholdsCrossed)
+	}
+
+	// Preview must not persist anything
+	expenses, err := actualRepo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to list expenses: %v", err)
+	}
+	if len(expenses) != 1 {
+		t.Errorf("Expected preview to leave 1 expense, got %d", len(expenses))
+	}
+}
+
+func TestActualExpenseCreate_PreviewWithoutBudget(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	reqBody := models.CreateActualExpenseRequest{
+		ItemName: "Eggs", Source: "Costco", ActualAmount: 35,
+		ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(
+		"POST",
+		"/api/actual-expenses?preview_budget=true",
+		bytes.NewReader(body),
+	)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var preview BudgetImpactPreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	impact := preview.Impacts[0]
+	if impact.Budget != nil {
+		t.Errorf("Expected no budget, got %+v", impact.Budget)
+	}
+	if impact.After.TotalSpent != 35 || impact.After.Status != BudgetStatusSafe {
+		t.Errorf("Expected safe status with 35 spent, got %+v", impact.After)
+	}
+}
+
+func TestActualExpenseCreateBulk_Valid(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	reqBody := models.BulkCreateActualExpenseRequest{
+		Expenses: []models.CreateActualExpenseRequest{
+			{ItemName: "Milk", Source: "Publix", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly},
+			{ItemName: "Tax", Source: "Publix", ActualAmount: 0.3, ExpenseType: models.ExpenseTypeTax},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/actual-expenses/bulk", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var response ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 2 {
+		t.Errorf("Expected 2 created expenses, got %d", response.Total)
+	}
+
+	expenses, _ := actualRepo.GetAll()
+	if len(expenses) != 2 {
+		t.Errorf("Expected 2 persisted expenses, got %d", len(expenses))
+	}
+}
+
+func TestActualExpenseCreateBulk_InvalidRowRejectsAll(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	reqBody := models.BulkCreateActualExpenseRequest{
+		Expenses: []models.CreateActualExpenseRequest{
+			{ItemName: "Milk", Source: "Publix", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly},
+			{ItemName: "", Source: "Publix", ActualAmount: 1, ExpenseType: models.ExpenseTypeWeekly},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/actual-expenses/bulk", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	expenses, _ := actualRepo.GetAll()
+	if len(expenses) != 0 {
+		t.Errorf("Expected no persisted expenses, got %d", len(expenses))
+	}
+}
+
+func TestActualExpenseSaveReceipts(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	save := func(body string) (*httptest.ResponseRecorder, SaveReceiptsResponse) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/actual-expenses/receipts", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var response SaveReceiptsResponse
+		json.NewDecoder(bytes.NewReader(rec.Body.Bytes())).Decode(&response)
+		return rec, response
+	}
+
+	rec, response := save(`{"receipts":[
+		{"expenses":[{"item_name":"Milk","source":"Publix","actual_amount":4,"expense_type":"WEEKLY","line_no":1},
+			{"item_name":"Tax","source":"Publix","actual_amount":0.3,"expense_type":"TAX","line_no":2}]},
+		{"expenses":[{"item_name":"Towel","source":"Target","actual_amount":10,"expense_type":"MISC","line_no":1}]}
+	]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if response.Saved != 2 || len(response.Receipts) != 2 {
+		t.Fatalf("Expected both receipts saved, got %+v", response)
+	}
+	first, second := response.Receipts[0], response.Receipts[1]
+	if first.ReceiptNumber == 0 || second.ReceiptNumber == first.ReceiptNumber {
+		t.Errorf("Expected a receipt number per receipt, got %d and %d", first.ReceiptNumber, second.ReceiptNumber)
+	}
+	if len(first.Expenses) != 2 || first.Expenses[1].ReceiptNumber != first.ReceiptNumber {
+		t.Errorf("Expected the items of a receipt to share its number, got %+v", first.Expenses)
+	}
+
+	// An invalid receipt does not hold back the others
+	rec, response = save(`{"receipts":[
+		{"expenses":[{"item_name":"","source":"Publix","actual_amount":4,"expense_type":"WEEKLY"}]},
+		{"expenses":[{"item_name":"Bread","source":"Kroger","actual_amount":3,"expense_type":"WEEKLY"}]}
+	]}`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusMultiStatus, rec.Code, rec.Body.String())
+	}
+	if response.Saved != 1 || response.Failed != 1 || response.Receipts[0].Error == "" {
+		t.Errorf("Expected the first receipt to fail alone, got %+v", response)
+	}
+
+	rec, _ = save(`{"receipts":[{"expenses":[]}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d when nothing was saved, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec, _ = save(`{"receipts":[]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without receipts, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	expenses, _ := actualRepo.GetAll()
+	if len(expenses) != 4 {
+		t.Errorf("Expected 4 persisted expenses, got %d", len(expenses))
+	}
+}
+
+func TestActualExpenseCreateBulk_Empty(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	req := httptest.NewRequest(
+		"POST",
+		"/api/actual-expenses/bulk",
+		bytes.NewReader([]byte(`{"expenses":[]}`)),
+	)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestActualExpenseList_ReceiptInLineOrder(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	lineNo := func(n int) *int { return &n }
+	reqBody := models.BulkCreateActualExpenseRequest{
+		Expenses: []models.CreateActualExpenseRequest{
+			{ItemName: "Eggs", Source: "Costco", ActualAmount: 15, ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(), ReceiptNumber: 7, LineNo: lineNo(2)},
+			{ItemName: "Tax", Source: "Costco", ActualAmount: 1, ExpenseType: models.ExpenseTypeTax, ReceiptDate: testReceiptDate(), ReceiptNumber: 7, LineNo: lineNo(3)},
+			{ItemName: "Milk", Source: "Costco", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(), ReceiptNumber: 7, LineNo: lineNo(1)},
+			{ItemName: "Beef", Source: "H-Mart", ActualAmount: 30, ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(), ReceiptNumber: 8, LineNo: lineNo(1)},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses/bulk", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	// A line added to the receipt later still sorts by its line number
+	body, _ = json.Marshal(models.CreateActualExpenseRequest{
+		ItemName: "Bags", Source: "Costco", ActualAmount: 0.1, ExpenseType: models.ExpenseTypeMisc,
+		ReceiptDate: testReceiptDate(), ReceiptNumber: 7, LineNo: lineNo(4),
+	})
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if _, err := db.Exec(`UPDATE actual_expenses SET created_at = datetime('now', '+1 hour') WHERE item_name = 'Bags'`); err != nil {
+		t.Fatalf("Failed to age expense: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses?receipt_number=7", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var response ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	expected := []string{"Milk", "Eggs", "Tax", "Bags"}
+	if len(response.Expenses) != len(expected) {
+		t.Fatalf("Expected %d expenses, got %d", len(expected), len(response.Expenses))
+	}
+	for i, expense := range response.Expenses {
+		if expense.ItemName != expected[i] {
+			t.Errorf("Position %d: expected %s, got %s", i, expected[i], expense.ItemName)
+		}
+		if expense.LineNo == nil || *expense.LineNo != i+1 {
+			t.Errorf("Position %d: expected line_no %d, got %v", i, i+1, expense.LineNo)
+		}
+	}
+}
+
+func TestActualExpenseCreate_InvalidLineNo(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	body := []byte(`{"item_name":"Milk","source":"Publix","actual_amount":4,"expense_type":"weekly","line_no":0}`)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	expenses, _ := actualRepo.GetAll()
+	if len(expenses) != 0 {
+		t.Errorf("Expected no persisted expenses, got %d", len(expenses))
+	}
+}
+
+func TestActualExpenseCreateBulk_SplitAcrossMonths(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	receiptDate := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	reqBody := models.BulkCreateActualExpenseRequest{
+		Expenses: []models.CreateActualExpenseRequest{
+			{ItemName: "Milk", Source: "Costco", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &receiptDate, ReceiptNumber: 7},
+			{ItemName: "Party food", Source: "Costco", ActualAmount: 30, ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &receiptDate, ReceiptNumber: 7, BudgetMonth: 6, BudgetYear: 2024},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses/bulk", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var created ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	split := created.Expenses[1]
+	if split.Month != 6 || split.Year != 2024 || !split.MonthAssigned || !split.ReceiptDate.Equal(receiptDate) {
+		t.Errorf("Expected the split item in 6/2024 with its receipt date kept, got %+v", split)
+	}
+
+	for month, weekly := range map[int]float64{6: 30, 7: 4} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/actual-expenses/summary?month=%d&year=2024", month), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var summary struct {
+			TotalWeekly float64 `json:"total_weekly"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+			t.Fatalf("Failed to decode summary: %v", err)
+		}
+		if summary.TotalWeekly != weekly {
+			t.Errorf("Month %d: expected %.2f weekly, got %.2f", month, weekly, summary.TotalWeekly)
+		}
+	}
+}
+
+func TestActualExpenseCreate_InvalidBudgetMonth(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	for _, split := range []string{
+		`"budget_month":6`,
+		`"budget_year":2024`,
+		`"budget_month":5,"budget_year":2024`,
+		`"budget_month":8,"budget_year":2024`,
+		`"budget_month":6,"budget_year":2023`,
+	} {
+		body := `{"item_name":"Milk","source":"Publix","actual_amount":4,"expense_type":"weekly","receipt_date":"2024-07-01T00:00:00Z",` + split + `}`
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", split, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	expenses, _ := actualRepo.GetAll()
+	if len(expenses) != 0 {
+		t.Errorf("Expected no persisted expenses, got %d", len(expenses))
+	}
+}
+
+func TestActualExpenseList_TypeFilterAnyCase(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	for _, expenseType := range []models.ExpenseType{models.ExpenseTypeWeekly, models.ExpenseTypeMisc} {
+		if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Item", Source: "Store", ActualAmount: 1, ExpenseType: expenseType,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		filter   string
+		expected int
+		status   int
+	}{
+		{"Weekly", 1, http.StatusOK},
+		{"MISC", 1, http.StatusOK},
+		{"all", 2, http.StatusOK},
+		{"daily", 0, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filter, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/actual-expenses?type="+tc.filter, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d", tc.status, rec.Code)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var response ActualExpenseListResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Total != tc.expected {
+				t.Errorf("Expected %d expenses, got %d", tc.expected, response.Total)
+			}
+		})
+	}
+}
+
+func TestActualExpenseList_DateRangeSearchAndPaging(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	for day, name := range []string{"Milk", "Oat Milk", "Bread", "Milk 100%"} {
+		date := time.Date(2024, 6, day+1, 12, 0, 0, 0, time.UTC)
+		if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: name, Source: "Store", ActualAmount: 1,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		total    int
+		returned int
+	}{
+		{"date range inclusive", "from=2024-06-02&to=2024-06-03", 2, 2},
+		{"search", "q=milk", 3, 3},
+		{"search escapes wildcards", "q=100%25", 1, 1},
+		{"search with range", "q=milk&from=2024-06-02", 2, 2},
+		{"paging", "limit=2&offset=1", 4, 2},
+		{"month and year", "month=6&year=2024", 4, 4},
+		{"year", "year=2024", 4, 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/actual-expenses?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+
+			var response ActualExpenseListResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Total != tc.total || len(response.Expenses) != tc.returned {
+				t.Errorf(
+					"Expected total %d with %d returned, got total %d with %d returned",
+					tc.total, tc.returned, response.Total, len(response.Expenses),
+				)
+			}
+		})
+	}
+}
+
+func TestActualExpenseList_InvalidFilters(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	for _, query := range []string{
+		"from=06/01/2024",
+		"from=2024-06-10&to=2024-06-01",
+		"limit=-1",
+		"limit=1000",
+		"offset=abc",
+		"month=june",
+		"month=6",
+		"tags=a,b,c,d,e,f,g,h,i,j,k",
+	} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/actual-expenses?"+query, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+		})
+	}
+}
+
+func TestActualExpenseList_Tags(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	var ids []int64
+	for _, tags := range [][]string{{"Work", "travel", "work "}, {"work"}, {"travel_2024"}, nil} {
+		body, _ := json.Marshal(models.CreateActualExpenseRequest{
+			ItemName: "Taxi", Source: "Cab Co", ActualAmount: 20, ExpenseType: models.ExpenseTypeMisc,
+			ReceiptDate: testReceiptDate(), Tags: tags,
+		})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses", bytes.NewReader(body)))
+		var expense models.ActualExpense
+		if err := json.NewDecoder(rec.Body).Decode(&expense); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("Failed to create expense: %d %v", rec.Code, err)
+		}
+		ids = append(ids, expense.ID)
+	}
+
+	list := func(query string) []models.ActualExpense {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response ActualExpenseListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response.Expenses
+	}
+
+	tagged := list("tags=work,TRAVEL")
+	if len(tagged) != 1 || tagged[0].ID != ids[0] || !slices.Equal(tagged[0].Tags, []string{"work", "travel"}) {
+		t.Errorf("Expected the expense with both tags, normalized, got %+v", tagged)
+	}
+	if work := list("tags=work"); len(work) != 2 {
+		t.Errorf("Expected 2 expenses tagged work, got %d", len(work))
+	}
+	// A tag is matched whole, and _ is not a wildcard
+	if travel := list("tags=travel"); len(travel) != 1 || travel[0].ID != ids[0] {
+		t.Errorf("Expected only the expense tagged travel, got %+v", travel)
+	}
+	if all := list(""); len(all) != 4 {
+		t.Errorf("Expected 4 expenses without a tags filter, got %d", len(all))
+	}
+
+	// An empty list removes the tags
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/actual-expenses/"+itoa(ids[1]),
+		strings.NewReader(`{"tags":[]}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if work := list("tags=work"); len(work) != 1 {
+		t.Errorf("Expected 1 expense tagged work after the update, got %d", len(work))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/actual-expenses/"+itoa(ids[1]),
+		strings.NewReader(`{"tags":["a,b"]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a tag with a comma, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestActualExpenseCreate_UnknownExpectedExpense(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	missing := int64(424242)
+	reqBody := models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 4,
+		ExpenseType: models.ExpenseTypeWeekly, ExpectedExpenseID: &missing,
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/actual-expenses", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestActualExpenseList_PageLinks(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	for i := range 5 {
+		date := time.Date(2024, 6, i+1, 12, 0, 0, 0, time.UTC)
+		if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Milk", Source: "Store", ActualAmount: 1,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "http://budget.test/api/actual-expenses?q=milk&limit=2&offset=2", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	page := func(offset int) string {
+		return fmt.Sprintf("http://budget.test/api/actual-expenses?limit=2&offset=%d&q=milk", offset)
+	}
+	wantLink := fmt.Sprintf(`<%s>; rel="next", <%s>; rel="prev", <%s>; rel="first", <%s>; rel="last"`,
+		page(4), page(0), page(0), page(4))
+	if link := rec.Header().Get("Link"); link != wantLink {
+		t.Errorf("Expected Link header\n%s\ngot\n%s", wantLink, link)
+	}
+
+	var response ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	want := Page{Count: 2, Total: 5, Limit: 2, Offset: 2, Next: page(4), Prev: page(0)}
+	if response.Page != want {
+		t.Errorf("Expected page %+v, got %+v", want, response.Page)
+	}
+
+	// The last page has no next link, and unpaged requests have no links at all
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "http://budget.test/api/actual-expenses?limit=2&offset=4", nil))
+	if link := rec.Header().Get("Link"); strings.Contains(link, `rel="next"`) {
+		t.Errorf("Expected no next link on the last page, got %s", link)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses", nil))
+	if link := rec.Header().Get("Link"); link != "" {
+		t.Errorf("Expected no Link header without paging, got %s", link)
+	}
+}
+
+func TestActualExpenseList_IncludeExpectedExpense(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	expected, err := repository.NewExpectedExpenseRepository(db).Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Milk", Source: "Store", ExpectedAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	var linkedID int64
+	for _, link := range []*int64{&expected.ID, &expected.ID, nil} {
+		expense, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Milk", Source: "Store", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+			ReceiptDate: testReceiptDate(), ExpectedExpenseID: link,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		if link != nil {
+			linkedID = expense.ID
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses?include=expected_expense", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	embedded := 0
+	for _, e := range response.Expenses {
+		if e.ExpectedExpense != nil {
+			embedded++
+			if e.ExpectedExpense.ID != expected.ID || e.ExpectedExpense.ItemName != "Milk" {
+				t.Errorf("Unexpected embedded expected expense %+v", e.ExpectedExpense)
+			}
+		}
+	}
+	if embedded != 2 {
+		t.Errorf("Expected 2 expenses with an embedded expected expense, got %d", embedded)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/actual-expenses/%d?include=expected_expense", linkedID), nil))
+	var expense models.ActualExpense
+	if err := json.NewDecoder(rec.Body).Decode(&expense); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if expense.ExpectedExpense == nil || expense.ExpectedExpense.ID != expected.ID {
+		t.Errorf("Expected the expected expense to be embedded, got %+v", expense.ExpectedExpense)
+	}
+
+	// Without include nothing is embedded, and unknown includes are rejected
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses", nil))
+	if strings.Contains(rec.Body.String(), `"expected_expense":`) {
+		t.Errorf("Expected no embedded records without include, got %s", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses?include=expected_expense,category", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unsupported include, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestActualExpenseApprove_HoldsLargeExpensesOutOfTotals(t *testing.T) {
+	db, _, _, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	if err := repository.NewSettingsRepository(db).SetApprovalRule(&models.ApprovalRule{Amount: 100}); err != nil {
+		t.Fatalf("Failed to set approval rule: %v", err)
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	create := func(amount float64) models.ActualExpense {
+		t.Helper()
+		rec := do("POST", "/api/actual-expenses", fmt.Sprintf(
+			`{"item_name": "Item", "source": "Store", "actual_amount": %g, "expense_type": "misc", "receipt_date": "2024-06-15T00:00:00Z"}`,
+			amount,
+		))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var expense models.ActualExpense
+		if err := json.NewDecoder(rec.Body).Decode(&expense); err != nil {
+			t.Fatalf("Failed to decode expense: %v", err)
+		}
+		return expense
+	}
+	totalMisc := func() float64 {
+		t.Helper()
+		rec := do("GET", "/api/actual-expenses/summary?month=6&year=2024", "")
+		var summary models.ActualExpenseSummary
+		if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+			t.Fatalf("Failed to decode summary: %v", err)
+		}
+		return summary.TotalMisc
+	}
+
+	small := create(100)
+	large := create(180)
+	if small.PendingApproval || !large.PendingApproval {
+		t.Fatalf("Expected only the expense over the rule to be pending, got %+v and %+v", small, large)
+	}
+	if total := totalMisc(); total != 100 {
+		t.Errorf("Expected the pending expense to be left out of totals, got %v", total)
+	}
+
+	rec := do("GET", "/api/actual-expenses?pending_approval=true", "")
+	var pending ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&pending); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(pending.Expenses) != 1 || pending.Expenses[0].ID != large.ID {
+		t.Errorf("Expected only the pending expense, got %+v", pending.Expenses)
+	}
+
+	rec = do("POST", fmt.Sprintf("/api/actual-expenses/%d/approve", large.ID), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var approved models.ActualExpense
+	if err := json.NewDecoder(rec.Body).Decode(&approved); err != nil {
+		t.Fatalf("Failed to decode expense: %v", err)
+	}
+	if approved.PendingApproval || approved.ApprovedAt == nil {
+		t.Errorf("Expected the expense to be approved, got %+v", approved)
+	}
+	if total := totalMisc(); total != 280 {
+		t.Errorf("Expected the approved expense in totals, got %v", total)
+	}
+
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{fmt.Sprintf("/api/actual-expenses/%d/approve", large.ID), http.StatusConflict},
+		{fmt.Sprintf("/api/actual-expenses/%d/approve", small.ID), http.StatusConflict},
+		{"/api/actual-expenses/999/approve", http.StatusNotFound},
+	} {
+		if rec := do("POST", tc.path, ""); rec.Code != tc.code {
+			t.Errorf("POST %s: expected status %d, got %d", tc.path, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestActualExpenseApprove_ByHouseholdMember(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	if err := repository.NewSettingsRepository(db).SetApprovalRule(&models.ApprovalRule{Amount: 100}); err != nil {
+		t.Fatalf("Failed to set approval rule: %v", err)
+	}
+	users := repository.NewUserRepository(db)
+	newUser := func(email string, admin bool) int64 {
+		t.Helper()
+		user, err := users.Create(email, "hash")
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if err := users.SetAdmin(user.ID, admin); err != nil {
+			t.Fatalf("Failed to set role: %v", err)
+		}
+		return user.ID
+	}
+	owner := newUser("owner@example.com", false)
+	admin := newUser("admin@example.com", true)
+	other := newUser("other@example.com", false)
+
+	expense, err := actualRepo.ForUser(owner).Create(&models.CreateActualExpenseRequest{
+		ItemName: "Bike", Source: "Store", ActualAmount: 180,
+		ExpenseType: models.ExpenseTypeMisc, ReceiptDate: testReceiptDate(),
+	})
+	if err != nil || !expense.PendingApproval {
+		t.Fatalf("Expected a pending expense, got %+v, %v", expense, err)
+	}
+
+	do := func(method, path string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	approvals := func(userID int64) []models.ActualExpense {
+		t.Helper()
+		rec := do("GET", "/api/actual-expenses/approvals", userID)
+		var expenses []models.ActualExpense
+		if err := json.NewDecoder(rec.Body).Decode(&expenses); err != nil {
+			t.Fatalf("Failed to decode approvals: %v", err)
+		}
+		return expenses
+	}
+	if pending := approvals(admin); len(pending) != 1 || pending[0].ID != expense.ID {
+		t.Errorf("Expected the admin to see the pending expense, got %+v", pending)
+	}
+	if pending := approvals(owner); len(pending) != 0 {
+		t.Errorf("Expected no approvals for the owner, got %+v", pending)
+	}
+
+	approve := fmt.Sprintf("/api/actual-expenses/%d/approve", expense.ID)
+	// The owner cannot approve their own expense, and other members do not see it
+	if rec := do("POST", approve, owner); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for the owner, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := do("POST", approve, other); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec := do("POST", approve, admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var approved models.ActualExpense
+	if err := json.NewDecoder(rec.Body).Decode(&approved); err != nil {
+		t.Fatalf("Failed to decode expense: %v", err)
+	}
+	if approved.PendingApproval || approved.ApprovedBy == nil || *approved.ApprovedBy != admin {
+		t.Errorf("Expected the expense approved by the admin, got %+v", approved)
+	}
+}
+
+func TestActualExpenseSummary_ReadsReportRepo(t *testing.T) {
+	// Stands in for a replica that has a write the primary database of the
+	// subtest never saw
+	replica := setupTestDB(t)
+	defer replica.Close()
+	if _, err := repository.NewActualExpenseRepository(replica).Create(&models.CreateActualExpenseRequest{
+		ItemName: "Bread", Source: "Bakery", ActualAmount: 4,
+		ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+	}); err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+
+	// A subtest gets its own database
+	t.Run("primary", func(t *testing.T) {
+		db := setupTestDB(t)
+		defer db.Close()
+
+		mux := createTestActualExpenseMux(NewActualExpenseHandler(
+			repository.NewActualExpenseRepository(db),
+			repository.NewBudgetRepository(db),
+			repository.NewActualExpenseRepository(replica),
+		))
+		for _, tc := range []struct {
+			path   string
+			weekly float64
+		}{
+			{"/api/actual-expenses/summary?month=6&year=2024", 4},
+			// Everything else uses the primary
+			{"/api/actual-expenses?month=6&year=2024", 0},
+		} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET %s: expected status %d, got %d", tc.path, http.StatusOK, rec.Code)
+			}
+			var body struct {
+				TotalWeekly float64                `json:"total_weekly"`
+				Expenses    []models.ActualExpense `json:"expenses"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			weekly := body.TotalWeekly
+			for _, e := range body.Expenses {
+				weekly += e.ActualAmount
+			}
+			if weekly != tc.weekly {
+				t.Errorf("GET %s: expected %.2f spent, got %.2f", tc.path, tc.weekly, weekly)
+			}
+		}
+	})
+}
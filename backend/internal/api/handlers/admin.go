@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"budget-tracker/internal/services/scheduler"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// defaultHistoryLimit is the page size of the receipt history list
+const defaultHistoryLimit = 50
+
+// AdminHandler handles maintenance endpoints under /api/admin
+type AdminHandler struct {
+	maintenance    *repository.MaintenanceRepository
+	users          *repository.UserRepository
+	receiptHistory *repository.ReceiptHistoryRepository
+	scheduler      *scheduler.Scheduler
+	settings       *repository.SettingsRepository
+	aiMonitor      *ai.HealthMonitor
+}
+
+// NewAdminHandler creates a new AdminHandler
+// sched is nil when background jobs are disabled; the schedule endpoints then
+// respond 503. aiMonitor is nil when no AI provider is configured; the AI
+// health endpoints then respond 503.
+func NewAdminHandler(
+	maintenance *repository.MaintenanceRepository,
+	users *repository.UserRepository,
+	receiptHistory *repository.ReceiptHistoryRepository,
+	sched *scheduler.Scheduler,
+	settings *repository.SettingsRepository,
+	aiMonitor *ai.HealthMonitor,
+) *AdminHandler {
+	return &AdminHandler{
+		maintenance:    maintenance,
+		users:          users,
+		receiptHistory: receiptHistory,
+		scheduler:      sched,
+		settings:       settings,
+		aiMonitor:      aiMonitor,
+	}
+}
+
+// Repair handles POST /api/admin/repair
+// Recomputes derived expense data and reports what was fixed.
+// Pass ?dry_run=true to report without changing anything.
+func (h *AdminHandler) Repair(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	report, err := h.maintenance.Repair(dryRun)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to repair data")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// Orphans handles GET /api/admin/orphans
+// Lists actual expenses linked to deleted expected expenses; POST /api/admin/repair cleans them up.
+func (h *AdminHandler) Orphans(w http.ResponseWriter, r *http.Request) {
+	report, err := h.maintenance.FindOrphanedReferences()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to find orphaned references")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// Integrity handles GET /api/admin/integrity
+// Runs the read-only data integrity checks and reports every issue found.
+func (h *AdminHandler) Integrity(w http.ResponseWriter, r *http.Request) {
+	report, err := h.maintenance.CheckIntegrity()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check data integrity")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// Migrations handles GET /api/admin/migrations
+// Lists the applied migrations and the ones still pending.
+func (h *AdminHandler) Migrations(w http.ResponseWriter, r *http.Request) {
+	status, err := h.maintenance.MigrationStatus()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to read migration status")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// Stats handles GET /api/admin/stats
+// Reports the database size and the row count of every table.
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.maintenance.Stats()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to read database stats")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
+// Users handles GET /api/admin/users
+// Lists every account, allowance sub-accounts included, with its active sessions.
+func (h *AdminHandler) Users(w http.ResponseWriter, r *http.Request) {
+	users, err := h.users.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, users)
+}
+
+// User handles GET /api/admin/users/{id}
+func (h *AdminHandler) User(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.users.GetByID(id)
+	if err != nil {
+		respondUserError(w, err, "Failed to get user")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, user)
+}
+
+// UpdateUser handles PUT /api/admin/users/{id}
+// Grants or revokes the admin role with {"is_admin": true|false}. Admins
+// cannot revoke their own role, so the last one cannot lock everyone out.
+func (h *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.users.GetByID(id)
+	if err != nil {
+		respondUserError(w, err, "Failed to get user")
+		return
+	}
+	if req.IsAdmin != nil {
+		switch {
+		case *req.IsAdmin && user.ParentID != nil:
+			respondError(w, http.StatusBadRequest, "Allowance sub-accounts cannot be admins")
+			return
+		case !*req.IsAdmin && id == requestUserID(r):
+			respondError(w, http.StatusBadRequest, "You cannot revoke your own admin role")
+			return
+		}
+		if err := h.users.SetAdmin(id, *req.IsAdmin); err != nil {
+			respondUserError(w, err, "Failed to update user")
+			return
+		}
+		user.IsAdmin = *req.IsAdmin
+	}
+
+	respondJSON(w, http.StatusOK, user)
+}
+
+// RevokeUserSessions handles DELETE /api/admin/users/{id}/sessions
+// Signs the user out everywhere: their refresh tokens stop working and their
+// access tokens expire within the token lifetime.
+func (h *AdminHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if _, err := h.users.GetByID(id); err != nil {
+		respondUserError(w, err, "Failed to get user")
+		return
+	}
+
+	revoked, err := h.users.RevokeAllSessions(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+func respondUserError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	respondError(w, http.StatusInternalServerError, message)
+}
+
+// ReceiptHistory handles GET /api/admin/receipts/history
+// Lists receipt processing runs newest first, without the raw model output.
+// Supports limit (default 50) and offset paging.
+func (h *AdminHandler) ReceiptHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := parseOptionalInt(query, "limit")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	offset, err := parseOptionalInt(query, "offset")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := h.receiptHistory.List(limit, offset)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidFilter) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to list receipt processing history")
+		return
+	}
+	if records == nil {
+		records = []repository.ReceiptProcessingRecord{}
+	}
+
+	respondJSON(w, http.StatusOK, records)
+}
+
+// ReceiptHistoryEntry handles GET /api/admin/receipts/history/{id}
+// Returns one processing run including the raw model output, for debugging
+// mis-extractions and turning them into prompt regression tests.
+func (h *AdminHandler) ReceiptHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	record, err := h.receiptHistory.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrProcessingRecordNotFound) {
+			respondError(w, http.StatusNotFound, "Receipt processing record not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to get receipt processing record")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, record)
+}
+
+// Schedules handles GET /api/admin/schedules
+// Lists the background jobs with their cron schedule, next and last run.
+func (h *AdminHandler) Schedules(w http.ResponseWriter, r *http.Request) {
+	if h.scheduler == nil {
+		respondError(w, http.StatusServiceUnavailable, "Background jobs are disabled")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.scheduler.Jobs())
+}
+
+// UpdateSchedules handles PUT /api/admin/schedules
+// Takes a map of job name to cron expression, e.g. {"next-month-budget": "0 6 * * *"}.
+// The new schedules apply immediately and are saved so they survive restarts.
+func (h *AdminHandler) UpdateSchedules(w http.ResponseWriter, r *http.Request) {
+	if h.scheduler == nil {
+		respondError(w, http.StatusServiceUnavailable, "Background jobs are disabled")
+		return
+	}
+
+	var updates map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil || len(updates) == 0 {
+		respondError(w, http.StatusBadRequest, "Invalid request body: expected a map of job name to cron expression")
+		return
+	}
+
+	// Validate everything before changing anything
+	known := make(map[string]bool)
+	for _, job := range h.scheduler.Jobs() {
+		known[job.Name] = true
+	}
+	for name, spec := range updates {
+		if !known[name] {
+			respondError(w, http.StatusNotFound, "Unknown job: "+name)
+			return
+		}
+		if _, err := scheduler.ParseSchedule(spec); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := h.settings.SetSchedules(updates); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save schedules")
+		return
+	}
+	for name, spec := range updates {
+		if _, err := h.scheduler.SetSchedule(name, spec); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to apply schedules")
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, h.scheduler.Jobs())
+}
+
+// AIHealth handles GET /api/admin/ai
+// Returns the last known state of the AI provider: whether it accepts work,
+// the remaining rate limit quota it reported and when it was last checked.
+func (h *AdminHandler) AIHealth(w http.ResponseWriter, r *http.Request) {
+	if h.aiMonitor == nil {
+		respondError(w, http.StatusServiceUnavailable, "AI provider not configured")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.aiMonitor.Health())
+}
+
+// CheckAIHealth handles POST /api/admin/ai/check
+// Probes the AI provider now instead of waiting for the ai-health job. The
+// probe spends a few tokens.
+func (h *AdminHandler) CheckAIHealth(w http.ResponseWriter, r *http.Request) {
+	if h.aiMonitor == nil {
+		respondError(w, http.StatusServiceUnavailable, "AI provider not configured")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.aiMonitor.Check(r.Context()))
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"budget-tracker/internal/services/scheduler"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminRepair_DryRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	actualRepo := repository.NewActualExpenseRepository(db)
+	expense, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 4,
+		ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE actual_expenses SET month = 1 WHERE id = ?`, expense.ID); err != nil {
+		t.Fatalf("Failed to corrupt month: %v", err)
+	}
+
+	handler := NewAdminHandler(repository.NewMaintenanceRepository(db), nil, nil, nil, nil, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/admin/repair", handler.Repair)
+
+	req := httptest.NewRequest("POST", "/api/admin/repair?dry_run=true", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var report repository.RepairReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !report.DryRun || report.MonthYearFixed != 1 {
+		t.Errorf("Expected dry run with 1 month/year fix, got %+v", report)
+	}
+
+	stored, _ := actualRepo.GetByID(expense.ID)
+	if stored.Month != 1 {
+		t.Errorf("Dry run must not change data, month is %d", stored.Month)
+	}
+}
+
+type noopJob struct{}
+
+func (noopJob) Name() string                                 { return "noop" }
+func (noopJob) Run(ctx context.Context, now time.Time) error { return nil }
+
+func TestAdminSchedules(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	sched := scheduler.New(time.Minute)
+	if err := sched.Add(noopJob{}, "@hourly"); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	settingsRepo := repository.NewSettingsRepository(db)
+	handler := NewAdminHandler(nil, nil, nil, sched, settingsRepo, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/schedules", handler.Schedules)
+	mux.HandleFunc("PUT /api/admin/schedules", handler.UpdateSchedules)
+
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"noop": "0 6 * * mon-fri"}`, http.StatusOK},
+		{`{"noop": "0 25 * * *"}`, http.StatusBadRequest},
+		{`{"missing": "@daily"}`, http.StatusNotFound},
+		{`{}`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/admin/schedules", strings.NewReader(tc.body)))
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.body, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/schedules", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var jobs []scheduler.JobStatus
+	if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Schedule != "0 6 * * mon-fri" {
+		t.Errorf("Expected the updated schedule, got %+v", jobs)
+	}
+
+	saved, err := settingsRepo.GetSchedules()
+	if err != nil {
+		t.Fatalf("Failed to load schedules: %v", err)
+	}
+	if saved["noop"] != "0 6 * * mon-fri" {
+		t.Errorf("Expected schedule to be saved, got %v", saved)
+	}
+
+	disabled := http.NewServeMux()
+	disabled.HandleFunc("GET /api/admin/schedules", NewAdminHandler(nil, nil, nil, nil, settingsRepo, nil).Schedules)
+	rec = httptest.NewRecorder()
+	disabled.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/schedules", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d with jobs disabled, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestAdminUsers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	users := repository.NewUserRepository(db)
+	handler := NewAdminHandler(repository.NewMaintenanceRepository(db), users, nil, nil, nil, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/users", handler.Users)
+	mux.HandleFunc("GET /api/admin/users/{id}", handler.User)
+	mux.HandleFunc("PUT /api/admin/users/{id}", handler.UpdateUser)
+	mux.HandleFunc("DELETE /api/admin/users/{id}/sessions", handler.RevokeUserSessions)
+
+	admin, err := users.Create("admin@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	member, err := users.Create("member@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := users.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("Failed to grant admin role: %v", err)
+	}
+	if _, err := users.CreateSession(member.ID, "hash-1", "test", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: admin.ID}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("GET", "/api/admin/users", "")
+	var listed []models.AdminUser
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode users: %v", err)
+	}
+	if len(listed) != 2 || !listed[0].IsAdmin || listed[1].IsAdmin || listed[1].ActiveSessions != 1 {
+		t.Errorf("Unexpected users %+v", listed)
+	}
+
+	memberPath := "/api/admin/users/" + itoa(member.ID)
+	rec = do("PUT", memberPath, `{"is_admin": true}`)
+	var updated models.User
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil || !updated.IsAdmin {
+		t.Errorf("Expected the member to be an admin, got %d: %+v", rec.Code, updated)
+	}
+	if isAdmin, err := users.IsAdmin(member.ID); err != nil || !isAdmin {
+		t.Errorf("Expected the admin role to be stored, got %v, %v", isAdmin, err)
+	}
+
+	// Admins cannot lock themselves out
+	if rec := do("PUT", "/api/admin/users/"+itoa(admin.ID), `{"is_admin": false}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d revoking the own role, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do("GET", "/api/admin/users/999", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown user, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = do("DELETE", memberPath+"/sessions", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"revoked":1`) {
+		t.Errorf("Expected 1 revoked session, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminMigrationsAndStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewAdminHandler(repository.NewMaintenanceRepository(db), nil, nil, nil, nil, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/migrations", handler.Migrations)
+	mux.HandleFunc("GET /api/admin/stats", handler.Stats)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/migrations", nil))
+	var status repository.MigrationStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode migration status: %v", err)
+	}
+	if len(status.Applied) == 0 || len(status.Pending) != 0 ||
+		status.CurrentVersion != status.Applied[len(status.Applied)-1].Version {
+		t.Errorf("Expected every migration to be applied, got %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/stats", nil))
+	var stats repository.DBStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.SizeBytes == 0 || len(stats.Tables) == 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	for _, table := range stats.Tables {
+		if table.Name == "schema_migrations" && table.Rows != int64(len(status.Applied)) {
+			t.Errorf("Expected %d schema_migrations rows, got %d", len(status.Applied), table.Rows)
+		}
+	}
+}
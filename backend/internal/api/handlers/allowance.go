@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// AllowanceHandler handles allowance sub-accounts: parents manage them and
+// issue their tokens, and the sub-accounts read their own allowance
+type AllowanceHandler struct {
+	repo   *repository.AllowanceRepository
+	users  *repository.UserRepository
+	tokens *auth.TokenIssuer
+}
+
+// NewAllowanceHandler creates a new AllowanceHandler
+// Sub-accounts need signed-in users, so without tokens every route responds 404.
+func NewAllowanceHandler(
+	repo *repository.AllowanceRepository,
+	users *repository.UserRepository,
+	tokens *auth.TokenIssuer,
+) *AllowanceHandler {
+	return &AllowanceHandler{repo: repo, users: users, tokens: tokens}
+}
+
+// List handles GET /api/allowances
+// Returns each sub-account with its spending in month/year (default: current month).
+func (h *AllowanceHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.claims(w, r)
+	if !ok {
+		return
+	}
+
+	month, year, err := parseMonthQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statuses, err := h.repo.ForUser(claims.UserID).Statuses(month, year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch allowances")
+		return
+	}
+	if statuses == nil {
+		statuses = []models.AllowanceStatus{}
+	}
+	respondJSON(w, http.StatusOK, statuses)
+}
+
+// Create handles POST /api/allowances
+func (h *AllowanceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.claims(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AllowanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	allowance, err := h.repo.ForUser(claims.UserID).Create(&req)
+	if err != nil {
+		respondAllowanceError(w, err, "Failed to create allowance account")
+		return
+	}
+	respondJSON(w, http.StatusCreated, allowance)
+}
+
+// Update handles PUT /api/allowances/{id}
+func (h *AllowanceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.claims(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid allowance ID")
+		return
+	}
+
+	var req models.UpdateAllowanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	allowance, err := h.repo.ForUser(claims.UserID).Update(id, &req)
+	if err != nil {
+		respondAllowanceError(w, err, "Failed to update allowance account")
+		return
+	}
+	respondJSON(w, http.StatusOK, allowance)
+}
+
+// Delete handles DELETE /api/allowances/{id}
+// The sub-account's sessions and logged expenses are deleted with it.
+func (h *AllowanceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.claims(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid allowance ID")
+		return
+	}
+
+	if err := h.repo.ForUser(claims.UserID).Delete(id); err != nil {
+		respondAllowanceError(w, err, "Failed to delete allowance account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// IssueToken handles POST /api/allowances/{id}/token
+// Starts a session for the sub-account and returns its restricted tokens, to
+// be handed to the device the sub-account uses. The parent can end it with
+// DELETE /api/allowances/{id} or the sub-account with /api/auth/logout.
+func (h *AllowanceHandler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.claims(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid allowance ID")
+		return
+	}
+
+	allowance, err := h.repo.ForUser(claims.UserID).Get(id)
+	if err != nil {
+		respondAllowanceError(w, err, "Failed to issue token")
+		return
+	}
+	user, err := h.users.GetByID(allowance.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+
+	resp, err := startSession(h.users, h.tokens, user, "Allowance: "+allowance.Name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+	respondJSON(w, http.StatusCreated, resp)
+}
+
+// Own handles GET /api/allowance
+// Returns the signed-in sub-account's spending in month/year (default:
+// current month); other users get 404.
+func (h *AllowanceHandler) Own(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.claims(w, r)
+	if !ok {
+		return
+	}
+
+	month, year, err := parseMonthQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status, err := h.repo.ForUser(claims.UserID).Own(month, year)
+	if err != nil {
+		respondAllowanceError(w, err, "Failed to fetch allowance")
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+// claims returns the signed-in user's claims, responding 404 when
+// authentication is disabled
+func (h *AllowanceHandler) claims(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if h.tokens == nil || !ok {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return nil, false
+	}
+	return claims, true
+}
+
+func respondAllowanceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrAllowanceNotFound):
+		respondError(w, http.StatusNotFound, "Allowance account not found")
+	case errors.Is(err, repository.ErrAllowanceExists):
+		respondError(w, http.StatusConflict, "An allowance account with this name already exists")
+	default:
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllowanceHandler_SubAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	users := repository.NewUserRepository(db)
+	expenseRepo := repository.NewActualExpenseRepository(db)
+	handler := NewAllowanceHandler(repository.NewAllowanceRepository(db), users, tokens)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/allowances", handler.List)
+	mux.HandleFunc("POST /api/allowances", handler.Create)
+	mux.HandleFunc("PUT /api/allowances/{id}", handler.Update)
+	mux.HandleFunc("DELETE /api/allowances/{id}", handler.Delete)
+	mux.HandleFunc("POST /api/allowances/{id}/token", handler.IssueToken)
+	mux.HandleFunc("GET /api/allowance", handler.Own)
+
+	parent, err := users.Create("parent@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other, err := users.Create("other@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	do := func(method, path, body string, claims *auth.Claims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithClaims(req.Context(), claims))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	asParent := &auth.Claims{UserID: parent.ID}
+
+	rec := do("POST", "/api/allowances", `{"name": " Sam ", "monthly_amount": 20}`, asParent)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var allowance models.Allowance
+	if err := json.NewDecoder(rec.Body).Decode(&allowance); err != nil {
+		t.Fatalf("Failed to decode allowance: %v", err)
+	}
+	if allowance.Name != "Sam" || allowance.MonthlyAmount != 20 {
+		t.Errorf("Unexpected allowance %+v", allowance)
+	}
+	path := "/api/allowances/" + itoa(allowance.ID)
+
+	// The parent issues the sub-account's restricted tokens
+	rec = do("POST", path+"/token", "", asParent)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var issued models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&issued); err != nil {
+		t.Fatalf("Failed to decode token response: %v", err)
+	}
+	asKid, err := tokens.Verify(issued.Token)
+	if err != nil || asKid.UserID != allowance.ID || !asKid.Restricted() {
+		t.Fatalf("Expected a restricted token for user %d, got %+v, %v", allowance.ID, asKid, err)
+	}
+	if issued.User.ParentID == nil || *issued.User.ParentID != parent.ID || issued.RefreshToken == "" {
+		t.Errorf("Unexpected token response %+v", issued)
+	}
+
+	// The approval rule does not hold sub-account expenses, nobody could approve them
+	if err := repository.NewSettingsRepository(db).SetApprovalRule(&models.ApprovalRule{Amount: 5}); err != nil {
+		t.Fatalf("Failed to set approval rule: %v", err)
+	}
+	expense, err := expenseRepo.ForUser(asKid.UserID).Create(&models.CreateActualExpenseRequest{
+		ItemName: "Comic", Source: "Bookshop", ActualAmount: 7.5, ExpenseType: models.ExpenseTypeMisc,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+	if expense.PendingApproval {
+		t.Errorf("Expected the sub-account expense not to be held, got %+v", expense)
+	}
+
+	rec = do("GET", "/api/allowance", "", asKid)
+	var own models.AllowanceStatus
+	if err := json.NewDecoder(rec.Body).Decode(&own); err != nil {
+		t.Fatalf("Failed to decode allowance status: %v", err)
+	}
+	if rec.Code != http.StatusOK || own.Spent != 7.5 || own.Remaining != 12.5 {
+		t.Errorf("Expected 7.50 spent of 20, got %d %+v", rec.Code, own)
+	}
+
+	// The parent sees the spending in the allowance list and the monthly summary
+	rec = do("GET", "/api/allowances", "", asParent)
+	var statuses []models.AllowanceStatus
+	if err := json.NewDecoder(rec.Body).Decode(&statuses); err != nil {
+		t.Fatalf("Failed to decode allowances: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Spent != 7.5 {
+		t.Errorf("Expected Sam's spending, got %+v", statuses)
+	}
+	now := time.Now()
+	summary, err := expenseRepo.ForUser(parent.ID).GetMonthlySummary(int(now.Month()), now.Year())
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+	if summary.TotalActual != 0 || len(summary.Allowances) != 1 || summary.Allowances[0].Spent != 7.5 {
+		t.Errorf("Expected the allowance spending next to the totals, got %+v", summary)
+	}
+
+	asOther := &auth.Claims{UserID: other.ID}
+	for _, tc := range []struct {
+		method, path, body string
+		claims             *auth.Claims
+		code               int
+	}{
+		{"POST", "/api/allowances", `{"name": "Sam", "monthly_amount": 5}`, asParent, http.StatusConflict},
+		{"POST", "/api/allowances", `{"name": "", "monthly_amount": 5}`, asParent, http.StatusBadRequest},
+		{"POST", "/api/allowances", `{"name": "Alex", "monthly_amount": 0}`, asParent, http.StatusBadRequest},
+		{"GET", "/api/allowances?month=13", "", asParent, http.StatusBadRequest},
+		{"PUT", path, `{"monthly_amount": 25}`, asParent, http.StatusOK},
+		// Only the parent manages the sub-account
+		{"PUT", path, `{"monthly_amount": 100}`, asOther, http.StatusNotFound},
+		{"POST", path + "/token", "", asOther, http.StatusNotFound},
+		{"DELETE", path, "", asOther, http.StatusNotFound},
+		// Users that are not sub-accounts have no allowance of their own
+		{"GET", "/api/allowance", "", asParent, http.StatusNotFound},
+	} {
+		if rec := do(tc.method, tc.path, tc.body, tc.claims); rec.Code != tc.code {
+			t.Errorf("%s %s as user %d: expected status %d, got %d: %s",
+				tc.method, tc.path, tc.claims.UserID, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := do("DELETE", path, "", asParent); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+	if _, err := users.GetByID(allowance.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("Expected the sub-account user to be deleted, got %v", err)
+	}
+	if count, err := expenseRepo.ForUser(allowance.ID).Count(repository.ExpenseFilter{}); err != nil || count != 0 {
+		t.Errorf("Expected the sub-account's expenses to be deleted, got %d, %v", count, err)
+	}
+
+	// Sub-accounts need authentication
+	disabled := NewAllowanceHandler(repository.NewAllowanceRepository(db), users, nil)
+	rec = httptest.NewRecorder()
+	disabled.List(rec, httptest.NewRequest("GET", "/api/allowances", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without authentication, got %d", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"net/http"
+	"strconv"
+)
+
+// defaultAuditLimit is the page size when the request sets no limit; the
+// log only grows, so it is never returned whole
+const defaultAuditLimit = 100
+
+// AuditHandler serves the audit log of budget and expense changes
+type AuditHandler struct {
+	repo *repository.AuditRepository
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(repo *repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+type AuditListResponse struct {
+	Entries []models.AuditEntry `json:"entries"`
+	Page
+}
+
+// List handles GET /api/audit
+// Supports optional filters: entity (budget, expected_expense, actual_expense),
+// entity_id, action (create, update, delete), from/to (YYYY-MM-DD, inclusive)
+// and limit/offset paging. Entries are newest first, 100 per page by default.
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.AuditFilter{
+		Entity: query.Get("entity"),
+		Action: query.Get("action"),
+	}
+
+	if filter.Entity != "" && !models.IsAuditEntity(filter.Entity) {
+		respondError(w, http.StatusBadRequest, "entity must be budget, expected_expense or actual_expense")
+		return
+	}
+	if filter.Action != "" && !models.IsAuditAction(filter.Action) {
+		respondError(w, http.StatusBadRequest, "action must be create, update or delete")
+		return
+	}
+	if v := query.Get("entity_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			respondError(w, http.StatusBadRequest, "entity_id must be a positive integer")
+			return
+		}
+		filter.EntityID = id
+	}
+
+	var err error
+	if filter.From, err = parseOptionalDate(query, "from"); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := parseOptionalDate(query, "to")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if to != nil {
+		if filter.From != nil && to.Before(*filter.From) {
+			respondError(w, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+		// to names a whole day; the repository bound is exclusive
+		end := to.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+
+	if filter.Limit, err = parseOptionalInt(query, "limit"); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Offset, err = parseOptionalInt(query, "offset"); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		respondError(w, http.StatusBadRequest, "limit and offset must not be negative")
+		return
+	}
+	if filter.Limit > repository.MaxListLimit {
+		respondError(w, http.StatusBadRequest, "limit must not exceed "+strconv.Itoa(repository.MaxListLimit))
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+
+	repo := h.repo.ForUser(requestUserID(r))
+	entries, err := repo.List(filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+	total, err := repo.Count(filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count audit log")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AuditListResponse{
+		Entries: entries,
+		Page:    newPage(w, r, len(entries), total, filter.Limit, filter.Offset),
+	})
+}
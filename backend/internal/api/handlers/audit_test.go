@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuditHandler_List(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	budgetRepo := repository.NewBudgetRepository(db)
+	expectedRepo := repository.NewExpectedExpenseRepository(db)
+	actualRepo := repository.NewActualExpenseRepository(db)
+	handler := NewAuditHandler(repository.NewAuditRepository(db))
+
+	budget, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{Month: 3, Year: 2026, Amount: 1000})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	amount := 1200.0
+	if _, err := budgetRepo.Update(budget.ID, &models.UpdateBudgetLimitRequest{Amount: &amount}); err != nil {
+		t.Fatalf("Failed to update budget: %v", err)
+	}
+	if err := budgetRepo.Delete(budget.ID); err != nil {
+		t.Fatalf("Failed to delete budget: %v", err)
+	}
+
+	expected, err := expectedRepo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Rent", Source: "Landlord", ExpectedAmount: 900, ExpenseType: models.ExpenseTypeMonthly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	actual, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Rent", Source: "Landlord", ActualAmount: 900, ExpenseType: models.ExpenseTypeMonthly,
+		ExpectedExpenseID: &expected.ID, ReceiptDate: testReceiptDate(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create actual expense: %v", err)
+	}
+	// Deleting the expected expense unlinks the actual one, which is audited too
+	if err := expectedRepo.Delete(expected.ID); err != nil {
+		t.Fatalf("Failed to delete expected expense: %v", err)
+	}
+
+	// Another user's changes stay out of the shared workspace's log
+	if _, err := budgetRepo.ForUser(2).Create(&models.CreateBudgetLimitRequest{Month: 3, Year: 2026, Amount: 50}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	list := func(query string, userID int64) AuditListResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/audit"+query, nil)
+		if userID != 0 {
+			req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		}
+		rec := httptest.NewRecorder()
+		handler.List(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", query, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response AuditListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	all := list("", 0)
+	if all.Total != 7 || all.Limit != defaultAuditLimit {
+		t.Fatalf("Expected 7 entries in one default page, got total %d, limit %d: %+v", all.Total, all.Limit, all.Entries)
+	}
+	// Newest first
+	if last := all.Entries[0]; last.Entity != models.AuditEntityExpectedExpense || last.Action != models.AuditDelete ||
+		last.After != nil {
+		t.Errorf("Expected the expected expense delete first, got %+v", last)
+	}
+
+	budgetLog := list("?entity=budget", 0)
+	if budgetLog.Total != 3 {
+		t.Fatalf("Expected 3 budget entries, got %+v", budgetLog.Entries)
+	}
+	update := budgetLog.Entries[1]
+	var before, after models.BudgetLimit
+	if err := json.Unmarshal(update.Before, &before); err != nil {
+		t.Fatalf("Failed to decode before: %v", err)
+	}
+	if err := json.Unmarshal(update.After, &after); err != nil {
+		t.Fatalf("Failed to decode after: %v", err)
+	}
+	if update.Action != models.AuditUpdate || before.Amount != 1000 || after.Amount != 1200 {
+		t.Errorf("Expected the budget update from 1000 to 1200, got %+v", update)
+	}
+
+	actualLog := list("?entity=actual_expense&entity_id="+itoa(actual.ID)+"&action=update", 0)
+	if actualLog.Total != 1 {
+		t.Fatalf("Expected the unlink of the actual expense, got %+v", actualLog.Entries)
+	}
+	var unlinked models.ActualExpense
+	if err := json.Unmarshal(actualLog.Entries[0].After, &unlinked); err != nil {
+		t.Fatalf("Failed to decode after: %v", err)
+	}
+	if unlinked.ExpectedExpenseID != nil {
+		t.Errorf("Expected the snapshot after unlinking, got %+v", unlinked)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if got := list("?from="+today+"&to="+today, 0).Total; got != 7 {
+		t.Errorf("Expected today's range to include every entry, got %d", got)
+	}
+	if got := list("?to=2000-01-01", 0).Total; got != 0 {
+		t.Errorf("Expected no entries before 2000, got %d", got)
+	}
+	if got := list("?limit=2&offset=2", 0); got.Count != 2 || got.Total != 7 || got.Next == "" {
+		t.Errorf("Expected a middle page of 2, got %+v", got.Page)
+	}
+	if got := list("", 2); got.Total != 1 || got.Entries[0].UserID != 2 {
+		t.Errorf("Expected only user 2's change, got %+v", got.Entries)
+	}
+
+	for _, query := range []string{
+		"?entity=receipt", "?action=approve", "?entity_id=abc", "?from=03-01-2026",
+		"?from=2026-03-02&to=2026-03-01", "?limit=-1",
+	} {
+		rec := httptest.NewRecorder()
+		handler.List(rec, httptest.NewRequest("GET", "/api/audit"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
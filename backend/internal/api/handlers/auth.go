@@ -0,0 +1,734 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// googleStateCookie holds the OAuth state between the Google redirect and its callback
+const googleStateCookie = "google_oauth_state"
+
+// AuthHandler handles user registration and sign-in
+type AuthHandler struct {
+	users             *repository.UserRepository
+	tokens            *auth.TokenIssuer
+	google            *auth.GoogleProvider
+	mailer            *auth.AccountMailer
+	allowRegistration atomic.Bool
+}
+
+// NewAuthHandler creates a new AuthHandler
+// tokens is nil when authentication is disabled; the auth endpoints then
+// respond 404. google is nil when sign-in with Google is not configured, and
+// mailer when no mail server is; email verification and password reset then
+// respond 404. Without allowRegistration only the first account can register.
+func NewAuthHandler(
+	users *repository.UserRepository,
+	tokens *auth.TokenIssuer,
+	google *auth.GoogleProvider,
+	mailer *auth.AccountMailer,
+	allowRegistration bool,
+) *AuthHandler {
+	h := &AuthHandler{
+		users:  users,
+		tokens: tokens,
+		google: google,
+		mailer: mailer,
+	}
+	h.allowRegistration.Store(allowRegistration)
+	return h
+}
+
+// SetAllowRegistration opens or closes registration to everyone while the
+// server runs, e.g. when the configuration is reloaded
+func (h *AuthHandler) SetAllowRegistration(allow bool) {
+	h.allowRegistration.Store(allow)
+}
+
+// Register handles POST /api/auth/register
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	var creds models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := creds.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if open, err := h.registrationOpen(); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to register")
+		return
+	} else if !open {
+		respondError(w, http.StatusForbidden, "Registration is closed")
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(creds.Password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to register")
+		return
+	}
+	user, err := h.users.Create(creds.Email, passwordHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			respondError(w, http.StatusConflict, "A user with this email already exists")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to register")
+		return
+	}
+
+	// The account works before the address is confirmed
+	if h.mailer != nil {
+		h.mailTokenInBackground(user, models.TokenPurposeVerifyEmail)
+	}
+	h.respondToken(w, r, http.StatusCreated, user)
+}
+
+// Login handles POST /api/auth/login
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	var creds models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// Do not reveal which part of the credentials was wrong
+	if err := creds.Validate(); err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	user, passwordHash, err := h.users.GetByEmail(creds.Email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		respondError(w, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+	// Accounts created by signing in with Google have no password
+	passwordless := user != nil && passwordHash == ""
+	if user == nil || passwordless {
+		// Hash anyway so unknown emails take as long as wrong passwords
+		passwordHash = dummyPasswordHash()
+	}
+
+	err = auth.CheckPassword(passwordHash, creds.Password)
+	if user == nil || passwordless || errors.Is(err, auth.ErrPasswordMismatch) {
+		respondError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+	if err != nil {
+		log.Printf("[Auth] Failed to check password of user %d: %v", user.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	h.respondToken(w, r, http.StatusOK, user)
+}
+
+// GoogleLogin handles GET /api/auth/google
+// Redirects the browser to Google's consent page; Google sends it back to
+// GoogleCallback.
+func (h *AuthHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil || h.google == nil {
+		respondError(w, http.StatusNotFound, "Sign-in with Google is not configured")
+		return
+	}
+
+	state, err := auth.NewOAuthState()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to start sign-in")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     googleStateCookie,
+		Value:    state,
+		Path:     "/api/auth/google",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.google.AuthCodeURL(state), http.StatusFound)
+}
+
+// GoogleCallback handles GET /api/auth/google/callback
+// Signs in the user with the Google account's email, creating the account
+// when registration is open, and issues the same token as Login. With a
+// success URL configured the browser is redirected there with the token in
+// the URL fragment; otherwise the token is returned as JSON.
+func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil || h.google == nil {
+		respondError(w, http.StatusNotFound, "Sign-in with Google is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	cookie, err := r.Cookie(googleStateCookie)
+	if err != nil || query.Get("state") == "" ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(query.Get("state"))) != 1 {
+		respondError(w, http.StatusBadRequest, "Invalid or expired sign-in state, please try again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: googleStateCookie, Path: "/api/auth/google", MaxAge: -1})
+
+	if query.Get("error") != "" || query.Get("code") == "" {
+		respondError(w, http.StatusUnauthorized, "Sign-in with Google was cancelled")
+		return
+	}
+
+	email, err := h.google.Exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		log.Printf("[Auth] Google sign-in failed: %v", err)
+		respondError(w, http.StatusUnauthorized, "Sign-in with Google failed")
+		return
+	}
+
+	user, passwordHash, err := h.users.GetByEmail(email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		user, err = h.registerGoogleUser(w, email)
+		if user == nil && err == nil {
+			return
+		}
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+	// Google only returns verified emails
+	if !user.EmailVerified {
+		if err := h.claimUnverifiedAccount(user.ID, passwordHash); err != nil {
+			log.Printf("[Auth] Failed to link Google account to user %d: %v", user.ID, err)
+			respondError(w, http.StatusInternalServerError, "Failed to sign in")
+			return
+		}
+		user.EmailVerified = true
+	}
+
+	successURL := h.google.SuccessURL()
+	if successURL == "" {
+		h.respondToken(w, r, http.StatusOK, user)
+		return
+	}
+	resp, err := h.signIn(r, user)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+	fragment := url.Values{
+		"token":              {resp.Token},
+		"expires_at":         {strconv.FormatInt(resp.ExpiresAt.Unix(), 10)},
+		"refresh_token":      {resp.RefreshToken},
+		"refresh_expires_at": {strconv.FormatInt(resp.RefreshExpiresAt.Unix(), 10)},
+	}
+	http.Redirect(w, r, successURL+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// claimUnverifiedAccount hands the account userID, whose email was never
+// verified, to the owner of the email who just signed in with Google.
+// Anyone could have registered the address with a password, so the password
+// is removed and its sessions are revoked before the email is marked
+// verified. The password can be set again with a password reset.
+func (h *AuthHandler) claimUnverifiedAccount(userID int64, passwordHash string) error {
+	if passwordHash != "" {
+		if err := h.users.SetPassword(userID, ""); err != nil {
+			return err
+		}
+		if _, err := h.users.RevokeAllSessions(userID); err != nil {
+			return err
+		}
+	}
+	return h.users.MarkEmailVerified(userID)
+}
+
+// registerGoogleUser creates a passwordless account for email. When
+// registration is closed it responds 403 and returns a nil user and error.
+func (h *AuthHandler) registerGoogleUser(w http.ResponseWriter, email string) (*models.User, error) {
+	open, err := h.registrationOpen()
+	if err != nil {
+		return nil, err
+	}
+	if !open {
+		respondError(w, http.StatusForbidden, "Registration is closed")
+		return nil, nil
+	}
+
+	user, err := h.users.Create(email, "")
+	if errors.Is(err, repository.ErrUserExists) {
+		// Signed in twice at once; the other request created the account
+		user, _, err = h.users.GetByEmail(email)
+	}
+	return user, err
+}
+
+// registrationOpen reports whether a new account may be created: always with
+// allowRegistration, otherwise only while there are no users
+func (h *AuthHandler) registrationOpen() (bool, error) {
+	if h.allowRegistration.Load() {
+		return true, nil
+	}
+	count, err := h.users.Count()
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// Me handles GET /api/auth/me
+// Returns the user the request's token was issued to.
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if h.tokens == nil || !ok {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	user, err := h.users.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			respondError(w, http.StatusUnauthorized, "User no longer exists")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, user)
+}
+
+// Refresh handles POST /api/auth/refresh
+// Exchanges a refresh token for a new access token and refresh token. Each
+// refresh token works once; the one sent is no longer valid afterwards.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+	session, err := h.users.RotateSession(auth.HashRefreshToken(req.RefreshToken), hash, h.tokens.RefreshExpiry())
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	user, err := h.users.GetByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			respondError(w, http.StatusUnauthorized, "User no longer exists")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	token, expiresAt, err := h.tokens.Issue(user, session.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             *user,
+	})
+}
+
+// Logout handles POST /api/auth/logout
+// Revokes the session of the refresh token. Access tokens already issued for
+// it stay valid until they expire.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	// Signing out twice is not an error
+	err := h.users.RevokeSessionByToken(auth.HashRefreshToken(req.RefreshToken))
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		respondError(w, http.StatusInternalServerError, "Failed to sign out")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sessions handles GET /api/auth/sessions
+// Lists the signed-in devices of the user, marking the one making the request.
+func (h *AuthHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if h.tokens == nil || !ok {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	sessions, err := h.users.ListSessions(claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch sessions")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	respondJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /api/auth/sessions/{id}
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if h.tokens == nil || !ok {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	if err := h.users.RevokeSession(claims.UserID, id); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions handles DELETE /api/auth/sessions
+// Signs the user out on every device, including the current one.
+func (h *AuthHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if h.tokens == nil || !ok {
+		respondError(w, http.StatusNotFound, "Authentication is disabled")
+		return
+	}
+
+	revoked, err := h.users.RevokeAllSessions(claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+// SendVerification handles POST /api/auth/verify-email/send
+// Emails the signed-in user a new link to confirm their address; links sent
+// earlier stop working.
+func (h *AuthHandler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if h.tokens == nil || h.mailer == nil || !ok {
+		respondError(w, http.StatusNotFound, "Email verification is not configured")
+		return
+	}
+
+	user, err := h.users.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			respondError(w, http.StatusUnauthorized, "User no longer exists")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+	if user.EmailVerified {
+		respondError(w, http.StatusConflict, "Email address is already verified")
+		return
+	}
+
+	if err := h.mailToken(r.Context(), user, models.TokenPurposeVerifyEmail); err != nil {
+		if errors.Is(err, errMailThrottled) {
+			respondError(w, http.StatusTooManyRequests, "An email was sent recently, please wait before asking again")
+			return
+		}
+		log.Printf("[Auth] Failed to send verification email to user %d: %v", user.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to send email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifyEmail handles POST /api/auth/verify-email
+// Confirms the address with the token from a verification email and returns
+// the user. Each token works once.
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil || h.mailer == nil {
+		respondError(w, http.StatusNotFound, "Email verification is not configured")
+		return
+	}
+
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := h.users.UseUserToken(models.TokenPurposeVerifyEmail, auth.HashRefreshToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			respondError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+	if err := h.users.MarkEmailVerified(userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	user, err := h.users.GetByID(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+	respondJSON(w, http.StatusOK, user)
+}
+
+// ForgotPassword handles POST /api/auth/forgot-password
+// Emails a password reset link to the address. It responds 202 whether or
+// not an account uses the address, and sends in the background so the
+// response time does not tell either.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil || h.mailer == nil {
+		respondError(w, http.StatusNotFound, "Password reset is not configured")
+		return
+	}
+
+	var req models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _, err := h.users.GetByEmail(req.Email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+	// Allowance sub-accounts have no mailbox and sign in with tokens from
+	// their parent
+	if user != nil && user.ParentID == nil {
+		h.mailTokenInBackground(user, models.TokenPurposeResetPassword)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /api/auth/reset-password
+// Sets a new password with the token from a reset email and signs the user
+// out on every device. Receiving the email also confirms the address.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil || h.mailer == nil {
+		respondError(w, http.StatusNotFound, "Password reset is not configured")
+		return
+	}
+
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Hash before using the token so a failure does not waste it
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+	userID, err := h.users.UseUserToken(models.TokenPurposeResetPassword, auth.HashRefreshToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			respondError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	if err := h.users.SetPassword(userID, passwordHash); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+	if _, err := h.users.RevokeAllSessions(userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	if err := h.users.MarkEmailVerified(userID); err != nil {
+		log.Printf("[Auth] Failed to mark email of user %d verified: %v", userID, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mailTokenInterval is the minimum time between two emails of one purpose to
+// the same user
+const mailTokenInterval = time.Minute
+
+// mailTimeout bounds sending an email in the background
+const mailTimeout = time.Minute
+
+var errMailThrottled = errors.New("an email was sent recently")
+
+// mailToken issues a single use token for purpose and emails it to user.
+// Tokens have the form of refresh tokens and are stored hashed the same way.
+func (h *AuthHandler) mailToken(ctx context.Context, user *models.User, purpose string) error {
+	issued, err := h.users.UserTokenIssuedSince(user.ID, purpose, time.Now().Add(-mailTokenInterval))
+	if err != nil {
+		return err
+	}
+	if issued {
+		return errMailThrottled
+	}
+
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	ttl := auth.VerifyEmailTTL
+	if purpose == models.TokenPurposeResetPassword {
+		ttl = auth.PasswordResetTTL
+	}
+	if _, err := h.users.CreateUserToken(user.ID, purpose, hash, time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	if purpose == models.TokenPurposeResetPassword {
+		return h.mailer.SendPasswordReset(ctx, user.Email, token)
+	}
+	return h.mailer.SendVerification(ctx, user.Email, token)
+}
+
+// mailTokenInBackground is mailToken for requests that respond before the
+// email is sent; failures are logged
+func (h *AuthHandler) mailTokenInBackground(user *models.User, purpose string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := h.mailToken(ctx, user, purpose); err != nil && !errors.Is(err, errMailThrottled) {
+			log.Printf("[Auth] Failed to send %s email to user %d: %v", purpose, user.ID, err)
+		}
+	}()
+}
+
+// requestUserID returns the id of the user the request is authenticated as,
+// or 0, the shared workspace, when authentication is disabled
+func requestUserID(r *http.Request) int64 {
+	if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+		return claims.UserID
+	}
+	return 0
+}
+
+func (h *AuthHandler) respondToken(w http.ResponseWriter, r *http.Request, status int, user *models.User) {
+	resp, err := h.signIn(r, user)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+
+	respondJSON(w, status, resp)
+}
+
+// signIn starts a session for user on the requesting device and issues its
+// access and refresh tokens
+func (h *AuthHandler) signIn(r *http.Request, user *models.User) (*models.AuthResponse, error) {
+	return startSession(h.users, h.tokens, user, r.UserAgent())
+}
+
+// startSession creates a session for user described by userAgent and issues
+// its access and refresh tokens
+func startSession(
+	users *repository.UserRepository,
+	tokens *auth.TokenIssuer,
+	user *models.User,
+	userAgent string,
+) (*models.AuthResponse, error) {
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshExpiresAt := tokens.RefreshExpiry()
+	sessionID, err := users.CreateSession(user.ID, hash, userAgent, refreshExpiresAt)
+	if err != nil {
+		log.Printf("[Auth] Failed to create session for user %d: %v", user.ID, err)
+		return nil, err
+	}
+
+	token, expiresAt, err := tokens.Issue(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+		User:             *user,
+	}, nil
+}
+
+// dummyPasswordHash is compared against when an email is unknown
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("not a real password")
+	if err != nil {
+		return ""
+	}
+	return hash
+})
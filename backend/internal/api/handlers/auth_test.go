@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"budget-tracker/internal/services/mail"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthHandler_RegisterAndLogin(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	handler := NewAuthHandler(repository.NewUserRepository(db), tokens, nil, nil, false)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/auth/register", handler.Register)
+	mux.HandleFunc("POST /api/auth/login", handler.Login)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post("/api/auth/register", `{"email": " Me@Example.com ", "password": "long enough"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var registered models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&registered); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if registered.User.Email != "me@example.com" || registered.Token == "" {
+		t.Errorf("Unexpected register response %+v", registered)
+	}
+	if claims, err := tokens.Verify(registered.Token); err != nil || claims.UserID != registered.User.ID {
+		t.Errorf("Expected a valid token for user %d, got %+v, %v", registered.User.ID, claims, err)
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		code       int
+	}{
+		{"/api/auth/register", `{"email": "other@example.com", "password": "long enough"}`, http.StatusForbidden},
+		{"/api/auth/register", `{"email": "not-an-email", "password": "long enough"}`, http.StatusBadRequest},
+		{"/api/auth/register", `{"email": "other@example.com", "password": "short"}`, http.StatusBadRequest},
+		{"/api/auth/login", `{"email": "me@example.com", "password": "long enough"}`, http.StatusOK},
+		{"/api/auth/login", `{"email": "ME@example.com", "password": "long enough"}`, http.StatusOK},
+		{"/api/auth/login", `{"email": "me@example.com", "password": "wrong password"}`, http.StatusUnauthorized},
+		{"/api/auth/login", `{"email": "nobody@example.com", "password": "long enough"}`, http.StatusUnauthorized},
+	} {
+		if rec := post(tc.path, tc.body); rec.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tc.path, tc.body, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	// With registration open, more accounts can be created but emails stay unique
+	handler.SetAllowRegistration(true)
+	if rec := post("/api/auth/register", `{"email": "other@example.com", "password": "long enough"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if rec := post("/api/auth/register", `{"email": "me@example.com", "password": "long enough"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+}
+
+func TestAuthHandler_RefreshAndSessions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	handler := NewAuthHandler(repository.NewUserRepository(db), tokens, nil, nil, false)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/auth/register", handler.Register)
+	mux.HandleFunc("POST /api/auth/login", handler.Login)
+	mux.HandleFunc("POST /api/auth/refresh", handler.Refresh)
+	mux.HandleFunc("POST /api/auth/logout", handler.Logout)
+	mux.HandleFunc("GET /api/auth/sessions", handler.Sessions)
+	mux.HandleFunc("DELETE /api/auth/sessions/{id}", handler.RevokeSession)
+
+	// do sends a request, authenticated with the claims of token when set
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			claims, err := tokens.Verify(token)
+			if err != nil {
+				t.Fatalf("Invalid access token: %v", err)
+			}
+			req = req.WithContext(auth.WithClaims(req.Context(), claims))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	signIn := func(path string) models.AuthResponse {
+		t.Helper()
+		rec := do("POST", path, `{"email": "me@example.com", "password": "long enough"}`, "")
+		var resp models.AuthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.RefreshToken == "" {
+			t.Fatalf("Expected a refresh token from %s, got %d %+v", path, rec.Code, resp)
+		}
+		return resp
+	}
+
+	first := signIn("/api/auth/register")
+	second := signIn("/api/auth/login")
+
+	// Refreshing rotates the refresh token; the old one stops working
+	rec := do("POST", "/api/auth/refresh", `{"refresh_token": "`+first.RefreshToken+`"}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var refreshed models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&refreshed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if refreshed.RefreshToken == first.RefreshToken || refreshed.User.Email != "me@example.com" {
+		t.Errorf("Unexpected refresh response %+v", refreshed)
+	}
+	if rec := do("POST", "/api/auth/refresh", `{"refresh_token": "`+first.RefreshToken+`"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a used refresh token to be rejected, got %d", rec.Code)
+	}
+
+	// Both devices are listed and the current one is marked
+	rec = do("GET", "/api/auth/sessions", "", refreshed.Token)
+	var sessions []models.Session
+	if err := json.NewDecoder(rec.Body).Decode(&sessions); err != nil {
+		t.Fatalf("Failed to decode sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v", sessions)
+	}
+	claims, _ := tokens.Verify(refreshed.Token)
+	var other int64
+	for _, s := range sessions {
+		if s.Current != (s.ID == claims.SessionID) {
+			t.Errorf("Session %d marked current=%v", s.ID, s.Current)
+		}
+		if !s.Current {
+			other = s.ID
+		}
+	}
+
+	// Revoking the other device ends its refresh token
+	if rec := do("DELETE", "/api/auth/sessions/"+strconv.FormatInt(other, 10), "", refreshed.Token); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+	if rec := do("DELETE", "/api/auth/sessions/"+strconv.FormatInt(other, 10), "", refreshed.Token); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d revoking twice, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := do("POST", "/api/auth/refresh", `{"refresh_token": "`+second.RefreshToken+`"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked refresh token to be rejected, got %d", rec.Code)
+	}
+
+	// Signing out does the same for the current device
+	for range 2 {
+		if rec := do("POST", "/api/auth/logout", `{"refresh_token": "`+refreshed.RefreshToken+`"}`, ""); rec.Code != http.StatusNoContent {
+			t.Errorf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+	}
+	if rec := do("POST", "/api/auth/refresh", `{"refresh_token": "`+refreshed.RefreshToken+`"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a signed out refresh token to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestAuthHandler_Disabled(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewAuthHandler(repository.NewUserRepository(db), nil, nil, nil, true)
+	rec := httptest.NewRecorder()
+	handler.Login(rec, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestAuthHandler_Google(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// Fake Google: code "me" signs in as me@example.com, "other" as other@example.com
+	fake := http.NewServeMux()
+	fake.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": r.FormValue("code")})
+	})
+	fake.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		account := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		json.NewEncoder(w).Encode(map[string]any{"email": account + "@example.com", "email_verified": true})
+	})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	google, err := auth.NewGoogleProvider(auth.GoogleConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://budget.test/api/auth/google/callback",
+		AuthURL:      server.URL + "/auth",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/userinfo",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Google provider: %v", err)
+	}
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	users := repository.NewUserRepository(db)
+	handler := NewAuthHandler(users, tokens, google, nil, false)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/auth/google", handler.GoogleLogin)
+	mux.HandleFunc("GET /api/auth/google/callback", handler.GoogleCallback)
+	mux.HandleFunc("POST /api/auth/login", handler.Login)
+
+	// signIn follows the redirect to Google and back with the given code
+	signIn := func(code string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/auth/google", nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("Expected redirect to Google, got %d: %s", rec.Code, rec.Body.String())
+		}
+		location, _ := url.Parse(rec.Header().Get("Location"))
+		state := location.Query().Get("state")
+		cookies := rec.Result().Cookies()
+		if state == "" || len(cookies) != 1 || cookies[0].Value != state {
+			t.Fatalf("Expected the state in the redirect and a cookie, got %s and %v", location, cookies)
+		}
+
+		req := httptest.NewRequest("GET", "/api/auth/google/callback?state="+state+"&code="+code, nil)
+		req.AddCookie(cookies[0])
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := signIn("me")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var signedIn models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&signedIn); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if claims, err := tokens.Verify(signedIn.Token); err != nil || claims.Email != "me@example.com" {
+		t.Errorf("Expected a token for me@example.com, got %+v (%v)", claims, err)
+	}
+
+	// Signing in again uses the same account
+	if rec := signIn("me"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":`+strconv.FormatInt(signedIn.User.ID, 10)) {
+		t.Errorf("Expected to sign in as user %d again, got %d: %s", signedIn.User.ID, rec.Code, rec.Body.String())
+	}
+	// Registration is closed once the first account exists
+	if rec := signIn("other"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for a new account, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	// The callback needs the state from the same browser
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/auth/google/callback?state=forged&code=me", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without the state cookie, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Accounts created with Google cannot sign in with a password
+	login := func(email, password string) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auth/login",
+			strings.NewReader(`{"email": "`+email+`", "password": "`+password+`"}`)))
+		return rec.Code
+	}
+	if code := login("me@example.com", "not a real password"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a password sign-in, got %d", http.StatusUnauthorized, code)
+	}
+
+	// Someone registered other@example.com with a password without verifying
+	// it; once its owner signs in with Google, that password stops working
+	hash, err := auth.HashPassword("squatter password")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	squatted, err := users.Create("other@example.com", hash)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if code := login("other@example.com", "squatter password"); code != http.StatusOK {
+		t.Fatalf("Expected the password to work before linking, got %d", code)
+	}
+	if rec := signIn("other"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if code := login("other@example.com", "squatter password"); code != http.StatusUnauthorized {
+		t.Errorf("Expected the unverified password to be removed, got %d", code)
+	}
+	if sessions, err := users.ListSessions(squatted.ID); err != nil || len(sessions) != 1 {
+		t.Errorf("Expected only the Google session to be left, got %+v (%v)", sessions, err)
+	}
+	if user, _, err := users.GetByEmail("other@example.com"); err != nil || !user.EmailVerified {
+		t.Errorf("Expected the email to be verified, got %+v (%v)", user, err)
+	}
+}
+
+// fakeMailer passes sent messages to the test
+type fakeMailer struct {
+	sent chan mail.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+func TestAuthHandler_EmailVerificationAndPasswordReset(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	mailer := &fakeMailer{sent: make(chan mail.Message, 4)}
+	users := repository.NewUserRepository(db)
+	handler := NewAuthHandler(users, tokens, nil, auth.NewAccountMailer(mailer, "https://budget.example.com/"), false)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/auth/register", handler.Register)
+	mux.HandleFunc("POST /api/auth/login", handler.Login)
+	mux.HandleFunc("POST /api/auth/refresh", handler.Refresh)
+	mux.HandleFunc("POST /api/auth/verify-email/send", handler.SendVerification)
+	mux.HandleFunc("POST /api/auth/verify-email", handler.VerifyEmail)
+	mux.HandleFunc("POST /api/auth/forgot-password", handler.ForgotPassword)
+	mux.HandleFunc("POST /api/auth/reset-password", handler.ResetPassword)
+
+	do := func(path, body string, claims *auth.Claims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if claims != nil {
+			req = req.WithContext(auth.WithClaims(req.Context(), claims))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	// received waits for the next email and returns the token of its link
+	received := func(page string) string {
+		t.Helper()
+		select {
+		case msg := <-mailer.sent:
+			if msg.To != "me@example.com" {
+				t.Errorf("Expected an email to me@example.com, got %q", msg.To)
+			}
+			_, link, ok := strings.Cut(msg.Body, "https://budget.example.com"+page+"?")
+			if !ok {
+				t.Fatalf("Expected a link to %s, got %q", page, msg.Body)
+			}
+			query, err := url.ParseQuery(strings.Fields(link)[0])
+			if err != nil || query.Get("token") == "" {
+				t.Fatalf("Expected a token in %q, got %v", link, err)
+			}
+			return query.Get("token")
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected an email linking to %s", page)
+			return ""
+		}
+	}
+
+	// Registering sends the verification email
+	rec := do("/api/auth/register", `{"email": "me@example.com", "password": "long enough"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var registered models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&registered); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if registered.User.EmailVerified {
+		t.Errorf("Expected a new account to be unverified, got %+v", registered.User)
+	}
+	verifyToken := received("/verify-email")
+
+	me := &auth.Claims{UserID: registered.User.ID}
+	if rec := do("/api/auth/verify-email/send", "", me); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d right after registering, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"token": ""}`, http.StatusBadRequest},
+		{`{"token": "unknown"}`, http.StatusBadRequest},
+		{`{"token": "` + verifyToken + `"}`, http.StatusOK},
+		// Each token works once
+		{`{"token": "` + verifyToken + `"}`, http.StatusBadRequest},
+	} {
+		if rec := do("/api/auth/verify-email", tc.body, nil); rec.Code != tc.code {
+			t.Errorf("Verify %s: expected status %d, got %d: %s", tc.body, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+	if user, err := users.GetByID(registered.User.ID); err != nil || !user.EmailVerified {
+		t.Errorf("Expected the email to be verified, got %+v, %v", user, err)
+	}
+	if rec := do("/api/auth/verify-email/send", "", me); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status %d once verified, got %d", http.StatusConflict, rec.Code)
+	}
+
+	// Unknown addresses get the same response and no email
+	if rec := do("/api/auth/forgot-password", `{"email": "nobody@example.com"}`, nil); rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d for an unknown email, got %d", http.StatusAccepted, rec.Code)
+	}
+	if rec := do("/api/auth/forgot-password", `{"email": "ME@example.com"}`, nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	resetToken := received("/reset-password")
+
+	if rec := do("/api/auth/reset-password", `{"token": "`+resetToken+`", "password": "short"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a short password, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do("/api/auth/reset-password", `{"token": "`+verifyToken+`", "password": "a new password"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a verification token not to reset the password, got %d", rec.Code)
+	}
+	if rec := do("/api/auth/reset-password", `{"token": "`+resetToken+`", "password": "a new password"}`, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	// The new password works, the old one and the existing sessions do not
+	for _, tc := range []struct {
+		path, body string
+		code       int
+	}{
+		{"/api/auth/login", `{"email": "me@example.com", "password": "long enough"}`, http.StatusUnauthorized},
+		{"/api/auth/login", `{"email": "me@example.com", "password": "a new password"}`, http.StatusOK},
+		{"/api/auth/refresh", `{"refresh_token": "` + registered.RefreshToken + `"}`, http.StatusUnauthorized},
+		{"/api/auth/reset-password", `{"token": "` + resetToken + `", "password": "another password"}`, http.StatusBadRequest},
+	} {
+		if rec := do(tc.path, tc.body, nil); rec.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tc.path, tc.body, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Without a mailer the endpoints do not exist
+	disabled := NewAuthHandler(users, tokens, nil, nil, false)
+	rec = httptest.NewRecorder()
+	disabled.ForgotPassword(rec, httptest.NewRequest("POST", "/api/auth/forgot-password", strings.NewReader(`{"email": "me@example.com"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without a mailer, got %d", http.StatusNotFound, rec.Code)
+	}
+}
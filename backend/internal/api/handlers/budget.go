@@ -3,10 +3,14 @@ package handlers
 import (
 	"budget-tracker/internal/models"
 	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/importer"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // BudgetHandler handles budget-related HTTP requests
@@ -19,20 +23,72 @@ func NewBudgetHandler(repo *repository.BudgetRepository) *BudgetHandler {
 	return &BudgetHandler{repo: repo}
 }
 
+// BudgetListResponse is a page of budgets, newest first
+type BudgetListResponse struct {
+	Budgets []models.BudgetLimit `json:"budgets"`
+	Page
+}
+
 // List handles GET /api/budgets
+// Supports optional filters: year, from/to (YYYY-MM, inclusive months),
+// include_deleted=true to list deleted budgets too, and limit/offset paging.
+// Total is the number of matching budgets before paging.
 func (h *BudgetHandler) List(w http.ResponseWriter, r *http.Request) {
-	budgets, err := h.repo.GetAll()
+	query := r.URL.Query()
+	var filter repository.BudgetFilter
+	var err error
+
+	if filter.Year, err = parseOptionalInt(query, "year"); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From, err = parseOptionalMonth(query, "from"); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = parseOptionalMonth(query, "to"); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		respondError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+	if v := query.Get("include_deleted"); v != "" {
+		if filter.IncludeDeleted, err = strconv.ParseBool(v); err != nil {
+			respondError(w, http.StatusBadRequest, "include_deleted must be true or false")
+			return
+		}
+	}
+	if filter.Limit, filter.Offset, err = parsePaging(query); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repo := h.repo.ForUser(requestUserID(r))
+	budgets, err := repo.List(filter)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch budgets")
 		return
 	}
 
+	total := len(budgets)
+	if filter.Limit > 0 || filter.Offset > 0 {
+		if total, err = repo.Count(filter); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to count budgets")
+			return
+		}
+	}
+
 	// Ensure we return an empty array instead of null
 	if budgets == nil {
 		budgets = []models.BudgetLimit{}
 	}
 
-	respondJSON(w, http.StatusOK, budgets)
+	respondJSON(w, http.StatusOK, BudgetListResponse{
+		Budgets: budgets,
+		Page:    newPage(w, r, len(budgets), total, filter.Limit, filter.Offset),
+	})
 }
 
 // Create handles POST /api/budgets
@@ -48,7 +104,7 @@ func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	budget, err := h.repo.Create(&req)
+	budget, err := h.repo.ForUser(requestUserID(r)).Create(&req)
 	if err != nil {
 		if errors.Is(err, repository.ErrBudgetExists) {
 			respondError(w, http.StatusConflict, "Budget for this month/year already exists")
@@ -61,6 +117,127 @@ func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusCreated, budget)
 }
 
+// BulkBudgetConflictResponse lists the rows of a bulk create whose month
+// already has a budget
+type BulkBudgetConflictResponse struct {
+	Error     string                      `json:"error"`
+	Conflicts []repository.BudgetConflict `json:"conflicts"`
+}
+
+// CreateBulk handles POST /api/budgets/bulk
+// All budgets are created in one transaction; when any month already has a
+// budget nothing is created and every conflicting row is listed.
+func (h *BudgetHandler) CreateBulk(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkCreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budgets, err := h.repo.ForUser(requestUserID(r)).CreateBulk(req.Budgets)
+	if err != nil {
+		var duplicates *repository.DuplicateBudgetsError
+		if errors.As(err, &duplicates) {
+			respondJSON(w, http.StatusConflict, BulkBudgetConflictResponse{
+				Error:     "Budgets already exist for some months",
+				Conflicts: duplicates.Conflicts,
+			})
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to create budgets")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, budgets)
+}
+
+// BudgetImportErrorResponse lists the lines of an imported CSV that are
+// invalid or whose month already has a budget
+type BudgetImportErrorResponse struct {
+	Error string               `json:"error"`
+	Lines []importer.LineError `json:"lines"`
+}
+
+// Import handles POST /api/budgets/import
+// Creates the budgets of a CSV with month, year, amount and optional
+// threshold columns, uploaded in the file form field or sent as the body.
+// Like CreateBulk, either every budget is created or none are; the lines
+// that are invalid or whose month already has a budget are all listed.
+func (h *BudgetHandler) Import(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(w, http.StatusRequestEntityTooLarge, "CSV file too large (max 10MB)")
+				return
+			}
+			respondError(w, http.StatusBadRequest, "Failed to parse form data")
+			return
+		}
+		file, _, err := r.FormFile(ImportFileKey)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "No CSV file provided. Use form field 'file'")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, err := importer.ParseBudgets(body)
+	if err != nil {
+		var lines *importer.LinesError
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &lines):
+			respondJSON(w, http.StatusBadRequest, BudgetImportErrorResponse{
+				Error: "Some lines are invalid",
+				Lines: lines.Lines,
+			})
+		case errors.As(err, &tooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, "CSV file too large (max 10MB)")
+		default:
+			respondError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	req := models.BulkCreateBudgetRequest{Budgets: make([]models.CreateBudgetLimitRequest, 0, len(rows))}
+	for _, row := range rows {
+		req.Budgets = append(req.Budgets, row.Budget)
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budgets, err := h.repo.ForUser(requestUserID(r)).CreateBulk(req.Budgets)
+	if err != nil {
+		var duplicates *repository.DuplicateBudgetsError
+		if errors.As(err, &duplicates) {
+			resp := BudgetImportErrorResponse{Error: "Budgets already exist for some months"}
+			for _, conflict := range duplicates.Conflicts {
+				resp.Lines = append(resp.Lines, importer.LineError{
+					Line:  rows[conflict.Index].Line,
+					Error: conflict.Error,
+				})
+			}
+			respondJSON(w, http.StatusConflict, resp)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to create budgets")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, budgets)
+}
+
 // Get handles GET /api/budgets/{id}
 func (h *BudgetHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := parseIDFromPath(r)
@@ -69,7 +246,7 @@ func (h *BudgetHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	budget, err := h.repo.GetByID(id)
+	budget, err := h.repo.ForUser(requestUserID(r)).GetByID(id)
 	if err != nil {
 		if errors.Is(err, repository.ErrBudgetNotFound) {
 			respondError(w, http.StatusNotFound, "Budget not found")
@@ -101,7 +278,7 @@ func (h *BudgetHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	budget, err := h.repo.Update(id, &req)
+	budget, err := h.repo.ForUser(requestUserID(r)).Update(id, &req)
 	if err != nil {
 		if errors.Is(err, repository.ErrBudgetNotFound) {
 			respondError(w, http.StatusNotFound, "Budget not found")
@@ -122,11 +299,15 @@ func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
+	if err := h.repo.ForUser(requestUserID(r)).Delete(id); err != nil {
 		if errors.Is(err, repository.ErrBudgetNotFound) {
 			respondError(w, http.StatusNotFound, "Budget not found")
 			return
 		}
+		if errors.Is(err, models.ErrMonthClosed) {
+			respondError(w, http.StatusConflict, "Budget month is closed, reopen it first")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "Failed to delete budget")
 		return
 	}
@@ -134,6 +315,305 @@ func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Restore handles POST /api/budgets/{id}/restore
+// Brings back a deleted budget with its thresholds and history. A budget that
+// is not deleted is returned unchanged.
+func (h *BudgetHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid budget ID")
+		return
+	}
+
+	budget, err := h.repo.ForUser(requestUserID(r)).Restore(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			respondError(w, http.StatusNotFound, "Budget not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to restore budget")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// Close handles POST /api/budgets/{id}/close
+// Closes the budget's month after it was reconciled: creating, changing or
+// deleting its actual expenses responds 409 until it is reopened.
+func (h *BudgetHandler) Close(w http.ResponseWriter, r *http.Request) {
+	h.setClosed(w, r, (*repository.BudgetRepository).Close)
+}
+
+// Reopen handles POST /api/budgets/{id}/reopen
+func (h *BudgetHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	h.setClosed(w, r, (*repository.BudgetRepository).Reopen)
+}
+
+func (h *BudgetHandler) setClosed(
+	w http.ResponseWriter,
+	r *http.Request,
+	set func(*repository.BudgetRepository, int64) (*models.BudgetLimit, error),
+) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid budget ID")
+		return
+	}
+
+	budget, err := set(h.repo.ForUser(requestUserID(r)), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			respondError(w, http.StatusNotFound, "Budget not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update budget")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// GetByMonth handles GET /api/budgets/by-month/{year}/{month}
+func (h *BudgetHandler) GetByMonth(w http.ResponseWriter, r *http.Request) {
+	year, month, err := parseMonthFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid year or month")
+		return
+	}
+	if month < 1 || month > 12 {
+		respondError(w, http.StatusBadRequest, models.ErrInvalidMonth.Error())
+		return
+	}
+
+	budget, err := h.repo.ForUser(requestUserID(r)).GetByMonthYear(month, year)
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			respondError(w, http.StatusNotFound, "Budget not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// GetCurrent handles GET /api/budgets/current
+// Returns the budget of the current month in the server's local time zone, or
+// in the IANA time zone given by ?tz=, e.g. America/New_York, so the month
+// turns over at the client's midnight.
+func (h *BudgetHandler) GetCurrent(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid time zone")
+			return
+		}
+		now = now.In(location)
+	}
+
+	budget, err := h.repo.ForUser(requestUserID(r)).GetByMonthYear(int(now.Month()), now.Year())
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			respondError(w, http.StatusNotFound, "Budget not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget")
+		return
+	}
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// GetYear handles GET /api/budgets/year/{year}
+// Returns all twelve months with their budget and spending, and the annual sums.
+func (h *BudgetHandler) GetYear(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(r.PathValue("year"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid year")
+		return
+	}
+	if year < 2020 || year > 2100 {
+		respondError(w, http.StatusBadRequest, models.ErrInvalidYear.Error())
+		return
+	}
+
+	budgetYear, err := h.repo.ForUser(requestUserID(r)).GetYear(year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget year")
+		return
+	}
+	respondJSON(w, http.StatusOK, budgetYear)
+}
+
+// History handles GET /api/budgets/{id}/history
+// Returns the changes of the budget's amount and threshold, oldest first.
+func (h *BudgetHandler) History(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid budget ID")
+		return
+	}
+
+	changes, err := h.repo.ForUser(requestUserID(r)).History(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			respondError(w, http.StatusNotFound, "Budget not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget history")
+		return
+	}
+	respondJSON(w, http.StatusOK, changes)
+}
+
+// YearOrHistory handles GET /api/budgets/{first}/{second}, which serves both
+// GET /api/budgets/year/{year} and GET /api/budgets/{id}/history. ServeMux
+// refuses to register the two patterns since both match
+// /api/budgets/year/history, which is the year route here.
+func (h *BudgetHandler) YearOrHistory(w http.ResponseWriter, r *http.Request) {
+	first, second := r.PathValue("first"), r.PathValue("second")
+	switch {
+	case first == "year":
+		r.SetPathValue("year", second)
+		h.GetYear(w, r)
+	case second == "history":
+		r.SetPathValue("id", first)
+		h.History(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// BudgetUpsertResponse is returned by PUT /api/budgets/by-month/{year}/{month}
+type BudgetUpsertResponse struct {
+	Created bool                `json:"created"`
+	Budget  *models.BudgetLimit `json:"budget"`
+}
+
+// Upsert handles PUT /api/budgets/by-month/{year}/{month}
+// Creates the month's budget or updates it if one exists, replacing the
+// GET-then-POST/PUT round trip. Responds 201 when created and 200 when updated.
+func (h *BudgetHandler) Upsert(w http.ResponseWriter, r *http.Request) {
+	year, month, err := parseMonthFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid year or month")
+		return
+	}
+
+	var req models.UpsertBudgetLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Year, req.Month = year, month
+
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budget, created, err := h.repo.ForUser(requestUserID(r)).Upsert(&req)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save budget")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondJSON(w, status, BudgetUpsertResponse{Created: created, Budget: budget})
+}
+
+// Copy handles POST /api/budgets/copy
+// Copies the amount and notification threshold of the budget of the month
+// before month/year (default: current month) into that month. Responds 409
+// when the month already has a budget, unless overwrite is set, and 404 when
+// the previous month has none.
+func (h *BudgetHandler) Copy(w http.ResponseWriter, r *http.Request) {
+	var req models.CopyBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Month == 0 && req.Year == 0 {
+		now := time.Now()
+		req.Month, req.Year = int(now.Month()), now.Year()
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repo := h.repo.ForUser(requestUserID(r))
+	prevMonth, prevYear := req.PreviousMonth()
+	previous, err := repo.GetByMonthYear(prevMonth, prevYear)
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			respondError(w, http.StatusNotFound, "No budget for the previous month")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget")
+		return
+	}
+
+	if req.Overwrite {
+		budget, created, err := repo.Upsert(&models.UpsertBudgetLimitRequest{
+			Month:                  req.Month,
+			Year:                   req.Year,
+			Amount:                 previous.Amount,
+			NotificationThreshold:  &previous.NotificationThreshold,
+			NotificationThresholds: previous.NotificationThresholds,
+			RolloverUnspent:        &previous.RolloverUnspent,
+			WeeklyLimit:            &previous.WeeklyLimit,
+		})
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to copy budget")
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		respondJSON(w, status, budget)
+		return
+	}
+
+	budget, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month:                  req.Month,
+		Year:                   req.Year,
+		Amount:                 previous.Amount,
+		NotificationThreshold:  previous.NotificationThreshold,
+		NotificationThresholds: previous.NotificationThresholds,
+		RolloverUnspent:        previous.RolloverUnspent,
+		WeeklyLimit:            previous.WeeklyLimit,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrBudgetExists) {
+			respondError(w, http.StatusConflict, "Budget for this month/year already exists")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to copy budget")
+		return
+	}
+	respondJSON(w, http.StatusCreated, budget)
+}
+
+// parseMonthFromPath reads the {year} and {month} path values
+func parseMonthFromPath(r *http.Request) (int, int, error) {
+	year, err := strconv.Atoi(r.PathValue("year"))
+	if err != nil {
+		return 0, 0, err
+	}
+	month, err := strconv.Atoi(r.PathValue("month"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return year, month, nil
+}
+
 // parseIDFromPath extracts the ID from the URL path using Go 1.22+ PathValue
 func parseIDFromPath(r *http.Request) (int64, error) {
 	idStr := r.PathValue("id")
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"errors"
+	"sort"
+)
+
+// BudgetSnapshot captures spending against a budget at a point in time
+type BudgetSnapshot struct {
+	TotalSpent     float64          `json:"total_spent"`
+	PercentageUsed float64          `json:"percentage_used"`
+	Status         BudgetStatusType `json:"status"`
+}
+
+// ThresholdCrossing describes a status boundary that a pending change would cross
+type ThresholdCrossing struct {
+	Status     BudgetStatusType `json:"status"`
+	Percentage float64          `json:"percentage"`
+}
+
+// BudgetImpact describes how pending expenses would change one month's budget status
+type BudgetImpact struct {
+	Month             int                 `json:"month"`
+	Year              int                 `json:"year"`
+	Budget            *models.BudgetLimit `json:"budget"`
+	AdditionalAmount  float64             `json:"additional_amount"`
+	Before            BudgetSnapshot      `json:"before"`
+	After             BudgetSnapshot      `json:"after"`
+	PercentageDelta   float64             `json:"percentage_delta"`
+	ThresholdsCrossed []ThresholdCrossing `json:"thresholds_crossed"`
+}
+
+// BudgetImpactPreviewResponse is returned instead of created expenses when
+// ?preview_budget=true is set; nothing is persisted
+type BudgetImpactPreviewResponse struct {
+	Preview      bool           `json:"preview"`
+	ExpenseCount int            `json:"expense_count"`
+	Impacts      []BudgetImpact `json:"impacts"`
+}
+
+// computeBudgetImpacts groups pending expenses by month and compares the
+// current budget status of each month with the status after the expenses land.
+// Expenses the approval rule would hold back do not count until approved.
+func computeBudgetImpacts(
+	budgetRepo *repository.BudgetRepository,
+	actualExpenseRepo *repository.ActualExpenseRepository,
+	reqs []models.CreateActualExpenseRequest,
+) ([]BudgetImpact, error) {
+	type monthKey struct{ month, year int }
+
+	additional := make(map[monthKey]float64)
+	for i := range reqs {
+		held, err := actualExpenseRepo.NeedsApproval(reqs[i].ActualAmount)
+		if err != nil {
+			return nil, err
+		}
+		if held {
+			continue
+		}
+		date := reqs[i].EffectiveReceiptDate()
+		key := monthKey{month: int(date.Month()), year: date.Year()}
+		additional[key] += reqs[i].ActualAmount
+	}
+
+	impacts := make([]BudgetImpact, 0, len(additional))
+	for key, amount := range additional {
+		budget, err := budgetRepo.GetByMonthYear(key.month, key.year)
+		if err != nil && !errors.Is(err, repository.ErrBudgetNotFound) {
+			return nil, err
+		}
+
+		summary, err := actualExpenseRepo.GetMonthlySummary(key.month, key.year)
+		if err != nil {
+			return nil, err
+		}
+
+		impact := BudgetImpact{
+			Month:             key.month,
+			Year:              key.year,
+			Budget:            budget,
+			AdditionalAmount:  amount,
+			Before:            snapshotBudget(budget, summary.TotalActual),
+			After:             snapshotBudget(budget, summary.TotalActual+amount),
+			ThresholdsCrossed: []ThresholdCrossing{},
+		}
+		impact.PercentageDelta = impact.After.PercentageUsed - impact.Before.PercentageUsed
+		if budget != nil {
+			impact.ThresholdsCrossed = thresholdsCrossed(
+				impact.Before.PercentageUsed,
+				impact.After.PercentageUsed,
+				budget.NotificationThreshold,
+			)
+		}
+		impacts = append(impacts, impact)
+	}
+
+	sort.Slice(impacts, func(i, j int) bool {
+		if impacts[i].Year != impacts[j].Year {
+			return impacts[i].Year < impacts[j].Year
+		}
+		return impacts[i].Month < impacts[j].Month
+	})
+
+	return impacts, nil
+}
+
+// snapshotBudget computes the status for a spent total; a missing budget is always safe
+func snapshotBudget(budget *models.BudgetLimit, spent float64) BudgetSnapshot {
+	snapshot := BudgetSnapshot{TotalSpent: spent, Status: BudgetStatusSafe}
+	if budget == nil || budget.Amount <= 0 {
+		return snapshot
+	}
+
+	snapshot.PercentageUsed = (spent / budget.Amount) * 100
+	snapshot.Status, _ = determineStatus(
+		snapshot.PercentageUsed,
+		budget.NotificationThreshold,
+		spent,
+		budget.Amount,
+	)
+	return snapshot
+}
+
+// thresholdsCrossed lists the status boundaries passed when moving from before to after.
+// Boundaries mirror determineStatus: warning at the notification threshold,
+// danger at 90% and over once spending exceeds 100%.
+func thresholdsCrossed(before, after, threshold float64) []ThresholdCrossing {
+	crossed := []ThresholdCrossing{}
+	if after <= before {
+		return crossed
+	}
+
+	boundaries := []ThresholdCrossing{
+		{Status: BudgetStatusWarning, Percentage: threshold * 100},
+		{Status: BudgetStatusDanger, Percentage: 90},
+	}
+	for _, b := range boundaries {
+		if before < b.Percentage && after >= b.Percentage {
+			crossed = append(crossed, b)
+		}
+	}
+	if before <= 100 && after > 100 {
+		crossed = append(crossed, ThresholdCrossing{Status: BudgetStatusOver, Percentage: 100})
+	}
+
+	return crossed
+}
@@ -3,11 +3,18 @@ package handlers
 import (
 	"budget-tracker/internal/models"
 	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestBudgetList_Empty(t *testing.T) {
@@ -27,13 +34,13 @@ func TestBudgetList_Empty(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
 	}
 
-	var budgets []models.BudgetLimit
-	if err := json.NewDecoder(rec.Body).Decode(&budgets); err != nil {
+	var list BudgetListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(budgets) != 0 {
-		t.Errorf("Expected empty list, got %d budgets", len(budgets))
+	if list.Budgets == nil || len(list.Budgets) != 0 || list.Total != 0 {
+		t.Errorf("Expected empty list, got %+v", list)
 	}
 }
 
@@ -68,13 +75,78 @@ func TestBudgetList_WithData(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
 	}
 
-	var budgets []models.BudgetLimit
-	if err := json.NewDecoder(rec.Body).Decode(&budgets); err != nil {
+	var list BudgetListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(budgets) != 3 {
-		t.Errorf("Expected 3 budgets, got %d", len(budgets))
+	if len(list.Budgets) != 3 || list.Total != 3 {
+		t.Errorf("Expected 3 budgets, got %d of %d", len(list.Budgets), list.Total)
+	}
+}
+
+func TestBudgetList_FilterAndPaging(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+
+	// November 2023 to April 2025
+	for i := 0; i < 18; i++ {
+		month, year := (10+i)%12+1, 2023+(10+i)/12
+		if _, err := repo.Create(&models.CreateBudgetLimitRequest{
+			Month: month, Year: year, Amount: 1000, NotificationThreshold: 0.8,
+		}); err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+	}
+
+	list := func(target string) BudgetListResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", target, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var list BudgetListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return list
+	}
+
+	year := list("/api/budgets?year=2024")
+	if len(year.Budgets) != 12 || year.Total != 12 {
+		t.Fatalf("Expected the 12 budgets of 2024, got %d of %d", len(year.Budgets), year.Total)
+	}
+	if first := year.Budgets[0]; first.Year != 2024 || first.Month != 12 {
+		t.Errorf("Expected newest first, got %d/%d", first.Month, first.Year)
+	}
+
+	// Month ranges are inclusive and may span years
+	span := list("/api/budgets?from=2023-12&to=2024-02")
+	if len(span.Budgets) != 3 || span.Budgets[2].Year != 2023 || span.Budgets[2].Month != 12 {
+		t.Errorf("Expected December 2023 to February 2024, got %+v", span.Budgets)
+	}
+
+	page := list("/api/budgets?from=2024-01&limit=5&offset=5")
+	if len(page.Budgets) != 5 || page.Total != 16 || page.Count != 5 {
+		t.Errorf("Expected 5 of 16 budgets, got %d of %d", len(page.Budgets), page.Total)
+	}
+	if page.Next == "" || page.Prev == "" {
+		t.Errorf("Expected links to the next and previous pages, got %+v", page.Page)
+	}
+	if first := page.Budgets[0]; first.Year != 2024 || first.Month != 11 {
+		t.Errorf("Expected the page to start at November 2024, got %d/%d", first.Month, first.Year)
+	}
+
+	for _, query := range []string{"year=x", "from=2024", "to=2024-13", "from=2024-05&to=2024-01", "limit=-1", "limit=501"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/budgets?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
 	}
 }
 
@@ -737,6 +809,195 @@ func TestBudgetCreate_DefaultThreshold(t *testing.T) {
 	}
 }
 
+func TestBudgetGetByMonth(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+
+	created, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month: 11, Year: 2025, Amount: 1000, NotificationThreshold: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test budget: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"exists", "/api/budgets/by-month/2025/11", http.StatusOK},
+		{"other month", "/api/budgets/by-month/2025/12", http.StatusNotFound},
+		{"invalid month", "/api/budgets/by-month/2025/13", http.StatusBadRequest},
+		{"non-numeric", "/api/budgets/by-month/2025/nov", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var budget models.BudgetLimit
+			if err := json.NewDecoder(rec.Body).Decode(&budget); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if budget.ID != created.ID {
+				t.Errorf("Expected budget %d, got %d", created.ID, budget.ID)
+			}
+		})
+	}
+}
+
+func TestBudgetUpsert_CreatesThenUpdates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+
+	upsert := func(body string) (int, BudgetUpsertResponse) {
+		t.Helper()
+		req := httptest.NewRequest("PUT", "/api/budgets/by-month/2025/11", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var response BudgetUpsertResponse
+		if rec.Code == http.StatusOK || rec.Code == http.StatusCreated {
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, response
+	}
+
+	code, response := upsert(`{"amount": 1000}`)
+	if code != http.StatusCreated || !response.Created {
+		t.Fatalf("Expected created budget, got status %d %+v", code, response)
+	}
+	if response.Budget.Month != 11 || response.Budget.Year != 2025 ||
+		response.Budget.NotificationThreshold != 0.8 {
+		t.Errorf("Unexpected created budget: %+v", response.Budget)
+	}
+
+	code, response = upsert(`{"amount": 1500, "notification_threshold": 0.9}`)
+	if code != http.StatusOK || response.Created {
+		t.Fatalf("Expected updated budget, got status %d %+v", code, response)
+	}
+	if response.Budget.Amount != 1500 || response.Budget.NotificationThreshold != 0.9 {
+		t.Errorf("Unexpected updated budget: %+v", response.Budget)
+	}
+
+	// Omitting the threshold keeps the stored one
+	code, response = upsert(`{"amount": 1200}`)
+	if code != http.StatusOK || response.Budget.NotificationThreshold != 0.9 {
+		t.Errorf("Expected threshold 0.9 to be kept, got status %d %+v", code, response.Budget)
+	}
+
+	budgets, _ := repo.GetAll()
+	if len(budgets) != 1 {
+		t.Errorf("Expected a single budget row, got %d", len(budgets))
+	}
+}
+
+func TestBudgetUpsert_Invalid(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	mux := createTestMux(NewBudgetHandler(repository.NewBudgetRepository(db)), nil)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"invalid month", "/api/budgets/by-month/2025/13", `{"amount": 1000}`},
+		{"non-numeric year", "/api/budgets/by-month/next/1", `{"amount": 1000}`},
+		{"invalid amount", "/api/budgets/by-month/2025/1", `{"amount": 0}`},
+		{"invalid threshold", "/api/budgets/by-month/2025/1", `{"amount": 1000, "notification_threshold": 2}`},
+		{"invalid json", "/api/budgets/by-month/2025/1", `{`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", tt.path, bytes.NewReader([]byte(tt.body)))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+		})
+	}
+}
+
+func TestBudgetCopy(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+	if _, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month: 12, Year: 2024, Amount: 2500, NotificationThreshold: 0.7,
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	copyBudget := func(body string) (int, models.BudgetLimit) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/budgets/copy", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var budget models.BudgetLimit
+		if rec.Code == http.StatusOK || rec.Code == http.StatusCreated {
+			if err := json.NewDecoder(rec.Body).Decode(&budget); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, budget
+	}
+
+	// December is copied into January of the next year
+	code, budget := copyBudget(`{"month": 1, "year": 2025}`)
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+	}
+	if budget.Month != 1 || budget.Year != 2025 || budget.Amount != 2500 || budget.NotificationThreshold != 0.7 {
+		t.Errorf("Unexpected copied budget: %+v", budget)
+	}
+
+	if code, _ := copyBudget(`{"month": 1, "year": 2025}`); code != http.StatusConflict {
+		t.Errorf("Expected status %d for an existing budget, got %d", http.StatusConflict, code)
+	}
+
+	// Overwrite replaces the existing budget
+	amount := 100.0
+	if _, err := repo.Update(budget.ID, &models.UpdateBudgetLimitRequest{Amount: &amount}); err != nil {
+		t.Fatalf("Failed to update budget: %v", err)
+	}
+	code, budget = copyBudget(`{"month": 1, "year": 2025, "overwrite": true}`)
+	if code != http.StatusOK || budget.Amount != 2500 {
+		t.Errorf("Expected the budget to be overwritten, got status %d %+v", code, budget)
+	}
+
+	if code, _ := copyBudget(`{"month": 6, "year": 2025}`); code != http.StatusNotFound {
+		t.Errorf("Expected status %d without a previous budget, got %d", http.StatusNotFound, code)
+	}
+	if code, _ := copyBudget(`{"month": 13, "year": 2025}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid month, got %d", http.StatusBadRequest, code)
+	}
+}
+
 // Helper function to convert int64 to string
 func itoa(i int64) string {
 	if i == 0 {
@@ -760,3 +1021,710 @@ func itoa(i int64) string {
 
 	return string(result)
 }
+
+func TestBudgetHandler_ScopedToUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewBudgetHandler(repository.NewBudgetRepository(db))
+	mux := createTestMux(handler, nil)
+
+	asUser := func(req *http.Request, userID int64) *http.Request {
+		return req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+	}
+
+	// Both users can have a budget for the same month
+	for _, userID := range []int64{1, 2} {
+		body := `{"month":3,"year":2024,"amount":1000,"notification_threshold":0.8}`
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, asUser(httptest.NewRequest("POST", "/api/budgets", bytes.NewBufferString(body)), userID))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("User %d: expected status %d, got %d: %s", userID, http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, asUser(httptest.NewRequest("GET", "/api/budgets", nil), 1))
+	var list BudgetListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	budgets := list.Budgets
+	if len(budgets) != 1 {
+		t.Fatalf("Expected user 1 to see 1 budget, got %d", len(budgets))
+	}
+
+	// Another user's budget looks like it does not exist
+	path := fmt.Sprintf("/api/budgets/%d", budgets[0].ID)
+	for _, method := range []string{"GET", "DELETE"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, asUser(httptest.NewRequest(method, path, nil), 2))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s as user 2: expected status %d, got %d", method, http.StatusNotFound, rec.Code)
+		}
+	}
+
+	// Without authentication requests use the shared workspace
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/budgets", nil))
+	list = BudgetListResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list.Budgets) != 0 {
+		t.Errorf("Expected the shared workspace to be empty, got %d budgets", len(list.Budgets))
+	}
+}
+
+func TestBudgetGetYear(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	actualRepo := repository.NewActualExpenseRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+	for _, month := range []int{1, 2} {
+		if _, err := repo.Create(&models.CreateBudgetLimitRequest{
+			Month: month, Year: 2024, Amount: 1000, NotificationThreshold: 0.8,
+		}); err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+	}
+	for _, e := range []struct {
+		amount float64
+		date   time.Time
+	}{
+		{100, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{50, time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{30, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Other years are left out
+		{999, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+	} {
+		if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Publix", ActualAmount: e.amount,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &e.date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/budgets/year/2024", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var year models.BudgetYear
+	if err := json.NewDecoder(rec.Body).Decode(&year); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(year.Months) != 12 {
+		t.Fatalf("Expected 12 months, got %d", len(year.Months))
+	}
+	if year.TotalBudget != 2000 || year.TotalSpent != 180 {
+		t.Errorf("Expected totals 2000/180, got %.2f/%.2f", year.TotalBudget, year.TotalSpent)
+	}
+	january := year.Months[0]
+	if january.Budget == nil || *january.Budget != 1000 || january.Spent != 150 || *january.Remaining != 850 {
+		t.Errorf("Unexpected January: %+v", january)
+	}
+	march := year.Months[2]
+	if march.Month != 3 || march.Budget != nil || march.Remaining != nil || march.Spent != 30 {
+		t.Errorf("Unexpected March: %+v", march)
+	}
+
+	for _, path := range []string{"/api/budgets/year/abc", "/api/budgets/year/1999"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestBudgetHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+	budget, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 2000, NotificationThreshold: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{"PUT", fmt.Sprintf("/api/budgets/%d", budget.ID), `{"amount": 2500}`},
+		// Nothing changed, nothing recorded
+		{"PUT", fmt.Sprintf("/api/budgets/%d", budget.ID), `{"amount": 2500}`},
+		{"PUT", "/api/budgets/by-month/2025/3", `{"amount": 2500, "notification_threshold": 0.9}`},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.body))))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status %d, got %d", tc.method, tc.path, http.StatusOK, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/budgets/%d/history", budget.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var changes []models.BudgetLimitChange
+	if err := json.NewDecoder(rec.Body).Decode(&changes); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+	if c := changes[0]; c.OldAmount != 2000 || c.NewAmount != 2500 || c.OldThreshold != 0.8 || c.NewThreshold != 0.8 {
+		t.Errorf("Unexpected first change %+v", c)
+	}
+	if c := changes[1]; c.OldAmount != 2500 || c.NewAmount != 2500 || c.OldThreshold != 0.8 || c.NewThreshold != 0.9 {
+		t.Errorf("Unexpected second change %+v", c)
+	}
+
+	for _, path := range []string{"/api/budgets/999/history", "/api/budgets/abc/other"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusNotFound, rec.Code)
+		}
+	}
+
+	// A deleted budget keeps its history for a restore, and loses it once a
+	// new budget takes its month
+	countHistory := func() int {
+		t.Helper()
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM budget_limit_history`).Scan(&count); err != nil {
+			t.Fatalf("Failed to count history: %v", err)
+		}
+		return count
+	}
+	if err := repo.Delete(budget.ID); err != nil {
+		t.Fatalf("Failed to delete budget: %v", err)
+	}
+	if count := countHistory(); count != 2 {
+		t.Errorf("Expected the history to be kept, got %d rows", count)
+	}
+	if _, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 1800, NotificationThreshold: 0.8,
+	}); err != nil {
+		t.Fatalf("Failed to create budget over the deleted one: %v", err)
+	}
+	if count := countHistory(); count != 0 {
+		t.Errorf("Expected the history to be deleted, got %d rows", count)
+	}
+}
+
+func TestBudgetGetCurrent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	if rec := get("/api/budgets/current"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without a budget, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := get("/api/budgets/current?tz=Mars/Olympus"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown time zone, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// 25 hours apart, so in different months around the turn of one
+	for _, tz := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", tz, err)
+		}
+		now := time.Now().In(location)
+		if _, _, err := repo.Upsert(&models.UpsertBudgetLimitRequest{
+			Month: int(now.Month()), Year: now.Year(), Amount: 1000,
+		}); err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+
+		rec := get("/api/budgets/current?tz=" + tz)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", tz, http.StatusOK, rec.Code)
+		}
+		var budget models.BudgetLimit
+		if err := json.NewDecoder(rec.Body).Decode(&budget); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if budget.Month != int(now.Month()) || budget.Year != now.Year() {
+			t.Errorf("%s: expected %d/%d, got %d/%d", tz, now.Month(), now.Year(), budget.Month, budget.Year)
+		}
+	}
+}
+
+func TestBudgetCreateBulk(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+	if _, err := repo.Create(&models.CreateBudgetLimitRequest{Month: 3, Year: 2025, Amount: 900}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/budgets/bulk", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// March exists and February is repeated; nothing is created
+	rec := post(`{"budgets": [
+		{"month": 1, "year": 2025, "amount": 1000},
+		{"month": 2, "year": 2025, "amount": 1000},
+		{"month": 3, "year": 2025, "amount": 1000},
+		{"month": 2, "year": 2025, "amount": 1200}
+	]}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+	var conflict BulkBudgetConflictResponse
+	if err := json.NewDecoder(rec.Body).Decode(&conflict); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(conflict.Conflicts) != 2 ||
+		conflict.Conflicts[0].Index != 2 || conflict.Conflicts[0].Month != 3 ||
+		conflict.Conflicts[1].Index != 3 || conflict.Conflicts[1].Error != "same month as budgets[1]" {
+		t.Errorf("Unexpected conflicts: %+v", conflict.Conflicts)
+	}
+	if budgets, err := repo.GetAll(); err != nil || len(budgets) != 1 {
+		t.Fatalf("Expected only the existing budget, got %+v (err %v)", budgets, err)
+	}
+
+	rec = post(`{"budgets": [
+		{"month": 1, "year": 2025, "amount": 1000},
+		{"month": 2, "year": 2025, "amount": 1100, "notification_threshold": 0.9}
+	]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created []models.BudgetLimit
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(created) != 2 || created[0].NotificationThreshold != 0.8 ||
+		created[1].Month != 2 || created[1].Amount != 1100 || created[1].NotificationThreshold != 0.9 {
+		t.Errorf("Unexpected budgets: %+v", created)
+	}
+
+	for _, body := range []string{
+		`{"budgets": []}`,
+		`{"budgets": [{"month": 13, "year": 2025, "amount": 1000}]}`,
+		`{"budgets": [{"month": 4, "year": 2025, "amount": 0}]}`,
+	} {
+		if rec := post(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, rec.Code)
+		}
+	}
+}
+
+func TestBudgetImport(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+	if _, err := repo.Create(&models.CreateBudgetLimitRequest{Month: 3, Year: 2024, Amount: 900}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	post := func(csv string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/budgets/import", strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decodeLines := func(rec *httptest.ResponseRecorder) []int {
+		t.Helper()
+		var resp BudgetImportErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		lines := []int{}
+		for _, line := range resp.Lines {
+			lines = append(lines, line.Line)
+		}
+		return lines
+	}
+
+	// Every invalid line is reported and nothing is created
+	rec := post("month,year,amount,threshold\n1,2024,1000,0.8\n13,2024,1000,\n2,2024,0,\n")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	if lines := decodeLines(rec); !slices.Equal(lines, []int{3, 4}) {
+		t.Errorf("Expected lines 3 and 4 reported, got %v", lines)
+	}
+
+	// March 2024 already has a budget
+	rec = post("month,year,amount\n1,2024,1000\n3,2024,1000\n")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+	if lines := decodeLines(rec); !slices.Equal(lines, []int{3}) {
+		t.Errorf("Expected line 3 reported, got %v", lines)
+	}
+	if budgets, err := repo.GetAll(); err != nil || len(budgets) != 1 {
+		t.Fatalf("Expected only the existing budget, got %+v (err %v)", budgets, err)
+	}
+
+	// Uploaded as a file like the other imports
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile(ImportFileKey, "budgets.csv")
+	file.Write([]byte("month,year,amount,threshold\n1,2024,1000,90%\n2,2024,1100,\n"))
+	form.Close()
+	req := httptest.NewRequest("POST", "/api/budgets/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created []models.BudgetLimit
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(created) != 2 || created[0].NotificationThreshold != 0.9 ||
+		created[1].Amount != 1100 || created[1].NotificationThreshold != 0.8 {
+		t.Errorf("Unexpected budgets: %+v", created)
+	}
+
+	for _, csv := range []string{"", "month,amount\n1,100\n", "month,year,amount\n"} {
+		if rec := post(csv); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %q, got %d", http.StatusBadRequest, csv, rec.Code)
+		}
+	}
+}
+
+func TestBudgetThresholds(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+
+	send := func(method, path, body string) (int, models.BudgetLimit) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var budget models.BudgetLimit
+		if rec.Code == http.StatusOK || rec.Code == http.StatusCreated {
+			if err := json.NewDecoder(rec.Body).Decode(&budget); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, budget
+	}
+	equal := func(got, want []float64) bool {
+		return fmt.Sprint(got) == fmt.Sprint(want)
+	}
+
+	// Sorted, without repeats, and the lowest is the single threshold
+	code, budget := send("POST", "/api/budgets",
+		`{"month": 5, "year": 2025, "amount": 1000, "notification_thresholds": [1, 0.5, 0.8, 0.5]}`)
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+	}
+	if !equal(budget.NotificationThresholds, []float64{0.5, 0.8, 1}) || budget.NotificationThreshold != 0.5 {
+		t.Errorf("Unexpected thresholds: %v (%v)", budget.NotificationThresholds, budget.NotificationThreshold)
+	}
+	path := fmt.Sprintf("/api/budgets/%d", budget.ID)
+
+	// Changing the amount keeps the thresholds
+	if _, updated := send("PUT", path, `{"amount": 1200}`); !equal(updated.NotificationThresholds, []float64{0.5, 0.8, 1}) {
+		t.Errorf("Expected thresholds to be kept, got %v", updated.NotificationThresholds)
+	}
+
+	// A single threshold replaces the list
+	if _, updated := send("PUT", path, `{"notification_threshold": 0.7}`); !equal(updated.NotificationThresholds, []float64{0.7}) {
+		t.Errorf("Expected a single threshold, got %v", updated.NotificationThresholds)
+	}
+
+	for _, body := range []string{
+		`{"notification_thresholds": []}`,
+		`{"notification_thresholds": [0.5, 1.5]}`,
+		`{"notification_thresholds": [0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 0.95, 1]}`,
+	} {
+		if code, _ := send("PUT", path, body); code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, code)
+		}
+	}
+
+	// A budget without thresholds gets the default one
+	if code, _ := send("PUT", "/api/budgets/by-month/2025/6", `{"amount": 900}`); code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+	}
+	if created, err := repo.GetByMonthYear(6, 2025); err != nil || !equal(created.NotificationThresholds, []float64{0.8}) {
+		t.Errorf("Expected the default threshold, got %+v (%v)", created, err)
+	}
+	send("PUT", "/api/budgets/by-month/2025/6", `{"amount": 900, "notification_thresholds": [0.9, 0.6]}`)
+
+	budgets, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to list budgets: %v", err)
+	}
+	if len(budgets) != 2 || !equal(budgets[0].NotificationThresholds, []float64{0.6, 0.9}) ||
+		!equal(budgets[1].NotificationThresholds, []float64{0.7}) {
+		t.Errorf("Unexpected budgets: %+v", budgets)
+	}
+
+	// Deleting the budget keeps its thresholds for a restore
+	if code, _ := send("DELETE", path, ""); code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM budget_limit_thresholds WHERE budget_id = ?`, budget.ID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count thresholds: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the thresholds to be kept, got %d", count)
+	}
+}
+
+func TestBudgetWeeklyLimit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+
+	send := func(method, path, body string) (int, models.BudgetLimit) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var budget models.BudgetLimit
+		if rec.Code == http.StatusOK || rec.Code == http.StatusCreated {
+			if err := json.NewDecoder(rec.Body).Decode(&budget); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, budget
+	}
+
+	if code, _ := send("POST", "/api/budgets", `{"month": 5, "year": 2025, "amount": 1000, "weekly_limit": -1}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a negative weekly limit, got %d", http.StatusBadRequest, code)
+	}
+
+	code, budget := send("POST", "/api/budgets", `{"month": 5, "year": 2025, "amount": 1000, "weekly_limit": 250}`)
+	if code != http.StatusCreated || budget.WeeklyLimit != 250 {
+		t.Fatalf("Expected a budget with a weekly limit, got %d %+v", code, budget)
+	}
+	path := fmt.Sprintf("/api/budgets/%d", budget.ID)
+
+	// Changing the amount keeps the weekly limit, and 0 removes it
+	if _, updated := send("PUT", path, `{"amount": 1200}`); updated.WeeklyLimit != 250 {
+		t.Errorf("Expected the weekly limit to be kept, got %v", updated.WeeklyLimit)
+	}
+	if _, updated := send("PUT", path, `{"weekly_limit": 0}`); updated.WeeklyLimit != 0 {
+		t.Errorf("Expected the weekly limit to be removed, got %v", updated.WeeklyLimit)
+	}
+
+	// Setting the month's budget without a weekly limit keeps it
+	if code, _ := send("PUT", path, `{"weekly_limit": 300}`); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if code, _ := send("PUT", "/api/budgets/by-month/2025/5", `{"amount": 1500}`); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if stored, err := repo.GetByMonthYear(5, 2025); err != nil || stored.WeeklyLimit != 300 {
+		t.Errorf("Expected the weekly limit to be kept, got %+v (%v)", stored, err)
+	}
+}
+
+func TestBudgetCloseMonth(t *testing.T) {
+	db, budgetRepo, actualRepo, _ := setupActualExpenseTest(t)
+	defer db.Close()
+
+	mux := createTestMux(NewBudgetHandler(budgetRepo), nil)
+	expenses := NewActualExpenseHandler(actualRepo, budgetRepo, nil)
+	mux.HandleFunc("POST /api/actual-expenses", expenses.Create)
+	mux.HandleFunc("PUT /api/actual-expenses/{id}", expenses.Update)
+	mux.HandleFunc("DELETE /api/actual-expenses/{id}", expenses.Delete)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	budget, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: 6, Year: 2024, Amount: 500, NotificationThreshold: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	expense, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 4,
+		ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+	budgetPath := fmt.Sprintf("/api/budgets/%d", budget.ID)
+	expensePath := fmt.Sprintf("/api/actual-expenses/%d", expense.ID)
+
+	rec := send("POST", budgetPath+"/close", "")
+	var closed models.BudgetLimit
+	if err := json.NewDecoder(rec.Body).Decode(&closed); err != nil || rec.Code != http.StatusOK || closed.ClosedAt == nil {
+		t.Fatalf("Expected the month closed, got %d %+v (%v)", rec.Code, closed, err)
+	}
+
+	// Every write to the month is refused, other months are not affected
+	june := `{"item_name": "Eggs", "source": "Publix", "actual_amount": 3, "expense_type": "weekly", "receipt_date": "2024-06-20T00:00:00Z"}`
+	july := `{"item_name": "Eggs", "source": "Publix", "actual_amount": 3, "expense_type": "weekly", "receipt_date": "2024-07-02T00:00:00Z"}`
+	for _, tc := range []struct{ method, path, body string }{
+		{"POST", "/api/actual-expenses", june},
+		{"PUT", expensePath, `{"actual_amount": 5}`},
+		{"DELETE", expensePath, ""},
+		{"DELETE", budgetPath, ""},
+	} {
+		if rec := send(tc.method, tc.path, tc.body); rec.Code != http.StatusConflict {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, http.StatusConflict, rec.Code)
+		}
+	}
+	if rec := send("POST", "/api/actual-expenses", july); rec.Code != http.StatusCreated {
+		t.Errorf("Expected another month to accept expenses, got %d", rec.Code)
+	}
+
+	// Closing again keeps when the month was closed
+	rec = send("POST", budgetPath+"/close", "")
+	var again models.BudgetLimit
+	if err := json.NewDecoder(rec.Body).Decode(&again); err != nil || !again.ClosedAt.Equal(*closed.ClosedAt) {
+		t.Errorf("Expected the closing time kept, got %+v (%v)", again, err)
+	}
+
+	rec = send("POST", budgetPath+"/reopen", "")
+	var reopened models.BudgetLimit
+	if err := json.NewDecoder(rec.Body).Decode(&reopened); err != nil || reopened.ClosedAt != nil {
+		t.Fatalf("Expected the month reopened, got %+v (%v)", reopened, err)
+	}
+	if rec := send("PUT", expensePath, `{"actual_amount": 5}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected a reopened month to accept changes, got %d", rec.Code)
+	}
+
+	if rec := send("POST", "/api/budgets/999/close", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown budget, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestBudgetRestore(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewBudgetRepository(db)
+	mux := createTestMux(NewBudgetHandler(repo), nil)
+	send := func(method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	list := func(query string) []models.BudgetLimit {
+		t.Helper()
+		rec := send("GET", "/api/budgets"+query)
+		var resp BudgetListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("GET /api/budgets%s: got %d (%v)", query, rec.Code, err)
+		}
+		return resp.Budgets
+	}
+
+	budget, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month: 6, Year: 2024, Amount: 1000, NotificationThresholds: []float64{0.5, 0.9},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	if _, err := repo.Create(&models.CreateBudgetLimitRequest{
+		Month: 7, Year: 2024, Amount: 800, NotificationThreshold: 0.8,
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	path := "/api/budgets/" + itoa(budget.ID)
+
+	if rec := send("DELETE", path); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := send("DELETE", path); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted budget to be gone, got %d", rec.Code)
+	}
+	if _, err := repo.GetByMonthYear(6, 2024); !errors.Is(err, repository.ErrBudgetNotFound) {
+		t.Errorf("Expected no budget for June, got %v", err)
+	}
+
+	// Deleted budgets are listed on request only
+	if budgets := list(""); len(budgets) != 1 || budgets[0].Month != 7 {
+		t.Errorf("Expected only July, got %+v", budgets)
+	}
+	budgets := list("?include_deleted=true")
+	if len(budgets) != 2 || budgets[1].ID != budget.ID || budgets[1].DeletedAt == nil {
+		t.Errorf("Expected the deleted June budget too, got %+v", budgets)
+	}
+	if rec := send("GET", "/api/budgets?include_deleted=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Restoring brings it back as it was, and is idempotent
+	for range 2 {
+		rec := send("POST", path+"/restore")
+		var restored models.BudgetLimit
+		if err := json.NewDecoder(rec.Body).Decode(&restored); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("Expected the budget restored, got %d (%v)", rec.Code, err)
+		}
+		if restored.DeletedAt != nil || restored.Amount != 1000 || !slices.Equal(restored.NotificationThresholds, []float64{0.5, 0.9}) {
+			t.Errorf("Unexpected restored budget %+v", restored)
+		}
+	}
+	if budgets := list(""); len(budgets) != 2 {
+		t.Errorf("Expected both budgets, got %+v", budgets)
+	}
+	if rec := send("POST", "/api/budgets/99999/restore"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// A new budget for the month of a deleted one replaces it for good
+	if rec := send("DELETE", path); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if _, created, err := repo.Upsert(&models.UpsertBudgetLimitRequest{Month: 6, Year: 2024, Amount: 1200}); err != nil || !created {
+		t.Fatalf("Expected a new June budget, got %v, %v", created, err)
+	}
+	if rec := send("POST", path+"/restore"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the replaced budget to be gone, got %d", rec.Code)
+	}
+	if budgets := list("?include_deleted=true"); len(budgets) != 2 {
+		t.Errorf("Expected two budgets, got %+v", budgets)
+	}
+}
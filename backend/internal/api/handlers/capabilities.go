@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"budget-tracker/internal/services/ai"
+	"net/http"
+	"slices"
+)
+
+// ExportFormats are the downloads the server offers: the Beancount ledger,
+// the tax report as CSV and the deductible report as a zip
+var ExportFormats = []string{"beancount", "tax_csv", "deductible_zip"}
+
+// CapabilitiesResponse is the response of GET /api/capabilities: the
+// optional subsystems enabled on this deployment and its upload limits, so
+// one frontend build can adapt to differently configured servers
+type CapabilitiesResponse struct {
+	// AIProcessing is false without an AI provider, e.g. in demo mode
+	AIProcessing bool `json:"ai_processing"`
+	// BankSync and Push are not part of this server yet and always false
+	BankSync      bool                `json:"bank_sync"`
+	Email         bool                `json:"email"`
+	Push          bool                `json:"push"`
+	ExportFormats []string            `json:"export_formats"`
+	Receipts      ReceiptCapabilities `json:"receipts"`
+}
+
+// ReceiptCapabilities are the documents receipt processing accepts
+type ReceiptCapabilities struct {
+	MaxUploadBytes int64    `json:"max_upload_bytes"`
+	AllowedTypes   []string `json:"allowed_types"`
+	// MaxPages is the most pages a PDF may have; 0 is no limit
+	MaxPages int `json:"max_pages"`
+}
+
+// CapabilitiesHandler reports the optional subsystems and limits of the
+// deployment, so the frontend can hide what is off and check uploads early
+type CapabilitiesHandler struct {
+	resp CapabilitiesResponse
+}
+
+// NewCapabilitiesHandler creates a new CapabilitiesHandler. features are the
+// features of GET /api/version, of which receipt_processing and email are
+// reported, and limits the documents receipt processing accepts.
+func NewCapabilitiesHandler(features map[string]bool, limits ai.DocumentLimits) *CapabilitiesHandler {
+	return &CapabilitiesHandler{resp: CapabilitiesResponse{
+		AIProcessing:  features["receipt_processing"],
+		Email:         features["email"],
+		ExportFormats: slices.Clone(ExportFormats),
+		Receipts: ReceiptCapabilities{
+			MaxUploadBytes: limits.MaxSize,
+			AllowedTypes:   slices.Clone(limits.AllowedTypes),
+			MaxPages:       limits.MaxPages,
+		},
+	}}
+}
+
+// Get handles GET /api/capabilities
+func (h *CapabilitiesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.resp)
+}
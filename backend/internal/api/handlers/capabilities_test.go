@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"budget-tracker/internal/services/ai"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCapabilitiesHandler_Get(t *testing.T) {
+	limits := ai.DocumentLimits{
+		MaxSize:      5 << 20,
+		AllowedTypes: []string{ai.MimeTypePDF, ai.MimeTypeJPEG},
+		MaxPages:     3,
+	}
+	handler := NewCapabilitiesHandler(map[string]bool{"receipt_processing": true, "email": false}, limits)
+
+	rec := httptest.NewRecorder()
+	handler.Get(rec, httptest.NewRequest("GET", "/api/capabilities", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var resp CapabilitiesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resp.AIProcessing || resp.Email || resp.BankSync || resp.Push {
+		t.Errorf("Expected only AI processing to be enabled, got %+v", resp)
+	}
+	if !slices.Equal(resp.ExportFormats, ExportFormats) {
+		t.Errorf("Expected the export formats %v, got %v", ExportFormats, resp.ExportFormats)
+	}
+	receipts := resp.Receipts
+	if receipts.MaxUploadBytes != 5<<20 || receipts.MaxPages != 3 ||
+		!slices.Equal(receipts.AllowedTypes, limits.AllowedTypes) {
+		t.Errorf("Expected the configured receipt limits, got %+v", receipts)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// commentPreviewLength bounds how much of a comment a notification quotes
+const commentPreviewLength = 140
+
+// CommentHandler handles the comment threads of actual expenses
+type CommentHandler struct {
+	repo             *repository.CommentRepository
+	expenseRepo      *repository.ActualExpenseRepository
+	notificationRepo *repository.NotificationRepository
+}
+
+// NewCommentHandler creates a new CommentHandler
+// notificationRepo is optional; when set, new comments create a notification.
+func NewCommentHandler(
+	repo *repository.CommentRepository,
+	expenseRepo *repository.ActualExpenseRepository,
+	notificationRepo *repository.NotificationRepository,
+) *CommentHandler {
+	return &CommentHandler{repo: repo, expenseRepo: expenseRepo, notificationRepo: notificationRepo}
+}
+
+// List handles GET /api/actual-expenses/{id}/comments
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid expense ID")
+		return
+	}
+
+	comments, err := h.repo.ForUser(requestUserID(r)).List(expenseID)
+	if err != nil {
+		respondCommentError(w, err, "Failed to fetch comments")
+		return
+	}
+	respondJSON(w, http.StatusOK, comments)
+}
+
+// Create handles POST /api/actual-expenses/{id}/comments
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid expense ID")
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := requestUserID(r)
+	comment, err := h.repo.ForUser(userID).Create(expenseID, req.Body)
+	if err != nil {
+		respondCommentError(w, err, "Failed to add comment")
+		return
+	}
+
+	h.notify(userID, comment)
+	respondJSON(w, http.StatusCreated, comment)
+}
+
+// Update handles PUT /api/actual-expenses/{id}/comments/{commentID}
+// Only the author of a comment can edit it.
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	expenseID, commentID, ok := parseCommentPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment, err := h.repo.ForUser(requestUserID(r)).Update(expenseID, commentID, req.Body)
+	if err != nil {
+		respondCommentError(w, err, "Failed to update comment")
+		return
+	}
+	respondJSON(w, http.StatusOK, comment)
+}
+
+// Delete handles DELETE /api/actual-expenses/{id}/comments/{commentID}
+// Only the author of a comment can delete it.
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	expenseID, commentID, ok := parseCommentPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.ForUser(requestUserID(r)).Delete(expenseID, commentID); err != nil {
+		respondCommentError(w, err, "Failed to delete comment")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notify creates a notification for a new comment for the owner of the
+// expense, unless they wrote it. The comment is already saved, so a failure
+// is only logged.
+func (h *CommentHandler) notify(userID int64, comment *models.Comment) {
+	if h.notificationRepo == nil {
+		return
+	}
+
+	ownerID, err := h.expenseRepo.ForUser(userID).HouseholdOwner(comment.ActualExpenseID)
+	if err != nil {
+		log.Printf("[Comments] Failed to find the owner of expense %d for notification: %v", comment.ActualExpenseID, err)
+		return
+	}
+	// Owners know about their own comments
+	if ownerID == userID {
+		return
+	}
+	expense, err := h.expenseRepo.ForUser(ownerID).GetByID(comment.ActualExpenseID)
+	if err != nil {
+		log.Printf("[Comments] Failed to load expense %d for notification: %v", comment.ActualExpenseID, err)
+		return
+	}
+
+	author := comment.Author
+	if author == "" {
+		author = "Someone"
+	}
+	message := comment.Body
+	if runes := []rune(message); len(runes) > commentPreviewLength {
+		message = string(runes[:commentPreviewLength]) + "…"
+	}
+
+	_, err = h.notificationRepo.ForUser(ownerID).Create(&models.Notification{
+		Kind:    models.NotificationCommentAdded,
+		Title:   fmt.Sprintf("%s commented on %s ($%.2f)", author, expense.ItemName, expense.ActualAmount),
+		Message: message,
+		Link:    fmt.Sprintf("/actual-expenses?month=%d&year=%d", expense.Month, expense.Year),
+	})
+	if err != nil {
+		log.Printf("[Comments] Failed to create notification for comment %d: %v", comment.ID, err)
+	}
+}
+
+// parseCommentPath returns the expense and comment IDs of a comment route,
+// responding 400 when either is invalid
+func parseCommentPath(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	expenseID, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid expense ID")
+		return 0, 0, false
+	}
+	commentID, err := strconv.ParseInt(r.PathValue("commentID"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid comment ID")
+		return 0, 0, false
+	}
+	return expenseID, commentID, true
+}
+
+func respondCommentError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrExpenseNotFound):
+		respondError(w, http.StatusNotFound, "Expense not found")
+	case errors.Is(err, repository.ErrCommentNotFound):
+		respondError(w, http.StatusNotFound, "Comment not found")
+	default:
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommentHandler_Thread(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expenseRepo := repository.NewActualExpenseRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	handler := NewCommentHandler(repository.NewCommentRepository(db), expenseRepo, notificationRepo)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/actual-expenses/{id}/comments", handler.List)
+	mux.HandleFunc("POST /api/actual-expenses/{id}/comments", handler.Create)
+	mux.HandleFunc("PUT /api/actual-expenses/{id}/comments/{commentID}", handler.Update)
+	mux.HandleFunc("DELETE /api/actual-expenses/{id}/comments/{commentID}", handler.Delete)
+	mux.HandleFunc("GET /api/notifications", NewNotificationHandler(nil, nil, nil, nil, notificationRepo, nil).List)
+
+	expense, err := expenseRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Car repair", Source: "Garage", ActualAmount: 180,
+		ExpenseType: models.ExpenseTypeMisc, ReceiptDate: testReceiptDate(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+	path := "/api/actual-expenses/" + itoa(expense.ID) + "/comments"
+
+	do := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if userID != 0 {
+			req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("POST", path, `{"body": "  What was this $180 charge?  "}`, 0)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var comment models.Comment
+	if err := json.NewDecoder(rec.Body).Decode(&comment); err != nil {
+		t.Fatalf("Failed to decode comment: %v", err)
+	}
+	if comment.Body != "What was this $180 charge?" || comment.ActualExpenseID != expense.ID {
+		t.Errorf("Unexpected comment %+v", comment)
+	}
+
+	// Owners are not notified of their own comments
+	if notifications, err := notificationRepo.List(false); err != nil || len(notifications) != 0 {
+		t.Errorf("Expected no notification for the owner's comment, got %+v, %v", notifications, err)
+	}
+
+	// An allowance parent comments on the sub-account's expense, which notifies
+	// the sub-account and no one else
+	parent, err := repository.NewUserRepository(db).Create("parent@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	child, err := repository.NewAllowanceRepository(db).ForUser(parent.ID).Create(
+		&models.AllowanceRequest{Name: "Kid", MonthlyAmount: 40},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create allowance account: %v", err)
+	}
+	owned, err := expenseRepo.ForUser(child.ID).Create(&models.CreateActualExpenseRequest{
+		ItemName: "Comics", Source: "Kiosk", ActualAmount: 9,
+		ExpenseType: models.ExpenseTypeMisc, ReceiptDate: testReceiptDate(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+	ownedPath := "/api/actual-expenses/" + itoa(owned.ID) + "/comments"
+	if rec := do("POST", ownedPath, `{"body": "Again?"}`, parent.ID); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	for _, tc := range []struct {
+		userID int64
+		want   int
+	}{{child.ID, 1}, {parent.ID, 0}} {
+		var listed []models.Notification
+		rec := do("GET", "/api/notifications", "", tc.userID)
+		if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed) != tc.want {
+			t.Errorf("Expected %d notification(s) for user %d, got %+v, %v", tc.want, tc.userID, listed, err)
+		}
+		if tc.want == 1 && (listed[0].Kind != models.NotificationCommentAdded || !strings.Contains(listed[0].Title, "Comics")) {
+			t.Errorf("Expected a comment notification, got %+v", listed[0])
+		}
+	}
+	rec = do("GET", ownedPath, "", parent.ID)
+	var thread []models.Comment
+	if err := json.NewDecoder(rec.Body).Decode(&thread); err != nil || len(thread) != 1 || thread[0].UserID != parent.ID {
+		t.Errorf("Expected the parent's comment in the thread, got %+v, %v", thread, err)
+	}
+
+	rec = do("PUT", path+"/"+itoa(comment.ID), `{"body": "The brakes, never mind"}`, 0)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	rec = do("GET", path, "", 0)
+	var comments []models.Comment
+	if err := json.NewDecoder(rec.Body).Decode(&comments); err != nil {
+		t.Fatalf("Failed to decode comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Body != "The brakes, never mind" {
+		t.Errorf("Expected the edited comment, got %+v", comments)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		userID             int64
+		code               int
+	}{
+		{"POST", path, `{"body": "   "}`, 0, http.StatusBadRequest},
+		{"POST", path, `{"body": "` + strings.Repeat("a", models.MaxCommentLength+1) + `"}`, 0, http.StatusBadRequest},
+		{"POST", "/api/actual-expenses/999/comments", `{"body": "hi"}`, 0, http.StatusNotFound},
+		{"PUT", path + "/999", `{"body": "hi"}`, 0, http.StatusNotFound},
+		{"PUT", path + "/abc", `{"body": "hi"}`, 0, http.StatusBadRequest},
+		// Other users do not see the expense
+		{"GET", path, "", 2, http.StatusNotFound},
+		{"DELETE", path + "/" + itoa(comment.ID), "", 2, http.StatusNotFound},
+		{"POST", ownedPath, `{"body": "hi"}`, 99, http.StatusNotFound},
+	} {
+		if rec := do(tc.method, tc.path, tc.body, tc.userID); rec.Code != tc.code {
+			t.Errorf("%s %s as user %d: expected status %d, got %d: %s",
+				tc.method, tc.path, tc.userID, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := do("DELETE", path+"/"+itoa(comment.ID), "", 0); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	// Deleting the expense removes its thread
+	if _, err := repository.NewCommentRepository(db).Create(expense.ID, "Still here"); err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if err := expenseRepo.Delete(expense.ID); err != nil {
+		t.Fatalf("Failed to delete expense: %v", err)
+	}
+	var remaining int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM expense_comments WHERE actual_expense_id = ?`, expense.ID,
+	).Scan(&remaining); err != nil || remaining != 0 {
+		t.Errorf("Expected the comments to be deleted with the expense, got %d, %v", remaining, err)
+	}
+}
@@ -5,15 +5,25 @@ import (
 	"budget-tracker/internal/repository"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// DeleteConflictResponse is returned with 409 when a blocked delete has dependents
+type DeleteConflictResponse struct {
+	Error      string                     `json:"error"`
+	Dependents []repository.LinkedExpense `json:"dependents"`
+}
+
 // ExpectedExpenseListResponse represents the response for listing expected expenses with filter info
 type ExpectedExpenseListResponse struct {
 	Expenses []models.ExpectedExpense `json:"expenses"`
 	Filter   string                   `json:"filter"`
-	Count    int                      `json:"count"`
+	Page
 }
 
 // ExpectedExpenseHandler handles expected expense-related HTTP requests
@@ -27,38 +37,60 @@ func NewExpectedExpenseHandler(repo *repository.ExpectedExpenseRepository) *Expe
 }
 
 // List handles GET /api/expected-expenses
-// Supports optional query parameter: ?type=WEEKLY or ?type=MONTHLY (no MISC for expected expenses)
+// Supports optional query parameters: ?type=WEEKLY or ?type=MONTHLY (no MISC for expected expenses),
+// category (in any case), active (true for the active items, false for the
+// archived ones), q (search in item name and source) and limit/offset paging.
+// Count is the number of returned expenses, Total the number matching before paging.
 func (h *ExpectedExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
-	// Check for type filter query parameter
-	typeFilter := r.URL.Query().Get("type")
+	query := r.URL.Query()
+	var filter repository.ExpenseFilter
+	filterLabel := "ALL"
 
-	var expenses []models.ExpectedExpense
-	var err error
-	var filterLabel string
-
-	if typeFilter != "" {
-		// Normalize to lowercase
-		typeFilter = strings.ToLower(typeFilter)
+	// Check for type filter query parameter
+	if typeFilter := query.Get("type"); typeFilter != "" {
+		expenseType := models.NormalizeExpenseType(typeFilter)
 
 		// Validate the filter value - only weekly and monthly allowed for expected expenses
-		if typeFilter != string(models.ExpenseTypeWeekly) &&
-			typeFilter != string(models.ExpenseTypeMonthly) {
+		if expenseType != models.ExpenseTypeWeekly && expenseType != models.ExpenseTypeMonthly {
 			respondError(w, http.StatusBadRequest, "Invalid type filter. Must be weekly or monthly")
 			return
 		}
 
-		expenses, err = h.repo.GetByType(models.ExpenseType(typeFilter))
-		filterLabel = strings.ToUpper(typeFilter)
-	} else {
-		expenses, err = h.repo.GetAll()
-		filterLabel = "ALL"
+		filter.Type = expenseType
+		filterLabel = strings.ToUpper(string(expenseType))
+	}
+
+	filter.Category = strings.TrimSpace(query.Get("category"))
+
+	if v := query.Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "active must be true or false")
+			return
+		}
+		filter.Active = &active
 	}
 
+	if err := parseSearchAndPaging(query, &filter); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repo := h.repo.ForUser(requestUserID(r))
+	expenses, err := repo.List(filter)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch expected expenses")
 		return
 	}
 
+	total := len(expenses)
+	if filter.Limit > 0 || filter.Offset > 0 {
+		if total, err = repo.Count(filter); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to count expected expenses")
+			return
+		}
+	}
+
 	// Ensure we return an empty array instead of null
 	if expenses == nil {
 		expenses = []models.ExpectedExpense{}
@@ -67,12 +99,69 @@ func (h *ExpectedExpenseHandler) List(w http.ResponseWriter, r *http.Request) {
 	response := ExpectedExpenseListResponse{
 		Expenses: expenses,
 		Filter:   filterLabel,
-		Count:    len(expenses),
+		Page:     newPage(w, r, len(expenses), total, filter.Limit, filter.Offset),
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
+// ExpectedExpenseDueResponse lists the expected expenses due in a range of
+// days
+type ExpectedExpenseDueResponse struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Expenses []models.DueExpense `json:"expenses"`
+	Total    float64             `json:"total"`
+}
+
+// maxDueRangeDays bounds the range of Due
+const maxDueRangeDays = 366
+
+// Due handles GET /api/expected-expenses/due
+// Lists the expected expenses due from ?from= through ?to= (YYYY-MM-DD,
+// default: the current week, Monday to Sunday), once per due date, by due
+// date. Items without a due day or recurrence are never due.
+func (h *ExpectedExpenseHandler) Due(w http.ResponseWriter, r *http.Request) {
+	var filter repository.ExpenseFilter
+	if err := parseDateRange(r.URL.Query(), &filter); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	from := models.WeekStart(time.Now())
+	if filter.From != nil {
+		from = *filter.From
+	}
+	to := from.AddDate(0, 0, 6)
+	if filter.To != nil {
+		to = *filter.To
+	}
+	if to.Before(from) {
+		respondError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+	if to.Sub(from) >= maxDueRangeDays*24*time.Hour {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("range must not exceed %d days", maxDueRangeDays))
+		return
+	}
+
+	due, err := h.repo.ForUser(requestUserID(r)).Due(from, to)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch due expected expenses")
+		return
+	}
+
+	response := ExpectedExpenseDueResponse{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Expenses: due,
+	}
+	for _, e := range due {
+		response.Total += e.ExpectedAmount
+	}
+	response.Total = math.Round(response.Total*100) / 100
+	respondJSON(w, http.StatusOK, response)
+}
+
 // Create handles POST /api/expected-expenses
 func (h *ExpectedExpenseHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateExpectedExpenseRequest
@@ -86,7 +175,7 @@ func (h *ExpectedExpenseHandler) Create(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	expense, err := h.repo.Create(&req)
+	expense, err := h.repo.ForUser(requestUserID(r)).Create(&req)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create expected expense")
 		return
@@ -103,7 +192,7 @@ func (h *ExpectedExpenseHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expense, err := h.repo.GetByID(id)
+	expense, err := h.repo.ForUser(requestUserID(r)).GetByID(id)
 	if err != nil {
 		if errors.Is(err, repository.ErrExpenseNotFound) {
 			respondError(w, http.StatusNotFound, "Expense not found")
@@ -135,12 +224,17 @@ func (h *ExpectedExpenseHandler) Update(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	expense, err := h.repo.Update(id, &req)
+	expense, err := h.repo.ForUser(requestUserID(r)).Update(id, &req)
 	if err != nil {
 		if errors.Is(err, repository.ErrExpenseNotFound) {
 			respondError(w, http.StatusNotFound, "Expense not found")
 			return
 		}
+		// The schedule may not suit the type or amount once applied
+		if errors.Is(err, models.ErrInvalidDueDay) || errors.Is(err, models.ErrInvalidAutoGenerate) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "Failed to update expected expense")
 		return
 	}
@@ -148,20 +242,88 @@ func (h *ExpectedExpenseHandler) Update(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, expense)
 }
 
-// Delete handles DELETE /api/expected-expenses/{id}
-func (h *ExpectedExpenseHandler) Delete(w http.ResponseWriter, r *http.Request) {
+// Archive handles POST /api/expected-expenses/{id}/archive
+// An archived item keeps its history but no longer counts or comes due
+func (h *ExpectedExpenseHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, (*repository.ExpectedExpenseRepository).Archive)
+}
+
+// Unarchive handles POST /api/expected-expenses/{id}/unarchive
+func (h *ExpectedExpenseHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, (*repository.ExpectedExpenseRepository).Unarchive)
+}
+
+func (h *ExpectedExpenseHandler) setActive(
+	w http.ResponseWriter,
+	r *http.Request,
+	set func(*repository.ExpectedExpenseRepository, int64) (*models.ExpectedExpense, error),
+) {
 	id, err := parseIDFromPath(r)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid expense ID")
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
+	expense, err := set(h.repo.ForUser(requestUserID(r)), id)
+	if err != nil {
 		if errors.Is(err, repository.ErrExpenseNotFound) {
 			respondError(w, http.StatusNotFound, "Expense not found")
 			return
 		}
-		respondError(w, http.StatusInternalServerError, "Failed to delete expected expense")
+		respondError(w, http.StatusInternalServerError, "Failed to update expected expense")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, expense)
+}
+
+// Delete handles DELETE /api/expected-expenses/{id}
+// Linked actual expenses are handled per ?on_linked=:
+//   - unlink (default): clear their expected_expense_id
+//   - block: respond 409 listing them instead of deleting
+//   - reassign: move them to ?reassign_to={id}
+//
+// Unlink and reassign respond 409 when a linked expense is in a closed month.
+func (h *ExpectedExpenseHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid expense ID")
+		return
+	}
+
+	opts := repository.DeleteOptions{Mode: repository.DeleteUnlink}
+	if mode := r.URL.Query().Get("on_linked"); mode != "" {
+		opts.Mode = repository.DeleteMode(mode)
+		if !opts.Mode.IsValid() {
+			respondError(w, http.StatusBadRequest, "Invalid on_linked. Must be unlink, block or reassign")
+			return
+		}
+	}
+	if opts.Mode == repository.DeleteReassign {
+		opts.ReassignTo, err = strconv.ParseInt(r.URL.Query().Get("reassign_to"), 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "reassign_to must be an expected expense ID")
+			return
+		}
+	}
+
+	if err := h.repo.ForUser(requestUserID(r)).DeleteWithOptions(id, opts); err != nil {
+		var linked *repository.LinkedExpensesError
+		switch {
+		case errors.Is(err, repository.ErrExpenseNotFound):
+			respondError(w, http.StatusNotFound, "Expense not found")
+		case errors.As(err, &linked):
+			respondJSON(w, http.StatusConflict, DeleteConflictResponse{
+				Error:      "Expected expense has linked actual expenses",
+				Dependents: linked.Dependents,
+			})
+		case errors.Is(err, repository.ErrInvalidReassignTarget):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrMonthClosed):
+			respondError(w, http.StatusConflict, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to delete expected expense")
+		}
 		return
 	}
 
@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestExpenseList_Empty(t *testing.T) {
@@ -237,6 +239,47 @@ func TestExpenseList_InvalidFilter(t *testing.T) {
 	}
 }
 
+func TestExpenseList_SearchAndPaging(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	handler := NewExpectedExpenseHandler(repo)
+	mux := createTestMux(nil, handler)
+
+	for _, name := range []string{"Milk", "Oat Milk", "Bread"} {
+		if _, err := repo.Create(&models.CreateExpectedExpenseRequest{
+			ItemName:       name,
+			Source:         "Store",
+			ExpectedAmount: 5.00,
+			ExpenseType:    models.ExpenseTypeWeekly,
+		}); err != nil {
+			t.Fatalf("Failed to create test expense: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/expected-expenses?q=MILK&limit=1", nil)
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var response ExpectedExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Count != 1 {
+		t.Errorf("Expected count 1, got %d", response.Count)
+	}
+	if response.Total != 2 {
+		t.Errorf("Expected total 2, got %d", response.Total)
+	}
+}
+
 func TestExpenseCreate_Valid(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -337,8 +380,8 @@ func TestExpenseCreate_ItemNameTooLong(t *testing.T) {
 	handler := NewExpectedExpenseHandler(repo)
 	mux := createTestMux(nil, handler)
 
-	// Create item name with more than 200 characters
-	longName := strings.Repeat("a", 201)
+	// Create item name with more than the shared item name limit
+	longName := strings.Repeat("a", models.MaxItemNameLength+1)
 
 	reqBody := models.CreateExpectedExpenseRequest{
 		ItemName:       longName,
@@ -359,6 +402,39 @@ func TestExpenseCreate_ItemNameTooLong(t *testing.T) {
 	}
 }
 
+func TestExpenseCreate_ItemNameAtSharedLimit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	handler := NewExpectedExpenseHandler(repo)
+	mux := createTestMux(nil, handler)
+
+	// Names accepted for actual expenses must also be valid for expected expenses
+	reqBody := models.CreateExpectedExpenseRequest{
+		ItemName:       strings.Repeat("a", models.MaxItemNameLength),
+		Source:         "Test Source",
+		ExpectedAmount: 100.00,
+		ExpenseType:    models.ExpenseTypeWeekly,
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/expected-expenses", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf(
+			"Expected status %d, got %d. Body: %s",
+			http.StatusCreated,
+			rec.Code,
+			rec.Body.String(),
+		)
+	}
+}
+
 func TestExpenseCreate_InvalidSource(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -503,9 +579,9 @@ func TestExpenseCreate_InvalidExpenseType(t *testing.T) {
 	}{
 		{"empty", ""},
 		{"invalid", "DAILY"},
-		{"uppercase", "WEEKLY"},
-		{"mixed case", "Weekly"},
+		{"whitespace only", "   "},
 		{"misc not allowed", "misc"}, // misc is not allowed for expected expenses
+		{"misc not allowed uppercase", "MISC"},
 	}
 
 	for _, tc := range testCases {
@@ -898,6 +974,155 @@ func TestExpenseDelete_Exists(t *testing.T) {
 	}
 }
 
+func TestExpenseDelete_LinkedActualExpenses(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		deleted        bool
+		linkedTo       string // "none", "original" or "target"
+	}{
+		{"default unlinks", "", http.StatusNoContent, true, "none"},
+		{"unlink", "?on_linked=unlink", http.StatusNoContent, true, "none"},
+		{"block", "?on_linked=block", http.StatusConflict, false, "original"},
+		{"reassign", "?on_linked=reassign&reassign_to=TARGET", http.StatusNoContent, true, "target"},
+		{"reassign to self", "?on_linked=reassign&reassign_to=SELF", http.StatusBadRequest, false, "original"},
+		{"reassign to missing", "?on_linked=reassign&reassign_to=999999", http.StatusBadRequest, false, "original"},
+		{"reassign without target", "?on_linked=reassign", http.StatusBadRequest, false, "original"},
+		{"unknown mode", "?on_linked=cascade", http.StatusBadRequest, false, "original"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			repo := repository.NewExpectedExpenseRepository(db)
+			actualRepo := repository.NewActualExpenseRepository(db)
+			mux := createTestMux(nil, NewExpectedExpenseHandler(repo))
+
+			original, err := repo.Create(&models.CreateExpectedExpenseRequest{
+				ItemName: "Milk", Source: "Publix", ExpectedAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create expected expense: %v", err)
+			}
+			target, err := repo.Create(&models.CreateExpectedExpenseRequest{
+				ItemName: "Oat Milk", Source: "Publix", ExpectedAmount: 5, ExpenseType: models.ExpenseTypeWeekly,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create expected expense: %v", err)
+			}
+			linked, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+				ItemName: "Milk", Source: "Publix", ActualAmount: 4,
+				ExpenseType: models.ExpenseTypeWeekly, ExpectedExpenseID: &original.ID,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create actual expense: %v", err)
+			}
+
+			query := strings.NewReplacer("TARGET", itoa(target.ID), "SELF", itoa(original.ID)).
+				Replace(tc.query)
+			req := httptest.NewRequest("DELETE", "/api/expected-expenses/"+itoa(original.ID)+query, nil)
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d. Body: %s", tc.expectedStatus, rec.Code, rec.Body.String())
+			}
+
+			if tc.expectedStatus == http.StatusConflict {
+				var response DeleteConflictResponse
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if len(response.Dependents) != 1 || response.Dependents[0].ID != linked.ID {
+					t.Errorf("Expected dependent %d, got %+v", linked.ID, response.Dependents)
+				}
+			}
+
+			_, err = repo.GetByID(original.ID)
+			if deleted := err != nil; deleted != tc.deleted {
+				t.Errorf("Expected deleted=%v, got %v", tc.deleted, deleted)
+			}
+
+			stored, err := actualRepo.GetByID(linked.ID)
+			if err != nil {
+				t.Fatalf("Linked actual expense must never be deleted: %v", err)
+			}
+			var want *int64
+			switch tc.linkedTo {
+			case "original":
+				want = &original.ID
+			case "target":
+				want = &target.ID
+			}
+			if (want == nil) != (stored.ExpectedExpenseID == nil) ||
+				(want != nil && *want != *stored.ExpectedExpenseID) {
+				t.Errorf("Expected link %v, got %v", want, stored.ExpectedExpenseID)
+			}
+		})
+	}
+}
+
+// TestExpenseDelete_ClosedMonth verifies linked expenses of a closed month
+// are neither unlinked nor reassigned
+func TestExpenseDelete_ClosedMonth(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	actualRepo := repository.NewActualExpenseRepository(db)
+	budgetRepo := repository.NewBudgetRepository(db)
+	mux := createTestMux(nil, NewExpectedExpenseHandler(repo))
+
+	original, err := repo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ExpectedAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	target, err := repo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Oat Milk", Source: "Publix", ExpectedAmount: 5, ExpenseType: models.ExpenseTypeWeekly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	linked, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+		ReceiptDate: testReceiptDate(), ExpectedExpenseID: &original.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create actual expense: %v", err)
+	}
+	budget, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: linked.Month, Year: linked.Year, Amount: 500, NotificationThreshold: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	if _, err := budgetRepo.Close(budget.ID); err != nil {
+		t.Fatalf("Failed to close month: %v", err)
+	}
+
+	for _, query := range []string{"", "?on_linked=reassign&reassign_to=" + itoa(target.ID)} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/expected-expenses/"+itoa(original.ID)+query, nil))
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%q: expected status %d, got %d. Body: %s", query, http.StatusConflict, rec.Code, rec.Body.String())
+		}
+	}
+
+	if _, err := repo.GetByID(original.ID); err != nil {
+		t.Errorf("Expected the expected expense to be kept: %v", err)
+	}
+	stored, err := actualRepo.GetByID(linked.ID)
+	if err != nil || stored.ExpectedExpenseID == nil || *stored.ExpectedExpenseID != original.ID {
+		t.Errorf("Expected the link to be kept, got %+v (%v)", stored, err)
+	}
+}
+
 func TestExpenseDelete_NotFound(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -933,3 +1158,370 @@ func TestExpenseDelete_InvalidID(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 }
+
+func TestExpenseCreate_ExpenseTypeAnyCase(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	handler := NewExpectedExpenseHandler(repo)
+	mux := createTestMux(nil, handler)
+
+	testCases := []struct {
+		name        string
+		expenseType string
+		expected    models.ExpenseType
+	}{
+		{"uppercase", "WEEKLY", models.ExpenseTypeWeekly},
+		{"mixed case", "Monthly", models.ExpenseTypeMonthly},
+		{"surrounding whitespace", "  weekly ", models.ExpenseTypeWeekly},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := []byte(`{"item_name":"Milk","source":"Publix","expected_amount":4,` +
+				`"expense_type":"` + tc.expenseType + `"}`)
+			req := httptest.NewRequest("POST", "/api/expected-expenses", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf(
+					"Expected status %d, got %d. Body: %s",
+					http.StatusCreated,
+					rec.Code,
+					rec.Body.String(),
+				)
+			}
+
+			var expense models.ExpectedExpense
+			if err := json.NewDecoder(rec.Body).Decode(&expense); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if expense.ExpenseType != tc.expected {
+				t.Errorf("Expected expense type %q, got %q", tc.expected, expense.ExpenseType)
+			}
+		})
+	}
+}
+
+func TestExpenseDue(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	mux := createTestMux(nil, NewExpectedExpenseHandler(repo))
+
+	start := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	day := func(d int) *int { return &d }
+	for _, req := range []models.CreateExpectedExpenseRequest{
+		{ItemName: "Rent", Source: "Landlord", ExpectedAmount: 1000, ExpenseType: models.ExpenseTypeMonthly, DueDay: day(31)},
+		{ItemName: "Gym", Source: "Gym", ExpectedAmount: 20, ExpenseType: models.ExpenseTypeWeekly, DueDay: day(1)},
+		{
+			ItemName: "Babysitter", Source: "Sitter", ExpectedAmount: 60, ExpenseType: models.ExpenseTypeWeekly,
+			Recurrence: "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+		},
+		{
+			ItemName: "Insurance", Source: "Insurer", ExpectedAmount: 90, ExpenseType: models.ExpenseTypeMonthly,
+			Recurrence: "rrule:freq=monthly;byday=-1fr",
+		},
+		{ItemName: "Groceries", Source: "Market", ExpectedAmount: 100, ExpenseType: models.ExpenseTypeWeekly},
+	} {
+		req.StartsOn = &start
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/expected-expenses", bytes.NewReader(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/expected-expenses/due"+query, nil))
+		return rec
+	}
+
+	rec := get("?from=2026-02-01&to=2026-02-28")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ExpectedExpenseDueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var got []string
+	for _, e := range response.Expenses {
+		got = append(got, e.DueDate.Format("01-02")+" "+e.ItemName)
+	}
+	// The rent falls on the last day of February, groceries have no due day
+	want := []string{
+		"02-02 Gym", "02-09 Gym", "02-13 Babysitter", "02-16 Gym", "02-23 Gym",
+		"02-27 Babysitter", "02-27 Insurance", "02-28 Rent",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected due expenses %v, got %v", want, got)
+	}
+	if response.From != "2026-02-01" || response.To != "2026-02-28" || response.Total != 1290 {
+		t.Errorf("Unexpected range or total: %+v", response)
+	}
+	if response.Expenses[6].Recurrence != "FREQ=MONTHLY;BYDAY=-1FR" {
+		t.Errorf("Expected the recurrence normalized, got %q", response.Expenses[6].Recurrence)
+	}
+
+	// The current week by default
+	rec = get("")
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected the current week, got %d: %v", rec.Code, err)
+	}
+	if monday := models.WeekStart(time.Now()).Format("2006-01-02"); response.From != monday {
+		t.Errorf("Expected the week from %s, got %s", monday, response.From)
+	}
+
+	for _, query := range []string{"?from=2026-02-10&to=2026-02-01", "?from=2026-01-01&to=2027-06-01", "?from=soon"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestExpenseSchedule_Invalid(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	mux := createTestMux(nil, NewExpectedExpenseHandler(repo))
+
+	for _, body := range []string{
+		`{"item_name": "Gym", "source": "Gym", "expected_amount": 20, "expense_type": "weekly", "due_day": 9}`,
+		`{"item_name": "Rent", "source": "Landlord", "expected_amount": 1000, "expense_type": "monthly", "due_day": 32}`,
+		`{"item_name": "Rent", "source": "Landlord", "expected_amount": 1000, "expense_type": "monthly", "recurrence": "FREQ=DAILY"}`,
+		`{"item_name": "Rent", "source": "Landlord", "expected_amount": 1000, "expense_type": "monthly", "recurrence": "FREQ=WEEKLY;BYDAY=1MO"}`,
+		`{"item_name": "Rent", "source": "Landlord", "expected_amount": 1000, "expense_type": "monthly", "auto_generate": true}`,
+		`{"item_name": "Rent", "source": "Landlord", "expected_amount": 0, "expense_type": "monthly", "due_day": 1, "auto_generate": true}`,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/expected-expenses", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	day := 28
+	rent, err := repo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Rent", Source: "Landlord", ExpectedAmount: 1000, ExpenseType: models.ExpenseTypeMonthly, DueDay: &day,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+	// Day 28 is not a day of the week
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(
+		"PUT", "/api/expected-expenses/"+itoa(rent.ID), strings.NewReader(`{"expense_type": "weekly"}`),
+	))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(
+		"PUT", "/api/expected-expenses/"+itoa(rent.ID), strings.NewReader(`{"expense_type": "weekly", "due_day": 0}`),
+	))
+	var updated models.ExpectedExpense
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected the due day cleared, got %d: %v", rec.Code, err)
+	}
+	if updated.DueDay != nil {
+		t.Errorf("Expected no due day, got %d", *updated.DueDay)
+	}
+}
+
+func TestExpenseArchive(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	mux := createTestMux(nil, NewExpectedExpenseHandler(repo))
+
+	day := 1
+	rent, err := repo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Rent", Source: "Landlord", ExpectedAmount: 1000, ExpenseType: models.ExpenseTypeMonthly, DueDay: &day,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	streaming, err := repo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Streaming", Source: "Netflix", ExpectedAmount: 15, ExpenseType: models.ExpenseTypeMonthly, DueDay: &day,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	// Spending linked before the subscription was cancelled stays linked
+	paid := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	actualRepo := repository.NewActualExpenseRepository(db)
+	if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Streaming", Source: "Netflix", ActualAmount: 15, ExpenseType: models.ExpenseTypeMonthly,
+		ExpectedExpenseID: &streaming.ID, ReceiptDate: &paid,
+	}); err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", path, nil))
+		return rec
+	}
+	list := func(query string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/expected-expenses"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response ExpectedExpenseListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var names []string
+		for _, e := range response.Expenses {
+			names = append(names, e.ItemName)
+		}
+		return names
+	}
+
+	rec := post("/api/expected-expenses/" + itoa(streaming.ID) + "/archive")
+	var archived models.ExpectedExpense
+	if err := json.NewDecoder(rec.Body).Decode(&archived); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || archived.IsActive {
+		t.Fatalf("Expected the item to be archived, got %d %+v", rec.Code, archived)
+	}
+
+	if total, err := repo.GetMonthlyExpectedTotal(); err != nil || total != 1000 {
+		t.Errorf("Expected the archived item not to count, got %v %v", total, err)
+	}
+	if got := list("?active=false"); !slices.Equal(got, []string{"Streaming"}) {
+		t.Errorf("Expected the archived item, got %v", got)
+	}
+	if got := list("?active=true"); !slices.Equal(got, []string{"Rent"}) {
+		t.Errorf("Expected the active item, got %v", got)
+	}
+	if got := list(""); len(got) != 2 {
+		t.Errorf("Expected every item without a filter, got %v", got)
+	}
+	firstOfMonth := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	due, err := repo.Due(firstOfMonth, firstOfMonth)
+	if err != nil || len(due) != 1 || due[0].ID != rent.ID {
+		t.Errorf("Expected only the rent to be due, got %+v %v", due, err)
+	}
+
+	// The archived item is still shown with its spending, expecting nothing
+	report, err := actualRepo.Variance(3, 2026)
+	if err != nil {
+		t.Fatalf("Failed to get variance: %v", err)
+	}
+	if len(report.Items) != 2 || !report.Items[1].Archived || report.Items[1].Expected != 0 ||
+		report.Items[1].Actual != 15 || report.ExpectedTotal != 1000 {
+		t.Errorf("Unexpected variance %+v", report)
+	}
+
+	rec = post("/api/expected-expenses/" + itoa(streaming.ID) + "/unarchive")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if total, err := repo.GetMonthlyExpectedTotal(); err != nil || total != 1015 {
+		t.Errorf("Expected the item to count again, got %v %v", total, err)
+	}
+
+	if rec := post("/api/expected-expenses/999/archive"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing item, got %d", http.StatusNotFound, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/expected-expenses?active=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid filter, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestExpenseCategory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewExpectedExpenseRepository(db)
+	mux := createTestMux(nil, NewExpectedExpenseHandler(repo))
+
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(data)))
+		return rec
+	}
+	var ids []int64
+	for _, req := range []models.CreateExpectedExpenseRequest{
+		{ItemName: "Milk", Source: "Publix", ExpectedAmount: 5, ExpenseType: models.ExpenseTypeWeekly, Category: " Groceries "},
+		{ItemName: "Eggs", Source: "Publix", ExpectedAmount: 4, ExpenseType: models.ExpenseTypeWeekly, Category: "groceries"},
+		{ItemName: "Rent", Source: "Landlord", ExpectedAmount: 1000, ExpenseType: models.ExpenseTypeMonthly},
+	} {
+		rec := send("POST", "/api/expected-expenses", req)
+		var created models.ExpectedExpense
+		if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %v", http.StatusCreated, rec.Code, err)
+		}
+		ids = append(ids, created.ID)
+	}
+	if milk, _ := repo.GetByID(ids[0]); milk.Category != "Groceries" {
+		t.Errorf("Expected the category to be trimmed, got %q", milk.Category)
+	}
+
+	list := func(query string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/expected-expenses"+query, nil))
+		var response ExpectedExpenseListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var names []string
+		for _, e := range response.Expenses {
+			names = append(names, e.ItemName)
+		}
+		slices.Sort(names)
+		return names
+	}
+	// The category matches in any case, but as a whole
+	if got := list("?category=GROCERIES"); !slices.Equal(got, []string{"Eggs", "Milk"}) {
+		t.Errorf("Expected the groceries, got %v", got)
+	}
+	if got := list("?category=Groc"); len(got) != 0 {
+		t.Errorf("Expected no partial category match, got %v", got)
+	}
+
+	// An empty category clears it
+	rec := send("PUT", "/api/expected-expenses/"+itoa(ids[1]), map[string]any{"category": ""})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := list("?category=groceries"); !slices.Equal(got, []string{"Milk"}) {
+		t.Errorf("Expected the cleared item to be left out, got %v", got)
+	}
+
+	rec = send("POST", "/api/expected-expenses", models.CreateExpectedExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ExpectedAmount: 5, ExpenseType: models.ExpenseTypeWeekly,
+		Category: strings.Repeat("a", models.MaxCategoryLength+1),
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a long category, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Categories lead the hints of the receipt prompt
+	handler := NewReceiptHandler(nil, repo, nil, nil, nil, nil, 0)
+	hints := handler.budgetCategories(0)
+	slices.Sort(hints)
+	want := []string{"Eggs (weekly)", "Groceries: Milk (weekly)", "Rent (monthly)"}
+	if !slices.Equal(hints, want) {
+		t.Errorf("Expected hints %v, got %v", want, hints)
+	}
+}
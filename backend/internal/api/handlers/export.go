@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/export"
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ExportHandler exports expenses for other accounting tools
+type ExportHandler struct {
+	actualExpenseRepo *repository.ActualExpenseRepository
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(actualExpenseRepo *repository.ActualExpenseRepository) *ExportHandler {
+	return &ExportHandler{actualExpenseRepo: actualExpenseRepo}
+}
+
+// Beancount handles GET /api/export/beancount
+// Returns a year of actual expenses as a Beancount ledger. Query parameters:
+// year (default: current year), account (the funding account, default
+// Assets:Checking) and currency (default USD).
+func (h *ExportHandler) Beancount(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	year, err := parseOptionalInt(query, "year")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	if year < 2020 || year > 2100 {
+		respondError(w, http.StatusBadRequest, "year must be between 2020 and 2100")
+		return
+	}
+
+	opts := export.BeancountOptions{
+		FundingAccount: query.Get("account"),
+		Currency:       query.Get("currency"),
+		OpenDate:       time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := opts.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	expenses, err := h.actualExpenseRepo.ForUser(requestUserID(r)).List(repository.ExpenseFilter{Year: year})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch expenses")
+		return
+	}
+
+	var ledger bytes.Buffer
+	if err := export.WriteBeancount(&ledger, expenses, opts); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to write ledger")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="budget-%d.beancount"`, year),
+	)
+	w.Write(ledger.Bytes())
+}
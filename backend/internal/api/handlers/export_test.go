@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportHandler_Beancount(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewActualExpenseRepository(db)
+	for _, e := range []struct {
+		name string
+		date string
+	}{{"Coffee", "2025-03-02"}, {"Old coffee", "2024-12-30"}} {
+		date, _ := time.Parse("2006-01-02", e.date)
+		if _, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: e.name, Source: "Cafe", ActualAmount: 4.5,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &date, ReceiptNumber: 1,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	handler := NewExportHandler(repo)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/export/beancount", handler.Beancount)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export/beancount?year=2025&account=Assets:Cash", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "budget-2025.beancount") {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+
+	ledger := rec.Body.String()
+	for _, want := range []string{
+		"2025-01-01 open Assets:Cash",
+		`2025-03-02 * "Cafe" "Receipt #1"`,
+		"Expenses:Weekly          4.50 USD ; Coffee",
+		"Assets:Cash              -4.50 USD",
+	} {
+		if !strings.Contains(ledger, want) {
+			t.Errorf("Expected ledger to contain %q:\n%s", want, ledger)
+		}
+	}
+	if strings.Contains(ledger, "Old coffee") {
+		t.Errorf("Expected only 2025 expenses, got:\n%s", ledger)
+	}
+
+	for _, query := range []string{"?year=abc", "?year=1999", "?account=checking", "?currency=usd"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export/beancount"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
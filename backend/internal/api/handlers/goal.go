@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// GoalHandler handles multi-month spending goals
+type GoalHandler struct {
+	repo *repository.SpendingGoalRepository
+}
+
+// NewGoalHandler creates a new GoalHandler
+func NewGoalHandler(repo *repository.SpendingGoalRepository) *GoalHandler {
+	return &GoalHandler{repo: repo}
+}
+
+// List handles GET /api/goals
+func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
+	goals, err := h.repo.ForUser(requestUserID(r)).List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch goals")
+		return
+	}
+	respondJSON(w, http.StatusOK, goals)
+}
+
+// Create handles POST /api/goals
+// The goal starts in the current month when start_month and start_year are omitted.
+func (h *GoalHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.SpendingGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.StartMonth == 0 && req.StartYear == 0 {
+		now := time.Now().UTC()
+		req.StartMonth, req.StartYear = int(now.Month()), now.Year()
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	goal, err := h.repo.ForUser(requestUserID(r)).Create(&req)
+	if err != nil {
+		respondGoalError(w, err, "Failed to create goal")
+		return
+	}
+	respondJSON(w, http.StatusCreated, goal)
+}
+
+// Get handles GET /api/goals/{id}
+func (h *GoalHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid goal ID")
+		return
+	}
+
+	goal, err := h.repo.ForUser(requestUserID(r)).Get(id)
+	if err != nil {
+		respondGoalError(w, err, "Failed to fetch goal")
+		return
+	}
+	respondJSON(w, http.StatusOK, goal)
+}
+
+// Delete handles DELETE /api/goals/{id}
+func (h *GoalHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid goal ID")
+		return
+	}
+
+	if err := h.repo.ForUser(requestUserID(r)).Delete(id); err != nil {
+		respondGoalError(w, err, "Failed to delete goal")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Progress handles GET /api/goals/{id}/progress
+// Returns the spending of every month of the goal that has started, with the
+// months met, the current streak and the totals across them.
+func (h *GoalHandler) Progress(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid goal ID")
+		return
+	}
+
+	progress, err := h.repo.ForUser(requestUserID(r)).Progress(id, time.Now())
+	if err != nil {
+		respondGoalError(w, err, "Failed to fetch goal progress")
+		return
+	}
+	respondJSON(w, http.StatusOK, progress)
+}
+
+func respondGoalError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrGoalNotFound) {
+		respondError(w, http.StatusNotFound, "Goal not found")
+		return
+	}
+	respondError(w, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoalHandler(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewGoalHandler(repository.NewSpendingGoalRepository(db))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/goals", handler.List)
+	mux.HandleFunc("POST /api/goals", handler.Create)
+	mux.HandleFunc("GET /api/goals/{id}", handler.Get)
+	mux.HandleFunc("DELETE /api/goals/{id}", handler.Delete)
+	mux.HandleFunc("GET /api/goals/{id}/progress", handler.Progress)
+
+	do := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{
+		`{"name": "", "monthly_limit": 600, "months": 6}`,
+		`{"name": "Food", "monthly_limit": 0, "months": 6}`,
+		`{"name": "Food", "monthly_limit": 600, "months": 0}`,
+		`{"name": "Food", "monthly_limit": 600, "months": 6, "expense_type": "food"}`,
+		`{"name": "Food", "monthly_limit": 600, "months": 6, "start_month": 13, "start_year": 2025}`,
+	} {
+		if rec := do("POST", "/api/goals", body, 1); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	// The goal starts in the current month by default
+	rec := do("POST", "/api/goals", `{"name": " Food ", "monthly_limit": 600, "expense_type": "weekly", "months": 6}`, 1)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var goal models.SpendingGoal
+	if err := json.NewDecoder(rec.Body).Decode(&goal); err != nil {
+		t.Fatalf("Failed to decode goal: %v", err)
+	}
+	now := time.Now().UTC()
+	if goal.Name != "Food" || goal.ExpenseType == nil || *goal.ExpenseType != models.ExpenseTypeWeekly ||
+		goal.StartMonth != int(now.Month()) || goal.StartYear != now.Year() {
+		t.Errorf("Unexpected goal %+v", goal)
+	}
+	path := "/api/goals/" + itoa(goal.ID)
+
+	rec = do("GET", path+"/progress", "", 1)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var progress models.SpendingGoalProgress
+	if err := json.NewDecoder(rec.Body).Decode(&progress); err != nil {
+		t.Fatalf("Failed to decode progress: %v", err)
+	}
+	if progress.Status != models.GoalOnTrack || len(progress.Months) != 1 || progress.MonthsRemaining != 5 {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+
+	// Other users cannot see or delete the goal
+	if rec := do("GET", path, "", 2); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := do("DELETE", path, "", 2); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	if rec := do("DELETE", path, "", 1); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = do("GET", "/api/goals", "", 1)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected no goals, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds the database ping of the readiness check
+const readyTimeout = 5 * time.Second
+
+// Readiness statuses
+const (
+	readyStatusReady    = "ready"
+	readyStatusDegraded = "degraded"
+	readyStatusNotReady = "not_ready"
+)
+
+// HealthHandler reports whether the server can serve requests
+type HealthHandler struct {
+	db        *repository.DB
+	aiMonitor *ai.HealthMonitor
+}
+
+// NewHealthHandler creates a new HealthHandler
+// aiMonitor is nil when no AI provider is configured.
+func NewHealthHandler(db *repository.DB, aiMonitor *ai.HealthMonitor) *HealthHandler {
+	return &HealthHandler{db: db, aiMonitor: aiMonitor}
+}
+
+// readiness is the response of the readiness check
+type readiness struct {
+	Status   string             `json:"status"`
+	Database string             `json:"database"`
+	AI       *ai.ProviderHealth `json:"ai"`
+}
+
+// Ready handles GET /readyz
+// Responds 503 when the database is unreachable. An AI provider that is out
+// of quota or rejects its key only degrades the status, since everything but
+// receipt processing still works; pass ?require=ai to respond 503 then too.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	resp := readiness{Status: readyStatusReady, Database: "ok"}
+	status := http.StatusOK
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		resp.Status = readyStatusNotReady
+		resp.Database = "unreachable"
+		status = http.StatusServiceUnavailable
+	}
+
+	aiHealthy := false
+	if h.aiMonitor != nil {
+		health := h.aiMonitor.Health()
+		resp.AI = &health
+		aiHealthy = health.Healthy()
+	}
+	if !aiHealthy && resp.Status == readyStatusReady {
+		resp.Status = readyStatusDegraded
+	}
+	if !aiHealthy && r.URL.Query().Get("require") == "ai" {
+		status = http.StatusServiceUnavailable
+	}
+
+	respondJSON(w, status, resp)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"budget-tracker/internal/services/ai"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler_Ready(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	provider := &fakeReceiptProvider{result: &ai.ReceiptProcessingResult{}}
+	monitor := ai.NewHealthMonitor(provider)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /readyz", NewHealthHandler(db, monitor).Ready)
+	mux.HandleFunc("GET /api/admin/ai", NewAdminHandler(nil, nil, nil, nil, nil, monitor).AIHealth)
+
+	ready := func(path string) (int, readiness) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var resp readiness
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return rec.Code, resp
+	}
+
+	monitor.ProcessReceiptDocument(context.Background(), "", "application/pdf", nil)
+	if code, resp := ready("/readyz?require=ai"); code != http.StatusOK || resp.Status != "ready" || resp.AI.Status != ai.HealthOK {
+		t.Errorf("Expected ready, got %d %+v", code, resp)
+	}
+
+	// Running out of credit degrades the server but keeps it in service
+	provider.err = ai.ErrQuotaExhausted
+	monitor.ProcessReceiptDocument(context.Background(), "", "application/pdf", nil)
+	if code, resp := ready("/readyz"); code != http.StatusOK || resp.Status != "degraded" || resp.AI.Status != ai.HealthExhausted {
+		t.Errorf("Expected degraded, got %d %+v", code, resp)
+	}
+	if code, _ := ready("/readyz?require=ai"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d when the AI provider is required, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/ai", nil))
+	var health ai.ProviderHealth
+	if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+		t.Fatalf("Failed to decode health: %v", err)
+	}
+	if rec.Code != http.StatusOK || health.Status != ai.HealthExhausted || health.LastFailureAt == nil {
+		t.Errorf("Expected the exhausted provider on the admin endpoint, got %d %+v", rec.Code, health)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/plugins"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/importer"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ImportFileKey is the multipart form field holding the exported CSV
+	ImportFileKey = "file"
+	// ImportMappingsKey is the multipart form field holding the reviewed
+	// category mappings as a JSON array
+	ImportMappingsKey = "mappings"
+)
+
+// ImportHandler imports transaction exports from other budgeting apps
+type ImportHandler struct {
+	repo *repository.ImportRepository
+}
+
+// NewImportHandler creates a new ImportHandler
+func NewImportHandler(repo *repository.ImportRepository) *ImportHandler {
+	return &ImportHandler{repo: repo}
+}
+
+// ImportPreviewResponse is the mapping review for an export
+type ImportPreviewResponse struct {
+	Format       importer.Format            `json:"format"`
+	Transactions []importer.Transaction     `json:"transactions"`
+	Categories   []importer.CategorySummary `json:"categories"`
+	Skipped      int                        `json:"skipped"`
+}
+
+// ImportResponse summarizes a completed import
+type ImportResponse struct {
+	*repository.ImportResult
+	// Skipped counts inflows, transfers and transactions in skipped categories
+	Skipped int `json:"skipped"`
+}
+
+// Preview handles POST /api/import/{format}/preview
+// Parses the uploaded export without saving anything and suggests how each
+// category maps onto expense types, for the user to review before importing.
+func (h *ImportHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	format, parsed, ok := h.parseUpload(w, r)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ImportPreviewResponse{
+		Format:       format,
+		Transactions: parsed.Transactions,
+		Categories:   importer.Summarize(parsed.Transactions),
+		Skipped:      parsed.Skipped,
+	})
+}
+
+// Import handles POST /api/import/{format}
+// Takes the same upload as Preview plus the reviewed category mappings and
+// creates the expenses in one transaction. Categories missing from the
+// mappings use the suggested mapping.
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	format, parsed, ok := h.parseUpload(w, r)
+	if !ok {
+		return
+	}
+
+	var mappings []importer.CategoryMapping
+	if raw := strings.TrimSpace(r.FormValue(ImportMappingsKey)); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mappings); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid mappings: must be a JSON array")
+			return
+		}
+	}
+
+	groups, skipped, err := importer.Plan(format, parsed.Transactions, mappings)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, g := range groups {
+		if err := plugins.ProcessExpenses(r.Context(), g.Expenses); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for i := range g.Expenses {
+			if err := g.Expenses[i].Validate(); err != nil {
+				respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	result, err := h.repo.ForUser(requestUserID(r)).Import(groups)
+	if err != nil {
+		if errors.Is(err, models.ErrMonthClosed) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to import expenses")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, ImportResponse{
+		ImportResult: result,
+		Skipped:      parsed.Skipped + skipped,
+	})
+}
+
+// parseUpload reads the format from the path and parses the uploaded export,
+// writing an error response and returning ok=false on failure
+func (h *ImportHandler) parseUpload(
+	w http.ResponseWriter,
+	r *http.Request,
+) (importer.Format, *importer.ParseResult, bool) {
+	format, err := importer.ParseFormat(r.PathValue("format"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return "", nil, false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, "Export file too large (max 10MB)")
+			return "", nil, false
+		}
+		respondError(w, http.StatusBadRequest, "Failed to parse form data")
+		return "", nil, false
+	}
+
+	file, _, err := r.FormFile(ImportFileKey)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "No export file provided. Use form field 'file'")
+		return "", nil, false
+	}
+	defer file.Close()
+
+	parsed, err := importer.Parse(format, file)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return "", nil, false
+	}
+	return format, parsed, true
+}
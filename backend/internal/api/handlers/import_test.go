@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"budget-tracker/internal/repository"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testMintExport = `"Date","Description","Original Description","Amount","Transaction Type","Category","Account Name","Labels","Notes"
+"10/01/2025","Power Co","POWER CO","80.00","debit","Utilities","Checking","",""
+"10/02/2025","Shell","SHELL OIL 1234","45.10","debit","Gas & Fuel","Visa","",""
+"10/04/2025","Paycheck","ACME PAYROLL","2500.00","credit","Paycheck","Checking","",""
+"11/01/2025","Power Co","POWER CO","100.00","debit","Utilities","Checking","",""
+`
+
+// newImportRequest builds a multipart upload of an export with optional mappings
+func newImportRequest(t *testing.T, target, export, mappings string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(ImportFileKey, "export.csv")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write([]byte(export))
+	if mappings != "" {
+		writer.WriteField(ImportMappingsKey, mappings)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", target, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestImportHandler(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewImportHandler(repository.NewImportRepository(db))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/import/{format}/preview", handler.Preview)
+	mux.HandleFunc("POST /api/import/{format}", handler.Import)
+
+	// Preview saves nothing and suggests a mapping per category
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newImportRequest(t, "/api/import/mint/preview", testMintExport, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var preview ImportPreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+		t.Fatalf("Failed to decode preview: %v", err)
+	}
+	if len(preview.Transactions) != 3 || preview.Skipped != 1 || len(preview.Categories) != 2 {
+		t.Fatalf("Unexpected preview: %+v", preview)
+	}
+	if c := preview.Categories[0]; c.Category != "Utilities" || !c.CreateExpected {
+		t.Errorf("Expected Utilities first with an expected expense, got %+v", c)
+	}
+
+	actualRepo := repository.NewActualExpenseRepository(db)
+	if expenses, _ := actualRepo.GetAll(); len(expenses) != 0 {
+		t.Fatalf("Preview must not save expenses, found %d", len(expenses))
+	}
+
+	// Import with the reviewed mappings
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, newImportRequest(t, "/api/import/mint", testMintExport,
+		`[{"category":"Gas & Fuel","expense_type":"misc"}]`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var result ImportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode import result: %v", err)
+	}
+	if result.ExpenseCount != 3 || result.Skipped != 1 || len(result.ExpectedExpenses) != 1 {
+		t.Fatalf("Unexpected import result: %+v", result)
+	}
+	utilities := result.ExpectedExpenses[0]
+	if utilities.ItemName != "Utilities" || utilities.ExpectedAmount != 90 {
+		t.Errorf("Expected a Utilities expected expense of 90, got %+v", utilities)
+	}
+
+	expenses, err := actualRepo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to list expenses: %v", err)
+	}
+	receipts := make(map[int64]bool)
+	for _, e := range expenses {
+		receipts[e.ReceiptNumber] = true
+		switch e.Source {
+		case "Power Co":
+			if e.ExpectedExpenseID == nil || *e.ExpectedExpenseID != utilities.ID {
+				t.Errorf("Expected utility expense linked to %d, got %v", utilities.ID, e.ExpectedExpenseID)
+			}
+		case "Shell":
+			if e.ExpenseType != "misc" || e.ExpectedExpenseID != nil {
+				t.Errorf("Expected unlinked misc gas expense, got %+v", e)
+			}
+		}
+	}
+	if len(receipts) != 3 {
+		t.Errorf("Expected each imported expense on its own receipt, got %d receipts", len(receipts))
+	}
+}
+
+func TestImportHandler_BadRequests(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewImportHandler(repository.NewImportRepository(db))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/import/{format}", handler.Import)
+
+	tests := []struct {
+		name     string
+		target   string
+		export   string
+		mappings string
+	}{
+		{"unknown format", "/api/import/quicken", testMintExport, ""},
+		{"wrong columns", "/api/import/ynab", testMintExport, ""},
+		{"invalid mappings json", "/api/import/mint", testMintExport, "{"},
+		{
+			"tax expected expense", "/api/import/mint", testMintExport,
+			`[{"category":"Utilities","expense_type":"tax","create_expected":true}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, newImportRequest(t, tt.target, tt.export, tt.mappings))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format accepted by date query parameters
+const dateLayout = "2006-01-02"
+
+// monthLayout is the format accepted by month query parameters
+const monthLayout = "2006-01"
+
+// parseSearchAndPaging reads the q, limit and offset query parameters into filter
+func parseSearchAndPaging(query url.Values, filter *repository.ExpenseFilter) error {
+	filter.Search = strings.TrimSpace(query.Get("q"))
+
+	var err error
+	filter.Limit, filter.Offset, err = parsePaging(query)
+	return err
+}
+
+// parsePaging reads the limit and offset query parameters
+func parsePaging(query url.Values) (int, int, error) {
+	limit, err := parseOptionalInt(query, "limit")
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := parseOptionalInt(query, "offset")
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit < 0 || offset < 0 {
+		return 0, 0, errors.New("limit and offset must not be negative")
+	}
+	if limit > repository.MaxListLimit {
+		return 0, 0, errors.New("limit must not exceed " + strconv.Itoa(repository.MaxListLimit))
+	}
+	return limit, offset, nil
+}
+
+// parseDateRange reads the from and to (YYYY-MM-DD) query parameters into filter
+func parseDateRange(query url.Values, filter *repository.ExpenseFilter) error {
+	var err error
+	if filter.From, err = parseOptionalDate(query, "from"); err != nil {
+		return err
+	}
+	if filter.To, err = parseOptionalDate(query, "to"); err != nil {
+		return err
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return errors.New("to must not be before from")
+	}
+	return nil
+}
+
+// parseMonthYear reads the month and year query parameters into filter. A
+// year lists the whole year; a month is only accepted with its year.
+func parseMonthYear(query url.Values, filter *repository.ExpenseFilter) error {
+	var err error
+	if filter.Month, err = parseOptionalInt(query, "month"); err != nil {
+		return err
+	}
+	if filter.Year, err = parseOptionalInt(query, "year"); err != nil {
+		return err
+	}
+	if filter.Month != 0 && filter.Year == 0 {
+		return errors.New("month must be given with year")
+	}
+	return nil
+}
+
+// parseTags reads the comma-separated tags query parameter, e.g.
+// tags=work,travel
+func parseTags(query url.Values) ([]string, error) {
+	if query.Get("tags") == "" {
+		return nil, nil
+	}
+	return models.NormalizeTags(strings.Split(query.Get("tags"), ","))
+}
+
+// parseOptionalInt returns 0 when the parameter is absent
+func parseOptionalInt(query url.Values, key string) (int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New(key + " must be an integer")
+	}
+	return n, nil
+}
+
+// parseMonthQuery reads the month and year query parameters, defaulting to
+// the current month
+func parseMonthQuery(query url.Values) (int, int, error) {
+	now := time.Now()
+	month, err := parseOptionalInt(query, "month")
+	if err != nil {
+		return 0, 0, err
+	}
+	year, err := parseOptionalInt(query, "year")
+	if err != nil {
+		return 0, 0, err
+	}
+	if month == 0 {
+		month = int(now.Month())
+	}
+	if year == 0 {
+		year = now.Year()
+	}
+	if month < 1 || month > 12 {
+		return 0, 0, models.ErrInvalidMonth
+	}
+	return month, year, nil
+}
+
+// parseOptionalDate returns nil when the parameter is absent
+func parseOptionalDate(query url.Values, key string) (*time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, errors.New(key + " must be a date in YYYY-MM-DD format")
+	}
+	return &date, nil
+}
+
+// parseOptionalMonth returns the first day of the month, or nil when the
+// parameter is absent
+func parseOptionalMonth(query url.Values, key string) (*time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	month, err := time.Parse(monthLayout, value)
+	if err != nil {
+		return nil, errors.New(key + " must be a month in YYYY-MM format")
+	}
+	return &month, nil
+}
+
+// includeExpectedExpense embeds each actual expense's linked expected expense
+const includeExpectedExpense = "expected_expense"
+
+// parseIncludes reads the comma-separated include query parameter, rejecting
+// related records that cannot be embedded
+func parseIncludes(query url.Values, supported ...string) (map[string]bool, error) {
+	includes := make(map[string]bool)
+	for _, name := range strings.Split(query.Get("include"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(supported, name) {
+			return nil, fmt.Errorf("unsupported include %q (supported: %s)", name, strings.Join(supported, ", "))
+		}
+		includes[name] = true
+	}
+	return includes, nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"budget-tracker/internal/services/merchants"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// MerchantHandler suggests expense sources from the merchants near the client
+type MerchantHandler struct {
+	directory merchants.Directory
+}
+
+// NewMerchantHandler creates a new MerchantHandler; a nil directory disables
+// the suggestions
+func NewMerchantHandler(directory merchants.Directory) *MerchantHandler {
+	return &MerchantHandler{directory: directory}
+}
+
+// Nearby handles GET /api/merchants/nearby
+// Returns the merchants within radius meters (default 150, at most 1000) of
+// lat and lon, nearest first, so the client can offer their names as the
+// source of a new expense. The location is only forwarded to the directory.
+func (h *MerchantHandler) Nearby(w http.ResponseWriter, r *http.Request) {
+	if h.directory == nil {
+		respondError(w, http.StatusServiceUnavailable, "Merchant directory not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	lat, err := parseCoordinate(query, "lat", 90)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lon, err := parseCoordinate(query, "lon", 180)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	radius, err := parseOptionalInt(query, "radius")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if radius == 0 {
+		radius = merchants.DefaultRadius
+	}
+	if radius < 0 || radius > merchants.MaxRadius {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("radius must be between 1 and %d", merchants.MaxRadius))
+		return
+	}
+
+	nearby, err := h.directory.Nearby(r.Context(), lat, lon, radius)
+	if errors.Is(err, merchants.ErrDirectoryUnavailable) {
+		log.Printf("Merchant lookup failed: %v", err)
+		respondError(w, http.StatusBadGateway, "Merchant directory unavailable")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to look up merchants")
+		return
+	}
+	respondJSON(w, http.StatusOK, nearby)
+}
+
+// parseCoordinate reads the required latitude or longitude key, which must be
+// within -limit and limit degrees
+func parseCoordinate(query url.Values, key string, limit float64) (float64, error) {
+	value, err := strconv.ParseFloat(query.Get(key), 64)
+	if err != nil || math.IsNaN(value) || math.Abs(value) > limit {
+		return 0, fmt.Errorf("%s must be a number between -%g and %g", key, limit, limit)
+	}
+	return value, nil
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"budget-tracker/internal/services/merchants"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeDirectory records the last search and returns merchants or err
+type fakeDirectory struct {
+	lat, lon  float64
+	radius    int
+	merchants []merchants.Merchant
+	err       error
+}
+
+func (d *fakeDirectory) Nearby(_ context.Context, lat, lon float64, radius int) ([]merchants.Merchant, error) {
+	d.lat, d.lon, d.radius = lat, lon, radius
+	return d.merchants, d.err
+}
+
+func TestMerchantHandler_Nearby(t *testing.T) {
+	directory := &fakeDirectory{merchants: []merchants.Merchant{
+		{Name: "Corner Market", Category: "convenience", Distance: 12},
+	}}
+	handler := NewMerchantHandler(directory)
+	get := func(h *MerchantHandler, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Nearby(rec, httptest.NewRequest("GET", "/api/merchants/nearby"+query, nil))
+		return rec
+	}
+
+	rec := get(handler, "?lat=49.2609&lon=-123.1139")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var nearby []merchants.Merchant
+	if err := json.NewDecoder(rec.Body).Decode(&nearby); err != nil {
+		t.Fatalf("Failed to decode merchants: %v", err)
+	}
+	if len(nearby) != 1 || nearby[0].Name != "Corner Market" {
+		t.Errorf("Unexpected merchants %+v", nearby)
+	}
+	if directory.lat != 49.2609 || directory.lon != -123.1139 || directory.radius != merchants.DefaultRadius {
+		t.Errorf("Searched %v,%v within %d, want the location within the default radius",
+			directory.lat, directory.lon, directory.radius)
+	}
+
+	if rec := get(handler, "?lat=1&lon=2&radius=500"); rec.Code != http.StatusOK || directory.radius != 500 {
+		t.Errorf("Expected radius 500 to be used, got status %d and radius %d", rec.Code, directory.radius)
+	}
+
+	for _, query := range []string{"", "?lat=1", "?lat=91&lon=0", "?lat=0&lon=-181", "?lat=x&lon=0",
+		"?lat=0&lon=0&radius=-1", "?lat=0&lon=0&radius=5000"} {
+		if rec := get(handler, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	directory.err = fmt.Errorf("%w: timeout", merchants.ErrDirectoryUnavailable)
+	if rec := get(handler, "?lat=0&lon=0"); rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected status %d when the directory fails, got %d", http.StatusBadGateway, rec.Code)
+	}
+
+	if rec := get(NewMerchantHandler(nil), "?lat=0&lon=0"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d without a directory, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"budget-tracker/internal/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMetricsDays = 7
+	maxMetricsDays     = 366
+)
+
+// MetricsHandler handles metrics-related HTTP requests
+type MetricsHandler struct {
+	repo *repository.MetricsRepository
+}
+
+// NewMetricsHandler creates a new MetricsHandler
+func NewMetricsHandler(repo *repository.MetricsRepository) *MetricsHandler {
+	return &MetricsHandler{repo: repo}
+}
+
+// FailureMetricsResponse represents failure counters over a range of days
+type FailureMetricsResponse struct {
+	From    string                    `json:"from"`
+	To      string                    `json:"to"`
+	Handler string                    `json:"handler,omitempty"`
+	Totals  map[string]int            `json:"totals"`
+	Daily   []repository.FailureCount `json:"daily"`
+}
+
+// Failures handles GET /api/metrics/failures
+// Supports ?handler= (e.g. receipts.process; default all) and ?days= (default 7, max 366).
+// Totals are keyed by error code.
+func (h *MetricsHandler) Failures(w http.ResponseWriter, r *http.Request) {
+	days := defaultMetricsDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxMetricsDays {
+			respondError(w, http.StatusBadRequest, "days must be between 1 and 366")
+			return
+		}
+		days = n
+	}
+	handler := r.URL.Query().Get("handler")
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -(days - 1))
+
+	counts, err := h.repo.FailureCounts(handler, from, to)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch failure metrics")
+		return
+	}
+
+	totals := make(map[string]int)
+	for _, c := range counts {
+		totals[c.Code] += c.Count
+	}
+	if counts == nil {
+		counts = []repository.FailureCount{}
+	}
+
+	respondJSON(w, http.StatusOK, FailureMetricsResponse{
+		From:    from.Format(dateLayout),
+		To:      to.Format(dateLayout),
+		Handler: handler,
+		Totals:  totals,
+		Daily:   counts,
+	})
+}
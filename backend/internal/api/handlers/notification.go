@@ -28,6 +28,49 @@ type BudgetStatusResponse struct {
 	PercentageUsed float64             `json:"percentage_used"`
 	Status         BudgetStatusType    `json:"status"`
 	Message        string              `json:"message"`
+	// IsDefault is set when CurrentBudget is the default budget because the
+	// month has no budget of its own
+	IsDefault bool `json:"is_default"`
+	// Rollover is what was left of the previous month's budget, when the
+	// budget rolls it over, and EffectiveAmount the budget amount with it;
+	// PercentageUsed and Status are against EffectiveAmount
+	Rollover        float64 `json:"rollover"`
+	EffectiveAmount float64 `json:"effective_amount"`
+	// CrossedThresholds are the budget's notification thresholds reached by
+	// PercentageUsed, lowest first
+	CrossedThresholds []float64 `json:"crossed_thresholds"`
+}
+
+// WeeklyStatusResponse compares the spending of a week with the weekly cap
+// of the budget of the month the week is asked for in
+type WeeklyStatusResponse struct {
+	WeekStart string `json:"week_start"` // Monday, YYYY-MM-DD
+	WeekEnd   string `json:"week_end"`   // Sunday, YYYY-MM-DD
+	// WeeklyLimit is 0 when the budget has no weekly cap
+	WeeklyLimit       float64          `json:"weekly_limit"`
+	TotalSpent        float64          `json:"total_spent"`
+	Remaining         float64          `json:"remaining"`
+	PercentageUsed    float64          `json:"percentage_used"`
+	Status            BudgetStatusType `json:"status"`
+	Message           string           `json:"message"`
+	CrossedThresholds []float64        `json:"crossed_thresholds"`
+}
+
+// SourceCapStatus is the spending of a month at a capped source with its
+// status, which is BudgetStatusOver once the cap is exceeded
+type SourceCapStatus struct {
+	models.SourceCapSpending
+	Status  BudgetStatusType `json:"status"`
+	Message string           `json:"message"`
+}
+
+// SourceStatusResponse lists the spending of a month at each capped source.
+// Exceeded counts the sources over their cap.
+type SourceStatusResponse struct {
+	Month    int               `json:"month"`
+	Year     int               `json:"year"`
+	Sources  []SourceCapStatus `json:"sources"`
+	Exceeded int               `json:"exceeded"`
 }
 
 // NotificationHandler handles notification-related HTTP requests
@@ -35,19 +78,71 @@ type NotificationHandler struct {
 	budgetRepo          *repository.BudgetRepository
 	expectedExpenseRepo *repository.ExpectedExpenseRepository
 	actualExpenseRepo   *repository.ActualExpenseRepository
+	settingsRepo        *repository.SettingsRepository
+	notificationRepo    *repository.NotificationRepository
+	sourceCapRepo       *repository.SourceCapRepository
 }
 
 // NewNotificationHandler creates a new NotificationHandler
+// settingsRepo is optional; when set, months without a budget fall back to
+// the default budget setting
 func NewNotificationHandler(
 	budgetRepo *repository.BudgetRepository,
 	expectedExpenseRepo *repository.ExpectedExpenseRepository,
 	actualExpenseRepo *repository.ActualExpenseRepository,
+	settingsRepo *repository.SettingsRepository,
+	notificationRepo *repository.NotificationRepository,
+	sourceCapRepo *repository.SourceCapRepository,
 ) *NotificationHandler {
 	return &NotificationHandler{
 		budgetRepo:          budgetRepo,
 		expectedExpenseRepo: expectedExpenseRepo,
 		actualExpenseRepo:   actualExpenseRepo,
+		settingsRepo:        settingsRepo,
+		notificationRepo:    notificationRepo,
+		sourceCapRepo:       sourceCapRepo,
+	}
+}
+
+// List handles GET /api/notifications
+// Returns the user's stored notifications newest first; pass ?unread=true
+// for unread only.
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.notificationRepo.ForUser(requestUserID(r)).List(unreadOnly)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
 	}
+
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	respondJSON(w, http.StatusOK, notifications)
+}
+
+// MarkRead handles POST /api/notifications/{id}/read
+// Another user's notification is not found.
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	notification, err := h.notificationRepo.ForUser(requestUserID(r)).MarkRead(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotificationNotFound) {
+			respondError(w, http.StatusNotFound, "Notification not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update notification")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, notification)
 }
 
 // BudgetStatus handles GET /api/notifications/budget-status
@@ -70,8 +165,22 @@ func (h *NotificationHandler) BudgetStatus(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	// Get budget for current month
-	budget, err := h.budgetRepo.GetByMonthYear(currentMonth, currentYear)
+	userID := requestUserID(r)
+
+	// Get budget for current month, falling back to the default budget
+	isDefault := false
+	budget, err := h.budgetRepo.ForUser(userID).GetByMonthYear(currentMonth, currentYear)
+	if errors.Is(err, repository.ErrBudgetNotFound) && h.settingsRepo != nil {
+		defaultBudget, defaultErr := h.settingsRepo.GetDefaultBudget()
+		switch {
+		case defaultErr == nil:
+			budget = defaultBudget.ForMonth(currentMonth, currentYear)
+			isDefault = true
+			err = nil
+		case !errors.Is(defaultErr, repository.ErrSettingNotFound):
+			err = defaultErr
+		}
+	}
 	if err != nil {
 		if errors.Is(err, repository.ErrBudgetNotFound) {
 			respondJSON(w, http.StatusOK, BudgetStatusResponse{
@@ -85,6 +194,7 @@ func (h *NotificationHandler) BudgetStatus(w http.ResponseWriter, r *http.Reques
 					now.Month().String(),
 					currentYear,
 				),
+				CrossedThresholds: []float64{},
 			})
 			return
 		}
@@ -93,7 +203,7 @@ func (h *NotificationHandler) BudgetStatus(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Calculate actual spending from actual_expenses table using the same summary logic
-	summary, err := h.actualExpenseRepo.GetMonthlySummary(currentMonth, currentYear)
+	summary, err := h.actualExpenseRepo.ForUser(userID).GetMonthlySummary(currentMonth, currentYear)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to calculate spending")
 		return
@@ -101,40 +211,151 @@ func (h *NotificationHandler) BudgetStatus(w http.ResponseWriter, r *http.Reques
 	totalSpent := summary.TotalActual
 
 	// Calculate expected total from expected_expenses
-	expectedTotal, err := h.expectedExpenseRepo.GetMonthlyExpectedTotal()
+	expectedTotal, err := h.expectedExpenseRepo.ForUser(userID).GetMonthlyExpectedTotal()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to calculate expected spending")
 		return
 	}
 
+	// Unspent money of the previous month raises the limit
+	rollover, err := h.budgetRepo.ForUser(userID).Rollover(budget)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to calculate budget rollover")
+		return
+	}
+	effectiveAmount := budget.Amount + rollover
+
 	// Calculate percentage used
 	percentageUsed := 0.0
-	if budget.Amount > 0 {
-		percentageUsed = (totalSpent / budget.Amount) * 100
+	if effectiveAmount > 0 {
+		percentageUsed = (totalSpent / effectiveAmount) * 100
 	}
 
 	// Determine status and message
-	status, message := h.determineStatus(
+	status, message := determineStatus(
 		percentageUsed,
 		budget.NotificationThreshold,
 		totalSpent,
-		budget.Amount,
+		effectiveAmount,
 	)
 
 	response := BudgetStatusResponse{
-		CurrentBudget:  budget,
-		TotalSpent:     totalSpent,
-		ExpectedTotal:  expectedTotal,
-		PercentageUsed: percentageUsed,
-		Status:         status,
-		Message:        message,
+		CurrentBudget:     budget,
+		TotalSpent:        totalSpent,
+		ExpectedTotal:     expectedTotal,
+		PercentageUsed:    percentageUsed,
+		Status:            status,
+		Message:           message,
+		IsDefault:         isDefault,
+		Rollover:          rollover,
+		EffectiveAmount:   effectiveAmount,
+		CrossedThresholds: budget.CrossedThresholds(percentageUsed),
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
+// WeeklyStatus handles GET /api/notifications/weekly-status
+// Returns the spending of the current week, or of the week containing
+// ?date=YYYY-MM-DD, against the weekly cap of that day's month's budget
+func (h *NotificationHandler) WeeklyStatus(w http.ResponseWriter, r *http.Request) {
+	day := time.Now()
+	date, err := parseOptionalDate(r.URL.Query(), "date")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if date != nil {
+		day = *date
+	}
+
+	userID := requestUserID(r)
+	weekStart := models.WeekStart(day)
+	response := WeeklyStatusResponse{
+		WeekStart:         weekStart.Format(dateLayout),
+		WeekEnd:           weekStart.AddDate(0, 0, 6).Format(dateLayout),
+		Status:            BudgetStatusSafe,
+		CrossedThresholds: []float64{},
+	}
+
+	response.TotalSpent, err = h.actualExpenseRepo.ForUser(userID).GetWeekTotal(weekStart)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to calculate spending")
+		return
+	}
+
+	budget, err := h.budgetRepo.ForUser(userID).GetByMonthYear(int(day.Month()), day.Year())
+	if err != nil && !errors.Is(err, repository.ErrBudgetNotFound) {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget")
+		return
+	}
+	if budget == nil || budget.WeeklyLimit == 0 {
+		response.Message = fmt.Sprintf("No weekly limit set for the week of %s", weekStart.Format("Jan 2"))
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+
+	response.WeeklyLimit = budget.WeeklyLimit
+	response.Remaining = budget.WeeklyLimit - response.TotalSpent
+	response.PercentageUsed = (response.TotalSpent / budget.WeeklyLimit) * 100
+	response.Status, response.Message = periodStatus(
+		"weekly",
+		response.PercentageUsed,
+		budget.NotificationThreshold,
+		response.TotalSpent,
+		budget.WeeklyLimit,
+	)
+	response.CrossedThresholds = budget.CrossedThresholds(response.PercentageUsed)
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// SourceStatus handles GET /api/notifications/source-status
+// Returns the spending of the current month, or of ?month=&year=, at each
+// source with a cap, flagging the sources whose cap is exceeded. Warnings
+// start at the default notification threshold.
+func (h *NotificationHandler) SourceStatus(w http.ResponseWriter, r *http.Request) {
+	month, year, err := parseMonthQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	spending, err := h.sourceCapRepo.ForUser(requestUserID(r)).Spending(month, year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to calculate spending by source")
+		return
+	}
+
+	response := SourceStatusResponse{Month: month, Year: year, Sources: make([]SourceCapStatus, len(spending))}
+	for i, s := range spending {
+		status, message := periodStatus(
+			s.Cap.Source,
+			s.PercentageUsed,
+			models.DefaultNotificationThreshold,
+			s.Spent,
+			s.Cap.MonthlyLimit,
+		)
+		response.Sources[i] = SourceCapStatus{SourceCapSpending: s, Status: status, Message: message}
+		if s.Exceeded {
+			response.Exceeded++
+		}
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
 // determineStatus determines the budget status based on percentage used
-func (h *NotificationHandler) determineStatus(
+func determineStatus(
+	percentageUsed, threshold float64,
+	spent, budget float64,
+) (BudgetStatusType, string) {
+	return periodStatus("monthly", percentageUsed, threshold, spent, budget)
+}
+
+// periodStatus determines the status of the monthly or weekly budget, or of
+// the cap of a source when period is its name
+func periodStatus(
+	period string,
 	percentageUsed, threshold float64,
 	spent, budget float64,
 ) (BudgetStatusType, string) {
@@ -143,23 +364,27 @@ func (h *NotificationHandler) determineStatus(
 	switch {
 	case percentageUsed > 100:
 		return BudgetStatusOver, fmt.Sprintf(
-			"You've exceeded your monthly budget by $%.2f",
+			"You've exceeded your %s budget by $%.2f",
+			period,
 			spent-budget,
 		)
 	case percentageUsed >= 90:
 		return BudgetStatusDanger, fmt.Sprintf(
-			"You've used %.0f%% of your monthly budget - approaching limit!",
+			"You've used %.0f%% of your %s budget - approaching limit!",
 			percentageUsed,
+			period,
 		)
 	case percentageUsed >= thresholdPercent:
 		return BudgetStatusWarning, fmt.Sprintf(
-			"You've used %.0f%% of your monthly budget",
+			"You've used %.0f%% of your %s budget",
 			percentageUsed,
+			period,
 		)
 	default:
 		return BudgetStatusSafe, fmt.Sprintf(
-			"You've used %.0f%% of your monthly budget - on track!",
+			"You've used %.0f%% of your %s budget - on track!",
 			percentageUsed,
+			period,
 		)
 	}
 }
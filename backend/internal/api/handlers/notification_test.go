@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotifications_ListAndMarkRead(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewNotificationRepository(db)
+	handler := NewNotificationHandler(nil, nil, nil, nil, repo, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/notifications", handler.List)
+	mux.HandleFunc("POST /api/notifications/{id}/read", handler.MarkRead)
+
+	first, err := repo.Create(&models.Notification{
+		Kind: models.NotificationBudgetCreated, Title: "Budget created", Message: "First",
+		Link: "/budget?month=11&year=2025",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notification: %v", err)
+	}
+	if _, err := repo.Create(&models.Notification{
+		Kind: models.NotificationBudgetCreated, Title: "Budget created", Message: "Second",
+	}); err != nil {
+		t.Fatalf("Failed to create notification: %v", err)
+	}
+
+	list := func(query string) []models.Notification {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/notifications"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var notifications []models.Notification
+		if err := json.NewDecoder(rec.Body).Decode(&notifications); err != nil {
+			t.Fatalf("Failed to decode notifications: %v", err)
+		}
+		return notifications
+	}
+
+	all := list("")
+	if len(all) != 2 || all[0].Message != "Second" {
+		t.Fatalf("Expected 2 notifications newest first, got %+v", all)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/notifications/"+itoa(first.ID)+"/read", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var read models.Notification
+	if err := json.NewDecoder(rec.Body).Decode(&read); err != nil {
+		t.Fatalf("Failed to decode notification: %v", err)
+	}
+	if read.ReadAt == nil || read.Link != "/budget?month=11&year=2025" {
+		t.Errorf("Expected read notification with link, got %+v", read)
+	}
+
+	unread := list("?unread=true")
+	if len(unread) != 1 || unread[0].Message != "Second" {
+		t.Errorf("Expected only the unread notification, got %+v", unread)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/notifications/999/read", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestNotifications_PerUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewNotificationRepository(db)
+	handler := NewNotificationHandler(nil, nil, nil, nil, repo, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/notifications", handler.List)
+	mux.HandleFunc("POST /api/notifications/{id}/read", handler.MarkRead)
+
+	own, err := repo.ForUser(1).Create(&models.Notification{
+		Kind: models.NotificationBudgetCreated, Title: "Budget created", Message: "Mine",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notification: %v", err)
+	}
+	other, err := repo.ForUser(2).Create(&models.Notification{
+		Kind: models.NotificationBudgetCreated, Title: "Budget created", Message: "Theirs",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notification: %v", err)
+	}
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: 1}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("GET", "/api/notifications")
+	var notifications []models.Notification
+	if err := json.NewDecoder(rec.Body).Decode(&notifications); err != nil {
+		t.Fatalf("Failed to decode notifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].ID != own.ID {
+		t.Errorf("Expected only the user's own notification, got %+v", notifications)
+	}
+
+	if rec := serve("POST", "/api/notifications/"+itoa(other.ID)+"/read"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d marking another user's notification, got %d", http.StatusNotFound, rec.Code)
+	}
+	if stored, err := repo.ForUser(2).GetByID(other.ID); err != nil || stored.ReadAt != nil {
+		t.Errorf("Expected the other user's notification to stay unread, got %+v (%v)", stored, err)
+	}
+}
+
+func TestBudgetStatus_Rollover(t *testing.T) {
+	db, budgetRepo, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	expenses := repository.NewActualExpenseRepository(db)
+	spend := func(amount float64, month time.Month) {
+		t.Helper()
+		date := time.Date(2025, month, 10, 12, 0, 0, 0, time.UTC)
+		if _, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ActualAmount: amount,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+	if _, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{Month: 2, Year: 2025, Amount: 1000}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	march, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 1000, RolloverUnspent: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	spend(700, time.February)
+	spend(1100, time.March)
+
+	// $300 left in February keeps March under its effective limit
+	status := fetchBudgetStatus(t, mux)
+	if status.Rollover != 300 || status.EffectiveAmount != 1300 ||
+		status.Status != BudgetStatusWarning || !status.CurrentBudget.RolloverUnspent {
+		t.Errorf("Unexpected status with rollover: %+v", status)
+	}
+
+	// An overspent February leaves nothing to roll over
+	spend(400, time.February)
+	if status := fetchBudgetStatus(t, mux); status.Rollover != 0 || status.Status != BudgetStatusOver {
+		t.Errorf("Unexpected status after February was overspent: %+v", status)
+	}
+
+	off := false
+	if _, err := budgetRepo.Update(march.ID, &models.UpdateBudgetLimitRequest{RolloverUnspent: &off}); err != nil {
+		t.Fatalf("Failed to update budget: %v", err)
+	}
+	if status := fetchBudgetStatus(t, mux); status.CurrentBudget.RolloverUnspent || status.EffectiveAmount != 1000 {
+		t.Errorf("Unexpected status without rollover: %+v", status)
+	}
+}
+
+func TestBudgetStatus_CrossedThresholds(t *testing.T) {
+	db, budgetRepo, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	if _, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 1000, NotificationThresholds: []float64{0.5, 0.8, 1},
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	if status := fetchBudgetStatus(t, mux); len(status.CrossedThresholds) != 0 {
+		t.Errorf("Expected no crossed thresholds, got %v", status.CrossedThresholds)
+	}
+
+	date := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	if _, err := repository.NewActualExpenseRepository(db).Create(&models.CreateActualExpenseRequest{
+		ItemName: "Rent", Source: "Landlord", ActualAmount: 850,
+		ExpenseType: models.ExpenseTypeMonthly, ReceiptDate: &date,
+	}); err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+
+	status := fetchBudgetStatus(t, mux)
+	if len(status.CrossedThresholds) != 2 || status.CrossedThresholds[0] != 0.5 ||
+		status.CrossedThresholds[1] != 0.8 || status.Status != BudgetStatusWarning {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestWeeklyStatus(t *testing.T) {
+	db, budgetRepo, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	fetch := func(query string) (*httptest.ResponseRecorder, WeeklyStatusResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/notifications/weekly-status"+query, nil))
+		var status WeeklyStatusResponse
+		json.Unmarshal(rec.Body.Bytes(), &status)
+		return rec, status
+	}
+
+	actualRepo := repository.NewActualExpenseRepository(db)
+	for _, e := range []struct {
+		day    int
+		amount float64
+	}{{9, 500}, {10, 150}, {16, 100}, {17, 70}} {
+		date := time.Date(2025, time.March, e.day, 12, 0, 0, 0, time.UTC)
+		if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Publix", ActualAmount: e.amount,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	// Without a weekly cap only the spending is reported
+	rec, status := fetch("?date=2025-03-12")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if status.WeeklyLimit != 0 || status.TotalSpent != 250 || status.Status != BudgetStatusSafe {
+		t.Errorf("Expected the spending of the week without a cap, got %+v", status)
+	}
+
+	if _, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 2000, WeeklyLimit: 300,
+		NotificationThresholds: []float64{0.5, 0.8},
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	// Monday to Sunday: the 9th is the Sunday before and the 17th the Monday after
+	_, status = fetch("?date=2025-03-12")
+	if status.WeekStart != "2025-03-10" || status.WeekEnd != "2025-03-16" {
+		t.Errorf("Expected the week of March 10 to 16, got %s to %s", status.WeekStart, status.WeekEnd)
+	}
+	if status.WeeklyLimit != 300 || status.TotalSpent != 250 || status.Remaining != 50 {
+		t.Errorf("Expected 250 of 300 spent, got %+v", status)
+	}
+	if status.Status != BudgetStatusWarning || len(status.CrossedThresholds) != 2 {
+		t.Errorf("Expected a warning past both thresholds, got %s %v", status.Status, status.CrossedThresholds)
+	}
+
+	_, status = fetch("?date=2025-03-09")
+	if status.TotalSpent != 500 || status.Status != BudgetStatusOver || status.Remaining != -200 {
+		t.Errorf("Expected the week of March 3 over its cap, got %+v", status)
+	}
+
+	if rec, _ := fetch("?date=March"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid date, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
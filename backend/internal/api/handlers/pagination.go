@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page is the paging envelope shared by list responses. Count is the number
+// of items returned and Total the number matching before paging. When the
+// request is paged, Next and Prev link to the neighbouring pages; the same
+// links are sent in an RFC 5988 Link header together with first and last.
+type Page struct {
+	Count  int    `json:"count"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Next   string `json:"next,omitempty"`
+	Prev   string `json:"prev,omitempty"`
+}
+
+// newPage builds the envelope for a page of count items and sets the Link
+// header on w. Unpaged requests (no limit) get no links.
+func newPage(w http.ResponseWriter, r *http.Request, count, total, limit, offset int) Page {
+	page := Page{Count: count, Total: total, Limit: limit, Offset: offset}
+	if limit <= 0 {
+		return page
+	}
+
+	var links []string
+	addLink := func(rel string, offset int) string {
+		link := pageURL(r, limit, offset)
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+		return link
+	}
+
+	if offset+limit < total {
+		page.Next = addLink("next", offset+limit)
+	}
+	if offset > 0 {
+		page.Prev = addLink("prev", max(offset-limit, 0))
+	}
+	addLink("first", 0)
+	addLink("last", max(total-1, 0)/limit*limit)
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+	return page
+}
+
+// pageURL returns the request's absolute URL with limit and offset replaced,
+// keeping every other query parameter
+func pageURL(r *http.Request, limit, offset int) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
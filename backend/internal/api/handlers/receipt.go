@@ -4,46 +4,89 @@ import (
 	"budget-tracker/internal/models"
 	"budget-tracker/internal/repository"
 	"budget-tracker/internal/services/ai"
+	"budget-tracker/internal/services/receipt"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	// MaxUploadSize is the maximum file size for receipt uploads (10MB)
-	MaxUploadSize = 10 << 20 // 10 MB
+	// MaxUploadSize is the maximum file size for uploads (10MB); the receipt
+	// limit defaults to it and is set with SetDocumentLimits
+	MaxUploadSize = ai.DefaultMaxDocumentSize
 	// FormFileKey is the key for the document file in the multipart form
 	FormFileKey = "document"
+	// ReceiptMetricsHandler is the handler name failures are counted under
+	ReceiptMetricsHandler = "receipts.process"
+	// ReceiptStageMetricsPrefix prefixes the stage name failures of one
+	// pipeline stage are counted under, e.g. receipts.extract
+	ReceiptStageMetricsPrefix = "receipts."
 )
 
 // ReceiptHandler handles receipt-related HTTP requests
 type ReceiptHandler struct {
-	aiClient            *ai.Client
-	documentProcessor   *ai.ImageProcessor
+	aiProvider          ai.ReceiptProvider
+	pipeline            *receipt.Pipeline
 	expectedExpenseRepo *repository.ExpectedExpenseRepository
 	actualExpenseRepo   *repository.ActualExpenseRepository
+	metricsRepo         *repository.MetricsRepository
+	historyRepo         *repository.ReceiptHistoryRepository
+	usageRepo           *repository.AIUsageRepository
+	monthlyQuota        int
+	limits              ai.DocumentLimits
 }
 
 // NewReceiptHandler creates a new ReceiptHandler
+// actualExpenseRepo is optional; when set, the user's category rules override
+// the expense type the AI picked for the items.
+// metricsRepo is optional; when set, every error response is counted by error code.
+// historyRepo is optional; when set, every run that reaches the AI provider is
+// stored with the raw model output.
+// usageRepo is optional; when set, the calls to the AI provider are counted per
+// user and month, and a user is refused once monthlyQuota calls were made in
+// the month. A monthlyQuota of 0 counts without a limit.
+// The handler accepts PDFs of up to MaxUploadSize until SetDocumentLimits is called.
 func NewReceiptHandler(
-	aiClient *ai.Client,
+	aiProvider ai.ReceiptProvider,
 	expectedExpenseRepo *repository.ExpectedExpenseRepository,
 	actualExpenseRepo *repository.ActualExpenseRepository,
+	metricsRepo *repository.MetricsRepository,
+	historyRepo *repository.ReceiptHistoryRepository,
+	usageRepo *repository.AIUsageRepository,
+	monthlyQuota int,
 ) *ReceiptHandler {
-	return &ReceiptHandler{
-		aiClient:            aiClient,
-		documentProcessor:   ai.NewImageProcessor(),
+	h := &ReceiptHandler{
+		aiProvider:          aiProvider,
 		expectedExpenseRepo: expectedExpenseRepo,
 		actualExpenseRepo:   actualExpenseRepo,
+		metricsRepo:         metricsRepo,
+		historyRepo:         historyRepo,
+		usageRepo:           usageRepo,
+		monthlyQuota:        monthlyQuota,
 	}
+	h.SetDocumentLimits(ai.DefaultDocumentLimits())
+	return h
+}
+
+// SetDocumentLimits sets the size, types and pages of the documents the
+// handler accepts. It must be called before the handler serves requests.
+func (h *ReceiptHandler) SetDocumentLimits(limits ai.DocumentLimits) {
+	h.limits = limits
+	h.pipeline = receipt.NewPipeline(receipt.LimitedStages(h.aiProvider, limits), stageMetrics{h.metricsRepo})
 }
 
 // Process handles POST /api/receipts/process
-// Accepts multipart form data with a PDF document and returns extracted receipt items
+// Accepts multipart form data with a document within the document limits and
+// returns extracted receipt items.
+// With ?allow_degraded=true a request that fails because the AI provider is
+// down or not configured gets a draft instead of the 503 or 504, so the items
+// can be entered by hand (see respondDraft).
 func (h *ReceiptHandler) Process(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -60,8 +103,9 @@ func (h *ReceiptHandler) Process(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 	fmt.Printf("[Receipt] Starting receipt processing\n")
 
-	// Check if AI client is configured
-	if h.aiClient == nil {
+	// Check if an AI provider is configured
+	allowDegraded := isAllowDegraded(r)
+	if h.aiProvider == nil && !allowDegraded {
 		h.respondReceiptError(
 			w,
 			http.StatusServiceUnavailable,
@@ -72,15 +116,15 @@ func (h *ReceiptHandler) Process(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Limit the request body size
-	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxSize)
 
 	// Parse the multipart form
-	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+	if err := r.ParseMultipartForm(h.limits.MaxSize); err != nil {
 		if strings.Contains(err.Error(), "request body too large") {
 			h.respondReceiptError(
 				w,
 				http.StatusRequestEntityTooLarge,
-				"PDF file too large (max 10MB)",
+				h.tooLargeMessage(),
 				models.ErrCodeInvalidDocument,
 			)
 			return
@@ -108,178 +152,391 @@ func (h *ReceiptHandler) Process(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 	fmt.Printf("[Receipt] File received: name=%s, size=%d bytes\n", header.Filename, header.Size)
 
-	// Validate file size
-	if header.Size == 0 {
-		h.respondReceiptError(
-			w,
-			http.StatusBadRequest,
-			"Empty document file",
-			models.ErrCodeInvalidDocument,
-		)
-		return
-	}
-
-	// Process the document
-	processedDocument, err := h.documentProcessor.ReadAndProcessReader(file)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		if errors.Is(err, ai.ErrUnsupportedFormat) {
-			h.respondReceiptError(
-				w,
-				http.StatusBadRequest,
-				"Unsupported format. Only PDF is supported",
-				models.ErrCodeInvalidDocument,
-			)
-			return
-		}
 		h.respondReceiptError(
 			w,
 			http.StatusBadRequest,
-			"Failed to process document",
+			"Failed to read document",
 			models.ErrCodeInvalidDocument,
 		)
 		return
 	}
 
-	fmt.Printf("[Receipt] Document processed: mimeType=%s, dataLength=%d\n", processedDocument.MimeType, len(processedDocument.Base64Data))
-
 	// Call the AI service with context timeout
 	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
 	defer cancel()
 
-	// Fetch existing expected expenses to build budget categories for AI categorization
-	var budgetCategories []string
-	if h.expectedExpenseRepo != nil {
-		expenses, err := h.expectedExpenseRepo.GetAll()
-		if err == nil {
-			// Build unique category list from expense item names
-			categoryMap := make(map[string]bool)
-			for _, expense := range expenses {
-				if !categoryMap[expense.ItemName] {
-					categoryMap[expense.ItemName] = true
-					// Include the type information for better AI categorization
-					categoryInfo := expense.ItemName + " (" + string(expense.ExpenseType) + ")"
-					budgetCategories = append(budgetCategories, categoryInfo)
-				}
-			}
+	userID := requestUserID(r)
+	job := &receipt.Job{
+		FileName: header.Filename,
+		Data:     data,
+		Budgets:  h.budgetCategories(userID),
+	}
+
+	// Category rules take precedence over the AI's expense type
+	if h.actualExpenseRepo != nil {
+		rules, err := h.actualExpenseRepo.ForUser(userID).CategoryRules()
+		if err != nil {
+			fmt.Printf("[Receipt] Failed to load category rules: %v\n", err)
 		}
+		job.Rules = rules
 	}
 
-	fmt.Printf("[Receipt] Calling AI service with %d budget categories\n", len(budgetCategories))
+	fmt.Printf("[Receipt] Running pipeline with %d budget categories\n", len(job.Budgets))
 
-	// Process receipt: OCR extraction + categorization in one request
-	result, err := h.aiClient.ProcessReceiptImage(
-		ctx,
-		processedDocument.Base64Data,
-		processedDocument.MimeType,
-		budgetCategories,
-	)
-	if err != nil {
-		h.handleAIError(w, err)
+	var extractor receipt.Extractor = &quotaExtractor{
+		Extractor: receipt.AIExtractor{Provider: h.aiProvider},
+		handler:   h,
+		userID:    userID,
+	}
+	if h.aiProvider == nil {
+		// The document is still validated before the draft is returned
+		extractor = unconfiguredExtractor{}
+	}
+	pipeline := h.pipeline.With(receipt.Stages{
+		Extract: extractor,
+		Persist: &historyPersister{handler: h, started: startTime},
+	})
+	if err := pipeline.Run(ctx, job); err != nil {
+		h.handlePipelineError(w, job, err, time.Since(startTime).Milliseconds(), allowDegraded)
 		return
 	}
 
 	// Calculate processing time
 	processingTimeMs := time.Since(startTime).Milliseconds()
 
-	// Get source from result
-	source := result.Source
-	if source == "" {
-		source = "Unknown"
+	fmt.Printf("[Receipt] Success: extracted %d items from %d receipts in %dms\n",
+		len(job.Items), len(job.Segments()), processingTimeMs)
+
+	stageTimes := make(map[string]int64, len(job.Durations))
+	for stage, duration := range job.Durations {
+		stageTimes[stage] = duration.Milliseconds()
+	}
+
+	receipts := make([]models.ReceiptResult, 0, len(job.Segments()))
+	for _, segment := range job.Segments() {
+		receipts = append(receipts, models.ReceiptResult{
+			Source:    segment.Source,
+			Items:     segment.Items,
+			ItemCount: segment.ItemCount,
+		})
+	}
+
+	// Return the response
+	respondJSON(w, http.StatusOK, models.ProcessReceiptResponse{
+		Success:          true,
+		Items:            job.Items,
+		ItemCount:        job.ItemCount,
+		ProcessingTimeMs: processingTimeMs,
+		ProcessingID:     job.ProcessingID,
+		StageTimesMs:     stageTimes,
+		Receipts:         receipts,
+	})
+}
+
+// stageMetrics logs how long each pipeline stage took and counts the
+// failures of each stage by error code
+type stageMetrics struct {
+	repo *repository.MetricsRepository
+}
+
+func (m stageMetrics) ObserveStage(stage string, duration time.Duration, code string) {
+	fmt.Printf("[Receipt] Stage %s: %dms %s\n", stage, duration.Milliseconds(), code)
+	if code == "" || m.repo == nil {
+		return
+	}
+	if err := m.repo.IncrementFailure(ReceiptStageMetricsPrefix+stage, code, time.Now()); err != nil {
+		fmt.Printf("[Receipt] Failed to record stage failure metric: %v\n", err)
+	}
+}
+
+// budgetCategories returns the names of the user's active expected expenses
+// with their type, e.g. "Milk (weekly)", for the AI to categorize the items
+// by. Categorized items lead with their category, e.g. "Groceries: Milk
+// (weekly)", so items of the category that are not listed can match too.
+func (h *ReceiptHandler) budgetCategories(userID int64) []string {
+	if h.expectedExpenseRepo == nil {
+		return nil
+	}
+	active := true
+	expenses, err := h.expectedExpenseRepo.ForUser(userID).List(repository.ExpenseFilter{Active: &active})
+	if err != nil {
+		return nil
 	}
 
-	// Prepare the response items from result
-	responseItems := make([]models.ReceiptItem, len(result.Items))
-	for i, item := range result.Items {
-		itemType := item.ItemType
-		if itemType == "" {
-			itemType = "misc"
+	var categories []string
+	seen := make(map[string]bool)
+	for _, expense := range expenses {
+		hint := expense.ItemName + " (" + string(expense.ExpenseType) + ")"
+		if expense.Category != "" {
+			hint = expense.Category + ": " + hint
 		}
-		responseItems[i] = models.ReceiptItem{
-			Source:    source,
-			Type:      itemType,
-			ItemCode:  item.ItemCode,
-			ItemPrice: item.ItemPrice,
-			ItemName:  item.ItemName,
+		if !seen[hint] {
+			seen[hint] = true
+			categories = append(categories, hint)
 		}
 	}
+	return categories
+}
 
-	fmt.Printf("[Receipt] Success: extracted %d items in %dms\n", len(responseItems), processingTimeMs)
+// quotaExtractor counts the call to the AI provider against the user's
+// monthly quota before extracting. Every call is billed, so it is counted
+// before it is made.
+type quotaExtractor struct {
+	receipt.Extractor
+	handler *ReceiptHandler
+	userID  int64
+}
+
+func (e *quotaExtractor) Extract(ctx context.Context, job *receipt.Job) error {
+	if err := e.handler.reserveAICall(e.userID); err != nil {
+		return &receipt.StageError{Code: models.ErrCodeQuotaExceeded, Err: err}
+	}
+	return e.Extractor.Extract(ctx, job)
+}
+
+// unconfiguredExtractor stands in for the AI provider when none is
+// configured, for requests that allow a degraded draft
+type unconfiguredExtractor struct{}
+
+func (unconfiguredExtractor) Extract(ctx context.Context, job *receipt.Job) error {
+	return ai.ErrAPIKeyNotSet
+}
+
+// historyPersister records successful runs in the processing history
+type historyPersister struct {
+	handler *ReceiptHandler
+	started time.Time
+}
+
+func (p *historyPersister) Persist(ctx context.Context, job *receipt.Job) error {
+	job.ProcessingID = p.handler.recordProcessing(&repository.ReceiptProcessingRecord{
+		FileName:         job.FileName,
+		FileSize:         int64(len(job.Data)),
+		Status:           repository.ReceiptStatusSuccess,
+		ItemCount:        len(job.Items),
+		ProcessingTimeMs: time.Since(p.started).Milliseconds(),
+	}, job.RawResponses)
+	return nil
+}
+
+// handlePipelineError responds to a failed stage. Failed calls to the AI
+// provider are recorded in the processing history like successful ones.
+// When allowDegraded is set, an AI provider that is down or not configured
+// gets a draft instead of the error.
+func (h *ReceiptHandler) handlePipelineError(
+	w http.ResponseWriter,
+	job *receipt.Job,
+	err error,
+	processingTimeMs int64,
+	allowDegraded bool,
+) {
+	fmt.Printf("[Receipt] Pipeline Error: %v\n", err)
+	code := receipt.ErrorCode(err)
+	stage := ""
+	var failure *receipt.StageError
+	if errors.As(err, &failure) {
+		stage = failure.Stage
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrAIQuotaExceeded):
+		resetsAt := quotaResetsAt(time.Now())
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetsAt).Seconds())+1))
+		h.respondStageError(w, http.StatusTooManyRequests,
+			fmt.Sprintf("Monthly limit of %d processed receipts reached. It resets on %s",
+				h.monthlyQuota, resetsAt.Format("January 2")),
+			code, stage)
+	case errors.Is(err, receipt.ErrEmptyDocument):
+		h.respondStageError(w, http.StatusBadRequest, "Empty document file", code, stage)
+	case errors.Is(err, ai.ErrUnsupportedFormat):
+		h.respondStageError(w, http.StatusBadRequest, "Unsupported format. Only PDF is supported", code, stage)
+	case errors.Is(err, ai.ErrTypeNotAllowed):
+		h.respondStageError(w, http.StatusBadRequest,
+			"Unsupported format. Accepted types: "+strings.Join(h.limits.AllowedTypes, ", "), code, stage)
+	case errors.Is(err, ai.ErrDocumentTooLarge):
+		h.respondStageError(w, http.StatusRequestEntityTooLarge, h.tooLargeMessage(), code, stage)
+	case errors.Is(err, ai.ErrTooManyPages):
+		h.respondStageError(w, http.StatusBadRequest,
+			fmt.Sprintf("Document has too many pages (max %d)", h.limits.MaxPages), code, stage)
+	case stage == receipt.StageValidate || stage == receipt.StagePreprocess:
+		h.respondStageError(w, http.StatusBadRequest, "Failed to process document", code, stage)
+	case stage == receipt.StageExtract:
+		var processingID int64
+		if h.aiProvider != nil {
+			processingID = h.recordProcessing(&repository.ReceiptProcessingRecord{
+				FileName:         job.FileName,
+				FileSize:         int64(len(job.Data)),
+				Status:           repository.ReceiptStatusError,
+				ErrorCode:        code,
+				ProcessingTimeMs: processingTimeMs,
+			}, job.RawResponses)
+		}
+		status, message, _ := aiErrorResponse(err)
+		if allowDegraded && isAIUnavailable(err) {
+			h.respondDraft(w, job, message, code, processingID, processingTimeMs)
+			return
+		}
+		h.respondStageError(w, status, message, code, stage)
+	default:
+		h.respondStageError(w, http.StatusInternalServerError, "Failed to process receipt", code, stage)
+	}
+}
+
+// isAllowDegraded reports whether the request accepts a draft when the AI
+// provider is unavailable
+func isAllowDegraded(r *http.Request) bool {
+	allow, _ := strconv.ParseBool(r.URL.Query().Get("allow_degraded"))
+	return allow
+}
+
+// isAIUnavailable reports whether err means the AI provider is down or not
+// configured, e.g. overloaded, out of credits or timed out, rather than the
+// document, the provider's answer or the user's quota being the problem
+func isAIUnavailable(err error) bool {
+	for _, target := range []error{
+		ai.ErrOverloaded, ai.ErrQuotaExhausted, ai.ErrMaxRetries, ai.ErrModelNotFound,
+		ai.ErrAPIKeyNotSet, ai.ErrTimeout, context.DeadlineExceeded,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// respondDraft answers a failed extraction with a draft to fill in by hand:
+// one receipt with the store guessed from the file name and no items. The
+// failure is still counted, and message and code are passed on with it.
+func (h *ReceiptHandler) respondDraft(
+	w http.ResponseWriter,
+	job *receipt.Job,
+	message string,
+	code string,
+	processingID int64,
+	processingTimeMs int64,
+) {
+	fmt.Printf("[Receipt] Degraded: returning a draft for %s (%s)\n", job.FileName, code)
+	h.countFailure(code)
 
-	// Return the response
 	respondJSON(w, http.StatusOK, models.ProcessReceiptResponse{
 		Success:          true,
-		Items:            responseItems,
+		Items:            []models.ReceiptItem{},
 		ProcessingTimeMs: processingTimeMs,
+		ProcessingID:     processingID,
+		Receipts: []models.ReceiptResult{{
+			Source: receipt.SourceFromFileName(job.FileName),
+			Items:  []models.ReceiptItem{},
+		}},
+		Degraded: true,
+		Error:    message,
+		Code:     code,
 	})
 }
 
+// tooLargeMessage is the error message for a document over the size limit
+func (h *ReceiptHandler) tooLargeMessage() string {
+	return fmt.Sprintf("Document too large (max %s)", formatUploadSize(h.limits.MaxSize))
+}
+
+// formatUploadSize formats a size in bytes for a message, e.g. 10MB or 512KB
+func formatUploadSize(size int64) string {
+	if size >= 1<<20 && size%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", size>>20)
+	}
+	if size >= 1<<10 {
+		return fmt.Sprintf("%dKB", size>>10)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// recordProcessing stores a processing run with the raw model output and
+// returns its id, or 0 when history is disabled or could not be written
+func (h *ReceiptHandler) recordProcessing(
+	rec *repository.ReceiptProcessingRecord,
+	rawResponses []string,
+) int64 {
+	if h.historyRepo == nil {
+		return 0
+	}
+
+	if len(rawResponses) > 0 {
+		rec.RawResponse = &rawResponses[0]
+	}
+	if len(rawResponses) > 1 {
+		rec.RepairResponse = &rawResponses[1]
+	}
+
+	id, err := h.historyRepo.Record(rec)
+	if err != nil {
+		fmt.Printf("[Receipt] Failed to record processing history: %v\n", err)
+		return 0
+	}
+	return id
+}
+
+// reserveAICall counts a call to the AI provider for userID. It returns
+// repository.ErrAIQuotaExceeded when the call may not be made.
+func (h *ReceiptHandler) reserveAICall(userID int64) error {
+	if h.usageRepo == nil {
+		return nil
+	}
+
+	_, err := h.usageRepo.ForUser(userID).Reserve(time.Now(), h.monthlyQuota)
+	if errors.Is(err, repository.ErrAIQuotaExceeded) {
+		return err
+	}
+	if err != nil {
+		// Usage tracking must not take receipt processing down with it
+		fmt.Printf("[Receipt] Failed to count AI usage: %v\n", err)
+	}
+	return nil
+}
+
+// quotaResetsAt returns when the monthly quota counted at now resets, at
+// the start of the next month (UTC)
+func quotaResetsAt(now time.Time) time.Time {
+	return time.Date(now.UTC().Year(), now.UTC().Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
 // handleAIError handles errors from the AI service and returns appropriate responses
 func (h *ReceiptHandler) handleAIError(w http.ResponseWriter, err error) {
 	fmt.Printf("[Receipt] AI Error: %v\n", err)
+	status, message, code := aiErrorResponse(err)
+	h.respondReceiptError(w, status, message, code)
+}
+
+// aiErrorResponse maps an AI service error to a status, message and error code
+func aiErrorResponse(err error) (int, string, string) {
+	code := receipt.AIErrorCode(err)
 	switch {
+	case errors.Is(err, ai.ErrInvalidDocument):
+		return http.StatusUnprocessableEntity, "Could not read this receipt. Scanned PDFs need the Anthropic provider", code
 	case errors.Is(err, ai.ErrTimeout):
-		h.respondReceiptError(
-			w,
-			http.StatusGatewayTimeout,
-			"Receipt processing timed out. Please try again",
-			models.ErrCodeTimeout,
-		)
+		return http.StatusGatewayTimeout, "Receipt processing timed out. Please try again", code
 	case errors.Is(err, ai.ErrRateLimit):
-		h.respondReceiptError(
-			w,
-			http.StatusTooManyRequests,
-			"Service is busy. Please try again in a moment",
-			models.ErrCodeRateLimit,
-		)
+		return http.StatusTooManyRequests, "Service is busy. Please try again in a moment", code
+	case errors.Is(err, ai.ErrQuotaExhausted):
+		return http.StatusServiceUnavailable, "AI service credits are used up. Receipts can be processed again once they are topped up", code
 	case errors.Is(err, ai.ErrOverloaded):
-		h.respondReceiptError(
-			w,
-			http.StatusServiceUnavailable,
-			"AI service is temporarily overloaded. Please try again in a few moments",
-			models.ErrCodeAPIError,
-		)
+		return http.StatusServiceUnavailable, "AI service is temporarily overloaded. Please try again in a few moments", code
+	case errors.Is(err, ai.ErrParseResponse):
+		return http.StatusBadGateway, "Could not read the AI response. Please try again", code
 	case errors.Is(err, ai.ErrAPIKeyNotSet):
-		h.respondReceiptError(
-			w,
-			http.StatusServiceUnavailable,
-			"AI service not configured",
-			models.ErrCodeInternalError,
-		)
+		return http.StatusServiceUnavailable, "AI service not configured", code
 	case errors.Is(err, ai.ErrMaxRetries):
-		h.respondReceiptError(
-			w,
-			http.StatusServiceUnavailable,
-			"Failed to process receipt after multiple attempts",
-			models.ErrCodeAPIError,
-		)
+		return http.StatusServiceUnavailable, "Failed to process receipt after multiple attempts", code
+	case errors.Is(err, ai.ErrModelNotFound):
+		return http.StatusServiceUnavailable, "The configured AI model is no longer available", code
 	case errors.Is(err, ai.ErrAPIError):
-		h.respondReceiptError(
-			w,
-			http.StatusBadGateway,
-			"AI service error. Please try again",
-			models.ErrCodeAPIError,
-		)
+		return http.StatusBadGateway, "AI service error. Please try again", code
 	case errors.Is(err, context.DeadlineExceeded):
-		h.respondReceiptError(
-			w,
-			http.StatusGatewayTimeout,
-			"Request timed out",
-			models.ErrCodeTimeout,
-		)
+		return http.StatusGatewayTimeout, "Request timed out", code
 	case errors.Is(err, context.Canceled):
-		h.respondReceiptError(
-			w,
-			http.StatusRequestTimeout,
-			"Request was canceled",
-			models.ErrCodeTimeout,
-		)
+		return http.StatusRequestTimeout, "Request was canceled", code
 	default:
-		h.respondReceiptError(
-			w,
-			http.StatusInternalServerError,
-			"Failed to process receipt",
-			models.ErrCodeInternalError,
-		)
+		return http.StatusInternalServerError, "Failed to process receipt", code
 	}
 }
 
@@ -290,12 +547,36 @@ func (h *ReceiptHandler) respondReceiptError(
 	message string,
 	code string,
 ) {
-	fmt.Printf("[Receipt] Error Response: status=%d, code=%s, message=%s\n", status, code, message)
+	h.respondStageError(w, status, message, code, "")
+}
+
+// respondStageError sends an error response for a failed pipeline stage;
+// stage is empty for failures before the pipeline runs
+func (h *ReceiptHandler) respondStageError(
+	w http.ResponseWriter,
+	status int,
+	message string,
+	code string,
+	stage string,
+) {
+	fmt.Printf("[Receipt] Error Response: status=%d, code=%s, stage=%s, message=%s\n", status, code, stage, message)
+	h.countFailure(code)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(models.ProcessReceiptError{
 		Success: false,
 		Error:   message,
 		Code:    code,
+		Stage:   stage,
 	})
 }
+
+// countFailure counts a failed request by error code
+func (h *ReceiptHandler) countFailure(code string) {
+	if h.metricsRepo == nil {
+		return
+	}
+	if err := h.metricsRepo.IncrementFailure(ReceiptMetricsHandler, code, time.Now()); err != nil {
+		fmt.Printf("[Receipt] Failed to record failure metric: %v\n", err)
+	}
+}
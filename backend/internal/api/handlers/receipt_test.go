@@ -2,14 +2,20 @@ package handlers
 
 import (
 	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
 	"budget-tracker/internal/services/ai"
+	"budget-tracker/internal/services/auth"
+	"budget-tracker/internal/services/receipt"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 // Test data for PDF and image formats
@@ -100,7 +106,7 @@ func TestReceiptHandler_ServiceUnavailableWithoutAIClient(t *testing.T) {
 	defer db.Close()
 
 	// Handler without AI client
-	handler := NewReceiptHandler(nil, nil, nil)
+	handler := NewReceiptHandler(nil, nil, nil, nil, nil, nil, 0)
 	mux := createTestReceiptMux(handler)
 
 	// Upload valid PDF
@@ -133,7 +139,7 @@ func TestReceiptHandler_ServiceUnavailableWithoutAIClient(t *testing.T) {
 
 // TestReceiptHandler_ErrorResponseStructure verifies the error response has the correct structure
 func TestReceiptHandler_ErrorResponseStructure(t *testing.T) {
-	handler := NewReceiptHandler(nil, nil, nil)
+	handler := NewReceiptHandler(nil, nil, nil, nil, nil, nil, 0)
 	mux := createTestReceiptMux(handler)
 
 	// Create request with no file to trigger error
@@ -207,15 +213,15 @@ func TestReceiptHandler_DocumentProcessorIntegration(t *testing.T) {
 
 // TestReceiptHandler_NewReceiptHandler verifies the handler is created correctly
 func TestReceiptHandler_NewReceiptHandler(t *testing.T) {
-	handler := NewReceiptHandler(nil, nil, nil)
+	handler := NewReceiptHandler(nil, nil, nil, nil, nil, nil, 0)
 
 	if handler == nil {
 		t.Fatal("Expected non-nil handler")
 	}
 
-	// Verify the document processor is initialized
-	if handler.documentProcessor == nil {
-		t.Error("Expected documentProcessor to be initialized")
+	// Verify the receipt pipeline is initialized
+	if handler.pipeline == nil {
+		t.Error("Expected pipeline to be initialized")
 	}
 }
 
@@ -312,7 +318,7 @@ func TestReceiptHandler_ReceiptItemStructure(t *testing.T) {
 		t.Fatalf("Failed to unmarshal to map: %v", err)
 	}
 
-	expectedKeys := []string{"source", "type", "item_code", "item_price", "item_name"}
+	expectedKeys := []string{"source", "type", "item_code", "item_price", "item_name", "line_no"}
 	for _, key := range expectedKeys {
 		if _, ok := m[key]; !ok {
 			t.Errorf("Expected JSON key '%s' to be present", key)
@@ -341,3 +347,445 @@ func TestReceiptHandler_FormFileKeyIsDocument(t *testing.T) {
 		t.Errorf("API contract violation: FormFileKey must be 'document', got '%s'", FormFileKey)
 	}
 }
+
+// TestReceiptHandler_ParseErrorCode verifies unparseable AI output is reported as PARSE_ERROR
+func TestReceiptHandler_ParseErrorCode(t *testing.T) {
+	handler := NewReceiptHandler(nil, nil, nil, nil, nil, nil, 0)
+
+	rec := httptest.NewRecorder()
+	handler.handleAIError(rec, fmt.Errorf("%w: unexpected end of JSON input", ai.ErrParseResponse))
+
+	var errResp models.ProcessReceiptError
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if errResp.Code != models.ErrCodeParseError {
+		t.Errorf("Expected error code '%s', got '%s'", models.ErrCodeParseError, errResp.Code)
+	}
+}
+
+// TestReceiptHandler_FailureMetrics verifies error responses are counted by code
+// and reported by the metrics endpoint
+func TestReceiptHandler_FailureMetrics(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	metricsRepo := repository.NewMetricsRepository(db)
+	handler := NewReceiptHandler(nil, nil, nil, metricsRepo, nil, nil, 0)
+
+	handler.handleAIError(httptest.NewRecorder(), ai.ErrTimeout)
+	handler.handleAIError(httptest.NewRecorder(), ai.ErrTimeout)
+	handler.handleAIError(httptest.NewRecorder(), ai.ErrRateLimit)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/metrics/failures", NewMetricsHandler(metricsRepo).Failures)
+
+	req := httptest.NewRequest("GET", "/api/metrics/failures?handler="+ReceiptMetricsHandler, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var response FailureMetricsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Totals[models.ErrCodeTimeout] != 2 || response.Totals[models.ErrCodeRateLimit] != 1 {
+		t.Errorf("Unexpected totals: %+v", response.Totals)
+	}
+	if len(response.Daily) != 2 || response.Daily[0].Day != response.To {
+		t.Errorf("Expected two counters for today (%s), got %+v", response.To, response.Daily)
+	}
+}
+
+// fakeReceiptProvider returns a canned result or error
+type fakeReceiptProvider struct {
+	result *ai.ReceiptProcessingResult
+	err    error
+	// mimeType is the type of the last document processed
+	mimeType string
+}
+
+func (p *fakeReceiptProvider) ProcessReceiptDocument(
+	ctx context.Context,
+	base64Data, mimeType string,
+	budgets []string,
+) (*ai.ReceiptProcessingResult, error) {
+	p.mimeType = mimeType
+	return p.result, p.err
+}
+
+// TestReceiptHandler_RecordsProcessingHistory verifies processing runs are stored
+// with the raw model output and exposed through the admin endpoints
+func TestReceiptHandler_RecordsProcessingHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	historyRepo := repository.NewReceiptHistoryRepository(db)
+	adminMux := http.NewServeMux()
+	adminHandler := NewAdminHandler(nil, nil, historyRepo, nil, nil, nil)
+	adminMux.HandleFunc("GET /api/admin/receipts/history", adminHandler.ReceiptHistory)
+	adminMux.HandleFunc("GET /api/admin/receipts/history/{id}", adminHandler.ReceiptHistoryEntry)
+
+	process := func(provider ai.ReceiptProvider) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := createTestReceiptMux(NewReceiptHandler(provider, nil, nil, nil, historyRepo, nil, 0))
+		req, err := createMultipartRequest(t, FormFileKey, "receipt.pdf", testValidPDFData)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rawAnswer := `{"source":"Publix","item_count":1,"items":[{"item_code":"MLK","item_price":3.99,"item_name":"Milk","item_type":"weekly"}]}`
+	rec := process(&fakeReceiptProvider{result: &ai.ReceiptProcessingResult{
+		Source:       "Publix",
+		Items:        []ai.CategorizedItem{{ItemCode: "MLK", ItemPrice: 3.99, ItemName: "Milk", ItemType: "weekly"}},
+		ItemCount:    1,
+		RawResponses: []string{rawAnswer},
+	}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response models.ProcessReceiptResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.ProcessingID == 0 {
+		t.Fatal("Expected a processing id in the response")
+	}
+	if response.ItemCount != 1 || response.Items[0].LineNo != 1 {
+		t.Errorf("Expected item_count 1 and line_no 1, got %+v", response)
+	}
+
+	rec = process(&fakeReceiptProvider{err: &ai.RawResponseError{
+		Err:          fmt.Errorf("%w: items: required field missing", ai.ErrParseResponse),
+		RawResponses: []string{"not json", `{"source":"Publix"}`},
+	}})
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadGateway, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	adminMux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/receipts/history", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var records []repository.ReceiptProcessingRecord
+	if err := json.NewDecoder(rec.Body).Decode(&records); err != nil {
+		t.Fatalf("Failed to decode history: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 history records, got %d", len(records))
+	}
+	failed := records[0]
+	if failed.Status != repository.ReceiptStatusError || failed.ErrorCode != models.ErrCodeParseError {
+		t.Errorf("Expected newest record to be a parse error, got %+v", failed)
+	}
+	if failed.RawResponse != nil {
+		t.Error("History list must not include raw responses")
+	}
+
+	rec = httptest.NewRecorder()
+	adminMux.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/admin/receipts/history/%d", failed.ID), nil))
+	var entry repository.ReceiptProcessingRecord
+	if err := json.NewDecoder(rec.Body).Decode(&entry); err != nil {
+		t.Fatalf("Failed to decode history entry: %v", err)
+	}
+	if entry.RawResponse == nil || *entry.RawResponse != "not json" ||
+		entry.RepairResponse == nil || *entry.RepairResponse != `{"source":"Publix"}` {
+		t.Errorf("Expected raw and repair responses, got %+v", entry)
+	}
+
+	rec = httptest.NewRecorder()
+	adminMux.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/admin/receipts/history/%d", response.ProcessingID), nil))
+	if err := json.NewDecoder(rec.Body).Decode(&entry); err != nil {
+		t.Fatalf("Failed to decode history entry: %v", err)
+	}
+	if entry.Status != repository.ReceiptStatusSuccess || entry.RawResponse == nil || *entry.RawResponse != rawAnswer {
+		t.Errorf("Expected successful run with raw response, got %+v", entry)
+	}
+
+	rec = httptest.NewRecorder()
+	adminMux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/receipts/history/999", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown record, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// TestReceiptHandler_MonthlyQuota verifies calls are counted per user and
+// refused with QUOTA_EXCEEDED once the monthly quota is used up
+func TestReceiptHandler_MonthlyQuota(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	provider := &fakeReceiptProvider{err: ai.ErrTimeout}
+	mux := createTestReceiptMux(NewReceiptHandler(provider, nil, nil, nil, nil, repository.NewAIUsageRepository(db), 2))
+
+	process := func(userID int64) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := createMultipartRequest(t, FormFileKey, "receipt.pdf", testValidPDFData)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Failed calls count too, they are billed all the same
+	for i := 0; i < 2; i++ {
+		if rec := process(1); rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("Call %d: expected status %d, got %d", i+1, http.StatusGatewayTimeout, rec.Code)
+		}
+	}
+
+	rec := process(1)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	var response models.ProcessReceiptError
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Code != models.ErrCodeQuotaExceeded {
+		t.Errorf("Expected code %s, got %s", models.ErrCodeQuotaExceeded, response.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// The quota is per user
+	if rec := process(2); rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected another user to reach the provider, got %d", rec.Code)
+	}
+}
+
+// TestReceiptHandler_PipelineStages verifies failures name the pipeline stage
+// and are counted per stage, and successful runs report the stage times
+func TestReceiptHandler_PipelineStages(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	metricsRepo := repository.NewMetricsRepository(db)
+	provider := &fakeReceiptProvider{err: ai.ErrTimeout}
+	mux := createTestReceiptMux(NewReceiptHandler(provider, nil, nil, metricsRepo, nil, nil, 0))
+
+	process := func(data []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := createMultipartRequest(t, FormFileKey, "receipt.pdf", data)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		data   []byte
+		status int
+		stage  string
+		code   string
+	}{
+		{testJPEGData, http.StatusBadRequest, receipt.StageValidate, models.ErrCodeInvalidDocument},
+		{testValidPDFData, http.StatusGatewayTimeout, receipt.StageExtract, models.ErrCodeTimeout},
+	} {
+		rec := process(tc.data)
+		var response models.ProcessReceiptError
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if rec.Code != tc.status || response.Stage != tc.stage || response.Code != tc.code {
+			t.Errorf("Expected %d %s/%s, got %d %+v", tc.status, tc.stage, tc.code, rec.Code, response)
+		}
+	}
+
+	counts, err := metricsRepo.FailureCounts(ReceiptStageMetricsPrefix+receipt.StageExtract, time.Now(), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to fetch failure counts: %v", err)
+	}
+	if len(counts) != 1 || counts[0].Code != models.ErrCodeTimeout || counts[0].Count != 1 {
+		t.Errorf("Expected one extract timeout, got %+v", counts)
+	}
+
+	provider.err = nil
+	provider.result = &ai.ReceiptProcessingResult{
+		Items: []ai.CategorizedItem{{ItemName: "Milk", ItemPrice: 3.99, ItemType: "weekly"}},
+	}
+	rec := process(testValidPDFData)
+	var response models.ProcessReceiptResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(response.StageTimesMs) != 6 {
+		t.Errorf("Expected the times of all 6 stages, got %d %+v", rec.Code, response)
+	}
+}
+
+func TestReceiptHandler_SeveralReceipts(t *testing.T) {
+	provider := &fakeReceiptProvider{result: &ai.ReceiptProcessingResult{
+		Receipts: []ai.ReceiptProcessingResult{
+			{Source: "Publix", ItemCount: 1, Items: []ai.CategorizedItem{{ItemName: "Milk", ItemPrice: 3.99, ItemType: "weekly"}}},
+			{Source: "Target", ItemCount: 1, Items: []ai.CategorizedItem{{ItemName: "Towel", ItemPrice: 10, ItemType: "misc"}}},
+		},
+	}}
+	mux := createTestReceiptMux(NewReceiptHandler(provider, nil, nil, nil, nil, nil, 0))
+
+	req, err := createMultipartRequest(t, FormFileKey, "receipts.pdf", testValidPDFData)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var response models.ProcessReceiptResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Receipts) != 2 || response.Receipts[1].Source != "Target" ||
+		response.Receipts[1].Items[0].LineNo != 1 {
+		t.Errorf("Expected each receipt on its own, got %+v", response.Receipts)
+	}
+	if len(response.Items) != 2 || response.ItemCount != 2 {
+		t.Errorf("Expected the items of both receipts, got %+v", response)
+	}
+}
+
+// TestReceiptHandler_DocumentLimits verifies uploads are checked against the
+// configured size, types and pages
+func TestReceiptHandler_DocumentLimits(t *testing.T) {
+	provider := &fakeReceiptProvider{result: &ai.ReceiptProcessingResult{
+		Items: []ai.CategorizedItem{{ItemName: "Milk", ItemPrice: 3.99, ItemType: "weekly"}},
+	}}
+	handler := NewReceiptHandler(provider, nil, nil, nil, nil, nil, 0)
+	handler.SetDocumentLimits(ai.DocumentLimits{
+		MaxSize:      1 << 10,
+		AllowedTypes: []string{ai.MimeTypePDF, ai.MimeTypeJPEG},
+		MaxPages:     1,
+	})
+	mux := createTestReceiptMux(handler)
+
+	process := func(data []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := createMultipartRequest(t, FormFileKey, "receipt", data)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Images are processed once allowed
+	if rec := process(testJPEGData); rec.Code != http.StatusOK || provider.mimeType != ai.MimeTypeJPEG {
+		t.Errorf("Expected the JPEG to be processed, got %d %q: %s", rec.Code, provider.mimeType, rec.Body.String())
+	}
+
+	twoPages := []byte("%PDF-1.4\n1 0 obj <</Type /Pages /Count 2>> endobj\n" +
+		"2 0 obj <</Type /Page>> endobj\n3 0 obj <</Type/Page>> endobj\n%%EOF")
+	for name, tc := range map[string]struct {
+		data   []byte
+		status int
+	}{
+		"png":       {testPNGData, http.StatusBadRequest},
+		"two pages": {twoPages, http.StatusBadRequest},
+		"too large": {append([]byte("%PDF-1.4\n"), make([]byte, 2<<10)...), http.StatusRequestEntityTooLarge},
+	} {
+		rec := process(tc.data)
+		var response models.ProcessReceiptError
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", name, err)
+		}
+		if rec.Code != tc.status || response.Code != models.ErrCodeInvalidDocument {
+			t.Errorf("%s: expected %d %s, got %d %+v", name, tc.status, models.ErrCodeInvalidDocument, rec.Code, response)
+		}
+	}
+}
+
+// TestReceiptHandler_DegradedDraft verifies requests that allow it get a
+// draft to fill in by hand while the AI provider is unavailable
+func TestReceiptHandler_DegradedDraft(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	metricsRepo := repository.NewMetricsRepository(db)
+	process := func(provider ai.ReceiptProvider, query, fileName string, data []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := createTestReceiptMux(NewReceiptHandler(provider, nil, nil, metricsRepo, nil, nil, 0))
+		req, err := createMultipartRequest(t, FormFileKey, fileName, data)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.URL.RawQuery = query
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	draft := func(rec *httptest.ResponseRecorder) models.ProcessReceiptResponse {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response models.ProcessReceiptResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !response.Success || !response.Degraded || response.Items == nil || len(response.Items) != 0 ||
+			len(response.Receipts) != 1 || len(response.Receipts[0].Items) != 0 {
+			t.Errorf("Expected a degraded draft without items, got %+v", response)
+		}
+		return response
+	}
+
+	// Without an AI provider
+	response := draft(process(nil, "allow_degraded=true", "costco_receipt_2026-10-03.pdf", testValidPDFData))
+	if response.Receipts[0].Source != "Costco" || response.Code != models.ErrCodeInternalError ||
+		response.Error != "AI service not configured" {
+		t.Errorf("Expected the source from the file name and the error, got %+v", response)
+	}
+
+	// With a provider that is down
+	overloaded := &fakeReceiptProvider{err: ai.ErrOverloaded}
+	response = draft(process(overloaded, "allow_degraded=true", "IMG_1234.pdf", testValidPDFData))
+	if response.Receipts[0].Source != receipt.UnknownSource || response.Code != models.ErrCodeAPIError {
+		t.Errorf("Expected an unknown source and API_ERROR, got %+v", response)
+	}
+
+	// Drafts are still counted as failures
+	counts, err := metricsRepo.FailureCounts(ReceiptMetricsHandler, time.Now(), time.Now())
+	if err != nil {
+		t.Fatalf("FailureCounts() error: %v", err)
+	}
+	if len(counts) != 2 || counts[0].Code != models.ErrCodeAPIError || counts[1].Code != models.ErrCodeInternalError {
+		t.Errorf("Expected one API_ERROR and one INTERNAL_ERROR failure, got %+v", counts)
+	}
+
+	// Drafts are opt-in, and only stand in for an unavailable provider
+	for name, tc := range map[string]struct {
+		provider ai.ReceiptProvider
+		query    string
+		data     []byte
+		status   int
+	}{
+		"not allowed":      {overloaded, "", testValidPDFData, http.StatusServiceUnavailable},
+		"unconfigured":     {nil, "allow_degraded=false", testValidPDFData, http.StatusServiceUnavailable},
+		"invalid document": {nil, "allow_degraded=true", testPNGData, http.StatusBadRequest},
+		"unreadable": {&fakeReceiptProvider{err: ai.ErrInvalidDocument}, "allow_degraded=true",
+			testValidPDFData, http.StatusUnprocessableEntity},
+		"malformed answer": {&fakeReceiptProvider{err: ai.ErrParseResponse}, "allow_degraded=true",
+			testValidPDFData, http.StatusBadGateway},
+	} {
+		if rec := process(tc.provider, tc.query, "receipt.pdf", tc.data); rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d: %s", name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+}
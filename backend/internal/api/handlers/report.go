@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/export"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ReportHandler serves reports computed over the actual expenses
+type ReportHandler struct {
+	repo *repository.ActualExpenseRepository
+}
+
+// NewReportHandler creates a new ReportHandler
+// repo may read from a replica, see ActualExpenseHandler.
+func NewReportHandler(repo *repository.ActualExpenseRepository) *ReportHandler {
+	return &ReportHandler{repo: repo}
+}
+
+// Pivot handles GET /api/reports/pivot
+// Returns a year of approved spending as a matrix of totals. rows and cols
+// pick the dimensions (category, month or source; default: rows=category,
+// cols=month) and year the year (default: current year).
+func (h *ReportHandler) Pivot(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	rows := query.Get("rows")
+	if rows == "" {
+		rows = models.PivotCategory
+	}
+	cols := query.Get("cols")
+	if cols == "" {
+		cols = models.PivotMonth
+	}
+	year, err := parseOptionalInt(query, "year")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	table, err := h.repo.ForUser(requestUserID(r)).Pivot(year, rows, cols)
+	if errors.Is(err, models.ErrInvalidPivot) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to build pivot table")
+		return
+	}
+	respondJSON(w, http.StatusOK, table)
+}
+
+// weekdayReportDays is the default range of the weekday report, twelve full
+// weeks so every day of the week is counted as often
+const weekdayReportDays = 12 * 7
+
+// Weekdays handles GET /api/reports/weekdays
+// Breaks down approved spending by the day of the week of its receipt date,
+// and into weekdays and weekend. from and to (YYYY-MM-DD) bound the receipt
+// dates (default: the twelve weeks up to today) and type limits it to one
+// expense type.
+func (h *ReportHandler) Weekdays(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter repository.ExpenseFilter
+	if err := parseDateRange(query, &filter); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if value := query.Get("type"); value != "" {
+		expenseType, err := models.ParseExpenseType(value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid type filter")
+			return
+		}
+		filter.Type = expenseType
+	}
+
+	to := time.Now().UTC()
+	if filter.To != nil {
+		to = *filter.To
+	}
+	from := to.AddDate(0, 0, 1-weekdayReportDays)
+	if filter.From != nil {
+		from = *filter.From
+	}
+	if to.Before(from) {
+		respondError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+
+	report, err := h.repo.ForUser(requestUserID(r)).Weekdays(from, to, filter.Type)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to build weekday report")
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+// Variance handles GET /api/reports/variance
+// Compares the expected expenses with the approved actual expenses matched
+// to them in month and year (default: current month), with the variance of
+// each item in amount and percent.
+func (h *ReportHandler) Variance(w http.ResponseWriter, r *http.Request) {
+	month, year, err := parseMonthQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.repo.ForUser(requestUserID(r)).Variance(month, year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to build variance report")
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+// Tax handles GET /api/reports/tax
+// Totals the approved tax-type line items of year (default: current year) by
+// month and store, e.g. the sales tax of a small-business filing. format=csv
+// downloads it as CSV instead of JSON.
+func (h *ReportHandler) Tax(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	year, err := parseOptionalInt(query, "year")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respondError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	report, err := h.repo.ForUser(requestUserID(r)).TaxReport(year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to build tax report")
+		return
+	}
+	if format != "csv" {
+		respondJSON(w, http.StatusOK, report)
+		return
+	}
+
+	var out bytes.Buffer
+	if err := export.WriteTaxCSV(&out, report); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to write tax report")
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="budget-tax-%d.csv"`, year),
+	)
+	w.Write(out.Bytes())
+}
+
+// Deductible handles GET /api/reports/deductible
+// Returns the approved deductible expenses of year (default: current year)
+// by receipt, with the other lines of their receipts. format=zip downloads
+// it as a zip of CSVs for a tax filing, see export.WriteDeductibleZip.
+func (h *ReportHandler) Deductible(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	year, err := parseOptionalInt(query, "year")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		respondError(w, http.StatusBadRequest, "format must be json or zip")
+		return
+	}
+
+	report, err := h.repo.ForUser(requestUserID(r)).DeductibleReport(year)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to build deductible report")
+		return
+	}
+	if format != "zip" {
+		respondJSON(w, http.StatusOK, report)
+		return
+	}
+
+	var out bytes.Buffer
+	if err := export.WriteDeductibleZip(&out, report); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to write deductible report")
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="budget-deductible-%d.zip"`, year),
+	)
+	w.Write(out.Bytes())
+}
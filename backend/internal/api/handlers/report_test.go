@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"archive/zip"
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReportHandler_Pivot(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewActualExpenseRepository(db)
+	for _, e := range []struct {
+		source string
+		typ    models.ExpenseType
+		amount float64
+		date   string
+	}{
+		{"Publix", models.ExpenseTypeWeekly, 10, "2025-01-05"},
+		{"Publix", models.ExpenseTypeWeekly, 5, "2025-01-20"},
+		{"Costco", models.ExpenseTypeMonthly, 40, "2025-03-01"},
+		{"Costco", models.ExpenseTypeWeekly, 2.5, "2025-03-02"},
+		{"Publix", models.ExpenseTypeWeekly, 99, "2024-12-31"},
+	} {
+		date, _ := time.Parse("2006-01-02", e.date)
+		if _, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Item", Source: e.source, ActualAmount: e.amount,
+			ExpenseType: e.typ, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/reports/pivot", NewReportHandler(repo).Pivot)
+	pivot := func(query string) models.PivotTable {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/pivot"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var table models.PivotTable
+		if err := json.NewDecoder(rec.Body).Decode(&table); err != nil {
+			t.Fatalf("Failed to decode pivot table: %v", err)
+		}
+		return table
+	}
+
+	// Defaults to categories by month, every month and category listed
+	table := pivot("?year=2025")
+	if table.Rows != models.PivotCategory || table.Cols != models.PivotMonth ||
+		len(table.RowKeys) != 4 || len(table.ColKeys) != 12 {
+		t.Fatalf("Unexpected layout %+v", table)
+	}
+	if table.Cells[0][0] != 15 || table.Cells[0][2] != 2.5 || table.Cells[1][2] != 40 {
+		t.Errorf("Unexpected cells %v", table.Cells)
+	}
+	if table.RowTotals[0] != 17.5 || table.ColTotals[2] != 42.5 || table.Total != 57.5 {
+		t.Errorf("Unexpected totals %v %v %.2f", table.RowTotals, table.ColTotals, table.Total)
+	}
+
+	table = pivot("?rows=source&cols=category&year=2025")
+	if !slices.Equal(table.RowKeys, []string{"Costco", "Publix"}) {
+		t.Fatalf("Expected the stores by name, got %v", table.RowKeys)
+	}
+	if !slices.Equal(table.Cells[0], []float64{2.5, 40, 0, 0}) || table.RowTotals[1] != 15 {
+		t.Errorf("Unexpected cells %v, totals %v", table.Cells, table.RowTotals)
+	}
+
+	for _, query := range []string{"?rows=month&cols=month", "?rows=store", "?year=abc"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/pivot"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestReportHandler_Weekdays(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewActualExpenseRepository(db)
+	for _, e := range []struct {
+		typ    models.ExpenseType
+		amount float64
+		date   string
+	}{
+		{models.ExpenseTypeWeekly, 10, "2025-01-06"}, // Monday
+		{models.ExpenseTypeWeekly, 40, "2025-01-08"}, // Wednesday
+		{models.ExpenseTypeWeekly, 30, "2025-01-11"}, // Saturday
+		{models.ExpenseTypeMisc, 20, "2025-01-11"},
+		{models.ExpenseTypeMisc, 50, "2025-01-18"},   // Saturday
+		{models.ExpenseTypeWeekly, 10, "2025-01-19"}, // Sunday
+		{models.ExpenseTypeWeekly, 99, "2025-01-05"}, // before the range
+	} {
+		date, _ := time.Parse("2006-01-02", e.date)
+		if _, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Item", Source: "Publix", ActualAmount: e.amount,
+			ExpenseType: e.typ, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/reports/weekdays", NewReportHandler(repo).Weekdays)
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/weekdays"+query, nil))
+		return rec
+	}
+	report := func(query string) models.WeekdayReport {
+		t.Helper()
+		rec := get(query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var report models.WeekdayReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode weekday report: %v", err)
+		}
+		return report
+	}
+
+	// Two full weeks, Monday to Sunday
+	got := report("?from=2025-01-06&to=2025-01-19")
+	if got.Total != 160 || len(got.Days) != 7 || got.Days[0].Weekday != "Monday" {
+		t.Fatalf("Unexpected report %+v", got)
+	}
+	saturday := got.Days[5]
+	if saturday.Weekday != "Saturday" || saturday.Days != 2 || saturday.Count != 3 ||
+		saturday.Total != 100 || saturday.Average != 50 || saturday.Share != 0.625 {
+		t.Errorf("Unexpected Saturday %+v", saturday)
+	}
+	if got.Weekend.Days != 4 || got.Weekend.Total != 110 || got.Weekend.Average != 27.5 {
+		t.Errorf("Unexpected weekend %+v", got.Weekend)
+	}
+	if got.Weekdays.Days != 10 || got.Weekdays.Count != 2 || got.Weekdays.Total != 50 || got.Weekdays.Average != 5 {
+		t.Errorf("Unexpected weekdays %+v", got.Weekdays)
+	}
+
+	if got := report("?from=2025-01-06&to=2025-01-19&type=misc"); got.Total != 70 || got.Days[5].Total != 70 {
+		t.Errorf("Unexpected misc report %+v", got)
+	}
+
+	// Twelve weeks up to today by default
+	got = report("")
+	days := 0
+	for _, d := range got.Days {
+		days += d.Days
+	}
+	if days != 84 || got.Days[0].Days != 12 {
+		t.Errorf("Expected twelve weeks by default, got %+v", got)
+	}
+
+	for _, query := range []string{"?from=2025-13-01", "?from=2025-01-19&to=2025-01-06", "?type=bogus"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, rec.Code)
+		}
+	}
+}
+
+func TestReportHandler_Variance(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expectedRepo := repository.NewExpectedExpenseRepository(db)
+	repo := repository.NewActualExpenseRepository(db)
+	milk, err := expectedRepo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ExpectedAmount: 5, ExpenseType: models.ExpenseTypeWeekly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	rent, err := expectedRepo.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Rent", Source: "Landlord", ExpectedAmount: 1000, ExpenseType: models.ExpenseTypeMonthly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	for _, e := range []struct {
+		expectedID *int64
+		amount     float64
+		date       string
+	}{
+		{&milk.ID, 6, "2025-03-03"},
+		{&milk.ID, 19, "2025-03-17"},
+		{&rent.ID, 950, "2025-03-01"},
+		{&milk.ID, 50, "2025-02-20"},
+		{nil, 30, "2025-03-05"},
+	} {
+		date, _ := time.Parse("2006-01-02", e.date)
+		if _, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Item", Source: "Store", ActualAmount: e.amount, ExpenseType: models.ExpenseTypeMisc,
+			ExpectedExpenseID: e.expectedID, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/reports/variance", NewReportHandler(repo).Variance)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/variance?month=3&year=2025", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var report models.VarianceReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode variance report: %v", err)
+	}
+
+	if len(report.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %+v", report.Items)
+	}
+	// Weekly milk is expected four times a month: $25 spent of $20
+	item := report.Items[0]
+	if item.ItemName != "Milk" || item.Expected != 20 || item.Actual != 25 || item.MatchedCount != 2 ||
+		item.Variance != 5 || item.VariancePercent == nil || *item.VariancePercent != 25 {
+		t.Errorf("Unexpected milk variance %+v", item)
+	}
+	item = report.Items[1]
+	if item.Variance != -50 || item.VariancePercent == nil || *item.VariancePercent != -5 {
+		t.Errorf("Unexpected rent variance %+v", item)
+	}
+	if report.ExpectedTotal != 1020 || report.ActualTotal != 975 || report.Variance != -45 ||
+		report.UnmatchedTotal != 30 {
+		t.Errorf("Unexpected totals %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/variance?month=13", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid month, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestReportHandler_Tax(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewActualExpenseRepository(db)
+	for _, e := range []struct {
+		source  string
+		typ     models.ExpenseType
+		amount  float64
+		date    string
+		pending bool
+	}{
+		{"Costco", models.ExpenseTypeTax, 1.2, "2025-03-01", false},
+		{"costco ", models.ExpenseTypeTax, 0.8, "2025-03-15", false},
+		{"Costco", models.ExpenseTypeWeekly, 40, "2025-03-01", false},
+		{"Publix", models.ExpenseTypeTax, 0.45, "2025-01-05", false},
+		{"Publix", models.ExpenseTypeTax, 9, "2025-01-06", true},
+		{"Publix", models.ExpenseTypeTax, 3, "2024-12-31", false},
+	} {
+		date, _ := time.Parse("2006-01-02", e.date)
+		expense, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Sales tax", Source: e.source, ActualAmount: e.amount,
+			ExpenseType: e.typ, ReceiptDate: &date,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		if e.pending {
+			if _, err := db.Exec(`UPDATE actual_expenses SET pending_approval = 1 WHERE id = ?`, expense.ID); err != nil {
+				t.Fatalf("Failed to hold expense: %v", err)
+			}
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/reports/tax", NewReportHandler(repo).Tax)
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/tax"+query, nil))
+		return rec
+	}
+
+	rec := get("?year=2025")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var report models.TaxReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode tax report: %v", err)
+	}
+	// Only approved tax items of the year, one store however it is spelled
+	want := []models.TaxReportRow{
+		{Month: 1, Source: "Publix", Items: 1, Total: 0.45},
+		{Month: 3, Source: "Costco", Items: 2, Total: 2},
+	}
+	if !slices.Equal(report.Rows, want) {
+		t.Errorf("Expected rows %+v, got %+v", want, report.Rows)
+	}
+	if report.Total != 2.45 || report.Items != 3 || report.MonthTotals[0] != 0.45 || report.MonthTotals[2] != 2 {
+		t.Errorf("Unexpected totals %+v", report)
+	}
+
+	rec = get("?year=2025&format=csv")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("Expected a CSV, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="budget-tax-2025.csv"` {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+	if body := rec.Body.String(); body != "month,source,items,tax\n2025-01,Publix,1,0.45\n2025-03,Costco,2,2.00\ntotal,,3,2.45\n" {
+		t.Errorf("Unexpected CSV:\n%s", body)
+	}
+
+	for _, query := range []string{"?year=abc", "?format=pdf"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestReportHandler_Deductible(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewActualExpenseRepository(db)
+	for _, e := range []struct {
+		item       string
+		amount     float64
+		date       string
+		receipt    int64
+		deductible bool
+	}{
+		{"Donation", 50, "2025-11-02", 7, true},
+		{"Coffee", 3.5, "2025-11-02", 7, false},
+		{"Donation", 25, "2025-04-10", 0, true},
+		{"Donation", 10, "2024-12-30", 0, true},
+		{"Groceries", 80, "2025-05-01", 0, false},
+	} {
+		date, _ := time.Parse("2006-01-02", e.date)
+		if _, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: e.item, Source: "Church", ActualAmount: e.amount, ExpenseType: models.ExpenseTypeMisc,
+			ReceiptDate: &date, ReceiptNumber: e.receipt, Deductible: e.deductible,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/reports/deductible", NewReportHandler(repo).Deductible)
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/reports/deductible"+query, nil))
+		return rec
+	}
+
+	rec := get("?year=2025")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var report models.DeductibleReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode deductible report: %v", err)
+	}
+	// Deductible items of the year by receipt, oldest first, with the other
+	// lines of their receipts
+	if report.Items != 2 || report.Total != 75 || len(report.Receipts) != 2 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	first, second := report.Receipts[0], report.Receipts[1]
+	if first.ReceiptNumber != 0 || len(first.Items) != 1 || first.Deductible != 25 {
+		t.Errorf("Unexpected first receipt %+v", first)
+	}
+	if second.ReceiptNumber != 7 || len(second.Items) != 2 || second.Total != 53.5 || second.Deductible != 50 {
+		t.Errorf("Unexpected second receipt %+v", second)
+	}
+
+	rec = get("?year=2025&format=zip")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected a zip, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="budget-deductible-2025.zip"` {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	wantNames := []string{"deductible-2025.csv", "receipts/2025-04-10-item-3.csv", "receipts/2025-11-02-7.csv"}
+	if !slices.Equal(names, wantNames) {
+		t.Fatalf("Expected files %v, got %v", wantNames, names)
+	}
+	summary, err := archive.File[0].Open()
+	if err != nil {
+		t.Fatalf("Failed to open summary: %v", err)
+	}
+	defer summary.Close()
+	body, _ := io.ReadAll(summary)
+	want := "date,receipt,source,item,type,amount\n" +
+		"2025-04-10,,Church,Donation,misc,25.00\n" +
+		"2025-11-02,7,Church,Donation,misc,50.00\n" +
+		"total,,,,,75.00\n"
+	if string(body) != want {
+		t.Errorf("Unexpected summary:\n%s", body)
+	}
+
+	for _, query := range []string{"?year=abc", "?format=pdf"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RuleHandler handles the category rules that set the expense type of new
+// expenses
+type RuleHandler struct {
+	repo *repository.CategoryRuleRepository
+}
+
+// NewRuleHandler creates a new RuleHandler
+func NewRuleHandler(repo *repository.CategoryRuleRepository) *RuleHandler {
+	return &RuleHandler{repo: repo}
+}
+
+// List handles GET /api/rules
+// Rules are listed in evaluation order, disabled ones included.
+func (h *RuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.repo.ForUser(requestUserID(r)).List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch rules")
+		return
+	}
+	respondJSON(w, http.StatusOK, rules)
+}
+
+// Create handles POST /api/rules
+func (h *RuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CategoryRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := h.repo.ForUser(requestUserID(r)).Create(&req)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create rule")
+		return
+	}
+	respondJSON(w, http.StatusCreated, rule)
+}
+
+// Get handles GET /api/rules/{id}
+func (h *RuleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+
+	rule, err := h.repo.ForUser(requestUserID(r)).Get(id)
+	if err != nil {
+		respondRuleError(w, err, "Failed to fetch rule")
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// Update handles PUT /api/rules/{id}
+// The rule is replaced with the request body.
+func (h *RuleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+
+	var req models.CategoryRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := h.repo.ForUser(requestUserID(r)).Update(id, &req)
+	if err != nil {
+		respondRuleError(w, err, "Failed to update rule")
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// Delete handles DELETE /api/rules/{id}
+func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+
+	if err := h.repo.ForUser(requestUserID(r)).Delete(id); err != nil {
+		respondRuleError(w, err, "Failed to delete rule")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Test handles POST /api/rules/test
+// Runs the enabled rules against a sample expense and returns the rule that
+// would set its expense type. Nothing is stored.
+func (h *RuleHandler) Test(w http.ResponseWriter, r *http.Request) {
+	var req models.RuleTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.repo.ForUser(requestUserID(r)).Test(&req)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to test rules")
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// Simulate handles POST /api/rules/simulate
+// Runs every rule, disabled ones included, against the expenses of the last
+// months and reports the expense types that would change, how often each rule
+// wins and the rules that conflict. Nothing is changed.
+func (h *RuleHandler) Simulate(w http.ResponseWriter, r *http.Request) {
+	var req models.RuleSimulationRequest
+	// An empty body simulates the default months
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sim, err := h.repo.ForUser(requestUserID(r)).Simulate(req.Months, time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to simulate rules")
+		return
+	}
+	respondJSON(w, http.StatusOK, sim)
+}
+
+func respondRuleError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrRuleNotFound) {
+		respondError(w, http.StatusNotFound, "Rule not found")
+		return
+	}
+	respondError(w, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleHandler(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewRuleHandler(repository.NewCategoryRuleRepository(db))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/rules", handler.List)
+	mux.HandleFunc("POST /api/rules", handler.Create)
+	mux.HandleFunc("POST /api/rules/test", handler.Test)
+	mux.HandleFunc("GET /api/rules/{id}", handler.Get)
+	mux.HandleFunc("PUT /api/rules/{id}", handler.Update)
+	mux.HandleFunc("DELETE /api/rules/{id}", handler.Delete)
+
+	do := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	create := func(body string) models.CategoryRule {
+		t.Helper()
+		rec := do("POST", "/api/rules", body, 1)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var rule models.CategoryRule
+		if err := json.NewDecoder(rec.Body).Decode(&rule); err != nil {
+			t.Fatalf("Failed to decode rule: %v", err)
+		}
+		return rule
+	}
+	test := func(body string, userID int64) models.RuleTestResult {
+		t.Helper()
+		rec := do("POST", "/api/rules/test", body, userID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var result models.RuleTestResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		return result
+	}
+
+	for _, body := range []string{
+		`{"name": "", "source_contains": "Shell", "expense_type": "misc"}`,
+		`{"name": "Nothing", "expense_type": "misc"}`,
+		`{"name": "Fuel", "source_contains": "Shell", "expense_type": "fuel"}`,
+		`{"name": "Range", "min_amount": 5, "max_amount": 3, "expense_type": "misc"}`,
+		`{"name": "Negative", "max_amount": -1, "expense_type": "misc"}`,
+	} {
+		if rec := do("POST", "/api/rules", body, 1); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	fuel := create(`{"name": "Fuel", "source_contains": "shell", "expense_type": "Weekly"}`)
+	if !fuel.Enabled || fuel.ExpenseType != models.ExpenseTypeWeekly {
+		t.Errorf("Unexpected rule %+v", fuel)
+	}
+	coffee := create(`{"name": "Coffee", "source_contains": "Starbucks", "max_amount": 3, "priority": 10, "expense_type": "misc"}`)
+
+	for _, tc := range []struct {
+		body string
+		rule int64
+	}{
+		{`{"item_name": "Unleaded", "source": "SHELL #123", "amount": 40}`, fuel.ID},
+		{`{"item_name": "Latte", "source": "Starbucks", "amount": 2.5}`, coffee.ID},
+		{`{"item_name": "Beans", "source": "Starbucks", "amount": 15}`, 0},
+	} {
+		result := test(tc.body, 1)
+		if tc.rule == 0 {
+			if result.Matched {
+				t.Errorf("%s: expected no match, got %+v", tc.body, result.Rule)
+			}
+		} else if !result.Matched || result.Rule.ID != tc.rule {
+			t.Errorf("%s: expected rule %d, got %+v", tc.body, tc.rule, result)
+		}
+	}
+
+	// The higher priority wins, and rules are listed in that order
+	create(`{"name": "Shell cafe", "source_contains": "Shell", "item_contains": "coffee", "priority": 5, "expense_type": "misc"}`)
+	if result := test(`{"item_name": "Coffee", "source": "Shell", "amount": 2}`, 1); result.ExpenseType != models.ExpenseTypeMisc {
+		t.Errorf("Expected the higher priority rule to win, got %+v", result)
+	}
+	var rules []models.CategoryRule
+	json.NewDecoder(do("GET", "/api/rules", "", 1).Body).Decode(&rules)
+	if len(rules) != 3 || rules[0].ID != coffee.ID || rules[2].ID != fuel.ID {
+		t.Errorf("Unexpected rule order %+v", rules)
+	}
+
+	// Disabled rules are kept but not applied
+	path := fmt.Sprintf("/api/rules/%d", fuel.ID)
+	rec := do("PUT", path, `{"name": "Fuel", "source_contains": "Shell", "enabled": false, "expense_type": "weekly"}`, 1)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if result := test(`{"item_name": "Unleaded", "source": "Shell", "amount": 40}`, 1); result.Matched {
+		t.Errorf("Expected the disabled rule to be skipped, got %+v", result.Rule)
+	}
+
+	// Other users do not see the rule
+	if rec := do("GET", path, "", 2); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+	if result := test(`{"item_name": "Latte", "source": "Starbucks", "amount": 2}`, 2); result.Matched {
+		t.Errorf("Expected no rules for another user, got %+v", result.Rule)
+	}
+
+	if rec := do("DELETE", path, "", 1); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := do("DELETE", path, "", 1); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestActualExpenseCreate_AppliesCategoryRules(t *testing.T) {
+	db, _, actualRepo, mux := setupActualExpenseTest(t)
+	defer db.Close()
+
+	if _, err := repository.NewCategoryRuleRepository(db).Create(&models.CategoryRuleRequest{
+		Name: "Fuel", SourceContains: "Shell", ExpenseType: models.ExpenseTypeWeekly,
+	}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if _, err := repository.NewCategoryRuleRepository(db).Create(&models.CategoryRuleRequest{
+		Name: "Charity", SourceContains: "Red Cross", ExpenseType: models.ExpenseTypeMisc, Deductible: true,
+	}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	body := `{"expenses": [
+		{"item_name": "Unleaded", "source": "Shell", "actual_amount": 40, "expense_type": "misc"},
+		{"item_name": "Sofa", "source": "IKEA", "actual_amount": 400, "expense_type": "misc"},
+		{"item_name": "Donation", "source": "Red Cross", "actual_amount": 20, "expense_type": "weekly"}
+	]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/actual-expenses/bulk", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	expenses, _ := actualRepo.GetAll()
+	types := map[string]models.ExpenseType{}
+	for _, e := range expenses {
+		types[e.ItemName] = e.ExpenseType
+	}
+	if types["Unleaded"] != models.ExpenseTypeWeekly || types["Sofa"] != models.ExpenseTypeMisc ||
+		types["Donation"] != models.ExpenseTypeMisc {
+		t.Errorf("Unexpected expense types %v", types)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/actual-expenses?deductible=true", nil))
+	var list ActualExpenseListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode expenses: %v", err)
+	}
+	if len(list.Expenses) != 1 || list.Expenses[0].ItemName != "Donation" || !list.Expenses[0].Deductible {
+		t.Errorf("Expected the donation to be tagged deductible, got %+v", list.Expenses)
+	}
+}
+
+func TestRuleHandler_Simulate(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// The expenses exist before the rules, so they keep their types
+	actualRepo := repository.NewActualExpenseRepository(db)
+	now := time.Now().UTC()
+	old := now.AddDate(-3, 0, 0)
+	for _, e := range []struct {
+		item, source string
+		amount       float64
+		expenseType  models.ExpenseType
+		date         *time.Time
+	}{
+		{"Unleaded", "Shell", 40, models.ExpenseTypeMisc, &now},
+		{"Coffee", "Shell", 3, models.ExpenseTypeWeekly, &now},
+		{"Latte", "Starbucks", 2.5, models.ExpenseTypeMisc, &now},
+		{"Sofa", "IKEA", 400, models.ExpenseTypeMisc, &now},
+		{"Unleaded", "Shell", 35, models.ExpenseTypeMisc, &old},
+	} {
+		if _, err := actualRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: e.item, Source: e.source, ActualAmount: e.amount,
+			ExpenseType: e.expenseType, ReceiptDate: e.date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	ruleRepo := repository.NewCategoryRuleRepository(db)
+	disabled, maxAmount := false, 3.0
+	var rules []*models.CategoryRule
+	for _, req := range []models.CategoryRuleRequest{
+		{Name: "Fuel", SourceContains: "Shell", ExpenseType: models.ExpenseTypeWeekly},
+		{Name: "Shell cafe", SourceContains: "shell", ItemContains: "coffee", ExpenseType: models.ExpenseTypeMisc},
+		{Name: "Coffee", SourceContains: "Starbucks", MaxAmount: &maxAmount, Enabled: &disabled, ExpenseType: models.ExpenseTypeMisc},
+	} {
+		rule, err := ruleRepo.Create(&req)
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	fuel, cafe, coffee := rules[0], rules[1], rules[2]
+
+	handler := NewRuleHandler(ruleRepo)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/rules/simulate", handler.Simulate)
+
+	for _, body := range []string{`{"months": 0.5}`, `{"months": -1}`, `{"months": 25}`} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/rules/simulate", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/rules/simulate", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var sim models.RuleSimulation
+	if err := json.NewDecoder(rec.Body).Decode(&sim); err != nil {
+		t.Fatalf("Failed to decode simulation: %v", err)
+	}
+
+	if sim.Months != models.DefaultSimulationMonths || sim.Checked != 4 || sim.Changed != 1 {
+		t.Errorf("Unexpected simulation %+v", sim)
+	}
+	if len(sim.Changes) != 1 || sim.Changes[0].ItemName != "Unleaded" ||
+		sim.Changes[0].From != models.ExpenseTypeMisc || sim.Changes[0].To != models.ExpenseTypeWeekly ||
+		sim.Changes[0].RuleID != fuel.ID {
+		t.Errorf("Unexpected changes %+v", sim.Changes)
+	}
+
+	want := []models.RuleSimulationStat{
+		{RuleID: fuel.ID, Name: "Fuel", Enabled: true, Matched: 2, Applied: 2, Changed: 1},
+		{RuleID: cafe.ID, Name: "Shell cafe", Enabled: true, Matched: 1, Shadowed: true},
+		{RuleID: coffee.ID, Name: "Coffee", Matched: 1, Applied: 1},
+	}
+	if len(sim.Rules) != len(want) {
+		t.Fatalf("Expected %d rule stats, got %+v", len(want), sim.Rules)
+	}
+	for i := range want {
+		if sim.Rules[i] != want[i] {
+			t.Errorf("Rule %d: expected %+v, got %+v", i, want[i], sim.Rules[i])
+		}
+	}
+
+	// The older rule only wins the coffee on a tie
+	if len(sim.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", sim.Conflicts)
+	}
+	if c := sim.Conflicts[0]; c.RuleID != fuel.ID || c.OtherRuleID != cafe.ID || c.Expenses != 1 || !c.SamePriority {
+		t.Errorf("Unexpected conflict %+v", c)
+	}
+
+	// Nothing was changed
+	expenses, _ := actualRepo.GetAll()
+	for _, e := range expenses {
+		if e.ItemName == "Unleaded" && e.ExpenseType != models.ExpenseTypeMisc {
+			t.Errorf("Expected the simulation to leave expense %d alone, got %s", e.ID, e.ExpenseType)
+		}
+	}
+}
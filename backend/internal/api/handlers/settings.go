@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SettingsHandler handles application settings HTTP requests
+type SettingsHandler struct {
+	repo *repository.SettingsRepository
+}
+
+// NewSettingsHandler creates a new SettingsHandler
+func NewSettingsHandler(repo *repository.SettingsRepository) *SettingsHandler {
+	return &SettingsHandler{repo: repo}
+}
+
+// GetDefaultBudget handles GET /api/settings/default-budget
+func (h *SettingsHandler) GetDefaultBudget(w http.ResponseWriter, r *http.Request) {
+	budget, err := h.repo.GetDefaultBudget()
+	if err != nil {
+		if errors.Is(err, repository.ErrSettingNotFound) {
+			respondError(w, http.StatusNotFound, "No default budget set")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch default budget")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// SetDefaultBudget handles PUT /api/settings/default-budget
+// The default is used by the budget status for months without a budget.
+func (h *SettingsHandler) SetDefaultBudget(w http.ResponseWriter, r *http.Request) {
+	var budget models.DefaultBudget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := budget.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.SetDefaultBudget(&budget); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save default budget")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// DeleteDefaultBudget handles DELETE /api/settings/default-budget
+func (h *SettingsHandler) DeleteDefaultBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.DeleteDefaultBudget(); err != nil {
+		if errors.Is(err, repository.ErrSettingNotFound) {
+			respondError(w, http.StatusNotFound, "No default budget set")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete default budget")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetApprovalRule handles GET /api/settings/approval-rule
+func (h *SettingsHandler) GetApprovalRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.repo.GetApprovalRule()
+	if err != nil {
+		if errors.Is(err, repository.ErrSettingNotFound) {
+			respondError(w, http.StatusNotFound, "No approval rule set")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch approval rule")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// SetApprovalRule handles PUT /api/settings/approval-rule
+// Actual expenses created over the amount are pending approval and left out
+// of totals until approved. Existing expenses are not affected.
+func (h *SettingsHandler) SetApprovalRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.ApprovalRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := rule.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.SetApprovalRule(&rule); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save approval rule")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// DeleteApprovalRule handles DELETE /api/settings/approval-rule
+// Expenses already pending stay pending until approved.
+func (h *SettingsHandler) DeleteApprovalRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.DeleteApprovalRule(); err != nil {
+		if errors.Is(err, repository.ErrSettingNotFound) {
+			respondError(w, http.StatusNotFound, "No approval rule set")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete approval rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBudgetFreeze handles GET /api/budgets/freeze
+func (h *SettingsHandler) GetBudgetFreeze(w http.ResponseWriter, r *http.Request) {
+	freeze, err := h.repo.GetBudgetFreeze()
+	if err != nil {
+		if errors.Is(err, repository.ErrSettingNotFound) {
+			respondError(w, http.StatusNotFound, "Budget is not frozen")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget freeze")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, freeze)
+}
+
+// FreezeBudget handles POST /api/budgets/freeze
+// The body, with an optional reason, may be left out. While the budget is
+// frozen new expenses need ?confirm=true and each one recorded is notified.
+// Freezing a frozen budget only updates the reason. The user who froze the
+// budget first is notified of the expenses recorded.
+func (h *SettingsHandler) FreezeBudget(w http.ResponseWriter, r *http.Request) {
+	var freeze models.BudgetFreeze
+	if err := json.NewDecoder(r.Body).Decode(&freeze); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	freeze.FrozenAt = time.Now().UTC()
+	freeze.FrozenBy = requestUserID(r)
+	current, err := h.repo.GetBudgetFreeze()
+	switch {
+	case err == nil:
+		freeze.FrozenAt = current.FrozenAt
+		freeze.FrozenBy = current.FrozenBy
+	case !errors.Is(err, repository.ErrSettingNotFound):
+		respondError(w, http.StatusInternalServerError, "Failed to fetch budget freeze")
+		return
+	}
+
+	if err := h.repo.SetBudgetFreeze(&freeze); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to freeze budget")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, freeze)
+}
+
+// UnfreezeBudget handles DELETE /api/budgets/freeze
+func (h *SettingsHandler) UnfreezeBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.DeleteBudgetFreeze(); err != nil {
+		if errors.Is(err, repository.ErrSettingNotFound) {
+			respondError(w, http.StatusNotFound, "Budget is not frozen")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to unfreeze budget")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
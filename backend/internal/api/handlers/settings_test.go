@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupSettingsTest wires the settings and notification handlers against a fresh database
+func setupSettingsTest(t *testing.T) (*repository.DB, *repository.BudgetRepository, *http.ServeMux) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	budgetRepo := repository.NewBudgetRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	settingsHandler := NewSettingsHandler(settingsRepo)
+	notificationHandler := NewNotificationHandler(
+		budgetRepo,
+		repository.NewExpectedExpenseRepository(db),
+		repository.NewActualExpenseRepository(db),
+		settingsRepo,
+		nil,
+		nil,
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/settings/default-budget", settingsHandler.GetDefaultBudget)
+	mux.HandleFunc("PUT /api/settings/default-budget", settingsHandler.SetDefaultBudget)
+	mux.HandleFunc("DELETE /api/settings/default-budget", settingsHandler.DeleteDefaultBudget)
+	mux.HandleFunc("GET /api/budgets/freeze", settingsHandler.GetBudgetFreeze)
+	mux.HandleFunc("POST /api/budgets/freeze", settingsHandler.FreezeBudget)
+	mux.HandleFunc("DELETE /api/budgets/freeze", settingsHandler.UnfreezeBudget)
+	mux.HandleFunc("GET /api/notifications/budget-status", notificationHandler.BudgetStatus)
+	mux.HandleFunc("GET /api/notifications/weekly-status", notificationHandler.WeeklyStatus)
+
+	return db, budgetRepo, mux
+}
+
+func fetchBudgetStatus(t *testing.T, mux *http.ServeMux) BudgetStatusResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/notifications/budget-status?month=3&year=2025", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var status BudgetStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode budget status: %v", err)
+	}
+	return status
+}
+
+func TestDefaultBudget_Lifecycle(t *testing.T) {
+	db, _, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/settings/default-budget", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d before setting, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	body := []byte(`{"amount": 2500}`)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/settings/default-budget", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/settings/default-budget", nil))
+	var budget models.DefaultBudget
+	if err := json.NewDecoder(rec.Body).Decode(&budget); err != nil {
+		t.Fatalf("Failed to decode default budget: %v", err)
+	}
+	if budget.Amount != 2500 || budget.NotificationThreshold != 0.8 {
+		t.Errorf("Expected 2500 with default threshold 0.8, got %+v", budget)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/settings/default-budget", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/settings/default-budget", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d deleting twice, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestDefaultBudget_Invalid(t *testing.T) {
+	db, _, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	for _, body := range []string{`{"amount": 0}`, `{"amount": 100, "notification_threshold": 1.5}`, `{`} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/settings/default-budget", bytes.NewReader([]byte(body))))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestBudgetFreeze_Lifecycle(t *testing.T) {
+	db, _, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/budgets/freeze", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d before freezing, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// The body is optional
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/budgets/freeze", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d freezing, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var freeze models.BudgetFreeze
+	if err := json.Unmarshal(rec.Body.Bytes(), &freeze); err != nil {
+		t.Fatalf("Failed to decode freeze: %v", err)
+	}
+	if freeze.FrozenAt.IsZero() || freeze.Reason != "" {
+		t.Errorf("Unexpected freeze: %+v", freeze)
+	}
+
+	// Freezing again keeps the time it started
+	rec = httptest.NewRecorder()
+	body := bytes.NewReader([]byte(`{"reason": "lockdown month"}`))
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/budgets/freeze", body))
+	var updated models.BudgetFreeze
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("Failed to decode freeze: %v", err)
+	}
+	if updated.Reason != "lockdown month" || !updated.FrozenAt.Equal(freeze.FrozenAt) {
+		t.Errorf("Expected the reason updated and the start kept, got %+v", updated)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/budgets/freeze", bytes.NewReader([]byte(`{`))))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid body, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/budgets/freeze", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d unfreezing, got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/budgets/freeze", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d unfreezing twice, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestBudgetStatus_DefaultBudgetFallback(t *testing.T) {
+	db, budgetRepo, mux := setupSettingsTest(t)
+	defer db.Close()
+
+	status := fetchBudgetStatus(t, mux)
+	if status.CurrentBudget != nil || status.IsDefault {
+		t.Errorf("Expected no budget without a default, got %+v", status)
+	}
+
+	body := []byte(`{"amount": 1000, "notification_threshold": 0.5}`)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/settings/default-budget", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Failed to set default budget: %d", rec.Code)
+	}
+
+	status = fetchBudgetStatus(t, mux)
+	if !status.IsDefault || status.CurrentBudget == nil {
+		t.Fatalf("Expected the default budget, got %+v", status)
+	}
+	if status.CurrentBudget.Amount != 1000 || status.CurrentBudget.Month != 3 ||
+		status.CurrentBudget.Year != 2025 || status.CurrentBudget.ID != 0 {
+		t.Errorf("Unexpected default budget: %+v", status.CurrentBudget)
+	}
+
+	// An explicit budget always wins over the default
+	if _, err := budgetRepo.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 3000, NotificationThreshold: 0.8,
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	status = fetchBudgetStatus(t, mux)
+	if status.IsDefault || status.CurrentBudget == nil || status.CurrentBudget.Amount != 3000 {
+		t.Errorf("Expected the explicit budget, got %+v", status)
+	}
+}
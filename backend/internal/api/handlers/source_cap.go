@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// SourceCapHandler handles the monthly spending caps of single sources
+type SourceCapHandler struct {
+	repo *repository.SourceCapRepository
+}
+
+// NewSourceCapHandler creates a new SourceCapHandler
+func NewSourceCapHandler(repo *repository.SourceCapRepository) *SourceCapHandler {
+	return &SourceCapHandler{repo: repo}
+}
+
+// List handles GET /api/source-caps
+func (h *SourceCapHandler) List(w http.ResponseWriter, r *http.Request) {
+	caps, err := h.repo.ForUser(requestUserID(r)).List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch source caps")
+		return
+	}
+	respondJSON(w, http.StatusOK, caps)
+}
+
+// Create handles POST /api/source-caps
+func (h *SourceCapHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.SourceCapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c, err := h.repo.ForUser(requestUserID(r)).Create(&req)
+	if err != nil {
+		respondSourceCapError(w, err, "Failed to create source cap")
+		return
+	}
+	respondJSON(w, http.StatusCreated, c)
+}
+
+// Update handles PUT /api/source-caps/{id}
+// The cap is replaced with the request body.
+func (h *SourceCapHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid source cap ID")
+		return
+	}
+
+	var req models.SourceCapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c, err := h.repo.ForUser(requestUserID(r)).Update(id, &req)
+	if err != nil {
+		respondSourceCapError(w, err, "Failed to update source cap")
+		return
+	}
+	respondJSON(w, http.StatusOK, c)
+}
+
+// Delete handles DELETE /api/source-caps/{id}
+func (h *SourceCapHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid source cap ID")
+		return
+	}
+
+	if err := h.repo.ForUser(requestUserID(r)).Delete(id); err != nil {
+		respondSourceCapError(w, err, "Failed to delete source cap")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func respondSourceCapError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrSourceCapNotFound):
+		respondError(w, http.StatusNotFound, "Source cap not found")
+	case errors.Is(err, repository.ErrSourceCapExists):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSourceCaps(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	capRepo := repository.NewSourceCapRepository(db)
+	capHandler := NewSourceCapHandler(capRepo)
+	notificationHandler := NewNotificationHandler(nil, nil, nil, nil, nil, capRepo)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/source-caps", capHandler.List)
+	mux.HandleFunc("POST /api/source-caps", capHandler.Create)
+	mux.HandleFunc("PUT /api/source-caps/{id}", capHandler.Update)
+	mux.HandleFunc("DELETE /api/source-caps/{id}", capHandler.Delete)
+	mux.HandleFunc("GET /api/notifications/source-status", notificationHandler.SourceStatus)
+
+	do := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{
+		`{"source": " ", "monthly_limit": 400}`,
+		`{"source": "Costco", "monthly_limit": 0}`,
+	} {
+		if rec := do("POST", "/api/source-caps", body, 1); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	rec := do("POST", "/api/source-caps", `{"source": " Costco ", "monthly_limit": 400}`, 1)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var costco models.SourceCap
+	if err := json.NewDecoder(rec.Body).Decode(&costco); err != nil {
+		t.Fatalf("Failed to decode cap: %v", err)
+	}
+	if costco.Source != "Costco" || costco.MonthlyLimit != 400 {
+		t.Errorf("Unexpected cap %+v", costco)
+	}
+	if rec := do("POST", "/api/source-caps", `{"source": "Publix", "monthly_limit": 200}`, 1); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+
+	// One cap per source, ignoring case; other users have their own
+	if rec := do("POST", "/api/source-caps", `{"source": "COSTCO", "monthly_limit": 300}`, 1); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a second cap, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := do("POST", "/api/source-caps", `{"source": "Costco", "monthly_limit": 300}`, 2); rec.Code != http.StatusCreated {
+		t.Errorf("Expected another user to cap the source, got %d", rec.Code)
+	}
+	path := "/api/source-caps/" + itoa(costco.ID)
+	if rec := do("PUT", path, `{"source": "Costco", "monthly_limit": 100}`, 2); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// $120 at Costco in June, spelled differently, and a held expense that
+	// does not count
+	expenses := repository.NewActualExpenseRepository(db).ForUser(1)
+	for _, e := range []struct {
+		source  string
+		amount  float64
+		pending bool
+	}{
+		{"Costco", 70, false},
+		{"costco ", 50, false},
+		{"Publix", 20, false},
+		{"Costco", 500, true},
+	} {
+		expense, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: e.source, ActualAmount: e.amount,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: testReceiptDate(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		if e.pending {
+			if _, err := db.Exec(`UPDATE actual_expenses SET pending_approval = 1 WHERE id = ?`, expense.ID); err != nil {
+				t.Fatalf("Failed to hold expense: %v", err)
+			}
+		}
+	}
+
+	status := func() SourceStatusResponse {
+		t.Helper()
+		rec := do("GET", "/api/notifications/source-status?month=6&year=2024", "", 1)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp SourceStatusResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode source status: %v", err)
+		}
+		return resp
+	}
+
+	resp := status()
+	if len(resp.Sources) != 2 || resp.Exceeded != 0 {
+		t.Fatalf("Expected two sources within their caps, got %+v", resp)
+	}
+	if s := resp.Sources[0]; s.Cap.Source != "Costco" || s.Spent != 120 || s.Remaining != 280 || s.Status != BudgetStatusSafe {
+		t.Errorf("Unexpected Costco status %+v", s)
+	}
+
+	// Lowering the cap below the spending flags the source
+	if rec := do("PUT", path, `{"source": "Costco", "monthly_limit": 100}`, 1); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	resp = status()
+	if s := resp.Sources[0]; !s.Exceeded || s.Status != BudgetStatusOver || resp.Exceeded != 1 ||
+		!strings.Contains(s.Message, "Costco budget by $20.00") {
+		t.Errorf("Expected Costco to be over its cap, got %+v", resp)
+	}
+	if s := resp.Sources[1]; s.Cap.Source != "Publix" || s.Spent != 20 || s.Exceeded {
+		t.Errorf("Unexpected Publix status %+v", s)
+	}
+
+	if rec := do("GET", "/api/notifications/source-status?month=13", "", 1); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid month, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	if rec := do("DELETE", path, "", 1); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if resp := status(); len(resp.Sources) != 1 || resp.Exceeded != 0 {
+		t.Errorf("Expected only the Publix cap left, got %+v", resp)
+	}
+}
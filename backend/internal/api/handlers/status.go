@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"budget-tracker/internal/repository"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// BuildInfo identifies the running build. The server sets it from values
+// injected with -ldflags at build time.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuiltAt   string `json:"built_at,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// StatusHandler summarizes the state of the server for a public status page
+type StatusHandler struct {
+	build     BuildInfo
+	dbMode    repository.Mode
+	settings  *repository.SettingsRepository
+	receipts  *repository.ReceiptHistoryRepository
+	startedAt time.Time
+}
+
+// NewStatusHandler creates a new StatusHandler; uptime counts from here
+func NewStatusHandler(
+	build BuildInfo,
+	dbMode repository.Mode,
+	settings *repository.SettingsRepository,
+	receipts *repository.ReceiptHistoryRepository,
+) *StatusHandler {
+	return &StatusHandler{
+		build:     build,
+		dbMode:    dbMode,
+		settings:  settings,
+		receipts:  receipts,
+		startedAt: time.Now(),
+	}
+}
+
+// StatusResponse is the response of the status page endpoint
+type StatusResponse struct {
+	Status               string          `json:"status"`
+	StartedAt            time.Time       `json:"started_at"`
+	UptimeSeconds        int64           `json:"uptime_seconds"`
+	Build                BuildInfo       `json:"build"`
+	DatabaseMode         repository.Mode `json:"database_mode"`
+	LastBackup           *time.Time      `json:"last_backup"`
+	LastReceiptProcessed *time.Time      `json:"last_receipt_processed"`
+}
+
+// Status handles GET /status
+// Only times are reported, never names or amounts, since the endpoint is
+// public. A database that cannot be read degrades the status and leaves the
+// last backup and receipt empty; the response is still 200, /readyz is
+// what load balancers should check.
+func (h *StatusHandler) Status(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := StatusResponse{
+		Status:        readyStatusReady,
+		StartedAt:     h.startedAt.UTC(),
+		UptimeSeconds: int64(now.Sub(h.startedAt).Seconds()),
+		Build:         h.build,
+		DatabaseMode:  h.dbMode,
+	}
+
+	backup, err := h.settings.GetLastBackup()
+	switch {
+	case err == nil:
+		resp.LastBackup = &backup.FinishedAt
+	case !errors.Is(err, repository.ErrSettingNotFound):
+		resp.Status = readyStatusDegraded
+	}
+	lastReceipt, err := h.receipts.LastSuccess()
+	if err != nil {
+		resp.Status = readyStatusDegraded
+	}
+	resp.LastReceiptProcessed = lastReceipt
+
+	respondJSON(w, http.StatusOK, resp)
+}
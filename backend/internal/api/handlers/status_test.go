@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"budget-tracker/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusHandler_Status(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	receipts := repository.NewReceiptHistoryRepository(db)
+	build := BuildInfo{Version: "1.4.0", Commit: "abc123", GoVersion: "go1.24"}
+	handler := NewStatusHandler(build, repository.ModeLocal, repository.NewSettingsRepository(db), receipts)
+
+	status := func() StatusResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.Status(rec, httptest.NewRequest("GET", "/status", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var resp StatusResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	resp := status()
+	if resp.Status != "ready" || resp.Build != build || resp.DatabaseMode != repository.ModeLocal ||
+		resp.StartedAt.IsZero() || resp.UptimeSeconds < 0 {
+		t.Errorf("Unexpected status: %+v", resp)
+	}
+	if resp.LastBackup != nil || resp.LastReceiptProcessed != nil {
+		t.Errorf("Expected no backup or receipt yet, got %+v", resp)
+	}
+
+	// Failed runs are not what a status page is after
+	if _, err := receipts.Record(&repository.ReceiptProcessingRecord{Status: repository.ReceiptStatusSuccess}); err != nil {
+		t.Fatalf("Failed to record receipt: %v", err)
+	}
+	if _, err := receipts.Record(&repository.ReceiptProcessingRecord{Status: repository.ReceiptStatusError}); err != nil {
+		t.Fatalf("Failed to record receipt: %v", err)
+	}
+	backup, err := repository.NewMaintenanceRepository(db).Backup(filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+
+	resp = status()
+	if resp.LastBackup == nil || !resp.LastBackup.Equal(backup.FinishedAt) {
+		t.Errorf("Expected the last backup at %v, got %v", backup.FinishedAt, resp.LastBackup)
+	}
+	if resp.LastReceiptProcessed == nil {
+		t.Error("Expected the last processed receipt")
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"errors"
+	"net/http"
+)
+
+// SuggestionHandler handles the AI suggestions for expenses entered tersely,
+// stored by the expense enrichment job
+type SuggestionHandler struct {
+	repo        *repository.ExpenseSuggestionRepository
+	expenseRepo *repository.ActualExpenseRepository
+}
+
+// NewSuggestionHandler creates a new SuggestionHandler
+func NewSuggestionHandler(
+	repo *repository.ExpenseSuggestionRepository,
+	expenseRepo *repository.ActualExpenseRepository,
+) *SuggestionHandler {
+	return &SuggestionHandler{repo: repo, expenseRepo: expenseRepo}
+}
+
+// List handles GET /api/actual-expenses/suggestions
+// Returns the pending suggestions, newest expense first.
+func (h *SuggestionHandler) List(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := h.repo.ForUser(requestUserID(r)).List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch suggestions")
+		return
+	}
+	respondJSON(w, http.StatusOK, suggestions)
+}
+
+// Accept handles POST /api/actual-expenses/{id}/suggestion/accept
+// Renames the expense and sets its type as suggested, and returns it.
+func (h *SuggestionHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid expense ID")
+		return
+	}
+
+	userID := requestUserID(r)
+	repo := h.repo.ForUser(userID)
+	suggestion, err := repo.Get(expenseID)
+	if err != nil {
+		respondSuggestionError(w, err, "Failed to fetch suggestion")
+		return
+	}
+
+	req := models.UpdateActualExpenseRequest{
+		ItemName:    &suggestion.ItemName,
+		ExpenseType: &suggestion.ExpenseType,
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, "Suggestion cannot be applied: "+err.Error())
+		return
+	}
+	expense, err := h.expenseRepo.ForUser(userID).Update(expenseID, &req)
+	if err != nil {
+		respondSuggestionError(w, err, "Failed to apply suggestion")
+		return
+	}
+	if err := repo.Resolve(expenseID, models.SuggestionAccepted); err != nil {
+		respondSuggestionError(w, err, "Failed to accept suggestion")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, expense)
+}
+
+// Dismiss handles DELETE /api/actual-expenses/{id}/suggestion
+// The expense keeps its name and is not suggested again.
+func (h *SuggestionHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := parseIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid expense ID")
+		return
+	}
+
+	if err := h.repo.ForUser(requestUserID(r)).Resolve(expenseID, models.SuggestionDismissed); err != nil {
+		respondSuggestionError(w, err, "Failed to dismiss suggestion")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func respondSuggestionError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrExpenseNotFound), errors.Is(err, repository.ErrSuggestionNotFound):
+		respondError(w, http.StatusNotFound, "Suggestion not found")
+	case errors.Is(err, models.ErrMonthClosed):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
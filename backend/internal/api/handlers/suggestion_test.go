@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSuggestionHandler_AcceptAndDismiss(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expenseRepo := repository.NewActualExpenseRepository(db)
+	suggestionRepo := repository.NewExpenseSuggestionRepository(db)
+	handler := NewSuggestionHandler(suggestionRepo, expenseRepo)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/actual-expenses/suggestions", handler.List)
+	mux.HandleFunc("POST /api/actual-expenses/{id}/suggestion/accept", handler.Accept)
+	mux.HandleFunc("DELETE /api/actual-expenses/{id}/suggestion", handler.Dismiss)
+
+	suggest := func(name, suggested string) *models.ActualExpense {
+		t.Helper()
+		expense, err := expenseRepo.Create(&models.CreateActualExpenseRequest{
+			ItemName: name, Source: "Visa", ActualAmount: 43.12,
+			ExpenseType: models.ExpenseTypeMisc, ReceiptDate: testReceiptDate(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		if _, err := suggestionRepo.Record(
+			expense.ID, suggested, models.ExpenseTypeWeekly, models.SuggestionPending,
+		); err != nil {
+			t.Fatalf("Failed to record suggestion: %v", err)
+		}
+		return expense
+	}
+	amazon := suggest("AMZN MKTP 43.12", "Amazon order")
+	coffee := suggest("SQ *BLUE BTL", "Blue Bottle coffee")
+
+	do := func(method, path string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if userID != 0 {
+			req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{UserID: userID}))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	list := func() []models.ExpenseSuggestion {
+		t.Helper()
+		rec := do("GET", "/api/actual-expenses/suggestions", 0)
+		var suggestions []models.ExpenseSuggestion
+		if err := json.NewDecoder(rec.Body).Decode(&suggestions); err != nil {
+			t.Fatalf("Failed to decode suggestions: %v", err)
+		}
+		return suggestions
+	}
+
+	if suggestions := list(); len(suggestions) != 2 || suggestions[0].ActualExpenseID != coffee.ID {
+		t.Fatalf("Expected both suggestions, newest first, got %+v", suggestions)
+	}
+
+	// Another user's suggestion looks like it does not exist
+	acceptPath := "/api/actual-expenses/" + itoa(amazon.ID) + "/suggestion/accept"
+	if rec := do("POST", acceptPath, 2); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec := do("POST", acceptPath, 0)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var accepted models.ActualExpense
+	if err := json.NewDecoder(rec.Body).Decode(&accepted); err != nil {
+		t.Fatalf("Failed to decode expense: %v", err)
+	}
+	if accepted.ItemName != "Amazon order" || accepted.ExpenseType != models.ExpenseTypeWeekly ||
+		accepted.ActualAmount != 43.12 {
+		t.Errorf("Expected the suggestion applied, got %+v", accepted)
+	}
+	if rec := do("POST", acceptPath, 0); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d accepting twice, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	dismissPath := "/api/actual-expenses/" + itoa(coffee.ID) + "/suggestion"
+	if rec := do("DELETE", dismissPath, 0); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if expense, err := expenseRepo.GetByID(coffee.ID); err != nil || expense.ItemName != "SQ *BLUE BTL" {
+		t.Errorf("Expected the dismissed expense unchanged, got %+v, %v", expense, err)
+	}
+	if suggestions := list(); len(suggestions) != 0 {
+		t.Errorf("Expected no pending suggestions, got %+v", suggestions)
+	}
+
+	if rec := do("POST", "/api/actual-expenses/abc/suggestion/accept", 0); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid ID, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"budget-tracker/internal/repository"
 	"database/sql"
+	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	_ "github.com/tursodatabase/go-libsql"
@@ -18,8 +20,9 @@ type TestDB struct {
 func setupTestDB(t *testing.T) *repository.DB {
 	t.Helper()
 
-	// Create in-memory database
-	sqlDB, err := sql.Open("libsql", "file::memory:?cache=shared")
+	// Create an in-memory database unique to this test so state never leaks
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	sqlDB, err := sql.Open("libsql", fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
 	if err != nil {
 		t.Fatalf("Failed to open in-memory database: %v", err)
 	}
@@ -31,6 +34,11 @@ func setupTestDB(t *testing.T) *repository.DB {
 		t.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Start from empty tables; seed migrations would otherwise skew assertions
+	if _, err := db.Exec("DELETE FROM expected_expenses"); err != nil {
+		t.Fatalf("Failed to clear seed data: %v", err)
+	}
+
 	return db
 }
 
@@ -45,17 +53,30 @@ func createTestMux(
 	if budgetHandler != nil {
 		mux.HandleFunc("GET /api/budgets", budgetHandler.List)
 		mux.HandleFunc("POST /api/budgets", budgetHandler.Create)
+		mux.HandleFunc("POST /api/budgets/copy", budgetHandler.Copy)
+		mux.HandleFunc("POST /api/budgets/bulk", budgetHandler.CreateBulk)
+		mux.HandleFunc("POST /api/budgets/import", budgetHandler.Import)
+		mux.HandleFunc("GET /api/budgets/current", budgetHandler.GetCurrent)
 		mux.HandleFunc("GET /api/budgets/{id}", budgetHandler.Get)
 		mux.HandleFunc("PUT /api/budgets/{id}", budgetHandler.Update)
 		mux.HandleFunc("DELETE /api/budgets/{id}", budgetHandler.Delete)
+		mux.HandleFunc("POST /api/budgets/{id}/close", budgetHandler.Close)
+		mux.HandleFunc("POST /api/budgets/{id}/reopen", budgetHandler.Reopen)
+		mux.HandleFunc("POST /api/budgets/{id}/restore", budgetHandler.Restore)
+		mux.HandleFunc("GET /api/budgets/{first}/{second}", budgetHandler.YearOrHistory)
+		mux.HandleFunc("GET /api/budgets/by-month/{year}/{month}", budgetHandler.GetByMonth)
+		mux.HandleFunc("PUT /api/budgets/by-month/{year}/{month}", budgetHandler.Upsert)
 	}
 
 	if expectedExpenseHandler != nil {
 		mux.HandleFunc("GET /api/expected-expenses", expectedExpenseHandler.List)
+		mux.HandleFunc("GET /api/expected-expenses/due", expectedExpenseHandler.Due)
 		mux.HandleFunc("POST /api/expected-expenses", expectedExpenseHandler.Create)
 		mux.HandleFunc("GET /api/expected-expenses/{id}", expectedExpenseHandler.Get)
 		mux.HandleFunc("PUT /api/expected-expenses/{id}", expectedExpenseHandler.Update)
 		mux.HandleFunc("DELETE /api/expected-expenses/{id}", expectedExpenseHandler.Delete)
+		mux.HandleFunc("POST /api/expected-expenses/{id}/archive", expectedExpenseHandler.Archive)
+		mux.HandleFunc("POST /api/expected-expenses/{id}/unarchive", expectedExpenseHandler.Unarchive)
 	}
 
 	return mux
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"maps"
+	"net/http"
+	"sync"
+)
+
+// VersionResponse is the response of GET /api/version: the build and which
+// optional features the server runs with
+type VersionResponse struct {
+	BuildInfo
+	Features map[string]bool `json:"features"`
+}
+
+// VersionHandler reports the running build, e.g. for bug reports
+type VersionHandler struct {
+	mu   sync.RWMutex
+	resp VersionResponse
+}
+
+// NewVersionHandler creates a new VersionHandler. features maps each optional
+// feature to whether it is enabled.
+func NewVersionHandler(build BuildInfo, features map[string]bool) *VersionHandler {
+	return &VersionHandler{resp: VersionResponse{BuildInfo: build, Features: maps.Clone(features)}}
+}
+
+// SetFeature records that a feature was turned on or off while the server
+// runs, e.g. by a configuration reload
+func (h *VersionHandler) SetFeature(name string, on bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Copied, so a response being encoded keeps the map it read
+	features := maps.Clone(h.resp.Features)
+	if features == nil {
+		features = make(map[string]bool)
+	}
+	features[name] = on
+	h.resp.Features = features
+}
+
+// Get handles GET /api/version
+func (h *VersionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	resp := h.resp
+	h.mu.RUnlock()
+	respondJSON(w, http.StatusOK, resp)
+}
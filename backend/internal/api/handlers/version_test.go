@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionHandler_Get(t *testing.T) {
+	build := BuildInfo{Version: "1.4.0", Commit: "abc123", BuiltAt: "2026-10-15T21:04:11Z", GoVersion: "go1.24"}
+	handler := NewVersionHandler(build, map[string]bool{"authentication": true, "demo_mode": false})
+
+	rec := httptest.NewRecorder()
+	handler.Get(rec, httptest.NewRequest("GET", "/api/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	// The build fields are at the top level, next to the features
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["version"] != "1.4.0" || resp["commit"] != "abc123" || resp["go_version"] != "go1.24" ||
+		resp["built_at"] != build.BuiltAt {
+		t.Errorf("Unexpected build info: %v", resp)
+	}
+	features, ok := resp["features"].(map[string]any)
+	if !ok || features["authentication"] != true || features["demo_mode"] != false {
+		t.Errorf("Expected the features, got %v", resp["features"])
+	}
+}
+
+func TestVersionHandler_SetFeature(t *testing.T) {
+	handler := NewVersionHandler(BuildInfo{Version: "1.4.0"}, map[string]bool{"registration": false})
+	handler.SetFeature("registration", true)
+
+	rec := httptest.NewRecorder()
+	handler.Get(rec, httptest.NewRequest("GET", "/api/version", nil))
+	var resp VersionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resp.Features["registration"] {
+		t.Errorf("Expected registration to be reported on after the change, got %v", resp.Features)
+	}
+}
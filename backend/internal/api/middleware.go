@@ -1,8 +1,18 @@
 package api
 
 import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 	"time"
 )
 
@@ -11,6 +21,7 @@ type CORSConfig struct {
 	AllowedOrigins []string
 	AllowedMethods []string
 	AllowedHeaders []string
+	ExposedHeaders []string
 	MaxAge         int
 }
 
@@ -20,6 +31,7 @@ func DefaultCORSConfig() CORSConfig {
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		ExposedHeaders: []string{"Link"},
 		MaxAge:         86400, // 24 hours
 	}
 }
@@ -49,6 +61,10 @@ func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
 					Set("Access-Control-Allow-Methods", joinStrings(cfg.AllowedMethods, ", "))
 				w.Header().
 					Set("Access-Control-Allow-Headers", joinStrings(cfg.AllowedHeaders, ", "))
+				if len(cfg.ExposedHeaders) > 0 {
+					w.Header().
+						Set("Access-Control-Expose-Headers", joinStrings(cfg.ExposedHeaders, ", "))
+				}
 				w.Header().Set("Access-Control-Max-Age", intToString(cfg.MaxAge))
 			}
 
@@ -107,6 +123,313 @@ func Recovery(next http.Handler) http.Handler {
 	})
 }
 
+// AdminTokenHeader carries the admin token on admin API requests
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminChecker reports whether a user has the admin role
+type AdminChecker interface {
+	IsAdmin(userID int64) (bool, error)
+}
+
+// RequireAdmin creates a middleware for the admin API. A request passes when
+// it presents the configured admin token in the X-Admin-Token header, or a
+// bearer token of a signed-in user with the admin role, whose claims are then
+// stored in the request context. Either way may be unconfigured: an empty
+// token disables the admin token, and nil tokens or admins disables the
+// admin role. Admin endpoints are disabled entirely when neither is
+// configured.
+func RequireAdmin(token string, tokens *auth.TokenIssuer, admins AdminChecker) func(http.Handler) http.Handler {
+	roles := tokens != nil && admins != nil
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" && !roles {
+				respondMiddlewareError(w, http.StatusNotFound, "Admin API is disabled")
+				return
+			}
+
+			if provided := r.Header.Get(AdminTokenHeader); provided != "" || !roles {
+				if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+					respondMiddlewareError(w, http.StatusUnauthorized, "Invalid admin token")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || bearer == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				respondMiddlewareError(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+			claims, err := tokens.Verify(bearer)
+			if err != nil {
+				message := "Invalid token"
+				if errors.Is(err, auth.ErrTokenExpired) {
+					message = "Token has expired"
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				respondMiddlewareError(w, http.StatusUnauthorized, message)
+				return
+			}
+			// Sub-accounts are never admins, whatever their row says
+			isAdmin := false
+			if !claims.Restricted() {
+				if isAdmin, err = admins.IsAdmin(claims.UserID); err != nil {
+					log.Printf("Failed to check admin role of user %d: %v", claims.UserID, err)
+					respondMiddlewareError(w, http.StatusInternalServerError, "Failed to check admin role")
+					return
+				}
+			}
+			if !isAdmin {
+				respondMiddlewareError(w, http.StatusForbidden, "Admin role required")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// RequireAuth creates a middleware that only lets requests through when they
+// carry a valid "Authorization: Bearer <token>" header, and stores the token's
+// claims in the request context (see auth.ClaimsFromContext). Requests pass
+// through unchecked when tokens is nil, i.e. authentication is disabled.
+func RequireAuth(tokens *auth.TokenIssuer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if tokens == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				respondMiddlewareError(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+
+			claims, err := tokens.Verify(token)
+			if err != nil {
+				message := "Invalid token"
+				if errors.Is(err, auth.ErrTokenExpired) {
+					message = "Token has expired"
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				respondMiddlewareError(w, http.StatusUnauthorized, message)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// RequireAdminRole creates a middleware for the routes that change what
+// applies to every user, e.g. the default budget. Only users with the admin
+// role in admins may use them, everyone else gets 403; nil admins admits no
+// one. It must run after RequireAuth, and requests pass through unchecked
+// while authentication is disabled.
+func RequireAdminRole(admins AdminChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := auth.ClaimsFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Sub-accounts are never admins, whatever their row says
+			isAdmin := false
+			if admins != nil && !claims.Restricted() {
+				var err error
+				if isAdmin, err = admins.IsAdmin(claims.UserID); err != nil {
+					log.Printf("Failed to check admin role of user %d: %v", claims.UserID, err)
+					respondMiddlewareError(w, http.StatusInternalServerError, "Failed to check admin role")
+					return
+				}
+			}
+			if !isAdmin {
+				respondMiddlewareError(w, http.StatusForbidden, "Admin role required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectRestricted is a middleware for routes that allowance sub-accounts may
+// not use. Requests with a restricted token (see auth.ScopeAllowance) get 403;
+// it must run after RequireAuth.
+func RejectRestricted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok && claims.Restricted() {
+			respondMiddlewareError(w, http.StatusForbidden, "Not available to allowance accounts")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FreezeConfirmParam is the query parameter that confirms an expense recorded
+// while the budget is frozen
+const FreezeConfirmParam = "confirm"
+
+// BudgetFreezes returns the budget freeze, or repository.ErrSettingNotFound
+// when the budget is not frozen
+type BudgetFreezes interface {
+	GetBudgetFreeze() (*models.BudgetFreeze, error)
+}
+
+// Notifier stores notifications for a user
+type Notifier interface {
+	CreateFor(userID int64, n *models.Notification) (*models.Notification, error)
+}
+
+// RequireFreezeConfirmation creates a middleware for the routes that record
+// expenses. While the budget is frozen, requests without ?confirm=true get
+// 409, and every request that then succeeds notifies the user who froze the
+// budget. Requests pass through unchecked when freezes is nil.
+func RequireFreezeConfirmation(freezes BudgetFreezes, notifications Notifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if freezes == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			freeze, err := freezes.GetBudgetFreeze()
+			if errors.Is(err, repository.ErrSettingNotFound) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to check budget freeze: %v", err)
+				respondMiddlewareError(w, http.StatusInternalServerError, "Failed to check budget freeze")
+				return
+			}
+			if r.URL.Query().Get(FreezeConfirmParam) != "true" {
+				respondMiddlewareError(
+					w,
+					http.StatusConflict,
+					"Budget is frozen, confirm the expense with ?"+FreezeConfirmParam+"=true",
+				)
+				return
+			}
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+			if wrapped.statusCode < 200 || wrapped.statusCode > 299 || notifications == nil {
+				return
+			}
+			if _, err := notifications.CreateFor(freeze.FrozenBy, freezeNotification(freeze, r)); err != nil {
+				log.Printf("Failed to notify expense during budget freeze: %v", err)
+			}
+		})
+	}
+}
+
+// freezeNotification is the notification for the expenses recorded by r
+// during freeze
+func freezeNotification(freeze *models.BudgetFreeze, r *http.Request) *models.Notification {
+	message := fmt.Sprintf("Expenses were recorded with %s %s while the budget is frozen", r.Method, r.URL.Path)
+	if freeze.Reason != "" {
+		message += ": " + freeze.Reason
+	}
+	return &models.Notification{
+		Kind:    models.NotificationBudgetFrozen,
+		Title:   "Expenses recorded during the budget freeze",
+		Message: message + ".",
+		Link:    "/actual-expenses",
+	}
+}
+
+// ParsePrefixes parses a comma-separated list of CIDR ranges such as
+// "203.0.113.0/24, 2001:db8::/32". A bare address is a range of one address.
+func ParsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", field)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q", field)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked())
+	}
+	return prefixes, nil
+}
+
+// AllowIPs creates a middleware that only lets requests through from clients
+// in one of the allowed ranges; others get 403. When the peer is one of
+// trustedProxies, the client is the last address in X-Forwarded-For that is
+// not a trusted proxy. Requests pass through unchecked when allowed is empty.
+func AllowIPs(allowed, trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := clientAddr(r, trustedProxies)
+			if !ok || !containsAddr(allowed, client) {
+				respondMiddlewareError(w, http.StatusForbidden, "Access from this address is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr returns the address of the client that sent r, following
+// X-Forwarded-For from right to left while the hops are trusted proxies
+func clientAddr(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	client, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	client = client.Unmap()
+
+	// Only the proxies' own entries can be trusted, the client may send any
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0 && containsAddr(trustedProxies, client); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		client = addr.Unmap()
+	}
+	return client, true
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func respondMiddlewareError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
 // Chain applies multiple middleware to a handler
 func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	for i := len(middlewares) - 1; i >= 0; i-- {
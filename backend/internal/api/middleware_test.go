@@ -0,0 +1,302 @@
+package api
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/auth"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequireAuth(t *testing.T) {
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	token, _, err := tokens.Issue(&models.User{ID: 3, Email: "me@example.com"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+
+	var userID int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = 0
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+			userID = claims.UserID
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		tokens *auth.TokenIssuer
+		header string
+		code   int
+		userID int64
+	}{
+		{"valid token", tokens, "Bearer " + token, http.StatusOK, 3},
+		{"missing header", tokens, "", http.StatusUnauthorized, 0},
+		{"wrong scheme", tokens, "Basic " + token, http.StatusUnauthorized, 0},
+		{"bad token", tokens, "Bearer " + token + "x", http.StatusUnauthorized, 0},
+		{"auth disabled", nil, "", http.StatusOK, 0},
+	} {
+		req := httptest.NewRequest("GET", "/api/budgets", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		RequireAuth(tc.tokens)(next).ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+		if rec.Code == http.StatusOK && userID != tc.userID {
+			t.Errorf("%s: expected user %d in context, got %d", tc.name, tc.userID, userID)
+		}
+	}
+}
+
+func TestRejectRestricted(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		claims *auth.Claims
+		code   int
+	}{
+		{"full access", &auth.Claims{UserID: 3}, http.StatusOK},
+		{"allowance account", &auth.Claims{UserID: 4, Scope: auth.ScopeAllowance}, http.StatusForbidden},
+		{"auth disabled", nil, http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/api/budgets", nil)
+		if tc.claims != nil {
+			req = req.WithContext(auth.WithClaims(req.Context(), tc.claims))
+		}
+		rec := httptest.NewRecorder()
+		RejectRestricted(next).ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+	}
+}
+
+// adminSet is an AdminChecker granting the admin role to the listed users
+type adminSet map[int64]bool
+
+func (a adminSet) IsAdmin(userID int64) (bool, error) {
+	return a[userID], nil
+}
+
+func TestRequireAdmin(t *testing.T) {
+	tokens, err := auth.NewTokenIssuer([]byte(strings.Repeat("s", auth.MinSecretLength)), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token issuer: %v", err)
+	}
+	issue := func(user *models.User) string {
+		t.Helper()
+		token, _, err := tokens.Issue(user, 0)
+		if err != nil {
+			t.Fatalf("Failed to issue token: %v", err)
+		}
+		return "Bearer " + token
+	}
+	parentID := int64(3)
+	adminToken := issue(&models.User{ID: 3, Email: "admin@example.com"})
+	userToken := issue(&models.User{ID: 5, Email: "user@example.com"})
+	// A sub-account is never an admin, even when its row says so
+	kidToken := issue(&models.User{ID: 4, Email: "kid@accounts.invalid", ParentID: &parentID})
+	admins := adminSet{3: true, 4: true}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name       string
+		token      string
+		tokens     *auth.TokenIssuer
+		admins     AdminChecker
+		adminToken string // X-Admin-Token header
+		bearer     string // Authorization header
+		code       int
+	}{
+		{"disabled", "", nil, nil, "secret", "", http.StatusNotFound},
+		{"admin token", "secret", nil, nil, "secret", "", http.StatusOK},
+		{"wrong admin token", "secret", nil, nil, "wrong", "", http.StatusUnauthorized},
+		{"missing admin token", "secret", nil, nil, "", adminToken, http.StatusUnauthorized},
+		{"admin role", "", tokens, admins, "", adminToken, http.StatusOK},
+		{"admin role with token configured", "secret", tokens, admins, "", adminToken, http.StatusOK},
+		{"admin token with roles configured", "secret", tokens, admins, "secret", "", http.StatusOK},
+		{"wrong admin token beats role", "secret", tokens, admins, "wrong", adminToken, http.StatusUnauthorized},
+		{"admin token unset", "", tokens, admins, "secret", "", http.StatusUnauthorized},
+		{"not an admin", "", tokens, admins, "", userToken, http.StatusForbidden},
+		{"allowance account", "", tokens, admins, "", kidToken, http.StatusForbidden},
+		{"bad bearer token", "", tokens, admins, "", adminToken + "x", http.StatusUnauthorized},
+		{"no credentials", "secret", tokens, admins, "", "", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest("GET", "/api/admin/users", nil)
+		if tc.adminToken != "" {
+			req.Header.Set(AdminTokenHeader, tc.adminToken)
+		}
+		if tc.bearer != "" {
+			req.Header.Set("Authorization", tc.bearer)
+		}
+		rec := httptest.NewRecorder()
+		RequireAdmin(tc.token, tc.tokens, tc.admins)(next).ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestRequireAdminRole(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	admins := adminSet{3: true, 4: true}
+
+	for _, tc := range []struct {
+		name   string
+		admins AdminChecker
+		claims *auth.Claims
+		code   int
+	}{
+		{"admin", admins, &auth.Claims{UserID: 3}, http.StatusOK},
+		{"not an admin", admins, &auth.Claims{UserID: 5}, http.StatusForbidden},
+		{"allowance account", admins, &auth.Claims{UserID: 4, Scope: auth.ScopeAllowance}, http.StatusForbidden},
+		{"roles disabled", nil, &auth.Claims{UserID: 3}, http.StatusForbidden},
+		{"auth disabled", admins, nil, http.StatusOK},
+	} {
+		req := httptest.NewRequest("PUT", "/api/settings/default-budget", nil)
+		if tc.claims != nil {
+			req = req.WithContext(auth.WithClaims(req.Context(), tc.claims))
+		}
+		rec := httptest.NewRecorder()
+		RequireAdminRole(tc.admins)(next).ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestAllowIPs(t *testing.T) {
+	allowed, err := ParsePrefixes("203.0.113.0/24, 2001:db8::/32,198.51.100.7")
+	if err != nil {
+		t.Fatalf("Failed to parse allowlist: %v", err)
+	}
+	proxies, err := ParsePrefixes("10.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to parse proxies: %v", err)
+	}
+	for _, bad := range []string{"203.0.113.0/33", "example.com", "10.0.0.0/8,nope"} {
+		if _, err := ParsePrefixes(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		allowed      []netip.Prefix
+		code         int
+	}{
+		{"allowed range", "203.0.113.9:4000", "", allowed, http.StatusOK},
+		{"allowed address", "198.51.100.7:4000", "", allowed, http.StatusOK},
+		{"allowed IPv6", "[2001:db8::1]:4000", "", allowed, http.StatusOK},
+		{"IPv4-mapped IPv6", "[::ffff:203.0.113.9]:4000", "", allowed, http.StatusOK},
+		{"other address", "198.51.100.8:4000", "", allowed, http.StatusForbidden},
+		{"loopback not listed", "127.0.0.1:4000", "", allowed, http.StatusForbidden},
+		{"untrusted peer forwarding", "198.51.100.8:4000", "203.0.113.9", allowed, http.StatusForbidden},
+		{"trusted proxy", "10.0.0.1:4000", "203.0.113.9", allowed, http.StatusOK},
+		{"trusted proxy, other client", "10.0.0.1:4000", "198.51.100.8", allowed, http.StatusForbidden},
+		{"spoofed hop before the client", "10.0.0.1:4000", "203.0.113.9, 198.51.100.8", allowed, http.StatusForbidden},
+		{"trusted proxy without header", "10.0.0.1:4000", "", allowed, http.StatusForbidden},
+		{"allowlist disabled", "198.51.100.8:4000", "", nil, http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/api/budgets", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		AllowIPs(tc.allowed, proxies)(next).ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+	}
+}
+
+// freezeState is a BudgetFreezes with an optional freeze
+type freezeState struct {
+	freeze *models.BudgetFreeze
+}
+
+func (f freezeState) GetBudgetFreeze() (*models.BudgetFreeze, error) {
+	if f.freeze == nil {
+		return nil, repository.ErrSettingNotFound
+	}
+	return f.freeze, nil
+}
+
+// notificationLog is a Notifier keeping what it was sent, by user
+type notificationLog map[int64][]*models.Notification
+
+func (l notificationLog) CreateFor(userID int64, n *models.Notification) (*models.Notification, error) {
+	l[userID] = append(l[userID], n)
+	return n, nil
+}
+
+func TestRequireFreezeConfirmation(t *testing.T) {
+	frozen := freezeState{&models.BudgetFreeze{Reason: "lockdown month", FrozenAt: time.Now(), FrozenBy: 3}}
+
+	for _, tc := range []struct {
+		name     string
+		freezes  BudgetFreezes
+		target   string
+		status   int
+		code     int
+		notified int
+	}{
+		{"not frozen", freezeState{}, "/api/actual-expenses", http.StatusCreated, http.StatusCreated, 0},
+		{"frozen without confirmation", frozen, "/api/actual-expenses", http.StatusCreated, http.StatusConflict, 0},
+		{"frozen, confirmed", frozen, "/api/actual-expenses?confirm=true", http.StatusCreated, http.StatusCreated, 1},
+		{"frozen, confirmed but invalid", frozen, "/api/actual-expenses?confirm=true", http.StatusBadRequest, http.StatusBadRequest, 0},
+		{"freeze disabled", nil, "/api/actual-expenses", http.StatusCreated, http.StatusCreated, 0},
+	} {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		})
+		notifications := notificationLog{}
+		req := httptest.NewRequest("POST", tc.target, nil)
+		rec := httptest.NewRecorder()
+		RequireFreezeConfirmation(tc.freezes, notifications)(next).ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+		// The user who froze the budget is notified
+		if len(notifications[3]) != tc.notified {
+			t.Errorf("%s: expected %d notifications, got %d", tc.name, tc.notified, len(notifications[3]))
+		}
+		for _, n := range notifications[3] {
+			if n.Kind != models.NotificationBudgetFrozen || !strings.Contains(n.Message, "lockdown month") {
+				t.Errorf("%s: unexpected notification %+v", tc.name, n)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"budget-tracker/internal/api/handlers"
+	"budget-tracker/internal/services/auth"
 	"encoding/json"
 	"net/http"
 )
@@ -13,6 +14,44 @@ type Handlers struct {
 	ActualExpense   *handlers.ActualExpenseHandler
 	Receipt         *handlers.ReceiptHandler
 	Notification    *handlers.NotificationHandler
+	Comment         *handlers.CommentHandler
+	Suggestion      *handlers.SuggestionHandler
+	Audit           *handlers.AuditHandler
+	Allowance       *handlers.AllowanceHandler
+	Goal            *handlers.GoalHandler
+	Rule            *handlers.RuleHandler
+	SourceCap       *handlers.SourceCapHandler
+	Merchant        *handlers.MerchantHandler
+	Admin           *handlers.AdminHandler
+	Metrics         *handlers.MetricsHandler
+	Settings        *handlers.SettingsHandler
+	Import          *handlers.ImportHandler
+	Export          *handlers.ExportHandler
+	Report          *handlers.ReportHandler
+	Health          *handlers.HealthHandler
+	Status          *handlers.StatusHandler
+	Version         *handlers.VersionHandler
+	Capabilities    *handlers.CapabilitiesHandler
+	Auth            *handlers.AuthHandler
+
+	// AdminToken and users with the admin role in Admins may use the
+	// /api/admin routes; with neither they are disabled. Only the users
+	// in Admins may change the settings that apply to every user.
+	AdminToken string
+	Admins     AdminChecker
+
+	// Tokens verifies the JWTs required by the other API routes; nil
+	// disables authentication
+	Tokens *auth.TokenIssuer
+
+	// While the budget in Freezes is frozen, the routes that record expenses
+	// need a confirmation and tell Notifications; nil Freezes disables it
+	Freezes       BudgetFreezes
+	Notifications Notifier
+
+	// Runtime holds the configuration that reloads without a restart; nil
+	// disables the /api/admin/config routes
+	Runtime *Runtime
 }
 
 // NewRouter creates a new HTTP router with all routes configured
@@ -20,40 +59,208 @@ type Handlers struct {
 func NewRouter(h *Handlers) *http.ServeMux {
 	mux := http.NewServeMux()
 
-	// Health check endpoint
+	// Health check and status page endpoints
 	mux.HandleFunc("GET /health", healthCheck)
+	mux.HandleFunc("GET /readyz", h.Health.Ready)
+	mux.HandleFunc("GET /status", h.Status.Status)
+
+	// Auth routes; signing in does not need a token
+	mux.HandleFunc("POST /api/auth/register", h.Auth.Register)
+	mux.HandleFunc("POST /api/auth/login", h.Auth.Login)
+	mux.HandleFunc("GET /api/auth/google", h.Auth.GoogleLogin)
+	mux.HandleFunc("GET /api/auth/google/callback", h.Auth.GoogleCallback)
+	mux.HandleFunc("POST /api/auth/refresh", h.Auth.Refresh)
+	mux.HandleFunc("POST /api/auth/logout", h.Auth.Logout)
+	mux.HandleFunc("POST /api/auth/verify-email", h.Auth.VerifyEmail)
+	mux.HandleFunc("POST /api/auth/forgot-password", h.Auth.ForgotPassword)
+	mux.HandleFunc("POST /api/auth/reset-password", h.Auth.ResetPassword)
+
+	// Every other API route except the admin ones needs a signed-in user when
+	// authentication is enabled. Allowance sub-accounts may only use the
+	// routes registered with allowanceRoute.
+	requireAuth := RequireAuth(h.Tokens)
+	protected := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, requireAuth(RejectRestricted(handler)))
+	}
+	allowanceRoute := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, requireAuth(handler))
+	}
+	// The settings that apply to every user may only be changed by admins
+	requireAdminRole := RequireAdminRole(h.Admins)
+	adminSetting := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, requireAuth(RejectRestricted(requireAdminRole(handler))))
+	}
+	// Expenses recorded while the budget is frozen must be confirmed
+	confirmFrozen := RequireFreezeConfirmation(h.Freezes, h.Notifications)
+	frozen := func(handler http.HandlerFunc) http.HandlerFunc {
+		return confirmFrozen(handler).ServeHTTP
+	}
+	allowanceRoute("GET /api/version", h.Version.Get)
+	allowanceRoute("GET /api/capabilities", h.Capabilities.Get)
+	allowanceRoute("GET /api/auth/me", h.Auth.Me)
+	allowanceRoute("GET /api/auth/sessions", h.Auth.Sessions)
+	allowanceRoute("DELETE /api/auth/sessions", h.Auth.RevokeAllSessions)
+	allowanceRoute("DELETE /api/auth/sessions/{id}", h.Auth.RevokeSession)
+	protected("POST /api/auth/verify-email/send", h.Auth.SendVerification)
+
+	// Allowance sub-account routes
+	protected("GET /api/allowances", h.Allowance.List)
+	protected("POST /api/allowances", h.Allowance.Create)
+	protected("PUT /api/allowances/{id}", h.Allowance.Update)
+	protected("DELETE /api/allowances/{id}", h.Allowance.Delete)
+	protected("POST /api/allowances/{id}/token", h.Allowance.IssueToken)
+	allowanceRoute("GET /api/allowance", h.Allowance.Own)
 
 	// Budget routes
-	mux.HandleFunc("GET /api/budgets", h.Budget.List)
-	mux.HandleFunc("POST /api/budgets", h.Budget.Create)
-	mux.HandleFunc("GET /api/budgets/{id}", h.Budget.Get)
-	mux.HandleFunc("PUT /api/budgets/{id}", h.Budget.Update)
-	mux.HandleFunc("DELETE /api/budgets/{id}", h.Budget.Delete)
+	protected("GET /api/budgets", h.Budget.List)
+	protected("POST /api/budgets", h.Budget.Create)
+	protected("POST /api/budgets/copy", h.Budget.Copy)
+	protected("POST /api/budgets/bulk", h.Budget.CreateBulk)
+	protected("POST /api/budgets/import", h.Budget.Import)
+	protected("GET /api/budgets/freeze", h.Settings.GetBudgetFreeze)
+	adminSetting("POST /api/budgets/freeze", h.Settings.FreezeBudget)
+	adminSetting("DELETE /api/budgets/freeze", h.Settings.UnfreezeBudget)
+	protected("GET /api/budgets/current", h.Budget.GetCurrent)
+	protected("GET /api/budgets/{id}", h.Budget.Get)
+	protected("PUT /api/budgets/{id}", h.Budget.Update)
+	protected("DELETE /api/budgets/{id}", h.Budget.Delete)
+	protected("POST /api/budgets/{id}/close", h.Budget.Close)
+	protected("POST /api/budgets/{id}/reopen", h.Budget.Reopen)
+	protected("POST /api/budgets/{id}/restore", h.Budget.Restore)
+	// GET /api/budgets/year/{year} and GET /api/budgets/{id}/history
+	protected("GET /api/budgets/{first}/{second}", h.Budget.YearOrHistory)
+	protected("GET /api/budgets/by-month/{year}/{month}", h.Budget.GetByMonth)
+	protected("PUT /api/budgets/by-month/{year}/{month}", h.Budget.Upsert)
 
 	// Expected Expenses routes
-	mux.HandleFunc("GET /api/expected-expenses", h.ExpectedExpense.List)
-	mux.HandleFunc("POST /api/expected-expenses", h.ExpectedExpense.Create)
-	mux.HandleFunc("GET /api/expected-expenses/{id}", h.ExpectedExpense.Get)
-	mux.HandleFunc("PUT /api/expected-expenses/{id}", h.ExpectedExpense.Update)
-	mux.HandleFunc("DELETE /api/expected-expenses/{id}", h.ExpectedExpense.Delete)
-
-	// Actual Expenses routes
-	mux.HandleFunc("GET /api/actual-expenses", h.ActualExpense.List)
-	mux.HandleFunc("POST /api/actual-expenses", h.ActualExpense.Create)
-	mux.HandleFunc(
+	protected("GET /api/expected-expenses", h.ExpectedExpense.List)
+	protected("GET /api/expected-expenses/due", h.ExpectedExpense.Due)
+	protected("POST /api/expected-expenses", h.ExpectedExpense.Create)
+	protected("GET /api/expected-expenses/{id}", h.ExpectedExpense.Get)
+	protected("PUT /api/expected-expenses/{id}", h.ExpectedExpense.Update)
+	protected("DELETE /api/expected-expenses/{id}", h.ExpectedExpense.Delete)
+	protected("POST /api/expected-expenses/{id}/archive", h.ExpectedExpense.Archive)
+	protected("POST /api/expected-expenses/{id}/unarchive", h.ExpectedExpense.Unarchive)
+
+	// Actual Expenses routes; allowance sub-accounts log their own spending
+	// here but cannot approve it
+	allowanceRoute("GET /api/actual-expenses", h.ActualExpense.List)
+	allowanceRoute("POST /api/actual-expenses", frozen(h.ActualExpense.Create))
+	protected("POST /api/actual-expenses/bulk", frozen(h.ActualExpense.CreateBulk))
+	protected("POST /api/actual-expenses/receipts", frozen(h.ActualExpense.SaveReceipts))
+	allowanceRoute(
 		"GET /api/actual-expenses/next-receipt-number",
 		h.ActualExpense.GetNextReceiptNumber,
 	)
-	mux.HandleFunc("GET /api/actual-expenses/summary", h.ActualExpense.GetSummary)
-	mux.HandleFunc("GET /api/actual-expenses/{id}", h.ActualExpense.Get)
-	mux.HandleFunc("PUT /api/actual-expenses/{id}", h.ActualExpense.Update)
-	mux.HandleFunc("DELETE /api/actual-expenses/{id}", h.ActualExpense.Delete)
+	allowanceRoute("GET /api/actual-expenses/summary", h.ActualExpense.GetSummary)
+	allowanceRoute("GET /api/actual-expenses/{id}", h.ActualExpense.Get)
+	allowanceRoute("PUT /api/actual-expenses/{id}", h.ActualExpense.Update)
+	allowanceRoute("DELETE /api/actual-expenses/{id}", h.ActualExpense.Delete)
+	protected("GET /api/actual-expenses/approvals", h.ActualExpense.PendingApprovals)
+	protected("POST /api/actual-expenses/{id}/approve", h.ActualExpense.Approve)
+
+	// Suggested names for expenses entered tersely
+	protected("GET /api/actual-expenses/suggestions", h.Suggestion.List)
+	protected("POST /api/actual-expenses/{id}/suggestion/accept", h.Suggestion.Accept)
+	protected("DELETE /api/actual-expenses/{id}/suggestion", h.Suggestion.Dismiss)
+
+	// Expense comment routes
+	protected("GET /api/actual-expenses/{id}/comments", h.Comment.List)
+	protected("POST /api/actual-expenses/{id}/comments", h.Comment.Create)
+	protected("PUT /api/actual-expenses/{id}/comments/{commentID}", h.Comment.Update)
+	protected("DELETE /api/actual-expenses/{id}/comments/{commentID}", h.Comment.Delete)
+
+	// Spending goal routes
+	protected("GET /api/goals", h.Goal.List)
+	protected("POST /api/goals", h.Goal.Create)
+	protected("GET /api/goals/{id}", h.Goal.Get)
+	protected("DELETE /api/goals/{id}", h.Goal.Delete)
+	protected("GET /api/goals/{id}/progress", h.Goal.Progress)
+
+	// Category rule routes
+	protected("GET /api/rules", h.Rule.List)
+	protected("POST /api/rules", h.Rule.Create)
+	protected("POST /api/rules/test", h.Rule.Test)
+	protected("POST /api/rules/simulate", h.Rule.Simulate)
+	protected("GET /api/rules/{id}", h.Rule.Get)
+	protected("PUT /api/rules/{id}", h.Rule.Update)
+	protected("DELETE /api/rules/{id}", h.Rule.Delete)
+
+	// Source cap routes
+	protected("GET /api/source-caps", h.SourceCap.List)
+	protected("POST /api/source-caps", h.SourceCap.Create)
+	protected("PUT /api/source-caps/{id}", h.SourceCap.Update)
+	protected("DELETE /api/source-caps/{id}", h.SourceCap.Delete)
+
+	// Merchant suggestions for quick-add; sub-accounts log expenses too
+	allowanceRoute("GET /api/merchants/nearby", h.Merchant.Nearby)
+
+	// Audit log route
+	protected("GET /api/audit", h.Audit.List)
 
 	// Receipt processing route
-	mux.HandleFunc("POST /api/receipts/process", h.Receipt.Process)
+	protected("POST /api/receipts/process", h.Receipt.Process)
 
 	// Notification routes
-	mux.HandleFunc("GET /api/notifications/budget-status", h.Notification.BudgetStatus)
+	protected("GET /api/notifications", h.Notification.List)
+	protected("POST /api/notifications/{id}/read", h.Notification.MarkRead)
+	protected("GET /api/notifications/budget-status", h.Notification.BudgetStatus)
+	protected("GET /api/notifications/weekly-status", h.Notification.WeeklyStatus)
+	protected("GET /api/notifications/source-status", h.Notification.SourceStatus)
+
+	// Settings routes
+	protected("GET /api/settings/default-budget", h.Settings.GetDefaultBudget)
+	adminSetting("PUT /api/settings/default-budget", h.Settings.SetDefaultBudget)
+	adminSetting("DELETE /api/settings/default-budget", h.Settings.DeleteDefaultBudget)
+	protected("GET /api/settings/approval-rule", h.Settings.GetApprovalRule)
+	adminSetting("PUT /api/settings/approval-rule", h.Settings.SetApprovalRule)
+	adminSetting("DELETE /api/settings/approval-rule", h.Settings.DeleteApprovalRule)
+
+	// Import routes (YNAB and Mint CSV exports)
+	protected("POST /api/import/{format}/preview", h.Import.Preview)
+	protected("POST /api/import/{format}", frozen(h.Import.Import))
+
+	// Export routes
+	protected("GET /api/export/beancount", h.Export.Beancount)
+
+	// Report routes
+	protected("GET /api/reports/pivot", h.Report.Pivot)
+	protected("GET /api/reports/weekdays", h.Report.Weekdays)
+	protected("GET /api/reports/variance", h.Report.Variance)
+	protected("GET /api/reports/tax", h.Report.Tax)
+	protected("GET /api/reports/deductible", h.Report.Deductible)
+
+	// Metrics routes
+	protected("GET /api/metrics/failures", h.Metrics.Failures)
+
+	// Admin routes; they are separate from the budget API above and never
+	// accept a token without the admin role
+	admin := RequireAdmin(h.AdminToken, h.Tokens, h.Admins)
+	mux.Handle("POST /api/admin/repair", admin(http.HandlerFunc(h.Admin.Repair)))
+	mux.Handle("GET /api/admin/orphans", admin(http.HandlerFunc(h.Admin.Orphans)))
+	mux.Handle("GET /api/admin/integrity", admin(http.HandlerFunc(h.Admin.Integrity)))
+	mux.Handle("GET /api/admin/migrations", admin(http.HandlerFunc(h.Admin.Migrations)))
+	mux.Handle("GET /api/admin/stats", admin(http.HandlerFunc(h.Admin.Stats)))
+	mux.Handle("GET /api/admin/users", admin(http.HandlerFunc(h.Admin.Users)))
+	mux.Handle("GET /api/admin/users/{id}", admin(http.HandlerFunc(h.Admin.User)))
+	mux.Handle("PUT /api/admin/users/{id}", admin(http.HandlerFunc(h.Admin.UpdateUser)))
+	mux.Handle(
+		"DELETE /api/admin/users/{id}/sessions",
+		admin(http.HandlerFunc(h.Admin.RevokeUserSessions)),
+	)
+	mux.Handle("GET /api/admin/schedules", admin(http.HandlerFunc(h.Admin.Schedules)))
+	mux.Handle("PUT /api/admin/schedules", admin(http.HandlerFunc(h.Admin.UpdateSchedules)))
+	mux.Handle("GET /api/admin/ai", admin(http.HandlerFunc(h.Admin.AIHealth)))
+	mux.Handle("POST /api/admin/ai/check", admin(http.HandlerFunc(h.Admin.CheckAIHealth)))
+	mux.Handle("GET /api/admin/receipts/history", admin(http.HandlerFunc(h.Admin.ReceiptHistory)))
+	mux.Handle(
+		"GET /api/admin/receipts/history/{id}",
+		admin(http.HandlerFunc(h.Admin.ReceiptHistoryEntry)),
+	)
+	if h.Runtime != nil {
+		mux.Handle("GET /api/admin/config", admin(runtimeConfig(h.Runtime)))
+		mux.Handle("POST /api/admin/config/reload", admin(reloadRuntimeConfig(h.Runtime)))
+	}
 
 	return mux
 }
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel selects which requests the request log records
+type LogLevel string
+
+// Log levels, most verbose first
+const (
+	// LogDebug records every request with its query string and client
+	LogDebug LogLevel = "debug"
+	// LogInfo records every request
+	LogInfo LogLevel = "info"
+	// LogWarn records the requests that failed, with a 4xx or 5xx status
+	LogWarn LogLevel = "warn"
+	// LogError records the requests that failed with a 5xx status
+	LogError LogLevel = "error"
+)
+
+// ParseLogLevel parses a log level name; empty is LogInfo
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch level := LogLevel(strings.ToLower(strings.TrimSpace(name))); level {
+	case "":
+		return LogInfo, nil
+	case LogDebug, LogInfo, LogWarn, LogError:
+		return level, nil
+	default:
+		return "", fmt.Errorf("invalid log level %q, use debug, info, warn or error", name)
+	}
+}
+
+// logs reports whether a request that got status is recorded at level
+func (level LogLevel) logs(status int) bool {
+	switch level {
+	case LogWarn:
+		return status >= 400
+	case LogError:
+		return status >= 500
+	default:
+		return true
+	}
+}
+
+// RuntimeConfig is the part of the configuration that can change while the
+// server runs, see Runtime
+type RuntimeConfig struct {
+	LogLevel LogLevel `json:"log_level"`
+	// CORSOrigins are the origins browsers may call the API from; "*"
+	// allows any
+	CORSOrigins []string `json:"cors_origins"`
+	// RateLimit is how many requests a client may send per minute, 0 for
+	// no limit
+	RateLimit int `json:"rate_limit"`
+	// Registration lets anyone register, not only the first account
+	Registration bool `json:"registration"`
+}
+
+// Runtime holds the current RuntimeConfig. A reload reads a whole new
+// snapshot and swaps it in atomically, so a request sees either the old
+// configuration or the new one, never a mix.
+type Runtime struct {
+	load     func() (*RuntimeConfig, error)
+	current  atomic.Pointer[RuntimeConfig]
+	mu       sync.Mutex
+	onReload []func(*RuntimeConfig)
+}
+
+// NewRuntime loads the configuration with load, which Reload calls again
+func NewRuntime(load func() (*RuntimeConfig, error)) (*Runtime, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	rt := &Runtime{load: load}
+	rt.current.Store(cfg)
+	return rt, nil
+}
+
+// Config returns the current configuration, which must not be modified
+func (rt *Runtime) Config() *RuntimeConfig {
+	return rt.current.Load()
+}
+
+// OnReload registers fn to be called with every configuration reloaded, for
+// the settings read outside of the middleware
+func (rt *Runtime) OnReload(fn func(*RuntimeConfig)) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.onReload = append(rt.onReload, fn)
+}
+
+// Reload loads the configuration again and swaps it in. On error the current
+// configuration is kept.
+func (rt *Runtime) Reload() (*RuntimeConfig, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	cfg, err := rt.load()
+	if err != nil {
+		return nil, err
+	}
+	rt.current.Store(cfg)
+	for _, fn := range rt.onReload {
+		fn(cfg)
+	}
+	log.Printf(
+		"Configuration reloaded: log level %s, CORS origins %s, rate limit %d/min, registration %t",
+		cfg.LogLevel, strings.Join(cfg.CORSOrigins, ", "), cfg.RateLimit, cfg.Registration,
+	)
+	return cfg, nil
+}
+
+// RequestLogger is Logger with the log level of rt's current configuration
+func RequestLogger(rt *Runtime) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			level := rt.Config().LogLevel
+			if !level.logs(wrapped.statusCode) {
+				return
+			}
+			if level == LogDebug {
+				log.Printf("%s %s %d %s from %s", r.Method, r.URL.RequestURI(), wrapped.statusCode,
+					time.Since(start), r.RemoteAddr)
+				return
+			}
+			log.Printf("%s %s %d %s", r.Method, r.URL.Path, wrapped.statusCode, time.Since(start))
+		})
+	}
+}
+
+// ReloadableCORS is CORS with the allowed origins of rt's current
+// configuration in place of cfg.AllowedOrigins
+func ReloadableCORS(rt *Runtime, cfg CORSConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := cfg
+			current.AllowedOrigins = rt.Config().CORSOrigins
+			CORS(current)(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitExempt are the paths probes call, which are never limited
+var rateLimitExempt = map[string]bool{"/health": true, "/readyz": true}
+
+// RateLimit creates a middleware that lets each client, told apart as by
+// AllowIPs, send the RateLimit of rt's current configuration per minute, in
+// bursts of up to that many. Others get 429 with a Retry-After header.
+// Requests pass through unchecked while the limit is 0.
+func RateLimit(rt *Runtime, trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	limiter := &rateLimiter{clients: make(map[netip.Addr]*rateBucket)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			perMinute := rt.Config().RateLimit
+			if perMinute <= 0 || rateLimitExempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			client, ok := clientAddr(r, trustedProxies)
+			if !ok {
+				respondMiddlewareError(w, http.StatusForbidden, "Access from this address is not allowed")
+				return
+			}
+			if allowed, retry := limiter.allow(client, perMinute, time.Now()); !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+				respondMiddlewareError(w, http.StatusTooManyRequests, "Too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimiter keeps a token bucket per client
+type rateLimiter struct {
+	mu        sync.Mutex
+	clients   map[netip.Addr]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// allow takes a token from client's bucket, which refills at perMinute
+// tokens a minute, and otherwise returns how long until one is there
+func (l *rateLimiter) allow(client netip.Addr, perMinute int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A bucket idle for a minute is full again, so it can be forgotten
+	if now.Sub(l.lastSweep) > time.Minute {
+		for addr, b := range l.clients {
+			if now.Sub(b.updated) > time.Minute {
+				delete(l.clients, addr)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	burst := float64(perMinute)
+	perSecond := burst / 60
+	b, ok := l.clients[client]
+	if !ok {
+		b = &rateBucket{tokens: burst, updated: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// runtimeConfig handles GET /api/admin/config
+func runtimeConfig(rt *Runtime) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rt.Config())
+	}
+}
+
+// reloadRuntimeConfig handles POST /api/admin/config/reload, the same as a
+// SIGHUP. A configuration that does not parse is kept out with 422.
+func reloadRuntimeConfig(rt *Runtime) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg, err := rt.Reload()
+		if err != nil {
+			log.Printf("Configuration reload failed, keeping the current one: %v", err)
+			respondMiddlewareError(w, http.StatusUnprocessableEntity, "Configuration reload failed: "+err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cfg)
+	}
+}
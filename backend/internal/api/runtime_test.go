@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRuntime is a Runtime whose reloads return *next, or err when set
+func testRuntime(t *testing.T, next *RuntimeConfig, err *error) *Runtime {
+	t.Helper()
+	rt, loadErr := NewRuntime(func() (*RuntimeConfig, error) {
+		if err != nil && *err != nil {
+			return nil, *err
+		}
+		cfg := *next
+		return &cfg, nil
+	})
+	if loadErr != nil {
+		t.Fatalf("Failed to create runtime: %v", loadErr)
+	}
+	return rt
+}
+
+func TestRuntime_Reload(t *testing.T) {
+	next := &RuntimeConfig{LogLevel: LogInfo, CORSOrigins: []string{"*"}}
+	var loadErr error
+	rt := testRuntime(t, next, &loadErr)
+
+	var seen []*RuntimeConfig
+	rt.OnReload(func(cfg *RuntimeConfig) { seen = append(seen, cfg) })
+
+	next.LogLevel = LogWarn
+	if _, err := rt.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if rt.Config().LogLevel != LogWarn || len(seen) != 1 {
+		t.Errorf("Expected the warn level to be swapped in and reported, got %+v", rt.Config())
+	}
+
+	// A configuration that does not load leaves the current one in place
+	loadErr = errors.New("invalid LOG_LEVEL")
+	if _, err := rt.Reload(); err == nil {
+		t.Fatal("Expected the reload to fail")
+	}
+	if rt.Config().LogLevel != LogWarn || len(seen) != 1 {
+		t.Errorf("Expected the warn level to be kept, got %+v", rt.Config())
+	}
+
+	for _, bad := range []string{"verbose", "2"} {
+		if _, err := ParseLogLevel(bad); err == nil {
+			t.Errorf("Expected log level %q to be rejected", bad)
+		}
+	}
+	if level, err := ParseLogLevel(" WARN "); err != nil || level != LogWarn {
+		t.Errorf("Expected WARN to parse as warn, got %q, %v", level, err)
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := &RuntimeConfig{LogLevel: LogInfo}
+	rt := testRuntime(t, cfg, nil)
+	handler := RequestLogger(rt)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	for _, tc := range []struct {
+		level  LogLevel
+		path   string
+		logged bool
+	}{
+		{LogInfo, "/api/budgets", true},
+		{LogWarn, "/api/budgets", false},
+		{LogWarn, "/missing", true},
+		{LogError, "/missing", false},
+	} {
+		cfg.LogLevel = tc.level
+		if _, err := rt.Reload(); err != nil {
+			t.Fatalf("Failed to reload: %v", err)
+		}
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tc.path+"?q=1", nil))
+		if logged := strings.Contains(buf.String(), tc.path); logged != tc.logged {
+			t.Errorf("%s %s: expected logged %t, got %q", tc.level, tc.path, tc.logged, buf.String())
+		}
+	}
+
+	// Only debug logs the query string
+	cfg.LogLevel = LogDebug
+	if _, err := rt.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	buf.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/budgets?q=1", nil))
+	if !strings.Contains(buf.String(), "/api/budgets?q=1") {
+		t.Errorf("Expected the query string at debug level, got %q", buf.String())
+	}
+}
+
+func TestReloadableCORS(t *testing.T) {
+	cfg := &RuntimeConfig{CORSOrigins: []string{"*"}}
+	rt := testRuntime(t, cfg, nil)
+	handler := ReloadableCORS(rt, DefaultCORSConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	allowOrigin := func(origin string) string {
+		req := httptest.NewRequest("GET", "/api/budgets", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if got := allowOrigin("https://other.example"); got != "*" {
+		t.Errorf("Expected any origin to be allowed, got %q", got)
+	}
+
+	cfg.CORSOrigins = []string{"https://budget.example"}
+	if _, err := rt.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := allowOrigin("https://budget.example"); got != "https://budget.example" {
+		t.Errorf("Expected the configured origin to be allowed, got %q", got)
+	}
+	if got := allowOrigin("https://other.example"); got != "" {
+		t.Errorf("Expected other origins to be refused after the reload, got %q", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := &RuntimeConfig{RateLimit: 0}
+	rt := testRuntime(t, cfg, nil)
+	handler := RateLimit(rt, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Off by default
+	for i := 0; i < 10; i++ {
+		if rec := send("/api/budgets", "203.0.113.9:4000"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected no limit, got status %d", rec.Code)
+		}
+	}
+
+	cfg.RateLimit = 3
+	if _, err := rt.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := send("/api/budgets", "203.0.113.9:4000"); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status %d, got %d", i+1, http.StatusOK, rec.Code)
+		}
+	}
+	rec := send("/api/budgets", "203.0.113.9:4000")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Other clients and probes are not held back
+	if rec := send("/api/budgets", "198.51.100.7:4000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected another client to pass, got %d", rec.Code)
+	}
+	if rec := send("/readyz", "203.0.113.9:4000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected /readyz to pass, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	limiter := &rateLimiter{clients: make(map[netip.Addr]*rateBucket)}
+	client := netip.MustParseAddr("203.0.113.9")
+	now := time.Now()
+
+	for i := 0; i < 60; i++ {
+		if ok, _ := limiter.allow(client, 60, now); !ok {
+			t.Fatalf("Request %d: expected the burst to pass", i+1)
+		}
+	}
+	ok, retry := limiter.allow(client, 60, now)
+	if ok || retry <= 0 || retry > time.Second {
+		t.Errorf("Expected to wait up to a second, got %t, %v", ok, retry)
+	}
+	// 60 a minute is one a second
+	if ok, _ := limiter.allow(client, 60, now.Add(time.Second)); !ok {
+		t.Error("Expected a token a second later")
+	}
+
+	// Idle clients are forgotten
+	limiter.allow(netip.MustParseAddr("198.51.100.7"), 60, now.Add(2*time.Minute))
+	if _, ok := limiter.clients[client]; ok {
+		t.Error("Expected the idle client to be swept")
+	}
+}
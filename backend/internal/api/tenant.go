@@ -0,0 +1,102 @@
+package api
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TenantRouter serves the households of a shared server, each with its own
+// database, from the subdomains of one domain: smith.budget.example.com is
+// served by the handler of tenant smith. The handler of a tenant is built by
+// open on its first request and reused afterwards.
+type TenantRouter struct {
+	domain  string
+	tenants []string
+	open    func(tenant string) (http.Handler, error)
+
+	mu       sync.Mutex
+	handlers map[string]*tenantEntry
+}
+
+// tenantEntry is the handler of one tenant; mu is held while it is opened
+type tenantEntry struct {
+	mu      sync.Mutex
+	handler http.Handler
+}
+
+// NewTenantRouter creates a router for tenants under domain. open builds the
+// handler of a tenant; when it fails the request gets a 503 and the next
+// request tries again.
+func NewTenantRouter(domain string, tenants []string, open func(tenant string) (http.Handler, error)) *TenantRouter {
+	return &TenantRouter{
+		domain:   strings.ToLower(strings.Trim(domain, ".")),
+		tenants:  tenants,
+		open:     open,
+		handlers: make(map[string]*tenantEntry),
+	}
+}
+
+// Tenant returns the tenant r is for, from its host, or "" when the host is
+// not the subdomain of a tenant
+func (t *TenantRouter) Tenant(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	name, ok := strings.CutSuffix(host, "."+t.domain)
+	if !ok || strings.Contains(name, ".") || !slices.Contains(t.tenants, name) {
+		return ""
+	}
+	return name
+}
+
+func (t *TenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant := t.Tenant(r)
+	if tenant == "" {
+		// Load balancers probe the server by address, not by household
+		if r.Method == http.MethodGet && r.URL.Path == "/health" {
+			healthCheck(w, r)
+			return
+		}
+		respondMiddlewareError(w, http.StatusNotFound, "Unknown household")
+		return
+	}
+
+	handler, err := t.handler(tenant)
+	if err != nil {
+		log.Printf("Failed to open tenant %s: %v", tenant, err)
+		respondMiddlewareError(w, http.StatusServiceUnavailable, "Household is unavailable, try again later")
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// handler returns the handler of tenant, opening it on first use. Requests
+// for a tenant that is being opened wait for it; other tenants are not held
+// up.
+func (t *TenantRouter) handler(tenant string) (http.Handler, error) {
+	t.mu.Lock()
+	entry, ok := t.handlers[tenant]
+	if !ok {
+		entry = &tenantEntry{}
+		t.handlers[tenant] = entry
+	}
+	t.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.handler != nil {
+		return entry.handler, nil
+	}
+	handler, err := t.open(tenant)
+	if err != nil {
+		return nil, err
+	}
+	entry.handler = handler
+	return handler, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTenantRouter(t *testing.T) {
+	opened := map[string]int{}
+	failing := true
+	router := NewTenantRouter("Budget.example.com", []string{"jones", "smith"}, func(tenant string) (http.Handler, error) {
+		if tenant == "jones" && failing {
+			return nil, errors.New("database is down")
+		}
+		opened[tenant]++
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tenant))
+		}), nil
+	})
+
+	do := func(host, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, host := range []string{"smith.budget.example.com", "SMITH.budget.example.com:8080", "smith.budget.example.com."} {
+		if rec := do(host, "/api/budgets"); rec.Code != http.StatusOK || rec.Body.String() != "smith" {
+			t.Errorf("%s: expected smith, got %d %q", host, rec.Code, rec.Body.String())
+		}
+	}
+	if opened["smith"] != 1 {
+		t.Errorf("Expected smith to be opened once, got %d", opened["smith"])
+	}
+
+	// Only single-label subdomains of configured tenants are served
+	for _, host := range []string{"budget.example.com", "doe.budget.example.com", "a.smith.budget.example.com", "smith.example.org"} {
+		if rec := do(host, "/api/budgets"); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", host, http.StatusNotFound, rec.Code)
+		}
+	}
+	if rec := do("10.0.0.5:8080", "/health"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the health check without a household, got %d", rec.Code)
+	}
+
+	// A tenant that fails to open is retried on the next request
+	if rec := do("jones.budget.example.com", "/api/budgets"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	failing = false
+	if rec := do("jones.budget.example.com", "/api/budgets"); rec.Code != http.StatusOK || rec.Body.String() != "jones" {
+		t.Errorf("Expected jones after a retry, got %d %q", rec.Code, rec.Body.String())
+	}
+}
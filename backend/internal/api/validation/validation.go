@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"budget-tracker/internal/models"
 	"fmt"
 	"strings"
 )
@@ -49,11 +50,11 @@ const (
 	MaxYear  = 2100
 )
 
-// Expense validation constants
+// Expense validation constants, shared with the models package
 const (
-	MaxItemNameLength = 200
-	MaxSourceLength   = 100
-	MaxItemCodeLength = 50
+	MaxItemNameLength = models.MaxItemNameLength
+	MaxSourceLength   = models.MaxExpectedSourceLength
+	MaxItemCodeLength = models.MaxItemCodeLength
 )
 
 // File validation constants
@@ -138,12 +139,13 @@ func ValidateStringMaxLength(value, field string, maxLength int) error {
 	return nil
 }
 
-// ValidateExpenseType validates expense type value
+// ValidateExpenseType validates an expected expense type value (any case)
 func ValidateExpenseType(expenseType string) error {
-	if expenseType != "WEEKLY" && expenseType != "MONTHLY" {
+	t := models.NormalizeExpenseType(expenseType)
+	if t != models.ExpenseTypeWeekly && t != models.ExpenseTypeMonthly {
 		return &ValidationError{
 			Field:   "expense_type",
-			Message: "must be WEEKLY or MONTHLY",
+			Message: "must be weekly or monthly",
 		}
 	}
 	return nil
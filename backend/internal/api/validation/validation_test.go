@@ -203,8 +203,10 @@ func TestValidateExpenseType(t *testing.T) {
 	}{
 		{"valid WEEKLY", "WEEKLY", false},
 		{"valid MONTHLY", "MONTHLY", false},
-		{"invalid lowercase weekly", "weekly", true},
-		{"invalid lowercase monthly", "monthly", true},
+		{"valid lowercase weekly", "weekly", false},
+		{"valid lowercase monthly", "monthly", false},
+		{"valid mixed case with whitespace", " Weekly ", false},
+		{"invalid misc for expected expenses", "MISC", true},
 		{"invalid DAILY", "DAILY", true},
 		{"invalid empty", "", true},
 		{"invalid random", "RANDOM", true},
@@ -0,0 +1,222 @@
+// Package config reads the settings and secrets of the server and budgetctl.
+//
+// A setting is looked up by its environment variable name in a chain of
+// sources, and the first non-empty value wins. After Load the chain is:
+//
+//  1. the process environment
+//  2. the file named by <NAME>_FILE, e.g. TURSO_AUTH_TOKEN_FILE
+//  3. the file /run/secrets/<name>, where Docker mounts secrets, with the name
+//     in lower case, e.g. /run/secrets/anthropic_api_key
+//  4. the .env file in the working directory, or the one named by ENV_FILE
+//
+// so secrets such as ANTHROPIC_API_KEY and TURSO_AUTH_TOKEN do not have to be
+// set in the plain environment. Before Load only the environment is read.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SecretsDir is where Docker mounts secrets
+const SecretsDir = "/run/secrets"
+
+// fileSuffix marks an environment variable that names the file holding the
+// value of the variable without it
+const fileSuffix = "_FILE"
+
+// Source is a place settings are read from
+type Source interface {
+	// Lookup returns the value of the setting name, or false when the
+	// source does not set it
+	Lookup(name string) (string, bool)
+}
+
+// Env reads the process environment
+type Env struct{}
+
+// Lookup implements Source
+func (Env) Lookup(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// Values is a Source of settings read up front, e.g. from files
+type Values map[string]string
+
+// Lookup implements Source
+func (v Values) Lookup(name string) (string, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+var (
+	mu      sync.RWMutex
+	sources = []Source{Env{}}
+)
+
+// Get returns the value of the setting name from the first source that sets
+// it to a non-empty value, or "" when none does
+func Get(name string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, source := range sources {
+		if value, ok := source.Lookup(name); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// SetSources replaces the sources Get reads from, in order of precedence
+func SetSources(s ...Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	sources = s
+}
+
+// Load reads the secrets directory and the .env file once and makes Get read
+// them, and the *_FILE variables, after the environment. A missing .env file
+// or secrets directory is not an error; an unreadable file, or a *_FILE
+// variable naming a file that does not exist, is.
+func Load() error {
+	if err := checkFileVariables(os.Environ()); err != nil {
+		return err
+	}
+	secrets, err := ReadSecretsDir(SecretsDir)
+	if err != nil {
+		return err
+	}
+
+	path, explicit := os.LookupEnv("ENV_FILE")
+	if !explicit {
+		path = ".env"
+	}
+	dotEnv, err := ReadDotEnv(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		dotEnv, err = Values{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	SetSources(Env{}, FileVariables{}, secrets, dotEnv)
+	return nil
+}
+
+// FileVariables reads the file named by the <NAME>_FILE variable of a
+// setting. The file is read on every lookup, so a rotated secret is picked up
+// the next time it is needed.
+type FileVariables struct{}
+
+// Lookup implements Source
+func (FileVariables) Lookup(name string) (string, bool) {
+	path := os.Getenv(name + fileSuffix)
+	if path == "" {
+		return "", false
+	}
+	value, err := readSecretFile(path)
+	if err != nil {
+		log.Printf("Warning: %s%s: %v", name, fileSuffix, err)
+		return "", false
+	}
+	return value, true
+}
+
+// checkFileVariables reports the first *_FILE variable of environ, given as
+// KEY=value pairs, that names a file which does not exist
+func checkFileVariables(environ []string) error {
+	for _, entry := range environ {
+		key, path, _ := strings.Cut(entry, "=")
+		if !strings.HasSuffix(key, fileSuffix) || path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ReadSecretsDir reads every file in dir, keyed by its name in upper case.
+// A missing directory reads as empty.
+func ReadSecretsDir(dir string) (Values, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Values{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	values := Values{}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		// Kubernetes mounts secrets as symlinks, so they are followed
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		value, err := readSecretFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values[strings.ToUpper(entry.Name())] = value
+	}
+	return values, nil
+}
+
+// readSecretFile reads a file holding one value. Editors and
+// "echo secret > file" leave a trailing newline, which is not part of it.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// ReadDotEnv parses a .env file of KEY=value lines. Blank lines and lines
+// starting with # are skipped, an "export " prefix is allowed, and values may
+// be wrapped in single or double quotes. Unquoted values end at " #".
+func ReadDotEnv(path string) (Values, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer f.Close()
+
+	values := Values{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", path, n)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestGet_Precedence(t *testing.T) {
+	t.Cleanup(func() { SetSources(Env{}) })
+
+	dir := t.TempDir()
+	t.Setenv("BUDGET_TEST_TOKEN_FILE", writeFile(t, dir, "token", "from-file\n"))
+	SetSources(
+		Env{},
+		FileVariables{},
+		Values{"BUDGET_TEST_TOKEN": "from-secrets", "BUDGET_TEST_PORT": "from-secrets"},
+		Values{"BUDGET_TEST_PORT": "from-dotenv", "BUDGET_TEST_MODE": "from-dotenv"},
+	)
+
+	if got := Get("BUDGET_TEST_TOKEN"); got != "from-file" {
+		t.Errorf("Expected the _FILE variable before the secrets, got %q", got)
+	}
+	if got := Get("BUDGET_TEST_PORT"); got != "from-secrets" {
+		t.Errorf("Expected the secrets before .env, got %q", got)
+	}
+
+	t.Setenv("BUDGET_TEST_MODE", "from-env")
+	if got := Get("BUDGET_TEST_MODE"); got != "from-env" {
+		t.Errorf("Expected the environment first, got %q", got)
+	}
+	// An empty variable does not hide the value of a later source
+	t.Setenv("BUDGET_TEST_MODE", "")
+	if got := Get("BUDGET_TEST_MODE"); got != "from-dotenv" {
+		t.Errorf("Expected .env for an empty variable, got %q", got)
+	}
+	if got := Get("BUDGET_TEST_UNSET"); got != "" {
+		t.Errorf("Expected an unset setting to be empty, got %q", got)
+	}
+}
+
+func TestReadDotEnv(t *testing.T) {
+	path := writeFile(t, t.TempDir(), ".env", `# Budget Tracker
+ANTHROPIC_API_KEY=sk-ant-123
+
+export TURSO_MODE=remote
+TURSO_AUTH_TOKEN="token with spaces # kept"
+SMTP_FROM='Budget <budget@example.com>'
+PORT=8080 # inline comment
+EMPTY=
+`)
+
+	values, err := ReadDotEnv(path)
+	if err != nil {
+		t.Fatalf("Failed to read .env: %v", err)
+	}
+	want := map[string]string{
+		"ANTHROPIC_API_KEY": "sk-ant-123",
+		"TURSO_MODE":        "remote",
+		"TURSO_AUTH_TOKEN":  "token with spaces # kept",
+		"SMTP_FROM":         "Budget <budget@example.com>",
+		"PORT":              "8080",
+		"EMPTY":             "",
+	}
+	for key, value := range want {
+		if got, ok := values[key]; !ok || got != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got)
+		}
+	}
+	if len(values) != len(want) {
+		t.Errorf("Expected %d values, got %+v", len(want), values)
+	}
+
+	bad := writeFile(t, t.TempDir(), ".env", "PORT=8080\nnot a setting\n")
+	if _, err := ReadDotEnv(bad); err == nil {
+		t.Error("Expected a line without = to be rejected")
+	}
+}
+
+func TestReadSecretsDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "anthropic_api_key", "sk-ant-123\n")
+	writeFile(t, dir, ".hidden", "ignored")
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(
+		writeFile(t, t.TempDir(), "token", "linked"),
+		filepath.Join(dir, "turso_auth_token"),
+	); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	values, err := ReadSecretsDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read secrets: %v", err)
+	}
+	if values["ANTHROPIC_API_KEY"] != "sk-ant-123" || values["TURSO_AUTH_TOKEN"] != "linked" || len(values) != 2 {
+		t.Errorf("Unexpected secrets: %+v", values)
+	}
+
+	if values, err := ReadSecretsDir(filepath.Join(dir, "missing")); err != nil || len(values) != 0 {
+		t.Errorf("Expected a missing directory to read as empty, got %+v, %v", values, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Cleanup(func() { SetSources(Env{}) })
+
+	dir := t.TempDir()
+	t.Setenv("ENV_FILE", writeFile(t, dir, "budget.env", "BUDGET_TEST_MODE=remote\n"))
+	if err := Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := Get("BUDGET_TEST_MODE"); got != "remote" {
+		t.Errorf("Expected the value from ENV_FILE, got %q", got)
+	}
+
+	t.Setenv("BUDGET_TEST_TOKEN_FILE", filepath.Join(dir, "missing"))
+	if err := Load(); err == nil {
+		t.Error("Expected a _FILE variable naming a missing file to fail")
+	}
+
+	t.Setenv("BUDGET_TEST_TOKEN_FILE", "")
+	t.Setenv("ENV_FILE", filepath.Join(dir, "missing.env"))
+	if err := Load(); err == nil {
+		t.Error("Expected a missing ENV_FILE to fail")
+	}
+}
@@ -0,0 +1,100 @@
+// Package events is an in-process event bus. Repositories publish what
+// changed, e.g. a budget was updated or an expense recorded, and subsystems like notifications and
+// plugins subscribe to react right away instead of polling the database.
+package events
+
+import (
+	"budget-tracker/internal/models"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Type names an event
+type Type string
+
+const (
+	BudgetCreated Type = "budget.created"
+	BudgetUpdated Type = "budget.updated"
+	BudgetDeleted Type = "budget.deleted"
+	// BudgetThresholdCrossed is published once per crossing of a notification
+	// threshold, when the alert is recorded
+	BudgetThresholdCrossed Type = "budget.threshold_crossed"
+
+	ExpenseCreated Type = "expense.created"
+	ExpenseUpdated Type = "expense.updated"
+	ExpenseDeleted Type = "expense.deleted"
+)
+
+// Event describes a change
+type Event struct {
+	Type   Type  `json:"type"`
+	UserID int64 `json:"user_id"`
+	// Budget is the budget after the change, or before it when deleted
+	Budget *models.BudgetLimit `json:"budget,omitempty"`
+	// Expense is the actual expense after the change, or before it when
+	// deleted
+	Expense *models.ActualExpense `json:"expense,omitempty"`
+	// Threshold is the crossed threshold of BudgetThresholdCrossed
+	Threshold float64   `json:"threshold,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Handler reacts to an event. It runs in the publisher's goroutine, after
+// the change is stored, so it must not block: start a goroutine for slow
+// work like a network call.
+type Handler func(Event)
+
+type subscription struct {
+	types   []Type
+	handler Handler
+}
+
+// Bus delivers published events to their subscribers. A nil *Bus drops
+// every event, so publishers need no bus.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+// NewBus creates an empty Bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls handler with every event of types, or with every event
+// when no types are given. Handlers are called in the order they subscribed.
+func (b *Bus) Subscribe(handler Handler, types ...Type) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, subscription{types: types, handler: handler})
+}
+
+// Publish delivers e to its subscribers, stamping it with the current time
+// when At is zero. A handler that panics is logged and does not stop the
+// others or the publisher.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, s := range b.subscriptions {
+		if len(s.types) == 0 || slices.Contains(s.types, e.Type) {
+			deliver(s.handler, e)
+		}
+	}
+}
+
+func deliver(handler Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Events] handler of %s panicked: %v", e.Type, r)
+		}
+	}()
+	handler(e)
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var all, updates []Type
+	bus.Subscribe(func(e Event) { all = append(all, e.Type) })
+	bus.Subscribe(func(e Event) { panic("broken handler") }, BudgetCreated)
+	bus.Subscribe(func(e Event) {
+		if e.At.IsZero() {
+			t.Errorf("Expected the event stamped, got %+v", e)
+		}
+		updates = append(updates, e.Type)
+	}, BudgetUpdated, BudgetDeleted)
+
+	bus.Publish(Event{Type: BudgetCreated})
+	bus.Publish(Event{Type: BudgetUpdated})
+	bus.Publish(Event{Type: BudgetThresholdCrossed})
+
+	if want := []Type{BudgetCreated, BudgetUpdated, BudgetThresholdCrossed}; !slices.Equal(all, want) {
+		t.Errorf("Expected every event, got %v", all)
+	}
+	if want := []Type{BudgetUpdated}; !slices.Equal(updates, want) {
+		t.Errorf("Expected only the subscribed types, got %v", updates)
+	}
+
+	// A nil bus drops events
+	var none *Bus
+	none.Publish(Event{Type: BudgetCreated})
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
@@ -16,10 +18,36 @@ type ActualExpense struct {
 	ExpectedExpenseID *int64      `json:"expected_expense_id,omitempty"`
 	ReceiptDate       time.Time   `json:"receipt_date"`
 	ReceiptNumber     int64       `json:"receipt_number"`
+	LineNo            *int        `json:"line_no,omitempty"`
 	Month             int         `json:"month"`
 	Year              int         `json:"year"`
 	CreatedAt         time.Time   `json:"created_at"`
 	UpdatedAt         time.Time   `json:"updated_at"`
+
+	// MonthAssigned is set when month/year were picked on save to split the
+	// receipt across months, instead of following the receipt date
+	MonthAssigned bool `json:"month_assigned"`
+
+	// PendingApproval is set while an expense over the approval rule waits
+	// for approval; pending expenses are left out of totals
+	PendingApproval bool       `json:"pending_approval"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
+	ApprovedBy      *int64     `json:"approved_by,omitempty"`
+
+	// Deductible marks the expense for the year-end deductible report, e.g.
+	// a donation
+	Deductible bool `json:"deductible"`
+
+	// AutoGenerated marks an expense created on the due date of an expected
+	// expense with auto_generate set rather than entered
+	AutoGenerated bool `json:"auto_generated"`
+
+	// Tags are free-form labels in lower case, e.g. work or vacation
+	Tags []string `json:"tags,omitempty"`
+
+	// ExpectedExpense is the linked expected expense, only set when the
+	// client asks for it with include=expected_expense
+	ExpectedExpense *ExpectedExpense `json:"expected_expense,omitempty"`
 }
 
 // CreateActualExpenseRequest for creating actual expenses
@@ -32,6 +60,43 @@ type CreateActualExpenseRequest struct {
 	ExpectedExpenseID *int64      `json:"expected_expense_id,omitempty"`
 	ReceiptDate       *time.Time  `json:"receipt_date,omitempty"`
 	ReceiptNumber     int64       `json:"receipt_number"`
+	LineNo            *int        `json:"line_no,omitempty"` // 1-based position on the receipt
+	// Deductible is also set by a matching deductible category rule
+	Deductible bool `json:"deductible,omitempty"`
+	// AutoGenerated is only set by the recurring expense job
+	AutoGenerated bool     `json:"-"`
+	Tags          []string `json:"tags,omitempty"`
+
+	// BudgetMonth and BudgetYear count the item toward the month before the
+	// receipt's, e.g. the part of a receipt dated the 1st that belongs to last
+	// month's groceries. Both or neither are set.
+	BudgetMonth int `json:"budget_month,omitempty"`
+	BudgetYear  int `json:"budget_year,omitempty"`
+}
+
+// EffectiveReceiptDate returns the receipt date, defaulting to now when unset
+func (r *CreateActualExpenseRequest) EffectiveReceiptDate() time.Time {
+	if r.ReceiptDate != nil {
+		return *r.ReceiptDate
+	}
+	return time.Now()
+}
+
+// BudgetPeriod returns the month and year the expense counts toward: the
+// requested budget month, or the month of receiptDate
+func (r *CreateActualExpenseRequest) BudgetPeriod(receiptDate time.Time) (month, year int, assigned bool) {
+	if r.BudgetMonth != 0 || r.BudgetYear != 0 {
+		return r.BudgetMonth, r.BudgetYear, true
+	}
+	return int(receiptDate.Month()), receiptDate.Year(), false
+}
+
+// IsSplitMonth reports whether month/year may hold an expense received on
+// receiptDate: its own month or, when split, the month before
+func IsSplitMonth(receiptDate time.Time, month, year int) bool {
+	previous := time.Date(receiptDate.Year(), receiptDate.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	return (month == int(receiptDate.Month()) && year == receiptDate.Year()) ||
+		(month == int(previous.Month()) && year == previous.Year())
 }
 
 func (r *CreateActualExpenseRequest) Validate() error {
@@ -41,13 +106,13 @@ func (r *CreateActualExpenseRequest) Validate() error {
 	if r.ItemName == "" {
 		return ErrItemNameRequired
 	}
-	if len(r.ItemName) > 255 {
+	if len(r.ItemName) > MaxItemNameLength {
 		return ErrItemNameTooLong
 	}
 	if r.Source == "" {
 		return ErrSourceRequired
 	}
-	if len(r.Source) > 255 {
+	if len(r.Source) > MaxSourceLength {
 		return ErrSourceTooLong
 	}
 	if r.ActualAmount <= 0 {
@@ -58,6 +123,65 @@ func (r *CreateActualExpenseRequest) Validate() error {
 		r.ExpenseType != ExpenseTypeMisc && r.ExpenseType != ExpenseTypeTax {
 		return ErrInvalidExpenseType
 	}
+	if r.LineNo != nil && *r.LineNo < 1 {
+		return ErrInvalidLineNo
+	}
+	var err error
+	if r.Tags, err = NormalizeTags(r.Tags); err != nil {
+		return err
+	}
+	if r.BudgetMonth != 0 || r.BudgetYear != 0 {
+		if r.BudgetMonth == 0 || r.BudgetYear == 0 {
+			return ErrBudgetMonthIncomplete
+		}
+		if !IsSplitMonth(r.EffectiveReceiptDate(), r.BudgetMonth, r.BudgetYear) {
+			return ErrInvalidBudgetMonth
+		}
+	}
+	return nil
+}
+
+// MaxBulkActualExpenses caps the number of expenses accepted in one bulk request
+const MaxBulkActualExpenses = 500
+
+// BulkCreateActualExpenseRequest for creating several actual expenses at once
+type BulkCreateActualExpenseRequest struct {
+	Expenses []CreateActualExpenseRequest `json:"expenses"`
+}
+
+func (r *BulkCreateActualExpenseRequest) Validate() error {
+	if len(r.Expenses) == 0 {
+		return ErrBulkEmpty
+	}
+	if len(r.Expenses) > MaxBulkActualExpenses {
+		return ErrBulkTooLarge
+	}
+	for i := range r.Expenses {
+		if err := r.Expenses[i].Validate(); err != nil {
+			return fmt.Errorf("expenses[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// MaxReceiptsPerRequest caps the number of receipts saved in one request
+const MaxReceiptsPerRequest = 20
+
+// SaveReceiptsRequest for saving the receipts of a processed document, each
+// as its own receipt
+type SaveReceiptsRequest struct {
+	Receipts []BulkCreateActualExpenseRequest `json:"receipts"`
+}
+
+// Validate checks the number of receipts; the receipts themselves are
+// validated when each is saved
+func (r *SaveReceiptsRequest) Validate() error {
+	if len(r.Receipts) == 0 {
+		return ErrReceiptsEmpty
+	}
+	if len(r.Receipts) > MaxReceiptsPerRequest {
+		return ErrReceiptsTooLarge
+	}
 	return nil
 }
 
@@ -69,6 +193,9 @@ type UpdateActualExpenseRequest struct {
 	ExpenseType       *ExpenseType `json:"expense_type,omitempty"`
 	ItemCode          *string      `json:"item_code,omitempty"`
 	ExpectedExpenseID *int64       `json:"expected_expense_id,omitempty"`
+	Deductible        *bool        `json:"deductible,omitempty"`
+	// Tags replaces the expense's tags; an empty list removes them
+	Tags *[]string `json:"tags,omitempty"`
 }
 
 func (r *UpdateActualExpenseRequest) Validate() error {
@@ -77,7 +204,7 @@ func (r *UpdateActualExpenseRequest) Validate() error {
 		if *r.ItemName == "" {
 			return ErrItemNameRequired
 		}
-		if len(*r.ItemName) > 255 {
+		if len(*r.ItemName) > MaxItemNameLength {
 			return ErrItemNameTooLong
 		}
 	}
@@ -86,7 +213,7 @@ func (r *UpdateActualExpenseRequest) Validate() error {
 		if *r.Source == "" {
 			return ErrSourceRequired
 		}
-		if len(*r.Source) > 255 {
+		if len(*r.Source) > MaxSourceLength {
 			return ErrSourceTooLong
 		}
 	}
@@ -99,9 +226,36 @@ func (r *UpdateActualExpenseRequest) Validate() error {
 			return ErrInvalidExpenseType
 		}
 	}
+	if r.Tags != nil {
+		tags, err := NormalizeTags(*r.Tags)
+		if err != nil {
+			return err
+		}
+		r.Tags = &tags
+	}
 	return nil
 }
 
+// NormalizeTags trims and lower-cases tags and drops empty and repeated
+// ones, so "Work" and "work " are one tag
+func NormalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(normalized, tag) {
+			continue
+		}
+		if len(tag) > MaxTagLength || strings.Contains(tag, ",") {
+			return nil, ErrInvalidTag
+		}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTagsPerExpense {
+		return nil, ErrTooManyTags
+	}
+	return normalized, nil
+}
+
 // ActualExpenseSummary for aggregated data
 type ActualExpenseSummary struct {
 	Month        int     `json:"month"`
@@ -111,4 +265,20 @@ type ActualExpenseSummary struct {
 	TotalMisc    float64 `json:"total_misc"`
 	TotalTax     float64 `json:"total_tax"`
 	TotalActual  float64 `json:"total_actual"`
+	// Allowances is the spending of the user's allowance sub-accounts, which
+	// is not part of the totals
+	Allowances []AllowanceStatus `json:"allowances,omitempty"`
+}
+
+// ApprovalRule sends actual expenses over Amount to pending approval
+type ApprovalRule struct {
+	Amount float64 `json:"amount"`
+}
+
+// Validate validates the ApprovalRule
+func (r *ApprovalRule) Validate() error {
+	if r.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
 }
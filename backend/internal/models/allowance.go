@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxAllowanceNameLength bounds the name of an allowance sub-account
+const MaxAllowanceNameLength = 50
+
+var ErrInvalidAllowanceName = fmt.Errorf(
+	"name is required and must be at most %d characters",
+	MaxAllowanceNameLength,
+)
+
+// Allowance is a sub-account, e.g. for a child, that logs its own expenses
+// against a monthly allowance. ID is the sub-account's user ID.
+type Allowance struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	MonthlyAmount float64   `json:"monthly_amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AllowanceStatus is a sub-account's spending in one month. Expenses held
+// for approval are not counted, as in the monthly totals.
+type AllowanceStatus struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	MonthlyAmount float64 `json:"monthly_amount"`
+	Spent         float64 `json:"spent"`
+	Remaining     float64 `json:"remaining"`
+}
+
+// AllowanceRequest is the request body for creating an allowance sub-account
+type AllowanceRequest struct {
+	Name          string  `json:"name"`
+	MonthlyAmount float64 `json:"monthly_amount"`
+}
+
+// Validate trims the name and validates the request
+func (r *AllowanceRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len([]rune(r.Name)) > MaxAllowanceNameLength {
+		return ErrInvalidAllowanceName
+	}
+	if r.MonthlyAmount <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// UpdateAllowanceRequest is the request body for updating an allowance sub-account
+type UpdateAllowanceRequest struct {
+	Name          *string  `json:"name,omitempty"`
+	MonthlyAmount *float64 `json:"monthly_amount,omitempty"`
+}
+
+// Validate trims the name and validates the fields that are set
+func (r *UpdateAllowanceRequest) Validate() error {
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		if name == "" || len([]rune(name)) > MaxAllowanceNameLength {
+			return ErrInvalidAllowanceName
+		}
+		r.Name = &name
+	}
+	if r.MonthlyAmount != nil && *r.MonthlyAmount <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
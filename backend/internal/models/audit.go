@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited entities
+const (
+	AuditEntityBudget          = "budget"
+	AuditEntityExpectedExpense = "expected_expense"
+	AuditEntityActualExpense   = "actual_expense"
+)
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry records one change to a budget or expense. Before and After are
+// snapshots of the record as the API returns it; Before is empty for a
+// create and After for a delete.
+type AuditEntry struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+	// Actor is the email of the user who made the change, empty in the
+	// shared workspace
+	Actor     string          `json:"actor"`
+	Entity    string          `json:"entity"`
+	EntityID  int64           `json:"entity_id"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// IsAuditEntity reports whether entity is one of the audited entities
+func IsAuditEntity(entity string) bool {
+	switch entity {
+	case AuditEntityBudget, AuditEntityExpectedExpense, AuditEntityActualExpense:
+		return true
+	}
+	return false
+}
+
+// IsAuditAction reports whether action is a known audit action
+func IsAuditAction(action string) bool {
+	return action == AuditCreate || action == AuditUpdate || action == AuditDelete
+}
@@ -1,16 +1,107 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"time"
+)
 
-// BudgetLimit represents a monthly budget limit
+// DefaultNotificationThreshold is the threshold of budgets created without one
+const DefaultNotificationThreshold = 0.8
+
+// MaxNotificationThresholds caps the notification thresholds of one budget
+const MaxNotificationThresholds = 10
+
+// BudgetLimit represents a monthly budget limit. With RolloverUnspent, what
+// was left of the previous month's budget is added to the month's effective
+// limit. WeeklyLimit optionally caps the spending of each week as well; 0
+// means no weekly cap.
 type BudgetLimit struct {
-	ID                    int64     `json:"id"`
-	Month                 int       `json:"month"`
-	Year                  int       `json:"year"`
-	Amount                float64   `json:"amount"`
-	NotificationThreshold float64   `json:"notification_threshold"`
-	CreatedAt             time.Time `json:"created_at"`
-	UpdatedAt             time.Time `json:"updated_at"`
+	ID     int64   `json:"id"`
+	Month  int     `json:"month"`
+	Year   int     `json:"year"`
+	Amount float64 `json:"amount"`
+	// NotificationThresholds are the fractions of the budget to be notified
+	// at, lowest first. NotificationThreshold is the lowest of them, where
+	// the warning status starts, kept for clients that set a single one.
+	NotificationThreshold  float64   `json:"notification_threshold"`
+	NotificationThresholds []float64 `json:"notification_thresholds"`
+	RolloverUnspent        bool      `json:"rollover_unspent"`
+	WeeklyLimit            float64   `json:"weekly_limit"`
+	// ClosedAt is set while the month is closed, see ErrMonthClosed
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+	// DeletedAt is set while the budget is deleted and can still be restored
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CrossedThresholds returns the notification thresholds reached at
+// percentageUsed percent of the budget, lowest first
+func (b *BudgetLimit) CrossedThresholds(percentageUsed float64) []float64 {
+	crossed := []float64{}
+	for _, threshold := range b.NotificationThresholds {
+		if percentageUsed >= threshold*100 {
+			crossed = append(crossed, threshold)
+		}
+	}
+	return crossed
+}
+
+// ThresholdChanges compares the thresholds reached at percentageUsed with
+// the ones already fired. Crossed are the thresholds newly reached. Recovered
+// are the fired ones spending fell back below by more than hysteresis
+// percentage points, so spending hovering at a threshold does not alert over
+// and over. Stale are fired thresholds the budget no longer has.
+func (b *BudgetLimit) ThresholdChanges(
+	percentageUsed float64,
+	fired []float64,
+	hysteresis float64,
+) (crossed, recovered, stale []float64) {
+	isFired := map[float64]bool{}
+	for _, threshold := range fired {
+		isFired[threshold] = true
+	}
+	has := map[float64]bool{}
+	for _, threshold := range b.NotificationThresholds {
+		has[threshold] = true
+		switch {
+		case !isFired[threshold] && percentageUsed >= threshold*100:
+			crossed = append(crossed, threshold)
+		case isFired[threshold] && percentageUsed < threshold*100-hysteresis:
+			recovered = append(recovered, threshold)
+		}
+	}
+	for _, threshold := range fired {
+		if !has[threshold] {
+			stale = append(stale, threshold)
+		}
+	}
+	return crossed, recovered, stale
+}
+
+// Escalation levels of budget alerts past the notification thresholds, as in
+// the budget status: danger from 90% of the budget, over past it
+const (
+	AlertLevelDanger = "danger"
+	AlertLevelOver   = "over"
+)
+
+// AlertLevelPercentages are the percentages of the budget where the
+// escalation levels start
+var AlertLevelPercentages = map[string]float64{AlertLevelDanger: 90, AlertLevelOver: 100}
+
+// AlertLevel returns the escalation level of percentageUsed, or "" below
+// danger
+func AlertLevel(percentageUsed float64) string {
+	switch {
+	case percentageUsed > AlertLevelPercentages[AlertLevelOver]:
+		return AlertLevelOver
+	case percentageUsed >= AlertLevelPercentages[AlertLevelDanger]:
+		return AlertLevelDanger
+	default:
+		return ""
+	}
 }
 
 // CreateBudgetLimitRequest represents the request body for creating a budget limit
@@ -19,12 +110,55 @@ type CreateBudgetLimitRequest struct {
 	Year                  int     `json:"year"`
 	Amount                float64 `json:"amount"`
 	NotificationThreshold float64 `json:"notification_threshold,omitempty"`
+	// NotificationThresholds takes precedence over NotificationThreshold
+	NotificationThresholds []float64 `json:"notification_thresholds,omitempty"`
+	RolloverUnspent        bool      `json:"rollover_unspent,omitempty"`
+	WeeklyLimit            float64   `json:"weekly_limit,omitempty"`
+}
+
+// MaxBulkBudgets caps the number of budgets accepted in one bulk request,
+// ten years of months
+const MaxBulkBudgets = 120
+
+// BulkCreateBudgetRequest for creating the budgets of several months at once
+type BulkCreateBudgetRequest struct {
+	Budgets []CreateBudgetLimitRequest `json:"budgets"`
 }
 
-// UpdateBudgetLimitRequest represents the request body for updating a budget limit
+// UpdateBudgetLimitRequest represents the request body for updating a budget
+// limit. A single NotificationThreshold replaces all the thresholds, and
+// NotificationThresholds takes precedence over it.
 type UpdateBudgetLimitRequest struct {
-	Amount                *float64 `json:"amount,omitempty"`
-	NotificationThreshold *float64 `json:"notification_threshold,omitempty"`
+	Amount                 *float64  `json:"amount,omitempty"`
+	NotificationThreshold  *float64  `json:"notification_threshold,omitempty"`
+	NotificationThresholds []float64 `json:"notification_thresholds,omitempty"`
+	RolloverUnspent        *bool     `json:"rollover_unspent,omitempty"`
+	// WeeklyLimit 0 removes the weekly cap
+	WeeklyLimit *float64 `json:"weekly_limit,omitempty"`
+}
+
+// UpsertBudgetLimitRequest represents the request body for setting the budget
+// of a month; month and year come from the URL. The thresholds are set as in
+// UpdateBudgetLimitRequest.
+type UpsertBudgetLimitRequest struct {
+	Month                  int       `json:"-"`
+	Year                   int       `json:"-"`
+	Amount                 float64   `json:"amount"`
+	NotificationThreshold  *float64  `json:"notification_threshold,omitempty"`
+	NotificationThresholds []float64 `json:"notification_thresholds,omitempty"`
+	// RolloverUnspent nil keeps the existing setting, or off for a new budget
+	RolloverUnspent *bool `json:"rollover_unspent,omitempty"`
+	// WeeklyLimit nil keeps the existing weekly cap, or none for a new budget
+	WeeklyLimit *float64 `json:"weekly_limit,omitempty"`
+}
+
+// CopyBudgetRequest represents the request body for copying the previous
+// month's budget into Month and Year. Overwrite replaces a budget the month
+// already has.
+type CopyBudgetRequest struct {
+	Month     int  `json:"month"`
+	Year      int  `json:"year"`
+	Overwrite bool `json:"overwrite,omitempty"`
 }
 
 // Validate validates the CreateBudgetLimitRequest
@@ -38,12 +172,40 @@ func (r *CreateBudgetLimitRequest) Validate() error {
 	if r.Amount <= 0 {
 		return ErrInvalidAmount
 	}
+	if r.WeeklyLimit < 0 {
+		return ErrInvalidWeeklyLimit
+	}
+	if len(r.NotificationThresholds) > 0 {
+		thresholds, err := normalizeThresholds(r.NotificationThresholds)
+		if err != nil {
+			return err
+		}
+		r.NotificationThresholds = thresholds
+		r.NotificationThreshold = thresholds[0]
+		return nil
+	}
 	if r.NotificationThreshold == 0 {
-		r.NotificationThreshold = 0.8 // Default value
+		r.NotificationThreshold = DefaultNotificationThreshold
 	}
 	if r.NotificationThreshold < 0 || r.NotificationThreshold > 1 {
 		return ErrInvalidThreshold
 	}
+	r.NotificationThresholds = []float64{r.NotificationThreshold}
+	return nil
+}
+
+func (r *BulkCreateBudgetRequest) Validate() error {
+	if len(r.Budgets) == 0 {
+		return ErrBulkBudgetsEmpty
+	}
+	if len(r.Budgets) > MaxBulkBudgets {
+		return ErrBulkBudgetsTooLarge
+	}
+	for i := range r.Budgets {
+		if err := r.Budgets[i].Validate(); err != nil {
+			return fmt.Errorf("budgets[%d]: %w", i, err)
+		}
+	}
 	return nil
 }
 
@@ -52,9 +214,157 @@ func (r *UpdateBudgetLimitRequest) Validate() error {
 	if r.Amount != nil && *r.Amount <= 0 {
 		return ErrInvalidAmount
 	}
-	if r.NotificationThreshold != nil &&
-		(*r.NotificationThreshold < 0 || *r.NotificationThreshold > 1) {
+	if r.WeeklyLimit != nil && *r.WeeklyLimit < 0 {
+		return ErrInvalidWeeklyLimit
+	}
+	return resolveThresholds(&r.NotificationThreshold, &r.NotificationThresholds)
+}
+
+// Validate validates the UpsertBudgetLimitRequest
+func (r *UpsertBudgetLimitRequest) Validate() error {
+	if r.Month < 1 || r.Month > 12 {
+		return ErrInvalidMonth
+	}
+	if r.Year < 2020 || r.Year > 2100 {
+		return ErrInvalidYear
+	}
+	if r.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if r.WeeklyLimit != nil && *r.WeeklyLimit < 0 {
+		return ErrInvalidWeeklyLimit
+	}
+	return resolveThresholds(&r.NotificationThreshold, &r.NotificationThresholds)
+}
+
+// resolveThresholds validates the optional thresholds of an update and sets
+// both fields when either is given: the list to the single threshold, or the
+// single threshold to the lowest of the list
+func resolveThresholds(threshold **float64, thresholds *[]float64) error {
+	if *thresholds != nil {
+		normalized, err := normalizeThresholds(*thresholds)
+		if err != nil {
+			return err
+		}
+		*thresholds = normalized
+		*threshold = &normalized[0]
+		return nil
+	}
+	if *threshold != nil {
+		if **threshold < 0 || **threshold > 1 {
+			return ErrInvalidThreshold
+		}
+		*thresholds = []float64{**threshold}
+	}
+	return nil
+}
+
+// normalizeThresholds checks thresholds and returns them sorted without
+// repeats
+func normalizeThresholds(thresholds []float64) ([]float64, error) {
+	if len(thresholds) == 0 || len(thresholds) > MaxNotificationThresholds {
+		return nil, ErrInvalidThresholds
+	}
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+	normalized := sorted[:0]
+	for i, threshold := range sorted {
+		if threshold < 0 || threshold > 1 {
+			return nil, ErrInvalidThreshold
+		}
+		if i == 0 || threshold != sorted[i-1] {
+			normalized = append(normalized, threshold)
+		}
+	}
+	return normalized, nil
+}
+
+// Validate validates the CopyBudgetRequest
+func (r *CopyBudgetRequest) Validate() error {
+	if r.Month < 1 || r.Month > 12 {
+		return ErrInvalidMonth
+	}
+	if r.Year < 2020 || r.Year > 2100 {
+		return ErrInvalidYear
+	}
+	return nil
+}
+
+// PreviousMonth returns the month before the request's month
+func (r *CopyBudgetRequest) PreviousMonth() (int, int) {
+	if r.Month == 1 {
+		return 12, r.Year - 1
+	}
+	return r.Month - 1, r.Year
+}
+
+// DefaultBudget is the monthly budget used for months without a budget limit
+type DefaultBudget struct {
+	Amount                float64 `json:"amount"`
+	NotificationThreshold float64 `json:"notification_threshold,omitempty"`
+}
+
+// Validate validates the DefaultBudget
+func (b *DefaultBudget) Validate() error {
+	if b.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if b.NotificationThreshold == 0 {
+		b.NotificationThreshold = DefaultNotificationThreshold
+	}
+	if b.NotificationThreshold < 0 || b.NotificationThreshold > 1 {
 		return ErrInvalidThreshold
 	}
 	return nil
 }
+
+// ForMonth returns the default as a budget limit for month and year.
+// The result has no ID because it is not stored in budget_limits.
+func (b *DefaultBudget) ForMonth(month, year int) *BudgetLimit {
+	return &BudgetLimit{
+		Month:                  month,
+		Year:                   year,
+		Amount:                 b.Amount,
+		NotificationThreshold:  b.NotificationThreshold,
+		NotificationThresholds: []float64{b.NotificationThreshold},
+	}
+}
+
+// BudgetFreeze puts the account in a lockdown month: while it is set, new
+// expenses need a confirmation and every one recorded is notified to the
+// user who froze the budget, FrozenBy
+type BudgetFreeze struct {
+	Reason   string    `json:"reason,omitempty"`
+	FrozenAt time.Time `json:"frozen_at"`
+	FrozenBy int64     `json:"frozen_by"`
+}
+
+// BudgetYearMonth is one month of a BudgetYear. Budget is nil when the month
+// has no budget limit, and then Remaining is nil too.
+type BudgetYearMonth struct {
+	Month     int      `json:"month"`
+	Budget    *float64 `json:"budget"`
+	Spent     float64  `json:"spent"`
+	Remaining *float64 `json:"remaining"`
+}
+
+// BudgetYear is the budget and spending of the twelve months of Year, with
+// the annual totals. TotalBudget only counts the months with a budget.
+type BudgetYear struct {
+	Year        int               `json:"year"`
+	Months      []BudgetYearMonth `json:"months"`
+	TotalBudget float64           `json:"total_budget"`
+	TotalSpent  float64           `json:"total_spent"`
+}
+
+// BudgetLimitChange is one change of a budget limit's amount or notification
+// threshold
+type BudgetLimitChange struct {
+	ID           int64     `json:"id"`
+	BudgetID     int64     `json:"budget_id"`
+	OldAmount    float64   `json:"old_amount"`
+	NewAmount    float64   `json:"new_amount"`
+	OldThreshold float64   `json:"old_threshold"`
+	NewThreshold float64   `json:"new_threshold"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
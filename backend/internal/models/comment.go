@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxCommentLength bounds the text of a comment, matching the database check
+const MaxCommentLength = 2000
+
+var ErrInvalidComment = fmt.Errorf("comment must be between 1 and %d characters", MaxCommentLength)
+
+// Comment is a message in the discussion thread of an actual expense
+type Comment struct {
+	ID              int64 `json:"id"`
+	ActualExpenseID int64 `json:"actual_expense_id"`
+	UserID          int64 `json:"user_id"`
+	// Author is the email of the user who wrote the comment, empty in the
+	// shared workspace
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CommentRequest is the request body for adding and editing a comment
+type CommentRequest struct {
+	Body string `json:"body"`
+}
+
+// Validate trims the comment and checks its length
+func (c *CommentRequest) Validate() error {
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Body == "" || len([]rune(c.Body)) > MaxCommentLength {
+		return ErrInvalidComment
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// DeductibleReceipt is a receipt with deductible items. Every line of the
+// receipt is listed, so it can stand in for the receipt in a tax filing;
+// an expense entered without a receipt number is a receipt of its own.
+type DeductibleReceipt struct {
+	ReceiptNumber int64           `json:"receipt_number"`
+	ReceiptDate   time.Time       `json:"receipt_date"`
+	Source        string          `json:"source"`
+	Items         []ActualExpense `json:"items"`
+	// Total is the whole receipt and Deductible its deductible items
+	Total      float64 `json:"total"`
+	Deductible float64 `json:"deductible"`
+}
+
+// DeductibleReport lists a year of approved deductible expenses by receipt,
+// oldest first, e.g. the donations to claim in a tax return
+type DeductibleReport struct {
+	Year     int                 `json:"year"`
+	Receipts []DeductibleReceipt `json:"receipts"`
+	// Items counts the deductible items and Total adds them up
+	Items int     `json:"items"`
+	Total float64 `json:"total"`
+}
@@ -1,6 +1,23 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+// Field length limits shared by the models, the validation package and the
+// database constraints (see migration 2026-10-16-001). Expected and actual
+// expenses use the same item name limit so any saved receipt item can be
+// promoted to an expected expense.
+const (
+	MaxItemNameLength       = 255
+	MaxSourceLength         = 255
+	MaxExpectedSourceLength = 100
+	MaxItemCodeLength       = 50
+	MaxCategoryLength       = 50
+	MaxTagLength            = 30
+	MaxTagsPerExpense       = 10
+)
 
 // Common validation errors
 var (
@@ -11,15 +28,58 @@ var (
 	ErrInvalidItemName    = errors.New("item name is required")
 	ErrInvalidSource      = errors.New("source is required")
 	ErrInvalidExpenseType = errors.New("expense type must be weekly, monthly, misc, or tax")
-	ErrInvalidItemNameLen = errors.New("item name must not exceed 200 characters")
-	ErrInvalidSourceLen   = errors.New("source must not exceed 100 characters")
-	ErrInvalidItemCodeLen = errors.New("item code must not exceed 50 characters")
-	ErrInvalidExpectedAmt = errors.New("expected amount must be greater than or equal to 0")
-	ErrExpenseNotFound    = errors.New("expense not found")
+	ErrInvalidItemNameLen = ErrItemNameTooLong
+	ErrInvalidSourceLen   = fmt.Errorf(
+		"source must not exceed %d characters",
+		MaxExpectedSourceLength,
+	)
+	ErrInvalidItemCodeLen = fmt.Errorf(
+		"item code must not exceed %d characters",
+		MaxItemCodeLength,
+	)
+	ErrInvalidExpectedAmt  = errors.New("expected amount must be greater than or equal to 0")
+	ErrInvalidCategoryLen  = fmt.Errorf("category must not exceed %d characters", MaxCategoryLength)
+	ErrExpenseNotFound     = errors.New("expense not found")
+	ErrInvalidDueDay       = errors.New("due day must be 1 to 31 for monthly items or 1 (Monday) to 7 (Sunday) for weekly items")
+	ErrInvalidAutoGenerate = errors.New("auto_generate needs a due day or recurrence and an expected amount greater than 0")
 
 	// Actual expense validation errors
-	ErrItemNameRequired = errors.New("item name is required")
-	ErrItemNameTooLong  = errors.New("item name must not exceed 255 characters")
-	ErrSourceRequired   = errors.New("source is required")
-	ErrSourceTooLong    = errors.New("source must not exceed 255 characters")
+	ErrItemNameRequired        = errors.New("item name is required")
+	ErrItemNameTooLong         = fmt.Errorf("item name must not exceed %d characters", MaxItemNameLength)
+	ErrSourceRequired          = errors.New("source is required")
+	ErrSourceTooLong           = fmt.Errorf("source must not exceed %d characters", MaxSourceLength)
+	ErrBulkEmpty               = errors.New("at least one expense is required")
+	ErrExpectedExpenseNotFound = errors.New("expected_expense_id does not reference an existing expected expense")
+	ErrBulkTooLarge            = errors.New("too many expenses in a single request (max 500)")
+	ErrInvalidLineNo           = errors.New("line_no must be 1 or greater")
+	ErrBudgetMonthIncomplete   = errors.New("budget_month and budget_year must be set together")
+	ErrInvalidBudgetMonth      = errors.New("budget_month and budget_year must be the receipt's month or the month before")
+	ErrTooManyTags             = fmt.Errorf("an expense may have at most %d tags", MaxTagsPerExpense)
+	ErrInvalidTag              = fmt.Errorf("tags must not contain commas or exceed %d characters", MaxTagLength)
 )
+
+// Receipt saving validation errors
+var (
+	ErrReceiptsEmpty    = errors.New("at least one receipt is required")
+	ErrReceiptsTooLarge = fmt.Errorf("too many receipts in a single request (max %d)", MaxReceiptsPerRequest)
+)
+
+// Bulk budget validation errors
+var (
+	ErrBulkBudgetsEmpty    = errors.New("at least one budget is required")
+	ErrBulkBudgetsTooLarge = fmt.Errorf("too many budgets in a single request (max %d)", MaxBulkBudgets)
+)
+
+// ErrInvalidThresholds is returned for an empty or too long list of
+// notification thresholds
+var ErrInvalidThresholds = fmt.Errorf(
+	"notification thresholds must list 1 to %d values",
+	MaxNotificationThresholds,
+)
+
+// ErrInvalidWeeklyLimit is returned for a negative weekly cap
+var ErrInvalidWeeklyLimit = errors.New("weekly limit must not be negative")
+
+// ErrMonthClosed is returned when creating, changing or deleting an actual
+// expense of a month whose budget was closed after reconciling it
+var ErrMonthClosed = errors.New("month is closed, reopen its budget to change its expenses")
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 )
@@ -15,6 +16,43 @@ const (
 	ExpenseTypeTax     ExpenseType = "tax"
 )
 
+// NormalizeExpenseType trims surrounding whitespace and lowercases s.
+// It does not check that the result is a known type.
+func NormalizeExpenseType(s string) ExpenseType {
+	return ExpenseType(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// ParseExpenseType normalizes s (any case, surrounding whitespace allowed)
+// and returns ErrInvalidExpenseType if it is not a known expense type
+func ParseExpenseType(s string) (ExpenseType, error) {
+	t := NormalizeExpenseType(s)
+	if !t.IsValid() {
+		return "", ErrInvalidExpenseType
+	}
+	return t, nil
+}
+
+// IsValid reports whether t is one of the known expense types
+func (t ExpenseType) IsValid() bool {
+	switch t {
+	case ExpenseTypeWeekly, ExpenseTypeMonthly, ExpenseTypeMisc, ExpenseTypeTax:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON accepts expense types in any case with surrounding whitespace,
+// e.g. " Weekly " becomes "weekly". Unknown values are kept (normalized) so the
+// request's Validate method can report them with a descriptive error.
+func (t *ExpenseType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = NormalizeExpenseType(s)
+	return nil
+}
+
 // ExpectedExpense represents a planned recurring expense
 type ExpectedExpense struct {
 	ID             int64       `json:"id"`
@@ -22,8 +60,110 @@ type ExpectedExpense struct {
 	Source         string      `json:"source"`
 	ExpectedAmount float64     `json:"expected_amount"`
 	ExpenseType    ExpenseType `json:"expense_type"`
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
+	// Category groups items, e.g. Groceries or Subscriptions; empty when
+	// uncategorized
+	Category string `json:"category,omitempty"`
+	// DueDay is the day of the month a monthly item is due, past the end of
+	// shorter months on their last day, or the ISO day of the week (1 for
+	// Monday to 7 for Sunday) a weekly item is due
+	DueDay *int `json:"due_day,omitempty"`
+	// Recurrence is an RRULE, see ParseRecurrence, for schedules DueDay
+	// cannot express, e.g. every other Friday. It takes precedence over DueDay.
+	Recurrence string `json:"recurrence,omitempty"`
+	// StartsOn is the first day the item may be due, from which recurrence
+	// intervals count. Unset, it is the creation date.
+	StartsOn *time.Time `json:"starts_on,omitempty"`
+	// AutoGenerate creates an actual expense of the expected amount on each
+	// due date, e.g. for rent, see the recurring-expenses job
+	AutoGenerate bool `json:"auto_generate"`
+	// IsActive is unset once the item is archived, e.g. a cancelled
+	// subscription: it keeps its history but no longer counts or comes due
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DueExpense is an expected expense on one of its due dates
+type DueExpense struct {
+	ExpectedExpense
+	DueDate time.Time `json:"due_date"`
+}
+
+// Start returns the first day e may be due
+func (e *ExpectedExpense) Start() time.Time {
+	if e.StartsOn != nil {
+		return dateOf(*e.StartsOn)
+	}
+	return dateOf(e.CreatedAt)
+}
+
+// DueOn reports whether e is due on the date of day. An archived item, or one
+// without a due day or recurrence, is never due.
+func (e *ExpectedExpense) DueOn(day time.Time) bool {
+	if !e.IsActive {
+		return false
+	}
+	if e.Recurrence != "" {
+		rule, err := ParseRecurrence(e.Recurrence)
+		return err == nil && rule.Occurs(day, e.Start())
+	}
+	if e.DueDay == nil || dateOf(day).Before(e.Start()) {
+		return false
+	}
+	switch e.ExpenseType {
+	case ExpenseTypeWeekly:
+		return (int(day.Weekday())+6)%7+1 == *e.DueDay
+	case ExpenseTypeMonthly:
+		return day.Day() == min(*e.DueDay, daysIn(day.Year(), day.Month()))
+	}
+	return false
+}
+
+// DueDates returns the dates e is due on from from through to, both included
+func (e *ExpectedExpense) DueDates(from, to time.Time) []time.Time {
+	var dates []time.Time
+	for day := dateOf(from); !day.After(dateOf(to)); day = day.AddDate(0, 0, 1) {
+		if e.DueOn(day) {
+			dates = append(dates, day)
+		}
+	}
+	return dates
+}
+
+// ValidateSchedule checks the due day against the expense type and parses
+// the recurrence
+func (e *ExpectedExpense) ValidateSchedule() error {
+	if err := validateSchedule(e.ExpenseType, e.DueDay, e.Recurrence); err != nil {
+		return err
+	}
+	return validateAutoGenerate(e.AutoGenerate, e.DueDay, e.Recurrence, e.ExpectedAmount)
+}
+
+// validateAutoGenerate checks that an auto generated item is ever due and
+// generates a valid amount
+func validateAutoGenerate(autoGenerate bool, dueDay *int, recurrence string, amount float64) error {
+	if autoGenerate && ((dueDay == nil && recurrence == "") || amount <= 0) {
+		return ErrInvalidAutoGenerate
+	}
+	return nil
+}
+
+func validateSchedule(expenseType ExpenseType, dueDay *int, recurrence string) error {
+	if dueDay != nil {
+		last := 31
+		if expenseType == ExpenseTypeWeekly {
+			last = 7
+		}
+		if *dueDay < 1 || *dueDay > last {
+			return ErrInvalidDueDay
+		}
+	}
+	if recurrence != "" {
+		if _, err := ParseRecurrence(recurrence); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // CreateExpectedExpenseRequest represents the request body for creating an expected expense
@@ -32,6 +172,11 @@ type CreateExpectedExpenseRequest struct {
 	Source         string      `json:"source"`
 	ExpectedAmount float64     `json:"expected_amount"`
 	ExpenseType    ExpenseType `json:"expense_type"`
+	Category       string      `json:"category,omitempty"`
+	DueDay         *int        `json:"due_day,omitempty"`
+	Recurrence     string      `json:"recurrence,omitempty"`
+	StartsOn       *time.Time  `json:"starts_on,omitempty"`
+	AutoGenerate   bool        `json:"auto_generate,omitempty"`
 }
 
 // UpdateExpectedExpenseRequest represents the request body for updating an expected expense
@@ -40,6 +185,12 @@ type UpdateExpectedExpenseRequest struct {
 	Source         *string      `json:"source,omitempty"`
 	ExpectedAmount *float64     `json:"expected_amount,omitempty"`
 	ExpenseType    *ExpenseType `json:"expense_type,omitempty"`
+	// An empty Category, DueDay 0 and an empty Recurrence clear them
+	Category     *string    `json:"category,omitempty"`
+	DueDay       *int       `json:"due_day,omitempty"`
+	Recurrence   *string    `json:"recurrence,omitempty"`
+	StartsOn     *time.Time `json:"starts_on,omitempty"`
+	AutoGenerate *bool      `json:"auto_generate,omitempty"`
 }
 
 // Validate validates the CreateExpectedExpenseRequest
@@ -48,13 +199,13 @@ func (r *CreateExpectedExpenseRequest) Validate() error {
 	if strings.TrimSpace(r.ItemName) == "" {
 		return ErrInvalidItemName
 	}
-	if len(r.ItemName) > 200 {
+	if len(r.ItemName) > MaxItemNameLength {
 		return ErrInvalidItemNameLen
 	}
 	if strings.TrimSpace(r.Source) == "" {
 		return ErrInvalidSource
 	}
-	if len(r.Source) > 100 {
+	if len(r.Source) > MaxExpectedSourceLength {
 		return ErrInvalidSourceLen
 	}
 	if r.ExpectedAmount < 0 {
@@ -64,7 +215,15 @@ func (r *CreateExpectedExpenseRequest) Validate() error {
 	if r.ExpenseType != ExpenseTypeWeekly && r.ExpenseType != ExpenseTypeMonthly {
 		return ErrInvalidExpenseType
 	}
-	return nil
+	r.Category = strings.TrimSpace(r.Category)
+	if len(r.Category) > MaxCategoryLength {
+		return ErrInvalidCategoryLen
+	}
+	r.Recurrence = normalizeRecurrence(r.Recurrence)
+	if err := validateSchedule(r.ExpenseType, r.DueDay, r.Recurrence); err != nil {
+		return err
+	}
+	return validateAutoGenerate(r.AutoGenerate, r.DueDay, r.Recurrence, r.ExpectedAmount)
 }
 
 // Validate validates the UpdateExpectedExpenseRequest
@@ -74,7 +233,7 @@ func (r *UpdateExpectedExpenseRequest) Validate() error {
 		if strings.TrimSpace(*r.ItemName) == "" {
 			return ErrInvalidItemName
 		}
-		if len(*r.ItemName) > 200 {
+		if len(*r.ItemName) > MaxItemNameLength {
 			return ErrInvalidItemNameLen
 		}
 	}
@@ -82,7 +241,7 @@ func (r *UpdateExpectedExpenseRequest) Validate() error {
 		if strings.TrimSpace(*r.Source) == "" {
 			return ErrInvalidSource
 		}
-		if len(*r.Source) > 100 {
+		if len(*r.Source) > MaxExpectedSourceLength {
 			return ErrInvalidSourceLen
 		}
 	}
@@ -94,5 +253,24 @@ func (r *UpdateExpectedExpenseRequest) Validate() error {
 		*r.ExpenseType != ExpenseTypeMonthly {
 		return ErrInvalidExpenseType
 	}
+	if r.Category != nil {
+		*r.Category = strings.TrimSpace(*r.Category)
+		if len(*r.Category) > MaxCategoryLength {
+			return ErrInvalidCategoryLen
+		}
+	}
+	// The due day is checked against the type once applied, see
+	// ExpectedExpense.ValidateSchedule
+	if r.DueDay != nil && (*r.DueDay < 0 || *r.DueDay > 31) {
+		return ErrInvalidDueDay
+	}
+	if r.Recurrence != nil {
+		*r.Recurrence = normalizeRecurrence(*r.Recurrence)
+		if *r.Recurrence != "" {
+			if _, err := ParseRecurrence(*r.Recurrence); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
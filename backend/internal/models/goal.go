@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Spending goal limits
+const (
+	MaxGoalNameLength = 100
+	MaxGoalMonths     = 60
+)
+
+var (
+	ErrInvalidGoalName = fmt.Errorf(
+		"name is required and must be at most %d characters",
+		MaxGoalNameLength,
+	)
+	ErrInvalidGoalMonths = fmt.Errorf("months must be between 1 and %d", MaxGoalMonths)
+)
+
+// Spending goal statuses
+const (
+	// GoalUpcoming: the first month of the goal has not started
+	GoalUpcoming = "upcoming"
+	// GoalOnTrack: every month so far stayed within the limit
+	GoalOnTrack = "on_track"
+	// GoalBroken: at least one month went over the limit
+	GoalBroken = "broken"
+	// GoalAchieved: every month of the goal ended within the limit
+	GoalAchieved = "achieved"
+)
+
+// SpendingGoal caps the spending per month for a run of months, e.g. "under
+// $600 a month on groceries for 6 months". ExpenseType limits the goal to one
+// expense type; nil counts all spending.
+type SpendingGoal struct {
+	ID           int64        `json:"id"`
+	Name         string       `json:"name"`
+	MonthlyLimit float64      `json:"monthly_limit"`
+	ExpenseType  *ExpenseType `json:"expense_type,omitempty"`
+	StartMonth   int          `json:"start_month"`
+	StartYear    int          `json:"start_year"`
+	Months       int          `json:"months"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// Month returns the month and year of the goal's i-th month, from 0
+func (g *SpendingGoal) Month(i int) (month, year int) {
+	t := time.Date(g.StartYear, time.Month(g.StartMonth)+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+	return int(t.Month()), t.Year()
+}
+
+// SpendingGoalRequest is the request body for creating a spending goal. The
+// goal starts in the current month when start_month and start_year are omitted.
+type SpendingGoalRequest struct {
+	Name         string       `json:"name"`
+	MonthlyLimit float64      `json:"monthly_limit"`
+	ExpenseType  *ExpenseType `json:"expense_type,omitempty"`
+	StartMonth   int          `json:"start_month"`
+	StartYear    int          `json:"start_year"`
+	Months       int          `json:"months"`
+}
+
+// Validate trims the name and validates the request
+func (r *SpendingGoalRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len([]rune(r.Name)) > MaxGoalNameLength {
+		return ErrInvalidGoalName
+	}
+	if r.MonthlyLimit <= 0 {
+		return ErrInvalidAmount
+	}
+	if r.ExpenseType != nil && !r.ExpenseType.IsValid() {
+		return ErrInvalidExpenseType
+	}
+	if r.StartMonth < 1 || r.StartMonth > 12 {
+		return ErrInvalidMonth
+	}
+	if r.StartYear < 2020 || r.StartYear > 2100 {
+		return ErrInvalidYear
+	}
+	if r.Months < 1 || r.Months > MaxGoalMonths {
+		return ErrInvalidGoalMonths
+	}
+	return nil
+}
+
+// GoalMonth is the spending of one month of a goal. A month that has not
+// ended yet is not Final and can still go over the limit.
+type GoalMonth struct {
+	Month     int     `json:"month"`
+	Year      int     `json:"year"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+	Met       bool    `json:"met"`
+	Final     bool    `json:"final"`
+}
+
+// SpendingGoalProgress aggregates a goal's spending over the months that have
+// started. Streak is the number of months within the limit since the goal
+// was last broken, the current month included while it is within the limit.
+type SpendingGoalProgress struct {
+	Goal            SpendingGoal `json:"goal"`
+	Status          string       `json:"status"`
+	Months          []GoalMonth  `json:"months"`
+	MonthsMet       int          `json:"months_met"`
+	MonthsBroken    int          `json:"months_broken"`
+	MonthsRemaining int          `json:"months_remaining"`
+	Streak          int          `json:"streak"`
+	TotalSpent      float64      `json:"total_spent"`
+	AverageSpent    float64      `json:"average_spent"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Notification kinds
+const (
+	NotificationBudgetCreated = "budget_created"
+	NotificationCommentAdded  = "comment_added"
+	NotificationAIUnavailable = "ai_unavailable"
+	NotificationGoalBroken    = "goal_broken"
+	NotificationWeeklyPace    = "weekly_pace"
+	// NotificationBudgetThreshold is sent when spending crosses a budget's
+	// notification threshold, and NotificationBudgetRecovered when it falls
+	// back below
+	NotificationBudgetThreshold = "budget_threshold"
+	NotificationBudgetRecovered = "budget_recovered"
+	// NotificationBudgetDanger is sent daily while spending is at 90% of a
+	// budget or more, and NotificationBudgetOver once when it exceeds it
+	NotificationBudgetDanger = "budget_danger"
+	NotificationBudgetOver   = "budget_over"
+	// NotificationBudgetFrozen is sent for every expense recorded while the
+	// budget is frozen
+	NotificationBudgetFrozen = "budget_frozen"
+	// NotificationExpenseSuggestions is sent when the AI suggested readable
+	// names for expenses entered tersely
+	NotificationExpenseSuggestions = "expense_suggestions"
+	// NotificationExpensesGenerated is sent when actual expenses were
+	// created from expected expenses on their due date
+	NotificationExpensesGenerated = "expenses_generated"
+)
+
+// Notification is a stored message for the user, e.g. from a background job
+type Notification struct {
+	ID        int64      `json:"id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Message   string     `json:"message"`
+	Link      string     `json:"link,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
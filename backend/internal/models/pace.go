@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// WeeklyPace compares the weekly expenses of the current week with the
+// expected weekly amount, prorated to the days of the week elapsed
+type WeeklyPace struct {
+	WeekStart time.Time
+	// Days is the number of days of the week elapsed, today included
+	Days int
+	// Spent is the approved weekly-type spending since WeekStart
+	Spent float64
+	// Expected is the sum of the weekly expected expenses
+	Expected float64
+}
+
+// WeekStart returns the Monday of the week containing t, at midnight UTC
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// Planned returns the share of the expected weekly amount for the days
+// elapsed
+func (p *WeeklyPace) Planned() float64 {
+	return p.Expected * float64(p.Days) / 7
+}
+
+// Ahead reports whether the spending exceeds the planned amount by more than
+// percent. Nothing is ahead of an empty plan.
+func (p *WeeklyPace) Ahead(percent float64) bool {
+	return p.Expected > 0 && p.Spent > p.Planned()*(1+percent/100)
+}
@@ -0,0 +1,40 @@
+package models
+
+import "errors"
+
+// Pivot dimensions, the values of the rows and cols parameters
+const (
+	PivotCategory = "category" // expense type
+	PivotMonth    = "month"
+	PivotSource   = "source" // store
+)
+
+var ErrInvalidPivot = errors.New("rows and cols must be two different dimensions of category, month or source")
+
+// PivotTable is a year of approved spending totalled along two dimensions,
+// laid out for a spreadsheet-like table: Cells[i][j] is the total for
+// RowKeys[i] and ColKeys[j]. Categories and months are always all listed,
+// in order; stores only when they have spending, by name.
+type PivotTable struct {
+	Year      int         `json:"year"`
+	Rows      string      `json:"rows"`
+	Cols      string      `json:"cols"`
+	RowKeys   []string    `json:"row_keys"`
+	ColKeys   []string    `json:"col_keys"`
+	Cells     [][]float64 `json:"cells"`
+	RowTotals []float64   `json:"row_totals"`
+	ColTotals []float64   `json:"col_totals"`
+	Total     float64     `json:"total"`
+}
+
+// ValidatePivot checks the rows and cols dimensions of a pivot table
+func ValidatePivot(rows, cols string) error {
+	if !isPivotDimension(rows) || !isPivotDimension(cols) || rows == cols {
+		return ErrInvalidPivot
+	}
+	return nil
+}
+
+func isPivotDimension(d string) bool {
+	return d == PivotCategory || d == PivotMonth || d == PivotSource
+}
@@ -7,13 +7,35 @@ type ReceiptItem struct {
 	ItemCode  string  `json:"item_code"`
 	ItemPrice float64 `json:"item_price"`
 	ItemName  string  `json:"item_name"`
+	LineNo    int     `json:"line_no"` // 1-based position on the receipt
 }
 
 // ProcessReceiptResponse represents the response for receipt processing
 type ProcessReceiptResponse struct {
 	Success          bool          `json:"success"`
 	Items            []ReceiptItem `json:"items"`
+	ItemCount        int           `json:"item_count"` // item count printed on the receipt, 0 if absent
 	ProcessingTimeMs int64         `json:"processing_time_ms"`
+	ProcessingID     int64         `json:"processing_id,omitempty"` // processing history entry, if recorded
+	// StageTimesMs is how long each stage of the receipt pipeline took
+	StageTimesMs map[string]int64 `json:"stage_times_ms,omitempty"`
+	// Receipts splits Items by receipt, one entry unless the document held
+	// several receipts; each is saved on its own
+	Receipts []ReceiptResult `json:"receipts"`
+	// Degraded is set on a draft returned while the AI provider is down,
+	// when the request allowed it: one receipt with the store guessed from
+	// the file name and no items, for the user to fill in. Error and Code
+	// are those the failure would have been answered with.
+	Degraded bool   `json:"degraded,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+// ReceiptResult is one receipt of a processed document
+type ReceiptResult struct {
+	Source    string        `json:"source"`
+	Items     []ReceiptItem `json:"items"`
+	ItemCount int           `json:"item_count"` // item count printed on the receipt, 0 if absent
 }
 
 // ProcessReceiptError represents an error response for receipt processing
@@ -21,6 +43,8 @@ type ProcessReceiptError struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error"`
 	Code    string `json:"code"`
+	// Stage is the pipeline stage that failed, e.g. extract
+	Stage string `json:"stage,omitempty"`
 }
 
 // Error codes for receipt processing
@@ -31,4 +55,5 @@ const (
 	ErrCodeParseError      = "PARSE_ERROR"
 	ErrCodeAPIError        = "API_ERROR"
 	ErrCodeInternalError   = "INTERNAL_ERROR"
+	ErrCodeQuotaExceeded   = "QUOTA_EXCEEDED"
 )
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// MaxRecurrenceLength bounds the stored recurrence rule
+const MaxRecurrenceLength = 200
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// weekdayCodes are the RRULE codes of the days of the week
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// RecurrenceDay is a BYDAY entry: a day of the week, with an ordinal within
+// the month for monthly and yearly rules, e.g. 1MO for the first Monday or
+// -1FR for the last Friday. An ordinal of 0 is every such day.
+type RecurrenceDay struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// Recurrence is the subset of an iCalendar RRULE (RFC 5545) that bills
+// follow, e.g. FREQ=MONTHLY;BYMONTHDAY=-1 for the last day of every month or
+// FREQ=WEEKLY;INTERVAL=2;BYDAY=FR for every other Friday. Intervals count
+// from a start date, which also gives the days the rule leaves open: a
+// monthly rule without BYMONTHDAY or BYDAY recurs on the start's day.
+type Recurrence struct {
+	Freq       string
+	Interval   int
+	ByDay      []RecurrenceDay
+	ByMonthDay []int
+	ByMonth    []time.Month
+	// Until is the last day the rule may recur on
+	Until *time.Time
+}
+
+// ParseRecurrence parses an RRULE such as "FREQ=MONTHLY;BYMONTHDAY=1",
+// with or without the "RRULE:" prefix. It supports FREQ (WEEKLY, MONTHLY or
+// YEARLY), INTERVAL, BYDAY, BYMONTHDAY, BYMONTH and UNTIL (YYYYMMDD).
+func ParseRecurrence(s string) (*Recurrence, error) {
+	s = normalizeRecurrence(s)
+	if len(s) > MaxRecurrenceLength {
+		return nil, fmt.Errorf("%w: must not exceed %d characters", ErrInvalidRecurrence, MaxRecurrenceLength)
+	}
+
+	r := &Recurrence{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q is not a NAME=VALUE part", ErrInvalidRecurrence, part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s is set twice", ErrInvalidRecurrence, key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			if value != FreqWeekly && value != FreqMonthly && value != FreqYearly {
+				return nil, fmt.Errorf("%w: FREQ must be WEEKLY, MONTHLY or YEARLY", ErrInvalidRecurrence)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 || r.Interval > 99 {
+				return nil, fmt.Errorf("%w: INTERVAL must be between 1 and 99", ErrInvalidRecurrence)
+			}
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := parseRecurrenceDay(code)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(v)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("%w: BYMONTHDAY must be 1 to 31 or -31 to -1", ErrInvalidRecurrence)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				month, err := strconv.Atoi(v)
+				if err != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("%w: BYMONTH must be 1 to 12", ErrInvalidRecurrence)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(month))
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL must be a date as YYYYMMDD", ErrInvalidRecurrence)
+			}
+			r.Until = &until
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if r.Freq == FreqWeekly {
+		if len(r.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("%w: BYMONTHDAY does not apply to WEEKLY", ErrInvalidRecurrence)
+		}
+		for _, day := range r.ByDay {
+			if day.Ordinal != 0 {
+				return nil, fmt.Errorf("%w: WEEKLY days take no ordinal", ErrInvalidRecurrence)
+			}
+		}
+	}
+	return r, nil
+}
+
+// normalizeRecurrence uppercases an RRULE and drops the "RRULE:" prefix
+func normalizeRecurrence(s string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+}
+
+func parseRecurrenceDay(code string) (RecurrenceDay, error) {
+	if len(code) < 2 {
+		return RecurrenceDay{}, fmt.Errorf("%w: unknown day %q", ErrInvalidRecurrence, code)
+	}
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return RecurrenceDay{}, fmt.Errorf("%w: unknown day %q", ErrInvalidRecurrence, code)
+	}
+	day := RecurrenceDay{Weekday: weekday}
+	if ordinal := code[:len(code)-2]; ordinal != "" {
+		n, err := strconv.Atoi(ordinal)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return RecurrenceDay{}, fmt.Errorf("%w: unknown day %q", ErrInvalidRecurrence, code)
+		}
+		day.Ordinal = n
+	}
+	return day, nil
+}
+
+// Occurs reports whether the rule recurs on the date of day, counting
+// intervals from the date of start. It never recurs before start.
+func (r *Recurrence) Occurs(day, start time.Time) bool {
+	day, start = dateOf(day), dateOf(start)
+	if day.Before(start) || (r.Until != nil && day.After(*r.Until)) {
+		return false
+	}
+	if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, day.Month()) {
+		return false
+	}
+
+	switch r.Freq {
+	case FreqWeekly:
+		weeks := int(WeekStart(day).Sub(WeekStart(start)).Hours()/24) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return slices.ContainsFunc(r.ByDay, func(d RecurrenceDay) bool { return d.Weekday == day.Weekday() })
+
+	case FreqMonthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+
+	case FreqYearly:
+		if (day.Year()-start.Year())%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByMonth) == 0 && day.Month() != start.Month() {
+			return false
+		}
+	}
+	return r.occursInMonth(day, start)
+}
+
+// occursInMonth matches the day of the month against BYMONTHDAY and BYDAY,
+// both when both are set, or the day of start when neither is
+func (r *Recurrence) occursInMonth(day, start time.Time) bool {
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		return day.Day() == start.Day()
+	}
+	last := daysIn(day.Year(), day.Month())
+	if len(r.ByMonthDay) > 0 && !slices.ContainsFunc(r.ByMonthDay, func(d int) bool {
+		return d == day.Day() || d < 0 && last+d+1 == day.Day()
+	}) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(d RecurrenceDay) bool {
+		if d.Weekday != day.Weekday() {
+			return false
+		}
+		switch {
+		case d.Ordinal > 0:
+			return (day.Day()-1)/7+1 == d.Ordinal
+		case d.Ordinal < 0:
+			return (last-day.Day())/7+1 == -d.Ordinal
+		}
+		return true
+	}) {
+		return false
+	}
+	return true
+}
+
+// daysIn returns the number of days of month in year
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// dateOf returns the date of t at midnight UTC
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Category rule limits
+const (
+	MaxRuleNameLength = 100
+	MaxRuleTextLength = 100
+)
+
+var (
+	ErrInvalidRuleName = fmt.Errorf(
+		"name is required and must be at most %d characters",
+		MaxRuleNameLength,
+	)
+	ErrInvalidRuleText = fmt.Errorf(
+		"source_contains and item_contains must be at most %d characters",
+		MaxRuleTextLength,
+	)
+	ErrRuleWithoutCondition = errors.New("a rule needs at least one of source_contains, item_contains, min_amount or max_amount")
+	ErrInvalidRuleAmounts   = errors.New("min_amount and max_amount must be positive and min_amount at most max_amount")
+)
+
+// CategoryRule sets the expense type of new expenses that meet all of its
+// conditions, e.g. "source contains Shell" or "amount up to 3 at Starbucks",
+// and with Deductible also marks them deductible. Text conditions ignore case
+// and unset conditions always match.
+type CategoryRule struct {
+	ID             int64       `json:"id"`
+	Name           string      `json:"name"`
+	Priority       int         `json:"priority"`
+	Enabled        bool        `json:"enabled"`
+	SourceContains string      `json:"source_contains,omitempty"`
+	ItemContains   string      `json:"item_contains,omitempty"`
+	MinAmount      *float64    `json:"min_amount,omitempty"`
+	MaxAmount      *float64    `json:"max_amount,omitempty"`
+	ExpenseType    ExpenseType `json:"expense_type"`
+	Deductible     bool        `json:"deductible"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// Matches reports whether an expense meets every condition of the rule
+func (r *CategoryRule) Matches(itemName, source string, amount float64) bool {
+	if r.SourceContains != "" && !containsFold(source, r.SourceContains) {
+		return false
+	}
+	if r.ItemContains != "" && !containsFold(itemName, r.ItemContains) {
+		return false
+	}
+	if r.MinAmount != nil && amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && amount > *r.MaxAmount {
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// MatchRule returns the first of rules that matches the expense, or nil.
+// rules must be in evaluation order, highest priority first.
+func MatchRule(rules []CategoryRule, itemName, source string, amount float64) *CategoryRule {
+	for i := range rules {
+		if rules[i].Matches(itemName, source, amount) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// CategoryRuleRequest is the request body for creating or replacing a rule.
+// The rule is enabled when enabled is omitted.
+type CategoryRuleRequest struct {
+	Name           string      `json:"name"`
+	Priority       int         `json:"priority"`
+	Enabled        *bool       `json:"enabled,omitempty"`
+	SourceContains string      `json:"source_contains"`
+	ItemContains   string      `json:"item_contains"`
+	MinAmount      *float64    `json:"min_amount,omitempty"`
+	MaxAmount      *float64    `json:"max_amount,omitempty"`
+	ExpenseType    ExpenseType `json:"expense_type"`
+	Deductible     bool        `json:"deductible"`
+}
+
+// Validate trims the name and conditions and validates the request
+func (r *CategoryRuleRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.SourceContains = strings.TrimSpace(r.SourceContains)
+	r.ItemContains = strings.TrimSpace(r.ItemContains)
+
+	if r.Name == "" || len([]rune(r.Name)) > MaxRuleNameLength {
+		return ErrInvalidRuleName
+	}
+	if len([]rune(r.SourceContains)) > MaxRuleTextLength || len([]rune(r.ItemContains)) > MaxRuleTextLength {
+		return ErrInvalidRuleText
+	}
+	if r.SourceContains == "" && r.ItemContains == "" && r.MinAmount == nil && r.MaxAmount == nil {
+		return ErrRuleWithoutCondition
+	}
+	if (r.MinAmount != nil && *r.MinAmount <= 0) || (r.MaxAmount != nil && *r.MaxAmount <= 0) ||
+		(r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount) {
+		return ErrInvalidRuleAmounts
+	}
+	if !r.ExpenseType.IsValid() {
+		return ErrInvalidExpenseType
+	}
+	return nil
+}
+
+// IsEnabled returns whether the rule is enabled, true when unset
+func (r *CategoryRuleRequest) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// RuleTestRequest is a sample expense to run the rules against
+type RuleTestRequest struct {
+	ItemName string  `json:"item_name"`
+	Source   string  `json:"source"`
+	Amount   float64 `json:"amount"`
+}
+
+// RuleTestResult is the outcome of a RuleTestRequest. Rule is the rule that
+// would set the expense type, nil when none matches.
+type RuleTestResult struct {
+	Matched     bool          `json:"matched"`
+	Rule        *CategoryRule `json:"rule,omitempty"`
+	ExpenseType ExpenseType   `json:"expense_type,omitempty"`
+	Deductible  bool          `json:"deductible,omitempty"`
+}
+
+// Rule simulation limits
+const (
+	DefaultSimulationMonths = 3
+	MaxSimulationMonths     = 24
+)
+
+var ErrInvalidSimulationMonths = fmt.Errorf("months must be between 1 and %d", MaxSimulationMonths)
+
+// RuleSimulationRequest is the request body for simulating the rules against
+// the expenses of the last Months months, the current one included
+type RuleSimulationRequest struct {
+	Months int `json:"months"`
+}
+
+// Validate defaults and validates the request
+func (r *RuleSimulationRequest) Validate() error {
+	if r.Months == 0 {
+		r.Months = DefaultSimulationMonths
+	}
+	if r.Months < 1 || r.Months > MaxSimulationMonths {
+		return ErrInvalidSimulationMonths
+	}
+	return nil
+}
+
+// RuleSimulation reports how the rules would categorize existing expenses.
+// Every rule is simulated as if it were enabled.
+type RuleSimulation struct {
+	Months    int                  `json:"months"`
+	Checked   int                  `json:"checked"`
+	Changed   int                  `json:"changed"`
+	Changes   []RuleChange         `json:"changes"`
+	Rules     []RuleSimulationStat `json:"rules"`
+	Conflicts []RuleConflict       `json:"conflicts"`
+}
+
+// RuleChange is an expense whose expense type the rules would change
+type RuleChange struct {
+	ExpenseID   int64       `json:"expense_id"`
+	ItemName    string      `json:"item_name"`
+	Source      string      `json:"source"`
+	Amount      float64     `json:"amount"`
+	ReceiptDate time.Time   `json:"receipt_date"`
+	From        ExpenseType `json:"from"`
+	To          ExpenseType `json:"to"`
+	RuleID      int64       `json:"rule_id"`
+}
+
+// RuleSimulationStat counts the expenses one rule matches, the ones it wins
+// because no rule before it matches, and the ones it would change. A rule
+// that matches expenses but never wins is shadowed by the rules before it.
+type RuleSimulationStat struct {
+	RuleID   int64  `json:"rule_id"`
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	Matched  int    `json:"matched"`
+	Applied  int    `json:"applied"`
+	Changed  int    `json:"changed"`
+	Shadowed bool   `json:"shadowed"`
+}
+
+// RuleConflict is a pair of rules that match the same expenses with
+// different expense types, so only the order decides between them. With
+// SamePriority the older rule wins only because it was created first.
+type RuleConflict struct {
+	RuleID       int64       `json:"rule_id"`
+	ExpenseType  ExpenseType `json:"expense_type"`
+	OtherRuleID  int64       `json:"other_rule_id"`
+	OtherType    ExpenseType `json:"other_expense_type"`
+	Expenses     int         `json:"expenses"`
+	SamePriority bool        `json:"same_priority"`
+}
+
+// SimulateRules runs rules, in evaluation order, against expenses.
+// RuleID of a conflict is the rule that wins it.
+func SimulateRules(rules []CategoryRule, expenses []ActualExpense) *RuleSimulation {
+	sim := &RuleSimulation{
+		Checked:   len(expenses),
+		Changes:   []RuleChange{},
+		Rules:     make([]RuleSimulationStat, len(rules)),
+		Conflicts: []RuleConflict{},
+	}
+	for i, rule := range rules {
+		sim.Rules[i] = RuleSimulationStat{RuleID: rule.ID, Name: rule.Name, Enabled: rule.Enabled}
+	}
+
+	conflicts := map[[2]int]int{}
+	for _, e := range expenses {
+		winner := -1
+		for i := range rules {
+			if !rules[i].Matches(e.ItemName, e.Source, e.ActualAmount) {
+				continue
+			}
+			sim.Rules[i].Matched++
+			if winner < 0 {
+				winner = i
+			} else if rules[i].ExpenseType != rules[winner].ExpenseType {
+				conflicts[[2]int{winner, i}]++
+			}
+		}
+		if winner < 0 {
+			continue
+		}
+
+		sim.Rules[winner].Applied++
+		if to := rules[winner].ExpenseType; to != e.ExpenseType {
+			sim.Rules[winner].Changed++
+			sim.Changed++
+			sim.Changes = append(sim.Changes, RuleChange{
+				ExpenseID:   e.ID,
+				ItemName:    e.ItemName,
+				Source:      e.Source,
+				Amount:      e.ActualAmount,
+				ReceiptDate: e.ReceiptDate,
+				From:        e.ExpenseType,
+				To:          to,
+				RuleID:      rules[winner].ID,
+			})
+		}
+	}
+
+	for i := range sim.Rules {
+		sim.Rules[i].Shadowed = sim.Rules[i].Matched > 0 && sim.Rules[i].Applied == 0
+	}
+	for pair, count := range conflicts {
+		winner, loser := rules[pair[0]], rules[pair[1]]
+		sim.Conflicts = append(sim.Conflicts, RuleConflict{
+			RuleID:       winner.ID,
+			ExpenseType:  winner.ExpenseType,
+			OtherRuleID:  loser.ID,
+			OtherType:    loser.ExpenseType,
+			Expenses:     count,
+			SamePriority: winner.Priority == loser.Priority,
+		})
+	}
+	// Most expenses first, then by rule ID
+	sort.Slice(sim.Conflicts, func(i, j int) bool {
+		a, b := sim.Conflicts[i], sim.Conflicts[j]
+		if a.Expenses != b.Expenses {
+			return a.Expenses > b.Expenses
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.OtherRuleID < b.OtherRuleID
+	})
+	return sim
+}
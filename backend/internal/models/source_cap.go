@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SourceCap limits the approved spending per month at one source, e.g.
+// "$400 a month at Costco", every month. Expenses count toward it when their
+// source matches, ignoring case and surrounding spaces.
+type SourceCap struct {
+	ID           int64     `json:"id"`
+	Source       string    `json:"source"`
+	MonthlyLimit float64   `json:"monthly_limit"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Matches reports whether an expense at source counts toward the cap
+func (c *SourceCap) Matches(source string) bool {
+	return strings.EqualFold(strings.TrimSpace(source), c.Source)
+}
+
+// SourceCapRequest is the request body for creating or replacing a source cap
+type SourceCapRequest struct {
+	Source       string  `json:"source"`
+	MonthlyLimit float64 `json:"monthly_limit"`
+}
+
+// Validate trims the source and validates the request
+func (r *SourceCapRequest) Validate() error {
+	r.Source = strings.TrimSpace(r.Source)
+	if r.Source == "" {
+		return ErrSourceRequired
+	}
+	if len(r.Source) > MaxSourceLength {
+		return ErrSourceTooLong
+	}
+	if r.MonthlyLimit <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// SourceCapSpending is a cap's approved spending in one month. Exceeded is
+// set once the spending is over the limit.
+type SourceCapSpending struct {
+	Cap            SourceCap `json:"cap"`
+	Month          int       `json:"month"`
+	Year           int       `json:"year"`
+	Spent          float64   `json:"spent"`
+	Remaining      float64   `json:"remaining"`
+	PercentageUsed float64   `json:"percentage_used"`
+	Exceeded       bool      `json:"exceeded"`
+}
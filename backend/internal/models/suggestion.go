@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+	"unicode"
+)
+
+// Expense suggestion statuses
+const (
+	SuggestionPending   = "pending"
+	SuggestionAccepted  = "accepted"
+	SuggestionDismissed = "dismissed"
+	// SuggestionNone records that the AI could not tell what the expense is,
+	// so it is not asked again
+	SuggestionNone = "none"
+)
+
+// ExpenseSuggestion is a readable name and expense type the AI suggested for
+// an expense entered tersely, e.g. "AMZN MKTP 43.12", to accept in one tap
+type ExpenseSuggestion struct {
+	ActualExpenseID int64 `json:"actual_expense_id"`
+	// CurrentName, Source and ActualAmount are the expense as entered
+	CurrentName  string      `json:"current_name"`
+	Source       string      `json:"source"`
+	ActualAmount float64     `json:"actual_amount"`
+	ItemName     string      `json:"item_name"`
+	ExpenseType  ExpenseType `json:"expense_type"`
+	Status       string      `json:"status"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// IsTerseName reports whether an expense name looks copied from a bank or
+// card statement, e.g. "AMZN MKTP 43.12" or "SQ *BLUE BOTTLE": it has letters
+// and none of them is lower case
+func IsTerseName(name string) bool {
+	hasLetter := false
+	for _, r := range name {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+	}
+	return hasLetter
+}
@@ -0,0 +1,21 @@
+package models
+
+// TaxReportRow is the tax paid at one store in one month
+type TaxReportRow struct {
+	Month  int    `json:"month"`
+	Source string `json:"source"`
+	// Items is the number of tax line items
+	Items int     `json:"items"`
+	Total float64 `json:"total"`
+}
+
+// TaxReport totals a year of approved tax-type line items by month and
+// store, e.g. for the sales tax of a small-business filing
+type TaxReport struct {
+	Year int            `json:"year"`
+	Rows []TaxReportRow `json:"rows"`
+	// MonthTotals holds the tax of January to December
+	MonthTotals []float64 `json:"month_totals"`
+	Items       int       `json:"items"`
+	Total       float64   `json:"total"`
+}
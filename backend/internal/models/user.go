@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Password length limits; the upper bound keeps hashing cheap to reject
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+	MaxEmailLength    = 254
+)
+
+// User validation errors
+var (
+	ErrInvalidEmail    = errors.New("a valid email address is required")
+	ErrInvalidPassword = fmt.Errorf(
+		"password must be between %d and %d characters",
+		MinPasswordLength,
+		MaxPasswordLength,
+	)
+)
+
+// User is an account that can sign in to the API
+type User struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	// ParentID is set for allowance sub-accounts, see Allowance
+	ParentID *int64 `json:"parent_id,omitempty"`
+	// EmailVerified is set once the user followed a verification email,
+	// reset their password or signed in with Google
+	EmailVerified bool `json:"email_verified"`
+	// IsAdmin grants the /api/admin endpoints
+	IsAdmin   bool      `json:"is_admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AdminUser is a user as listed by the admin API
+type AdminUser struct {
+	User
+	ActiveSessions int `json:"active_sessions"`
+}
+
+// UpdateUserRequest is the admin request body for changing a user
+type UpdateUserRequest struct {
+	IsAdmin *bool `json:"is_admin,omitempty"`
+}
+
+// Credentials is the request body for registering and signing in
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Validate normalizes the email and validates the credentials
+func (c *Credentials) Validate() error {
+	email, err := normalizeEmail(c.Email)
+	if err != nil {
+		return err
+	}
+	c.Email = email
+	return validatePassword(c.Password)
+}
+
+// normalizeEmail lowercases and trims email and checks it is a bare address
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || len(email) > MaxEmailLength {
+		return "", ErrInvalidEmail
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// validatePassword checks the password length limits
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// Purposes of the single use tokens sent by email
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// ErrMissingToken is returned when a request lacks the emailed token
+var ErrMissingToken = errors.New("token is required")
+
+// VerifyEmailRequest is the request body for confirming an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// Validate validates the verification request
+func (v *VerifyEmailRequest) Validate() error {
+	if strings.TrimSpace(v.Token) == "" {
+		return ErrMissingToken
+	}
+	return nil
+}
+
+// PasswordResetRequest is the request body for requesting a reset email
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate normalizes the email like Credentials.Validate
+func (p *PasswordResetRequest) Validate() error {
+	email, err := normalizeEmail(p.Email)
+	if err != nil {
+		return err
+	}
+	p.Email = email
+	return nil
+}
+
+// ResetPasswordRequest is the request body for setting a new password with
+// the token from a reset email
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate validates the token and the new password
+func (r *ResetPasswordRequest) Validate() error {
+	if strings.TrimSpace(r.Token) == "" {
+		return ErrMissingToken
+	}
+	return validatePassword(r.Password)
+}
+
+// AuthResponse is returned after registering, signing in or refreshing.
+// Token is the short-lived access token; RefreshToken obtains a new pair
+// from /api/auth/refresh and works once.
+type AuthResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+}
+
+// RefreshRequest is the request body for refreshing and signing out
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Session is a signed-in device, holding one refresh token
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,68 @@
+package models
+
+// VarianceItem compares one expected expense with the approved actual
+// expenses matched to it through expected_expense_id in a month
+type VarianceItem struct {
+	ExpectedExpenseID int64       `json:"expected_expense_id"`
+	ItemName          string      `json:"item_name"`
+	Source            string      `json:"source"`
+	ExpenseType       ExpenseType `json:"expense_type"`
+	// Archived is set for an archived item that still had spending linked
+	// in the month; nothing is expected of it
+	Archived bool `json:"archived,omitempty"`
+	// Expected is the expected amount for the month, weekly items counted
+	// four times as in the monthly expected total
+	Expected     float64 `json:"expected_amount"`
+	Actual       float64 `json:"actual_amount"`
+	MatchedCount int     `json:"matched_count"`
+	// Variance is Actual less Expected, positive when over plan, and
+	// VariancePercent that as a percentage of Expected, null when nothing
+	// was expected
+	Variance        float64  `json:"variance"`
+	VariancePercent *float64 `json:"variance_percent"`
+}
+
+// VarianceReport compares a month's expected expenses with the actual
+// expenses matched to them, item by item
+type VarianceReport struct {
+	Month           int            `json:"month"`
+	Year            int            `json:"year"`
+	Items           []VarianceItem `json:"items"`
+	ExpectedTotal   float64        `json:"expected_total"`
+	ActualTotal     float64        `json:"actual_total"`
+	Variance        float64        `json:"variance"`
+	VariancePercent *float64       `json:"variance_percent"`
+	// UnmatchedTotal is the month's approved spending not matched to any
+	// expected expense, left out of the totals above
+	UnmatchedTotal float64 `json:"unmatched_total"`
+}
+
+// MonthlyExpected returns the expected amount of an expense for a month:
+// four times a weekly amount, other amounts as they are
+func MonthlyExpected(expenseType ExpenseType, amount float64) float64 {
+	if expenseType == ExpenseTypeWeekly {
+		return amount * 4
+	}
+	return amount
+}
+
+// variance returns actual less expected and that as a percentage of
+// expected, nil when expected is 0
+func variance(expected, actual float64) (float64, *float64) {
+	diff := actual - expected
+	if expected == 0 {
+		return diff, nil
+	}
+	percent := diff / expected * 100
+	return diff, &percent
+}
+
+// SetVariance fills in the variance of the item
+func (i *VarianceItem) SetVariance() {
+	i.Variance, i.VariancePercent = variance(i.Expected, i.Actual)
+}
+
+// SetVariance fills in the variance of the report's totals
+func (r *VarianceReport) SetVariance() {
+	r.Variance, r.VariancePercent = variance(r.ExpectedTotal, r.ActualTotal)
+}
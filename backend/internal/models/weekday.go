@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WeekdaySpending is the approved spending on one day of the week, or on the
+// weekdays or the weekend together, over a range of receipt dates
+type WeekdaySpending struct {
+	Weekday string `json:"weekday,omitempty"`
+	// Days is the number of such days in the range, spent on or not
+	Days  int     `json:"days"`
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+	// Average is Total per day, so the five weekdays compare with the
+	// two days of the weekend
+	Average float64 `json:"average"`
+	// Share is the part of the range's total, from 0 to 1
+	Share float64 `json:"share"`
+}
+
+// WeekdayReport breaks down the spending between From and To, both included,
+// by day of the week, Monday first, and into weekdays and weekend
+type WeekdayReport struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Type     ExpenseType       `json:"type,omitempty"`
+	Days     []WeekdaySpending `json:"days"`
+	Weekdays WeekdaySpending   `json:"weekdays"`
+	Weekend  WeekdaySpending   `json:"weekend"`
+	Total    float64           `json:"total"`
+}
+
+// IsWeekend reports whether d is Saturday or Sunday
+func IsWeekend(d time.Weekday) bool {
+	return d == time.Saturday || d == time.Sunday
+}
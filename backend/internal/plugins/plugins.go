@@ -0,0 +1,200 @@
+// Package plugins lets integrations extend the server without changing its
+// handlers. A plugin is a Go package that registers itself from an init
+// function, like a database/sql driver:
+//
+//	func init() {
+//		plugins.RegisterExpenseProcessor("bank-payees", payeeCleaner{})
+//	}
+//
+// Plugins live in the module, under backend/plugins, since they import this
+// internal package, and are compiled in with a blank import in
+// cmd/server/plugins.go. Put the import in a file of its own with a build tag
+// to make the plugin optional.
+package plugins
+
+import (
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExpenseProcessor changes or rejects expenses before they are stored
+type ExpenseProcessor interface {
+	// ProcessExpense is called with every expense created through the API,
+	// including bulk creates and imports, before it is validated. It may
+	// change req, e.g. to clean up a bank's payee names; an error rejects the
+	// expense.
+	ProcessExpense(ctx context.Context, req *models.CreateActualExpenseRequest) error
+}
+
+// NotificationChannel delivers notifications outside the app, e.g. to a chat
+// service
+type NotificationChannel interface {
+	// Send is called with every stored notification. Errors are logged and
+	// do not affect the notification.
+	Send(ctx context.Context, n *models.Notification) error
+}
+
+// EventHandler reacts to the changes the server publishes, e.g. to call a
+// webhook when a budget is updated
+type EventHandler interface {
+	// HandleEvent is called with every published event. Errors are logged
+	// and do not affect the change.
+	HandleEvent(ctx context.Context, e events.Event) error
+}
+
+// sendTimeout bounds the delivery of one notification or event by one plugin
+const sendTimeout = 30 * time.Second
+
+type namedProcessor struct {
+	name string
+	ExpenseProcessor
+}
+
+type namedChannel struct {
+	name string
+	NotificationChannel
+}
+
+type namedEventHandler struct {
+	name string
+	EventHandler
+}
+
+var (
+	mu            sync.RWMutex
+	names         = map[string]bool{}
+	processors    []namedProcessor
+	channels      []namedChannel
+	eventHandlers []namedEventHandler
+)
+
+// register claims name, panicking on a duplicate like database/sql.Register
+func register(name string, plugin any) {
+	if plugin == nil {
+		panic("plugins: register " + name + " is nil")
+	}
+	if names[name] {
+		panic("plugins: register called twice for " + name)
+	}
+	names[name] = true
+}
+
+// RegisterExpenseProcessor adds an expense processor; processors run in the
+// order they are registered. It panics when name is already registered.
+func RegisterExpenseProcessor(name string, p ExpenseProcessor) {
+	mu.Lock()
+	defer mu.Unlock()
+	register(name, p)
+	processors = append(processors, namedProcessor{name, p})
+}
+
+// RegisterNotificationChannel adds a notification channel. It panics when
+// name is already registered.
+func RegisterNotificationChannel(name string, c NotificationChannel) {
+	mu.Lock()
+	defer mu.Unlock()
+	register(name, c)
+	channels = append(channels, namedChannel{name, c})
+}
+
+// RegisterEventHandler adds an event handler. It panics when name is already
+// registered.
+func RegisterEventHandler(name string, h EventHandler) {
+	mu.Lock()
+	defer mu.Unlock()
+	register(name, h)
+	eventHandlers = append(eventHandlers, namedEventHandler{name, h})
+}
+
+// Names returns the names of the registered plugins, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// HasNotificationChannels reports whether a notification channel is
+// registered
+func HasNotificationChannels() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(channels) > 0
+}
+
+// HasEventHandlers reports whether an event handler is registered
+func HasEventHandlers() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(eventHandlers) > 0
+}
+
+// ProcessExpense runs the expense processors on req and returns the first
+// error, naming the processor that returned it
+func ProcessExpense(ctx context.Context, req *models.CreateActualExpenseRequest) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, p := range processors {
+		if err := p.ProcessExpense(ctx, req); err != nil {
+			return fmt.Errorf("%s: %w", p.name, err)
+		}
+	}
+	return nil
+}
+
+// ProcessExpenses runs the expense processors on each of reqs
+func ProcessExpenses(ctx context.Context, reqs []models.CreateActualExpenseRequest) error {
+	for i := range reqs {
+		if err := ProcessExpense(ctx, &reqs[i]); err != nil {
+			return fmt.Errorf("expense %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Notify sends n through every notification channel in turn, logging the
+// failures
+func Notify(ctx context.Context, n *models.Notification) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, c := range channels {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := c.Send(sendCtx, n); err != nil {
+			log.Printf("[Plugins] %s failed to send notification %d: %v", c.name, n.ID, err)
+		}
+		cancel()
+	}
+}
+
+// HandleEvent passes e to every event handler in turn, logging the failures
+func HandleEvent(ctx context.Context, e events.Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, h := range eventHandlers {
+		handleCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := h.HandleEvent(handleCtx, e); err != nil {
+			log.Printf("[Plugins] %s failed to handle %s: %v", h.name, e.Type, err)
+		}
+		cancel()
+	}
+}
+
+// reset unregisters every plugin, for tests
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	names = map[string]bool{}
+	processors = nil
+	channels = nil
+	eventHandlers = nil
+}
@@ -0,0 +1,120 @@
+package plugins
+
+import (
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// upperSource uppercases the source of every expense
+type upperSource struct{}
+
+func (upperSource) ProcessExpense(_ context.Context, req *models.CreateActualExpenseRequest) error {
+	req.Source = strings.ToUpper(req.Source)
+	return nil
+}
+
+// rejectFree rejects expenses without an amount
+type rejectFree struct{}
+
+func (rejectFree) ProcessExpense(_ context.Context, req *models.CreateActualExpenseRequest) error {
+	if req.ActualAmount == 0 {
+		return errors.New("free items are not expenses")
+	}
+	return nil
+}
+
+// recordingChannel records the notifications sent and fails with err
+type recordingChannel struct {
+	sent []string
+	err  error
+}
+
+func (c *recordingChannel) Send(_ context.Context, n *models.Notification) error {
+	c.sent = append(c.sent, n.Title)
+	return c.err
+}
+
+// recordingHandler records the events handled and fails with err
+type recordingHandler struct {
+	handled []events.Type
+	err     error
+}
+
+func (h *recordingHandler) HandleEvent(_ context.Context, e events.Event) error {
+	h.handled = append(h.handled, e.Type)
+	return h.err
+}
+
+func TestExpenseProcessors(t *testing.T) {
+	t.Cleanup(reset)
+	RegisterExpenseProcessor("upper-source", upperSource{})
+	RegisterExpenseProcessor("reject-free", rejectFree{})
+
+	reqs := []models.CreateActualExpenseRequest{{Source: "costco", ActualAmount: 10}}
+	if err := ProcessExpenses(context.Background(), reqs); err != nil {
+		t.Fatalf("ProcessExpenses failed: %v", err)
+	}
+	if reqs[0].Source != "COSTCO" {
+		t.Errorf("Expected the source to be processed, got %q", reqs[0].Source)
+	}
+
+	reqs = append(reqs, models.CreateActualExpenseRequest{Source: "sample"})
+	err := ProcessExpenses(context.Background(), reqs)
+	if err == nil || !strings.Contains(err.Error(), "expense 1: reject-free") {
+		t.Errorf("Expected the second expense to be rejected by reject-free, got %v", err)
+	}
+
+	if names := Names(); len(names) != 2 || names[0] != "reject-free" {
+		t.Errorf("Unexpected plugin names %v", names)
+	}
+	if HasNotificationChannels() {
+		t.Error("Expected no notification channels")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Cleanup(reset)
+	failing := &recordingChannel{err: errors.New("webhook down")}
+	working := &recordingChannel{}
+	RegisterNotificationChannel("failing", failing)
+	RegisterNotificationChannel("working", working)
+
+	// A failing channel does not stop the others
+	Notify(context.Background(), &models.Notification{Title: "Budget created"})
+	if len(failing.sent) != 1 || len(working.sent) != 1 || working.sent[0] != "Budget created" {
+		t.Errorf("Expected both channels to be called, got %v and %v", failing.sent, working.sent)
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	t.Cleanup(reset)
+	if HasEventHandlers() {
+		t.Fatal("Expected no event handlers")
+	}
+	failing := &recordingHandler{err: errors.New("webhook down")}
+	working := &recordingHandler{}
+	RegisterEventHandler("failing", failing)
+	RegisterEventHandler("working", working)
+
+	// A failing handler does not stop the others
+	HandleEvent(context.Background(), events.Event{Type: events.BudgetUpdated})
+	if len(failing.handled) != 1 || len(working.handled) != 1 || working.handled[0] != events.BudgetUpdated {
+		t.Errorf("Expected both handlers to be called, got %v and %v", failing.handled, working.handled)
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	t.Cleanup(reset)
+	RegisterExpenseProcessor("upper-source", upperSource{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	RegisterNotificationChannel("upper-source", &recordingChannel{})
+}
@@ -1,161 +1,542 @@
 package repository
 
 import (
+	"budget-tracker/internal/events"
 	"budget-tracker/internal/models"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
+var (
+	ErrNotPendingApproval = errors.New("expense is not pending approval")
+	ErrSelfApproval       = errors.New("expenses cannot be approved by their owner")
+)
+
+// ActualExpenseRepository handles actual_expenses database operations.
+// It only sees the expenses of one user, see ForUser.
 type ActualExpenseRepository struct {
-	db *DB
+	db     *DB
+	userID int64
+	events *events.Bus
 }
 
 func NewActualExpenseRepository(db *DB) *ActualExpenseRepository {
 	return &ActualExpenseRepository{db: db}
 }
 
+// ForUser returns a copy of the repository that reads and writes userID's expenses
+func (r *ActualExpenseRepository) ForUser(userID int64) *ActualExpenseRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// PublishTo sets the bus the repository publishes the creation, changes and
+// deletion of expenses to, once they are stored
+func (r *ActualExpenseRepository) PublishTo(bus *events.Bus) {
+	r.events = bus
+}
+
+// publish sends an event about expense to the bus, if any
+func (r *ActualExpenseRepository) publish(typ events.Type, expense *models.ActualExpense) {
+	r.events.Publish(events.Event{Type: typ, UserID: r.userID, Expense: expense})
+}
+
 func (r *ActualExpenseRepository) Create(
 	req *models.CreateActualExpenseRequest,
 ) (*models.ActualExpense, error) {
-	receiptDate := time.Now()
-	if req.ReceiptDate != nil {
-		receiptDate = *req.ReceiptDate
+	id, err := insertActualExpense(r.db, r.userID, req)
+	if err != nil {
+		return nil, err
 	}
-	month := int(receiptDate.Month())
-	year := receiptDate.Year()
 
-	result, err := r.db.Exec(`
-		INSERT INTO actual_expenses (item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, month, year)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, req.ItemName, req.Source, req.ActualAmount, req.ExpenseType, req.ItemCode, req.ExpectedExpenseID, receiptDate, req.ReceiptNumber, month, year)
+	created, err := r.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	r.publish(events.ExpenseCreated, created)
+	return created, nil
+}
 
-	id, err := result.LastInsertId()
+// CreateBulk inserts all expenses in a single transaction; either every row
+// is created or none are
+func (r *ActualExpenseRepository) CreateBulk(
+	reqs []models.CreateActualExpenseRequest,
+) ([]models.ActualExpense, error) {
+	return r.createBulk(reqs, false)
+}
+
+// CreateReceipt creates the expenses of one receipt in a single transaction.
+// The ones without a receipt number get the next free one, so each receipt
+// of a document is numbered on its own.
+func (r *ActualExpenseRepository) CreateReceipt(
+	reqs []models.CreateActualExpenseRequest,
+) ([]models.ActualExpense, error) {
+	return r.createBulk(reqs, true)
+}
+
+func (r *ActualExpenseRepository) createBulk(
+	reqs []models.CreateActualExpenseRequest,
+	number bool,
+) ([]models.ActualExpense, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	if number {
+		receiptNumber, err := nextReceiptNumber(tx, r.userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to number receipt: %w", err)
+		}
+		for i := range reqs {
+			if reqs[i].ReceiptNumber == 0 {
+				reqs[i].ReceiptNumber = receiptNumber
+			}
+		}
+	}
+
+	ids := make([]int64, 0, len(reqs))
+	for i := range reqs {
+		id, err := insertActualExpense(tx, r.userID, &reqs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create expense %d: %w", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	expenses := make([]models.ActualExpense, 0, len(ids))
+	for _, id := range ids {
+		expense, err := r.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, *expense)
+	}
+	for i := range expenses {
+		r.publish(events.ExpenseCreated, &expenses[i])
+	}
 
-	return r.GetByID(id)
+	return expenses, nil
 }
 
-func (r *ActualExpenseRepository) GetByID(id int64) (*models.ActualExpense, error) {
-	var expense models.ActualExpense
-	var itemCode sql.NullString
-	var expectedExpenseID sql.NullInt64
+// querier is satisfied by both *DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
 
-	err := r.db.QueryRow(`
-		SELECT id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, month, year, created_at, updated_at
-		FROM actual_expenses WHERE id = ?
-	`, id).Scan(
-		&expense.ID, &expense.ItemName, &expense.Source, &expense.ActualAmount,
-		&expense.ExpenseType, &itemCode, &expectedExpenseID, &expense.ReceiptDate,
-		&expense.ReceiptNumber, &expense.Month, &expense.Year, &expense.CreatedAt, &expense.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, models.ErrExpenseNotFound
+// checkExpectedExpenseRef returns models.ErrExpectedExpenseNotFound when id is
+// set but does not reference an existing expected expense of userID
+func checkExpectedExpenseRef(db querier, userID int64, id *int64) error {
+	if id == nil {
+		return nil
+	}
+
+	var exists bool
+	if err := db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM expected_expenses WHERE id = ? AND user_id = ?)`, *id, userID,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %d", models.ErrExpectedExpenseNotFound, *id)
+	}
+	return nil
+}
+
+// checkMonthOpen returns models.ErrMonthClosed when userID closed the budget
+// of month/year
+func checkMonthOpen(db querier, userID int64, month, year int) error {
+	var closed bool
+	if err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM budget_limits
+			WHERE user_id = ? AND month = ? AND year = ? AND closed_at IS NOT NULL
+		)
+	`, userID, month, year).Scan(&closed); err != nil {
+		return fmt.Errorf("failed to check closed month: %w", err)
 	}
+	if closed {
+		return fmt.Errorf("%w: %04d-%02d", models.ErrMonthClosed, year, month)
+	}
+	return nil
+}
+
+// insertActualExpense stores req as an expense of userID on db, which may be
+// a transaction, and audits it. Callers publish the expense once committed.
+func insertActualExpense(
+	db querier,
+	userID int64,
+	req *models.CreateActualExpenseRequest,
+) (int64, error) {
+	if err := checkExpectedExpenseRef(db, userID, req.ExpectedExpenseID); err != nil {
+		return 0, err
+	}
+
+	if err := applyCategoryRules(db, userID, req); err != nil {
+		return 0, err
+	}
+
+	pending, err := needsApproval(db, userID, req.ActualAmount)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	receiptDate := req.EffectiveReceiptDate()
+	month, year, assigned := req.BudgetPeriod(receiptDate)
+	if err := checkMonthOpen(db, userID, month, year); err != nil {
+		return 0, err
+	}
+
+	itemName, source, err := sealNameAndSource(req.ItemName, req.Source)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO actual_expenses (user_id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, line_no, month, year, month_assigned, pending_approval, deductible, auto_generated, tags)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, itemName, source, req.ActualAmount, req.ExpenseType, req.ItemCode, req.ExpectedExpenseID, receiptDate, req.ReceiptNumber, req.LineNo, month, year, assigned, pending, req.Deductible, req.AutoGenerated, joinTags(req.Tags))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	created, err := getActualExpense(db, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordAudit(db, userID, models.AuditEntityActualExpense, id, models.AuditCreate, nil, created); err != nil {
+		return 0, err
 	}
+	return id, nil
+}
 
-	if itemCode.Valid {
-		expense.ItemCode = &itemCode.String
+// needsApproval reports whether an expense of amount by userID goes to pending
+// approval under the approval rule setting. Allowance sub-accounts cannot
+// approve expenses, so their allowance is their only limit.
+func needsApproval(db querier, userID int64, amount float64) (bool, error) {
+	var subAccount bool
+	if err := db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM allowance_accounts WHERE user_id = ?)`, userID,
+	).Scan(&subAccount); err != nil {
+		return false, fmt.Errorf("failed to check allowance account: %w", err)
+	}
+	if subAccount {
+		return false, nil
 	}
-	if expectedExpenseID.Valid {
-		expense.ExpectedExpenseID = &expectedExpenseID.Int64
+
+	var value string
+	err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, SettingApprovalRule).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get approval rule: %w", err)
 	}
 
-	return &expense, nil
+	var rule models.ApprovalRule
+	if err := json.Unmarshal([]byte(value), &rule); err != nil {
+		return false, fmt.Errorf("failed to decode approval rule: %w", err)
+	}
+	return rule.Amount > 0 && amount > rule.Amount, nil
 }
 
-func (r *ActualExpenseRepository) GetAll() ([]models.ActualExpense, error) {
-	rows, err := r.db.Query(`
-		SELECT id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, month, year, created_at, updated_at
-		FROM actual_expenses ORDER BY receipt_date DESC, created_at DESC
-	`)
+// CategoryRules returns the scoped user's enabled category rules in
+// evaluation order. New expenses get the expense type of the first one they
+// match.
+func (r *ActualExpenseRepository) CategoryRules() ([]models.CategoryRule, error) {
+	return listRules(r.db, r.userID, true)
+}
+
+func (r *ActualExpenseRepository) GetByID(id int64) (*models.ActualExpense, error) {
+	return getActualExpense(r.db, r.userID, id)
+}
+
+// getActualExpense reads expense id of userID on db, which may be a transaction
+func getActualExpense(db querier, userID, id int64) (*models.ActualExpense, error) {
+	rows, err := db.Query(`
+		SELECT `+actualExpenseColumns+`
+		FROM actual_expenses WHERE id = ? AND user_id = ?
+	`, id, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return r.scanRows(rows)
+	expenses, err := scanActualExpenses(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(expenses) == 0 {
+		return nil, models.ErrExpenseNotFound
+	}
+	return &expenses[0], nil
+}
+
+// actualExpenseColumns is the column list scanned by scanActualExpenses
+const actualExpenseColumns = `id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, line_no, month, year, month_assigned, created_at, updated_at, pending_approval, approved_at, approved_by, deductible, auto_generated, tags`
+
+// actualExpenseQuery builds a filtered query over actual_expenses.
+// Only the columns listed here may be filtered on. Items of the same receipt
+// are kept in their printed order.
+func actualExpenseQuery(filter ExpenseFilter) *selectBuilder {
+	b := newSelectBuilder(
+		"actual_expenses",
+		actualExpenseColumns,
+		"expense_type", "month", "year", "receipt_date", "receipt_number",
+		"item_name", "source", "item_code", "user_id", "pending_approval", "deductible",
+		"auto_generated", "tags",
+	).order("receipt_date DESC, receipt_number DESC, line_no, id")
+
+	if filter.Type != "" {
+		b.where("expense_type", "=", filter.Type)
+	}
+	if filter.Month != 0 {
+		b.where("month", "=", filter.Month)
+	}
+	if filter.Year != 0 {
+		b.where("year", "=", filter.Year)
+	}
+	if filter.ReceiptNumber != 0 {
+		b.where("receipt_number", "=", filter.ReceiptNumber)
+	}
+	if filter.From != nil {
+		b.where("receipt_date", ">=", *filter.From)
+	}
+	if filter.To != nil {
+		// Inclusive upper bound: everything before the start of the next day
+		b.where("receipt_date", "<", filter.To.AddDate(0, 0, 1))
+	}
+	if filter.Search != "" {
+		b.whereAny(
+			[]string{"item_name", "source", "item_code"},
+			"LIKE",
+			likePattern(filter.Search),
+		)
+	}
+	if filter.Pending != nil {
+		b.where("pending_approval", "=", *filter.Pending)
+	}
+	if filter.Deductible != nil {
+		b.where("deductible", "=", *filter.Deductible)
+	}
+	if filter.AutoGenerated != nil {
+		b.where("auto_generated", "=", *filter.AutoGenerated)
+	}
+	for _, tag := range filter.Tags {
+		b.where("tags", "LIKE", "%"+likeEscape(joinTags([]string{tag}))+"%")
+	}
+	return b.page(filter.Limit, filter.Offset)
 }
 
-func (r *ActualExpenseRepository) GetByMonthYear(month, year int) ([]models.ActualExpense, error) {
-	rows, err := r.db.Query(`
-		SELECT id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, month, year, created_at, updated_at
-		FROM actual_expenses WHERE month = ? AND year = ? ORDER BY receipt_date DESC, created_at DESC
-	`, month, year)
+// query scopes actualExpenseQuery to the repository's user
+func (r *ActualExpenseRepository) query(filter ExpenseFilter) *selectBuilder {
+	return actualExpenseQuery(filter).where("user_id", "=", r.userID)
+}
+
+// List returns the actual expenses matching filter, newest receipt first
+func (r *ActualExpenseRepository) List(filter ExpenseFilter) ([]models.ActualExpense, error) {
+	if filter.Search != "" && encryptingFields() {
+		if err := checkPage(filter.Limit, filter.Offset); err != nil {
+			return nil, err
+		}
+		matches, err := r.searchDecrypted(filter)
+		if err != nil {
+			return nil, err
+		}
+		return pageSlice(matches, filter.Limit, filter.Offset), nil
+	}
+
+	query, args, err := r.query(filter).build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return r.scanRows(rows)
+	return scanActualExpenses(rows)
 }
 
-func (r *ActualExpenseRepository) GetByType(
-	expenseType models.ExpenseType,
-) ([]models.ActualExpense, error) {
-	rows, err := r.db.Query(`
-		SELECT id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, month, year, created_at, updated_at
-		FROM actual_expenses WHERE expense_type = ? ORDER BY receipt_date DESC, created_at DESC
-	`, expenseType)
+// Count returns the number of actual expenses matching filter, ignoring paging
+func (r *ActualExpenseRepository) Count(filter ExpenseFilter) (int, error) {
+	if filter.Search != "" && encryptingFields() {
+		matches, err := r.searchDecrypted(filter)
+		return len(matches), err
+	}
+
+	query, args, err := r.query(filter).buildCount()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// searchDecrypted applies filter.Search after decrypting, since LIKE cannot
+// see into encrypted columns. It reads every expense matching the other
+// filters and ignores paging.
+func (r *ActualExpenseRepository) searchDecrypted(filter ExpenseFilter) ([]models.ActualExpense, error) {
+	search := filter.Search
+	filter.Search, filter.Limit, filter.Offset = "", 0, 0
+	expenses, err := r.List(filter)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	return r.scanRows(rows)
+	var matches []models.ActualExpense
+	for _, e := range expenses {
+		if containsFold(e.ItemName, search) || containsFold(e.Source, search) ||
+			(e.ItemCode != nil && containsFold(*e.ItemCode, search)) {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
+
+// IncludeExpectedExpenses sets ExpectedExpense on the expenses linked to one.
+// The linked expected expenses are loaded together (see
+// ExpectedExpenseRepository.GetByIDs) instead of with a query per expense.
+func (r *ActualExpenseRepository) IncludeExpectedExpenses(expenses []models.ActualExpense) error {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, e := range expenses {
+		if e.ExpectedExpenseID != nil && !seen[*e.ExpectedExpenseID] {
+			seen[*e.ExpectedExpenseID] = true
+			ids = append(ids, *e.ExpectedExpenseID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	linked, err := NewExpectedExpenseRepository(r.db).ForUser(r.userID).GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+	for i := range expenses {
+		if id := expenses[i].ExpectedExpenseID; id != nil {
+			if expected, ok := linked[*id]; ok {
+				expenses[i].ExpectedExpense = &expected
+			}
+		}
+	}
+	return nil
+}
+
+func (r *ActualExpenseRepository) GetAll() ([]models.ActualExpense, error) {
+	return r.List(ExpenseFilter{})
+}
+
+func (r *ActualExpenseRepository) GetByMonthYear(month, year int) ([]models.ActualExpense, error) {
+	return r.List(ExpenseFilter{Month: month, Year: year})
+}
+
+func (r *ActualExpenseRepository) GetByType(
+	expenseType models.ExpenseType,
+) ([]models.ActualExpense, error) {
+	return r.List(ExpenseFilter{Type: expenseType})
 }
 
 func (r *ActualExpenseRepository) GetByTypeAndMonthYear(
 	expenseType models.ExpenseType,
 	month, year int,
 ) ([]models.ActualExpense, error) {
-	rows, err := r.db.Query(`
-		SELECT id, item_name, source, actual_amount, expense_type, item_code, expected_expense_id, receipt_date, receipt_number, month, year, created_at, updated_at
-		FROM actual_expenses WHERE expense_type = ? AND month = ? AND year = ? ORDER BY receipt_date DESC, created_at DESC
-	`, expenseType, month, year)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	return r.scanRows(rows)
+	return r.List(ExpenseFilter{Type: expenseType, Month: month, Year: year})
 }
 
 func (r *ActualExpenseRepository) GetMonthlyTotal(month, year int) (float64, error) {
 	var total sql.NullFloat64
 	err := r.db.QueryRow(`
-		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses WHERE month = ? AND year = ?
-	`, month, year).Scan(&total)
+		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses
+		WHERE user_id = ? AND month = ? AND year = ? AND pending_approval = 0
+	`, r.userID, month, year).Scan(&total)
 	if err != nil {
 		return 0, err
 	}
 	return total.Float64, nil
 }
 
+// GetWeekTotal sums the scoped user's spending of the week starting at
+// weekStart, by receipt date. Expenses held for approval are not counted.
+func (r *ActualExpenseRepository) GetWeekTotal(weekStart time.Time) (float64, error) {
+	var total float64
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses
+		WHERE user_id = ? AND pending_approval = 0
+			AND receipt_date >= ? AND receipt_date < ?
+	`, r.userID, weekStart, weekStart.AddDate(0, 0, 7)).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to sum weekly spending: %w", err)
+	}
+	return total, nil
+}
+
+// GetMonthlySummary totals a month's approved actual expenses per expense type.
+// Grouping lets SQLite answer from the (user_id, year, month, pending_approval,
+// expense_type, actual_amount) index alone, see BenchmarkGetMonthlySummary.
 func (r *ActualExpenseRepository) GetMonthlySummary(
 	month, year int,
 ) (*models.ActualExpenseSummary, error) {
 	summary := &models.ActualExpenseSummary{Month: month, Year: year}
 
-	err := r.db.QueryRow(`
-		SELECT 
-			COALESCE(SUM(CASE WHEN expense_type = 'weekly' THEN actual_amount ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN expense_type = 'monthly' THEN actual_amount ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN expense_type = 'misc' THEN actual_amount ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN expense_type = 'tax' THEN actual_amount ELSE 0 END), 0),
-			COALESCE(SUM(actual_amount), 0)
-		FROM actual_expenses WHERE month = ? AND year = ?
-	`, month, year).Scan(&summary.TotalWeekly, &summary.TotalMonthly, &summary.TotalMisc, &summary.TotalTax, &summary.TotalActual)
+	rows, err := r.db.Query(`
+		SELECT expense_type, SUM(actual_amount)
+		FROM actual_expenses
+		WHERE user_id = ? AND year = ? AND month = ? AND pending_approval = 0
+		GROUP BY expense_type
+	`, r.userID, year, month)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var expenseType string
+		var total float64
+		if err := rows.Scan(&expenseType, &total); err != nil {
+			return nil, err
+		}
+		switch models.ExpenseType(expenseType) {
+		case models.ExpenseTypeWeekly:
+			summary.TotalWeekly = total
+		case models.ExpenseTypeMonthly:
+			summary.TotalMonthly = total
+		case models.ExpenseTypeMisc:
+			summary.TotalMisc = total
+		case models.ExpenseTypeTax:
+			summary.TotalTax = total
+		}
+		summary.TotalActual += total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	if summary.Allowances, err = allowanceStatuses(r.db, "parent_id", r.userID, month, year); err != nil {
+		return nil, err
+	}
 	return summary, nil
 }
 
@@ -167,6 +548,10 @@ func (r *ActualExpenseRepository) Update(
 	if err != nil {
 		return nil, err
 	}
+	if err := checkMonthOpen(r.db, r.userID, existing.Month, existing.Year); err != nil {
+		return nil, err
+	}
+	before := *existing
 
 	if req.ItemName != nil {
 		existing.ItemName = *req.ItemName
@@ -174,7 +559,18 @@ func (r *ActualExpenseRepository) Update(
 	if req.Source != nil {
 		existing.Source = *req.Source
 	}
-	if req.ActualAmount != nil {
+	if req.ActualAmount != nil && *req.ActualAmount != existing.ActualAmount {
+		// A changed amount is checked against the approval rule again, so an
+		// approved expense cannot be raised past it unseen
+		pending, err := needsApproval(r.db, r.userID, *req.ActualAmount)
+		if err != nil {
+			return nil, err
+		}
+		if pending {
+			existing.PendingApproval = true
+			existing.ApprovedAt = nil
+			existing.ApprovedBy = nil
+		}
 		existing.ActualAmount = *req.ActualAmount
 	}
 	if req.ExpenseType != nil {
@@ -184,22 +580,134 @@ func (r *ActualExpenseRepository) Update(
 		existing.ItemCode = req.ItemCode
 	}
 	if req.ExpectedExpenseID != nil {
+		if err := checkExpectedExpenseRef(r.db, r.userID, req.ExpectedExpenseID); err != nil {
+			return nil, err
+		}
 		existing.ExpectedExpenseID = req.ExpectedExpenseID
 	}
+	if req.Deductible != nil {
+		existing.Deductible = *req.Deductible
+	}
+	if req.Tags != nil {
+		existing.Tags = *req.Tags
+	}
 
+	itemName, source, err := sealNameAndSource(existing.ItemName, existing.Source)
+	if err != nil {
+		return nil, err
+	}
 	_, err = r.db.Exec(`
-		UPDATE actual_expenses SET item_name = ?, source = ?, actual_amount = ?, expense_type = ?, item_code = ?, expected_expense_id = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, existing.ItemName, existing.Source, existing.ActualAmount, existing.ExpenseType, existing.ItemCode, existing.ExpectedExpenseID, id)
+		UPDATE actual_expenses SET item_name = ?, source = ?, actual_amount = ?, expense_type = ?, item_code = ?, expected_expense_id = ?, pending_approval = ?, approved_at = ?, approved_by = ?, deductible = ?, tags = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+	`, itemName, source, existing.ActualAmount, existing.ExpenseType, existing.ItemCode, existing.ExpectedExpenseID, existing.PendingApproval, existing.ApprovedAt, existing.ApprovedBy, existing.Deductible, joinTags(existing.Tags), id, r.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.auditUpdate(&before)
+}
+
+// auditUpdate records the change from before to the expense's current state,
+// publishes it and returns the current state
+func (r *ActualExpenseRepository) auditUpdate(before *models.ActualExpense) (*models.ActualExpense, error) {
+	after, err := r.GetByID(before.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(
+		r.db, r.userID, models.AuditEntityActualExpense, before.ID, models.AuditUpdate, before, after,
+	); err != nil {
+		return nil, err
+	}
+	r.publish(events.ExpenseUpdated, after)
+	return after, nil
+}
+
+// HouseholdOwner returns the owner of expense id when the scoped user owns it
+// or oversees its owner, as their allowance parent or as an admin. Other
+// users' expenses are reported as models.ErrExpenseNotFound.
+func (r *ActualExpenseRepository) HouseholdOwner(id int64) (int64, error) {
+	return householdExpenseOwner(r.db, r.userID, id)
+}
+
+// PendingApprovals returns the expenses of other users pending approval that
+// the scoped user may approve, oldest first: everyone's for an admin
+func (r *ActualExpenseRepository) PendingApprovals() ([]models.ActualExpense, error) {
+	rows, err := r.db.Query(`
+		SELECT `+actualExpenseColumns+`
+		FROM actual_expenses
+		WHERE pending_approval = 1 AND user_id != ?
+			AND (
+				user_id IN (SELECT user_id FROM allowance_accounts WHERE parent_id = ?)
+				OR EXISTS (
+					SELECT 1 FROM users
+					WHERE id = ? AND is_admin = 1 AND id NOT IN (SELECT user_id FROM allowance_accounts)
+				)
+			)
+		ORDER BY created_at, id
+	`, r.userID, r.userID, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending approvals: %w", err)
+	}
+	defer rows.Close()
+
+	expenses, err := scanActualExpenses(rows)
+	if err != nil {
+		return nil, err
+	}
+	if expenses == nil {
+		expenses = []models.ActualExpense{}
+	}
+	return expenses, nil
+}
+
+// Approve counts a pending expense of the scoped user in totals again,
+// recording approverID as the user who approved it, who must oversee the
+// owner, see HouseholdOwner. Owners cannot approve their own expenses,
+// ErrSelfApproval, except in the shared workspace (user 0) used while
+// authentication is disabled. Expenses that are not pending return
+// ErrNotPendingApproval.
+func (r *ActualExpenseRepository) Approve(id, approverID int64) (*models.ActualExpense, error) {
+	if approverID == r.userID && r.userID != 0 {
+		return nil, ErrSelfApproval
+	}
+	before, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMonthOpen(r.db, r.userID, before.Month, before.Year); err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE actual_expenses SET pending_approval = 0, approved_at = ?, approved_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND pending_approval = 1
+	`, time.Now().UTC(), approverID, id, r.userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.GetByID(id)
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotPendingApproval
+	}
+
+	return r.auditUpdate(before)
 }
 
 func (r *ActualExpenseRepository) Delete(id int64) error {
-	result, err := r.db.Exec(`DELETE FROM actual_expenses WHERE id = ?`, id)
+	before, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := checkMonthOpen(r.db, r.userID, before.Month, before.Year); err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`DELETE FROM actual_expenses WHERE id = ? AND user_id = ?`, id, r.userID)
 	if err != nil {
 		return err
 	}
@@ -212,14 +720,29 @@ func (r *ActualExpenseRepository) Delete(id int64) error {
 		return models.ErrExpenseNotFound
 	}
 
+	if err := recordAudit(r.db, r.userID, models.AuditEntityActualExpense, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	r.publish(events.ExpenseDeleted, before)
 	return nil
 }
 
+// NeedsApproval reports whether a new expense of amount would be pending approval
+func (r *ActualExpenseRepository) NeedsApproval(amount float64) (bool, error) {
+	return needsApproval(r.db, r.userID, amount)
+}
+
 func (r *ActualExpenseRepository) GetNextReceiptNumber() (int64, error) {
+	return nextReceiptNumber(r.db, r.userID)
+}
+
+// nextReceiptNumber returns the receipt number after userID's highest;
+// every user numbers their receipts from 1
+func nextReceiptNumber(db querier, userID int64) (int64, error) {
 	var maxReceiptNumber sql.NullInt64
-	err := r.db.QueryRow(`
-		SELECT MAX(receipt_number) FROM actual_expenses
-	`).Scan(&maxReceiptNumber)
+	err := db.QueryRow(`
+		SELECT MAX(receipt_number) FROM actual_expenses WHERE user_id = ?
+	`, userID).Scan(&maxReceiptNumber)
 	if err != nil {
 		return 0, err
 	}
@@ -230,22 +753,30 @@ func (r *ActualExpenseRepository) GetNextReceiptNumber() (int64, error) {
 	return 1, nil
 }
 
-func (r *ActualExpenseRepository) scanRows(rows *sql.Rows) ([]models.ActualExpense, error) {
+func scanActualExpenses(rows *sql.Rows) ([]models.ActualExpense, error) {
 	var expenses []models.ActualExpense
 
 	for rows.Next() {
 		var expense models.ActualExpense
 		var itemCode sql.NullString
 		var expectedExpenseID sql.NullInt64
+		var lineNo sql.NullInt64
+		var approvedAt sql.NullTime
+		var approvedBy sql.NullInt64
+		var tags string
 
 		err := rows.Scan(
 			&expense.ID, &expense.ItemName, &expense.Source, &expense.ActualAmount,
 			&expense.ExpenseType, &itemCode, &expectedExpenseID, &expense.ReceiptDate,
-			&expense.ReceiptNumber, &expense.Month, &expense.Year, &expense.CreatedAt, &expense.UpdatedAt,
+			&expense.ReceiptNumber, &lineNo, &expense.Month, &expense.Year, &expense.MonthAssigned, &expense.CreatedAt, &expense.UpdatedAt,
+			&expense.PendingApproval, &approvedAt, &approvedBy, &expense.Deductible, &expense.AutoGenerated, &tags,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if err := openNameAndSource(&expense.ItemName, &expense.Source); err != nil {
+			return nil, err
+		}
 
 		if itemCode.Valid {
 			expense.ItemCode = &itemCode.String
@@ -253,9 +784,37 @@ func (r *ActualExpenseRepository) scanRows(rows *sql.Rows) ([]models.ActualExpen
 		if expectedExpenseID.Valid {
 			expense.ExpectedExpenseID = &expectedExpenseID.Int64
 		}
+		if lineNo.Valid {
+			n := int(lineNo.Int64)
+			expense.LineNo = &n
+		}
+		if approvedAt.Valid {
+			expense.ApprovedAt = &approvedAt.Time
+		}
+		if approvedBy.Valid {
+			expense.ApprovedBy = &approvedBy.Int64
+		}
+		expense.Tags = splitTags(tags)
 
 		expenses = append(expenses, expense)
 	}
 
 	return expenses, rows.Err()
 }
+
+// joinTags stores tags between commas, e.g. ",work,travel,", so each can be
+// matched with LIKE on its commas; no tags are stored as ""
+func joinTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.Join(tags, ",") + ","
+}
+
+// splitTags reads tags stored by joinTags
+func splitTags(stored string) []string {
+	if stored = strings.Trim(stored, ","); stored == "" {
+		return nil
+	}
+	return strings.Split(stored, ",")
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+)
+
+// benchExpenses is the table size the benchmarks run against, about 4,000
+// expenses per month over two years. Latency targets at this size:
+//
+//	GetMonthlySummary  under 2ms   (one indexed aggregate)
+//	CountMonth         under 1ms
+//	ListPage           under 50ms  (newest 50 of all expenses)
+//	GetByMonthYear     under 150ms (a month of ~4,000 rows; most of it is the
+//	                                driver decoding rows, not the query)
+//
+// Run with: go test ./internal/repository -run '^$' -bench .
+const benchExpenses = 100_000
+
+var (
+	benchOnce sync.Once
+	benchRepo *ActualExpenseRepository
+)
+
+// benchActualExpenseRepo returns a repository over benchExpenses seeded
+// expenses; the database is built once and shared by all benchmarks
+func benchActualExpenseRepo(b *testing.B) *ActualExpenseRepository {
+	b.Helper()
+	benchOnce.Do(func() {
+		sqlDB, err := sql.Open("libsql", "file:bench_actual_expenses?mode=memory&cache=shared")
+		if err != nil {
+			b.Fatalf("Failed to open in-memory database: %v", err)
+		}
+		db := &DB{DB: sqlDB}
+		if err := db.RunMigrations(); err != nil {
+			b.Fatalf("Failed to run migrations: %v", err)
+		}
+		seedActualExpenses(b, db, benchExpenses)
+		benchRepo = NewActualExpenseRepository(db)
+	})
+	if benchRepo == nil {
+		b.Fatal("Benchmark database setup failed")
+	}
+	return benchRepo
+}
+
+func BenchmarkGetMonthlySummary(b *testing.B) {
+	repo := benchActualExpenseRepo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.GetMonthlySummary(6, 2025); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetByMonthYear(b *testing.B) {
+	repo := benchActualExpenseRepo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.GetByMonthYear(6, 2025); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListPage(b *testing.B) {
+	repo := benchActualExpenseRepo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.List(ExpenseFilter{Limit: 50}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCountMonth(b *testing.B) {
+	repo := benchActualExpenseRepo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Count(ExpenseFilter{Type: "weekly", Month: 6, Year: 2025}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
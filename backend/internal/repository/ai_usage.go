@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrAIQuotaExceeded = errors.New("monthly AI quota exceeded")
+
+// monthLayout is the format of the ai_usage.month column
+const monthLayout = "2006-01"
+
+// AIUsageRepository handles ai_usage database operations.
+// It only sees the usage of one user, see ForUser.
+type AIUsageRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewAIUsageRepository creates a new AIUsageRepository
+func NewAIUsageRepository(db *DB) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that counts userID's calls
+func (r *AIUsageRepository) ForUser(userID int64) *AIUsageRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// Reserve counts one call in the month of at and returns the calls made that
+// month, this one included. When limit calls were already made the call is
+// not counted and ErrAIQuotaExceeded is returned; a limit of 0 is unlimited.
+// The check and the increment are one statement, so concurrent uploads
+// cannot both take the last call.
+func (r *AIUsageRepository) Reserve(at time.Time, limit int) (int, error) {
+	var calls int
+	err := r.db.QueryRow(`
+		INSERT INTO ai_usage (user_id, month, calls) VALUES (?, ?, 1)
+		ON CONFLICT (user_id, month) DO UPDATE SET calls = calls + 1
+		WHERE ? = 0 OR calls < ?
+		RETURNING calls
+	`, r.userID, at.UTC().Format(monthLayout), limit, limit).Scan(&calls)
+	if errors.Is(err, sql.ErrNoRows) {
+		return limit, ErrAIQuotaExceeded
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to count AI usage: %w", err)
+	}
+	return calls, nil
+}
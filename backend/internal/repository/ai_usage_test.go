@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAIUsageRepository_Reserve(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	repo := NewAIUsageRepository(db)
+	march := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	for want := 1; want <= 2; want++ {
+		calls, err := repo.ForUser(1).Reserve(march, 2)
+		if err != nil || calls != want {
+			t.Fatalf("Expected call %d, got %d, %v", want, calls, err)
+		}
+	}
+	if _, err := repo.ForUser(1).Reserve(march, 2); !errors.Is(err, ErrAIQuotaExceeded) {
+		t.Fatalf("Expected ErrAIQuotaExceeded, got %v", err)
+	}
+
+	// A raised quota, another user and the next month all start over
+	if calls, err := repo.ForUser(1).Reserve(march, 3); err != nil || calls != 3 {
+		t.Errorf("Expected call 3 with a raised quota, got %d, %v", calls, err)
+	}
+	if calls, err := repo.ForUser(2).Reserve(march, 2); err != nil || calls != 1 {
+		t.Errorf("Expected call 1 for another user, got %d, %v", calls, err)
+	}
+	if calls, err := repo.ForUser(1).Reserve(march.Add(time.Hour), 2); err != nil || calls != 1 {
+		t.Errorf("Expected call 1 in April, got %d, %v", calls, err)
+	}
+
+	// Without a quota calls are only counted
+	for i := 0; i < 3; i++ {
+		if _, err := repo.ForUser(1).Reserve(march, 0); err != nil {
+			t.Fatalf("Expected no limit, got %v", err)
+		}
+	}
+	var calls int
+	if err := db.QueryRow(`SELECT calls FROM ai_usage WHERE user_id = 1 AND month = '2026-03'`).Scan(&calls); err != nil || calls != 6 {
+		t.Errorf("Expected 6 calls in March, got %d, %v", calls, err)
+	}
+}
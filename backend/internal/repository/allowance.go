@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrAllowanceNotFound = errors.New("allowance account not found")
+	ErrAllowanceExists   = errors.New("an allowance account with this name already exists")
+)
+
+// AllowanceRepository handles allowance sub-accounts. It manages the
+// sub-accounts of one parent user, see ForUser.
+type AllowanceRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewAllowanceRepository creates a new AllowanceRepository
+func NewAllowanceRepository(db *DB) *AllowanceRepository {
+	return &AllowanceRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that manages userID's sub-accounts
+func (r *AllowanceRepository) ForUser(userID int64) *AllowanceRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// List returns the scoped user's sub-accounts by name
+func (r *AllowanceRepository) List() ([]models.Allowance, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, name, monthly_amount, created_at
+		FROM allowance_accounts WHERE parent_id = ?
+		ORDER BY name
+	`, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query allowance accounts: %w", err)
+	}
+	defer rows.Close()
+
+	allowances := []models.Allowance{}
+	for rows.Next() {
+		var a models.Allowance
+		if err := rows.Scan(&a.ID, &a.Name, &a.MonthlyAmount, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan allowance account: %w", err)
+		}
+		allowances = append(allowances, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating allowance accounts: %w", err)
+	}
+	return allowances, nil
+}
+
+// Get returns sub-account id of the scoped user
+func (r *AllowanceRepository) Get(id int64) (*models.Allowance, error) {
+	var a models.Allowance
+	err := r.db.QueryRow(`
+		SELECT user_id, name, monthly_amount, created_at
+		FROM allowance_accounts WHERE user_id = ? AND parent_id = ?
+	`, id, r.userID).Scan(&a.ID, &a.Name, &a.MonthlyAmount, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAllowanceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowance account: %w", err)
+	}
+	return &a, nil
+}
+
+// Create adds a sub-account for the scoped user. The sub-account is a user
+// without a password and an unreachable email, so it can only sign in with
+// tokens its parent issues.
+func (r *AllowanceRepository) Create(req *models.AllowanceRequest) (*models.Allowance, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate allowance account email: %w", err)
+	}
+	email := fmt.Sprintf("allowance-%d-%s@accounts.invalid", r.userID, hex.EncodeToString(b))
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO users (email, password_hash) VALUES (?, '')`, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allowance user: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO allowance_accounts (user_id, parent_id, name, monthly_amount, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, r.userID, req.Name, req.MonthlyAmount, time.Now().UTC()); err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, ErrAllowanceExists
+		}
+		return nil, fmt.Errorf("failed to create allowance account: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit allowance account: %w", err)
+	}
+	return r.Get(id)
+}
+
+// Update renames sub-account id or changes its allowance
+func (r *AllowanceRepository) Update(id int64, req *models.UpdateAllowanceRequest) (*models.Allowance, error) {
+	existing, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.MonthlyAmount != nil {
+		existing.MonthlyAmount = *req.MonthlyAmount
+	}
+
+	if _, err := r.db.Exec(`
+		UPDATE allowance_accounts SET name = ?, monthly_amount = ?
+		WHERE user_id = ? AND parent_id = ?
+	`, existing.Name, existing.MonthlyAmount, id, r.userID); err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, ErrAllowanceExists
+		}
+		return nil, fmt.Errorf("failed to update allowance account: %w", err)
+	}
+	return existing, nil
+}
+
+// Delete removes sub-account id together with its sessions and the expenses
+// it logged
+func (r *AllowanceRepository) Delete(id int64) error {
+	if _, err := r.Get(id); err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleted explicitly so nothing depends on the foreign_keys pragma
+	for _, query := range []string{
+		`DELETE FROM actual_expenses WHERE user_id = ?`,
+		`DELETE FROM sessions WHERE user_id = ?`,
+		`DELETE FROM allowance_accounts WHERE user_id = ?`,
+		`DELETE FROM users WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("failed to delete allowance account: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit allowance account delete: %w", err)
+	}
+	return nil
+}
+
+// Statuses returns the spending of the scoped user's sub-accounts in a month
+func (r *AllowanceRepository) Statuses(month, year int) ([]models.AllowanceStatus, error) {
+	return allowanceStatuses(r.db, "parent_id", r.userID, month, year)
+}
+
+// Own returns the spending in a month of the scoped user when it is a
+// sub-account itself, or ErrAllowanceNotFound
+func (r *AllowanceRepository) Own(month, year int) (*models.AllowanceStatus, error) {
+	statuses, err := allowanceStatuses(r.db, "user_id", r.userID, month, year)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, ErrAllowanceNotFound
+	}
+	return &statuses[0], nil
+}
+
+// allowanceStatuses sums the approved spending of the sub-accounts whose
+// column (parent_id or user_id) is userID
+func allowanceStatuses(db querier, column string, userID int64, month, year int) ([]models.AllowanceStatus, error) {
+	rows, err := db.Query(`
+		SELECT a.user_id, a.name, a.monthly_amount, COALESCE(SUM(e.actual_amount), 0)
+		FROM allowance_accounts a
+		LEFT JOIN actual_expenses e
+			ON e.user_id = a.user_id AND e.year = ? AND e.month = ? AND e.pending_approval = 0
+		WHERE a.`+column+` = ?
+		GROUP BY a.user_id, a.name, a.monthly_amount
+		ORDER BY a.name
+	`, year, month, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query allowance spending: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []models.AllowanceStatus
+	for rows.Next() {
+		var s models.AllowanceStatus
+		if err := rows.Scan(&s.ID, &s.Name, &s.MonthlyAmount, &s.Spent); err != nil {
+			return nil, fmt.Errorf("failed to scan allowance spending: %w", err)
+		}
+		s.Remaining = s.MonthlyAmount - s.Spent
+		statuses = append(statuses, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating allowance spending: %w", err)
+	}
+	return statuses, nil
+}
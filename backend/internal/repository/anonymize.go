@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	mathrand "math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+)
+
+// anonymizeAction says what Anonymize does with the values of a column
+type anonymizeAction int
+
+const (
+	// anonymizeKeep keeps values that reveal nothing, e.g. expense types
+	anonymizeKeep anonymizeAction = iota
+	// anonymizePseudonym replaces text with a pseudonym derived from it, so
+	// equal values, ignoring case and surrounding spaces, stay equal
+	anonymizePseudonym
+	// anonymizeAmount scales and jitters amounts
+	anonymizeAmount
+	// anonymizeReplace sets every value to a fixed one
+	anonymizeReplace
+	// anonymizeEmail replaces addresses with one made from the row's ID
+	anonymizeEmail
+	// anonymizeSettings scrubs the amounts and free text of setting values
+	anonymizeSettings
+)
+
+// anonymizeRule is the action for one column, with the prefix of its
+// pseudonyms or its replacement value
+type anonymizeRule struct {
+	action anonymizeAction
+	prefix string
+	value  any
+}
+
+var (
+	keepColumn   = anonymizeRule{action: anonymizeKeep}
+	amountColumn = anonymizeRule{action: anonymizeAmount}
+	nullColumn   = anonymizeRule{action: anonymizeReplace}
+)
+
+func pseudonymColumn(prefix string) anonymizeRule {
+	return anonymizeRule{action: anonymizePseudonym, prefix: prefix}
+}
+
+// anonymizedColumns lists the action for every text and real column, by
+// table. Anonymize refuses a database with a text or real column missing
+// here, so a new column cannot leak into a bundle unnoticed.
+var anonymizedColumns = map[string]map[string]anonymizeRule{
+	"actual_expenses": {
+		"item_name": pseudonymColumn("item"), "source": pseudonymColumn("source"), "item_code": pseudonymColumn("code"),
+		"actual_amount": amountColumn, "expense_type": keepColumn, "tags": {action: anonymizeReplace, value: ""},
+	},
+	"ai_usage":           {"month": keepColumn},
+	"allowance_accounts": {"name": pseudonymColumn("account"), "monthly_amount": amountColumn},
+	"audit_log": {
+		"entity": keepColumn, "action": keepColumn, "before_json": nullColumn, "after_json": nullColumn,
+	},
+	"budget_alert_escalations": {"level": keepColumn},
+	"budget_limit_history": {
+		"old_amount": amountColumn, "new_amount": amountColumn, "old_threshold": keepColumn, "new_threshold": keepColumn,
+	},
+	"budget_limit_thresholds": {"threshold": keepColumn},
+	"budget_limits":           {"amount": amountColumn, "notification_threshold": keepColumn, "weekly_limit": amountColumn},
+	"budget_threshold_alerts": {"threshold": keepColumn},
+	"category_rules": {
+		"name": pseudonymColumn("rule"), "source_contains": pseudonymColumn("source"), "item_contains": pseudonymColumn("item"),
+		"min_amount": amountColumn, "max_amount": amountColumn, "expense_type": keepColumn,
+	},
+	"expected_expenses": {
+		"item_name": pseudonymColumn("item"), "source": pseudonymColumn("source"),
+		"expected_amount": amountColumn, "expense_type": keepColumn, "recurrence": keepColumn,
+		"category": pseudonymColumn("category"),
+	},
+	"expense_comments": {"body": {action: anonymizeReplace, value: "comment"}},
+	"expense_suggestions": {
+		"item_name": pseudonymColumn("item"), "expense_type": keepColumn, "status": keepColumn,
+	},
+	"failure_counts": {"handler": keepColumn, "code": keepColumn},
+	"notifications": {
+		"kind": keepColumn, "title": {action: anonymizeReplace, value: ""},
+		"message": {action: anonymizeReplace, value: ""}, "link": keepColumn,
+	},
+	"receipt_processing_history": {
+		"file_name": pseudonymColumn("receipt"), "status": keepColumn, "error_code": keepColumn,
+		"raw_response": nullColumn, "repair_response": nullColumn,
+	},
+	"schema_migrations": {"description": keepColumn},
+	"settings":          {"key": keepColumn, "value": {action: anonymizeSettings}},
+	"source_caps":       {"source": pseudonymColumn("source"), "monthly_limit": amountColumn},
+	"spending_goals": {
+		"name": pseudonymColumn("goal"), "monthly_limit": amountColumn, "expense_type": keepColumn,
+	},
+	"users": {
+		"email": {action: anonymizeEmail}, "password_hash": {action: anonymizeReplace, value: ""},
+	},
+}
+
+// anonymizedTables are emptied: sessions and single use tokens are of no use
+// elsewhere, and without the environment tag the bundle opens in any
+// environment
+var anonymizedTables = []string{"sessions", "user_tokens", "schema_environment"}
+
+// AnonymizedTable counts the rows of one table of a bundle
+type AnonymizedTable struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// AnonymizeReport summarizes an Anonymize run
+type AnonymizeReport struct {
+	Path   string            `json:"path"`
+	Tables []AnonymizedTable `json:"tables"`
+}
+
+// anonymizer holds the secrets of one Anonymize run. Neither is stored, so
+// the bundle cannot be mapped back to the original values.
+type anonymizer struct {
+	salt  []byte
+	scale float64
+	rng   *mathrand.Rand
+}
+
+func newAnonymizer() (*anonymizer, error) {
+	var salt, seed [32]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate seed: %w", err)
+	}
+	rng := mathrand.New(mathrand.NewChaCha8(seed))
+	return &anonymizer{salt: salt[:], scale: 0.5 + rng.Float64()*1.5, rng: rng}, nil
+}
+
+// pseudonym returns prefix and a keyed hash of value, ignoring case and
+// surrounding spaces, e.g. source-3fa2c1d0
+func (a *anonymizer) pseudonym(prefix, value string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return prefix + "-" + hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// amount scales value by the run's factor and up to 10% of noise, in cents.
+// Zero stays zero, since it often means "none".
+func (a *anonymizer) amount(value float64) float64 {
+	if value == 0 {
+		return 0
+	}
+	jitter := 0.9 + a.rng.Float64()*0.2
+	return math.Round(value*a.scale*jitter*100) / 100
+}
+
+// Anonymize writes a copy of the database to path with its names, stores,
+// free text and credentials replaced and its amounts perturbed, keeping every
+// row and relation, so the copy can be shared to reproduce a bug. Equal
+// names stay equal, but the substring matches of category rules no longer
+// match. Encrypted columns are decrypted, so SetFieldCipher must have been
+// called for an encrypted database, and the copy is not encrypted.
+func (r *MaintenanceRepository) Anonymize(path string) (*AnonymizeReport, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrBackupExists)
+	}
+	if _, err := r.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return nil, fmt.Errorf("failed to copy the database: %w", err)
+	}
+
+	report, err := anonymizeCopy(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return report, nil
+}
+
+// anonymizeCopy scrubs the copy at path in place and vacuums it, so none of
+// the original values are left in its free pages
+func anonymizeCopy(path string) (*AnonymizeReport, error) {
+	db, err := NewDB(Config{Mode: ModeLocal, LocalPath: path})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tables, err := anonymizeTables(db)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newAnonymizer()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if slices.Contains(anonymizedTables, table) {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return nil, fmt.Errorf("failed to empty %s: %w", table, err)
+			}
+			continue
+		}
+		for column, rule := range anonymizedColumns[table] {
+			if err := a.apply(tx, table, column, rule); err != nil {
+				return nil, fmt.Errorf("failed to anonymize %s.%s: %w", table, column, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return nil, fmt.Errorf("failed to compact the bundle: %w", err)
+	}
+
+	report := &AnonymizeReport{Path: path, Tables: []AnonymizedTable{}}
+	for _, table := range tables {
+		t := AnonymizedTable{Table: table}
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&t.Rows); err != nil {
+			return nil, err
+		}
+		report.Tables = append(report.Tables, t)
+	}
+	return report, nil
+}
+
+// anonymizeTables returns the tables of db by name, or an error naming every
+// text or real column that has no rule in anonymizedColumns
+func anonymizeTables(db *DB) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT m.name, p.name, UPPER(p.type)
+		FROM sqlite_master m, pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+		ORDER BY m.name, p.cid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the schema: %w", err)
+	}
+	defer rows.Close()
+
+	var tables, unknown []string
+	for rows.Next() {
+		var table, column, typ string
+		if err := rows.Scan(&table, &column, &typ); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+		if slices.Contains(anonymizedTables, table) {
+			continue
+		}
+		if _, ok := anonymizedColumns[table][column]; !ok && (strings.Contains(typ, "TEXT") || typ == "REAL") {
+			unknown = append(unknown, table+"."+column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("no anonymization rule for %s", strings.Join(unknown, ", "))
+	}
+	return tables, nil
+}
+
+// apply anonymizes table.column, which come from anonymizedColumns and never
+// from input
+func (a *anonymizer) apply(tx *sql.Tx, table, column string, rule anonymizeRule) error {
+	switch rule.action {
+	case anonymizeKeep:
+		return nil
+	case anonymizeReplace:
+		_, err := tx.Exec(`UPDATE `+table+` SET `+column+` = ? WHERE `+column+` IS NOT NULL`, rule.value)
+		return err
+	case anonymizeEmail:
+		_, err := tx.Exec(`UPDATE ` + table + ` SET ` + column + ` = 'user-' || rowid || '@example.invalid'`)
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT rowid, ` + column + ` FROM ` + table + ` WHERE ` + column + ` IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	type value struct {
+		rowid int64
+		value any
+	}
+	var values []value
+	for rows.Next() {
+		var v value
+		var text string
+		var number float64
+		switch rule.action {
+		case anonymizeAmount:
+			err = rows.Scan(&v.rowid, &number)
+			v.value = a.amount(number)
+		case anonymizePseudonym:
+			if err = rows.Scan(&v.rowid, &text); err == nil {
+				if text, err = openField(column, text); err == nil {
+					v.value = a.pseudonym(rule.prefix, text)
+				}
+			}
+		case anonymizeSettings:
+			if err = rows.Scan(&v.rowid, &text); err == nil {
+				v.value, err = a.setting(text)
+			}
+		}
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		values = append(values, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		if _, err := tx.Exec(`UPDATE `+table+` SET `+column+` = ? WHERE rowid = ?`, v.value, v.rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setting scrubs a JSON setting value: amounts are perturbed and reasons
+// cleared, the rest, e.g. job schedules, is kept
+func (a *anonymizer) setting(value string) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		// Not an object, e.g. a plain value: nothing to scrub
+		return value, nil
+	}
+	for key, v := range fields {
+		switch n, isNumber := v.(float64); {
+		case key == "amount" && isNumber:
+			fields[key] = a.amount(n)
+		case key == "reason":
+			fields[key] = ""
+		}
+	}
+	scrubbed, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(scrubbed), nil
+}
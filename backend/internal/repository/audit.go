@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditFilter selects audit log entries; zero values match everything
+type AuditFilter struct {
+	Entity   string
+	EntityID int64
+	Action   string
+	From     *time.Time // inclusive lower bound on created_at
+	To       *time.Time // exclusive upper bound on created_at
+	Limit    int
+	Offset   int
+}
+
+// AuditRepository reads the audit log written by the budget and expense
+// repositories. It only sees the changes of one user, see ForUser.
+type AuditRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that reads userID's changes
+func (r *AuditRepository) ForUser(userID int64) *AuditRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// query builds the filtered query over the user's audit log, newest first
+func (r *AuditRepository) query(filter AuditFilter) *selectBuilder {
+	b := newSelectBuilder(
+		"audit_log",
+		`id, user_id, COALESCE((SELECT email FROM users WHERE users.id = audit_log.user_id), ''),
+		entity, entity_id, action, before_json, after_json, created_at`,
+		"user_id", "entity", "entity_id", "action", "created_at",
+	).order("created_at DESC, id DESC").where("user_id", "=", r.userID)
+
+	if filter.Entity != "" {
+		b.where("entity", "=", filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		b.where("entity_id", "=", filter.EntityID)
+	}
+	if filter.Action != "" {
+		b.where("action", "=", filter.Action)
+	}
+	if filter.From != nil {
+		b.where("created_at", ">=", filter.From.UTC())
+	}
+	if filter.To != nil {
+		b.where("created_at", "<", filter.To.UTC())
+	}
+	return b.page(filter.Limit, filter.Offset)
+}
+
+// List returns the entries matching filter, newest first
+func (r *AuditRepository) List(filter AuditFilter) ([]models.AuditEntry, error) {
+	query, args, err := r.query(filter).build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(
+			&e.ID, &e.UserID, &e.Actor, &e.Entity, &e.EntityID, &e.Action, &before, &after, &e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if before.Valid {
+			plain, err := openField("before_json", before.String)
+			if err != nil {
+				return nil, err
+			}
+			e.Before = json.RawMessage(plain)
+		}
+		if after.Valid {
+			plain, err := openField("after_json", after.String)
+			if err != nil {
+				return nil, err
+			}
+			e.After = json.RawMessage(plain)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Count returns the number of entries matching filter, ignoring paging
+func (r *AuditRepository) Count(filter AuditFilter) (int, error) {
+	query, args, err := r.query(filter).buildCount()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count audit log: %w", err)
+	}
+	return count, nil
+}
+
+// recordAudit is the hook the budget and expense repositories call after each
+// change, on the same connection or transaction as the change. before and
+// after are the record's snapshots; pass nil for the side that does not exist.
+func recordAudit(
+	db querier,
+	userID int64,
+	entity string,
+	entityID int64,
+	action string,
+	before, after any,
+) error {
+	beforeJSON, err := auditSnapshot("before_json", before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditSnapshot("after_json", after)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO audit_log (user_id, entity, entity_id, action, before_json, after_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, userID, entity, entityID, action, beforeJSON, afterJSON, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record %s of %s %d: %w", action, entity, entityID, err)
+	}
+	return nil
+}
+
+// auditSnapshot encodes v for column. Snapshots of expenses hold their item
+// names and stores, so they are encrypted like the expense columns.
+func auditSnapshot(column string, v any) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	sealed, err := sealField(column, string(data))
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: sealed, Valid: true}, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrBackupExists is returned when the backup file is already there; a backup
+// never overwrites a file
+var ErrBackupExists = errors.New("backup file already exists")
+
+// BackupRecord describes the last successful backup
+type BackupRecord struct {
+	FinishedAt time.Time `json:"finished_at"`
+	SizeBytes  int64     `json:"size_bytes"`
+}
+
+// Backup writes a consistent copy of the database to path with VACUUM INTO,
+// which works while the server is running, and records it as the last backup.
+// Encrypted columns stay encrypted in the copy.
+func (r *MaintenanceRepository) Backup(path string) (*BackupRecord, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrBackupExists)
+	}
+	if _, err := r.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return nil, fmt.Errorf("failed to back up the database: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the backup: %w", err)
+	}
+
+	record := &BackupRecord{FinishedAt: time.Now().UTC(), SizeBytes: info.Size()}
+	if err := NewSettingsRepository(r.db).set(SettingLastBackup, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
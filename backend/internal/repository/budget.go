@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"budget-tracker/internal/events"
 	"budget-tracker/internal/models"
 	"database/sql"
 	"errors"
@@ -14,26 +15,109 @@ var (
 	ErrBudgetExists   = errors.New("budget limit already exists for this month/year")
 )
 
-// BudgetRepository handles budget_limits database operations
+// BudgetRepository handles budget_limits database operations.
+// It only sees the budgets of one user, see ForUser.
 type BudgetRepository struct {
-	db *DB
+	db     *DB
+	userID int64
+	events *events.Bus
 }
 
-// NewBudgetRepository creates a new BudgetRepository
+// NewBudgetRepository creates a new BudgetRepository for the shared
+// workspace (user 0) used while authentication is disabled
 func NewBudgetRepository(db *DB) *BudgetRepository {
 	return &BudgetRepository{db: db}
 }
 
+// ForUser returns a copy of the repository that reads and writes userID's budgets
+func (r *BudgetRepository) ForUser(userID int64) *BudgetRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// PublishTo sets the bus the repository publishes the creation, changes,
+// deletion and threshold crossings of budgets to, once they are stored
+func (r *BudgetRepository) PublishTo(bus *events.Bus) {
+	r.events = bus
+}
+
+// publish sends an event about budget to the bus, if any
+func (r *BudgetRepository) publish(typ events.Type, budget *models.BudgetLimit, threshold float64) {
+	r.events.Publish(events.Event{Type: typ, UserID: r.userID, Budget: budget, Threshold: threshold})
+}
+
+// UserIDs returns every user with budgets or an account, or just the shared
+// workspace when there are neither. Allowance sub-accounts have no budgets
+// of their own and are left out.
+func (r *BudgetRepository) UserIDs() ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM (
+			SELECT user_id AS id FROM budget_limits
+			UNION
+			SELECT id FROM users
+		)
+		WHERE id NOT IN (SELECT user_id FROM allowance_accounts)
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget owners: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan budget owner: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budget owners: %w", err)
+	}
+
+	if len(ids) == 0 {
+		ids = []int64{0}
+	}
+	return ids, nil
+}
+
+const budgetColumns = `id, month, year, amount, notification_threshold, rollover_unspent, weekly_limit, closed_at, deleted_at, created_at, updated_at`
+
+func scanBudget(row interface{ Scan(...any) error }, b *models.BudgetLimit) error {
+	var closedAt, deletedAt sql.NullTime
+	if err := row.Scan(
+		&b.ID, &b.Month, &b.Year, &b.Amount,
+		&b.NotificationThreshold, &b.RolloverUnspent, &b.WeeklyLimit, &closedAt, &deletedAt, &b.CreatedAt, &b.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	if closedAt.Valid {
+		b.ClosedAt = &closedAt.Time
+	}
+	if deletedAt.Valid {
+		b.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
+const insertBudgetQuery = `
+	INSERT INTO budget_limits (user_id, month, year, amount, notification_threshold, rollover_unspent, weekly_limit)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
 // Create creates a new budget limit
 func (r *BudgetRepository) Create(
 	req *models.CreateBudgetLimitRequest,
 ) (*models.BudgetLimit, error) {
-	query := `
-		INSERT INTO budget_limits (month, year, amount, notification_threshold)
-		VALUES (?, ?, ?, ?)
-	`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	result, err := r.db.Exec(query, req.Month, req.Year, req.Amount, req.NotificationThreshold)
+	id, err := r.insertBudget(tx, req)
 	if err != nil {
 		// Check for unique constraint violation
 		if isUniqueConstraintError(err) {
@@ -41,47 +125,302 @@ func (r *BudgetRepository) Create(
 		}
 		return nil, fmt.Errorf("failed to create budget limit: %w", err)
 	}
+	created, err := getBudgetByID(tx, r.userID, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(tx, r.userID, models.AuditEntityBudget, id, models.AuditCreate, nil, created); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget limit: %w", err)
+	}
+	r.publishChange(models.AuditCreate, nil, created)
+	return created, nil
+}
+
+// BudgetConflict is a row of a bulk create whose month already has a budget,
+// or appears earlier in the same request
+type BudgetConflict struct {
+	Index int    `json:"index"`
+	Month int    `json:"month"`
+	Year  int    `json:"year"`
+	Error string `json:"error"`
+}
+
+// DuplicateBudgetsError is returned by CreateBulk when rows conflict. It
+// matches ErrBudgetExists with errors.Is.
+type DuplicateBudgetsError struct {
+	Conflicts []BudgetConflict
+}
+
+func (e *DuplicateBudgetsError) Error() string {
+	return fmt.Sprintf("%s (%d)", ErrBudgetExists, len(e.Conflicts))
+}
+
+func (e *DuplicateBudgetsError) Is(target error) bool {
+	return target == ErrBudgetExists
+}
 
+// CreateBulk creates the budgets of several months in a single transaction;
+// either every budget is created or none are. Every row whose month already
+// has a budget is reported in a DuplicateBudgetsError.
+func (r *BudgetRepository) CreateBulk(
+	reqs []models.CreateBudgetLimitRequest,
+) ([]models.BudgetLimit, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var conflicts []BudgetConflict
+	seen := map[[2]int]int{}
+	ids := make([]int64, 0, len(reqs))
+	for i, req := range reqs {
+		conflict := BudgetConflict{Index: i, Month: req.Month, Year: req.Year}
+		if first, ok := seen[[2]int{req.Year, req.Month}]; ok {
+			conflict.Error = fmt.Sprintf("same month as budgets[%d]", first)
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+		seen[[2]int{req.Year, req.Month}] = i
+
+		id, err := r.insertBudget(tx, &req)
+		if isUniqueConstraintError(err) {
+			conflict.Error = ErrBudgetExists.Error()
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create budget %d: %w", i, err)
+		}
+		ids = append(ids, id)
+	}
+	if len(conflicts) > 0 {
+		return nil, &DuplicateBudgetsError{Conflicts: conflicts}
+	}
+
+	budgets := make([]models.BudgetLimit, 0, len(ids))
+	for _, id := range ids {
+		created, err := getBudgetByID(tx, r.userID, id, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx, r.userID, models.AuditEntityBudget, id, models.AuditCreate, nil, created); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, *created)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budgets: %w", err)
+	}
+	for i := range budgets {
+		r.publishChange(models.AuditCreate, nil, &budgets[i])
+	}
+	return budgets, nil
+}
+
+// insertBudget adds the budget of req with its notification thresholds and
+// returns its ID. A request without a list of thresholds gets its single
+// threshold. A deleted budget of the month is removed for good first.
+func (r *BudgetRepository) insertBudget(db querier, req *models.CreateBudgetLimitRequest) (int64, error) {
+	thresholds := req.NotificationThresholds
+	if len(thresholds) == 0 {
+		thresholds = []float64{req.NotificationThreshold}
+	}
+	if err := purgeDeletedBudget(db, r.userID, req.Month, req.Year); err != nil {
+		return 0, err
+	}
+	result, err := db.Exec(insertBudgetQuery,
+		r.userID, req.Month, req.Year, req.Amount, thresholds[0], req.RolloverUnspent, req.WeeklyLimit)
+	if err != nil {
+		return 0, err
+	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if err := setBudgetThresholds(db, id, thresholds); err != nil {
+		return 0, err
 	}
+	return id, nil
+}
 
-	return r.GetByID(id)
+// setBudgetThresholds replaces the notification thresholds of budget id
+func setBudgetThresholds(db querier, id int64, thresholds []float64) error {
+	if _, err := db.Exec(`DELETE FROM budget_limit_thresholds WHERE budget_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to clear budget thresholds: %w", err)
+	}
+	for _, threshold := range thresholds {
+		if _, err := db.Exec(`
+			INSERT OR IGNORE INTO budget_limit_thresholds (budget_id, threshold) VALUES (?, ?)
+		`, id, threshold); err != nil {
+			return fmt.Errorf("failed to set budget threshold: %w", err)
+		}
+	}
+	return nil
 }
 
-// GetByID retrieves a budget limit by ID
+// loadBudgetThresholds fills in the notification thresholds of budgets,
+// which must belong to userID. A budget without stored thresholds gets its
+// single threshold.
+func loadBudgetThresholds(db querier, userID int64, budgets ...*models.BudgetLimit) error {
+	query := `
+		SELECT t.budget_id, t.threshold
+		FROM budget_limit_thresholds t
+		JOIN budget_limits b ON b.id = t.budget_id
+		WHERE b.user_id = ?`
+	args := []any{userID}
+	if len(budgets) == 1 {
+		query += ` AND t.budget_id = ?`
+		args = append(args, budgets[0].ID)
+	}
+	rows, err := db.Query(query+` ORDER BY t.threshold`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query budget thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	thresholds := map[int64][]float64{}
+	for rows.Next() {
+		var id int64
+		var threshold float64
+		if err := rows.Scan(&id, &threshold); err != nil {
+			return fmt.Errorf("failed to scan budget threshold: %w", err)
+		}
+		thresholds[id] = append(thresholds[id], threshold)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating budget thresholds: %w", err)
+	}
+
+	for _, b := range budgets {
+		b.NotificationThresholds = thresholds[b.ID]
+		if len(b.NotificationThresholds) == 0 {
+			b.NotificationThresholds = []float64{b.NotificationThreshold}
+		}
+	}
+	return nil
+}
+
+// audit records a change to budget id and publishes it; pass nil for a
+// missing snapshot
+func (r *BudgetRepository) audit(action string, id int64, before, after *models.BudgetLimit) error {
+	var beforeSnapshot, afterSnapshot any
+	if before != nil {
+		beforeSnapshot = before
+	}
+	if after != nil {
+		afterSnapshot = after
+	}
+	if err := recordAudit(r.db, r.userID, models.AuditEntityBudget, id, action, beforeSnapshot, afterSnapshot); err != nil {
+		return err
+	}
+	r.publishChange(action, before, after)
+	return nil
+}
+
+// publishChange publishes the event of an audited change
+func (r *BudgetRepository) publishChange(action string, before, after *models.BudgetLimit) {
+	switch action {
+	case models.AuditCreate:
+		r.publish(events.BudgetCreated, after, 0)
+	case models.AuditUpdate:
+		r.publish(events.BudgetUpdated, after, 0)
+	case models.AuditDelete:
+		r.publish(events.BudgetDeleted, before, 0)
+	}
+}
+
+// GetByID retrieves a budget limit by ID. Deleted budgets are not found.
 func (r *BudgetRepository) GetByID(id int64) (*models.BudgetLimit, error) {
+	return r.getByID(id, false)
+}
+
+// getByID retrieves a budget limit by ID, including deleted ones when
+// includeDeleted is set
+func (r *BudgetRepository) getByID(id int64, includeDeleted bool) (*models.BudgetLimit, error) {
+	return getBudgetByID(r.db, r.userID, id, includeDeleted)
+}
+
+// getBudgetByID reads userID's budget id on db, which may be a transaction
+func getBudgetByID(db querier, userID, id int64, includeDeleted bool) (*models.BudgetLimit, error) {
 	query := `
-		SELECT id, month, year, amount, notification_threshold, created_at, updated_at
+		SELECT ` + budgetColumns + `
 		FROM budget_limits
-		WHERE id = ?
+		WHERE id = ? AND user_id = ?
 	`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 
 	var b models.BudgetLimit
-	err := r.db.QueryRow(query, id).Scan(
-		&b.ID, &b.Month, &b.Year, &b.Amount,
-		&b.NotificationThreshold, &b.CreatedAt, &b.UpdatedAt,
-	)
+	err := scanBudget(db.QueryRow(query, id, userID), &b)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrBudgetNotFound
 		}
 		return nil, fmt.Errorf("failed to get budget limit: %w", err)
 	}
+	if err := loadBudgetThresholds(db, userID, &b); err != nil {
+		return nil, err
+	}
 
 	return &b, nil
 }
 
-// GetAll retrieves all budget limits
+// BudgetFilter narrows List and Count. From and To are inclusive months; only
+// their year and month are used. Deleted budgets are left out unless
+// IncludeDeleted is set.
+type BudgetFilter struct {
+	Year           int
+	From           *time.Time
+	To             *time.Time
+	IncludeDeleted bool
+	Limit          int
+	Offset         int
+}
+
+// budgetMonthIndex orders the months of budget_limits across years, e.g. for
+// month ranges
+const budgetMonthIndex = "year * 12 + month"
+
+// GetAll retrieves all budget limits, newest first
 func (r *BudgetRepository) GetAll() ([]models.BudgetLimit, error) {
-	query := `
-		SELECT id, month, year, amount, notification_threshold, created_at, updated_at
-		FROM budget_limits
-		ORDER BY year DESC, month DESC
-	`
+	return r.List(BudgetFilter{})
+}
+
+// query builds the filtered query over the user's budget limits, newest first
+func (r *BudgetRepository) query(filter BudgetFilter) *selectBuilder {
+	b := newSelectBuilder("budget_limits", budgetColumns, "user_id", "year", budgetMonthIndex, "deleted_at").
+		order("year DESC, month DESC").
+		where("user_id", "=", r.userID)
+
+	if !filter.IncludeDeleted {
+		b.whereNull("deleted_at")
+	}
+	if filter.Year != 0 {
+		b.where("year", "=", filter.Year)
+	}
+	if filter.From != nil {
+		b.where(budgetMonthIndex, ">=", filter.From.Year()*12+int(filter.From.Month()))
+	}
+	if filter.To != nil {
+		b.where(budgetMonthIndex, "<=", filter.To.Year()*12+int(filter.To.Month()))
+	}
+	return b.page(filter.Limit, filter.Offset)
+}
 
-	rows, err := r.db.Query(query)
+// List returns the budget limits matching filter, newest first
+func (r *BudgetRepository) List(filter BudgetFilter) ([]models.BudgetLimit, error) {
+	query, args, err := r.query(filter).build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query budget limits: %w", err)
 	}
@@ -90,10 +429,7 @@ func (r *BudgetRepository) GetAll() ([]models.BudgetLimit, error) {
 	var budgets []models.BudgetLimit
 	for rows.Next() {
 		var b models.BudgetLimit
-		if err := rows.Scan(
-			&b.ID, &b.Month, &b.Year, &b.Amount,
-			&b.NotificationThreshold, &b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		if err := scanBudget(rows, &b); err != nil {
 			return nil, fmt.Errorf("failed to scan budget limit: %w", err)
 		}
 		budgets = append(budgets, b)
@@ -102,10 +438,35 @@ func (r *BudgetRepository) GetAll() ([]models.BudgetLimit, error) {
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating budget limits: %w", err)
 	}
+	rows.Close()
+
+	scoped := make([]*models.BudgetLimit, len(budgets))
+	for i := range budgets {
+		scoped[i] = &budgets[i]
+	}
+	if len(scoped) > 0 {
+		if err := loadBudgetThresholds(r.db, r.userID, scoped...); err != nil {
+			return nil, err
+		}
+	}
 
 	return budgets, nil
 }
 
+// Count returns the number of budget limits matching filter, ignoring paging
+func (r *BudgetRepository) Count(filter BudgetFilter) (int, error) {
+	query, args, err := r.query(filter).buildCount()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count budget limits: %w", err)
+	}
+	return count, nil
+}
+
 // Update updates a budget limit
 func (r *BudgetRepository) Update(
 	id int64,
@@ -116,6 +477,7 @@ func (r *BudgetRepository) Update(
 	if err != nil {
 		return nil, err
 	}
+	before := *existing
 
 	// Apply updates
 	if req.Amount != nil {
@@ -123,28 +485,111 @@ func (r *BudgetRepository) Update(
 	}
 	if req.NotificationThreshold != nil {
 		existing.NotificationThreshold = *req.NotificationThreshold
+		existing.NotificationThresholds = []float64{*req.NotificationThreshold}
+	}
+	if len(req.NotificationThresholds) > 0 {
+		existing.NotificationThreshold = req.NotificationThresholds[0]
+		existing.NotificationThresholds = req.NotificationThresholds
+	}
+	if req.RolloverUnspent != nil {
+		existing.RolloverUnspent = *req.RolloverUnspent
+	}
+	if req.WeeklyLimit != nil {
+		existing.WeeklyLimit = *req.WeeklyLimit
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE budget_limits
-		SET amount = ?, notification_threshold = ?, updated_at = ?
-		WHERE id = ?
+		SET amount = ?, notification_threshold = ?, rollover_unspent = ?, weekly_limit = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
 	`
 
 	now := time.Now()
-	_, err = r.db.Exec(query, existing.Amount, existing.NotificationThreshold, now, id)
+	_, err = tx.Exec(query,
+		existing.Amount, existing.NotificationThreshold, existing.RolloverUnspent, existing.WeeklyLimit,
+		now, id, r.userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update budget limit: %w", err)
 	}
+	if err := setBudgetThresholds(tx, id, existing.NotificationThresholds); err != nil {
+		return nil, err
+	}
+	updated, err := getBudgetByID(tx, r.userID, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordBudgetChange(tx, &before, updated); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(tx, r.userID, models.AuditEntityBudget, id, models.AuditUpdate, &before, updated); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget limit: %w", err)
+	}
+	r.publishChange(models.AuditUpdate, &before, updated)
+	return updated, nil
+}
+
+// Close closes the month of a budget limit, so its actual expenses cannot be
+// changed until Reopen. Closing a closed month keeps when it was closed.
+func (r *BudgetRepository) Close(id int64) (*models.BudgetLimit, error) {
+	return r.setClosed(id, `COALESCE(closed_at, ?)`, time.Now().UTC())
+}
+
+// Reopen lets the actual expenses of a closed month be changed again
+func (r *BudgetRepository) Reopen(id int64) (*models.BudgetLimit, error) {
+	return r.setClosed(id, `?`, nil)
+}
+
+// setClosed sets closed_at to the expression closedAt with arg and records
+// the change in the audit log
+func (r *BudgetRepository) setClosed(id int64, closedAt string, arg any) (*models.BudgetLimit, error) {
+	before, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
 
-	return r.GetByID(id)
+	_, err = r.db.Exec(`
+		UPDATE budget_limits SET closed_at = `+closedAt+`, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, arg, time.Now(), id, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close budget limit: %w", err)
+	}
+
+	updated, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.audit(models.AuditUpdate, id, before, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
-// Delete deletes a budget limit
+// Delete moves a budget limit to the trash, from where Restore brings it
+// back with its thresholds and history. The budget of a closed month must be
+// reopened first, since deleting it would reopen the month.
 func (r *BudgetRepository) Delete(id int64) error {
-	query := `DELETE FROM budget_limits WHERE id = ?`
+	before, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if before.ClosedAt != nil {
+		return models.ErrMonthClosed
+	}
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.Exec(`
+		UPDATE budget_limits SET deleted_at = ?, updated_at = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, time.Now().UTC(), time.Now(), id, r.userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete budget limit: %w", err)
 	}
@@ -158,32 +603,310 @@ func (r *BudgetRepository) Delete(id int64) error {
 		return ErrBudgetNotFound
 	}
 
+	return r.audit(models.AuditDelete, id, before, nil)
+}
+
+// Restore brings back a deleted budget limit. Restoring a budget that is not
+// deleted returns it unchanged.
+func (r *BudgetRepository) Restore(id int64) (*models.BudgetLimit, error) {
+	before, err := r.getByID(id, true)
+	if err != nil {
+		return nil, err
+	}
+	if before.DeletedAt == nil {
+		return before, nil
+	}
+
+	if _, err := r.db.Exec(`
+		UPDATE budget_limits SET deleted_at = NULL, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, time.Now(), id, r.userID); err != nil {
+		return nil, fmt.Errorf("failed to restore budget limit: %w", err)
+	}
+
+	restored, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.audit(models.AuditUpdate, id, before, restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// purgeDeletedBudget removes userID's deleted budget of month/year for good,
+// so a new budget can take its month
+func purgeDeletedBudget(db querier, userID int64, month, year int) error {
+	var id int64
+	err := db.QueryRow(`
+		SELECT id FROM budget_limits
+		WHERE user_id = ? AND month = ? AND year = ? AND deleted_at IS NOT NULL
+	`, userID, month, year).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find deleted budget: %w", err)
+	}
+
+	// Deleted explicitly so nothing depends on the foreign_keys pragma
+	if _, err := db.Exec(`DELETE FROM budget_limit_history WHERE budget_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete budget history: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM budget_limit_thresholds WHERE budget_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete budget thresholds: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM budget_threshold_alerts WHERE budget_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete budget threshold alerts: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM budget_alert_escalations WHERE budget_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete budget alert escalations: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM budget_limits WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete budget limit: %w", err)
+	}
 	return nil
 }
 
 // GetByMonthYear retrieves a budget limit by month and year
 func (r *BudgetRepository) GetByMonthYear(month, year int) (*models.BudgetLimit, error) {
+	return getBudgetByMonthYear(r.db, r.userID, month, year)
+}
+
+// getBudgetByMonthYear reads userID's budget for a month on db, which may be
+// a transaction
+func getBudgetByMonthYear(db querier, userID int64, month, year int) (*models.BudgetLimit, error) {
 	query := `
-		SELECT id, month, year, amount, notification_threshold, created_at, updated_at
+		SELECT ` + budgetColumns + `
 		FROM budget_limits
-		WHERE month = ? AND year = ?
+		WHERE user_id = ? AND month = ? AND year = ? AND deleted_at IS NULL
 	`
 
 	var b models.BudgetLimit
-	err := r.db.QueryRow(query, month, year).Scan(
-		&b.ID, &b.Month, &b.Year, &b.Amount,
-		&b.NotificationThreshold, &b.CreatedAt, &b.UpdatedAt,
-	)
+	err := scanBudget(db.QueryRow(query, userID, month, year), &b)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrBudgetNotFound
 		}
 		return nil, fmt.Errorf("failed to get budget limit: %w", err)
 	}
+	if err := loadBudgetThresholds(db, userID, &b); err != nil {
+		return nil, err
+	}
 
 	return &b, nil
 }
 
+// GetYear returns the budget and the spending of every month of year, with
+// the annual totals. Expenses held for approval are not counted, as in the
+// monthly totals.
+func (r *BudgetRepository) GetYear(year int) (*models.BudgetYear, error) {
+	rows, err := r.db.Query(`
+		WITH RECURSIVE months(month) AS (
+			SELECT 1 UNION ALL SELECT month + 1 FROM months WHERE month < 12
+		)
+		SELECT m.month, b.amount, COALESCE(SUM(e.actual_amount), 0)
+		FROM months m
+		LEFT JOIN budget_limits b
+			ON b.user_id = ? AND b.year = ? AND b.month = m.month AND b.deleted_at IS NULL
+		LEFT JOIN actual_expenses e
+			ON e.user_id = ? AND e.year = ? AND e.month = m.month AND e.pending_approval = 0
+		GROUP BY m.month, b.amount
+		ORDER BY m.month
+	`, r.userID, year, r.userID, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget year: %w", err)
+	}
+	defer rows.Close()
+
+	result := &models.BudgetYear{Year: year, Months: []models.BudgetYearMonth{}}
+	for rows.Next() {
+		var m models.BudgetYearMonth
+		var amount sql.NullFloat64
+		if err := rows.Scan(&m.Month, &amount, &m.Spent); err != nil {
+			return nil, fmt.Errorf("failed to scan budget month: %w", err)
+		}
+		if amount.Valid {
+			remaining := amount.Float64 - m.Spent
+			m.Budget, m.Remaining = &amount.Float64, &remaining
+			result.TotalBudget += amount.Float64
+		}
+		result.TotalSpent += m.Spent
+		result.Months = append(result.Months, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budget months: %w", err)
+	}
+	return result, nil
+}
+
+// Rollover returns what budget carries over from the month before: the
+// previous month's budget amount less its spending, when budget has
+// RolloverUnspent set and the previous month has a budget and was not
+// overspent. Only the previous month's own amount counts, so unspent money
+// rolls over one month and does not pile up. Expenses held for approval are
+// not counted, as in the monthly totals.
+func (r *BudgetRepository) Rollover(budget *models.BudgetLimit) (float64, error) {
+	if !budget.RolloverUnspent {
+		return 0, nil
+	}
+	month, year := budget.Month-1, budget.Year
+	if month == 0 {
+		month, year = 12, year-1
+	}
+	previous, err := r.GetByMonthYear(month, year)
+	if errors.Is(err, ErrBudgetNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var spent float64
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses
+		WHERE user_id = ? AND month = ? AND year = ? AND pending_approval = 0
+	`, r.userID, month, year).Scan(&spent); err != nil {
+		return 0, fmt.Errorf("failed to sum previous month spending: %w", err)
+	}
+	return max(previous.Amount-spent, 0), nil
+}
+
+// Upsert atomically creates or updates the budget limit for req's month and
+// year and reports whether it was created. Without notification thresholds
+// the existing ones are kept, or a new budget gets the 0.8 default, and a nil
+// rollover setting or weekly cap keeps the existing one, or is off.
+func (r *BudgetRepository) Upsert(
+	req *models.UpsertBudgetLimitRequest,
+) (*models.BudgetLimit, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	if err := purgeDeletedBudget(tx, r.userID, req.Month, req.Year); err != nil {
+		return nil, false, err
+	}
+	before, err := getBudgetByMonthYear(tx, r.userID, req.Month, req.Year)
+	if err != nil && !errors.Is(err, ErrBudgetNotFound) {
+		return nil, false, err
+	}
+
+	query := `
+		INSERT INTO budget_limits (user_id, month, year, amount, notification_threshold, rollover_unspent, weekly_limit)
+		VALUES (?, ?, ?, ?, COALESCE(?, 0.8), COALESCE(?, 0), COALESCE(?, 0))
+		ON CONFLICT (user_id, month, year) DO UPDATE SET
+			amount = excluded.amount,
+			notification_threshold = COALESCE(?, notification_threshold),
+			rollover_unspent = COALESCE(?, rollover_unspent),
+			weekly_limit = COALESCE(?, weekly_limit),
+			updated_at = ?
+	`
+
+	thresholds := req.NotificationThresholds
+	if len(thresholds) == 0 && req.NotificationThreshold != nil {
+		thresholds = []float64{*req.NotificationThreshold}
+	}
+	var threshold *float64
+	if len(thresholds) > 0 {
+		threshold = &thresholds[0]
+	}
+
+	now := time.Now()
+	if _, err := tx.Exec(
+		query,
+		r.userID, req.Month, req.Year, req.Amount, threshold, req.RolloverUnspent, req.WeeklyLimit,
+		threshold, req.RolloverUnspent, req.WeeklyLimit, now,
+	); err != nil {
+		return nil, false, fmt.Errorf("failed to upsert budget limit: %w", err)
+	}
+
+	budget, err := getBudgetByMonthYear(tx, r.userID, req.Month, req.Year)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(thresholds) > 0 || before == nil {
+		if len(thresholds) == 0 {
+			thresholds = []float64{budget.NotificationThreshold}
+		}
+		if err := setBudgetThresholds(tx, budget.ID, thresholds); err != nil {
+			return nil, false, err
+		}
+		budget.NotificationThresholds = thresholds
+	}
+	action := models.AuditUpdate
+	var beforeSnapshot any
+	if before == nil {
+		action = models.AuditCreate
+	} else {
+		beforeSnapshot = before
+		if err := recordBudgetChange(tx, before, budget); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := recordAudit(tx, r.userID, models.AuditEntityBudget, budget.ID, action, beforeSnapshot, budget); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	r.publishChange(action, before, budget)
+	return budget, before == nil, nil
+}
+
+// recordBudgetChange adds a history row when the amount or threshold of a
+// budget changed from before to after
+func recordBudgetChange(db querier, before, after *models.BudgetLimit) error {
+	if before.Amount == after.Amount && before.NotificationThreshold == after.NotificationThreshold {
+		return nil
+	}
+	if _, err := db.Exec(`
+		INSERT INTO budget_limit_history
+			(budget_id, old_amount, new_amount, old_threshold, new_threshold, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, after.ID, before.Amount, after.Amount,
+		before.NotificationThreshold, after.NotificationThreshold, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record budget change: %w", err)
+	}
+	return nil
+}
+
+// History returns the changes of budget id, oldest first
+func (r *BudgetRepository) History(id int64) ([]models.BudgetLimitChange, error) {
+	if _, err := r.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, budget_id, old_amount, new_amount, old_threshold, new_threshold, changed_at
+		FROM budget_limit_history
+		WHERE budget_id = ?
+		ORDER BY changed_at, id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.BudgetLimitChange{}
+	for rows.Next() {
+		var c models.BudgetLimitChange
+		if err := rows.Scan(
+			&c.ID, &c.BudgetID, &c.OldAmount, &c.NewAmount,
+			&c.OldThreshold, &c.NewThreshold, &c.ChangedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan budget change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budget history: %w", err)
+	}
+	return changes, nil
+}
+
 // isUniqueConstraintError checks if the error is a unique constraint violation.
 // This works with libsql driver which returns SQLite-compatible error messages.
 func isUniqueConstraintError(err error) bool {
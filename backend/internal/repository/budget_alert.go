@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"fmt"
+	"time"
+)
+
+// PercentageUsed returns how much of budget its month's spending used, in
+// percent, measured like the budget status: approved expenses against the
+// amount plus what rolled over from the month before
+func (r *BudgetRepository) PercentageUsed(budget *models.BudgetLimit) (float64, error) {
+	rollover, err := r.Rollover(budget)
+	if err != nil {
+		return 0, err
+	}
+	var spent float64
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses
+		WHERE user_id = ? AND month = ? AND year = ? AND pending_approval = 0
+	`, r.userID, budget.Month, budget.Year).Scan(&spent); err != nil {
+		return 0, fmt.Errorf("failed to sum budget spending: %w", err)
+	}
+	if effective := budget.Amount + rollover; effective > 0 {
+		return spent / effective * 100, nil
+	}
+	return 0, nil
+}
+
+// FiredThresholds returns the thresholds of the budget whose alert was sent
+// and not cleared since, lowest first
+func (r *BudgetRepository) FiredThresholds(budgetID int64) ([]float64, error) {
+	rows, err := r.db.Query(`
+		SELECT a.threshold FROM budget_threshold_alerts a
+		JOIN budget_limits b ON b.id = a.budget_id
+		WHERE a.budget_id = ? AND b.user_id = ?
+		ORDER BY a.threshold
+	`, budgetID, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fired thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	fired := []float64{}
+	for rows.Next() {
+		var threshold float64
+		if err := rows.Scan(&threshold); err != nil {
+			return nil, fmt.Errorf("failed to scan fired threshold: %w", err)
+		}
+		fired = append(fired, threshold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fired thresholds: %w", err)
+	}
+	return fired, nil
+}
+
+// RecordThresholdAlert marks threshold of the budget as fired and reports
+// whether it was not marked before, so each crossing alerts once. A new
+// crossing is published.
+func (r *BudgetRepository) RecordThresholdAlert(budgetID int64, threshold float64) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT OR IGNORE INTO budget_threshold_alerts (budget_id, threshold, fired_at)
+		VALUES (?, ?, ?)
+	`, budgetID, threshold, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to record threshold alert: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record threshold alert: %w", err)
+	}
+	if n > 0 && r.events != nil {
+		budget, err := r.GetByID(budgetID)
+		if err != nil {
+			return true, err
+		}
+		r.publish(events.BudgetThresholdCrossed, budget, threshold)
+	}
+	return n > 0, nil
+}
+
+// ClearThresholdAlert resets threshold of the budget, so crossing it again
+// alerts again, and reports whether it was fired
+func (r *BudgetRepository) ClearThresholdAlert(budgetID int64, threshold float64) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM budget_threshold_alerts WHERE budget_id = ? AND threshold = ?
+	`, budgetID, threshold)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear threshold alert: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear threshold alert: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RecordAlertEscalation marks the budget as alerted at level and reports
+// whether the alert is due: level was not alerted since it was last cleared,
+// or, with a repeat, its last alert is at least repeat old
+func (r *BudgetRepository) RecordAlertEscalation(
+	budgetID int64,
+	level string,
+	now time.Time,
+	repeat time.Duration,
+) (bool, error) {
+	query := `
+		INSERT OR IGNORE INTO budget_alert_escalations (budget_id, level, alerted_at)
+		VALUES (?, ?, ?)
+	`
+	args := []any{budgetID, level, now.UTC()}
+	if repeat > 0 {
+		query = `
+			INSERT INTO budget_alert_escalations (budget_id, level, alerted_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (budget_id, level) DO UPDATE SET alerted_at = excluded.alerted_at
+			WHERE alerted_at <= ?
+		`
+		args = append(args, now.Add(-repeat).UTC())
+	}
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to record alert escalation: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record alert escalation: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ClearAlertEscalation resets level of the budget, so reaching it again
+// alerts right away, and reports whether it was alerted
+func (r *BudgetRepository) ClearAlertEscalation(budgetID int64, level string) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM budget_alert_escalations WHERE budget_id = ? AND level = ?
+	`, budgetID, level)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear alert escalation: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear alert escalation: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"slices"
+	"testing"
+)
+
+func TestBudgetRepository_PublishTo(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	bus := events.NewBus()
+	var published []events.Event
+	bus.Subscribe(func(e events.Event) { published = append(published, e) })
+	budgets := NewBudgetRepository(db)
+	budgets.PublishTo(bus)
+	budgets = budgets.ForUser(7)
+
+	budget, err := budgets.Create(&models.CreateBudgetLimitRequest{Month: 3, Year: 2025, Amount: 1000})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	amount := 900.0
+	if _, err := budgets.Update(budget.ID, &models.UpdateBudgetLimitRequest{Amount: &amount}); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	if err := budgets.Delete(budget.ID); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := budgets.Restore(budget.ID); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if _, _, err := budgets.Upsert(&models.UpsertBudgetLimitRequest{Month: 4, Year: 2025, Amount: 800}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	if recorded, err := budgets.RecordThresholdAlert(budget.ID, 0.8); err != nil || !recorded {
+		t.Fatalf("RecordThresholdAlert() = %v, %v", recorded, err)
+	}
+	// An alert recorded before is no new crossing
+	if _, err := budgets.RecordThresholdAlert(budget.ID, 0.8); err != nil {
+		t.Fatalf("RecordThresholdAlert() error: %v", err)
+	}
+
+	types := make([]events.Type, 0, len(published))
+	for _, e := range published {
+		types = append(types, e.Type)
+		if e.UserID != 7 || e.Budget == nil || e.At.IsZero() {
+			t.Errorf("Expected the user, budget and time of %s, got %+v", e.Type, e)
+		}
+	}
+	want := []events.Type{
+		events.BudgetCreated, events.BudgetUpdated, events.BudgetDeleted, events.BudgetUpdated,
+		events.BudgetCreated, events.BudgetThresholdCrossed,
+	}
+	if !slices.Equal(types, want) {
+		t.Fatalf("Expected events %v, got %v", want, types)
+	}
+	if updated := published[1].Budget; updated.Amount != 900 {
+		t.Errorf("Expected the budget after the update, got %+v", updated)
+	}
+	if crossed := published[5]; crossed.Budget.ID != budget.ID || crossed.Threshold != 0.8 {
+		t.Errorf("Unexpected crossing %+v", crossed)
+	}
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+const commentColumns = `c.id, c.actual_expense_id, c.user_id, COALESCE(u.email, ''), c.body, c.created_at, c.updated_at`
+
+// CommentRepository handles expense_comments database operations.
+// It only sees the comments on the expenses one user owns or oversees, e.g.
+// as the owner's allowance parent (see ForUser), and only that user's own
+// comments can be changed.
+type CommentRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewCommentRepository creates a new CommentRepository
+func NewCommentRepository(db *DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that reads and writes comments as userID
+func (r *CommentRepository) ForUser(userID int64) *CommentRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// List returns the comments on expenseID, oldest first, or
+// models.ErrExpenseNotFound when the expense does not exist
+func (r *CommentRepository) List(expenseID int64) ([]models.Comment, error) {
+	if err := r.checkExpense(expenseID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+commentColumns+`
+		FROM expense_comments c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.actual_expense_id = ?
+		ORDER BY c.created_at, c.id
+	`, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var c models.Comment
+		if err := rows.Scan(
+			&c.ID, &c.ActualExpenseID, &c.UserID, &c.Author, &c.Body, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+	return comments, nil
+}
+
+// Create adds a comment by the scoped user to expenseID
+func (r *CommentRepository) Create(expenseID int64, body string) (*models.Comment, error) {
+	if err := r.checkExpense(expenseID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(`
+		INSERT INTO expense_comments (actual_expense_id, user_id, body, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, expenseID, r.userID, body, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return r.get(expenseID, id)
+}
+
+// Update replaces the text of comment id on expenseID. Comments by other
+// users are reported as not found.
+func (r *CommentRepository) Update(expenseID, id int64, body string) (*models.Comment, error) {
+	if err := r.checkExpense(expenseID); err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE expense_comments SET body = ?, updated_at = ?
+		WHERE id = ? AND actual_expense_id = ? AND user_id = ?
+	`, body, time.Now().UTC(), id, expenseID, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	if err := commentAffected(result); err != nil {
+		return nil, err
+	}
+	return r.get(expenseID, id)
+}
+
+// Delete removes comment id from expenseID; only its author can delete it
+func (r *CommentRepository) Delete(expenseID, id int64) error {
+	if err := r.checkExpense(expenseID); err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`
+		DELETE FROM expense_comments
+		WHERE id = ? AND actual_expense_id = ? AND user_id = ?
+	`, id, expenseID, r.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return commentAffected(result)
+}
+
+func (r *CommentRepository) get(expenseID, id int64) (*models.Comment, error) {
+	var c models.Comment
+	err := r.db.QueryRow(`
+		SELECT `+commentColumns+`
+		FROM expense_comments c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.id = ? AND c.actual_expense_id = ?
+	`, id, expenseID).Scan(
+		&c.ID, &c.ActualExpenseID, &c.UserID, &c.Author, &c.Body, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return &c, nil
+}
+
+// checkExpense returns models.ErrExpenseNotFound unless the scoped user owns
+// or oversees expenseID
+func (r *CommentRepository) checkExpense(expenseID int64) error {
+	_, err := householdExpenseOwner(r.db, r.userID, expenseID)
+	return err
+}
+
+func commentAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
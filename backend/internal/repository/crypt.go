@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"budget-tracker/internal/config"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldKeySize is the length of the AES-256 field encryption key in bytes
+const FieldKeySize = 32
+
+// encryptedPrefix marks an encrypted column value; the version allows the
+// format to change without guessing
+const encryptedPrefix = "enc:v1:"
+
+var ErrFieldKeyMissing = errors.New("database has encrypted fields but FIELD_ENCRYPTION_KEY is not set")
+
+// FieldCipher encrypts sensitive text columns (item names, stores, audit
+// snapshots and raw receipt responses) with AES-256-GCM, so a copy of the
+// database does not reveal the purchase history without the key
+type FieldCipher struct {
+	aead cipher.AEAD
+}
+
+// NewFieldCipher creates a FieldCipher from a FieldKeySize byte key
+func NewFieldCipher(key []byte) (*FieldCipher, error) {
+	if len(key) != FieldKeySize {
+		return nil, fmt.Errorf("field encryption key must be %d bytes, got %d", FieldKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FieldCipher{aead: aead}, nil
+}
+
+// NewFieldCipherFromEnv creates a FieldCipher from the base64 key in
+// FIELD_ENCRYPTION_KEY, or returns nil when it is not set
+func NewFieldCipherFromEnv() (*FieldCipher, error) {
+	encoded := config.Get("FIELD_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("FIELD_ENCRYPTION_KEY is not valid base64: %w", err)
+	}
+	return NewFieldCipher(key)
+}
+
+// fieldCipher encrypts the sensitive columns written by every repository; nil
+// stores them in plain text
+var fieldCipher *FieldCipher
+
+// SetFieldCipher enables field encryption for all repositories, or disables
+// it when c is nil. Call it once at startup, before the repositories are used.
+// Values written without encryption stay readable either way.
+func SetFieldCipher(c *FieldCipher) {
+	fieldCipher = c
+}
+
+// encryptingFields reports whether sensitive columns are stored encrypted, in
+// which case SQL cannot compare or search them
+func encryptingFields() bool {
+	return fieldCipher != nil
+}
+
+// sealField encrypts value for column, which is bound to the ciphertext so
+// values cannot be swapped between columns. It returns value unchanged when
+// encryption is disabled.
+func sealField(column, value string) (string, error) {
+	if fieldCipher == nil {
+		return value, nil
+	}
+	nonce := make([]byte, fieldCipher.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := fieldCipher.aead.Seal(nonce, nonce, []byte(value), []byte(column))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// sealNullableField is sealField for nullable columns; nil stays NULL
+func sealNullableField(column string, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	sealed, err := sealField(column, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// openField decrypts a value read from column. Values written before
+// encryption was enabled are returned as they are.
+func openField(column, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+	if fieldCipher == nil {
+		return "", ErrFieldKeyMissing
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	nonceSize := fieldCipher.aead.NonceSize()
+	if err != nil || len(sealed) < nonceSize {
+		return "", fmt.Errorf("malformed encrypted %s", column)
+	}
+	plain, err := fieldCipher.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s (wrong FIELD_ENCRYPTION_KEY?): %w", column, err)
+	}
+	return string(plain), nil
+}
+
+// sealNameAndSource encrypts the item_name and source of an expense
+func sealNameAndSource(itemName, source string) (string, string, error) {
+	itemName, err := sealField("item_name", itemName)
+	if err != nil {
+		return "", "", err
+	}
+	source, err = sealField("source", source)
+	if err != nil {
+		return "", "", err
+	}
+	return itemName, source, nil
+}
+
+// openNameAndSource decrypts the item_name and source of an expense in place
+func openNameAndSource(itemName, source *string) error {
+	var err error
+	if *itemName, err = openField("item_name", *itemName); err != nil {
+		return err
+	}
+	*source, err = openField("source", *source)
+	return err
+}
+
+// containsFold is the in-memory equivalent of the LIKE search used when the
+// searched columns are encrypted
+func containsFold(value, search string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(search))
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// enableFieldEncryption sets a field cipher for the rest of the test
+func enableFieldEncryption(t *testing.T) {
+	t.Helper()
+
+	c, err := NewFieldCipher(bytes.Repeat([]byte("k"), FieldKeySize))
+	if err != nil {
+		t.Fatalf("Failed to create field cipher: %v", err)
+	}
+	SetFieldCipher(c)
+	t.Cleanup(func() { SetFieldCipher(nil) })
+}
+
+func TestFieldCipher(t *testing.T) {
+	if _, err := NewFieldCipher([]byte("short")); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+
+	enableFieldEncryption(t)
+	sealed, err := sealField("source", "Publix")
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if !strings.HasPrefix(sealed, encryptedPrefix) || strings.Contains(sealed, "Publix") {
+		t.Fatalf("Expected an encrypted value, got %q", sealed)
+	}
+	if again, _ := sealField("source", "Publix"); again == sealed {
+		t.Error("Expected a fresh nonce for every value")
+	}
+
+	if plain, err := openField("source", sealed); err != nil || plain != "Publix" {
+		t.Errorf("Expected Publix, got %q, %v", plain, err)
+	}
+	if _, err := openField("item_name", sealed); err == nil {
+		t.Error("Expected a value sealed for another column to be rejected")
+	}
+	if plain, err := openField("source", "Costco"); err != nil || plain != "Costco" {
+		t.Errorf("Expected plain text values to be read as they are, got %q, %v", plain, err)
+	}
+
+	SetFieldCipher(nil)
+	if _, err := openField("source", sealed); !errors.Is(err, ErrFieldKeyMissing) {
+		t.Errorf("Expected ErrFieldKeyMissing without a key, got %v", err)
+	}
+}
+
+func TestFieldEncryption_Repositories(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM expected_expenses`); err != nil {
+		t.Fatalf("Failed to clear seed data: %v", err)
+	}
+
+	repo := NewActualExpenseRepository(db)
+	create := func(itemName, source string) *models.ActualExpense {
+		t.Helper()
+		expense, err := repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: itemName, Source: source, ActualAmount: 3,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptNumber: 7,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		return expense
+	}
+	legacy := create("Milk", "Publix")
+
+	enableFieldEncryption(t)
+	bread := create(strings.Repeat("Sourdough ", 25)+"bread", "Publix")
+	if _, err := NewExpectedExpenseRepository(db).Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Bread", Source: "Publix", ExpectedAmount: 3, ExpenseType: models.ExpenseTypeWeekly,
+	}); err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+
+	var stored string
+	if err := db.QueryRow(`SELECT source FROM actual_expenses WHERE id = ?`, bread.ID).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read column: %v", err)
+	}
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		t.Errorf("Expected the store to be encrypted, got %q", stored)
+	}
+
+	// Search runs on the decrypted values, old plain text rows included
+	for _, search := range []string{"publix", "bread"} {
+		found, err := repo.List(ExpenseFilter{Search: search, Limit: 1})
+		if err != nil {
+			t.Fatalf("Failed to search %q: %v", search, err)
+		}
+		count, err := repo.Count(ExpenseFilter{Search: search})
+		if err != nil {
+			t.Fatalf("Failed to count %q: %v", search, err)
+		}
+		want := map[string]int{"publix": 2, "bread": 1}[search]
+		if len(found) != 1 || count != want {
+			t.Errorf("Search %q: expected 1 of %d, got %d of %d", search, want, len(found), count)
+		}
+	}
+	expected, err := NewExpectedExpenseRepository(db).List(ExpenseFilter{Search: "BREAD"})
+	if err != nil || len(expected) != 1 || expected[0].Source != "Publix" {
+		t.Errorf("Expected the decrypted expected expense, got %+v, %v", expected, err)
+	}
+
+	entries, err := NewAuditRepository(db).List(AuditFilter{EntityID: bread.ID, Entity: models.AuditEntityActualExpense})
+	if err != nil || len(entries) != 1 || !strings.Contains(string(entries[0].After), "Sourdough") {
+		t.Errorf("Expected the decrypted audit snapshot, got %+v, %v", entries, err)
+	}
+
+	// Both items name the same store, so the receipt number is not a duplicate
+	report, err := NewMaintenanceRepository(db).CheckIntegrity()
+	if err != nil || report.Counts[IntegrityDuplicateReceipt] != 0 {
+		t.Errorf("Expected no duplicate receipts, got %+v, %v", report, err)
+	}
+
+	encrypted, err := NewMaintenanceRepository(db).EncryptFields(false)
+	if err != nil {
+		t.Fatalf("EncryptFields failed: %v", err)
+	}
+	if encrypted.Columns[0].Rows != 1 || encrypted.Columns[1].Rows != 1 {
+		t.Errorf("Expected the legacy expense to be encrypted, got %+v", encrypted.Columns)
+	}
+	if err := db.QueryRow(`SELECT item_name FROM actual_expenses WHERE id = ?`, legacy.ID).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read column: %v", err)
+	}
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		t.Errorf("Expected the legacy item name to be encrypted, got %q", stored)
+	}
+	if got, err := repo.GetByID(legacy.ID); err != nil || got.ItemName != "Milk" {
+		t.Errorf("Expected Milk, got %+v, %v", got, err)
+	}
+
+	again, err := NewMaintenanceRepository(db).EncryptFields(false)
+	if err != nil {
+		t.Fatalf("Second EncryptFields failed: %v", err)
+	}
+	for _, c := range again.Columns {
+		if c.Rows != 0 {
+			t.Errorf("Expected nothing left to encrypt, got %+v", again.Columns)
+		}
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"budget-tracker/internal/config"
 	_ "github.com/tursodatabase/go-libsql"
 )
 
@@ -22,6 +24,9 @@ const (
 // DB holds the database connection
 type DB struct {
 	*sql.DB
+	// environment is the APP_ENV the connection was configured for; the
+	// database must be tagged with it before migrations run
+	environment string
 }
 
 // Config holds database configuration
@@ -30,11 +35,15 @@ type Config struct {
 	LocalPath   string // Path for local mode (e.g., "./data/budget.db")
 	DatabaseURL string // Turso URL for remote mode (e.g., "libsql://xxx.turso.io")
 	AuthToken   string // Turso auth token for remote mode
+	// Environment (APP_ENV) replaces EnvironmentPlaceholder in LocalPath and
+	// DatabaseURL, and is checked against the tag of the database before
+	// migrations run. Empty disables both.
+	Environment string
 }
 
 // NewConfigFromEnv creates a Config from environment variables
 func NewConfigFromEnv() Config {
-	mode := os.Getenv("TURSO_MODE")
+	mode := config.Get("TURSO_MODE")
 	if mode == "" {
 		mode = "local" // Default to local mode
 	}
@@ -42,14 +51,31 @@ func NewConfigFromEnv() Config {
 	return Config{
 		Mode:        Mode(mode),
 		LocalPath:   getEnvOrDefault("TURSO_LOCAL_PATH", "./data/budget.db"),
-		DatabaseURL: os.Getenv("TURSO_DATABASE_URL"),
-		AuthToken:   os.Getenv("TURSO_AUTH_TOKEN"),
+		DatabaseURL: config.Get("TURSO_DATABASE_URL"),
+		AuthToken:   config.Get("TURSO_AUTH_TOKEN"),
+		Environment: strings.ToLower(config.Get("APP_ENV")),
 	}
 }
 
+// NewReplicaConfigFromEnv returns the configuration of the read replica that
+// serves report queries, from TURSO_REPLICA_URL and TURSO_REPLICA_AUTH_TOKEN
+// (default: TURSO_AUTH_TOKEN). ok is false when no replica is configured.
+func NewReplicaConfigFromEnv() (cfg Config, ok bool) {
+	url := config.Get("TURSO_REPLICA_URL")
+	if url == "" {
+		return Config{}, false
+	}
+	return Config{
+		Mode:        ModeRemote,
+		DatabaseURL: url,
+		AuthToken:   getEnvOrDefault("TURSO_REPLICA_AUTH_TOKEN", config.Get("TURSO_AUTH_TOKEN")),
+		Environment: strings.ToLower(config.Get("APP_ENV")),
+	}, true
+}
+
 // getEnvOrDefault returns the environment variable value or a default
 func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := config.Get(key); value != "" {
 		return value
 	}
 	return defaultValue
@@ -57,6 +83,17 @@ func getEnvOrDefault(key, defaultValue string) string {
 
 // NewDB creates a new database connection
 func NewDB(cfg Config) (*DB, error) {
+	if err := validateEnvironment(cfg.Environment); err != nil {
+		return nil, err
+	}
+	var err error
+	if cfg.LocalPath, err = expandEnvironment(cfg.LocalPath, cfg.Environment); err != nil {
+		return nil, err
+	}
+	if cfg.DatabaseURL, err = expandEnvironment(cfg.DatabaseURL, cfg.Environment); err != nil {
+		return nil, err
+	}
+
 	var dsn string
 
 	switch cfg.Mode {
@@ -119,7 +156,7 @@ func NewDB(cfg Config) (*DB, error) {
 
 	log.Printf("Database connected successfully (mode: %s)", cfg.Mode)
 
-	return &DB{db}, nil
+	return &DB{DB: db, environment: cfg.Environment}, nil
 }
 
 // Close closes the database connection
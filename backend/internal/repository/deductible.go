@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// DeductibleReport lists the scoped user's approved deductible expenses of
+// year by receipt, with the other lines of their receipts
+func (r *ActualExpenseRepository) DeductibleReport(year int) (*models.DeductibleReport, error) {
+	deductible, approved := true, false
+	expenses, err := r.List(ExpenseFilter{Year: year, Deductible: &deductible, Pending: &approved})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deductible expenses: %w", err)
+	}
+
+	// The other lines of the receipts, whatever month they count toward
+	var numbers []any
+	for _, e := range expenses {
+		if e.ReceiptNumber != 0 && !slices.Contains(numbers, any(e.ReceiptNumber)) {
+			numbers = append(numbers, e.ReceiptNumber)
+		}
+	}
+	lines := map[int64][]models.ActualExpense{}
+	if len(numbers) > 0 {
+		query, args, err := r.query(ExpenseFilter{}).whereIn("receipt_number", numbers).build()
+		if err != nil {
+			return nil, err
+		}
+		rows, err := r.db.Query(query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query receipt lines: %w", err)
+		}
+		defer rows.Close()
+		receiptLines, err := scanActualExpenses(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan receipt lines: %w", err)
+		}
+		for _, line := range receiptLines {
+			lines[line.ReceiptNumber] = append(lines[line.ReceiptNumber], line)
+		}
+	}
+
+	report := &models.DeductibleReport{Year: year, Receipts: []models.DeductibleReceipt{}}
+	seen := map[int64]bool{}
+	for _, e := range expenses {
+		report.Items++
+		report.Total += e.ActualAmount
+		if e.ReceiptNumber != 0 && seen[e.ReceiptNumber] {
+			continue
+		}
+		seen[e.ReceiptNumber] = true
+
+		receipt := models.DeductibleReceipt{
+			ReceiptNumber: e.ReceiptNumber,
+			ReceiptDate:   e.ReceiptDate,
+			Source:        e.Source,
+			Items:         []models.ActualExpense{e},
+		}
+		if e.ReceiptNumber != 0 {
+			receipt.Items = lines[e.ReceiptNumber]
+		}
+		for _, item := range receipt.Items {
+			receipt.Total += item.ActualAmount
+			if item.Deductible && !item.PendingApproval {
+				receipt.Deductible += item.ActualAmount
+			}
+		}
+		receipt.Total, receipt.Deductible = roundCents(receipt.Total), roundCents(receipt.Deductible)
+		report.Receipts = append(report.Receipts, receipt)
+	}
+	report.Total = roundCents(report.Total)
+
+	slices.SortStableFunc(report.Receipts, func(a, b models.DeductibleReceipt) int {
+		return cmp.Or(a.ReceiptDate.Compare(b.ReceiptDate), cmp.Compare(a.ReceiptNumber, b.ReceiptNumber))
+	})
+	return report, nil
+}
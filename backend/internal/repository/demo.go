@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// DemoEnvironment is the environment tag of the demo database. ClearData
+// only runs on a database with this tag, so demo mode cannot wipe real data.
+const DemoEnvironment = "demo"
+
+var ErrNotDemoDatabase = errors.New("not a demo database")
+
+// NewDemoConfigFromEnv returns the configuration of the demo database: a
+// local file at DEMO_DB_PATH (default: ./data/demo.db), separate from the
+// TURSO_* database
+func NewDemoConfigFromEnv() Config {
+	return Config{
+		Mode:        ModeLocal,
+		LocalPath:   getEnvOrDefault("DEMO_DB_PATH", "./data/demo.db"),
+		Environment: DemoEnvironment,
+	}
+}
+
+// NewDemoDB connects to the demo database of cfg. An existing file must
+// already be tagged as the demo database; anything else is refused, so
+// pointing DEMO_DB_PATH at real data fails instead of getting it reset.
+func NewDemoDB(cfg Config) (*DB, error) {
+	_, statErr := os.Stat(cfg.LocalPath)
+	db, err := NewDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if statErr != nil {
+		// A new file, claimed by the first migration
+		return db, nil
+	}
+
+	tag, err := db.Environment()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if tag != DemoEnvironment {
+		db.Close()
+		return nil, fmt.Errorf("%w: %s is tagged %q; use a new file for DEMO_DB_PATH",
+			ErrNotDemoDatabase, cfg.LocalPath, tag)
+	}
+	return db, nil
+}
+
+// ClearData deletes every row of every table but the migration bookkeeping,
+// leaving an empty, migrated database. It refuses to run unless the database
+// is tagged as the demo database.
+func (r *MaintenanceRepository) ClearData() error {
+	tag, err := environmentTag(r.db)
+	if err != nil {
+		return err
+	}
+	if tag != DemoEnvironment {
+		return fmt.Errorf("%w: refusing to clear a database tagged %q", ErrNotDemoDatabase, tag)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+			AND name NOT IN ('schema_migrations', 'schema_environment')
+		ORDER BY name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	// Foreign keys are checked at commit, when every table is empty, so the
+	// tables can be cleared in any order
+	if _, err := tx.Exec(`PRAGMA defer_foreign_keys = ON`); err != nil {
+		return fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+	// The names come from sqlite_master, so quoting them is enough
+	for _, name := range names {
+		if _, err := tx.Exec(`DELETE FROM "` + name + `"`); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+	}
+	// Restart the IDs; the table only exists once an AUTOINCREMENT table has rows
+	var hasSequence bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_sequence')
+	`).Scan(&hasSequence); err != nil {
+		return fmt.Errorf("failed to check sqlite_sequence: %w", err)
+	}
+	if hasSequence {
+		if _, err := tx.Exec(`DELETE FROM sqlite_sequence`); err != nil {
+			return fmt.Errorf("failed to reset IDs: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit demo reset: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestNewDemoDB(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{Mode: ModeLocal, LocalPath: dir + "/demo.db", Environment: DemoEnvironment}
+
+	// A new file is claimed by the first migration
+	db, err := NewDemoDB(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open a new demo database: %v", err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	db.Close()
+	if db, err = NewDemoDB(cfg); err != nil {
+		t.Fatalf("Failed to reopen the demo database: %v", err)
+	}
+	db.Close()
+
+	// Real data is refused, tagged or not
+	for _, env := range []string{"prod", ""} {
+		path := dir + "/budget-" + env + ".db"
+		real, err := NewDB(Config{Mode: ModeLocal, LocalPath: path, Environment: env})
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		if err := real.RunMigrations(); err != nil {
+			t.Fatalf("Failed to run migrations: %v", err)
+		}
+		real.Close()
+
+		cfg.LocalPath = path
+		if _, err := NewDemoDB(cfg); !errors.Is(err, ErrNotDemoDatabase) {
+			t.Errorf("Expected a database tagged %q to be refused, got %v", env, err)
+		}
+	}
+}
+
+func TestMaintenanceRepository_ClearData(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	budgets := NewBudgetRepository(db)
+	budget, err := budgets.Create(&models.CreateBudgetLimitRequest{
+		Month: 1, Year: 2026, Amount: 1000, NotificationThreshold: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	goal, err := NewSpendingGoalRepository(db).Create(&models.SpendingGoalRequest{
+		Name: "Groceries", MonthlyLimit: 400, StartMonth: 1, StartYear: 2026, Months: 3,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create goal: %v", err)
+	}
+	if _, err := NewSpendingGoalRepository(db).RecordBreak(goal.ID, 1, 2026); err != nil {
+		t.Fatalf("Failed to record break: %v", err)
+	}
+
+	maintenance := NewMaintenanceRepository(db)
+	if err := maintenance.ClearData(); !errors.Is(err, ErrNotDemoDatabase) {
+		t.Fatalf("Expected an untagged database to be refused, got %v", err)
+	}
+	if _, err := budgets.GetByID(budget.ID); err != nil {
+		t.Fatalf("Expected the budget to be kept, got %v", err)
+	}
+
+	if err := db.SetEnvironment(DemoEnvironment); err != nil {
+		t.Fatalf("Failed to tag database: %v", err)
+	}
+	if err := maintenance.ClearData(); err != nil {
+		t.Fatalf("ClearData failed: %v", err)
+	}
+	stats, err := maintenance.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	for _, table := range stats.Tables {
+		if table.Rows != 0 && table.Name != "schema_migrations" && table.Name != "schema_environment" {
+			t.Errorf("Expected %s to be empty, got %d rows", table.Name, table.Rows)
+		}
+	}
+
+	// The schema is kept and IDs restart
+	budget, err = budgets.Create(&models.CreateBudgetLimitRequest{
+		Month: 1, Year: 2026, Amount: 1000, NotificationThreshold: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create budget after clearing: %v", err)
+	}
+	if budget.ID != 1 {
+		t.Errorf("Expected IDs to restart at 1, got %d", budget.ID)
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var ErrEnvironmentMismatch = errors.New("database belongs to another environment")
+
+// EnvironmentPlaceholder is replaced with APP_ENV in TURSO_LOCAL_PATH,
+// TURSO_DATABASE_URL and TURSO_REPLICA_URL, so one configuration selects a
+// different database per environment
+const EnvironmentPlaceholder = "{env}"
+
+// environmentPattern limits environment names to what fits in a hostname or
+// file name
+var environmentPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// validateEnvironment checks an environment name; empty means untagged
+func validateEnvironment(name string) error {
+	if name != "" && !environmentPattern.MatchString(name) {
+		return fmt.Errorf("invalid APP_ENV %q: use lowercase letters, digits and dashes", name)
+	}
+	return nil
+}
+
+// expandEnvironment replaces EnvironmentPlaceholder in value with env
+func expandEnvironment(value, env string) (string, error) {
+	if !strings.Contains(value, EnvironmentPlaceholder) {
+		return value, nil
+	}
+	if env == "" {
+		return "", fmt.Errorf("%q contains %s but APP_ENV is not set", value, EnvironmentPlaceholder)
+	}
+	return strings.ReplaceAll(value, EnvironmentPlaceholder, env), nil
+}
+
+// Environment returns the environment the database is tagged with, or ""
+// when it is not tagged
+func (db *DB) Environment() (string, error) {
+	return environmentTag(db)
+}
+
+// SetEnvironment tags the database with name, replacing any existing tag.
+// Use it to reuse a database in another environment on purpose, e.g. after
+// copying production into staging.
+func (db *DB) SetEnvironment(name string) error {
+	if name == "" {
+		return errors.New("environment name is required")
+	}
+	if err := validateEnvironment(name); err != nil {
+		return err
+	}
+	if err := createEnvironmentTable(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`
+		INSERT INTO schema_environment (id, name) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, tagged_at = CURRENT_TIMESTAMP
+	`, name); err != nil {
+		return fmt.Errorf("failed to tag database: %w", err)
+	}
+	return nil
+}
+
+// checkEnvironment refuses a database tagged for another environment than
+// the one the connection was configured for (APP_ENV). With tag set, an
+// untagged database is tagged with the configured environment, so the first
+// environment to migrate it claims it. Without APP_ENV nothing is checked.
+func (db *DB) checkEnvironment(tag bool) error {
+	if db.environment == "" {
+		return nil
+	}
+
+	current, err := environmentTag(db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current == db.environment:
+		return nil
+	case current != "":
+		return fmt.Errorf(
+			"%w: it is tagged %q but APP_ENV is %q; check the database URL, or run "+
+				`"budgetctl set-environment" to reuse it on purpose`,
+			ErrEnvironmentMismatch, current, db.environment,
+		)
+	case !tag:
+		return nil
+	}
+
+	if err := db.SetEnvironment(db.environment); err != nil {
+		return err
+	}
+	log.Printf("Tagged database as environment %q", db.environment)
+	return nil
+}
+
+// environmentTag reads the environment tag; databases created before tags
+// existed have no schema_environment table and are untagged
+func environmentTag(db querier) (string, error) {
+	var exists bool
+	if err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_environment')
+	`).Scan(&exists); err != nil {
+		return "", fmt.Errorf("failed to read environment tag: %w", err)
+	}
+	if !exists {
+		return "", nil
+	}
+
+	var name string
+	err := db.QueryRow(`SELECT name FROM schema_environment WHERE id = 1`).Scan(&name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to read environment tag: %w", err)
+	}
+	return name, nil
+}
+
+// createEnvironmentTable creates the single row table holding the tag. It is
+// not a migration because the tag is checked before migrations run.
+func createEnvironmentTable(db querier) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_environment (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			name TEXT NOT NULL,
+			tagged_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_environment table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEnvironmentTag(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// Without APP_ENV nothing is tagged
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if env, err := db.Environment(); err != nil || env != "" {
+		t.Fatalf("Expected an untagged database, got %q, %v", env, err)
+	}
+
+	// The first environment to migrate the database claims it
+	db.environment = "dev"
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations as dev: %v", err)
+	}
+	if env, err := db.Environment(); err != nil || env != "dev" {
+		t.Fatalf("Expected the database to be tagged dev, got %q, %v", env, err)
+	}
+
+	db.environment = "prod"
+	if err := db.RunMigrations(); !errors.Is(err, ErrEnvironmentMismatch) {
+		t.Errorf("Expected prod migrations against the dev database to be refused, got %v", err)
+	}
+	if _, err := db.VerifyMigrations(); !errors.Is(err, ErrEnvironmentMismatch) {
+		t.Errorf("Expected the migration check to be refused too, got %v", err)
+	}
+
+	if err := db.SetEnvironment("prod"); err != nil {
+		t.Fatalf("Failed to retag: %v", err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		t.Errorf("Expected a retagged database to migrate, got %v", err)
+	}
+	if err := db.SetEnvironment("Prod Copy"); err == nil {
+		t.Error("Expected an invalid environment name to be rejected")
+	}
+}
+
+func TestNewDB_EnvironmentPlaceholder(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewDB(Config{Mode: ModeLocal, LocalPath: dir + "/budget-{env}.db"}); err == nil {
+		t.Error("Expected a placeholder without APP_ENV to be rejected")
+	}
+	if _, err := NewDB(Config{Mode: ModeLocal, LocalPath: dir + "/budget.db", Environment: "../prod"}); err == nil {
+		t.Error("Expected an invalid environment name to be rejected")
+	}
+
+	db, err := NewDB(Config{Mode: ModeLocal, LocalPath: dir + "/budget-{env}.db", Environment: "staging"})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	var file string
+	if err := db.QueryRow(`SELECT file FROM pragma_database_list WHERE name = 'main'`).Scan(&file); err != nil {
+		t.Fatalf("Failed to read database file: %v", err)
+	}
+	if file != dir+"/budget-staging.db" {
+		t.Errorf("Expected the staging database file, got %q", file)
+	}
+}
@@ -2,84 +2,245 @@ package repository
 
 import (
 	"budget-tracker/internal/models"
+	"cmp"
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 )
 
 var ErrExpenseNotFound = errors.New("expense not found")
 
-// ExpectedExpenseRepository handles expected_expenses database operations
+// ExpectedExpenseRepository handles expected_expenses database operations.
+// It only sees the expected expenses of one user, see ForUser.
 type ExpectedExpenseRepository struct {
-	db *DB
+	db     *DB
+	userID int64
 }
 
-// NewExpectedExpenseRepository creates a new ExpectedExpenseRepository
+// NewExpectedExpenseRepository creates a new ExpectedExpenseRepository for
+// the shared workspace (user 0) used while authentication is disabled
 func NewExpectedExpenseRepository(db *DB) *ExpectedExpenseRepository {
 	return &ExpectedExpenseRepository{db: db}
 }
 
+// ForUser returns a copy of the repository that reads and writes userID's
+// expected expenses
+func (r *ExpectedExpenseRepository) ForUser(userID int64) *ExpectedExpenseRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
 // Create creates a new expected expense
 func (r *ExpectedExpenseRepository) Create(
 	req *models.CreateExpectedExpenseRequest,
 ) (*models.ExpectedExpense, error) {
+	id, err := insertExpectedExpense(r.db, r.userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(id)
+}
+
+func insertExpectedExpense(
+	db querier,
+	userID int64,
+	req *models.CreateExpectedExpenseRequest,
+) (int64, error) {
+	itemName, source, err := sealNameAndSource(req.ItemName, req.Source)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		INSERT INTO expected_expenses (item_name, source, expected_amount, expense_type)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO expected_expenses (user_id, item_name, source, expected_amount, expense_type, category, due_day, recurrence, starts_on, auto_generate)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.Exec(
+	result, err := db.Exec(
 		query,
-		req.ItemName,
-		req.Source,
+		userID,
+		itemName,
+		source,
 		req.ExpectedAmount,
 		req.ExpenseType,
+		sql.NullString{String: req.Category, Valid: req.Category != ""},
+		req.DueDay,
+		sql.NullString{String: req.Recurrence, Valid: req.Recurrence != ""},
+		req.StartsOn,
+		req.AutoGenerate,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create expected expense: %w", err)
+		return 0, fmt.Errorf("failed to create expected expense: %w", err)
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
 	}
-
-	return r.GetByID(id)
+	created, err := getExpectedExpense(db, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordAudit(db, userID, models.AuditEntityExpectedExpense, id, models.AuditCreate, nil, created); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
 // GetByID retrieves an expected expense by ID
 func (r *ExpectedExpenseRepository) GetByID(id int64) (*models.ExpectedExpense, error) {
-	query := `
-		SELECT id, item_name, source, expected_amount, expense_type, created_at, updated_at
-		FROM expected_expenses
-		WHERE id = ?
-	`
+	return getExpectedExpense(r.db, r.userID, id)
+}
 
-	var e models.ExpectedExpense
-	err := r.db.QueryRow(query, id).Scan(
-		&e.ID, &e.ItemName, &e.Source, &e.ExpectedAmount,
-		&e.ExpenseType, &e.CreatedAt, &e.UpdatedAt,
-	)
+// getExpectedExpense reads expected expense id of userID on db, which may be
+// a transaction
+func getExpectedExpense(db querier, userID, id int64) (*models.ExpectedExpense, error) {
+	query := `SELECT ` + expectedExpenseColumns + ` FROM expected_expenses WHERE id = ? AND user_id = ?`
+
+	e, err := scanExpectedExpense(db.QueryRow(query, id, userID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrExpenseNotFound
 		}
 		return nil, fmt.Errorf("failed to get expected expense: %w", err)
 	}
+	return e, nil
+}
+
+const expectedExpenseColumns = `id, item_name, source, expected_amount, expense_type, category, due_day, recurrence, starts_on, auto_generate, is_active, created_at, updated_at`
 
+func scanExpectedExpense(row interface{ Scan(...any) error }) (*models.ExpectedExpense, error) {
+	var e models.ExpectedExpense
+	var category sql.NullString
+	var dueDay sql.NullInt64
+	var recurrence sql.NullString
+	var startsOn sql.NullTime
+	if err := row.Scan(
+		&e.ID, &e.ItemName, &e.Source, &e.ExpectedAmount,
+		&e.ExpenseType, &category, &dueDay, &recurrence, &startsOn, &e.AutoGenerate, &e.IsActive, &e.CreatedAt, &e.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := openNameAndSource(&e.ItemName, &e.Source); err != nil {
+		return nil, err
+	}
+	e.Category = category.String
+	if dueDay.Valid {
+		day := int(dueDay.Int64)
+		e.DueDay = &day
+	}
+	e.Recurrence = recurrence.String
+	if startsOn.Valid {
+		e.StartsOn = &startsOn.Time
+	}
 	return &e, nil
 }
 
-// GetAll retrieves all expected expenses
-func (r *ExpectedExpenseRepository) GetAll() ([]models.ExpectedExpense, error) {
-	query := `
-		SELECT id, item_name, source, expected_amount, expense_type, created_at, updated_at
-		FROM expected_expenses
-		ORDER BY created_at DESC
-	`
+// expectedExpenseQuery builds a filtered query over expected_expenses.
+// Only the columns listed here may be filtered on; expected expenses have no
+// month or receipt date, so those filters yield ErrInvalidFilter.
+func expectedExpenseQuery(filter ExpenseFilter) *selectBuilder {
+	b := newSelectBuilder(
+		"expected_expenses",
+		expectedExpenseColumns,
+		"id", "expense_type", "item_name", "source", "user_id", "is_active", "category",
+	).order("created_at DESC")
+
+	if filter.Type != "" {
+		b.where("expense_type", "=", filter.Type)
+	}
+	if filter.Category != "" {
+		// LIKE without wildcards matches the category in any case
+		b.where("category", "LIKE", likeEscape(filter.Category))
+	}
+	if filter.Active != nil {
+		b.where("is_active", "=", *filter.Active)
+	}
+	if filter.Month != 0 || filter.Year != 0 || filter.ReceiptNumber != 0 || filter.From != nil || filter.To != nil {
+		b.err = fmt.Errorf("%w: expected expenses have no month or receipt date", ErrInvalidFilter)
+	}
+	if filter.Search != "" {
+		b.whereAny([]string{"item_name", "source"}, "LIKE", likePattern(filter.Search))
+	}
+	return b.page(filter.Limit, filter.Offset)
+}
+
+// query scopes expectedExpenseQuery to the repository's user
+func (r *ExpectedExpenseRepository) query(filter ExpenseFilter) *selectBuilder {
+	return expectedExpenseQuery(filter).where("user_id", "=", r.userID)
+}
+
+// List retrieves the expected expenses matching filter, newest first
+func (r *ExpectedExpenseRepository) List(filter ExpenseFilter) ([]models.ExpectedExpense, error) {
+	if filter.Search != "" && encryptingFields() {
+		if err := checkPage(filter.Limit, filter.Offset); err != nil {
+			return nil, err
+		}
+		matches, err := r.searchDecrypted(filter)
+		if err != nil {
+			return nil, err
+		}
+		return pageSlice(matches, filter.Limit, filter.Offset), nil
+	}
+	return r.list(r.query(filter))
+}
+
+// searchDecrypted applies filter.Search after decrypting, since LIKE cannot
+// see into encrypted columns. It ignores paging.
+func (r *ExpectedExpenseRepository) searchDecrypted(filter ExpenseFilter) ([]models.ExpectedExpense, error) {
+	search := filter.Search
+	filter.Search, filter.Limit, filter.Offset = "", 0, 0
+	expenses, err := r.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.ExpectedExpense
+	for _, e := range expenses {
+		if containsFold(e.ItemName, search) || containsFold(e.Source, search) {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
+
+// idBatchSize bounds the number of ids bound in one IN (...) lookup
+const idBatchSize = 500
+
+// GetByIDs returns the expected expenses with the given ids keyed by id,
+// leaving out ids that do not exist. It costs one query per idBatchSize ids
+// rather than one per id.
+func (r *ExpectedExpenseRepository) GetByIDs(ids []int64) (map[int64]models.ExpectedExpense, error) {
+	found := make(map[int64]models.ExpectedExpense, len(ids))
+	for start := 0; start < len(ids); start += idBatchSize {
+		batch := ids[start:min(start+idBatchSize, len(ids))]
+		values := make([]any, len(batch))
+		for i, id := range batch {
+			values[i] = id
+		}
 
-	rows, err := r.db.Query(query)
+		expenses, err := r.list(r.query(ExpenseFilter{}).whereIn("id", values))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range expenses {
+			found[e.ID] = e
+		}
+	}
+	return found, nil
+}
+
+func (r *ExpectedExpenseRepository) list(b *selectBuilder) ([]models.ExpectedExpense, error) {
+	query, args, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query expected expenses: %w", err)
 	}
@@ -87,14 +248,11 @@ func (r *ExpectedExpenseRepository) GetAll() ([]models.ExpectedExpense, error) {
 
 	var expenses []models.ExpectedExpense
 	for rows.Next() {
-		var e models.ExpectedExpense
-		if err := rows.Scan(
-			&e.ID, &e.ItemName, &e.Source, &e.ExpectedAmount,
-			&e.ExpenseType, &e.CreatedAt, &e.UpdatedAt,
-		); err != nil {
+		e, err := scanExpectedExpense(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan expected expense: %w", err)
 		}
-		expenses = append(expenses, e)
+		expenses = append(expenses, *e)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -104,6 +262,30 @@ func (r *ExpectedExpenseRepository) GetAll() ([]models.ExpectedExpense, error) {
 	return expenses, nil
 }
 
+// Count returns the number of expected expenses matching filter, ignoring paging
+func (r *ExpectedExpenseRepository) Count(filter ExpenseFilter) (int, error) {
+	if filter.Search != "" && encryptingFields() {
+		matches, err := r.searchDecrypted(filter)
+		return len(matches), err
+	}
+
+	query, args, err := r.query(filter).buildCount()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count expected expenses: %w", err)
+	}
+	return count, nil
+}
+
+// GetAll retrieves all expected expenses
+func (r *ExpectedExpenseRepository) GetAll() ([]models.ExpectedExpense, error) {
+	return r.List(ExpenseFilter{})
+}
+
 // Update updates an expected expense
 func (r *ExpectedExpenseRepository) Update(
 	id int64,
@@ -114,6 +296,7 @@ func (r *ExpectedExpenseRepository) Update(
 	if err != nil {
 		return nil, err
 	}
+	before := *existing
 
 	// Apply updates
 	if req.ItemName != nil {
@@ -128,84 +311,319 @@ func (r *ExpectedExpenseRepository) Update(
 	if req.ExpenseType != nil {
 		existing.ExpenseType = *req.ExpenseType
 	}
+	if req.Category != nil {
+		existing.Category = *req.Category
+	}
+	if req.DueDay != nil {
+		existing.DueDay = req.DueDay
+		if *req.DueDay == 0 {
+			existing.DueDay = nil
+		}
+	}
+	if req.Recurrence != nil {
+		existing.Recurrence = *req.Recurrence
+	}
+	if req.StartsOn != nil {
+		existing.StartsOn = req.StartsOn
+	}
+	if req.AutoGenerate != nil {
+		existing.AutoGenerate = *req.AutoGenerate
+	}
+	if err := existing.ValidateSchedule(); err != nil {
+		return nil, err
+	}
+
+	itemName, source, err := sealNameAndSource(existing.ItemName, existing.Source)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
 		UPDATE expected_expenses
-		SET item_name = ?, source = ?, expected_amount = ?, expense_type = ?, updated_at = ?
-		WHERE id = ?
+		SET item_name = ?, source = ?, expected_amount = ?, expense_type = ?, category = ?,
+			due_day = ?, recurrence = ?, starts_on = ?, auto_generate = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
 	`
 
 	now := time.Now()
-	_, err = r.db.Exec(query, existing.ItemName, existing.Source, existing.ExpectedAmount,
-		existing.ExpenseType, now, id)
+	_, err = r.db.Exec(query, itemName, source, existing.ExpectedAmount, existing.ExpenseType,
+		sql.NullString{String: existing.Category, Valid: existing.Category != ""}, existing.DueDay, sql.NullString{String: existing.Recurrence, Valid: existing.Recurrence != ""}, existing.StartsOn, existing.AutoGenerate,
+		now, id, r.userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update expected expense: %w", err)
 	}
 
-	return r.GetByID(id)
+	updated, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(
+		r.db, r.userID, models.AuditEntityExpectedExpense, id, models.AuditUpdate, &before, updated,
+	); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Archive stops expected expense id from counting toward the expected
+// totals and from coming due, keeping it and its linked actual expenses
+func (r *ExpectedExpenseRepository) Archive(id int64) (*models.ExpectedExpense, error) {
+	return r.setActive(id, false)
+}
+
+// Unarchive makes an archived expected expense count and come due again
+func (r *ExpectedExpenseRepository) Unarchive(id int64) (*models.ExpectedExpense, error) {
+	return r.setActive(id, true)
+}
+
+// setActive sets is_active and records the change in the audit log
+func (r *ExpectedExpenseRepository) setActive(id int64, active bool) (*models.ExpectedExpense, error) {
+	before, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE expected_expenses SET is_active = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, active, time.Now(), id, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive expected expense: %w", err)
+	}
+
+	updated, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(
+		r.db, r.userID, models.AuditEntityExpectedExpense, id, models.AuditUpdate, before, updated,
+	); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteMode controls what happens to actual expenses linked to a deleted expected expense
+type DeleteMode string
+
+const (
+	DeleteUnlink   DeleteMode = "unlink"   // clear expected_expense_id on linked expenses
+	DeleteBlock    DeleteMode = "block"    // refuse to delete while expenses are linked
+	DeleteReassign DeleteMode = "reassign" // move linked expenses to another expected expense
+)
+
+// IsValid reports whether m is a known delete mode
+func (m DeleteMode) IsValid() bool {
+	return m == DeleteUnlink || m == DeleteBlock || m == DeleteReassign
+}
+
+// DeleteOptions configures DeleteWithOptions. ReassignTo is required for DeleteReassign.
+type DeleteOptions struct {
+	Mode       DeleteMode
+	ReassignTo int64
+}
+
+var (
+	ErrHasLinkedExpenses     = errors.New("expected expense has linked actual expenses")
+	ErrInvalidReassignTarget = errors.New("reassign target must be a different, existing expected expense")
+)
+
+// LinkedExpense is an actual expense that references an expected expense
+type LinkedExpense struct {
+	ID           int64     `json:"id"`
+	ItemName     string    `json:"item_name"`
+	ActualAmount float64   `json:"actual_amount"`
+	ReceiptDate  time.Time `json:"receipt_date"`
+}
+
+// LinkedExpensesError is returned by DeleteWithOptions in DeleteBlock mode.
+// It matches ErrHasLinkedExpenses with errors.Is.
+type LinkedExpensesError struct {
+	Dependents []LinkedExpense
 }
 
-// Delete deletes an expected expense
+func (e *LinkedExpensesError) Error() string {
+	return fmt.Sprintf("%s (%d)", ErrHasLinkedExpenses, len(e.Dependents))
+}
+
+func (e *LinkedExpensesError) Is(target error) bool {
+	return target == ErrHasLinkedExpenses
+}
+
+// Delete deletes an expected expense, unlinking any actual expenses that reference it
 func (r *ExpectedExpenseRepository) Delete(id int64) error {
-	query := `DELETE FROM expected_expenses WHERE id = ?`
+	return r.DeleteWithOptions(id, DeleteOptions{Mode: DeleteUnlink})
+}
+
+// DeleteWithOptions deletes an expected expense and handles linked actual
+// expenses according to opts.Mode, all in one transaction
+func (r *ExpectedExpenseRepository) DeleteWithOptions(id int64, opts DeleteOptions) error {
+	if !opts.Mode.IsValid() {
+		return fmt.Errorf("unknown delete mode %q", opts.Mode)
+	}
 
-	result, err := r.db.Exec(query, id)
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete expected expense: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	before, err := getExpectedExpense(tx, r.userID, id)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrExpenseNotFound
+	// Linked actual expenses change too, so their updates go in the audit log,
+	// and they may not be changed in a closed month
+	var linked []*models.ActualExpense
+	if opts.Mode != DeleteBlock {
+		if linked, err = r.linkedSnapshots(tx, id); err != nil {
+			return err
+		}
+		checked := make(map[[2]int]bool)
+		for _, expense := range linked {
+			if period := [2]int{expense.Month, expense.Year}; !checked[period] {
+				checked[period] = true
+				if err := checkMonthOpen(tx, r.userID, expense.Month, expense.Year); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	var exists bool
+	switch opts.Mode {
+	case DeleteBlock:
+		dependents, err := linkedExpenses(tx, id)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			return &LinkedExpensesError{Dependents: dependents}
+		}
+
+	case DeleteReassign:
+		if opts.ReassignTo == id {
+			return ErrInvalidReassignTarget
+		}
+		if err := tx.QueryRow(
+			`SELECT EXISTS (SELECT 1 FROM expected_expenses WHERE id = ? AND user_id = ?)`,
+			opts.ReassignTo, r.userID,
+		).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check reassign target: %w", err)
+		}
+		if !exists {
+			return ErrInvalidReassignTarget
+		}
+		if _, err := tx.Exec(`
+			UPDATE actual_expenses SET expected_expense_id = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE expected_expense_id = ?
+		`, opts.ReassignTo, id); err != nil {
+			return fmt.Errorf("failed to reassign linked expenses: %w", err)
+		}
+
+	case DeleteUnlink:
+		if _, err := tx.Exec(`
+			UPDATE actual_expenses SET expected_expense_id = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE expected_expense_id = ?
+		`, id); err != nil {
+			return fmt.Errorf("failed to unlink linked expenses: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM generated_expenses WHERE expected_expense_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete generated expense dates: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM expected_expenses WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete expected expense: %w", err)
+	}
+
+	for _, old := range linked {
+		updated, err := getActualExpense(tx, r.userID, old.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(
+			tx, r.userID, models.AuditEntityActualExpense, old.ID, models.AuditUpdate, old, updated,
+		); err != nil {
+			return err
+		}
+	}
+	if err := recordAudit(
+		tx, r.userID, models.AuditEntityExpectedExpense, id, models.AuditDelete, before, nil,
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delete: %w", err)
 	}
 
 	return nil
 }
 
-// GetByType retrieves expected expenses by type
-func (r *ExpectedExpenseRepository) GetByType(
-	expenseType models.ExpenseType,
-) ([]models.ExpectedExpense, error) {
-	query := `
-		SELECT id, item_name, source, expected_amount, expense_type, created_at, updated_at
-		FROM expected_expenses
-		WHERE expense_type = ?
-		ORDER BY created_at DESC
-	`
+// linkedSnapshots reads the scoped user's actual expenses that reference
+// expected expense id
+func (r *ExpectedExpenseRepository) linkedSnapshots(tx *sql.Tx, id int64) ([]*models.ActualExpense, error) {
+	dependents, err := linkedExpenses(tx, id)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := r.db.Query(query, expenseType)
+	snapshots := make([]*models.ActualExpense, 0, len(dependents))
+	for _, d := range dependents {
+		expense, err := getActualExpense(tx, r.userID, d.ID)
+		if errors.Is(err, models.ErrExpenseNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, expense)
+	}
+	return snapshots, nil
+}
+
+// linkedExpenses lists the actual expenses that reference expected expense id
+func linkedExpenses(tx *sql.Tx, id int64) ([]LinkedExpense, error) {
+	rows, err := tx.Query(`
+		SELECT id, item_name, actual_amount, receipt_date
+		FROM actual_expenses WHERE expected_expense_id = ?
+		ORDER BY receipt_date DESC, id
+	`, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query expected expenses by type: %w", err)
+		return nil, fmt.Errorf("failed to list linked expenses: %w", err)
 	}
 	defer rows.Close()
 
-	var expenses []models.ExpectedExpense
+	var dependents []LinkedExpense
 	for rows.Next() {
-		var e models.ExpectedExpense
-		if err := rows.Scan(
-			&e.ID, &e.ItemName, &e.Source, &e.ExpectedAmount,
-			&e.ExpenseType, &e.CreatedAt, &e.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan expected expense: %w", err)
+		var e LinkedExpense
+		if err := rows.Scan(&e.ID, &e.ItemName, &e.ActualAmount, &e.ReceiptDate); err != nil {
+			return nil, fmt.Errorf("failed to scan linked expense: %w", err)
+		}
+		if e.ItemName, err = openField("item_name", e.ItemName); err != nil {
+			return nil, err
 		}
-		expenses = append(expenses, e)
+		dependents = append(dependents, e)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating expected expenses: %w", err)
-	}
+	return dependents, rows.Err()
+}
 
-	return expenses, nil
+// GetByType retrieves expected expenses by type
+func (r *ExpectedExpenseRepository) GetByType(
+	expenseType models.ExpenseType,
+) ([]models.ExpectedExpense, error) {
+	return r.List(ExpenseFilter{Type: expenseType})
 }
 
-// GetMonthlyExpectedTotal calculates the expected monthly total
+// GetMonthlyExpectedTotal calculates the expected monthly total of the
+// active expected expenses
 // Weekly expenses are multiplied by 4 for monthly estimate
 func (r *ExpectedExpenseRepository) GetMonthlyExpectedTotal() (float64, error) {
-	expenses, err := r.GetAll()
+	active := true
+	expenses, err := r.List(ExpenseFilter{Active: &active})
 	if err != nil {
 		return 0, err
 	}
@@ -223,3 +641,23 @@ func (r *ExpectedExpenseRepository) GetMonthlyExpectedTotal() (float64, error) {
 
 	return totalMonthly, nil
 }
+
+// Due returns the active expected expenses due from from through to, both
+// included, in due date order
+func (r *ExpectedExpenseRepository) Due(from, to time.Time) ([]models.DueExpense, error) {
+	expenses, err := r.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	due := []models.DueExpense{}
+	for _, e := range expenses {
+		for _, date := range e.DueDates(from, to) {
+			due = append(due, models.DueExpense{ExpectedExpense: e, DueDate: date})
+		}
+	}
+	slices.SortStableFunc(due, func(a, b models.DueExpense) int {
+		return cmp.Or(a.DueDate.Compare(b.DueDate), cmp.Compare(a.ID, b.ID))
+	})
+	return due, nil
+}
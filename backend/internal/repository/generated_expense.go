@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UserIDs returns the users with active expected expenses set to auto_generate
+func (r *ExpectedExpenseRepository) UserIDs() ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT user_id FROM expected_expenses
+		WHERE auto_generate = 1 AND is_active = 1
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expected expense owners: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expected expense owner: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expected expense owners: %w", err)
+	}
+	return ids, nil
+}
+
+// GenerateDue creates the actual expense of every due date from from through
+// to of the scoped user's active expected expenses with auto_generate set, and
+// returns the ones created. Each due date is handled once, so deleting a
+// generated expense does not bring it back. A due date is skipped when an
+// expense linked to the item was entered for it, or when its month is closed.
+func (r *ExpectedExpenseRepository) GenerateDue(from, to time.Time) ([]models.ActualExpense, error) {
+	expenses, err := r.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var generated []models.ActualExpense
+	for _, e := range expenses {
+		if !e.AutoGenerate {
+			continue
+		}
+		for _, date := range e.DueDates(from, to) {
+			expense, err := r.generate(&e, date)
+			if err != nil {
+				return generated, fmt.Errorf("expected expense %d on %s: %w", e.ID, date.Format(time.DateOnly), err)
+			}
+			if expense != nil {
+				generated = append(generated, *expense)
+			}
+		}
+	}
+	return generated, nil
+}
+
+// generate creates the actual expense of e due on date, unless date was
+// handled before or is skipped, in which case it returns nil
+func (r *ExpectedExpenseRepository) generate(e *models.ExpectedExpense, date time.Time) (*models.ActualExpense, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT OR IGNORE INTO generated_expenses (expected_expense_id, due_date, created_at)
+		VALUES (?, ?, ?)
+	`, e.ID, date.Format(time.DateOnly), time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to record due date: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+
+	var entered bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM actual_expenses
+			WHERE user_id = ? AND expected_expense_id = ? AND receipt_date >= ? AND receipt_date < ?
+		)
+	`, r.userID, e.ID, date, date.AddDate(0, 0, 1)).Scan(&entered); err != nil {
+		return nil, fmt.Errorf("failed to check entered expenses: %w", err)
+	}
+
+	var expense *models.ActualExpense
+	if !entered {
+		id, err := insertActualExpense(tx, r.userID, &models.CreateActualExpenseRequest{
+			ItemName:          e.ItemName,
+			Source:            e.Source,
+			ActualAmount:      e.ExpectedAmount,
+			ExpenseType:       e.ExpenseType,
+			ExpectedExpenseID: &e.ID,
+			ReceiptDate:       &date,
+			AutoGenerated:     true,
+		})
+		switch {
+		case errors.Is(err, models.ErrMonthClosed):
+			// The totals of a closed month stay as they were closed
+		case err != nil:
+			return nil, fmt.Errorf("failed to create expense: %w", err)
+		default:
+			if _, err := tx.Exec(`
+				UPDATE generated_expenses SET actual_expense_id = ?
+				WHERE expected_expense_id = ? AND due_date = ?
+			`, id, e.ID, date.Format(time.DateOnly)); err != nil {
+				return nil, fmt.Errorf("failed to record generated expense: %w", err)
+			}
+			if expense, err = getActualExpense(tx, r.userID, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit generated expense: %w", err)
+	}
+	return expense, nil
+}
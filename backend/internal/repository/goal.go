@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrGoalNotFound = errors.New("spending goal not found")
+
+// SpendingGoalRepository handles multi-month spending goals. It manages the
+// goals of one user, see ForUser.
+type SpendingGoalRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewSpendingGoalRepository creates a new SpendingGoalRepository
+func NewSpendingGoalRepository(db *DB) *SpendingGoalRepository {
+	return &SpendingGoalRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that manages userID's goals
+func (r *SpendingGoalRepository) ForUser(userID int64) *SpendingGoalRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// UserIDs returns every user with spending goals
+func (r *SpendingGoalRepository) UserIDs() ([]int64, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT user_id FROM spending_goals ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query goal owners: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan goal owner: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating goal owners: %w", err)
+	}
+	return ids, nil
+}
+
+const goalColumns = `id, name, monthly_limit, expense_type, start_month, start_year, months, created_at`
+
+func scanGoal(row interface{ Scan(...any) error }) (*models.SpendingGoal, error) {
+	var g models.SpendingGoal
+	var expenseType sql.NullString
+	if err := row.Scan(
+		&g.ID, &g.Name, &g.MonthlyLimit, &expenseType,
+		&g.StartMonth, &g.StartYear, &g.Months, &g.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if expenseType.Valid {
+		t := models.ExpenseType(expenseType.String)
+		g.ExpenseType = &t
+	}
+	return &g, nil
+}
+
+// List returns the scoped user's goals, the latest start first
+func (r *SpendingGoalRepository) List() ([]models.SpendingGoal, error) {
+	rows, err := r.db.Query(`
+		SELECT `+goalColumns+`
+		FROM spending_goals WHERE user_id = ?
+		ORDER BY start_year DESC, start_month DESC, id DESC
+	`, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spending goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []models.SpendingGoal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan spending goal: %w", err)
+		}
+		goals = append(goals, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating spending goals: %w", err)
+	}
+	return goals, nil
+}
+
+// Get returns goal id of the scoped user
+func (r *SpendingGoalRepository) Get(id int64) (*models.SpendingGoal, error) {
+	g, err := scanGoal(r.db.QueryRow(`
+		SELECT `+goalColumns+`
+		FROM spending_goals WHERE id = ? AND user_id = ?
+	`, id, r.userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGoalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending goal: %w", err)
+	}
+	return g, nil
+}
+
+// Create adds a goal for the scoped user
+func (r *SpendingGoalRepository) Create(req *models.SpendingGoalRequest) (*models.SpendingGoal, error) {
+	var expenseType any
+	if req.ExpenseType != nil {
+		expenseType = string(*req.ExpenseType)
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO spending_goals
+			(user_id, name, monthly_limit, expense_type, start_month, start_year, months, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.userID, req.Name, req.MonthlyLimit, expenseType,
+		req.StartMonth, req.StartYear, req.Months, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spending goal: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return r.Get(id)
+}
+
+// Delete removes goal id of the scoped user and its recorded breaks
+func (r *SpendingGoalRepository) Delete(id int64) error {
+	if _, err := r.Get(id); err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleted explicitly so nothing depends on the foreign_keys pragma
+	for _, query := range []string{
+		`DELETE FROM spending_goal_breaks WHERE goal_id = ?`,
+		`DELETE FROM spending_goals WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("failed to delete spending goal: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit spending goal delete: %w", err)
+	}
+	return nil
+}
+
+// Progress returns the spending of goal id in each of its months up to the
+// one containing now. Expenses held for approval are not counted, as in the
+// monthly totals. A month counts as broken as soon as its spending goes over
+// the limit, even before it ends.
+func (r *SpendingGoalRepository) Progress(id int64, now time.Time) (*models.SpendingGoalProgress, error) {
+	goal, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	first := goal.StartYear*12 + goal.StartMonth - 1
+	last := first + goal.Months - 1
+	query := `
+		SELECT year, month, COALESCE(SUM(actual_amount), 0)
+		FROM actual_expenses
+		WHERE user_id = ? AND pending_approval = 0
+			AND year * 12 + month - 1 BETWEEN ? AND ?`
+	args := []any{r.userID, first, last}
+	if goal.ExpenseType != nil {
+		query += ` AND expense_type = ?`
+		args = append(args, string(*goal.ExpenseType))
+	}
+	query += ` GROUP BY year, month`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query goal spending: %w", err)
+	}
+	defer rows.Close()
+
+	spent := map[int]float64{}
+	for rows.Next() {
+		var year, month int
+		var amount float64
+		if err := rows.Scan(&year, &month, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan goal spending: %w", err)
+		}
+		spent[year*12+month-1] = amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating goal spending: %w", err)
+	}
+
+	now = now.UTC()
+	current := now.Year()*12 + int(now.Month()) - 1
+	progress := &models.SpendingGoalProgress{Goal: *goal, Months: []models.GoalMonth{}}
+	for i := 0; i < goal.Months && first+i <= current; i++ {
+		month, year := goal.Month(i)
+		m := models.GoalMonth{
+			Month:     month,
+			Year:      year,
+			Spent:     spent[first+i],
+			Remaining: goal.MonthlyLimit - spent[first+i],
+			Final:     first+i < current,
+		}
+		m.Met = m.Spent <= goal.MonthlyLimit
+		if m.Met {
+			progress.MonthsMet++
+			progress.Streak++
+		} else {
+			progress.MonthsBroken++
+			progress.Streak = 0
+		}
+		progress.TotalSpent += m.Spent
+		progress.Months = append(progress.Months, m)
+	}
+	progress.MonthsRemaining = goal.Months - len(progress.Months)
+	if len(progress.Months) > 0 {
+		progress.AverageSpent = progress.TotalSpent / float64(len(progress.Months))
+	}
+
+	switch {
+	case len(progress.Months) == 0:
+		progress.Status = models.GoalUpcoming
+	case progress.MonthsBroken > 0:
+		progress.Status = models.GoalBroken
+	case last < current:
+		progress.Status = models.GoalAchieved
+	default:
+		progress.Status = models.GoalOnTrack
+	}
+	return progress, nil
+}
+
+// RecordBreak marks a month of goal goalID as broken and reports whether it
+// was not marked before, so each broken month is notified once
+func (r *SpendingGoalRepository) RecordBreak(goalID int64, month, year int) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT OR IGNORE INTO spending_goal_breaks (goal_id, month, year, notified_at)
+		VALUES (?, ?, ?, ?)
+	`, goalID, month, year, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to record goal break: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record goal break: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSpendingGoalRepository_Progress(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	expenses := NewActualExpenseRepository(db).ForUser(1)
+	spend := func(amount float64, expenseType models.ExpenseType, month time.Month) {
+		t.Helper()
+		date := time.Date(2025, month, 10, 12, 0, 0, 0, time.UTC)
+		if _, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ActualAmount: amount,
+			ExpenseType: expenseType, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+	spend(500, models.ExpenseTypeWeekly, time.January)
+	spend(700, models.ExpenseTypeWeekly, time.February)
+	spend(300, models.ExpenseTypeMonthly, time.March) // another type
+	spend(200, models.ExpenseTypeWeekly, time.March)
+	spend(900, models.ExpenseTypeWeekly, time.May) // after the goal
+
+	weekly := models.ExpenseTypeWeekly
+	goals := NewSpendingGoalRepository(db).ForUser(1)
+	goal, err := goals.Create(&models.SpendingGoalRequest{
+		Name: "Groceries", MonthlyLimit: 600, ExpenseType: &weekly,
+		StartMonth: 1, StartYear: 2025, Months: 4,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create goal: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		wantStatus string
+		wantMonths int
+		wantMet    int
+		wantStreak int
+		wantTotal  float64
+	}{
+		{name: "before the start", now: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), wantStatus: models.GoalUpcoming},
+		{name: "first month", now: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), wantStatus: models.GoalOnTrack, wantMonths: 1, wantMet: 1, wantStreak: 1, wantTotal: 500},
+		{name: "broken", now: time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), wantStatus: models.GoalBroken, wantMonths: 3, wantMet: 2, wantStreak: 1, wantTotal: 1400},
+		{name: "ended", now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), wantStatus: models.GoalBroken, wantMonths: 4, wantMet: 3, wantStreak: 2, wantTotal: 1400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress, err := goals.Progress(goal.ID, tt.now)
+			if err != nil {
+				t.Fatalf("Progress() error: %v", err)
+			}
+			if progress.Status != tt.wantStatus || len(progress.Months) != tt.wantMonths ||
+				progress.MonthsMet != tt.wantMet || progress.Streak != tt.wantStreak ||
+				progress.TotalSpent != tt.wantTotal {
+				t.Errorf("Unexpected progress %+v", progress)
+			}
+			if progress.MonthsRemaining != goal.Months-tt.wantMonths {
+				t.Errorf("Expected %d months remaining, got %d", goal.Months-tt.wantMonths, progress.MonthsRemaining)
+			}
+		})
+	}
+
+	// All spending counts when the goal has no expense type
+	all, err := goals.Create(&models.SpendingGoalRequest{
+		Name: "Everything", MonthlyLimit: 1000, StartMonth: 3, StartYear: 2025, Months: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create goal: %v", err)
+	}
+	progress, err := goals.Progress(all.ID, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Progress() error: %v", err)
+	}
+	if progress.Status != models.GoalAchieved || progress.TotalSpent != 500 || !progress.Months[0].Final {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+
+	// Goals are scoped to their user
+	if _, err := NewSpendingGoalRepository(db).ForUser(2).Progress(goal.ID, time.Now()); !errors.Is(err, ErrGoalNotFound) {
+		t.Errorf("Expected ErrGoalNotFound for another user, got %v", err)
+	}
+
+	if recorded, err := goals.RecordBreak(goal.ID, 2, 2025); err != nil || !recorded {
+		t.Fatalf("Expected the first break to be recorded, got %v, %v", recorded, err)
+	}
+	if recorded, err := goals.RecordBreak(goal.ID, 2, 2025); err != nil || recorded {
+		t.Errorf("Expected a repeated break to be ignored, got %v, %v", recorded, err)
+	}
+	if err := goals.Delete(goal.ID); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	var breaks int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM spending_goal_breaks`).Scan(&breaks); err != nil || breaks != 0 {
+		t.Errorf("Expected the breaks to be deleted with the goal, got %d, %v", breaks, err)
+	}
+}
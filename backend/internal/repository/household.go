@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// The users of a server form one household. Besides its owner, an expense
+// is seen by the members who oversee the owner: the owner's allowance
+// parent, and the admins, who approve held expenses.
+
+// oversees reports whether userID oversees the expenses of ownerID, as
+// ownerID's allowance parent or as an admin. No one oversees themself, and
+// allowance sub-accounts oversee no one.
+func oversees(db querier, userID, ownerID int64) (bool, error) {
+	if userID == ownerID {
+		return false, nil
+	}
+	var ok bool
+	if err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM allowance_accounts WHERE user_id = ? AND parent_id = ?)
+			OR EXISTS (
+				SELECT 1 FROM users
+				WHERE id = ? AND is_admin = 1 AND id NOT IN (SELECT user_id FROM allowance_accounts)
+			)
+	`, ownerID, userID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check household: %w", err)
+	}
+	return ok, nil
+}
+
+// householdExpenseOwner returns the owner of expense id when userID owns or
+// oversees it, and models.ErrExpenseNotFound otherwise
+func householdExpenseOwner(db querier, userID, id int64) (int64, error) {
+	var ownerID int64
+	err := db.QueryRow(`SELECT user_id FROM actual_expenses WHERE id = ?`, id).Scan(&ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.ErrExpenseNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expense owner: %w", err)
+	}
+	if ownerID == userID {
+		return ownerID, nil
+	}
+	ok, err := oversees(db, userID, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, models.ErrExpenseNotFound
+	}
+	return ownerID, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"fmt"
+)
+
+// ImportGroup is a set of actual expenses imported together, optionally with
+// a new expected expense they are all linked to
+type ImportGroup struct {
+	Expected *models.CreateExpectedExpenseRequest
+	Expenses []models.CreateActualExpenseRequest
+}
+
+// ImportResult summarizes an Import run
+type ImportResult struct {
+	ExpenseCount       int                      `json:"expense_count"`
+	ExpectedExpenses   []models.ExpectedExpense `json:"expected_expenses"`
+	FirstReceiptNumber int64                    `json:"first_receipt_number,omitempty"`
+	LastReceiptNumber  int64                    `json:"last_receipt_number,omitempty"`
+}
+
+// ImportRepository stores expenses imported from other budgeting apps
+type ImportRepository struct {
+	db       *DB
+	userID   int64
+	expected *ExpectedExpenseRepository
+}
+
+func NewImportRepository(db *DB) *ImportRepository {
+	return &ImportRepository{db: db, expected: NewExpectedExpenseRepository(db)}
+}
+
+// ForUser returns a copy of the repository that imports into userID's expenses
+func (r *ImportRepository) ForUser(userID int64) *ImportRepository {
+	return &ImportRepository{db: r.db, userID: userID, expected: r.expected.ForUser(userID)}
+}
+
+// Import creates every group in a single transaction; either everything is
+// imported or nothing is. Imported transactions have no receipt, so each
+// expense gets its own receipt number, continuing after the highest one in use.
+func (r *ImportRepository) Import(groups []ImportGroup) (*ImportResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	receiptNumber, err := nextReceiptNumber(tx, r.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{ExpectedExpenses: []models.ExpectedExpense{}}
+	var expectedIDs []int64
+	for _, g := range groups {
+		var expectedID *int64
+		if g.Expected != nil {
+			id, err := insertExpectedExpense(tx, r.userID, g.Expected)
+			if err != nil {
+				return nil, err
+			}
+			expectedID = &id
+			expectedIDs = append(expectedIDs, id)
+		}
+
+		for i := range g.Expenses {
+			req := g.Expenses[i]
+			req.ExpectedExpenseID = expectedID
+			req.ReceiptNumber = receiptNumber
+			if _, err := insertActualExpense(tx, r.userID, &req); err != nil {
+				return nil, fmt.Errorf("failed to import expense %q: %w", req.ItemName, err)
+			}
+
+			if result.FirstReceiptNumber == 0 {
+				result.FirstReceiptNumber = receiptNumber
+			}
+			result.LastReceiptNumber = receiptNumber
+			result.ExpenseCount++
+			receiptNumber++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range expectedIDs {
+		expense, err := r.expected.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		result.ExpectedExpenses = append(result.ExpectedExpenses, *expense)
+	}
+
+	return result, nil
+}
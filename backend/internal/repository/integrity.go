@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Integrity check names
+const (
+	IntegrityMonthYear         = "month_year_mismatch"
+	IntegrityNegativeTotal     = "negative_total"
+	IntegrityDuplicateReceipt  = "duplicate_receipt_number"
+	IntegrityBudgetThreshold   = "budget_threshold_out_of_range"
+	IntegrityOrphanedReference = "orphaned_reference"
+)
+
+// IntegrityIssue is a single inconsistency found by CheckIntegrity
+type IntegrityIssue struct {
+	Check       string `json:"check"`
+	ID          int64  `json:"id,omitempty"` // expense or budget id, when the issue is about one row
+	Description string `json:"description"`
+}
+
+// IntegrityReport lists the inconsistencies found by CheckIntegrity
+type IntegrityReport struct {
+	CheckedAt time.Time        `json:"checked_at"`
+	OK        bool             `json:"ok"`
+	Counts    map[string]int   `json:"counts"`
+	Issues    []IntegrityIssue `json:"issues"`
+}
+
+func (r *IntegrityReport) add(check string, id int64, format string, args ...any) {
+	r.Counts[check]++
+	r.Issues = append(r.Issues, IntegrityIssue{
+		Check:       check,
+		ID:          id,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// CheckIntegrity looks for data the API would never write but that can
+// appear after manual edits, imports or bugs:
+//   - actual expenses whose month/year do not match their receipt date
+//   - monthly summaries (per expense type) with a negative total
+//   - receipt numbers shared by different stores or dates of one user
+//   - budgets with a notification threshold outside 0-1
+//   - actual expenses linked to deleted or other users' expected expenses
+//
+// It only reads; Repair fixes the month/year and orphan issues.
+func (r *MaintenanceRepository) CheckIntegrity() (*IntegrityReport, error) {
+	report := &IntegrityReport{
+		CheckedAt: time.Now().UTC(),
+		Counts:    make(map[string]int),
+		Issues:    []IntegrityIssue{},
+	}
+
+	checks := []struct {
+		name string
+		run  func(*IntegrityReport) error
+	}{
+		{IntegrityMonthYear, r.checkMonthYear},
+		{IntegrityNegativeTotal, r.checkNegativeTotals},
+		{IntegrityDuplicateReceipt, r.checkDuplicateReceipts},
+		{IntegrityBudgetThreshold, r.checkBudgetThresholds},
+		{IntegrityOrphanedReference, r.checkOrphans},
+	}
+	for _, c := range checks {
+		report.Counts[c.name] = 0
+		if err := c.run(report); err != nil {
+			return nil, fmt.Errorf("%s check failed: %w", c.name, err)
+		}
+	}
+
+	report.OK = len(report.Issues) == 0
+	return report, nil
+}
+
+func (r *MaintenanceRepository) checkMonthYear(report *IntegrityReport) error {
+	rows, err := r.db.Query(`
+		SELECT id, receipt_date, month, year, month_assigned
+		FROM actual_expenses WHERE receipt_date IS NOT NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id          int64
+			receiptDate time.Time
+			month, year int
+			assigned    bool
+		)
+		if err := rows.Scan(&id, &receiptDate, &month, &year, &assigned); err != nil {
+			return err
+		}
+		if !monthYearMatches(receiptDate, month, year, assigned) {
+			report.add(IntegrityMonthYear, id,
+				"month/year %d/%d does not match receipt date %s",
+				month, year, receiptDate.Format("2006-01-02"))
+		}
+	}
+	return rows.Err()
+}
+
+// monthYearMatches reports whether month/year agree with receiptDate. Items
+// split off a receipt may count toward the month before it.
+func monthYearMatches(receiptDate time.Time, month, year int, assigned bool) bool {
+	if assigned {
+		return models.IsSplitMonth(receiptDate, month, year)
+	}
+	return int(receiptDate.Month()) == month && receiptDate.Year() == year
+}
+
+func (r *MaintenanceRepository) checkNegativeTotals(report *IntegrityReport) error {
+	rows, err := r.db.Query(`
+		SELECT user_id, year, month, expense_type, SUM(actual_amount)
+		FROM actual_expenses
+		GROUP BY user_id, year, month, expense_type
+		HAVING SUM(actual_amount) < 0
+		ORDER BY user_id, year, month, expense_type
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			userID      int64
+			year, month int
+			expenseType string
+			total       float64
+		)
+		if err := rows.Scan(&userID, &year, &month, &expenseType, &total); err != nil {
+			return err
+		}
+		report.add(IntegrityNegativeTotal, 0,
+			"%s total for %d/%d of user %d is %.2f", expenseType, month, year, userID, total)
+	}
+	return rows.Err()
+}
+
+func (r *MaintenanceRepository) checkDuplicateReceipts(report *IntegrityReport) error {
+	// Items of one receipt share its number, store and date; a number used
+	// for several stores or dates was handed out twice. Every user has their
+	// own receipt numbers. Stores may be encrypted, so they are compared in
+	// Go rather than with COUNT(DISTINCT source).
+	rows, err := r.db.Query(`
+		SELECT user_id, receipt_number, source, date(receipt_date)
+		FROM actual_expenses
+		WHERE receipt_number > 0
+		ORDER BY user_id, receipt_number
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type receipt struct{ userID, number int64 }
+	var current receipt
+	sources, dates := map[string]bool{}, map[string]bool{}
+	flush := func() {
+		if len(sources) > 1 || len(dates) > 1 {
+			report.add(IntegrityDuplicateReceipt, 0,
+				"receipt number %d of user %d is used by %d stores on %d dates",
+				current.number, current.userID, len(sources), len(dates))
+		}
+		clear(sources)
+		clear(dates)
+	}
+
+	for rows.Next() {
+		var key receipt
+		var source string
+		var date sql.NullString
+		if err := rows.Scan(&key.userID, &key.number, &source, &date); err != nil {
+			return err
+		}
+		if source, err = openField("source", source); err != nil {
+			return err
+		}
+		if key != current {
+			flush()
+			current = key
+		}
+		sources[source] = true
+		if date.Valid {
+			dates[date.String] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+func (r *MaintenanceRepository) checkBudgetThresholds(report *IntegrityReport) error {
+	rows, err := r.db.Query(`
+		SELECT id, month, year, COALESCE(CAST(notification_threshold AS TEXT), 'NULL')
+		FROM budget_limits
+		WHERE notification_threshold IS NULL
+			OR notification_threshold < 0 OR notification_threshold > 1
+		ORDER BY year, month
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id          int64
+			month, year int
+			threshold   string
+		)
+		if err := rows.Scan(&id, &month, &year, &threshold); err != nil {
+			return err
+		}
+		report.add(IntegrityBudgetThreshold, id,
+			"budget for %d/%d has notification threshold %s (must be between 0 and 1)",
+			month, year, threshold)
+	}
+	return rows.Err()
+}
+
+func (r *MaintenanceRepository) checkOrphans(report *IntegrityReport) error {
+	refs, err := queryOrphanedReferences(r.db)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		report.add(IntegrityOrphanedReference, ref.ExpenseID,
+			"expected expense %d no longer exists", ref.ExpectedExpenseID)
+	}
+	return nil
+}
@@ -0,0 +1,458 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceRepository runs data repair jobs that span several tables
+type MaintenanceRepository struct {
+	db *DB
+}
+
+func NewMaintenanceRepository(db *DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// RepairFix describes a single change made (or, in a dry run, proposed) by Repair
+type RepairFix struct {
+	ExpenseID   int64  `json:"expense_id"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+// Repair fix kinds
+const (
+	RepairMonthYear      = "month_year"
+	RepairOrphanRelinked = "orphan_relinked"
+	RepairOrphanCleared  = "orphan_cleared"
+)
+
+// RepairReport summarizes a Repair run
+type RepairReport struct {
+	DryRun          bool        `json:"dry_run"`
+	MonthYearFixed  int         `json:"month_year_fixed"`
+	OrphansRelinked int         `json:"orphans_relinked"`
+	OrphansCleared  int         `json:"orphans_cleared"`
+	Fixes           []RepairFix `json:"fixes"`
+}
+
+// Repair recomputes derived data on actual expenses:
+//   - month/year are recomputed from receipt_date, unless an item split off
+//     the receipt counts toward the month before
+//   - expected_expense_id references to deleted expected expenses are re-linked
+//     to the single expected expense with the same item name and source, or
+//     cleared when there is no unambiguous match
+//
+// Monthly summaries are computed on read, so there is nothing stored to rebuild.
+// With dryRun set the changes are reported but rolled back.
+func (r *MaintenanceRepository) Repair(dryRun bool) (*RepairReport, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &RepairReport{DryRun: dryRun, Fixes: []RepairFix{}}
+
+	if err := repairMonthYear(tx, report); err != nil {
+		return nil, fmt.Errorf("failed to repair month/year: %w", err)
+	}
+	if err := repairOrphanedExpectedLinks(tx, report); err != nil {
+		return nil, fmt.Errorf("failed to repair expected expense links: %w", err)
+	}
+
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func repairMonthYear(tx *sql.Tx, report *RepairReport) error {
+	type row struct {
+		id          int64
+		receiptDate time.Time
+		month, year int
+		assigned    bool
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, receipt_date, month, year, month_assigned
+		FROM actual_expenses WHERE receipt_date IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	var stale []row
+	for rows.Next() {
+		var e row
+		if err := rows.Scan(&e.id, &e.receiptDate, &e.month, &e.year, &e.assigned); err != nil {
+			rows.Close()
+			return err
+		}
+		if !monthYearMatches(e.receiptDate, e.month, e.year, e.assigned) {
+			stale = append(stale, e)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range stale {
+		month, year := int(e.receiptDate.Month()), e.receiptDate.Year()
+		if _, err := tx.Exec(`
+			UPDATE actual_expenses SET month = ?, year = ?, month_assigned = 0, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, month, year, e.id); err != nil {
+			return err
+		}
+
+		report.MonthYearFixed++
+		report.Fixes = append(report.Fixes, RepairFix{
+			ExpenseID: e.id,
+			Kind:      RepairMonthYear,
+			Description: fmt.Sprintf(
+				"month/year %d/%d -> %d/%d from receipt date %s",
+				e.month, e.year, month, year, e.receiptDate.Format("2006-01-02"),
+			),
+		})
+	}
+
+	return nil
+}
+
+func repairOrphanedExpectedLinks(tx *sql.Tx, report *RepairReport) error {
+	orphans, err := queryOrphanedReferences(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orphans {
+		match, err := findUniqueExpectedExpense(tx, o.UserID, o.ItemName, o.Source)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE actual_expenses SET expected_expense_id = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, match, o.ExpenseID); err != nil {
+			return err
+		}
+
+		fix := RepairFix{ExpenseID: o.ExpenseID}
+		if match.Valid {
+			report.OrphansRelinked++
+			fix.Kind = RepairOrphanRelinked
+			fix.Description = fmt.Sprintf(
+				"expected expense %d no longer exists; re-linked to %d",
+				o.ExpectedExpenseID, match.Int64,
+			)
+		} else {
+			report.OrphansCleared++
+			fix.Kind = RepairOrphanCleared
+			fix.Description = fmt.Sprintf(
+				"expected expense %d no longer exists; link cleared",
+				o.ExpectedExpenseID,
+			)
+		}
+		report.Fixes = append(report.Fixes, fix)
+	}
+
+	return nil
+}
+
+// OrphanedReference is an actual expense linked to an expected expense that no
+// longer exists or belongs to another user
+type OrphanedReference struct {
+	ExpenseID         int64  `json:"expense_id"`
+	UserID            int64  `json:"user_id"`
+	ExpectedExpenseID int64  `json:"expected_expense_id"`
+	ItemName          string `json:"item_name"`
+	Source            string `json:"source"`
+}
+
+// OrphanReport lists dangling expected_expense_id references
+type OrphanReport struct {
+	Count      int                 `json:"count"`
+	References []OrphanedReference `json:"references"`
+}
+
+// FindOrphanedReferences reports actual expenses whose expected_expense_id points
+// at a deleted expected expense. Repair cleans them up.
+func (r *MaintenanceRepository) FindOrphanedReferences() (*OrphanReport, error) {
+	refs, err := queryOrphanedReferences(r.db)
+	if err != nil {
+		return nil, err
+	}
+	if refs == nil {
+		refs = []OrphanedReference{}
+	}
+	return &OrphanReport{Count: len(refs), References: refs}, nil
+}
+
+func queryOrphanedReferences(db querier) ([]OrphanedReference, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.user_id, a.expected_expense_id, a.item_name, a.source
+		FROM actual_expenses a
+		LEFT JOIN expected_expenses e ON e.id = a.expected_expense_id AND e.user_id = a.user_id
+		WHERE a.expected_expense_id IS NOT NULL AND e.id IS NULL
+		ORDER BY a.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []OrphanedReference
+	for rows.Next() {
+		var ref OrphanedReference
+		if err := rows.Scan(&ref.ExpenseID, &ref.UserID, &ref.ExpectedExpenseID, &ref.ItemName, &ref.Source); err != nil {
+			return nil, err
+		}
+		if err := openNameAndSource(&ref.ItemName, &ref.Source); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// findUniqueExpectedExpense returns the id of userID's only expected expense
+// with the given item name and source (case-insensitive), or NULL if there is
+// no match or more than one. The names are compared after decrypting, so the
+// user's expected expenses are read and matched in Go.
+func findUniqueExpectedExpense(
+	tx *sql.Tx,
+	userID int64,
+	itemName, source string,
+) (sql.NullInt64, error) {
+	rows, err := tx.Query(`
+		SELECT id, item_name, source FROM expected_expenses WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var name, store string
+		if err := rows.Scan(&id, &name, &store); err != nil {
+			return sql.NullInt64{}, err
+		}
+		if err := openNameAndSource(&name, &store); err != nil {
+			return sql.NullInt64{}, err
+		}
+		if strings.EqualFold(name, itemName) && strings.EqualFold(store, source) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	if len(ids) != 1 {
+		return sql.NullInt64{}, nil
+	}
+	return sql.NullInt64{Int64: ids[0], Valid: true}, nil
+}
+
+// OwnerReport summarizes an AssignOwner run
+type OwnerReport struct {
+	DryRun              bool  `json:"dry_run"`
+	UserID              int64 `json:"user_id"`
+	Budgets             int64 `json:"budgets"`
+	BudgetsSkipped      int64 `json:"budgets_skipped"`
+	ExpectedExpenses    int64 `json:"expected_expenses"`
+	ActualExpenses      int64 `json:"actual_expenses"`
+	Notifications       int64 `json:"notifications"`
+	ReceiptNumberOffset int64 `json:"receipt_number_offset"`
+}
+
+// AssignOwner moves the shared workspace (user 0), where data lives while
+// authentication is disabled, to userID. Budgets for months userID already
+// has a budget for are skipped and stay in the shared workspace. Receipt
+// numbers of the moved expenses are shifted past userID's highest so they
+// stay unique. With dryRun set the changes are reported but rolled back.
+func (r *MaintenanceRepository) AssignOwner(userID int64, dryRun bool) (*OwnerReport, error) {
+	if userID == 0 {
+		return nil, fmt.Errorf("cannot assign the shared workspace to itself")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &OwnerReport{DryRun: dryRun, UserID: userID}
+
+	result, err := tx.Exec(`
+		UPDATE OR IGNORE budget_limits SET user_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = 0
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move budgets: %w", err)
+	}
+	if report.Budgets, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow(
+		`SELECT COUNT(*) FROM budget_limits WHERE user_id = 0`,
+	).Scan(&report.BudgetsSkipped); err != nil {
+		return nil, err
+	}
+
+	result, err = tx.Exec(`
+		UPDATE expected_expenses SET user_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = 0
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move expected expenses: %w", err)
+	}
+	if report.ExpectedExpenses, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.QueryRow(
+		`SELECT COALESCE(MAX(receipt_number), 0) FROM actual_expenses WHERE user_id = ?`, userID,
+	).Scan(&report.ReceiptNumberOffset); err != nil {
+		return nil, err
+	}
+	result, err = tx.Exec(`
+		UPDATE actual_expenses
+		SET user_id = ?,
+			receipt_number = CASE WHEN receipt_number > 0 THEN receipt_number + ? ELSE receipt_number END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = 0
+	`, userID, report.ReceiptNumberOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move actual expenses: %w", err)
+	}
+	if report.ActualExpenses, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	result, err = tx.Exec(`UPDATE notifications SET user_id = ? WHERE user_id = 0`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move notifications: %w", err)
+	}
+	if report.Notifications, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// encryptedColumns lists the columns sealed with the field cipher, by table
+var encryptedColumns = []struct{ table, column string }{
+	{"actual_expenses", "item_name"},
+	{"actual_expenses", "source"},
+	{"expected_expenses", "item_name"},
+	{"expected_expenses", "source"},
+	{"audit_log", "before_json"},
+	{"audit_log", "after_json"},
+	{"receipt_processing_history", "raw_response"},
+	{"receipt_processing_history", "repair_response"},
+}
+
+// EncryptedColumn counts the values EncryptFields encrypted in one column
+type EncryptedColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Rows   int    `json:"rows"`
+}
+
+// EncryptReport summarizes an EncryptFields run
+type EncryptReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Columns []EncryptedColumn `json:"columns"`
+}
+
+// EncryptFields encrypts the values of the sensitive columns that were
+// written before field encryption was enabled. It requires SetFieldCipher and
+// leaves values that are already encrypted alone, so it can be re-run. With
+// dryRun set the values are counted but left unchanged.
+func (r *MaintenanceRepository) EncryptFields(dryRun bool) (*EncryptReport, error) {
+	if !encryptingFields() {
+		return nil, fmt.Errorf("field encryption is not enabled, set FIELD_ENCRYPTION_KEY")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &EncryptReport{DryRun: dryRun, Columns: []EncryptedColumn{}}
+	for _, c := range encryptedColumns {
+		count, err := encryptColumn(tx, c.table, c.column)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s.%s: %w", c.table, c.column, err)
+		}
+		report.Columns = append(report.Columns, EncryptedColumn{Table: c.table, Column: c.column, Rows: count})
+	}
+
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// encryptColumn seals the plain text values of table.column, which come from
+// encryptedColumns and never from input
+func encryptColumn(tx *sql.Tx, table, column string) (int, error) {
+	rows, err := tx.Query(`
+		SELECT id, `+column+` FROM `+table+`
+		WHERE `+column+` IS NOT NULL AND `+column+` NOT LIKE ?
+	`, encryptedPrefix+"%")
+	if err != nil {
+		return 0, err
+	}
+
+	type value struct {
+		id    int64
+		plain string
+	}
+	var plain []value
+	for rows.Next() {
+		var v value
+		if err := rows.Scan(&v.id, &v.plain); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plain = append(plain, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, v := range plain {
+		sealed, err := sealField(column, v.plain)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE `+table+` SET `+column+` = ? WHERE id = ?`, sealed, v.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(plain), nil
+}
@@ -0,0 +1,538 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceRepair(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	// Orphans can only be created with foreign keys off, which is per connection
+	db.SetMaxOpenConns(1)
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM expected_expenses`); err != nil {
+		t.Fatalf("Failed to clear seed data: %v", err)
+	}
+	allowOrphans(t, db)
+
+	mustExec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("Failed to execute %q: %v", query, err)
+		}
+	}
+
+	mustExec(`INSERT INTO expected_expenses (id, item_name, source, expected_amount, expense_type)
+		VALUES (10, 'Milk', 'Publix', 4, 'weekly')`)
+	insert := `INSERT INTO actual_expenses
+		(id, item_name, source, actual_amount, expense_type, expected_expense_id, receipt_date, month, year)
+		VALUES (?, ?, ?, 1, 'weekly', ?, '2024-06-15', ?, ?)`
+	mustExec(insert, 1, "Healthy", "Store", nil, 6, 2024)
+	mustExec(insert, 2, "Wrong month", "Store", nil, 1, 2023)
+	mustExec(insert, 3, "milk", "PUBLIX", 99, 6, 2024)
+	mustExec(insert, 4, "Unknown", "Store", 98, 6, 2024)
+
+	maintenance := NewMaintenanceRepository(db)
+
+	orphans, err := maintenance.FindOrphanedReferences()
+	if err != nil {
+		t.Fatalf("Failed to find orphans: %v", err)
+	}
+	if orphans.Count != 2 || orphans.References[0].ExpectedExpenseID != 99 {
+		t.Errorf("Expected orphans for expected expenses 99 and 98, got %+v", orphans)
+	}
+
+	report, err := maintenance.Repair(true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(report.Fixes) != 3 {
+		t.Fatalf("Expected 3 proposed fixes, got %+v", report.Fixes)
+	}
+	var month int
+	if err := db.QueryRow(`SELECT month FROM actual_expenses WHERE id = 2`).Scan(&month); err != nil {
+		t.Fatalf("Failed to query month: %v", err)
+	}
+	if month != 1 {
+		t.Errorf("Dry run must not change data, month is %d", month)
+	}
+
+	report, err = maintenance.Repair(false)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if report.MonthYearFixed != 1 || report.OrphansRelinked != 1 || report.OrphansCleared != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	repo := NewActualExpenseRepository(db)
+	wrongMonth, _ := repo.GetByID(2)
+	if wrongMonth.Month != 6 || wrongMonth.Year != 2024 {
+		t.Errorf("Expected month/year 6/2024, got %d/%d", wrongMonth.Month, wrongMonth.Year)
+	}
+	relinked, _ := repo.GetByID(3)
+	if relinked.ExpectedExpenseID == nil || *relinked.ExpectedExpenseID != 10 {
+		t.Errorf("Expected re-link to expected expense 10, got %v", relinked.ExpectedExpenseID)
+	}
+	cleared, _ := repo.GetByID(4)
+	if cleared.ExpectedExpenseID != nil {
+		t.Errorf("Expected cleared link, got %v", *cleared.ExpectedExpenseID)
+	}
+
+	report, err = maintenance.Repair(false)
+	if err != nil {
+		t.Fatalf("Second repair failed: %v", err)
+	}
+	if len(report.Fixes) != 0 {
+		t.Errorf("Expected repair to be idempotent, got %+v", report.Fixes)
+	}
+}
+
+func TestMaintenanceRepair_KeepsSplitMonth(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	insert := `INSERT INTO actual_expenses
+		(id, item_name, source, actual_amount, expense_type, receipt_date, month, year, month_assigned)
+		VALUES (?, 'Item', 'Store', 1, 'weekly', '2024-01-01', ?, ?, ?)`
+	for _, row := range [][]any{
+		{1, 12, 2023, 1}, // split into the month before
+		{2, 11, 2023, 1}, // too far back even for a split
+		{3, 12, 2023, 0}, // not split
+	} {
+		if _, err := db.Exec(insert, row...); err != nil {
+			t.Fatalf("Failed to insert expense: %v", err)
+		}
+	}
+
+	maintenance := NewMaintenanceRepository(db)
+	integrity, err := maintenance.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if integrity.Counts[IntegrityMonthYear] != 2 {
+		t.Errorf("Expected 2 month/year issues, got %+v", integrity.Issues)
+	}
+
+	report, err := maintenance.Repair(false)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if report.MonthYearFixed != 2 {
+		t.Errorf("Expected 2 month/year fixes, got %+v", report.Fixes)
+	}
+
+	repo := NewActualExpenseRepository(db)
+	for id, want := range map[int64][2]int{1: {12, 2023}, 2: {1, 2024}, 3: {1, 2024}} {
+		expense, err := repo.GetByID(id)
+		if err != nil {
+			t.Fatalf("Failed to get expense %d: %v", id, err)
+		}
+		if expense.Month != want[0] || expense.Year != want[1] {
+			t.Errorf("Expense %d: expected %d/%d, got %d/%d", id, want[0], want[1], expense.Month, expense.Year)
+		}
+	}
+}
+
+// allowOrphans simulates a database from before expected_expense_id was
+// enforced. Foreign keys are per connection, so db must use a single one.
+func allowOrphans(t *testing.T, db *DB) {
+	t.Helper()
+
+	for _, stmt := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`DROP TRIGGER trg_actual_expenses_expected_fk_insert`,
+		`DROP TRIGGER trg_actual_expenses_expected_fk_update`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+	}
+}
+
+func TestExpectedExpenseReference_Enforced(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	// Enforcement must not depend on the connection's foreign_keys pragma
+	db.SetMaxOpenConns(1)
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+		t.Fatalf("Failed to disable foreign keys: %v", err)
+	}
+
+	expected, err := NewExpectedExpenseRepository(db).Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ExpectedAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+
+	insert := `INSERT INTO actual_expenses
+		(item_name, source, actual_amount, expense_type, expected_expense_id, month, year)
+		VALUES ('Milk', 'Publix', 4, 'weekly', ?, 6, 2024)`
+
+	if _, err := db.Exec(insert, expected.ID+1000); err == nil {
+		t.Error("Expected insert with a dangling reference to fail")
+	}
+
+	result, err := db.Exec(insert, expected.ID)
+	if err != nil {
+		t.Fatalf("Failed to insert linked expense: %v", err)
+	}
+	id, _ := result.LastInsertId()
+
+	if _, err := db.Exec(
+		`UPDATE actual_expenses SET expected_expense_id = ? WHERE id = ?`, expected.ID+1000, id,
+	); err == nil {
+		t.Error("Expected update to a dangling reference to fail")
+	}
+
+	if err := NewExpectedExpenseRepository(db).Delete(expected.ID); err != nil {
+		t.Fatalf("Failed to delete expected expense: %v", err)
+	}
+
+	actual, err := NewActualExpenseRepository(db).GetByID(id)
+	if err != nil {
+		t.Fatalf("Failed to get actual expense: %v", err)
+	}
+	if actual.ExpectedExpenseID != nil {
+		t.Errorf("Expected reference to be set to NULL, got %d", *actual.ExpectedExpenseID)
+	}
+}
+
+func TestActualExpenseRepository_RejectsUnknownExpectedExpense(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	missing := int64(424242)
+	repo := NewActualExpenseRepository(db)
+	_, err := repo.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 4,
+		ExpenseType: models.ExpenseTypeWeekly, ExpectedExpenseID: &missing,
+	})
+	if !errors.Is(err, models.ErrExpectedExpenseNotFound) {
+		t.Errorf("Expected ErrExpectedExpenseNotFound, got %v", err)
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	maintenance := NewMaintenanceRepository(db)
+	report, err := maintenance.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if !report.OK || len(report.Issues) != 0 {
+		t.Fatalf("Expected a clean database to pass, got %+v", report.Issues)
+	}
+
+	allowOrphans(t, db)
+	mustExec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("Failed to execute %q: %v", query, err)
+		}
+	}
+
+	insert := `INSERT INTO actual_expenses
+		(id, item_name, source, actual_amount, expense_type, expected_expense_id, receipt_date, receipt_number, month, year)
+		VALUES (?, ?, ?, ?, 'misc', ?, ?, ?, ?, ?)`
+	mustExec(insert, 1, "Item", "Store A", 5, nil, "2024-06-15", 7, 6, 2024)
+	mustExec(insert, 2, "Second item", "Store A", 3, nil, "2024-06-15", 7, 6, 2024)
+	mustExec(insert, 3, "Other store", "Store B", 2, nil, "2024-06-20", 7, 6, 2024)
+	mustExec(insert, 4, "Refund", "Store C", -50, nil, "2024-07-01", 8, 7, 2024)
+	mustExec(insert, 5, "Wrong month", "Store D", 1, nil, "2024-08-01", 9, 1, 2023)
+	mustExec(insert, 6, "Orphan", "Store E", 1, 999, "2024-08-02", 10, 8, 2024)
+	mustExec(`INSERT INTO budget_limits (id, month, year, amount, notification_threshold)
+		VALUES (1, 6, 2024, 500, 1.5), (2, 7, 2024, 500, 0.8)`)
+
+	report, err = maintenance.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if report.OK {
+		t.Fatal("Expected issues to be reported")
+	}
+
+	want := map[string]int{
+		IntegrityMonthYear:         1,
+		IntegrityNegativeTotal:     1,
+		IntegrityDuplicateReceipt:  1,
+		IntegrityBudgetThreshold:   1,
+		IntegrityOrphanedReference: 1,
+	}
+	for check, count := range want {
+		if report.Counts[check] != count {
+			t.Errorf("Expected %d %s issues, got %d (%+v)", count, check, report.Counts[check], report.Issues)
+		}
+	}
+	if len(report.Issues) != 5 {
+		t.Errorf("Expected 5 issues, got %+v", report.Issues)
+	}
+}
+
+func TestAssignOwner(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM expected_expenses`); err != nil {
+		t.Fatalf("Failed to clear seed data: %v", err)
+	}
+
+	const userID = 7
+	shared := struct {
+		budgets  *BudgetRepository
+		expected *ExpectedExpenseRepository
+		actual   *ActualExpenseRepository
+	}{NewBudgetRepository(db), NewExpectedExpenseRepository(db), NewActualExpenseRepository(db)}
+	owned := struct {
+		budgets  *BudgetRepository
+		expected *ExpectedExpenseRepository
+		actual   *ActualExpenseRepository
+	}{shared.budgets.ForUser(userID), shared.expected.ForUser(userID), shared.actual.ForUser(userID)}
+
+	for _, month := range []int{5, 6} {
+		if _, err := shared.budgets.Create(&models.CreateBudgetLimitRequest{Month: month, Year: 2024, Amount: 100}); err != nil {
+			t.Fatalf("Failed to create shared budget: %v", err)
+		}
+	}
+	if _, err := owned.budgets.Create(&models.CreateBudgetLimitRequest{Month: 6, Year: 2024, Amount: 200}); err != nil {
+		t.Fatalf("Failed to create the same month for another user: %v", err)
+	}
+	expected, err := shared.expected.Create(&models.CreateExpectedExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ExpectedAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expected expense: %v", err)
+	}
+	date := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	for _, r := range []struct {
+		repo   *ActualExpenseRepository
+		number int64
+	}{{shared.actual, 1}, {shared.actual, 2}, {owned.actual, 1}} {
+		if _, err := r.repo.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Milk", Source: "Publix", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+			ReceiptDate: &date, ReceiptNumber: r.number,
+		}); err != nil {
+			t.Fatalf("Failed to create actual expense: %v", err)
+		}
+	}
+
+	// Each user only sees their own data
+	if _, err := owned.expected.GetByID(expected.ID); !errors.Is(err, ErrExpenseNotFound) {
+		t.Errorf("Expected another user's expected expense to be hidden, got %v", err)
+	}
+	if _, err := owned.actual.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Milk", Source: "Publix", ActualAmount: 4, ExpenseType: models.ExpenseTypeWeekly,
+		ExpectedExpenseID: &expected.ID,
+	}); !errors.Is(err, models.ErrExpectedExpenseNotFound) {
+		t.Errorf("Expected linking another user's expected expense to fail, got %v", err)
+	}
+	if summary, err := owned.actual.GetMonthlySummary(6, 2024); err != nil || summary.TotalActual != 4 {
+		t.Errorf("Expected user total 4, got %+v (%v)", summary, err)
+	}
+	if next, err := owned.actual.GetNextReceiptNumber(); err != nil || next != 2 {
+		t.Errorf("Expected the user's next receipt number to be 2, got %d (%v)", next, err)
+	}
+
+	if _, err := NewNotificationRepository(db).Create(&models.Notification{
+		Kind: models.NotificationBudgetCreated, Title: "Budget created", Message: "Shared",
+	}); err != nil {
+		t.Fatalf("Failed to create notification: %v", err)
+	}
+
+	report, err := NewMaintenanceRepository(db).AssignOwner(userID, false)
+	if err != nil {
+		t.Fatalf("AssignOwner failed: %v", err)
+	}
+	want := OwnerReport{
+		UserID: userID, Budgets: 1, BudgetsSkipped: 1,
+		ExpectedExpenses: 1, ActualExpenses: 2, Notifications: 1, ReceiptNumberOffset: 1,
+	}
+	if *report != want {
+		t.Errorf("Expected report %+v, got %+v", want, *report)
+	}
+
+	budget, err := owned.budgets.GetByMonthYear(6, 2024)
+	if err != nil || budget.Amount != 200 {
+		t.Errorf("Expected the user's own June budget to be kept, got %+v (%v)", budget, err)
+	}
+	if _, err := owned.budgets.GetByMonthYear(5, 2024); err != nil {
+		t.Errorf("Expected the shared May budget to be moved: %v", err)
+	}
+	if _, err := owned.expected.GetByID(expected.ID); err != nil {
+		t.Errorf("Expected the expected expense to be moved: %v", err)
+	}
+	if next, err := owned.actual.GetNextReceiptNumber(); err != nil || next != 4 {
+		t.Errorf("Expected moved receipts to be renumbered after the user's, got next %d (%v)", next, err)
+	}
+	if expenses, err := shared.actual.GetAll(); err != nil || len(expenses) != 0 {
+		t.Errorf("Expected no shared expenses left, got %d (%v)", len(expenses), err)
+	}
+	if notifications, err := NewNotificationRepository(db).ForUser(userID).List(false); err != nil || len(notifications) != 1 {
+		t.Errorf("Expected the notification to be moved, got %+v (%v)", notifications, err)
+	}
+}
+
+func TestMaintenanceBackup(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	settings := NewSettingsRepository(db)
+	if _, err := settings.GetLastBackup(); !errors.Is(err, ErrSettingNotFound) {
+		t.Fatalf("Expected no backup yet, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "budget-backup.db")
+	record, err := NewMaintenanceRepository(db).Backup(path)
+	if err != nil {
+		t.Fatalf("Backup() error: %v", err)
+	}
+	if record.SizeBytes == 0 {
+		t.Errorf("Expected the size of the backup, got %+v", record)
+	}
+	last, err := settings.GetLastBackup()
+	if err != nil || !last.FinishedAt.Equal(record.FinishedAt) {
+		t.Errorf("Expected the backup recorded, got %+v, %v", last, err)
+	}
+
+	// The copy is a database of its own
+	backup, err := sql.Open("libsql", "file:"+path)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	var migrations int
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&migrations); err != nil || migrations == 0 {
+		t.Errorf("Expected the migrations in the backup, got %d, %v", migrations, err)
+	}
+
+	if _, err := NewMaintenanceRepository(db).Backup(path); !errors.Is(err, ErrBackupExists) {
+		t.Errorf("Expected ErrBackupExists, got %v", err)
+	}
+}
+
+func TestMaintenanceAnonymize(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO users (id, email, password_hash) VALUES (1, 'jane@example.com', 'hash')`,
+		`INSERT INTO sessions (user_id, refresh_token_hash, created_at, last_used_at, expires_at)
+			VALUES (1, 'token', '2024-06-01', '2024-06-01', '2024-07-01')`,
+		`INSERT INTO budget_limits (user_id, month, year, amount) VALUES (1, 6, 2024, 1000)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+	expenses := NewActualExpenseRepository(db).ForUser(1)
+	date := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	for _, source := range []string{"Costco", " costco", "Publix"} {
+		if _, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Milk", Source: source, ActualAmount: 12.5,
+			ExpenseType: models.ExpenseTypeWeekly, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.db")
+	report, err := NewMaintenanceRepository(db).Anonymize(path)
+	if err != nil {
+		t.Fatalf("Anonymize() error: %v", err)
+	}
+	rows := map[string]int64{}
+	for _, table := range report.Tables {
+		rows[table.Table] = table.Rows
+	}
+	if rows["actual_expenses"] != 3 || rows["budget_limits"] != 1 || rows["users"] != 1 || rows["sessions"] != 0 {
+		t.Errorf("Expected the rows kept and the sessions dropped, got %v", rows)
+	}
+
+	bundle, err := sql.Open("libsql", "file:"+path)
+	if err != nil {
+		t.Fatalf("Failed to open bundle: %v", err)
+	}
+	defer bundle.Close()
+	var sources []string
+	var amounts []float64
+	result, err := bundle.Query(`SELECT source, actual_amount FROM actual_expenses ORDER BY id`)
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	for result.Next() {
+		var source string
+		var amount float64
+		if err := result.Scan(&source, &amount); err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		sources, amounts = append(sources, source), append(amounts, amount)
+	}
+	result.Close()
+	if len(sources) != 3 || sources[0] != sources[1] || sources[0] == sources[2] || !strings.HasPrefix(sources[0], "source-") {
+		t.Errorf("Expected the sources pseudonymized, equal ones alike, got %v", sources)
+	}
+	for _, amount := range amounts {
+		if amount <= 0 || amount == 12.5 {
+			t.Errorf("Expected amounts perturbed, got %v", amounts)
+			break
+		}
+	}
+	var email, hash string
+	if err := bundle.QueryRow(`SELECT email, password_hash FROM users`).Scan(&email, &hash); err != nil ||
+		email != "user-1@example.invalid" || hash != "" {
+		t.Errorf("Expected the user scrubbed, got %q %q (%v)", email, hash, err)
+	}
+
+	if _, err := NewMaintenanceRepository(db).Anonymize(path); !errors.Is(err, ErrBackupExists) {
+		t.Errorf("Expected ErrBackupExists, got %v", err)
+	}
+
+	// A column without a rule is refused rather than copied as is
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN nickname TEXT`); err != nil {
+		t.Fatalf("Failed to add column: %v", err)
+	}
+	refused := filepath.Join(t.TempDir(), "refused.db")
+	if _, err := NewMaintenanceRepository(db).Anonymize(refused); err == nil || !strings.Contains(err.Error(), "users.nickname") {
+		t.Errorf("Expected the new column refused, got %v", err)
+	}
+	if _, err := os.Stat(refused); !os.IsNotExist(err) {
+		t.Errorf("Expected no bundle left behind, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// dayLayout is the format of the failure_counts.day column
+const dayLayout = "2006-01-02"
+
+// MetricsRepository handles failure_counts database operations
+type MetricsRepository struct {
+	db *DB
+}
+
+// NewMetricsRepository creates a new MetricsRepository
+func NewMetricsRepository(db *DB) *MetricsRepository {
+	return &MetricsRepository{db: db}
+}
+
+// FailureCount is the number of failures of one handler with one error code on one day
+type FailureCount struct {
+	Day     string `json:"day"`
+	Handler string `json:"handler"`
+	Code    string `json:"code"`
+	Count   int    `json:"count"`
+}
+
+// IncrementFailure adds one failure with code to handler's counter for the day of at
+func (r *MetricsRepository) IncrementFailure(handler, code string, at time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO failure_counts (day, handler, code, count) VALUES (?, ?, ?, 1)
+		ON CONFLICT (day, handler, code) DO UPDATE SET count = count + 1
+	`, at.Format(dayLayout), handler, code)
+	if err != nil {
+		return fmt.Errorf("failed to increment failure count: %w", err)
+	}
+	return nil
+}
+
+// FailureCounts returns the counters for days in [from, to], oldest first.
+// An empty handler matches all handlers.
+func (r *MetricsRepository) FailureCounts(handler string, from, to time.Time) ([]FailureCount, error) {
+	rows, err := r.db.Query(`
+		SELECT day, handler, code, count FROM failure_counts
+		WHERE day >= ? AND day <= ? AND (? = '' OR handler = ?)
+		ORDER BY day, handler, code
+	`, from.Format(dayLayout), to.Format(dayLayout), handler, handler)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failure counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []FailureCount
+	for rows.Next() {
+		var c FailureCount
+		var day time.Time
+		if err := rows.Scan(&day, &c.Handler, &c.Code, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan failure count: %w", err)
+		}
+		c.Day = day.Format(dayLayout)
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
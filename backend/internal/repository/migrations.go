@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"database/sql"
 	"embed"
 	"fmt"
 	"io/fs"
@@ -31,7 +32,8 @@ type Migration struct {
 
 // splitSQLStatements splits SQL content into individual statements.
 // It handles semicolons inside single-quoted string literals by tracking
-// quote state. Each statement is trimmed of whitespace, and empty statements
+// quote state, and keeps CREATE TRIGGER bodies (BEGIN ... END) together.
+// Each statement is trimmed of whitespace, and empty statements
 // are excluded from the result.
 //
 // Limitations:
@@ -51,7 +53,7 @@ func splitSQLStatements(sql string) []string {
 			// Toggle string state on single quote
 			inString = !inString
 			current.WriteByte(ch)
-		} else if ch == ';' && !inString {
+		} else if ch == ';' && !inString && !isOpenTrigger(current.String()) {
 			// Statement delimiter found outside of string
 			stmt := strings.TrimSpace(current.String())
 			if stmt != "" {
@@ -72,6 +74,38 @@ func splitSQLStatements(sql string) []string {
 	return statements
 }
 
+// isOpenTrigger reports whether stmt is a CREATE TRIGGER statement whose
+// BEGIN ... END body has not been closed yet, so semicolons inside the body
+// must not end the statement. Leading "--" comment lines are ignored.
+func isOpenTrigger(stmt string) bool {
+	var lines []string
+	for _, line := range strings.Split(stmt, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if len(lines) == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+
+	fields := strings.Fields(strings.ToUpper(strings.Join(lines, " ")))
+	if len(fields) < 2 || fields[0] != "CREATE" {
+		return false
+	}
+
+	isTrigger := false
+	for _, f := range fields[1:] {
+		if f == "TRIGGER" {
+			isTrigger = true
+			break
+		}
+		if f != "TEMP" && f != "TEMPORARY" {
+			return false
+		}
+	}
+
+	return isTrigger && fields[len(fields)-1] != "END"
+}
+
 // parseFilename extracts version number from a migration filename.
 // Format: YYYY-MM-DD-NNN.sql -> YYYYMMDDNNN (e.g., "2025-11-29-001.sql" -> 20251129001)
 func parseFilename(filename string) (int, error) {
@@ -139,14 +173,96 @@ func loadMigrations() ([]Migration, error) {
 func (db *DB) RunMigrations() error {
 	log.Println("Running database migrations...")
 
+	// Refuse to migrate another environment's database
+	if err := db.checkEnvironment(true); err != nil {
+		return err
+	}
+
 	// Load migrations from embedded files
 	migrations, err := loadMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
 
-	// First, ensure schema_migrations table exists
-	_, err = db.Exec(`
+	if err := createMigrationsTable(db); err != nil {
+		return err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	// Run pending migrations
+	for _, m := range migrations {
+		if applied[m.Version] {
+			log.Printf("Migration %d (%s) already applied", m.Version, m.Description)
+			continue
+		}
+
+		log.Printf("Applying migration %d: %s", m.Version, m.Description)
+
+		// Execute migration in a transaction
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration %d: %w", m.Version, err)
+		}
+		if err := applyMigration(tx, m); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+		}
+
+		log.Printf("Migration %d (%s) applied successfully", m.Version, m.Description)
+	}
+
+	log.Println("All migrations completed")
+	return nil
+}
+
+// VerifyMigrations applies the pending migrations in a transaction that is
+// always rolled back. It returns the migrations RunMigrations would apply and
+// fails if any of them would, without changing the database.
+func (db *DB) VerifyMigrations() ([]Migration, error) {
+	if err := db.checkEnvironment(false); err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := createMigrationsTable(tx); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := applyMigration(tx, m); err != nil {
+			return nil, err
+		}
+		pending = append(pending, m)
+	}
+	return pending, nil
+}
+
+func createMigrationsTable(db querier) error {
+	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INTEGER PRIMARY KEY,
 			description TEXT NOT NULL,
@@ -156,25 +272,28 @@ func (db *DB) RunMigrations() error {
 	if err != nil {
 		return fmt.Errorf("failed to create schema_migrations table: %w", err)
 	}
+	return nil
+}
 
-	// Get applied migrations
+// appliedVersions returns the versions recorded in schema_migrations
+func appliedVersions(db querier) (map[int]bool, error) {
 	applied := make(map[int]bool)
 	rows, err := db.Query("SELECT version FROM schema_migrations")
 	if err != nil {
-		return fmt.Errorf("failed to query schema_migrations: %w", err)
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var version int
 		if err := rows.Scan(&version); err != nil {
-			return fmt.Errorf("failed to scan migration version: %w", err)
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
 		}
 		applied[version] = true
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating migrations: %w", err)
+		return nil, fmt.Errorf("error iterating migrations: %w", err)
 	}
 
 	// Handle backward compatibility: mark new versions as applied if their legacy equivalents exist
@@ -184,46 +303,25 @@ func (db *DB) RunMigrations() error {
 		}
 	}
 
-	// Run pending migrations
-	for _, m := range migrations {
-		if applied[m.Version] {
-			log.Printf("Migration %d (%s) already applied", m.Version, m.Description)
-			continue
-		}
-
-		log.Printf("Applying migration %d: %s", m.Version, m.Description)
-
-		// Execute migration in a transaction
-		tx, err := db.Begin()
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction for migration %d: %w", m.Version, err)
-		}
-
-		// Split migration SQL into individual statements and execute each
-		statements := splitSQLStatements(m.SQL)
-		for i, stmt := range statements {
-			if _, err := tx.Exec(stmt); err != nil {
-				tx.Rollback()
-				return fmt.Errorf("failed to execute migration %d (statement %d): %w", m.Version, i+1, err)
-			}
-		}
-
-		// Record the migration
-		if _, err := tx.Exec(
-			"INSERT INTO schema_migrations (version, description) VALUES (?, ?)",
-			m.Version, m.Description,
-		); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
-		}
+	return applied, nil
+}
 
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+// applyMigration executes a migration and records it in schema_migrations
+func applyMigration(tx *sql.Tx, m Migration) error {
+	// Split migration SQL into individual statements and execute each
+	statements := splitSQLStatements(m.SQL)
+	for i, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to execute migration %d (statement %d): %w", m.Version, i+1, err)
 		}
-
-		log.Printf("Migration %d (%s) applied successfully", m.Version, m.Description)
 	}
 
-	log.Println("All migrations completed")
+	// Record the migration
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, description) VALUES (?, ?)",
+		m.Version, m.Description,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
 	return nil
 }
@@ -0,0 +1,35 @@
+-- Migration: 2026-10-16-001
+-- Description: Enforce the shared item_name length limit on both expense tables
+-- Expected expenses used to cap item_name at 200 characters while actual
+-- expenses allowed 255, so long receipt items could not become expected
+-- expenses. Both tables now share the 255 character limit (models.MaxItemNameLength).
+-- SQLite cannot add CHECK constraints to existing columns, so the limit is
+-- enforced with triggers instead of rebuilding the tables.
+
+CREATE TRIGGER IF NOT EXISTS trg_expected_expenses_item_name_insert
+BEFORE INSERT ON expected_expenses
+WHEN length(NEW.item_name) > 255
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_expected_expenses_item_name_update
+BEFORE UPDATE OF item_name ON expected_expenses
+WHEN length(NEW.item_name) > 255
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_item_name_insert
+BEFORE INSERT ON actual_expenses
+WHEN length(NEW.item_name) > 255
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_item_name_update
+BEFORE UPDATE OF item_name ON actual_expenses
+WHEN length(NEW.item_name) > 255
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
@@ -0,0 +1,33 @@
+-- Migration: 2026-10-16-002
+-- Description: Enforce the actual_expenses.expected_expense_id reference
+-- The consolidated schema declares the foreign key with ON DELETE SET NULL,
+-- but databases created before it have no constraint, and SQLite only
+-- enforces foreign keys on connections with PRAGMA foreign_keys enabled.
+-- SQLite cannot add a foreign key to an existing table without rebuilding it,
+-- so the same rules are enforced with triggers, which apply on every
+-- connection. Existing orphans are reported and cleaned up by
+-- `budgetctl repair` rather than here.
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_expected_fk_insert
+BEFORE INSERT ON actual_expenses
+WHEN NEW.expected_expense_id IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM expected_expenses WHERE id = NEW.expected_expense_id)
+BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed: expected_expense_id');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_expected_fk_update
+BEFORE UPDATE OF expected_expense_id ON actual_expenses
+WHEN NEW.expected_expense_id IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM expected_expenses WHERE id = NEW.expected_expense_id)
+BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed: expected_expense_id');
+END;
+
+-- ON DELETE SET NULL
+CREATE TRIGGER IF NOT EXISTS trg_expected_expenses_delete_set_null
+AFTER DELETE ON expected_expenses
+BEGIN
+    UPDATE actual_expenses SET expected_expense_id = NULL
+    WHERE expected_expense_id = OLD.id;
+END;
@@ -0,0 +1,12 @@
+-- Migration: 2026-10-16-003
+-- Description: Add daily failure counters per handler and error code
+-- Used to tell whether failed receipt uploads were caused by the document
+-- (INVALID_DOCUMENT) or by the AI provider (TIMEOUT, RATE_LIMIT, PARSE_ERROR, ...).
+
+CREATE TABLE IF NOT EXISTS failure_counts (
+    day DATE NOT NULL,
+    handler TEXT NOT NULL,
+    code TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, handler, code)
+);
@@ -0,0 +1,6 @@
+-- Migration: 2026-10-16-004
+-- Description: Keep the printed order of receipt items
+-- line_no is the 1-based position of the item on its receipt. It is NULL for
+-- expenses entered by hand and for rows saved before this migration.
+
+ALTER TABLE actual_expenses ADD COLUMN line_no INTEGER;
@@ -0,0 +1,19 @@
+-- Migration: 2026-10-16-005
+-- Description: Keep a history of receipt processing runs with the raw model output
+-- raw_response is the first answer of the model. repair_response is set when
+-- that answer failed validation and the model was asked to repair it.
+
+CREATE TABLE IF NOT EXISTS receipt_processing_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    processed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    file_name TEXT NOT NULL DEFAULT '',
+    file_size INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL CHECK (status IN ('success', 'error')),
+    error_code TEXT,
+    item_count INTEGER NOT NULL DEFAULT 0,
+    processing_time_ms INTEGER NOT NULL DEFAULT 0,
+    raw_response TEXT,
+    repair_response TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipt_processing_history_processed_at ON receipt_processing_history(processed_at);
@@ -0,0 +1,9 @@
+-- Migration: 2026-10-16-006
+-- Description: Add a key/value settings table
+-- Values are JSON encoded. SettingsRepository lists the known keys.
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,15 @@
+-- Migration: 2026-10-16-007
+-- Description: Add stored notifications emitted by background jobs
+-- link is a frontend path the notification points at, e.g. /budget?month=11&year=2025
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    link TEXT,
+    read_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_read_at ON notifications(read_at);
@@ -0,0 +1,10 @@
+-- Migration: 2026-10-16-008
+-- Description: Add user accounts for JWT authentication
+-- email is stored lowercased, password_hash is a PBKDF2 hash (see services/auth)
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,10 @@
+-- Migration: 2026-10-16-009
+-- Description: Cover the monthly summary queries with an index
+-- The summary sums actual_amount per expense_type for one month, so an index
+-- on (year, month, expense_type, actual_amount) answers it without reading the
+-- table. It also serves every lookup the old (year, month) index did.
+
+CREATE INDEX IF NOT EXISTS idx_actual_expenses_month_summary
+    ON actual_expenses(year, month, expense_type, actual_amount);
+
+DROP INDEX IF EXISTS idx_actual_expenses_month_year;
@@ -0,0 +1,38 @@
+-- Migration: 2026-10-16-010
+-- Description: Scope budgets and expenses to users
+-- user_id 0 is the shared workspace used while authentication is disabled.
+-- Existing rows stay there until budgetctl assign-owner hands them to an account.
+-- budget_limits is rebuilt because one budget per month and year becomes one per user.
+
+ALTER TABLE expected_expenses ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE actual_expenses ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE TEMP TABLE budget_limits_copy AS
+SELECT id, month, year, amount, notification_threshold, created_at, updated_at FROM budget_limits;
+
+DROP TABLE budget_limits;
+
+CREATE TABLE budget_limits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    month INTEGER NOT NULL,
+    year INTEGER NOT NULL,
+    amount REAL NOT NULL,
+    notification_threshold REAL DEFAULT 0.8,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, month, year)
+);
+
+INSERT INTO budget_limits (id, month, year, amount, notification_threshold, created_at, updated_at)
+SELECT id, month, year, amount, notification_threshold, created_at, updated_at FROM budget_limits_copy;
+
+DROP TABLE budget_limits_copy;
+
+CREATE INDEX IF NOT EXISTS idx_expected_expenses_user ON expected_expenses(user_id);
+
+DROP INDEX IF EXISTS idx_actual_expenses_month_summary;
+
+CREATE INDEX IF NOT EXISTS idx_actual_expenses_user_month_summary
+    ON actual_expenses(user_id, year, month, expense_type, actual_amount);
@@ -0,0 +1,17 @@
+-- Migration: 2026-10-16-011
+-- Description: Add sessions holding refresh tokens
+-- Only a SHA-256 hash of the refresh token is stored. Refreshing rotates the
+-- hash, and revoking a session sets revoked_at so its token stops working.
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash TEXT NOT NULL UNIQUE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
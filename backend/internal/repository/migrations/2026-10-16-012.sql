@@ -0,0 +1,24 @@
+-- Migration: 2026-10-16-012
+-- Description: Add comment threads on actual expenses
+-- user_id is the author. Comments are removed with their expense by a trigger
+-- rather than a foreign key, like the expected expense reference in
+-- 2026-10-16-002, so a migration that rebuilds actual_expenses must recreate
+-- the trigger.
+
+CREATE TABLE IF NOT EXISTS expense_comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actual_expense_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    body TEXT NOT NULL CHECK (length(body) BETWEEN 1 AND 2000),
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_comments_expense
+    ON expense_comments(actual_expense_id, created_at);
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_delete_comments
+AFTER DELETE ON actual_expenses
+BEGIN
+    DELETE FROM expense_comments WHERE actual_expense_id = OLD.id;
+END;
@@ -0,0 +1,16 @@
+-- Migration: 2026-10-16-013
+-- Description: Add approval of large actual expenses
+-- Expenses over the approval rule amount are saved with pending_approval set
+-- and left out of totals until approved. Existing expenses count as approved.
+-- The summary index gains pending_approval so totals still read only the index.
+
+ALTER TABLE actual_expenses ADD COLUMN pending_approval INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE actual_expenses ADD COLUMN approved_at DATETIME;
+
+ALTER TABLE actual_expenses ADD COLUMN approved_by INTEGER;
+
+DROP INDEX IF EXISTS idx_actual_expenses_user_month_summary;
+
+CREATE INDEX IF NOT EXISTS idx_actual_expenses_user_month_summary
+    ON actual_expenses(user_id, year, month, pending_approval, expense_type, actual_amount);
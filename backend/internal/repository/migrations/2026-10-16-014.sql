@@ -0,0 +1,20 @@
+-- Migration: 2026-10-16-014
+-- Description: Add the audit log of budget and expense changes
+-- Rows are written by the repositories next to each create, update and delete.
+-- before_json and after_json hold snapshots of the record, NULL for the side
+-- that does not exist. user_id is the user who made the change.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    entity TEXT NOT NULL CHECK (entity IN ('budget', 'expected_expense', 'actual_expense')),
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before_json TEXT,
+    after_json TEXT,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_created ON audit_log(user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(user_id, entity, entity_id);
@@ -0,0 +1,14 @@
+-- Migration: 2026-10-16-015
+-- Description: Add allowance sub-accounts
+-- A sub-account is a users row without a password, owned by the parent user.
+-- Its tokens are restricted to logging its own expenses, and monthly_amount
+-- is the allowance its spending is measured against each month.
+
+CREATE TABLE IF NOT EXISTS allowance_accounts (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    monthly_amount REAL NOT NULL CHECK (monthly_amount >= 0),
+    created_at DATETIME NOT NULL,
+    UNIQUE(parent_id, name)
+);
@@ -0,0 +1,38 @@
+-- Migration: 2026-10-16-016
+-- Description: Exempt encrypted item names from the item_name length triggers
+-- With FIELD_ENCRYPTION_KEY set, item_name holds the encrypted, base64 encoded
+-- name, which is longer than the name itself. The 255 character limit is
+-- still enforced on the plain name before it is encrypted (models.MaxItemNameLength).
+
+DROP TRIGGER IF EXISTS trg_expected_expenses_item_name_insert;
+DROP TRIGGER IF EXISTS trg_expected_expenses_item_name_update;
+DROP TRIGGER IF EXISTS trg_actual_expenses_item_name_insert;
+DROP TRIGGER IF EXISTS trg_actual_expenses_item_name_update;
+
+CREATE TRIGGER IF NOT EXISTS trg_expected_expenses_item_name_insert
+BEFORE INSERT ON expected_expenses
+WHEN length(NEW.item_name) > 255 AND NEW.item_name NOT LIKE 'enc:v1:%'
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_expected_expenses_item_name_update
+BEFORE UPDATE OF item_name ON expected_expenses
+WHEN length(NEW.item_name) > 255 AND NEW.item_name NOT LIKE 'enc:v1:%'
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_item_name_insert
+BEFORE INSERT ON actual_expenses
+WHEN length(NEW.item_name) > 255 AND NEW.item_name NOT LIKE 'enc:v1:%'
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_item_name_update
+BEFORE UPDATE OF item_name ON actual_expenses
+WHEN length(NEW.item_name) > 255 AND NEW.item_name NOT LIKE 'enc:v1:%'
+BEGIN
+    SELECT RAISE(ABORT, 'item_name must not exceed 255 characters');
+END;
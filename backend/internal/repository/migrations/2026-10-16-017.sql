@@ -0,0 +1,19 @@
+-- Migration: 2026-10-16-017
+-- Description: Add email verification and single use account tokens
+-- user_tokens holds the tokens mailed for email verification and password
+-- reset. Like sessions, only a SHA-256 hash of the token is stored, and a
+-- token stops working once used_at is set or it expires.
+
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose);
@@ -0,0 +1,12 @@
+-- Migration: 2026-10-16-018
+-- Description: Count AI receipt processing calls per user and month
+-- Every receipt upload that reaches the AI provider adds one call to the
+-- month it was made in, failed ones included since they are billed too.
+-- month is formatted as YYYY-MM and user_id 0 is the shared workspace.
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+    user_id INTEGER NOT NULL DEFAULT 0,
+    month TEXT NOT NULL,
+    calls INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, month)
+);
@@ -0,0 +1,28 @@
+-- Migration: 2026-10-16-019
+-- Description: Add multi-month spending goals
+-- A goal caps the approved spending per month, optionally of one expense
+-- type, for a run of months starting at start_month/start_year.
+-- spending_goal_breaks records each month a goal was exceeded in, so the
+-- notification for a broken streak is sent once.
+
+CREATE TABLE IF NOT EXISTS spending_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    monthly_limit REAL NOT NULL CHECK (monthly_limit > 0),
+    expense_type TEXT CHECK (expense_type IN ('weekly', 'monthly', 'misc', 'tax')),
+    start_month INTEGER NOT NULL CHECK (start_month BETWEEN 1 AND 12),
+    start_year INTEGER NOT NULL,
+    months INTEGER NOT NULL CHECK (months BETWEEN 1 AND 60),
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_spending_goals_user ON spending_goals(user_id);
+
+CREATE TABLE IF NOT EXISTS spending_goal_breaks (
+    goal_id INTEGER NOT NULL REFERENCES spending_goals(id) ON DELETE CASCADE,
+    month INTEGER NOT NULL,
+    year INTEGER NOT NULL,
+    notified_at DATETIME NOT NULL,
+    PRIMARY KEY (goal_id, year, month)
+);
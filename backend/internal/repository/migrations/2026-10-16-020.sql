@@ -0,0 +1,7 @@
+-- Migration: 2026-10-16-020
+-- Description: Add the admin role to users
+-- Admins may use the /api/admin endpoints with their normal sign-in token,
+-- as an alternative to the shared ADMIN_TOKEN. Nobody is an admin until
+-- granted with budgetctl set-admin or by another admin.
+
+ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- Migration: 2026-10-16-021
+-- Description: Let receipt items count toward the month before the receipt
+-- Set when an item was saved with budget_month and budget_year, so month and
+-- year were chosen instead of following receipt_date. The integrity check and
+-- repair accept the month before the receipt for these rows.
+
+ALTER TABLE actual_expenses ADD COLUMN month_assigned INTEGER NOT NULL DEFAULT 0;
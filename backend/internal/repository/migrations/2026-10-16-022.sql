@@ -0,0 +1,23 @@
+-- Migration: 2026-10-16-022
+-- Description: Add category rules that set the expense type of new expenses
+-- A rule matches when every condition it has is met: the source and item
+-- name contain the given text, ignoring case, and the amount is within
+-- min_amount and max_amount. The enabled rule with the highest priority wins,
+-- the oldest one on a tie.
+
+CREATE TABLE IF NOT EXISTS category_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    source_contains TEXT NOT NULL DEFAULT '',
+    item_contains TEXT NOT NULL DEFAULT '',
+    min_amount REAL,
+    max_amount REAL,
+    expense_type TEXT NOT NULL CHECK (expense_type IN ('weekly', 'monthly', 'misc', 'tax')),
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_rules_user ON category_rules(user_id);
@@ -0,0 +1,16 @@
+-- Migration: 2026-10-16-023
+-- Description: Keep the history of budget amount and threshold changes
+-- One row per change of a budget limit, with the values before and after it.
+-- Creating a budget is not recorded, the first row holds its initial values.
+
+CREATE TABLE IF NOT EXISTS budget_limit_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    budget_id INTEGER NOT NULL REFERENCES budget_limits(id) ON DELETE CASCADE,
+    old_amount REAL NOT NULL,
+    new_amount REAL NOT NULL,
+    old_threshold REAL NOT NULL,
+    new_threshold REAL NOT NULL,
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_limit_history_budget ON budget_limit_history(budget_id);
@@ -0,0 +1,11 @@
+-- Migration: 2026-10-16-024
+-- Description: Record the weeks a weekly pace nudge was sent in
+-- One row per user and week, so the mid-week nudge for weekly spending
+-- running ahead of the expected weekly amount is sent once a week.
+
+CREATE TABLE IF NOT EXISTS weekly_pace_nudges (
+    user_id INTEGER NOT NULL,
+    week_start DATE NOT NULL,
+    notified_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, week_start)
+);
@@ -0,0 +1,6 @@
+-- Migration: 2026-10-16-025
+-- Description: Let a budget roll the unspent amount of the month before over
+-- When rollover_unspent is 1, what was left of the previous month's budget
+-- is added to the month's effective limit in the budget status.
+
+ALTER TABLE budget_limits ADD COLUMN rollover_unspent INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- Migration: 2026-10-16-026
+-- Description: Allow several notification thresholds per budget
+-- Each budget gets one row per threshold, e.g. 0.5, 0.8 and 1.0. The
+-- notification_threshold column stays as the lowest of them, and existing
+-- budgets start with that single threshold.
+
+CREATE TABLE IF NOT EXISTS budget_limit_thresholds (
+    budget_id INTEGER NOT NULL REFERENCES budget_limits(id) ON DELETE CASCADE,
+    threshold REAL NOT NULL,
+    PRIMARY KEY (budget_id, threshold)
+);
+
+INSERT OR IGNORE INTO budget_limit_thresholds (budget_id, threshold)
+SELECT id, notification_threshold FROM budget_limits
+WHERE notification_threshold IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Migration: 2026-10-16-027
+-- Description: Add an optional weekly cap to budgets
+-- weekly_limit caps the spending of each week of the month alongside the
+-- monthly amount. 0 means the budget has no weekly cap.
+
+ALTER TABLE budget_limits ADD COLUMN weekly_limit REAL NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Migration: 2026-10-16-028
+-- Description: Track the notification thresholds a budget has crossed
+-- A row is added when spending crosses a threshold and the alert is sent,
+-- and removed when spending falls back below it, so the next crossing
+-- alerts again.
+
+CREATE TABLE IF NOT EXISTS budget_threshold_alerts (
+    budget_id INTEGER NOT NULL REFERENCES budget_limits(id) ON DELETE CASCADE,
+    threshold REAL NOT NULL,
+    fired_at DATETIME NOT NULL,
+    PRIMARY KEY (budget_id, threshold)
+);
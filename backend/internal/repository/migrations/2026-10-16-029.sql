@@ -0,0 +1,26 @@
+-- Migration: 2026-10-16-029
+-- Description: Store AI suggestions for expenses entered tersely
+-- The expense enrichment job adds one row per expense it asked about, with
+-- the status pending, or none when the AI could not tell what it is, so no
+-- expense is asked about twice. item_name is encrypted like the name of the
+-- expense. Rows are removed with their expense by a trigger, like the
+-- comments in 2026-10-16-012.
+
+CREATE TABLE IF NOT EXISTS expense_suggestions (
+    actual_expense_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    item_name TEXT NOT NULL,
+    expense_type TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    resolved_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_suggestions_user_status
+    ON expense_suggestions(user_id, status);
+
+CREATE TRIGGER IF NOT EXISTS trg_actual_expenses_delete_suggestions
+AFTER DELETE ON actual_expenses
+BEGIN
+    DELETE FROM expense_suggestions WHERE actual_expense_id = OLD.id;
+END;
@@ -0,0 +1,6 @@
+-- Migration: 2026-10-16-030
+-- Description: Let a budget close its month after it was reconciled
+-- While closed_at is set, the actual expenses of the month of the budget
+-- cannot be created, changed or deleted until it is reopened.
+
+ALTER TABLE budget_limits ADD COLUMN closed_at DATETIME;
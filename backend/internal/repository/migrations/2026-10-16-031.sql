@@ -0,0 +1,17 @@
+-- Migration: 2026-10-16-031
+-- Description: Add per-source budget caps
+-- A cap limits the approved spending per month at one source, e.g. Costco,
+-- every month. Sources match ignoring case, so a user has one cap per source.
+-- The source is stored like source_contains of the category rules.
+
+CREATE TABLE IF NOT EXISTS source_caps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    source TEXT NOT NULL,
+    monthly_limit REAL NOT NULL CHECK (monthly_limit > 0),
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_source_caps_user_source
+    ON source_caps(user_id, source COLLATE NOCASE);
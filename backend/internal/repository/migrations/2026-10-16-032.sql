@@ -0,0 +1,6 @@
+-- Migration: 2026-10-16-032
+-- Description: Soft delete budgets so they can be restored
+-- Deleting a budget sets deleted_at and keeps its thresholds and history.
+-- Creating a budget for the month of a deleted one removes the deleted one.
+
+ALTER TABLE budget_limits ADD COLUMN deleted_at DATETIME;
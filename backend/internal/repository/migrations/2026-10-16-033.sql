@@ -0,0 +1,10 @@
+-- Migration: 2026-10-16-033
+-- Description: Tag actual expenses as tax deductible, manually or by a category rule
+-- A matching rule with deductible set marks new expenses deductible.
+-- The partial index serves the year-end deductible report.
+
+ALTER TABLE actual_expenses ADD COLUMN deductible INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE category_rules ADD COLUMN deductible INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_actual_expenses_deductible ON actual_expenses(user_id, year) WHERE deductible = 1;
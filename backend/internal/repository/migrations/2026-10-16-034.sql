@@ -0,0 +1,11 @@
+-- Migration: 2026-10-16-034
+-- Description: Due dates and recurrence rules on expected expenses
+-- due_day is the day of the month of a monthly item or the ISO weekday of a weekly one.
+-- recurrence holds an RRULE for other schedules and takes precedence over due_day.
+-- starts_on anchors recurrence intervals and defaults to the creation date when NULL.
+
+ALTER TABLE expected_expenses ADD COLUMN due_day INTEGER;
+
+ALTER TABLE expected_expenses ADD COLUMN recurrence TEXT;
+
+ALTER TABLE expected_expenses ADD COLUMN starts_on DATE;
@@ -0,0 +1,18 @@
+-- Migration: 2026-10-16-035
+-- Description: Generate actual expenses from scheduled expected expenses
+-- An expected expense with auto_generate set gets an actual expense on each due date.
+-- Those expenses have auto_generated set.
+-- generated_expenses records each due date handled, as YYYY-MM-DD, so it is generated once
+-- even when its expense is deleted. actual_expense_id is NULL when nothing was created.
+
+ALTER TABLE expected_expenses ADD COLUMN auto_generate INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE actual_expenses ADD COLUMN auto_generated INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS generated_expenses (
+    expected_expense_id INTEGER NOT NULL REFERENCES expected_expenses(id) ON DELETE CASCADE,
+    due_date DATE NOT NULL,
+    actual_expense_id INTEGER,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (expected_expense_id, due_date)
+);
@@ -0,0 +1,7 @@
+-- Migration: 2026-10-16-036
+-- Description: Archive expected expenses
+-- An archived expected expense, e.g. a cancelled subscription, has is_active unset.
+-- It keeps its linked actual expenses but no longer counts toward the expected totals,
+-- is never due and generates no expenses.
+
+ALTER TABLE expected_expenses ADD COLUMN is_active INTEGER NOT NULL DEFAULT 1;
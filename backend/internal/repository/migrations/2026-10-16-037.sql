@@ -0,0 +1,6 @@
+-- Migration: 2026-10-16-037
+-- Description: Categories on expected expenses
+-- category groups expected expenses, e.g. Groceries or Subscriptions, for filtering
+-- and as a hint for categorizing receipt items. NULL when uncategorized.
+
+ALTER TABLE expected_expenses ADD COLUMN category TEXT;
@@ -0,0 +1,7 @@
+-- Migration: 2026-10-16-038
+-- Description: Tag actual expenses
+-- tags holds the lower-case tags of the expense between commas, e.g.
+-- ,work,travel, so a tag is matched with LIKE on its surrounding commas. It
+-- is empty for an expense without tags.
+
+ALTER TABLE actual_expenses ADD COLUMN tags TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Migration: 2026-10-16-039
+-- Description: Scope notifications to users
+-- Each job and request notifies the user whose data it is about.
+-- user_id 0 is the shared workspace used while authentication is disabled,
+-- where existing notifications stay until budgetctl assign-owner moves them.
+
+ALTER TABLE notifications ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
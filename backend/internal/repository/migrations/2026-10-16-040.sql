@@ -0,0 +1,13 @@
+-- Migration: 2026-10-16-040
+-- Description: Track the escalated alerts of a budget
+-- Past its notification thresholds a budget escalates to danger, from 90% of
+-- its amount, and over, past it. A row records when a level was last
+-- alerted, so danger repeats daily and over alerts once, and is removed when
+-- spending falls back below the level.
+
+CREATE TABLE IF NOT EXISTS budget_alert_escalations (
+    budget_id INTEGER NOT NULL REFERENCES budget_limits(id) ON DELETE CASCADE,
+    level TEXT NOT NULL,
+    alerted_at DATETIME NOT NULL,
+    PRIMARY KEY (budget_id, level)
+);
@@ -2,16 +2,19 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"testing"
 
 	_ "github.com/tursodatabase/go-libsql"
 )
 
-// setupTestDB creates an in-memory SQLite database for testing
+// setupTestDB creates an in-memory SQLite database for testing.
+// Each test gets its own named database so state never leaks between tests.
 func setupTestDB(t *testing.T) *DB {
 	t.Helper()
 
-	sqlDB, err := sql.Open("libsql", "file::memory:?cache=shared")
+	sqlDB, err := sql.Open("libsql", testDSN(t))
 	if err != nil {
 		t.Fatalf("Failed to open in-memory database: %v", err)
 	}
@@ -19,6 +22,12 @@ func setupTestDB(t *testing.T) *DB {
 	return &DB{DB: sqlDB}
 }
 
+// testDSN returns an in-memory DSN unique to the running test
+func testDSN(t *testing.T) string {
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	return fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
+}
+
 // TestSplitSQLStatements tests the splitSQLStatements function
 func TestSplitSQLStatements(t *testing.T) {
 	tests := []struct {
@@ -94,6 +103,28 @@ CREATE INDEX idx_orders ON orders(id);`,
 			sql:      ";;;",
 			expected: []string{},
 		},
+		{
+			name: "trigger body kept together",
+			sql: `CREATE TRIGGER trg BEFORE INSERT ON t
+BEGIN
+    SELECT RAISE(ABORT, 'bad');
+    SELECT 1;
+END;
+SELECT 2;`,
+			expected: []string{
+				"CREATE TRIGGER trg BEFORE INSERT ON t\nBEGIN\n    SELECT RAISE(ABORT, 'bad');\n    SELECT 1;\nEND",
+				"SELECT 2",
+			},
+		},
+		{
+			name: "temp trigger after comment",
+			sql: `-- guard
+CREATE TEMP TRIGGER IF NOT EXISTS trg AFTER DELETE ON t BEGIN DELETE FROM u; END; SELECT 1;`,
+			expected: []string{
+				"-- guard\nCREATE TEMP TRIGGER IF NOT EXISTS trg AFTER DELETE ON t BEGIN DELETE FROM u; END",
+				"SELECT 1",
+			},
+		},
 		{
 			name: "complex migration with comments",
 			sql: `-- This is a comment
@@ -603,3 +634,93 @@ func TestRunMigrations_TransactionRollback(t *testing.T) {
 		}
 	}
 }
+
+// TestRunMigrations_ItemNameLengthConstraint tests that both expense tables
+// enforce the shared item_name length limit
+func TestRunMigrations_ItemNameLengthConstraint(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error: %v", err)
+	}
+
+	maxName := strings.Repeat("a", 255)
+	tooLong := strings.Repeat("a", 256)
+
+	t.Run("expected_expenses accepts 255 characters", func(t *testing.T) {
+		_, err := db.Exec(`
+			INSERT INTO expected_expenses (item_name, source, expected_amount, expense_type)
+			VALUES (?, 'Costco', 1, 'weekly')
+		`, maxName)
+		if err != nil {
+			t.Errorf("Expected 255 character item_name to be accepted: %v", err)
+		}
+	})
+
+	t.Run("expected_expenses rejects 256 characters", func(t *testing.T) {
+		_, err := db.Exec(`
+			INSERT INTO expected_expenses (item_name, source, expected_amount, expense_type)
+			VALUES (?, 'Costco', 1, 'weekly')
+		`, tooLong)
+		if err == nil {
+			t.Error("Expected 256 character item_name to be rejected")
+		}
+	})
+
+	t.Run("actual_expenses rejects 256 characters on update", func(t *testing.T) {
+		result, err := db.Exec(`
+			INSERT INTO actual_expenses (item_name, source, actual_amount, expense_type, month, year)
+			VALUES ('Milk', 'Publix', 1, 'weekly', 1, 2025)
+		`)
+		if err != nil {
+			t.Fatalf("Failed to insert actual expense: %v", err)
+		}
+		id, _ := result.LastInsertId()
+
+		if _, err := db.Exec(
+			"UPDATE actual_expenses SET item_name = ? WHERE id = ?",
+			tooLong,
+			id,
+		); err == nil {
+			t.Error("Expected 256 character item_name update to be rejected")
+		}
+	})
+}
+
+func TestVerifyMigrations_DoesNotChangeDatabase(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	pending, err := db.VerifyMigrations()
+	if err != nil {
+		t.Fatalf("VerifyMigrations failed: %v", err)
+	}
+	if len(pending) != len(migrations) {
+		t.Errorf("Expected all %d migrations pending on a new database, got %d", len(migrations), len(pending))
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`).Scan(&tables); err != nil {
+		t.Fatalf("Failed to count tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("Expected VerifyMigrations to roll back, found %d tables", tables)
+	}
+
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	pending, err = db.VerifyMigrations()
+	if err != nil {
+		t.Fatalf("VerifyMigrations failed: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending migrations after RunMigrations, got %d", len(pending))
+	}
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// MaxNotifications caps the number of notifications returned by List
+const MaxNotifications = 100
+
+// NotificationRepository handles notifications database operations.
+// It manages the notifications of one user, see ForUser.
+type NotificationRepository struct {
+	db       *DB
+	userID   int64
+	onCreate func(*models.Notification)
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository(db *DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that manages userID's
+// notifications. The copy shares the OnCreate hook set so far.
+func (r *NotificationRepository) ForUser(userID int64) *NotificationRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// OnCreate sets a function called with every notification Create stores,
+// e.g. to deliver it elsewhere too. It runs in the caller's goroutine.
+func (r *NotificationRepository) OnCreate(hook func(*models.Notification)) {
+	r.onCreate = hook
+}
+
+// Create stores a notification for the scoped user
+func (r *NotificationRepository) Create(n *models.Notification) (*models.Notification, error) {
+	var link sql.NullString
+	if n.Link != "" {
+		link = sql.NullString{String: n.Link, Valid: true}
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO notifications (user_id, kind, title, message, link) VALUES (?, ?, ?, ?, ?)
+	`, r.userID, n.Kind, n.Title, n.Message, link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	stored, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.onCreate != nil {
+		r.onCreate(stored)
+	}
+	return stored, nil
+}
+
+// CreateFor stores a notification for userID, whichever user the repository
+// is scoped to
+func (r *NotificationRepository) CreateFor(userID int64, n *models.Notification) (*models.Notification, error) {
+	return r.ForUser(userID).Create(n)
+}
+
+// GetByID retrieves a notification of the scoped user by ID
+func (r *NotificationRepository) GetByID(id int64) (*models.Notification, error) {
+	rows, err := r.db.Query(`
+		SELECT id, kind, title, message, link, read_at, created_at
+		FROM notifications WHERE id = ? AND user_id = ?
+	`, id, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	defer rows.Close()
+
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, ErrNotificationNotFound
+	}
+	return &notifications[0], nil
+}
+
+// List returns the scoped user's newest notifications first, optionally
+// only unread ones
+func (r *NotificationRepository) List(unreadOnly bool) ([]models.Notification, error) {
+	rows, err := r.db.Query(`
+		SELECT id, kind, title, message, link, read_at, created_at
+		FROM notifications
+		WHERE user_id = ? AND (? = 0 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, r.userID, unreadOnly, MaxNotifications)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// MarkRead marks a notification of the scoped user as read; marking it
+// again keeps the first read time
+func (r *NotificationRepository) MarkRead(id int64) (*models.Notification, error) {
+	result, err := r.db.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?
+	`, time.Now(), id, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrNotificationNotFound
+	}
+
+	return r.GetByID(id)
+}
+
+func scanNotifications(rows *sql.Rows) ([]models.Notification, error) {
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		var link sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(
+			&n.ID, &n.Kind, &n.Title, &n.Message, &link, &readAt, &n.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		n.Link = link.String
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"fmt"
+	"time"
+)
+
+// WeeklyPaceRepository compares the weekly spending of the current week with
+// the weekly expected expenses. It reads the expenses of one user, see
+// ForUser.
+type WeeklyPaceRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewWeeklyPaceRepository creates a new WeeklyPaceRepository
+func NewWeeklyPaceRepository(db *DB) *WeeklyPaceRepository {
+	return &WeeklyPaceRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that reads userID's expenses
+func (r *WeeklyPaceRepository) ForUser(userID int64) *WeeklyPaceRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// UserIDs returns every user with active weekly expected expenses
+func (r *WeeklyPaceRepository) UserIDs() ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT user_id FROM expected_expenses
+		WHERE expense_type = 'weekly' AND is_active = 1
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekly plan owners: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan weekly plan owner: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating weekly plan owners: %w", err)
+	}
+	return ids, nil
+}
+
+// Pace returns the scoped user's weekly spending in the week containing now,
+// from Monday up to now. Expenses are dated by their receipt, and expenses
+// held for approval are not counted.
+func (r *WeeklyPaceRepository) Pace(now time.Time) (*models.WeeklyPace, error) {
+	now = now.UTC()
+	pace := &models.WeeklyPace{WeekStart: models.WeekStart(now)}
+	pace.Days = int(now.Sub(pace.WeekStart)/(24*time.Hour)) + 1
+
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(expected_amount), 0) FROM expected_expenses
+		WHERE user_id = ? AND expense_type = 'weekly' AND is_active = 1
+	`, r.userID).Scan(&pace.Expected); err != nil {
+		return nil, fmt.Errorf("failed to sum weekly expected expenses: %w", err)
+	}
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses
+		WHERE user_id = ? AND expense_type = 'weekly' AND pending_approval = 0
+			AND receipt_date >= ? AND receipt_date <= ?
+	`, r.userID, pace.WeekStart, now).Scan(&pace.Spent); err != nil {
+		return nil, fmt.Errorf("failed to sum weekly spending: %w", err)
+	}
+	return pace, nil
+}
+
+// RecordNudge marks the week starting at weekStart as nudged for the scoped
+// user and reports whether it was not marked before, so each week is nudged
+// once
+func (r *WeeklyPaceRepository) RecordNudge(weekStart time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT OR IGNORE INTO weekly_pace_nudges (user_id, week_start, notified_at)
+		VALUES (?, ?, ?)
+	`, r.userID, weekStart.Format("2006-01-02"), time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to record weekly pace nudge: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record weekly pace nudge: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Latency budgets for the repository queries behind the list and summary
+// endpoints, as p95 over perfIterations calls on perfExpenses expenses spread
+// over two years. They are generous so only real regressions (e.g. a lost
+// index or a query that scans every row per call) fail; run with -v to see
+// the measured values. Wall-clock budgets are flaky on a busy machine, so
+// the test only runs with BUDGET_PERF=1, like the benchmarks only run with
+// -bench.
+const (
+	perfExpenses   = 20_000
+	perfIterations = 100
+)
+
+func TestPerformanceBudget(t *testing.T) {
+	if os.Getenv("BUDGET_PERF") != "1" {
+		t.Skip("set BUDGET_PERF=1 to check the performance budget")
+	}
+
+	db := setupTestDB(t)
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	seedActualExpenses(t, db, perfExpenses)
+	repo := NewActualExpenseRepository(db)
+
+	cases := []struct {
+		name   string
+		budget time.Duration
+		run    func() error
+	}{
+		{"GetByMonthYear", 100 * time.Millisecond, func() error {
+			_, err := repo.GetByMonthYear(6, 2025)
+			return err
+		}},
+		{"List page", 50 * time.Millisecond, func() error {
+			_, err := repo.List(ExpenseFilter{Limit: 50})
+			return err
+		}},
+		{"List search", 100 * time.Millisecond, func() error {
+			_, err := repo.List(ExpenseFilter{Search: "item 42", Limit: 50})
+			return err
+		}},
+		{"Count month", 10 * time.Millisecond, func() error {
+			_, err := repo.Count(ExpenseFilter{Month: 6, Year: 2025})
+			return err
+		}},
+		{"GetMonthlySummary", 10 * time.Millisecond, func() error {
+			_, err := repo.GetMonthlySummary(6, 2025)
+			return err
+		}},
+		{"GetMonthlyTotal", 10 * time.Millisecond, func() error {
+			_, err := repo.GetMonthlyTotal(6, 2025)
+			return err
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p95, err := measureP95(perfIterations, tc.run)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			t.Logf("p95 %s (budget %s)", p95, tc.budget)
+			if p95 > tc.budget {
+				t.Errorf("p95 latency %s exceeds budget %s", p95, tc.budget)
+			}
+		})
+	}
+}
+
+// measureP95 calls run n times and returns the nearest-rank p95 latency
+func measureP95(n int, run func() error) (time.Duration, error) {
+	latencies := make([]time.Duration, n)
+	for i := range latencies {
+		start := time.Now()
+		if err := run(); err != nil {
+			return 0, err
+		}
+		latencies[i] = time.Since(start)
+	}
+	slices.Sort(latencies)
+	return latencies[(n*95+99)/100-1], nil
+}
+
+// seedActualExpenses inserts count expenses spread over 2024 and 2025, about
+// ten items per receipt, in a single transaction
+func seedActualExpenses(tb testing.TB, db *DB, count int) {
+	tb.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		tb.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO actual_expenses
+		(item_name, source, actual_amount, expense_type, receipt_date, month, year, receipt_number)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tb.Fatalf("Failed to prepare insert: %v", err)
+	}
+	defer stmt.Close()
+
+	types := []string{"weekly", "weekly", "weekly", "monthly", "misc", "tax"}
+	sources := []string{"Publix", "Target", "Costco", "Amazon", "Walgreens"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range count {
+		receipt := i / 10
+		date := start.AddDate(0, 0, receipt%730)
+		_, err := stmt.Exec(
+			fmt.Sprintf("Item %d", i%500),
+			sources[receipt%len(sources)],
+			float64(i%5000)/100+0.99,
+			types[i%len(types)],
+			date.Format("2006-01-02"),
+			int(date.Month()),
+			date.Year(),
+			receipt+1,
+		)
+		if err != nil {
+			tb.Fatalf("Failed to insert expense %d: %v", i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tb.Fatalf("Failed to commit seed data: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Pivot totals the scoped user's approved expenses in year along the rows
+// and cols dimensions (models.PivotCategory, PivotMonth or PivotSource).
+// Stores may be encrypted, so they are grouped after decrypting.
+func (r *ActualExpenseRepository) Pivot(year int, rows, cols string) (*models.PivotTable, error) {
+	if err := models.ValidatePivot(rows, cols); err != nil {
+		return nil, err
+	}
+
+	// Without stores the totals come from the summary index alone
+	source := "''"
+	if rows == models.PivotSource || cols == models.PivotSource {
+		source = "source"
+	}
+	result, err := r.db.Query(`
+		SELECT expense_type, month, `+source+`, SUM(actual_amount)
+		FROM actual_expenses
+		WHERE user_id = ? AND year = ? AND pending_approval = 0
+		GROUP BY 1, 2, 3
+	`, r.userID, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pivot totals: %w", err)
+	}
+	defer result.Close()
+
+	type cell struct{ row, col string }
+	totals := make(map[cell]float64)
+	stores := make(map[string]bool)
+	for result.Next() {
+		var expenseType, store string
+		var month int
+		var amount float64
+		if err := result.Scan(&expenseType, &month, &store, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan pivot total: %w", err)
+		}
+		if store, err = openField("source", store); err != nil {
+			return nil, err
+		}
+		stores[store] = true
+
+		keys := map[string]string{
+			models.PivotCategory: expenseType,
+			models.PivotMonth:    strconv.Itoa(month),
+			models.PivotSource:   store,
+		}
+		totals[cell{keys[rows], keys[cols]}] += amount
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pivot totals: %w", err)
+	}
+
+	table := &models.PivotTable{
+		Year:    year,
+		Rows:    rows,
+		Cols:    cols,
+		RowKeys: pivotKeys(rows, stores),
+		ColKeys: pivotKeys(cols, stores),
+	}
+	table.Cells = make([][]float64, len(table.RowKeys))
+	table.RowTotals = make([]float64, len(table.RowKeys))
+	table.ColTotals = make([]float64, len(table.ColKeys))
+	for i, row := range table.RowKeys {
+		table.Cells[i] = make([]float64, len(table.ColKeys))
+		for j, col := range table.ColKeys {
+			total := totals[cell{row, col}]
+			table.Cells[i][j] = total
+			table.RowTotals[i] += total
+			table.ColTotals[j] += total
+			table.Total += total
+		}
+	}
+	return table, nil
+}
+
+// pivotKeys lists the keys of dimension in table order
+func pivotKeys(dimension string, stores map[string]bool) []string {
+	switch dimension {
+	case models.PivotCategory:
+		return []string{
+			string(models.ExpenseTypeWeekly), string(models.ExpenseTypeMonthly),
+			string(models.ExpenseTypeMisc), string(models.ExpenseTypeTax),
+		}
+	case models.PivotMonth:
+		keys := make([]string, 12)
+		for i := range keys {
+			keys[i] = strconv.Itoa(i + 1)
+		}
+		return keys
+	default:
+		keys := make([]string, 0, len(stores))
+		for store := range stores {
+			keys = append(keys, store)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			return cmp.Or(strings.Compare(strings.ToLower(a), strings.ToLower(b)), strings.Compare(a, b))
+		})
+		return keys
+	}
+}
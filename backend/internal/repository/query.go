@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// MaxListLimit caps the page size accepted by list methods
+const MaxListLimit = 500
+
+// ExpenseFilter holds the optional filters supported by the expense list methods.
+// Zero values mean "no filter". Each repository only allows the filters that
+// make sense for its table; using an unsupported one returns ErrInvalidFilter.
+type ExpenseFilter struct {
+	Type          models.ExpenseType
+	Category      string // expected expenses of the category, in any case
+	Month         int
+	Year          int
+	ReceiptNumber int64
+	From          *time.Time // inclusive receipt_date lower bound
+	To            *time.Time // inclusive receipt_date upper bound
+	Search        string     // case-insensitive substring match on text columns
+	Pending       *bool      // actual expenses pending approval, or not
+	Deductible    *bool      // actual expenses marked deductible, or not
+	AutoGenerated *bool      // actual expenses generated from an expected expense, or entered
+	Tags          []string   // actual expenses with all of these lower-case tags
+	Active        *bool      // expected expenses not archived, or archived
+	Limit         int
+	Offset        int
+}
+
+// selectBuilder assembles SELECT statements from allowlisted columns and
+// operators so filter values are always bound as parameters and user input
+// never reaches the SQL text
+type selectBuilder struct {
+	table      string
+	columns    string
+	filterable map[string]bool
+	conditions []string
+	args       []any
+	orderBy    string
+	limit      int
+	offset     int
+	err        error
+}
+
+// allowedOperators lists the comparison operators accepted by where
+var allowedOperators = map[string]bool{
+	"=": true, "<": true, "<=": true, ">": true, ">=": true, "LIKE": true,
+}
+
+func newSelectBuilder(table, columns string, filterable ...string) *selectBuilder {
+	b := &selectBuilder{
+		table:      table,
+		columns:    columns,
+		filterable: make(map[string]bool, len(filterable)),
+	}
+	for _, c := range filterable {
+		b.filterable[c] = true
+	}
+	return b
+}
+
+// where adds "column op ?" to the WHERE clause
+func (b *selectBuilder) where(column, op string, value any) *selectBuilder {
+	if err := b.check(column, op); err != nil {
+		b.err = err
+		return b
+	}
+	b.conditions = append(b.conditions, condition(column, op))
+	b.args = append(b.args, value)
+	return b
+}
+
+// whereAny adds "(c1 op ? OR c2 op ? ...)" binding the same value to each column
+func (b *selectBuilder) whereAny(columns []string, op string, value any) *selectBuilder {
+	clauses := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if err := b.check(column, op); err != nil {
+			b.err = err
+			return b
+		}
+		clauses = append(clauses, condition(column, op))
+		b.args = append(b.args, value)
+	}
+	b.conditions = append(b.conditions, "("+strings.Join(clauses, " OR ")+")")
+	return b
+}
+
+// whereIn adds "column IN (?, ?, ...)"; an empty list matches nothing
+func (b *selectBuilder) whereIn(column string, values []any) *selectBuilder {
+	if err := b.check(column, "="); err != nil {
+		b.err = err
+		return b
+	}
+	if len(values) == 0 {
+		b.conditions = append(b.conditions, "0")
+		return b
+	}
+	b.conditions = append(b.conditions, column+" IN (?"+strings.Repeat(", ?", len(values)-1)+")")
+	b.args = append(b.args, values...)
+	return b
+}
+
+// whereNull adds "column IS NULL"
+func (b *selectBuilder) whereNull(column string) *selectBuilder {
+	if err := b.check(column, "="); err != nil {
+		b.err = err
+		return b
+	}
+	b.conditions = append(b.conditions, column+" IS NULL")
+	return b
+}
+
+// condition renders a single parameterized comparison
+func condition(column, op string) string {
+	if op == "LIKE" {
+		return column + ` LIKE ? ESCAPE '\'`
+	}
+	return column + " " + op + " ?"
+}
+
+func (b *selectBuilder) check(column, op string) error {
+	if !b.filterable[column] {
+		return fmt.Errorf("%w: %s cannot be filtered on %s", ErrInvalidFilter, b.table, column)
+	}
+	if !allowedOperators[op] {
+		return fmt.Errorf("%w: unsupported operator %q", ErrInvalidFilter, op)
+	}
+	return nil
+}
+
+// order sets the ORDER BY clause; callers pass trusted constant strings only
+func (b *selectBuilder) order(orderBy string) *selectBuilder {
+	b.orderBy = orderBy
+	return b
+}
+
+// checkPage validates a limit and offset; a limit of 0 means no limit
+func checkPage(limit, offset int) error {
+	if limit < 0 || offset < 0 {
+		return fmt.Errorf("%w: limit and offset must not be negative", ErrInvalidFilter)
+	}
+	if limit > MaxListLimit {
+		return fmt.Errorf("%w: limit must not exceed %d", ErrInvalidFilter, MaxListLimit)
+	}
+	return nil
+}
+
+func (b *selectBuilder) page(limit, offset int) *selectBuilder {
+	if err := checkPage(limit, offset); err != nil {
+		b.err = err
+		return b
+	}
+	b.limit = limit
+	b.offset = offset
+	return b
+}
+
+// pageSlice applies a checked limit and offset to rows filtered in Go, the
+// way page does in SQL
+func pageSlice[T any](rows []T, limit, offset int) []T {
+	if offset >= len(rows) {
+		return []T{}
+	}
+	rows = rows[offset:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func (b *selectBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// build returns the SELECT statement and its arguments
+func (b *selectBuilder) build() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	query := "SELECT " + b.columns + " FROM " + b.table + b.whereClause()
+	args := append([]any{}, b.args...)
+	if b.orderBy != "" {
+		query += " ORDER BY " + b.orderBy
+	}
+	if b.limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, b.limit)
+		if b.offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, b.offset)
+		}
+	} else if b.offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, b.offset)
+	}
+
+	return query, args, nil
+}
+
+// buildCount returns a COUNT(*) statement over the same filters, ignoring paging
+func (b *selectBuilder) buildCount() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return "SELECT COUNT(*) FROM " + b.table + b.whereClause(), b.args, nil
+}
+
+// likePattern wraps s for a substring LIKE match, escaping LIKE wildcards
+func likePattern(s string) string {
+	return "%" + likeEscape(s) + "%"
+}
+
+// likeEscape escapes the LIKE wildcards of s, for a whole-value match that
+// ignores case
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
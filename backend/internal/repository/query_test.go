@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSelectBuilder_Build(t *testing.T) {
+	query, args, err := newSelectBuilder("things", "id, name", "name", "kind").
+		where("kind", "=", "a").
+		whereAny([]string{"name", "kind"}, "LIKE", likePattern("50%_off")).
+		order("id DESC").
+		page(10, 20).
+		build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedQuery := `SELECT id, name FROM things WHERE kind = ? AND ` +
+		`(name LIKE ? ESCAPE '\' OR kind LIKE ? ESCAPE '\') ORDER BY id DESC LIMIT ? OFFSET ?`
+	if query != expectedQuery {
+		t.Errorf("Unexpected query:\n got: %s\nwant: %s", query, expectedQuery)
+	}
+
+	pattern := `%50\%\_off%`
+	expectedArgs := []any{"a", pattern, pattern, 10, 20}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, got %v", expectedArgs, args)
+	}
+}
+
+func TestSelectBuilder_WhereIn(t *testing.T) {
+	query, args, err := newSelectBuilder("things", "id", "id").
+		whereIn("id", []any{int64(1), int64(2), int64(3)}).
+		build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query != "SELECT id FROM things WHERE id IN (?, ?, ?)" {
+		t.Errorf("Unexpected query: %s", query)
+	}
+	if len(args) != 3 {
+		t.Errorf("Expected 3 args, got %v", args)
+	}
+
+	query, _, err = newSelectBuilder("things", "id", "id").whereIn("id", nil).build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query != "SELECT id FROM things WHERE 0" {
+		t.Errorf("Expected an empty list to match nothing, got: %s", query)
+	}
+
+	if _, _, err := newSelectBuilder("things", "id").whereIn("name", []any{"a"}).build(); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expected ErrInvalidFilter for a column that is not filterable, got %v", err)
+	}
+}
+
+func TestSelectBuilder_OffsetWithoutLimit(t *testing.T) {
+	query, args, err := newSelectBuilder("things", "id").page(0, 5).build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query != "SELECT id FROM things LIMIT -1 OFFSET ?" {
+		t.Errorf("Unexpected query: %s", query)
+	}
+	if len(args) != 1 || args[0] != 5 {
+		t.Errorf("Expected offset arg, got %v", args)
+	}
+}
+
+func TestSelectBuilder_CountIgnoresPaging(t *testing.T) {
+	query, args, err := newSelectBuilder("things", "id", "kind").
+		where("kind", "=", "a").
+		page(10, 20).
+		buildCount()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query != "SELECT COUNT(*) FROM things WHERE kind = ?" {
+		t.Errorf("Unexpected query: %s", query)
+	}
+	if len(args) != 1 {
+		t.Errorf("Expected 1 arg, got %v", args)
+	}
+}
+
+func TestSelectBuilder_RejectsInvalidFilters(t *testing.T) {
+	testCases := []struct {
+		name    string
+		builder *selectBuilder
+	}{
+		{"column not allowlisted", newSelectBuilder("things", "id", "kind").where("name", "=", "x")},
+		{"injected column", newSelectBuilder("things", "id", "kind").where("kind = 1 OR 1", "=", "x")},
+		{"operator not allowlisted", newSelectBuilder("things", "id", "kind").where("kind", "!=", "x")},
+		{"negative limit", newSelectBuilder("things", "id").page(-1, 0)},
+		{"limit too large", newSelectBuilder("things", "id").page(MaxListLimit+1, 0)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := tc.builder.build(); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("Expected ErrInvalidFilter, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExpectedExpenseQuery_RejectsDateFilters(t *testing.T) {
+	if _, _, err := expectedExpenseQuery(ExpenseFilter{Month: 6}).build(); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expected ErrInvalidFilter for month filter, got %v", err)
+	}
+}
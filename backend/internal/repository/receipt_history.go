@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrProcessingRecordNotFound = errors.New("receipt processing record not found")
+
+// Receipt processing statuses
+const (
+	ReceiptStatusSuccess = "success"
+	ReceiptStatusError   = "error"
+)
+
+// ReceiptProcessingRecord is one run of the receipt processor
+type ReceiptProcessingRecord struct {
+	ID               int64     `json:"id"`
+	ProcessedAt      time.Time `json:"processed_at"`
+	FileName         string    `json:"file_name"`
+	FileSize         int64     `json:"file_size"`
+	Status           string    `json:"status"`
+	ErrorCode        string    `json:"error_code,omitempty"`
+	ItemCount        int       `json:"item_count"`
+	ProcessingTimeMs int64     `json:"processing_time_ms"`
+	RawResponse      *string   `json:"raw_response,omitempty"`
+	RepairResponse   *string   `json:"repair_response,omitempty"`
+}
+
+// ReceiptHistoryRepository handles receipt_processing_history database operations
+type ReceiptHistoryRepository struct {
+	db *DB
+}
+
+// NewReceiptHistoryRepository creates a new ReceiptHistoryRepository
+func NewReceiptHistoryRepository(db *DB) *ReceiptHistoryRepository {
+	return &ReceiptHistoryRepository{db: db}
+}
+
+// Record stores a processing run and returns its id
+func (r *ReceiptHistoryRepository) Record(rec *ReceiptProcessingRecord) (int64, error) {
+	var errorCode sql.NullString
+	if rec.ErrorCode != "" {
+		errorCode = sql.NullString{String: rec.ErrorCode, Valid: true}
+	}
+	// The model output lists every item of the receipt
+	rawResponse, err := sealNullableField("raw_response", rec.RawResponse)
+	if err != nil {
+		return 0, err
+	}
+	repairResponse, err := sealNullableField("repair_response", rec.RepairResponse)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO receipt_processing_history (file_name, file_size, status, error_code, item_count, processing_time_ms, raw_response, repair_response)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.FileName, rec.FileSize, rec.Status, errorCode, rec.ItemCount, rec.ProcessingTimeMs, rawResponse, repairResponse)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record receipt processing: %w", err)
+	}
+
+	return result.LastInsertId()
+}
+
+// List returns processing runs newest first, without the raw model output
+func (r *ReceiptHistoryRepository) List(limit, offset int) ([]ReceiptProcessingRecord, error) {
+	query, args, err := newSelectBuilder(
+		"receipt_processing_history",
+		"id, processed_at, file_name, file_size, status, error_code, item_count, processing_time_ms",
+	).order("processed_at DESC, id DESC").page(limit, offset).build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ReceiptProcessingRecord
+	for rows.Next() {
+		var rec ReceiptProcessingRecord
+		var errorCode sql.NullString
+		if err := rows.Scan(
+			&rec.ID, &rec.ProcessedAt, &rec.FileName, &rec.FileSize, &rec.Status,
+			&errorCode, &rec.ItemCount, &rec.ProcessingTimeMs,
+		); err != nil {
+			return nil, err
+		}
+		rec.ErrorCode = errorCode.String
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
+// LastSuccess returns when a receipt was last processed successfully, or nil
+// when none was
+func (r *ReceiptHistoryRepository) LastSuccess() (*time.Time, error) {
+	var processedAt time.Time
+	err := r.db.QueryRow(`
+		SELECT processed_at FROM receipt_processing_history
+		WHERE status = ? ORDER BY processed_at DESC, id DESC LIMIT 1
+	`, ReceiptStatusSuccess).Scan(&processedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the last processed receipt: %w", err)
+	}
+	return &processedAt, nil
+}
+
+// GetByID returns a processing run including the raw model output
+func (r *ReceiptHistoryRepository) GetByID(id int64) (*ReceiptProcessingRecord, error) {
+	var rec ReceiptProcessingRecord
+	var errorCode, rawResponse, repairResponse sql.NullString
+
+	err := r.db.QueryRow(`
+		SELECT id, processed_at, file_name, file_size, status, error_code, item_count, processing_time_ms, raw_response, repair_response
+		FROM receipt_processing_history WHERE id = ?
+	`, id).Scan(
+		&rec.ID, &rec.ProcessedAt, &rec.FileName, &rec.FileSize, &rec.Status,
+		&errorCode, &rec.ItemCount, &rec.ProcessingTimeMs, &rawResponse, &repairResponse,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrProcessingRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec.ErrorCode = errorCode.String
+	if rawResponse.Valid {
+		plain, err := openField("raw_response", rawResponse.String)
+		if err != nil {
+			return nil, err
+		}
+		rec.RawResponse = &plain
+	}
+	if repairResponse.Valid {
+		plain, err := openField("repair_response", repairResponse.String)
+		if err != nil {
+			return nil, err
+		}
+		rec.RepairResponse = &plain
+	}
+
+	return &rec, nil
+}
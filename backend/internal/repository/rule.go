@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrRuleNotFound = errors.New("category rule not found")
+
+// CategoryRuleRepository handles the rules that set the expense type of new
+// expenses. It manages the rules of one user, see ForUser.
+type CategoryRuleRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewCategoryRuleRepository creates a new CategoryRuleRepository
+func NewCategoryRuleRepository(db *DB) *CategoryRuleRepository {
+	return &CategoryRuleRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that manages userID's rules
+func (r *CategoryRuleRepository) ForUser(userID int64) *CategoryRuleRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+const ruleColumns = `id, name, priority, enabled, source_contains, item_contains, min_amount, max_amount, expense_type, deductible, created_at, updated_at`
+
+// ruleOrder is the order rules are evaluated in
+const ruleOrder = `priority DESC, id`
+
+func scanRule(row interface{ Scan(...any) error }) (*models.CategoryRule, error) {
+	var rule models.CategoryRule
+	var minAmount, maxAmount sql.NullFloat64
+	if err := row.Scan(
+		&rule.ID, &rule.Name, &rule.Priority, &rule.Enabled,
+		&rule.SourceContains, &rule.ItemContains, &minAmount, &maxAmount,
+		&rule.ExpenseType, &rule.Deductible, &rule.CreatedAt, &rule.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if minAmount.Valid {
+		rule.MinAmount = &minAmount.Float64
+	}
+	if maxAmount.Valid {
+		rule.MaxAmount = &maxAmount.Float64
+	}
+	return &rule, nil
+}
+
+// List returns the scoped user's rules in evaluation order, disabled ones
+// included
+func (r *CategoryRuleRepository) List() ([]models.CategoryRule, error) {
+	return listRules(r.db, r.userID, false)
+}
+
+// Enabled returns the scoped user's enabled rules in evaluation order
+func (r *CategoryRuleRepository) Enabled() ([]models.CategoryRule, error) {
+	return listRules(r.db, r.userID, true)
+}
+
+func listRules(db querier, userID int64, enabledOnly bool) ([]models.CategoryRule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM category_rules WHERE user_id = ?`
+	if enabledOnly {
+		query += ` AND enabled = 1`
+	}
+	rows, err := db.Query(query+` ORDER BY `+ruleOrder, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.CategoryRule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Get returns rule id of the scoped user
+func (r *CategoryRuleRepository) Get(id int64) (*models.CategoryRule, error) {
+	rule, err := scanRule(r.db.QueryRow(`
+		SELECT `+ruleColumns+`
+		FROM category_rules WHERE id = ? AND user_id = ?
+	`, id, r.userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category rule: %w", err)
+	}
+	return rule, nil
+}
+
+// Create adds a rule for the scoped user
+func (r *CategoryRuleRepository) Create(req *models.CategoryRuleRequest) (*models.CategoryRule, error) {
+	now := time.Now().UTC()
+	result, err := r.db.Exec(`
+		INSERT INTO category_rules
+			(user_id, name, priority, enabled, source_contains, item_contains, min_amount, max_amount, expense_type, deductible, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.userID, req.Name, req.Priority, req.IsEnabled(), req.SourceContains, req.ItemContains,
+		req.MinAmount, req.MaxAmount, req.ExpenseType, req.Deductible, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category rule: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return r.Get(id)
+}
+
+// Update replaces rule id of the scoped user with req
+func (r *CategoryRuleRepository) Update(id int64, req *models.CategoryRuleRequest) (*models.CategoryRule, error) {
+	result, err := r.db.Exec(`
+		UPDATE category_rules
+		SET name = ?, priority = ?, enabled = ?, source_contains = ?, item_contains = ?,
+			min_amount = ?, max_amount = ?, expense_type = ?, deductible = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, req.Name, req.Priority, req.IsEnabled(), req.SourceContains, req.ItemContains,
+		req.MinAmount, req.MaxAmount, req.ExpenseType, req.Deductible, time.Now().UTC(), id, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update category rule: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to update category rule: %w", err)
+	} else if n == 0 {
+		return nil, ErrRuleNotFound
+	}
+	return r.Get(id)
+}
+
+// Delete removes rule id of the scoped user
+func (r *CategoryRuleRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM category_rules WHERE id = ? AND user_id = ?`, id, r.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete category rule: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete category rule: %w", err)
+	} else if n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// Test returns the rule that would set the expense type of a sample expense
+func (r *CategoryRuleRepository) Test(req *models.RuleTestRequest) (*models.RuleTestResult, error) {
+	rules, err := r.Enabled()
+	if err != nil {
+		return nil, err
+	}
+	result := &models.RuleTestResult{}
+	if rule := models.MatchRule(rules, req.ItemName, req.Source, req.Amount); rule != nil {
+		result.Matched, result.Rule, result.ExpenseType = true, rule, rule.ExpenseType
+		result.Deductible = rule.Deductible
+	}
+	return result, nil
+}
+
+// Simulate runs all of the scoped user's rules, disabled ones included,
+// against their expenses of the last months months up to the one containing
+// now. Nothing is changed.
+func (r *CategoryRuleRepository) Simulate(months int, now time.Time) (*models.RuleSimulation, error) {
+	rules, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.UTC()
+	first := now.Year()*12 + int(now.Month()) - months
+	rows, err := r.db.Query(`
+		SELECT `+actualExpenseColumns+`
+		FROM actual_expenses
+		WHERE user_id = ? AND year * 12 + month - 1 >= ?
+		ORDER BY receipt_date, id
+	`, r.userID, first)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expenses: %w", err)
+	}
+	defer rows.Close()
+	expenses, err := scanActualExpenses(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan expenses: %w", err)
+	}
+
+	sim := models.SimulateRules(rules, expenses)
+	sim.Months = months
+	return sim, nil
+}
+
+// applyCategoryRules sets the expense type of req from the first of userID's
+// enabled rules it matches, and marks req deductible when that rule is. The
+// type sent with the expense is kept when no rule matches.
+func applyCategoryRules(db querier, userID int64, req *models.CreateActualExpenseRequest) error {
+	rules, err := listRules(db, userID, true)
+	if err != nil {
+		return err
+	}
+	if rule := models.MatchRule(rules, req.ItemName, req.Source, req.ActualAmount); rule != nil {
+		req.ExpenseType = rule.ExpenseType
+		req.Deductible = req.Deductible || rule.Deductible
+	}
+	return nil
+}
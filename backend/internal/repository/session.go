@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 255
+
+// CreateSession starts a session for userID holding a refresh token hash and
+// returns its id. Expired sessions of the user are removed at the same time.
+// Times are stored in UTC so they compare as stored.
+func (r *UserRepository) CreateSession(
+	userID int64,
+	refreshTokenHash, userAgent string,
+	expiresAt time.Time,
+) (int64, error) {
+	now := time.Now().UTC()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	if _, err := r.db.Exec(
+		`DELETE FROM sessions WHERE user_id = ? AND expires_at <= ?`, userID, now,
+	); err != nil {
+		return 0, fmt.Errorf("failed to remove expired sessions: %w", err)
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO sessions (user_id, refresh_token_hash, user_agent, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, refreshTokenHash, userAgent, now, now, expiresAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// RotateSession replaces the refresh token hash of the active session holding
+// refreshTokenHash and extends it to expiresAt, so every refresh token works
+// once. It returns the session, or ErrSessionNotFound when the token is
+// unknown, already used, revoked or expired.
+func (r *UserRepository) RotateSession(
+	refreshTokenHash, newHash string,
+	expiresAt time.Time,
+) (*models.Session, error) {
+	now := time.Now().UTC()
+
+	session, err := r.getSession(`
+		SELECT id, user_id, user_agent, created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?
+	`, refreshTokenHash, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hash condition makes two concurrent refreshes with one token fail
+	// for all but the first
+	result, err := r.db.Exec(`
+		UPDATE sessions SET refresh_token_hash = ?, last_used_at = ?, expires_at = ?
+		WHERE id = ? AND refresh_token_hash = ?
+	`, newHash, now, expiresAt.UTC(), session.ID, refreshTokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	session.LastUsedAt = now
+	session.ExpiresAt = expiresAt.UTC()
+	return session, nil
+}
+
+// ListSessions returns the active sessions of userID, most recently used first
+func (r *UserRepository) ListSessions(userID int64) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, user_agent, created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC, id DESC
+	`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(
+			&s.ID, &s.UserID, &s.UserAgent, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends session id of userID; its refresh token stops working
+func (r *UserRepository) RevokeSession(userID, id int64) error {
+	return r.revoke(`
+		UPDATE sessions SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, time.Now().UTC(), id, userID)
+}
+
+// RevokeSessionByToken ends the session holding refreshTokenHash
+func (r *UserRepository) RevokeSessionByToken(refreshTokenHash string) error {
+	return r.revoke(`
+		UPDATE sessions SET revoked_at = ?
+		WHERE refresh_token_hash = ? AND revoked_at IS NULL
+	`, time.Now().UTC(), refreshTokenHash)
+}
+
+// RevokeAllSessions ends every session of userID and returns how many were active
+func (r *UserRepository) RevokeAllSessions(userID int64) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL
+	`, time.Now().UTC(), userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *UserRepository) revoke(query string, args ...any) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) getSession(query string, args ...any) (*models.Session, error) {
+	var s models.Session
+	err := r.db.QueryRow(query, args...).Scan(
+		&s.ID, &s.UserID, &s.UserAgent, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrSettingNotFound = errors.New("setting not found")
+
+// Setting keys
+const (
+	SettingDefaultBudget = "default_budget"
+	SettingSchedules     = "schedules"
+	SettingApprovalRule  = "approval_rule"
+	SettingBudgetFreeze  = "budget_freeze"
+	SettingLastBackup    = "last_backup"
+)
+
+// SettingsRepository handles settings database operations.
+// Values are stored as JSON so each setting can have its own shape.
+type SettingsRepository struct {
+	db *DB
+}
+
+// NewSettingsRepository creates a new SettingsRepository
+func NewSettingsRepository(db *DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// get decodes the value stored under key into v
+func (r *SettingsRepository) get(key string, v any) error {
+	var value string
+	err := r.db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSettingNotFound
+		}
+		return fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return fmt.Errorf("failed to decode setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// set stores v as JSON under key, replacing any existing value
+func (r *SettingsRepository) set(key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode setting %s: %w", key, err)
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+	`, key, string(value))
+	if err != nil {
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// delete removes key; deleting a missing key returns ErrSettingNotFound
+func (r *SettingsRepository) delete(key string) error {
+	result, err := r.db.Exec(`DELETE FROM settings WHERE key = ?`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete setting %s: %w", key, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrSettingNotFound
+	}
+	return nil
+}
+
+// GetDefaultBudget returns the default monthly budget, or ErrSettingNotFound
+func (r *SettingsRepository) GetDefaultBudget() (*models.DefaultBudget, error) {
+	var budget models.DefaultBudget
+	if err := r.get(SettingDefaultBudget, &budget); err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// SetDefaultBudget stores the default monthly budget
+func (r *SettingsRepository) SetDefaultBudget(budget *models.DefaultBudget) error {
+	return r.set(SettingDefaultBudget, budget)
+}
+
+// DeleteDefaultBudget removes the default monthly budget
+func (r *SettingsRepository) DeleteDefaultBudget() error {
+	return r.delete(SettingDefaultBudget)
+}
+
+// GetApprovalRule returns the approval rule for large expenses, or ErrSettingNotFound
+func (r *SettingsRepository) GetApprovalRule() (*models.ApprovalRule, error) {
+	var rule models.ApprovalRule
+	if err := r.get(SettingApprovalRule, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SetApprovalRule stores the approval rule for large expenses
+func (r *SettingsRepository) SetApprovalRule(rule *models.ApprovalRule) error {
+	return r.set(SettingApprovalRule, rule)
+}
+
+// DeleteApprovalRule removes the approval rule; new expenses no longer need approval
+func (r *SettingsRepository) DeleteApprovalRule() error {
+	return r.delete(SettingApprovalRule)
+}
+
+// GetBudgetFreeze returns the budget freeze, or ErrSettingNotFound when the
+// budget is not frozen
+func (r *SettingsRepository) GetBudgetFreeze() (*models.BudgetFreeze, error) {
+	var freeze models.BudgetFreeze
+	if err := r.get(SettingBudgetFreeze, &freeze); err != nil {
+		return nil, err
+	}
+	return &freeze, nil
+}
+
+// SetBudgetFreeze freezes the budget
+func (r *SettingsRepository) SetBudgetFreeze(freeze *models.BudgetFreeze) error {
+	return r.set(SettingBudgetFreeze, freeze)
+}
+
+// DeleteBudgetFreeze lifts the budget freeze
+func (r *SettingsRepository) DeleteBudgetFreeze() error {
+	return r.delete(SettingBudgetFreeze)
+}
+
+// GetLastBackup returns the last successful backup, or ErrSettingNotFound
+// when the database was never backed up
+func (r *SettingsRepository) GetLastBackup() (*BackupRecord, error) {
+	var backup BackupRecord
+	if err := r.get(SettingLastBackup, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// GetSchedules returns the cron schedules set for background jobs, by job name.
+// Jobs without an entry use their default schedule.
+func (r *SettingsRepository) GetSchedules() (map[string]string, error) {
+	schedules := make(map[string]string)
+	if err := r.get(SettingSchedules, &schedules); err != nil && !errors.Is(err, ErrSettingNotFound) {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// SetSchedules merges the given job schedules into the stored ones
+func (r *SettingsRepository) SetSchedules(updates map[string]string) error {
+	schedules, err := r.GetSchedules()
+	if err != nil {
+		return err
+	}
+	for name, spec := range updates {
+		schedules[name] = spec
+	}
+	return r.set(SettingSchedules, schedules)
+}
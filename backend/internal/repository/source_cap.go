@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrSourceCapNotFound = errors.New("source cap not found")
+	ErrSourceCapExists   = errors.New("a cap for this source already exists")
+)
+
+// SourceCapRepository handles the monthly spending caps of single sources.
+// It manages the caps of one user, see ForUser.
+type SourceCapRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewSourceCapRepository creates a new SourceCapRepository
+func NewSourceCapRepository(db *DB) *SourceCapRepository {
+	return &SourceCapRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that manages userID's caps
+func (r *SourceCapRepository) ForUser(userID int64) *SourceCapRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+const sourceCapColumns = `id, source, monthly_limit, created_at, updated_at`
+
+func scanSourceCap(row interface{ Scan(...any) error }) (*models.SourceCap, error) {
+	var c models.SourceCap
+	if err := row.Scan(&c.ID, &c.Source, &c.MonthlyLimit, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List returns the scoped user's caps by source
+func (r *SourceCapRepository) List() ([]models.SourceCap, error) {
+	rows, err := r.db.Query(`
+		SELECT `+sourceCapColumns+`
+		FROM source_caps WHERE user_id = ?
+		ORDER BY source COLLATE NOCASE
+	`, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source caps: %w", err)
+	}
+	defer rows.Close()
+
+	caps := []models.SourceCap{}
+	for rows.Next() {
+		c, err := scanSourceCap(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan source cap: %w", err)
+		}
+		caps = append(caps, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating source caps: %w", err)
+	}
+	return caps, nil
+}
+
+// Get returns cap id of the scoped user
+func (r *SourceCapRepository) Get(id int64) (*models.SourceCap, error) {
+	c, err := scanSourceCap(r.db.QueryRow(`
+		SELECT `+sourceCapColumns+`
+		FROM source_caps WHERE id = ? AND user_id = ?
+	`, id, r.userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSourceCapNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source cap: %w", err)
+	}
+	return c, nil
+}
+
+// Create adds a cap for the scoped user. It returns ErrSourceCapExists when
+// the user already caps the source.
+func (r *SourceCapRepository) Create(req *models.SourceCapRequest) (*models.SourceCap, error) {
+	now := time.Now().UTC()
+	result, err := r.db.Exec(`
+		INSERT INTO source_caps (user_id, source, monthly_limit, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, r.userID, req.Source, req.MonthlyLimit, now, now)
+	if isUniqueConstraintError(err) {
+		return nil, ErrSourceCapExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source cap: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return r.Get(id)
+}
+
+// Update replaces cap id of the scoped user with req
+func (r *SourceCapRepository) Update(id int64, req *models.SourceCapRequest) (*models.SourceCap, error) {
+	result, err := r.db.Exec(`
+		UPDATE source_caps SET source = ?, monthly_limit = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, req.Source, req.MonthlyLimit, time.Now().UTC(), id, r.userID)
+	if isUniqueConstraintError(err) {
+		return nil, ErrSourceCapExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update source cap: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to update source cap: %w", err)
+	} else if n == 0 {
+		return nil, ErrSourceCapNotFound
+	}
+	return r.Get(id)
+}
+
+// Delete removes cap id of the scoped user
+func (r *SourceCapRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM source_caps WHERE id = ? AND user_id = ?`, id, r.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete source cap: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete source cap: %w", err)
+	} else if n == 0 {
+		return ErrSourceCapNotFound
+	}
+	return nil
+}
+
+// Spending returns the approved spending of month at each of the scoped
+// user's capped sources. Stores may be encrypted, so they are matched to the
+// caps after decrypting.
+func (r *SourceCapRepository) Spending(month, year int) ([]models.SourceCapSpending, error) {
+	caps, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	spending := make([]models.SourceCapSpending, len(caps))
+	if len(caps) == 0 {
+		return spending, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT source, SUM(actual_amount)
+		FROM actual_expenses
+		WHERE user_id = ? AND year = ? AND month = ? AND pending_approval = 0
+		GROUP BY source
+	`, r.userID, year, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spending by source: %w", err)
+	}
+	defer rows.Close()
+
+	for i, c := range caps {
+		spending[i] = models.SourceCapSpending{Cap: c, Month: month, Year: year}
+	}
+	for rows.Next() {
+		var store string
+		var amount float64
+		if err := rows.Scan(&store, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan spending by source: %w", err)
+		}
+		if store, err = openField("source", store); err != nil {
+			return nil, err
+		}
+		for i := range spending {
+			if spending[i].Cap.Matches(store) {
+				spending[i].Spent += amount
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating spending by source: %w", err)
+	}
+
+	for i := range spending {
+		s := &spending[i]
+		s.Remaining = s.Cap.MonthlyLimit - s.Spent
+		s.PercentageUsed = s.Spent / s.Cap.MonthlyLimit * 100
+		s.Exceeded = s.Spent > s.Cap.MonthlyLimit
+	}
+	return spending, nil
+}
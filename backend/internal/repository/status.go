@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// MigrationInfo describes one migration; AppliedAt is nil while it is pending
+type MigrationInfo struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// MigrationStatus lists the migrations applied to the database and the ones
+// RunMigrations would still apply
+type MigrationStatus struct {
+	Environment    string          `json:"environment,omitempty"`
+	CurrentVersion int             `json:"current_version"`
+	Applied        []MigrationInfo `json:"applied"`
+	Pending        []MigrationInfo `json:"pending"`
+}
+
+// MigrationStatus reports the applied and pending migrations without
+// changing the database. Versions recorded by older releases that this build
+// no longer ships are listed as applied too.
+func (r *MaintenanceRepository) MigrationStatus() (*MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// Rolled back so schema_migrations is not created in a fresh database
+	defer tx.Rollback()
+
+	if err := createMigrationsTable(tx); err != nil {
+		return nil, err
+	}
+	environment, err := environmentTag(tx)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{
+		Environment: environment,
+		Applied:     []MigrationInfo{},
+		Pending:     []MigrationInfo{},
+	}
+	rows, err := tx.Query(`SELECT version, description, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m MigrationInfo
+		var appliedAt time.Time
+		if err := rows.Scan(&m.Version, &m.Description, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		m.AppliedAt = &appliedAt
+		status.Applied = append(status.Applied, m)
+		status.CurrentVersion = m.Version
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			status.Pending = append(status.Pending, MigrationInfo{Version: m.Version, Description: m.Description})
+		}
+	}
+	return status, nil
+}
+
+// TableStats is the number of rows in one table
+type TableStats struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// DBStats describes the size of the database
+type DBStats struct {
+	SizeBytes int64        `json:"size_bytes"`
+	PageSize  int64        `json:"page_size"`
+	PageCount int64        `json:"page_count"`
+	FreePages int64        `json:"free_pages"`
+	Tables    []TableStats `json:"tables"`
+}
+
+// Stats returns the size of the database and the row count of every table,
+// by name
+func (r *MaintenanceRepository) Stats() (*DBStats, error) {
+	stats := &DBStats{Tables: []TableStats{}}
+	if err := r.db.QueryRow(`
+		SELECT page_count, page_size, freelist_count
+		FROM pragma_page_count(), pragma_page_size(), pragma_freelist_count()
+	`).Scan(&stats.PageCount, &stats.PageSize, &stats.FreePages); err != nil {
+		return nil, fmt.Errorf("failed to read database size: %w", err)
+	}
+	stats.SizeBytes = stats.PageCount * stats.PageSize
+
+	rows, err := r.db.Query(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	// The names come from sqlite_master, so quoting them is enough
+	for _, name := range names {
+		table := TableStats{Name: name}
+		if err := r.db.QueryRow(`SELECT COUNT(*) FROM "` + name + `"`).Scan(&table.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", name, err)
+		}
+		stats.Tables = append(stats.Tables, table)
+	}
+	return stats, nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrSuggestionNotFound = errors.New("suggestion not found")
+
+// historyScanLimit bounds the recent expenses History looks through
+const historyScanLimit = 500
+
+// ExpenseSuggestionRepository stores the AI suggestions for expenses entered
+// tersely, see models.IsTerseName. It only sees the expenses of one user,
+// see ForUser.
+type ExpenseSuggestionRepository struct {
+	db     *DB
+	userID int64
+}
+
+// NewExpenseSuggestionRepository creates a new ExpenseSuggestionRepository
+func NewExpenseSuggestionRepository(db *DB) *ExpenseSuggestionRepository {
+	return &ExpenseSuggestionRepository{db: db}
+}
+
+// ForUser returns a copy of the repository that reads and writes userID's suggestions
+func (r *ExpenseSuggestionRepository) ForUser(userID int64) *ExpenseSuggestionRepository {
+	scoped := *r
+	scoped.userID = userID
+	return &scoped
+}
+
+// UserIDs returns every user with actual expenses
+func (r *ExpenseSuggestionRepository) UserIDs() ([]int64, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT user_id FROM actual_expenses ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense owners: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expense owner: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expense owners: %w", err)
+	}
+	return ids, nil
+}
+
+// Candidates returns up to limit of the user's expenses with a terse name
+// that were not asked about yet, newest first. Only expenses entered by hand
+// or imported count; the items of a receipt have a line number and were
+// named by the receipt processing.
+func (r *ExpenseSuggestionRepository) Candidates(limit int) ([]models.ActualExpense, error) {
+	// Names may be encrypted, so they are checked after reading
+	rows, err := r.db.Query(`
+		SELECT e.id, e.item_name, e.source, e.actual_amount
+		FROM actual_expenses e
+		LEFT JOIN expense_suggestions s ON s.actual_expense_id = e.id
+		WHERE e.user_id = ? AND e.line_no IS NULL AND s.actual_expense_id IS NULL
+		ORDER BY e.id DESC
+	`, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestion candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []models.ActualExpense{}
+	for rows.Next() && len(candidates) < limit {
+		var e models.ActualExpense
+		if err := rows.Scan(&e.ID, &e.ItemName, &e.Source, &e.ActualAmount); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion candidate: %w", err)
+		}
+		if err := openNameAndSource(&e.ItemName, &e.Source); err != nil {
+			return nil, err
+		}
+		if models.IsTerseName(e.ItemName) {
+			candidates = append(candidates, e)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suggestion candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// History returns up to limit of the user's recent expenses with a readable
+// name, as "name at source (type)", for the AI to follow how the user names
+// and categorizes expenses. Names are listed once.
+func (r *ExpenseSuggestionRepository) History(limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT item_name, source, expense_type
+		FROM actual_expenses
+		WHERE user_id = ? AND pending_approval = 0 AND expense_type != 'tax'
+		ORDER BY id DESC
+		LIMIT ?
+	`, r.userID, historyScanLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []string{}
+	seen := map[string]bool{}
+	for rows.Next() && len(history) < limit {
+		var name, source, expenseType string
+		if err := rows.Scan(&name, &source, &expenseType); err != nil {
+			return nil, fmt.Errorf("failed to scan expense history: %w", err)
+		}
+		if err := openNameAndSource(&name, &source); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if models.IsTerseName(name) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		history = append(history, fmt.Sprintf("%s at %s (%s)", name, source, expenseType))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expense history: %w", err)
+	}
+	return history, nil
+}
+
+// Record stores the suggestion for expenseID with status pending, or none
+// with an empty name when there is no suggestion. It reports false when the
+// expense was already asked about.
+func (r *ExpenseSuggestionRepository) Record(
+	expenseID int64,
+	itemName string,
+	expenseType models.ExpenseType,
+	status string,
+) (bool, error) {
+	sealed, err := sealField("item_name", itemName)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.db.Exec(`
+		INSERT OR IGNORE INTO expense_suggestions
+			(actual_expense_id, user_id, item_name, expense_type, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, expenseID, r.userID, sealed, expenseType, status, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to record suggestion: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record suggestion: %w", err)
+	}
+	return n > 0, nil
+}
+
+const suggestionQuery = `
+	SELECT s.actual_expense_id, e.item_name, e.source, e.actual_amount,
+		s.item_name, s.expense_type, s.status, s.created_at
+	FROM expense_suggestions s
+	JOIN actual_expenses e ON e.id = s.actual_expense_id
+	WHERE s.user_id = ? AND s.status = 'pending'`
+
+// List returns the pending suggestions, newest expense first
+func (r *ExpenseSuggestionRepository) List() ([]models.ExpenseSuggestion, error) {
+	rows, err := r.db.Query(suggestionQuery+` ORDER BY s.actual_expense_id DESC`, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	defer rows.Close()
+	return scanSuggestions(rows)
+}
+
+// Get returns the pending suggestion for expenseID
+func (r *ExpenseSuggestionRepository) Get(expenseID int64) (*models.ExpenseSuggestion, error) {
+	rows, err := r.db.Query(suggestionQuery+` AND s.actual_expense_id = ?`, r.userID, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggestion: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions, err := scanSuggestions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, ErrSuggestionNotFound
+	}
+	return &suggestions[0], nil
+}
+
+// Resolve marks the pending suggestion for expenseID accepted or dismissed
+func (r *ExpenseSuggestionRepository) Resolve(expenseID int64, status string) error {
+	result, err := r.db.Exec(`
+		UPDATE expense_suggestions SET status = ?, resolved_at = ?
+		WHERE actual_expense_id = ? AND user_id = ? AND status = 'pending'
+	`, status, time.Now().UTC(), expenseID, r.userID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve suggestion: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrSuggestionNotFound
+	}
+	return nil
+}
+
+func scanSuggestions(rows *sql.Rows) ([]models.ExpenseSuggestion, error) {
+	suggestions := []models.ExpenseSuggestion{}
+	for rows.Next() {
+		var s models.ExpenseSuggestion
+		if err := rows.Scan(
+			&s.ActualExpenseID, &s.CurrentName, &s.Source, &s.ActualAmount,
+			&s.ItemName, &s.ExpenseType, &s.Status, &s.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion: %w", err)
+		}
+		if err := openNameAndSource(&s.CurrentName, &s.Source); err != nil {
+			return nil, err
+		}
+		var err error
+		if s.ItemName, err = openField("item_name", s.ItemName); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suggestions: %w", err)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// TaxReport totals the scoped user's approved tax-type line items in year by
+// month and store. Stores may be encrypted, so they are grouped after
+// decrypting, ignoring case and surrounding spaces like source caps.
+func (r *ActualExpenseRepository) TaxReport(year int) (*models.TaxReport, error) {
+	result, err := r.db.Query(`
+		SELECT month, source, COUNT(*), SUM(actual_amount)
+		FROM actual_expenses
+		WHERE user_id = ? AND year = ? AND expense_type = ? AND pending_approval = 0
+		GROUP BY 1, 2
+	`, r.userID, year, models.ExpenseTypeTax)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax totals: %w", err)
+	}
+	defer result.Close()
+
+	type key struct {
+		month  int
+		source string
+	}
+	rows := map[key]*models.TaxReportRow{}
+	for result.Next() {
+		var row models.TaxReportRow
+		if err := result.Scan(&row.Month, &row.Source, &row.Items, &row.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan tax total: %w", err)
+		}
+		source, err := openField("source", row.Source)
+		if err != nil {
+			return nil, err
+		}
+		row.Source = strings.TrimSpace(source)
+
+		k := key{row.Month, strings.ToLower(row.Source)}
+		existing, ok := rows[k]
+		if !ok {
+			rows[k] = &row
+			continue
+		}
+		existing.Items += row.Items
+		existing.Total += row.Total
+		// The same spelling whatever order the rows come in
+		existing.Source = min(existing.Source, row.Source)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tax totals: %w", err)
+	}
+
+	report := &models.TaxReport{
+		Year:        year,
+		Rows:        make([]models.TaxReportRow, 0, len(rows)),
+		MonthTotals: make([]float64, 12),
+	}
+	for _, row := range rows {
+		row.Total = roundCents(row.Total)
+		report.Rows = append(report.Rows, *row)
+		report.MonthTotals[row.Month-1] += row.Total
+		report.Items += row.Items
+		report.Total += row.Total
+	}
+	slices.SortFunc(report.Rows, func(a, b models.TaxReportRow) int {
+		return cmp.Or(
+			cmp.Compare(a.Month, b.Month),
+			strings.Compare(strings.ToLower(a.Source), strings.ToLower(b.Source)),
+		)
+	})
+	for i := range report.MonthTotals {
+		report.MonthTotals[i] = roundCents(report.MonthTotals[i])
+	}
+	report.Total = roundCents(report.Total)
+	return report, nil
+}
+
+// roundCents rounds away the float error of summed amounts
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
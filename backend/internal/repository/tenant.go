@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"budget-tracker/internal/config"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantPlaceholder is replaced with the tenant in TURSO_LOCAL_PATH and
+// TURSO_DATABASE_URL in tenant mode, so each household has a database of
+// its own
+const TenantPlaceholder = "{tenant}"
+
+// TenantDatabases opens the database of each tenant of a shared server on
+// its first use, migrates it and keeps it open for the requests that follow.
+// Only the configured tenants are opened.
+type TenantDatabases struct {
+	base    Config
+	tenants []string
+
+	mu   sync.Mutex
+	open map[string]*DB
+}
+
+// NewTenantDatabases creates the databases of tenants from base, whose local
+// path or database URL must contain TenantPlaceholder. Tenant names follow
+// the rules of environment names, so they fit in a hostname or file name.
+func NewTenantDatabases(base Config, tenants []string) (*TenantDatabases, error) {
+	if len(tenants) == 0 {
+		return nil, errors.New("no tenants configured")
+	}
+	target := base.LocalPath
+	if base.Mode == ModeRemote {
+		target = base.DatabaseURL
+	}
+	if !strings.Contains(target, TenantPlaceholder) {
+		return nil, fmt.Errorf("%q must contain %s so every tenant has its own database", target, TenantPlaceholder)
+	}
+
+	names := make([]string, 0, len(tenants))
+	for _, name := range tenants {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !environmentPattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid tenant %q: use lowercase letters, digits and dashes", name)
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no tenants configured")
+	}
+	slices.Sort(names)
+	return &TenantDatabases{base: base, tenants: names, open: make(map[string]*DB)}, nil
+}
+
+// NewTenantDatabasesFromEnv creates the databases of the comma-separated
+// TENANTS from the database configuration. ok is false when TENANTS is not
+// set and the server has a single database.
+func NewTenantDatabasesFromEnv() (dbs *TenantDatabases, ok bool, err error) {
+	v := config.Get("TENANTS")
+	if v == "" {
+		return nil, false, nil
+	}
+	dbs, err = NewTenantDatabases(NewConfigFromEnv(), strings.Split(v, ","))
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid TENANTS: %w", err)
+	}
+	return dbs, true, nil
+}
+
+// Tenants returns the configured tenants in alphabetical order
+func (t *TenantDatabases) Tenants() []string {
+	return slices.Clone(t.tenants)
+}
+
+// Mode returns the connection mode of the tenants' databases
+func (t *TenantDatabases) Mode() Mode {
+	return t.base.Mode
+}
+
+// Config returns the database configuration of tenant. It returns
+// ErrUnknownTenant for a tenant that is not configured.
+func (t *TenantDatabases) Config(tenant string) (Config, error) {
+	if !slices.Contains(t.tenants, tenant) {
+		return Config{}, fmt.Errorf("%q: %w", tenant, ErrUnknownTenant)
+	}
+	cfg := t.base
+	cfg.LocalPath = strings.ReplaceAll(cfg.LocalPath, TenantPlaceholder, tenant)
+	cfg.DatabaseURL = strings.ReplaceAll(cfg.DatabaseURL, TenantPlaceholder, tenant)
+	return cfg, nil
+}
+
+// Open returns the database of tenant, opening and migrating it on first use.
+// It returns ErrUnknownTenant for a tenant that is not configured.
+func (t *TenantDatabases) Open(tenant string) (*DB, error) {
+	cfg, err := t.Config(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if db, ok := t.open[tenant]; ok {
+		return db, nil
+	}
+
+	db, err := NewDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the database of %s: %w", tenant, err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate the database of %s: %w", tenant, err)
+	}
+	log.Printf("Opened the database of tenant %s", tenant)
+	t.open[tenant] = db
+	return db, nil
+}
+
+// Close closes every database opened so far
+func (t *TenantDatabases) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var errs []error
+	for tenant, db := range t.open {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", tenant, err))
+		}
+		delete(t.open, tenant)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTenantDatabases(t *testing.T) {
+	dir := t.TempDir()
+	base := Config{Mode: ModeLocal, LocalPath: filepath.Join(dir, "{tenant}.db")}
+
+	if _, err := NewTenantDatabases(Config{Mode: ModeLocal, LocalPath: filepath.Join(dir, "budget.db")}, []string{"smith"}); err == nil {
+		t.Error("Expected a shared database path to be refused")
+	}
+	if _, err := NewTenantDatabases(base, []string{"Smith", "not a host"}); err == nil {
+		t.Error("Expected an invalid tenant to be refused")
+	}
+
+	dbs, err := NewTenantDatabases(base, []string{" Smith", "jones", "smith", ""})
+	if err != nil {
+		t.Fatalf("Failed to configure tenants: %v", err)
+	}
+	defer dbs.Close()
+	if got := dbs.Tenants(); !slices.Equal(got, []string{"jones", "smith"}) {
+		t.Errorf("Expected tenants jones and smith, got %v", got)
+	}
+
+	// Nothing is opened before first use
+	if _, err := os.Stat(filepath.Join(dir, "smith.db")); !os.IsNotExist(err) {
+		t.Fatalf("Expected no database before first use, got %v", err)
+	}
+	if _, err := dbs.Open("doe"); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("Expected ErrUnknownTenant, got %v", err)
+	}
+
+	smith, err := dbs.Open("smith")
+	if err != nil {
+		t.Fatalf("Failed to open smith: %v", err)
+	}
+	if again, err := dbs.Open("smith"); err != nil || again != smith {
+		t.Errorf("Expected the open database to be reused, got %v", err)
+	}
+	jones, err := dbs.Open("jones")
+	if err != nil {
+		t.Fatalf("Failed to open jones: %v", err)
+	}
+
+	// Both are migrated and hold their own data
+	if _, err := smith.Exec(`INSERT INTO users (email, password_hash) VALUES ('a@smith.test', 'x')`); err != nil {
+		t.Fatalf("Failed to add a user to smith: %v", err)
+	}
+	var count int
+	if err := jones.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected jones to have no users, got %d, %v", count, err)
+	}
+
+	if err := dbs.Close(); err != nil {
+		t.Fatalf("Failed to close tenants: %v", err)
+	}
+	if err := smith.Ping(); err == nil {
+		t.Error("Expected the databases to be closed")
+	}
+}
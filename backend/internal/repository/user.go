@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("a user with this email already exists")
+)
+
+// userColumns is the column list scanned by get
+const userColumns = `id, email, password_hash,
+	(SELECT parent_id FROM allowance_accounts WHERE allowance_accounts.user_id = users.id),
+	email_verified_at IS NOT NULL, is_admin, created_at`
+
+// UserRepository handles user account database operations
+type UserRepository struct {
+	db *DB
+}
+
+// NewUserRepository creates a new UserRepository
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create stores a user with an already hashed password
+func (r *UserRepository) Create(email, passwordHash string) (*models.User, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO users (email, password_hash) VALUES (?, ?)
+	`, email, passwordHash)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	return r.GetByID(id)
+}
+
+// GetByID retrieves a user by ID
+func (r *UserRepository) GetByID(id int64) (*models.User, error) {
+	user, _, err := r.get(`SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+	return user, err
+}
+
+// GetByEmail retrieves a user and their password hash by email
+func (r *UserRepository) GetByEmail(email string) (*models.User, string, error) {
+	return r.get(`SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+}
+
+// SetPassword replaces the password hash of userID
+func (r *UserRepository) SetPassword(userID int64, passwordHash string) error {
+	return r.update(`UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID)
+}
+
+// MarkEmailVerified records that userID confirmed their email address; an
+// already verified address keeps its original time
+func (r *UserRepository) MarkEmailVerified(userID int64) error {
+	return r.update(`
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?
+	`, time.Now().UTC(), userID)
+}
+
+// SetAdmin grants or revokes the admin role of userID
+func (r *UserRepository) SetAdmin(userID int64, isAdmin bool) error {
+	return r.update(`UPDATE users SET is_admin = ? WHERE id = ?`, isAdmin, userID)
+}
+
+// IsAdmin reports whether userID has the admin role; an unknown user does not
+func (r *UserRepository) IsAdmin(userID int64) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRow(`SELECT is_admin FROM users WHERE id = ?`, userID).Scan(&isAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get user role: %w", err)
+	}
+	return isAdmin, nil
+}
+
+// AdminIDs returns the IDs of the users with the admin role, lowest first.
+// Sub-accounts are never admins.
+func (r *UserRepository) AdminIDs() ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM users
+		WHERE is_admin = 1 AND id NOT IN (SELECT user_id FROM allowance_accounts)
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query admins: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan admin: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating admins: %w", err)
+	}
+	return ids, nil
+}
+
+// List returns every user with their number of active sessions, oldest first
+func (r *UserRepository) List() ([]models.AdminUser, error) {
+	rows, err := r.db.Query(`
+		SELECT `+userColumns+`,
+			(SELECT COUNT(*) FROM sessions
+			 WHERE sessions.user_id = users.id AND revoked_at IS NULL AND expires_at > ?)
+		FROM users ORDER BY id
+	`, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.AdminUser{}
+	for rows.Next() {
+		var u models.AdminUser
+		var passwordHash string
+		if err := rows.Scan(
+			&u.ID, &u.Email, &passwordHash, &u.ParentID, &u.EmailVerified, &u.IsAdmin, &u.CreatedAt,
+			&u.ActiveSessions,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+	return users, nil
+}
+
+// Count returns the number of users
+func (r *UserRepository) Count() (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+func (r *UserRepository) update(query string, args ...any) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) get(query string, arg any) (*models.User, string, error) {
+	var user models.User
+	var passwordHash string
+	err := r.db.QueryRow(query, arg).Scan(
+		&user.ID, &user.Email, &passwordHash, &user.ParentID, &user.EmailVerified, &user.IsAdmin, &user.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrUserNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, passwordHash, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUserTokenNotFound = errors.New("token is invalid or has expired")
+
+// CreateUserToken stores the hash of a single use token mailed to userID for
+// purpose and returns its id. Earlier unused tokens of the same purpose stop
+// working, so only the most recent email is valid, and expired or used tokens
+// of the user are removed.
+func (r *UserRepository) CreateUserToken(
+	userID int64,
+	purpose, tokenHash string,
+	expiresAt time.Time,
+) (int64, error) {
+	now := time.Now().UTC()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM user_tokens
+		WHERE user_id = ? AND (purpose = ? OR used_at IS NOT NULL OR expires_at <= ?)
+	`, userID, purpose, now); err != nil {
+		return 0, fmt.Errorf("failed to remove previous tokens: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, purpose, tokenHash, now, expiresAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return id, tx.Commit()
+}
+
+// UserTokenIssuedSince reports whether a token for purpose was created for
+// userID after since, which throttles how often emails are sent
+func (r *UserRepository) UserTokenIssuedSince(userID int64, purpose string, since time.Time) (bool, error) {
+	var issued bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM user_tokens WHERE user_id = ? AND purpose = ? AND created_at > ?
+		)
+	`, userID, purpose, since.UTC()).Scan(&issued)
+	if err != nil {
+		return false, fmt.Errorf("failed to check tokens: %w", err)
+	}
+	return issued, nil
+}
+
+// UseUserToken marks the unused, unexpired token with tokenHash as used and
+// returns the id of its user, or ErrUserTokenNotFound. A token works once
+// even when two requests present it at the same time.
+func (r *UserRepository) UseUserToken(purpose, tokenHash string) (int64, error) {
+	now := time.Now().UTC()
+
+	var userID int64
+	err := r.db.QueryRow(`
+		UPDATE user_tokens SET used_at = ?
+		WHERE purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING user_id
+	`, now, purpose, tokenHash, now).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserTokenNotFound
+		}
+		return 0, fmt.Errorf("failed to use token: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"fmt"
+)
+
+// Variance compares every active expected expense of the scoped user with
+// the approved actual expenses of month/year linked to it, in the order the
+// expected expenses were created. Actual expenses count toward their budget
+// month, as in the monthly summary. Archived expected expenses are only
+// listed when spending is linked to them in the month, with nothing expected.
+func (r *ActualExpenseRepository) Variance(month, year int) (*models.VarianceReport, error) {
+	rows, err := r.db.Query(`
+		SELECT e.id, e.item_name, e.source, e.expense_type, e.expected_amount, e.is_active,
+			COALESCE(SUM(a.actual_amount), 0), COUNT(a.id)
+		FROM expected_expenses e
+		LEFT JOIN actual_expenses a ON a.expected_expense_id = e.id AND a.user_id = e.user_id
+			AND a.month = ? AND a.year = ? AND a.pending_approval = 0
+		WHERE e.user_id = ?
+		GROUP BY e.id
+		HAVING e.is_active = 1 OR COUNT(a.id) > 0
+		ORDER BY e.id
+	`, month, year, r.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense variance: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.VarianceReport{Month: month, Year: year, Items: []models.VarianceItem{}}
+	for rows.Next() {
+		var item models.VarianceItem
+		var expected float64
+		var active bool
+		if err := rows.Scan(
+			&item.ExpectedExpenseID, &item.ItemName, &item.Source, &item.ExpenseType, &expected, &active,
+			&item.Actual, &item.MatchedCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan expense variance: %w", err)
+		}
+		if err := openNameAndSource(&item.ItemName, &item.Source); err != nil {
+			return nil, err
+		}
+		item.Archived = !active
+		if active {
+			item.Expected = models.MonthlyExpected(item.ExpenseType, expected)
+		}
+		item.SetVariance()
+		report.ExpectedTotal += item.Expected
+		report.ActualTotal += item.Actual
+		report.Items = append(report.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expense variance: %w", err)
+	}
+	report.SetVariance()
+
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(actual_amount), 0) FROM actual_expenses
+		WHERE user_id = ? AND month = ? AND year = ? AND pending_approval = 0
+			AND expected_expense_id IS NULL
+	`, r.userID, month, year).Scan(&report.UnmatchedTotal); err != nil {
+		return nil, fmt.Errorf("failed to sum unmatched spending: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"budget-tracker/internal/models"
+	"fmt"
+	"time"
+)
+
+// Weekdays totals the scoped user's approved expenses with a receipt date
+// between from and to, both included, by day of the week. expenseType
+// limits the report to one type when set. Expenses without a receipt date
+// are left out.
+func (r *ActualExpenseRepository) Weekdays(from, to time.Time, expenseType models.ExpenseType) (*models.WeekdayReport, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	query := `
+		SELECT receipt_date, COUNT(*), SUM(actual_amount)
+		FROM actual_expenses
+		WHERE user_id = ? AND pending_approval = 0
+			AND receipt_date >= ? AND receipt_date < ?`
+	args := []any{r.userID, from, to.AddDate(0, 0, 1)}
+	if expenseType != "" {
+		query += ` AND expense_type = ?`
+		args = append(args, string(expenseType))
+	}
+	query += ` GROUP BY 1`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekday spending: %w", err)
+	}
+	defer rows.Close()
+
+	// Indexed by time.Weekday, Sunday first
+	var days [7]models.WeekdaySpending
+	for rows.Next() {
+		var date time.Time
+		var count int
+		var amount float64
+		if err := rows.Scan(&date, &count, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan weekday spending: %w", err)
+		}
+		weekday := date.UTC().Weekday()
+		days[weekday].Count += count
+		days[weekday].Total += amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating weekday spending: %w", err)
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days[day.Weekday()].Days++
+	}
+
+	report := &models.WeekdayReport{
+		From: from.Format("2006-01-02"),
+		To:   to.Format("2006-01-02"),
+		Type: expenseType,
+		Days: make([]models.WeekdaySpending, 0, 7),
+	}
+	for i := range days {
+		report.Total += days[i].Total
+	}
+	for i := 1; i <= 7; i++ {
+		weekday := time.Weekday(i % 7)
+		d := days[weekday]
+		d.Weekday = weekday.String()
+		part := &report.Weekdays
+		if models.IsWeekend(weekday) {
+			part = &report.Weekend
+		}
+		part.Days += d.Days
+		part.Count += d.Count
+		part.Total += d.Total
+		report.Days = append(report.Days, d)
+	}
+
+	for i := range report.Days {
+		finishWeekdaySpending(&report.Days[i], report.Total)
+	}
+	finishWeekdaySpending(&report.Weekdays, report.Total)
+	finishWeekdaySpending(&report.Weekend, report.Total)
+	return report, nil
+}
+
+// finishWeekdaySpending fills in the per-day average and the share of total
+func finishWeekdaySpending(s *models.WeekdaySpending, total float64) {
+	if s.Days > 0 {
+		s.Average = s.Total / float64(s.Days)
+	}
+	if total > 0 {
+		s.Share = s.Total / total
+	}
+}
@@ -2,12 +2,11 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 
+	"budget-tracker/internal/config"
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 )
@@ -28,6 +27,17 @@ func handleAPIError(err error) error {
 				"%w: authentication failed - check ANTHROPIC_API_KEY",
 				ErrAPIKeyNotSet,
 			)
+		case 404:
+			// The only resource a request names is the model
+			return fmt.Errorf("%w: %w: %v", ErrAPIError, ErrModelNotFound, err)
+		case 402:
+			return fmt.Errorf("%w: %v", ErrQuotaExhausted, err)
+		case 400:
+			// Anthropic reports an empty credit balance as an invalid request
+			if strings.Contains(strings.ToLower(apiErr.RawJSON()), "credit balance") {
+				return fmt.Errorf("%w: %v", ErrQuotaExhausted, err)
+			}
+			return fmt.Errorf("%w: status %d - %v", ErrAPIError, apiErr.StatusCode, err)
 		case 429:
 			return ErrRateLimit
 		case 408, 504:
@@ -73,6 +83,7 @@ var (
 	ErrAPIError        = errors.New("API returned an error")
 	ErrMaxRetries      = errors.New("max retries exceeded")
 	ErrOverloaded      = errors.New("AI service is temporarily overloaded")
+	ErrQuotaExhausted  = errors.New("AI provider quota or credit is exhausted")
 )
 
 const (
@@ -82,31 +93,17 @@ const (
 // Client represents the AI service client for receipt processing
 type Client struct {
 	client    anthropic.Client
-	model     anthropic.Model
+	models    *modelChain
 	maxTokens int
 }
 
 // Config holds AI client configuration
 type Config struct {
-	APIKey    string
-	Model     string
-	MaxTokens int
-}
-
-// RawReceiptItem represents an item extracted from OCR (uncategorized)
-type RawReceiptItem struct {
-	ItemCode  string  `json:"item_code"`
-	ItemPrice float64 `json:"item_price"`
-	ItemName  string  `json:"item_name"`
-}
-
-// OCRExtractionResult represents the output of OCR extraction
-type OCRExtractionResult struct {
-	Source    string           `json:"source"`
-	Items     []RawReceiptItem `json:"items"`
-	Total     float64          `json:"total"`
-	Tax       float64          `json:"tax"`
-	ItemCount int              `json:"item_count"`
+	APIKey string
+	Model  string
+	// FallbackModels are tried in order once Model is no longer served
+	FallbackModels []string
+	MaxTokens      int
 }
 
 // CategorizedItem represents an item with budget category assigned
@@ -117,11 +114,6 @@ type CategorizedItem struct {
 	ItemType  string  `json:"item_type"`
 }
 
-// CategorizationResult represents the output of categorization
-type CategorizationResult struct {
-	Items []CategorizedItem `json:"items"`
-}
-
 // ReceiptProcessingResult represents the combined OCR + categorization result
 type ReceiptProcessingResult struct {
 	Source    string            `json:"source"`
@@ -129,23 +121,30 @@ type ReceiptProcessingResult struct {
 	Total     float64           `json:"total"`
 	Tax       float64           `json:"tax"`
 	ItemCount int               `json:"item_count"`
+
+	// Receipts holds each receipt of a document that contains several, e.g.
+	// a scan of a whole shopping trip. The fields above then add them up, so
+	// callers expecting one receipt still get every item.
+	Receipts []ReceiptProcessingResult `json:"receipts,omitempty"`
+
+	// RawResponses holds the model's answers as received: the first answer and,
+	// if it had to be repaired, the repaired one
+	RawResponses []string `json:"-"`
 }
 
 // NewClient creates a new AI service client
 func NewClient(cfg Config) (*Client, error) {
 	apiKey := cfg.APIKey
 	if apiKey == "" {
-		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+		apiKey = config.Get("ANTHROPIC_API_KEY")
 	}
 	if apiKey == "" {
 		return nil, ErrAPIKeyNotSet
 	}
 
-	var model anthropic.Model
-	if cfg.Model != "" {
-		model = anthropic.Model(cfg.Model)
-	} else {
-		model = anthropic.ModelClaudeSonnet4_5
+	model := cfg.Model
+	if model == "" {
+		model = string(anthropic.ModelClaudeSonnet4_5)
 	}
 
 	maxTokens := cfg.MaxTokens
@@ -160,36 +159,92 @@ func NewClient(cfg Config) (*Client, error) {
 
 	return &Client{
 		client:    client,
-		model:     model,
+		models:    newModelChain(model, cfg.FallbackModels),
 		maxTokens: maxTokens,
 	}, nil
 }
 
-// NewClientFromEnv creates a new AI service client using environment variables
+// NewClientFromEnv creates a new AI service client using environment
+// variables: ANTHROPIC_API_KEY, the optional ANTHROPIC_MODEL and
+// AI_FALLBACK_MODELS
 func NewClientFromEnv() (*Client, error) {
-	return NewClient(Config{})
+	return NewClient(Config{
+		Model:          config.Get("ANTHROPIC_MODEL"),
+		FallbackModels: FallbackModelsFromEnv(),
+	})
 }
 
-// AnalyzeDocument sends a PDF document with a prompt to the AI and returns the response
-// Only PDF format (application/pdf) is supported
+// ModelStatus returns the model in use and the fallbacks left
+func (c *Client) ModelStatus() ModelStatus {
+	return c.models.status()
+}
+
+// CheckCredentials looks up the configured model, which fails when the API
+// key is invalid or the model does not exist. A missing model falls back to
+// the next one that exists.
+func (c *Client) CheckCredentials(ctx context.Context) error {
+	return c.models.do(func(model string) error {
+		_, err := c.client.Models.Get(ctx, model, anthropic.ModelGetParams{})
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *anthropic.Error
+		if errors.As(err, &apiErr) {
+			switch apiErr.StatusCode {
+			case 401, 403:
+				return fmt.Errorf("%w: authentication failed - check ANTHROPIC_API_KEY", ErrAPIKeyNotSet)
+			case 404:
+				return fmt.Errorf("%w: %w: %q", ErrAPIError, ErrModelNotFound, model)
+			}
+			return fmt.Errorf("%w: status %d", ErrAPIError, apiErr.StatusCode)
+		}
+		return fmt.Errorf("%w: %v", ErrAPIError, err)
+	})
+}
+
+// newMessage sends params with the active model, falling back to the next
+// model while the active one is not found
+func (c *Client) newMessage(
+	ctx context.Context,
+	params anthropic.MessageNewParams,
+	opts ...option.RequestOption,
+) (*anthropic.Message, error) {
+	var message *anthropic.Message
+	err := c.models.do(func(model string) error {
+		params.Model = anthropic.Model(model)
+		var err error
+		message, err = c.client.Messages.New(ctx, params, opts...)
+		if err != nil {
+			return handleAPIError(err)
+		}
+		return nil
+	})
+	return message, err
+}
+
+// AnalyzeDocument sends a document with a prompt to the AI and returns the response
+// PDFs and JPEG, PNG and WebP images are supported
 func (c *Client) AnalyzeDocument(
 	ctx context.Context,
 	base64Data, mimeType, prompt string,
 ) (string, error) {
-	// Only PDF is supported
-	if mimeType != "application/pdf" {
-		return "", fmt.Errorf("%w: unsupported mime type: %s (only application/pdf is supported)", ErrInvalidDocument, mimeType)
+	var contentBlock anthropic.ContentBlockParamUnion
+	switch mimeType {
+	case MimeTypePDF:
+		contentBlock = anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{
+			Type:      "base64",
+			MediaType: MimeTypePDF,
+			Data:      base64Data,
+		})
+	case MimeTypeJPEG, MimeTypePNG, MimeTypeWebP:
+		contentBlock = anthropic.NewImageBlockBase64(mimeType, base64Data)
+	default:
+		return "", fmt.Errorf("%w: unsupported mime type: %s", ErrInvalidDocument, mimeType)
 	}
 
-	contentBlock := anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{
-		Type:      "base64",
-		MediaType: "application/pdf",
-		Data:      base64Data,
-	})
-
-	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+	message, err := c.newMessage(ctx, anthropic.MessageNewParams{
 		MaxTokens: int64(c.maxTokens),
-		Model:     c.model,
 		Messages: []anthropic.MessageParam{
 			{
 				Role: anthropic.MessageParamRoleUser,
@@ -201,7 +256,7 @@ func (c *Client) AnalyzeDocument(
 		},
 	})
 	if err != nil {
-		return "", handleAPIError(err)
+		return "", err
 	}
 
 	// Extract response text from content
@@ -216,9 +271,8 @@ func (c *Client) AnalyzeDocument(
 
 // SendTextPrompt sends a text-only prompt to the AI and returns the response
 func (c *Client) SendTextPrompt(ctx context.Context, prompt string) (string, error) {
-	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+	message, err := c.newMessage(ctx, anthropic.MessageNewParams{
 		MaxTokens: int64(c.maxTokens),
-		Model:     c.model,
 		Messages: []anthropic.MessageParam{
 			{
 				Role: anthropic.MessageParamRoleUser,
@@ -229,7 +283,7 @@ func (c *Client) SendTextPrompt(ctx context.Context, prompt string) (string, err
 		},
 	})
 	if err != nil {
-		return "", handleAPIError(err)
+		return "", err
 	}
 
 	// Extract response text from content
@@ -271,16 +325,22 @@ Also extract:
 - tax: The tax amount (0 if not shown)
 - item_count: Total number of items extracted
 
+=== MULTIPLE PAGES AND RECEIPTS ===
+- A receipt continued over several pages is ONE receipt: do not split it and do not repeat its header items
+- If the document holds several DIFFERENT receipts (different stores, dates or transactions), return {"receipts": [...]} with one object per receipt, each in the format below, in document order
+- For a single receipt, return the object below without "receipts"
+
 === CATEGORIZATION RULES ===
 Budget Categories: %s
 
 1. Compare each item against the Budget Categories list
 2. If item matches a category, assign the type in parentheses (e.g., "Apple (monthly)" → "monthly")
-3. Types must be lowercase: "weekly", "monthly", "misc", or "tax"
-4. If item does NOT match any category, assign "misc"
-5. If item_name contains "tax", "TAX", "HST", "GST", "VAT", assign "tax"
-6. Do NOT guess - only match against provided categories
-7. *** TAX LINE ITEMS ARE MANDATORY ***: If you see ANY tax line (sales tax, VAT, HST, GST, PST, etc.) on the receipt, you MUST extract it as a separate item with item_type "tax", item_code "TAX", and item_name "Tax"
+3. A category may lead with a group before a colon (e.g., "Groceries: Milk (weekly)"). An item that clearly belongs to the group (e.g., "Eggs" for "Groceries") takes that type even when it is not the named item; the named item wins when several match
+4. Types must be lowercase: "weekly", "monthly", "misc", or "tax"
+5. If item does NOT match any category, assign "misc"
+6. If item_name contains "tax", "TAX", "HST", "GST", "VAT", assign "tax"
+7. Do NOT guess - only match against provided categories
+8. *** TAX LINE ITEMS ARE MANDATORY ***: If you see ANY tax line (sales tax, VAT, HST, GST, PST, etc.) on the receipt, you MUST extract it as a separate item with item_type "tax", item_code "TAX", and item_name "Tax"
 
 === OUTPUT FORMAT ===
 IMPORTANT: Return ONLY the raw JSON object, nothing else.
@@ -316,125 +376,8 @@ IMPORTANT: Return ONLY the raw JSON object, nothing else.
 	)
 }
 
-// Deprecated: Use ReceiptProcessingPrompt and ProcessReceiptDocument instead
-// OCRExtractionPrompt returns the prompt for pure OCR extraction (no categorization)
-func OCRExtractionPrompt() string {
-	return `You are a precise receipt OCR system. Your ONLY task is to extract data exactly as printed on the receipt.
-
-=== CRITICAL REQUIREMENTS ===
-*** EXTRACT EVERY SINGLE ITEM - No omissions allowed ***
-*** COPY ITEM CODES EXACTLY AS PRINTED - Do not modify or abbreviate ***
-*** COPY PRICES EXACTLY AS SHOWN - Do not round or modify ***
-*** PRESERVE ORDER - Extract items from top to bottom ***
-*** DO NOT CATEGORIZE - This is extraction only ***
-
-=== EXTRACTION RULES ===
-For EACH line item on the receipt, extract:
-1. item_code: The EXACT code/SKU as printed (if not visible, use "N/A")
-2. item_price: The EXACT price as a decimal number
-3. item_name: Your best interpretation of the item name
-
-Also extract:
-- source: Store name from receipt header (use "Unknown" if not visible)
-- total: The total amount shown on receipt
-- tax: The tax amount (0 if not shown)
-- item_count: Total number of items extracted
-
-=== OUTPUT FORMAT ===
-CRITICAL: Return ONLY raw JSON. Do NOT wrap in markdown code blocks.
-Do NOT use ` + "`" + `` + "`" + `` + "`" + `json or ` + "`" + `` + "`" + `` + "`" + ` - just return the raw JSON object.
-
-{
-  "source": "Store Name",
-  "item_count": 0,
-  "total": 0.00,
-  "tax": 0.00,
-  "items": [
-    {
-      "item_code": "EXACT_CODE",
-      "item_price": 0.00,
-      "item_name": "Item Name"
-    }
-  ]
-}
-
-=== WARNINGS ===
-- EVERY line item must be extracted
-- Item codes must be EXACTLY as printed
-- Prices must be EXACTLY as shown
-- Items must be in receipt order (top to bottom)
-- Return ONLY raw JSON, absolutely NO markdown formatting or code blocks`
-}
-
-// Deprecated: Use ReceiptProcessingPrompt and ProcessReceiptDocument instead
-// CategorizationPrompt returns the prompt for categorizing extracted items
-func CategorizationPrompt(itemsJSON string, budgets []string) string {
-	budgetList := "None"
-	if len(budgets) > 0 {
-		budgetList = strings.Join(budgets, ", ")
-	}
-
-	return fmt.Sprintf(
-		`You are a budget categorization system. Categorize each item based on the budget categories provided.
-
-=== INPUT ===
-Extracted items: %s
-
-Budget Categories: %s
-
-=== CATEGORIZATION RULES ===
-1. Compare each item against the Budget Categories list
-2. If item matches a category, assign the type in parentheses (e.g., "Apple (monthly)" → "monthly")
-3. Types must be lowercase: "weekly", "monthly", "misc", or "tax"
-4. If item does NOT match any category, assign "misc"
-5. If item_code contains "tax", "TAX", "HST", "GST", "VAT", assign "tax"
-6. Do NOT guess - only match against provided categories
-
-=== OUTPUT FORMAT ===
-CRITICAL: Return ONLY raw JSON. Do NOT wrap in markdown code blocks.
-Do NOT use `+"`"+``+"`"+``+"`"+`json or `+"`"+``+"`"+``+"`"+` - just return the raw JSON object:
-{
-  "items": [
-    {
-      "item_code": "BB GARLIC HOT",
-      "item_price": 0.00,
-      "item_name": "Name",
-      "item_type": "weekly|monthly|misc|tax"
-    }
-  ]
-}
-
-=== WARNINGS ===
-- Preserve the exact item_code, item_price from input
-- Only add item_type based on categorization rules
-- Maintain the same order as input
-- Return ONLY raw JSON, absolutely NO markdown formatting or code blocks`,
-		itemsJSON,
-		budgetList,
-	)
-}
-
-// Legacy types for backward compatibility
-type ReceiptData struct {
-	Items []ReceiptItem `json:"items"`
-	Total float64       `json:"total"`
-	Date  string        `json:"date"`
-	Store string        `json:"store"`
-}
-
-type ReceiptItem struct {
-	Name     string  `json:"name"`
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
-}
-
-// ProcessReceipt is deprecated - use AnalyzeDocument instead
-func (c *Client) ProcessReceipt(imageData []byte) (*ReceiptData, error) {
-	return nil, errors.New("use AnalyzeDocument instead")
-}
-
-// ProcessReceiptDocument performs OCR extraction and categorization on a PDF receipt in a single AI request
-// Only PDF format (application/pdf) is supported
+// ProcessReceiptDocument performs OCR extraction and categorization on a receipt in a single AI request
+// The receipt may be a PDF or a JPEG, PNG or WebP image
 func (c *Client) ProcessReceiptDocument(
 	ctx context.Context,
 	base64Data, mimeType string,
@@ -447,86 +390,5 @@ func (c *Client) ProcessReceiptDocument(
 		return nil, fmt.Errorf("receipt processing failed: %w", err)
 	}
 
-	// Strip any markdown code block formatting from the response
-	responseText = stripMarkdownCodeBlock(responseText)
-
-	var result ReceiptProcessingResult
-	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		return nil, fmt.Errorf(
-			"%w: failed to parse result: %v\nResponse was: %s",
-			ErrParseResponse,
-			err,
-			responseText,
-		)
-	}
-
-	return &result, nil
-}
-
-// ProcessReceiptImage is deprecated, use ProcessReceiptDocument instead
-func (c *Client) ProcessReceiptImage(
-	ctx context.Context,
-	base64Data, mimeType string,
-	budgets []string,
-) (*ReceiptProcessingResult, error) {
-	return c.ProcessReceiptDocument(ctx, base64Data, mimeType, budgets)
-}
-
-// Deprecated: Use ProcessReceiptDocument instead
-// ExtractReceiptItems performs OCR extraction on a PDF receipt document (Step 1)
-// Only PDF format (application/pdf) is supported
-func (c *Client) ExtractReceiptItems(
-	ctx context.Context,
-	base64Data, mimeType string,
-) (*OCRExtractionResult, error) {
-	prompt := OCRExtractionPrompt()
-
-	responseText, err := c.AnalyzeDocument(ctx, base64Data, mimeType, prompt)
-	if err != nil {
-		return nil, fmt.Errorf("document extraction failed: %w", err)
-	}
-
-	var result OCRExtractionResult
-	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		return nil, fmt.Errorf(
-			"%w: failed to parse OCR result: %v\nResponse was: %s",
-			ErrParseResponse,
-			err,
-			responseText,
-		)
-	}
-
-	return &result, nil
-}
-
-// Deprecated: Use ProcessReceiptDocument instead
-// CategorizeItems categorizes extracted items against budget categories (Step 2)
-func (c *Client) CategorizeItems(
-	ctx context.Context,
-	items []RawReceiptItem,
-	budgets []string,
-) (*CategorizationResult, error) {
-	// Convert items to JSON for the prompt
-	itemsJSON, err := json.Marshal(items)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal items: %w", err)
-	}
-
-	prompt := CategorizationPrompt(string(itemsJSON), budgets)
-
-	responseText, err := c.SendTextPrompt(ctx, prompt)
-	if err != nil {
-		return nil, fmt.Errorf("categorization failed: %w", err)
-	}
-
-	var result CategorizationResult
-	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		return nil, fmt.Errorf(
-			"%w: failed to parse categorization result: %v",
-			ErrParseResponse,
-			err,
-		)
-	}
-
-	return &result, nil
+	return parseReceiptResultWithRepair(ctx, c, responseText)
 }
@@ -1,11 +1,15 @@
 package ai
 
 import (
+	"bytes"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 )
 
 // PDF processing errors
@@ -94,3 +98,131 @@ func (p *PDFProcessor) ValidateFormat(data []byte) (string, error) {
 
 	return "", ErrUnsupportedFormat
 }
+
+// Document limit errors
+var (
+	ErrTypeNotAllowed   = errors.New("document type not allowed")
+	ErrDocumentTooLarge = errors.New("document too large")
+	ErrTooManyPages     = errors.New("document has too many pages")
+)
+
+// Mime types of the documents DetectMimeType recognizes
+const (
+	MimeTypePDF  = "application/pdf"
+	MimeTypeJPEG = "image/jpeg"
+	MimeTypePNG  = "image/png"
+	MimeTypeWebP = "image/webp"
+)
+
+// SupportedMimeTypes are the document types a deployment may allow
+var SupportedMimeTypes = []string{MimeTypePDF, MimeTypeJPEG, MimeTypePNG, MimeTypeWebP}
+
+// DefaultMaxDocumentSize is the default upload limit of a document (10MB)
+const DefaultMaxDocumentSize = 10 << 20
+
+// DocumentLimits are the documents a deployment accepts for processing
+type DocumentLimits struct {
+	// MaxSize is the largest document in bytes
+	MaxSize int64
+	// AllowedTypes are the accepted mime types, a subset of SupportedMimeTypes
+	AllowedTypes []string
+	// MaxPages is the most pages a PDF may have; 0 is no limit
+	MaxPages int
+}
+
+// DefaultDocumentLimits accepts PDFs of up to 10MB with any number of pages
+func DefaultDocumentLimits() DocumentLimits {
+	return DocumentLimits{MaxSize: DefaultMaxDocumentSize, AllowedTypes: []string{MimeTypePDF}}
+}
+
+// Allows reports whether documents of mimeType are accepted
+func (l DocumentLimits) Allows(mimeType string) bool {
+	return slices.Contains(l.AllowedTypes, mimeType)
+}
+
+// Validate checks the limits themselves, e.g. after reading them from the
+// environment
+func (l DocumentLimits) Validate() error {
+	if l.MaxSize <= 0 {
+		return errors.New("the maximum document size must be positive")
+	}
+	if len(l.AllowedTypes) == 0 {
+		return errors.New("at least one document type must be allowed")
+	}
+	for _, t := range l.AllowedTypes {
+		if !slices.Contains(SupportedMimeTypes, t) {
+			return fmt.Errorf("unsupported document type %q, expected one of %s",
+				t, strings.Join(SupportedMimeTypes, ", "))
+		}
+	}
+	if l.MaxPages < 0 {
+		return errors.New("the maximum number of pages must not be negative")
+	}
+	return nil
+}
+
+// DetectMimeType returns the mime type of data from its magic bytes, or ""
+// when it is none of SupportedMimeTypes
+func DetectMimeType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF")):
+		return MimeTypePDF
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return MimeTypeJPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return MimeTypePNG
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return MimeTypeWebP
+	}
+	return ""
+}
+
+// pagePattern matches the page objects of a PDF, but not the /Pages tree nodes
+var pagePattern = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// CountPDFPages counts the page objects of a PDF. Pages stored in compressed
+// object streams are not seen, so 0 means the count is unknown.
+func CountPDFPages(data []byte) int {
+	return len(pagePattern.FindAllIndex(data, -1))
+}
+
+// DocumentProcessor validates documents against the limits of the deployment
+// and encodes them for the AI provider
+type DocumentProcessor struct {
+	limits DocumentLimits
+}
+
+// NewDocumentProcessor creates a new DocumentProcessor
+func NewDocumentProcessor(limits DocumentLimits) *DocumentProcessor {
+	return &DocumentProcessor{limits: limits}
+}
+
+// Validate returns the mime type of data, or ErrDocumentTooLarge,
+// ErrTypeNotAllowed or ErrTooManyPages when data is outside the limits
+func (p *DocumentProcessor) Validate(data []byte) (string, error) {
+	if int64(len(data)) > p.limits.MaxSize {
+		return "", fmt.Errorf("%w: %d bytes, the limit is %d", ErrDocumentTooLarge, len(data), p.limits.MaxSize)
+	}
+	mimeType := DetectMimeType(data)
+	if mimeType == "" || !p.limits.Allows(mimeType) {
+		return "", fmt.Errorf("%w: accepted types are %s", ErrTypeNotAllowed, strings.Join(p.limits.AllowedTypes, ", "))
+	}
+	if mimeType == MimeTypePDF && p.limits.MaxPages > 0 {
+		if pages := CountPDFPages(data); pages > p.limits.MaxPages {
+			return "", fmt.Errorf("%w: %d pages, the limit is %d", ErrTooManyPages, pages, p.limits.MaxPages)
+		}
+	}
+	return mimeType, nil
+}
+
+// Process validates data and encodes it for the AI provider
+func (p *DocumentProcessor) Process(data []byte) (*ProcessedDocument, error) {
+	mimeType, err := p.Validate(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ProcessedDocument{
+		Base64Data: base64.StdEncoding.EncodeToString(data),
+		MimeType:   mimeType,
+	}, nil
+}
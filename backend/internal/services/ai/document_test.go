@@ -2,6 +2,7 @@ package ai
 
 import (
 	"bytes"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -282,3 +283,50 @@ func TestPDFProcessor_RejectsRandomBytes(t *testing.T) {
 		t.Errorf("Expected ErrUnsupportedFormat, got: %v", err)
 	}
 }
+
+func TestDocumentProcessor_Validate(t *testing.T) {
+	processor := NewDocumentProcessor(DocumentLimits{
+		MaxSize:      128,
+		AllowedTypes: []string{MimeTypePDF, MimeTypePNG},
+		MaxPages:     1,
+	})
+
+	onePage := []byte("%PDF-1.4\n1 0 obj <</Type /Pages>> endobj\n2 0 obj <</Type /Page>> endobj")
+	if mimeType, err := processor.Validate(onePage); err != nil || mimeType != MimeTypePDF {
+		t.Errorf("Expected a one page PDF to be accepted, got %q %v", mimeType, err)
+	}
+	if mimeType, err := processor.Validate(pngData); err != nil || mimeType != MimeTypePNG {
+		t.Errorf("Expected an allowed PNG to be accepted, got %q %v", mimeType, err)
+	}
+
+	twoPages := []byte("%PDF-1.4\n<</Type /Page>>\n<</Type/Page>>")
+	for name, tc := range map[string]struct {
+		data []byte
+		err  error
+	}{
+		"jpeg":      {jpegData, ErrTypeNotAllowed},
+		"unknown":   {[]byte("plain text"), ErrTypeNotAllowed},
+		"two pages": {twoPages, ErrTooManyPages},
+		"too large": {append([]byte("%PDF-1.4\n"), make([]byte, 128)...), ErrDocumentTooLarge},
+	} {
+		if _, err := processor.Validate(tc.data); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", name, tc.err, err)
+		}
+	}
+}
+
+func TestDocumentLimits_Validate(t *testing.T) {
+	if err := DefaultDocumentLimits().Validate(); err != nil {
+		t.Errorf("Expected the default limits to be valid, got %v", err)
+	}
+	for _, limits := range []DocumentLimits{
+		{MaxSize: 0, AllowedTypes: []string{MimeTypePDF}},
+		{MaxSize: 1, AllowedTypes: nil},
+		{MaxSize: 1, AllowedTypes: []string{"image/gif"}},
+		{MaxSize: 1, AllowedTypes: []string{MimeTypePDF}, MaxPages: -1},
+	} {
+		if err := limits.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", limits)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExpenseEnricher is implemented by providers that can suggest a readable
+// name and expense type for terse expense entries, e.g. "AMZN MKTP 43.12"
+type ExpenseEnricher interface {
+	EnrichExpenses(ctx context.Context, entries []EnrichmentEntry, history []string) ([]EnrichmentSuggestion, error)
+}
+
+var (
+	_ ExpenseEnricher = (*Client)(nil)
+	_ ExpenseEnricher = (*OpenAICompatibleClient)(nil)
+)
+
+// EnrichmentEntry is an expense as the user entered it
+type EnrichmentEntry struct {
+	ID     int64   `json:"id"`
+	Name   string  `json:"name"`
+	Source string  `json:"source"`
+	Amount float64 `json:"amount"`
+}
+
+// EnrichmentSuggestion is the model's readable name and expense type for the
+// entry with ID
+type EnrichmentSuggestion struct {
+	ID       int64  `json:"id"`
+	ItemName string `json:"item_name"`
+	ItemType string `json:"item_type"`
+}
+
+// enrichmentTypes are the item_type values EnrichmentPrompt asks for
+var enrichmentTypes = map[string]bool{"weekly": true, "monthly": true, "misc": true}
+
+// EnrichmentPrompt returns the prompt asking for readable names of entries,
+// with history, e.g. "Milk at Costco (weekly)", showing how the user names
+// and categorizes their expenses
+func EnrichmentPrompt(entriesJSON string, history []string) string {
+	historyList := "None"
+	if len(history) > 0 {
+		historyList = "- " + strings.Join(history, "\n- ")
+	}
+
+	return fmt.Sprintf(
+		`You are a budget assistant. The expenses below were entered tersely, often copied from a bank or card statement. Suggest a short, human-readable name and an expense type for each.
+
+=== EXPENSES ===
+%s
+
+=== THE USER'S RECENT EXPENSES ===
+%s
+
+=== RULES ===
+1. Decode merchant abbreviations and statement codes, e.g. "AMZN MKTP" is an Amazon Marketplace order
+2. Name the purchase the way the user names similar expenses in their recent expenses
+3. item_type must be "weekly", "monthly" or "misc", following the user's recent expenses for similar purchases
+4. Use "misc" when nothing similar is in the recent expenses
+5. Leave an expense out when you cannot tell what it is; do NOT guess
+
+=== OUTPUT FORMAT ===
+CRITICAL: Return ONLY raw JSON, with no markdown code blocks:
+{
+  "suggestions": [
+    {"id": 1, "item_name": "Amazon order", "item_type": "misc"}
+  ]
+}`,
+		entriesJSON,
+		historyList,
+	)
+}
+
+// enrichExpenses asks p for suggestions for entries
+func enrichExpenses(
+	ctx context.Context,
+	p textPrompter,
+	entries []EnrichmentEntry,
+	history []string,
+) ([]EnrichmentSuggestion, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	entriesJSON, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entries: %w", err)
+	}
+
+	responseText, err := p.SendTextPrompt(ctx, EnrichmentPrompt(string(entriesJSON), history))
+	if err != nil {
+		return nil, fmt.Errorf("expense enrichment failed: %w", err)
+	}
+	return decodeEnrichment(responseText, entries)
+}
+
+// decodeEnrichment decodes the model's answer to EnrichmentPrompt. Suggestions
+// for unknown entries or without a name are dropped, and unknown types are
+// misc.
+func decodeEnrichment(responseText string, entries []EnrichmentEntry) ([]EnrichmentSuggestion, error) {
+	var result struct {
+		Suggestions []EnrichmentSuggestion `json:"suggestions"`
+	}
+	if err := json.Unmarshal([]byte(stripMarkdownCodeBlock(responseText)), &result); err != nil {
+		return nil, &RawResponseError{
+			Err:          fmt.Errorf("%w: failed to parse enrichment result: %v", ErrParseResponse, err),
+			RawResponses: []string{responseText},
+		}
+	}
+
+	asked := make(map[int64]bool, len(entries))
+	for _, entry := range entries {
+		asked[entry.ID] = true
+	}
+
+	suggestions := []EnrichmentSuggestion{}
+	for _, s := range result.Suggestions {
+		s.ItemName = strings.TrimSpace(s.ItemName)
+		if !asked[s.ID] || s.ItemName == "" {
+			continue
+		}
+		// One suggestion per entry
+		delete(asked, s.ID)
+		s.ItemType = strings.ToLower(strings.TrimSpace(s.ItemType))
+		if !enrichmentTypes[s.ItemType] {
+			s.ItemType = "misc"
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, nil
+}
+
+// EnrichExpenses suggests readable names and expense types for entries
+func (c *Client) EnrichExpenses(
+	ctx context.Context,
+	entries []EnrichmentEntry,
+	history []string,
+) ([]EnrichmentSuggestion, error) {
+	return enrichExpenses(ctx, c, entries, history)
+}
+
+// EnrichExpenses suggests readable names and expense types for entries
+func (c *OpenAICompatibleClient) EnrichExpenses(
+	ctx context.Context,
+	entries []EnrichmentEntry,
+	history []string,
+) ([]EnrichmentSuggestion, error) {
+	return enrichExpenses(ctx, c, entries, history)
+}
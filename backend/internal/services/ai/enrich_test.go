@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnrichExpenses(t *testing.T) {
+	entries := []EnrichmentEntry{
+		{ID: 1, Name: "AMZN MKTP 43.12", Source: "Visa", Amount: 43.12},
+		{ID: 2, Name: "SQ *BLUE BTL", Source: "Visa", Amount: 5.5},
+		{ID: 3, Name: "POS 0042", Source: "Visa", Amount: 12},
+	}
+	response := "```json\n" + `{"suggestions": [
+		{"id": 1, "item_name": " Amazon order ", "item_type": "MISC"},
+		{"id": 2, "item_name": "Coffee", "item_type": "daily"},
+		{"id": 2, "item_name": "Blue Bottle coffee", "item_type": "weekly"},
+		{"id": 3, "item_name": "", "item_type": "misc"},
+		{"id": 9, "item_name": "Not asked", "item_type": "misc"}
+	]}` + "\n```"
+
+	p := &fakePrompter{responses: []string{response}}
+	suggestions, err := enrichExpenses(context.Background(), p, entries, []string{"Milk at Costco (weekly)"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []EnrichmentSuggestion{
+		{ID: 1, ItemName: "Amazon order", ItemType: "misc"},
+		{ID: 2, ItemName: "Coffee", ItemType: "misc"},
+	}
+	if !reflect.DeepEqual(suggestions, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, suggestions)
+	}
+	if len(p.prompts) != 1 || !strings.Contains(p.prompts[0], "AMZN MKTP 43.12") ||
+		!strings.Contains(p.prompts[0], "- Milk at Costco (weekly)") {
+		t.Errorf("Expected the entries and history in the prompt, got %v", p.prompts)
+	}
+
+	p = &fakePrompter{responses: []string{"not json"}}
+	_, err = enrichExpenses(context.Background(), p, entries, nil)
+	if !errors.Is(err, ErrParseResponse) || len(RawResponses(err)) != 1 {
+		t.Errorf("Expected a parse error keeping the response, got %v", err)
+	}
+
+	// Nothing to ask, no request
+	p = &fakePrompter{}
+	if suggestions, err := enrichExpenses(context.Background(), p, nil, nil); err != nil || suggestions != nil ||
+		len(p.prompts) != 0 {
+		t.Errorf("Expected no request without entries, got %v, %v", suggestions, err)
+	}
+}
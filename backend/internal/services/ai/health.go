@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Provider health statuses
+const (
+	// HealthUnknown: no receipt was processed and no probe ran yet
+	HealthUnknown = "unknown"
+	// HealthOK: the last request was accepted
+	HealthOK = "ok"
+	// HealthExhausted: the provider refused work because the quota or
+	// credit ran out
+	HealthExhausted = "exhausted"
+	// HealthUnauthorized: the provider rejected the API key
+	HealthUnauthorized = "unauthorized"
+	// HealthError: the last probe failed for another reason, e.g. a timeout
+	HealthError = "error"
+)
+
+// probeTimeout bounds a single health probe
+const probeTimeout = 30 * time.Second
+
+// ProviderHealth is the last known state of the AI provider
+type ProviderHealth struct {
+	Status string `json:"status"`
+	// Message is the error behind a status other than ok
+	Message string `json:"message,omitempty"`
+	// Quota is the remaining allowance from the last probe, if reported
+	Quota         *Quota     `json:"quota,omitempty"`
+	CheckedAt     *time.Time `json:"checked_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	// Model is the model in use, with the models retired since startup, for
+	// providers that fall back to alternate models
+	Model *ModelStatus `json:"model,omitempty"`
+}
+
+// Healthy reports whether receipts can be expected to process; unknown
+// counts as healthy until something failed
+func (h ProviderHealth) Healthy() bool {
+	return h.Status == HealthOK || h.Status == HealthUnknown
+}
+
+// HealthMonitor wraps a ReceiptProvider and tracks whether it still accepts
+// work, from the outcome of every receipt it processes and from periodic
+// probes (see Check), so an exhausted quota shows up before users hit it
+type HealthMonitor struct {
+	provider ReceiptProvider
+	now      func() time.Time
+
+	mu     sync.Mutex
+	health ProviderHealth
+}
+
+var _ ReceiptProvider = (*HealthMonitor)(nil)
+
+// NewHealthMonitor creates a HealthMonitor for provider
+func NewHealthMonitor(provider ReceiptProvider) *HealthMonitor {
+	return &HealthMonitor{
+		provider: provider,
+		now:      time.Now,
+		health:   ProviderHealth{Status: HealthUnknown},
+	}
+}
+
+// ProcessReceiptDocument processes the receipt with the wrapped provider and
+// records the outcome
+func (m *HealthMonitor) ProcessReceiptDocument(
+	ctx context.Context,
+	base64Data, mimeType string,
+	budgets []string,
+) (*ReceiptProcessingResult, error) {
+	result, err := m.provider.ProcessReceiptDocument(ctx, base64Data, mimeType, budgets)
+	switch {
+	case err == nil, errors.Is(err, ErrParseResponse):
+		// The provider answered, even if the answer was unusable
+		m.record(HealthOK, nil, nil, false)
+	case errors.Is(err, ErrQuotaExhausted):
+		m.record(HealthExhausted, err, nil, false)
+	case errors.Is(err, ErrAPIKeyNotSet):
+		m.record(HealthUnauthorized, err, nil, false)
+	case errors.Is(err, ErrModelNotFound):
+		// The configured model and every fallback are gone
+		m.record(HealthError, err, nil, false)
+	}
+	// Timeouts, rate limits and bad documents say nothing about the quota
+	return result, err
+}
+
+// Check probes the provider with the cheapest request it supports and
+// returns the updated health. Providers that can only verify their
+// credentials cannot detect an exhausted quota before a receipt fails.
+func (m *HealthMonitor) Check(ctx context.Context) ProviderHealth {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var quota *Quota
+	var err error
+	switch p := m.provider.(type) {
+	case QuotaChecker:
+		quota, err = p.CheckQuota(ctx)
+	case CredentialChecker:
+		err = p.CheckCredentials(ctx)
+	default:
+		return m.Health()
+	}
+
+	switch {
+	case err == nil:
+		m.record(HealthOK, nil, quota, true)
+	case errors.Is(err, ErrQuotaExhausted):
+		m.record(HealthExhausted, err, quota, true)
+	case errors.Is(err, ErrAPIKeyNotSet):
+		m.record(HealthUnauthorized, err, quota, true)
+	default:
+		m.record(HealthError, err, quota, true)
+	}
+	return m.Health()
+}
+
+// Health returns the last known health
+func (m *HealthMonitor) Health() ProviderHealth {
+	m.mu.Lock()
+	health := m.health
+	m.mu.Unlock()
+
+	if reporter, ok := m.provider.(ModelReporter); ok {
+		status := reporter.ModelStatus()
+		health.Model = &status
+	}
+	return health
+}
+
+// record updates the health after a request; probe is set for Check, whose
+// quota replaces the previous one
+func (m *HealthMonitor) record(status string, err error, quota *Quota, probe bool) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if status != m.health.Status && m.health.Status != HealthUnknown {
+		fmt.Printf("[AI] Provider health changed from %s to %s\n", m.health.Status, status)
+	}
+	m.health.Status = status
+	m.health.Message = ""
+	if err != nil {
+		m.health.Message = err.Error()
+		m.health.LastFailureAt = &now
+	} else {
+		m.health.LastSuccessAt = &now
+	}
+	if probe {
+		m.health.CheckedAt = &now
+		m.health.Quota = quota
+	}
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeQuotaProvider answers receipts and quota probes with preset errors
+type fakeQuotaProvider struct {
+	processErr error
+	quotaErr   error
+	quota      *Quota
+}
+
+func (p *fakeQuotaProvider) ProcessReceiptDocument(
+	context.Context, string, string, []string,
+) (*ReceiptProcessingResult, error) {
+	if p.processErr != nil {
+		return nil, p.processErr
+	}
+	return &ReceiptProcessingResult{}, nil
+}
+
+func (p *fakeQuotaProvider) CheckQuota(context.Context) (*Quota, error) {
+	return p.quota, p.quotaErr
+}
+
+func TestHealthMonitor(t *testing.T) {
+	remaining := int64(42)
+	provider := &fakeQuotaProvider{quota: &Quota{TokensRemaining: &remaining}}
+	monitor := NewHealthMonitor(provider)
+
+	if health := monitor.Health(); health.Status != HealthUnknown || !health.Healthy() {
+		t.Errorf("Expected unknown health before any request, got %+v", health)
+	}
+
+	health := monitor.Check(context.Background())
+	if health.Status != HealthOK || health.CheckedAt == nil || health.Quota == nil || *health.Quota.TokensRemaining != 42 {
+		t.Errorf("Expected ok with the reported quota, got %+v", health)
+	}
+
+	// Receipts that fail for reasons unrelated to the quota keep the status
+	provider.processErr = ErrTimeout
+	monitor.ProcessReceiptDocument(context.Background(), "", "application/pdf", nil)
+	if health := monitor.Health(); health.Status != HealthOK {
+		t.Errorf("Expected a timeout not to change the status, got %+v", health)
+	}
+
+	provider.processErr = ErrQuotaExhausted
+	if _, err := monitor.ProcessReceiptDocument(context.Background(), "", "application/pdf", nil); !errors.Is(err, ErrQuotaExhausted) {
+		t.Errorf("Expected the provider error to be returned, got %v", err)
+	}
+	health = monitor.Health()
+	if health.Status != HealthExhausted || health.Healthy() || health.LastFailureAt == nil || health.Message == "" {
+		t.Errorf("Expected exhausted after a receipt ran out of credit, got %+v", health)
+	}
+
+	provider.quotaErr = ErrAPIKeyNotSet
+	if health := monitor.Check(context.Background()); health.Status != HealthUnauthorized {
+		t.Errorf("Expected unauthorized, got %+v", health)
+	}
+	provider.quotaErr = ErrOverloaded
+	if health := monitor.Check(context.Background()); health.Status != HealthError {
+		t.Errorf("Expected error, got %+v", health)
+	}
+
+	provider.quotaErr = nil
+	if health := monitor.Check(context.Background()); health.Status != HealthOK || health.Message != "" {
+		t.Errorf("Expected ok once the probe succeeds again, got %+v", health)
+	}
+}
+
+func TestOpenAICompatibleClient_CheckQuota(t *testing.T) {
+	exhausted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-remaining-requests", "499")
+		if exhausted {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"code": "insufficient_quota"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "OK"}}]}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewOpenAICompatibleClient(OpenAICompatibleConfig{BaseURL: server.URL, Model: "m"})
+	quota, err := client.CheckQuota(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if quota == nil || *quota.RequestsLimit != 500 || *quota.RequestsRemaining != 499 || quota.TokensLimit != nil {
+		t.Errorf("Expected the request limits from the headers, got %+v", quota)
+	}
+
+	// An exhausted quota is not a rate limit that passes by waiting
+	exhausted = true
+	if _, err := client.CheckQuota(context.Background()); !errors.Is(err, ErrQuotaExhausted) || errors.Is(err, ErrRateLimit) {
+		t.Errorf("Expected ErrQuotaExhausted, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// LegacyRemovalVersion is the release that removes the deprecated receipt
+// methods of Client below, their types and prompts. The receipt package is
+// the supported pipeline; these methods only adapt to it.
+const LegacyRemovalVersion = "0.1.0"
+
+// deprecationsLogged records the deprecated methods already logged, so each
+// is logged once per process rather than on every call
+var deprecationsLogged sync.Map
+
+// logDeprecated logs the first call of the deprecated method name
+func logDeprecated(name, replacement string) {
+	if _, logged := deprecationsLogged.LoadOrStore(name, true); !logged {
+		log.Printf("[AI] %s is deprecated and will be removed in %s; use %s instead",
+			name, LegacyRemovalVersion, replacement)
+	}
+}
+
+// RawReceiptItem represents an item extracted from OCR (uncategorized)
+//
+// Deprecated: Use CategorizedItem, removed in LegacyRemovalVersion
+type RawReceiptItem struct {
+	ItemCode  string  `json:"item_code"`
+	ItemPrice float64 `json:"item_price"`
+	ItemName  string  `json:"item_name"`
+}
+
+// OCRExtractionResult represents the output of OCR extraction
+//
+// Deprecated: Use ReceiptProcessingResult, removed in LegacyRemovalVersion
+type OCRExtractionResult struct {
+	Source    string           `json:"source"`
+	Items     []RawReceiptItem `json:"items"`
+	Total     float64          `json:"total"`
+	Tax       float64          `json:"tax"`
+	ItemCount int              `json:"item_count"`
+}
+
+// CategorizationResult represents the output of categorization
+//
+// Deprecated: Use ReceiptProcessingResult, removed in LegacyRemovalVersion
+type CategorizationResult struct {
+	Items []CategorizedItem `json:"items"`
+}
+
+// ReceiptData is the result of ProcessReceipt
+//
+// Deprecated: Use ReceiptProcessingResult, removed in LegacyRemovalVersion
+type ReceiptData struct {
+	Items []ReceiptItem `json:"items"`
+	Total float64       `json:"total"`
+	Date  string        `json:"date"`
+	Store string        `json:"store"`
+}
+
+// ReceiptItem is an item of ReceiptData
+//
+// Deprecated: Use CategorizedItem, removed in LegacyRemovalVersion
+type ReceiptItem struct {
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+// OCRExtractionPrompt returns the prompt for pure OCR extraction (no categorization)
+//
+// Deprecated: Use ReceiptProcessingPrompt, removed in LegacyRemovalVersion
+func OCRExtractionPrompt() string {
+	return `You are a precise receipt OCR system. Your ONLY task is to extract data exactly as printed on the receipt.
+
+=== CRITICAL REQUIREMENTS ===
+*** EXTRACT EVERY SINGLE ITEM - No omissions allowed ***
+*** COPY ITEM CODES EXACTLY AS PRINTED - Do not modify or abbreviate ***
+*** COPY PRICES EXACTLY AS SHOWN - Do not round or modify ***
+*** PRESERVE ORDER - Extract items from top to bottom ***
+*** DO NOT CATEGORIZE - This is extraction only ***
+
+=== EXTRACTION RULES ===
+For EACH line item on the receipt, extract:
+1. item_code: The EXACT code/SKU as printed (if not visible, use "N/A")
+2. item_price: The EXACT price as a decimal number
+3. item_name: Your best interpretation of the item name
+
+Also extract:
+- source: Store name from receipt header (use "Unknown" if not visible)
+- total: The total amount shown on receipt
+- tax: The tax amount (0 if not shown)
+- item_count: Total number of items extracted
+
+=== OUTPUT FORMAT ===
+CRITICAL: Return ONLY raw JSON. Do NOT wrap in markdown code blocks.
+Do NOT use ` + "`" + `` + "`" + `` + "`" + `json or ` + "`" + `` + "`" + `` + "`" + ` - just return the raw JSON object.
+
+{
+  "source": "Store Name",
+  "item_count": 0,
+  "total": 0.00,
+  "tax": 0.00,
+  "items": [
+    {
+      "item_code": "EXACT_CODE",
+      "item_price": 0.00,
+      "item_name": "Item Name"
+    }
+  ]
+}
+
+=== WARNINGS ===
+- EVERY line item must be extracted
+- Item codes must be EXACTLY as printed
+- Prices must be EXACTLY as shown
+- Items must be in receipt order (top to bottom)
+- Return ONLY raw JSON, absolutely NO markdown formatting or code blocks`
+}
+
+// CategorizationPrompt returns the prompt for categorizing extracted items
+//
+// Deprecated: Use ReceiptProcessingPrompt, removed in LegacyRemovalVersion
+func CategorizationPrompt(itemsJSON string, budgets []string) string {
+	budgetList := "None"
+	if len(budgets) > 0 {
+		budgetList = strings.Join(budgets, ", ")
+	}
+
+	return fmt.Sprintf(
+		`You are a budget categorization system. Categorize each item based on the budget categories provided.
+
+=== INPUT ===
+Extracted items: %s
+
+Budget Categories: %s
+
+=== CATEGORIZATION RULES ===
+1. Compare each item against the Budget Categories list
+2. If item matches a category, assign the type in parentheses (e.g., "Apple (monthly)" → "monthly")
+3. Types must be lowercase: "weekly", "monthly", "misc", or "tax"
+4. If item does NOT match any category, assign "misc"
+5. If item_code contains "tax", "TAX", "HST", "GST", "VAT", assign "tax"
+6. Do NOT guess - only match against provided categories
+
+=== OUTPUT FORMAT ===
+CRITICAL: Return ONLY raw JSON. Do NOT wrap in markdown code blocks.
+Do NOT use `+"`"+``+"`"+``+"`"+`json or `+"`"+``+"`"+``+"`"+` - just return the raw JSON object:
+{
+  "items": [
+    {
+      "item_code": "BB GARLIC HOT",
+      "item_price": 0.00,
+      "item_name": "Name",
+      "item_type": "weekly|monthly|misc|tax"
+    }
+  ]
+}
+
+=== WARNINGS ===
+- Preserve the exact item_code, item_price from input
+- Only add item_type based on categorization rules
+- Maintain the same order as input
+- Return ONLY raw JSON, absolutely NO markdown formatting or code blocks`,
+		itemsJSON,
+		budgetList,
+	)
+}
+
+// ProcessReceipt processes the PDF receipt in pdfData
+//
+// Deprecated: Use the receipt package, removed in LegacyRemovalVersion
+func (c *Client) ProcessReceipt(pdfData []byte) (*ReceiptData, error) {
+	logDeprecated("ProcessReceipt", "receipt.Pipeline")
+	document, err := NewPDFProcessor().ProcessDocument(bytes.NewReader(pdfData))
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.ProcessReceiptDocument(context.Background(), document.Base64Data, document.MimeType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return legacyReceiptData(result), nil
+}
+
+// legacyReceiptData converts a processed receipt into the ProcessReceipt result
+func legacyReceiptData(result *ReceiptProcessingResult) *ReceiptData {
+	data := &ReceiptData{Store: result.Source, Total: result.Total, Items: []ReceiptItem{}}
+	for _, item := range result.Items {
+		data.Items = append(data.Items, ReceiptItem{Name: item.ItemName, Quantity: 1, Price: item.ItemPrice})
+	}
+	return data
+}
+
+// ProcessReceiptImage extracts and categorizes the items of a PDF receipt
+//
+// Deprecated: Use ProcessReceiptDocument or the receipt package, removed in
+// LegacyRemovalVersion
+func (c *Client) ProcessReceiptImage(
+	ctx context.Context,
+	base64Data, mimeType string,
+	budgets []string,
+) (*ReceiptProcessingResult, error) {
+	logDeprecated("ProcessReceiptImage", "receipt.Pipeline")
+	return c.ProcessReceiptDocument(ctx, base64Data, mimeType, budgets)
+}
+
+// ExtractReceiptItems extracts the items of a PDF receipt document without
+// categorizing them. It is served by the single receipt request, the expense
+// types are dropped.
+//
+// Deprecated: Use ProcessReceiptDocument or the receipt package, removed in
+// LegacyRemovalVersion
+func (c *Client) ExtractReceiptItems(
+	ctx context.Context,
+	base64Data, mimeType string,
+) (*OCRExtractionResult, error) {
+	logDeprecated("ExtractReceiptItems", "receipt.Pipeline")
+	result, err := c.ProcessReceiptDocument(ctx, base64Data, mimeType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return legacyExtraction(result), nil
+}
+
+// legacyExtraction converts a processed receipt into the ExtractReceiptItems
+// result
+func legacyExtraction(result *ReceiptProcessingResult) *OCRExtractionResult {
+	extraction := &OCRExtractionResult{
+		Source:    result.Source,
+		Items:     make([]RawReceiptItem, len(result.Items)),
+		Total:     result.Total,
+		Tax:       result.Tax,
+		ItemCount: result.ItemCount,
+	}
+	for i, item := range result.Items {
+		extraction.Items[i] = RawReceiptItem{ItemCode: item.ItemCode, ItemPrice: item.ItemPrice, ItemName: item.ItemName}
+	}
+	return extraction
+}
+
+// CategorizeItems categorizes extracted items against budget categories.
+// Without the document there is nothing for the receipt pipeline to read, so
+// this still sends its own text prompt.
+//
+// Deprecated: Use ProcessReceiptDocument or the receipt package, which
+// categorize while extracting, removed in LegacyRemovalVersion
+func (c *Client) CategorizeItems(
+	ctx context.Context,
+	items []RawReceiptItem,
+	budgets []string,
+) (*CategorizationResult, error) {
+	logDeprecated("CategorizeItems", "receipt.Pipeline")
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal items: %w", err)
+	}
+
+	responseText, err := c.SendTextPrompt(ctx, CategorizationPrompt(string(itemsJSON), budgets))
+	if err != nil {
+		return nil, fmt.Errorf("categorization failed: %w", err)
+	}
+
+	var result CategorizationResult
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		return nil, fmt.Errorf(
+			"%w: failed to parse categorization result: %v",
+			ErrParseResponse,
+			err,
+		)
+	}
+
+	return &result, nil
+}
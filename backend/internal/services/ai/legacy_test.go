@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLegacyAdapters(t *testing.T) {
+	result, problems := decodeReceiptResult(validReceiptJSON)
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+
+	extraction := legacyExtraction(result)
+	if extraction.Source != "Publix" || extraction.ItemCount != 1 || extraction.Tax != 0.3 ||
+		len(extraction.Items) != 2 || extraction.Items[0] != (RawReceiptItem{"MLK 2%", 3.99, "2% Milk"}) {
+		t.Errorf("Unexpected extraction: %+v", extraction)
+	}
+
+	data := legacyReceiptData(result)
+	if data.Store != "Publix" || data.Total != 4.29 ||
+		len(data.Items) != 2 || data.Items[0] != (ReceiptItem{Name: "2% Milk", Quantity: 1, Price: 3.99}) {
+		t.Errorf("Unexpected receipt data: %+v", data)
+	}
+}
+
+func TestLogDeprecated_Once(t *testing.T) {
+	var out bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&out)
+
+	for i := 0; i < 3; i++ {
+		logDeprecated("TestOnlyMethod", "receipt.Pipeline")
+	}
+	if n := strings.Count(out.String(), "TestOnlyMethod is deprecated and will be removed in "+LegacyRemovalVersion); n != 1 {
+		t.Errorf("Expected the deprecation to be logged once, got %d times: %q", n, out.String())
+	}
+}
@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"budget-tracker/internal/config"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrModelNotFound is returned, wrapped with ErrAPIError, when the provider
+// no longer serves the model, e.g. after it was retired
+var ErrModelNotFound = errors.New("model not found")
+
+// ModelStatus is the model a provider currently uses and the fallbacks left
+type ModelStatus struct {
+	// Configured is the model the provider was configured with
+	Configured string `json:"configured"`
+	// Active is the model requests are sent to; it differs from Configured
+	// after a fallback
+	Active    string         `json:"active"`
+	Fallbacks []string       `json:"fallbacks,omitempty"`
+	Retired   []RetiredModel `json:"retired,omitempty"`
+}
+
+// RetiredModel is a model the provider stopped serving
+type RetiredModel struct {
+	Model     string    `json:"model"`
+	Error     string    `json:"error"`
+	RetiredAt time.Time `json:"retired_at"`
+}
+
+// ModelReporter is implemented by providers that fall back to alternate
+// models when the configured one is retired
+type ModelReporter interface {
+	ModelStatus() ModelStatus
+}
+
+var (
+	_ ModelReporter = (*Client)(nil)
+	_ ModelReporter = (*OpenAICompatibleClient)(nil)
+)
+
+// FallbackModelsFromEnv reads AI_FALLBACK_MODELS, a comma-separated list of
+// models to try in order once the configured model is no longer served
+func FallbackModelsFromEnv() []string {
+	var models []string
+	for _, model := range strings.Split(config.Get("AI_FALLBACK_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// modelChain is the configured model followed by its fallbacks. A model that
+// is reported missing is retired for the life of the process and the next one
+// takes over.
+type modelChain struct {
+	mu      sync.Mutex
+	models  []string
+	active  int
+	retired []RetiredModel
+}
+
+func newModelChain(model string, fallbacks []string) *modelChain {
+	models := []string{model}
+	for _, fallback := range fallbacks {
+		if fallback != model {
+			models = append(models, fallback)
+		}
+	}
+	return &modelChain{models: models}
+}
+
+// current returns the active model
+func (m *modelChain) current() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.models[m.active]
+}
+
+// do calls call with the active model, and again with the next model each
+// time the active one is reported missing, until one works or none is left
+func (m *modelChain) do(call func(model string) error) error {
+	for {
+		model := m.current()
+		err := call(model)
+		if !errors.Is(err, ErrModelNotFound) || !m.retire(model, err) {
+			return err
+		}
+	}
+}
+
+// retire moves past model if it is still the active one and reports whether
+// another model is left to try. Concurrent requests that fail on the same
+// model retire it once.
+func (m *modelChain) retire(model string, err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.models[m.active] != model {
+		return true
+	}
+	if m.active == len(m.models)-1 {
+		return false
+	}
+
+	m.retired = append(m.retired, RetiredModel{Model: model, Error: err.Error(), RetiredAt: time.Now().UTC()})
+	m.active++
+	log.Printf("[AI] Model %s is no longer available, falling back to %s: %v", model, m.models[m.active], err)
+	return true
+}
+
+// status returns the chain as a ModelStatus
+func (m *modelChain) status() ModelStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return ModelStatus{
+		Configured: m.models[0],
+		Active:     m.models[m.active],
+		Fallbacks:  append([]string(nil), m.models[m.active+1:]...),
+		Retired:    append([]RetiredModel(nil), m.retired...),
+	}
+}
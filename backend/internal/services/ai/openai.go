@@ -0,0 +1,299 @@
+package ai
+
+import (
+	"budget-tracker/internal/config"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrBaseURLNotSet = errors.New("OPENAI_BASE_URL environment variable not set")
+	ErrModelNotSet   = errors.New("OPENAI_MODEL environment variable not set")
+)
+
+// OpenAICompatibleClient processes receipts with any server implementing the
+// OpenAI chat completions API (Ollama, vLLM, llama.cpp, ...), so receipts never
+// leave the machine. Such servers cannot read PDFs, so the receipt text is
+// extracted locally with ExtractPDFText and only the text is sent.
+type OpenAICompatibleClient struct {
+	httpClient *http.Client
+	baseURL    string
+	models     *modelChain
+	apiKey     string
+	maxTokens  int
+}
+
+// OpenAICompatibleConfig holds OpenAI-compatible client configuration
+type OpenAICompatibleConfig struct {
+	BaseURL string // e.g. http://localhost:11434/v1 for Ollama
+	Model   string
+	// FallbackModels are tried in order once Model is no longer served
+	FallbackModels []string
+	APIKey         string // optional; most self-hosted servers ignore it
+	MaxTokens      int
+	HTTPClient     *http.Client
+}
+
+// NewOpenAICompatibleClient creates a new OpenAI-compatible client
+func NewOpenAICompatibleClient(cfg OpenAICompatibleConfig) (*OpenAICompatibleClient, error) {
+	if cfg.BaseURL == "" {
+		return nil, ErrBaseURLNotSet
+	}
+	if cfg.Model == "" {
+		return nil, ErrModelNotSet
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &OpenAICompatibleClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		models:     newModelChain(cfg.Model, cfg.FallbackModels),
+		apiKey:     cfg.APIKey,
+		maxTokens:  maxTokens,
+	}, nil
+}
+
+// NewOpenAICompatibleClientFromEnv creates a client from OPENAI_BASE_URL,
+// OPENAI_MODEL and the optional OPENAI_API_KEY and AI_FALLBACK_MODELS
+func NewOpenAICompatibleClientFromEnv() (*OpenAICompatibleClient, error) {
+	return NewOpenAICompatibleClient(OpenAICompatibleConfig{
+		BaseURL:        config.Get("OPENAI_BASE_URL"),
+		Model:          config.Get("OPENAI_MODEL"),
+		FallbackModels: FallbackModelsFromEnv(),
+		APIKey:         config.Get("OPENAI_API_KEY"),
+	})
+}
+
+// ModelStatus returns the model in use and the fallbacks left
+func (c *OpenAICompatibleClient) ModelStatus() ModelStatus {
+	return c.models.status()
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// SendTextPrompt sends a text-only prompt and returns the response
+func (c *OpenAICompatibleClient) SendTextPrompt(ctx context.Context, prompt string) (string, error) {
+	completion, _, err := c.chatCompletion(ctx, prompt, c.maxTokens)
+	if err != nil {
+		return "", err
+	}
+	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("%w: no text in response content", ErrParseResponse)
+	}
+
+	return completion.Choices[0].Message.Content, nil
+}
+
+// chatCompletion sends prompt as a chat completion request and returns the
+// decoded response with its headers; the headers are also returned with a
+// status error when the server answered. A model the server does not serve
+// falls back to the next one.
+func (c *OpenAICompatibleClient) chatCompletion(
+	ctx context.Context,
+	prompt string,
+	maxTokens int,
+) (*chatCompletionResponse, http.Header, error) {
+	var completion *chatCompletionResponse
+	var header http.Header
+	err := c.models.do(func(model string) error {
+		var err error
+		completion, header, err = c.chatCompletionWith(ctx, model, prompt, maxTokens)
+		return err
+	})
+	return completion, header, err
+}
+
+// chatCompletionWith sends one chat completion request to model
+func (c *OpenAICompatibleClient) chatCompletionWith(
+	ctx context.Context,
+	model, prompt string,
+	maxTokens int,
+) (*chatCompletionResponse, http.Header, error) {
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:     model,
+		Messages:  []chatMessage{{Role: "user", Content: prompt}},
+		MaxTokens: maxTokens,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.baseURL+"/chat/completions",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrAPIError, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrAPIError, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.Header, fmt.Errorf("%w: failed to read response: %v", ErrAPIError, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.Header, openAIStatusError(resp.StatusCode, respBody)
+	}
+
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, resp.Header, fmt.Errorf("%w: invalid completion response: %v", ErrParseResponse, err)
+	}
+	return &completion, resp.Header, nil
+}
+
+type modelListResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// CheckCredentials lists the server's models and checks the configured model
+// is among them, or else falls back to the first fallback that is
+func (c *OpenAICompatibleClient) CheckCredentials(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAPIError, err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAPIError, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read response: %v", ErrAPIError, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return openAIStatusError(resp.StatusCode, body)
+	}
+
+	var models modelListResponse
+	if err := json.Unmarshal(body, &models); err != nil {
+		return fmt.Errorf("%w: invalid model list: %v", ErrParseResponse, err)
+	}
+	return c.models.do(func(model string) error {
+		for _, m := range models.Data {
+			// Ollama lists untagged models with their implicit :latest tag
+			if m.ID == model || m.ID == model+":latest" {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %w: %q is not served at %s", ErrAPIError, ErrModelNotFound, model, c.baseURL)
+	})
+}
+
+// openAIStatusError maps an HTTP error status to the package's error types
+func openAIStatusError(status int, body []byte) error {
+	fmt.Printf("OpenAI-compatible API Error: Status=%d Body=%s\n", status, string(body))
+
+	switch status {
+	case 401, 403:
+		return fmt.Errorf("%w: authentication failed - check OPENAI_API_KEY", ErrAPIKeyNotSet)
+	case 402:
+		return fmt.Errorf("%w: %s", ErrQuotaExhausted, string(body))
+	case 404:
+		// A wrong base URL is a 404 too, but its body says nothing about models
+		if bytes.Contains(bytes.ToLower(body), []byte("model")) {
+			return fmt.Errorf("%w: %w: %s", ErrAPIError, ErrModelNotFound, string(body))
+		}
+		return fmt.Errorf("%w: status %d - %s", ErrAPIError, status, string(body))
+	case 429:
+		// OpenAI answers 429 both for rate limits and for an exhausted quota
+		if bytes.Contains(body, []byte("insufficient_quota")) {
+			return fmt.Errorf("%w: %s", ErrQuotaExhausted, string(body))
+		}
+		return ErrRateLimit
+	case 408, 504:
+		return ErrTimeout
+	case 503:
+		return ErrOverloaded
+	default:
+		return fmt.Errorf("%w: status %d - %s", ErrAPIError, status, string(body))
+	}
+}
+
+// ProcessReceiptDocument extracts the receipt text locally and asks the model
+// to structure and categorize it
+func (c *OpenAICompatibleClient) ProcessReceiptDocument(
+	ctx context.Context,
+	base64Data, mimeType string,
+	budgets []string,
+) (*ReceiptProcessingResult, error) {
+	if mimeType != "application/pdf" {
+		return nil, fmt.Errorf("%w: unsupported mime type: %s (only application/pdf is supported)", ErrInvalidDocument, mimeType)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid base64 data: %v", ErrInvalidDocument, err)
+	}
+
+	text, err := ExtractPDFText(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	prompt := ReceiptProcessingPrompt(budgets) +
+		"\n\n=== RECEIPT TEXT ===\nThe receipt text below was extracted from the PDF, one printed line per line.\n\n" +
+		text
+
+	responseText, err := c.SendTextPrompt(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("receipt processing failed: %w", err)
+	}
+
+	return parseReceiptResultWithRepair(ctx, c, responseText)
+}
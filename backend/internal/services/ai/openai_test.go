@@ -0,0 +1,249 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAICompatibleClient_ProcessReceiptDocument(t *testing.T) {
+	var received chatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Expected no Authorization header without an API key, got %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{
+				"message": map[string]string{
+					"role": "assistant",
+					"content": "```json\n" + `{"source":"Publix","item_count":1,"total":1.99,"tax":0,` +
+						`"items":[{"item_code":"ORG BANAN","item_price":1.99,"item_name":"Organic Bananas","item_type":"weekly"}]}` +
+						"\n```",
+				},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAICompatibleClient(OpenAICompatibleConfig{
+		BaseURL: server.URL + "/v1/",
+		Model:   "llama3.1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	pdf := base64.StdEncoding.EncodeToString(buildTestPDF(t, true, testReceiptContent))
+	result, err := client.ProcessReceiptDocument(
+		context.Background(), pdf, "application/pdf", []string{"Bananas (weekly)"},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if received.Model != "llama3.1" || len(received.Messages) != 1 {
+		t.Fatalf("Unexpected request: %+v", received)
+	}
+	prompt := received.Messages[0].Content
+	if !strings.Contains(prompt, "ORG BANAN (LB)") || !strings.Contains(prompt, "Bananas (weekly)") {
+		t.Errorf("Expected prompt to contain the extracted text and budgets, got:\n%s", prompt)
+	}
+
+	if result.Source != "Publix" || len(result.Items) != 1 || result.Items[0].ItemType != "weekly" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestOpenAICompatibleClient_StatusErrors(t *testing.T) {
+	testCases := []struct {
+		status   int
+		expected error
+	}{
+		{http.StatusTooManyRequests, ErrRateLimit},
+		{http.StatusGatewayTimeout, ErrTimeout},
+		{http.StatusServiceUnavailable, ErrOverloaded},
+		{http.StatusUnauthorized, ErrAPIKeyNotSet},
+		{http.StatusInternalServerError, ErrAPIError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					t.Errorf("Expected bearer API key, got %q", r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client, _ := NewOpenAICompatibleClient(OpenAICompatibleConfig{
+				BaseURL: server.URL, Model: "m", APIKey: "secret",
+			})
+			if _, err := client.SendTextPrompt(context.Background(), "hi"); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleClient_ScannedPDF(t *testing.T) {
+	client, _ := NewOpenAICompatibleClient(OpenAICompatibleConfig{BaseURL: "http://unused", Model: "m"})
+
+	_, err := client.ProcessReceiptDocument(
+		context.Background(),
+		base64.StdEncoding.EncodeToString(validPDFData),
+		"application/pdf",
+		nil,
+	)
+	if !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("Expected ErrInvalidDocument for a PDF without text, got %v", err)
+	}
+}
+
+func TestNewOpenAICompatibleClient_RequiresConfig(t *testing.T) {
+	if _, err := NewOpenAICompatibleClient(OpenAICompatibleConfig{Model: "m"}); err != ErrBaseURLNotSet {
+		t.Errorf("Expected ErrBaseURLNotSet, got %v", err)
+	}
+	if _, err := NewOpenAICompatibleClient(OpenAICompatibleConfig{BaseURL: "http://x"}); err != ErrModelNotSet {
+		t.Errorf("Expected ErrModelNotSet, got %v", err)
+	}
+}
+
+func TestNewProviderFromEnv(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "OpenAI")
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:11434/v1")
+	t.Setenv("OPENAI_MODEL", "llama3.1")
+
+	provider, err := NewProviderFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := provider.(*OpenAICompatibleClient); !ok {
+		t.Errorf("Expected OpenAI-compatible provider, got %T", provider)
+	}
+
+	t.Setenv("AI_PROVIDER", "bard")
+	if provider, err := NewProviderFromEnv(); err == nil || provider != nil {
+		t.Errorf("Expected error and nil provider for unknown AI_PROVIDER, got %v, %v", provider, err)
+	}
+}
+
+func TestOpenAICompatibleClient_CheckCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]string{{"id": "llama3:latest"}, {"id": "qwen2.5"}},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		model     string
+		fallbacks []string
+		apiKey    string
+		wantErr   error
+	}{
+		{"served model", "qwen2.5", nil, "good", nil},
+		{"implicit latest tag", "llama3", nil, "good", nil},
+		{"unknown model", "mistral", nil, "good", ErrAPIError},
+		{"served fallback", "mistral", []string{"qwen2.5"}, "good", nil},
+		{"rejected key", "qwen2.5", nil, "bad", ErrAPIKeyNotSet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewOpenAICompatibleClient(OpenAICompatibleConfig{
+				BaseURL:        server.URL + "/v1/",
+				Model:          tt.model,
+				FallbackModels: tt.fallbacks,
+				APIKey:         tt.apiKey,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			err = client.CheckCredentials(context.Background())
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleClient_ModelFallback(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requested = append(requested, req.Model)
+		if req.Model != "qwen2.5" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model \"` + req.Model + `\" not found, try pulling it first"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": "OK"}}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAICompatibleClient(OpenAICompatibleConfig{
+		BaseURL:        server.URL,
+		Model:          "llama3.1",
+		FallbackModels: []string{"mistral", "qwen2.5"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.SendTextPrompt(context.Background(), "hi"); err != nil {
+		t.Fatalf("Expected a fallback to answer, got %v", err)
+	}
+	if strings.Join(requested, ",") != "llama3.1,mistral,qwen2.5" {
+		t.Errorf("Expected the models to be tried in order, got %v", requested)
+	}
+
+	// Retired models are not tried again
+	requested = nil
+	if _, err := client.SendTextPrompt(context.Background(), "hi"); err != nil || len(requested) != 1 {
+		t.Errorf("Expected one request to the fallback, got %v, %v", requested, err)
+	}
+
+	status := client.ModelStatus()
+	if status.Configured != "llama3.1" || status.Active != "qwen2.5" || len(status.Fallbacks) != 0 {
+		t.Errorf("Unexpected model status: %+v", status)
+	}
+	if len(status.Retired) != 2 || status.Retired[0].Model != "llama3.1" || status.Retired[1].Model != "mistral" {
+		t.Errorf("Expected both models to be retired, got %+v", status.Retired)
+	}
+
+	// Without fallbacks left the error is returned
+	client, _ = NewOpenAICompatibleClient(OpenAICompatibleConfig{BaseURL: server.URL, Model: "mistral"})
+	_, err = client.SendTextPrompt(context.Background(), "hi")
+	if !errors.Is(err, ErrModelNotFound) || !errors.Is(err, ErrAPIError) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+	if status := client.ModelStatus(); status.Active != "mistral" || len(status.Retired) != 0 {
+		t.Errorf("Expected the only model to stay active, got %+v", status)
+	}
+}
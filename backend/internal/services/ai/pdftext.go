@@ -0,0 +1,283 @@
+package ai
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrNoExtractableText is returned when a PDF has no text layer (e.g. a scanned receipt)
+var ErrNoExtractableText = errors.New("PDF has no extractable text")
+
+// streamPattern matches a stream object's dictionary and the start of its data
+var streamPattern = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// ExtractPDFText is the local parser: it pulls the text shown by Tj, TJ, ' and "
+// operators out of a PDF's content streams, one line per text line.
+//
+// It handles uncompressed and FlateDecode streams with simple font encodings,
+// which covers receipts printed to PDF by most POS systems and browsers.
+// PDFs that only contain images (scans) or use CID-keyed fonts yield
+// ErrNoExtractableText and need a provider that reads the document itself.
+func ExtractPDFText(data []byte) (string, error) {
+	if len(data) < 4 || string(data[:4]) != "%PDF" {
+		return "", ErrUnsupportedFormat
+	}
+
+	var lines []string
+	for _, loc := range streamPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		content := bytes.TrimRight(data[start:start+end], "\r\n")
+
+		if strings.Contains(dict, "/FlateDecode") {
+			r, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			decoded, err := io.ReadAll(r)
+			r.Close()
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			content = decoded
+		} else if strings.Contains(dict, "/Filter") {
+			// Other filters (images, DCT, ...) never carry text we can read
+			continue
+		}
+
+		lines = append(lines, contentStreamText(content)...)
+	}
+
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return "", ErrNoExtractableText
+	}
+	return text, nil
+}
+
+// contentStreamText tokenizes a content stream and collects shown text into lines
+func contentStreamText(content []byte) []string {
+	var (
+		lines    []string
+		line     strings.Builder
+		operands []string
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(line.String()); s != "" {
+			lines = append(lines, s)
+		}
+		line.Reset()
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := readLiteralString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, n := readHexString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '[':
+			// TJ arrays: keep the strings, treat large negative kerning as a space
+			j := i + 1
+			var sb strings.Builder
+			for j < len(content) && content[j] != ']' {
+				switch {
+				case content[j] == '(':
+					s, n := readLiteralString(content[j:])
+					sb.WriteString(s)
+					j += n
+				case content[j] == '<':
+					s, n := readHexString(content[j:])
+					sb.WriteString(s)
+					j += n
+				case content[j] == '-' || (content[j] >= '0' && content[j] <= '9'):
+					k := j
+					for k < len(content) && strings.IndexByte("-.0123456789", content[k]) >= 0 {
+						k++
+					}
+					if content[j] == '-' && k-j > 3 {
+						sb.WriteByte(' ')
+					}
+					j = k
+				default:
+					j++
+				}
+			}
+			operands = append(operands, sb.String())
+			i = j + 1
+		case isPDFWhitespace(c):
+			i++
+		case c == '/':
+			// Name operand, e.g. the font in "/F1 12 Tf"
+			j := i + 1
+			for j < len(content) && !isPDFWhitespace(content[j]) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			i = j
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		default:
+			j := i
+			for j < len(content) && !isPDFWhitespace(content[j]) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			token := string(content[i:j])
+			i = j
+
+			switch token {
+			case "Tj", "TJ":
+				if len(operands) > 0 {
+					line.WriteString(operands[len(operands)-1])
+				}
+			case "'", `"`:
+				flush()
+				if len(operands) > 0 {
+					line.WriteString(operands[len(operands)-1])
+				}
+			case "Td", "TD", "T*", "Tm", "ET":
+				flush()
+			default:
+				// Numbers and names are operands; only strings matter here
+				if !isOperator(token) {
+					continue
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	flush()
+
+	return lines
+}
+
+// readLiteralString decodes a (...) string, returning it and the bytes consumed
+func readLiteralString(b []byte) (string, int) {
+	var sb strings.Builder
+	depth := 0
+	i := 0
+	for ; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '\\' && i+1 < len(b):
+			i++
+			switch e := b[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					k := 0
+					for ; k < 3 && i+k < len(b) && b[i+k] >= '0' && b[i+k] <= '7'; k++ {
+						v = v*8 + int(b[i+k]-'0')
+					}
+					i += k - 1
+					sb.WriteByte(byte(v))
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				sb.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return sb.String(), i + 1
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), i
+}
+
+// readHexString decodes a <...> string, returning it and the bytes consumed.
+// Non-printable results (typically CID glyph ids) are dropped.
+func readHexString(b []byte) (string, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		return "", len(b)
+	}
+
+	var digits []byte
+	for _, c := range b[1:end] {
+		if !isPDFWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		hi, ok1 := hexValue(digits[i])
+		lo, ok2 := hexValue(digits[i+1])
+		if !ok1 || !ok2 {
+			return "", end + 1
+		}
+		v := hi<<4 | lo
+		if v < 0x20 || v > 0x7e {
+			return "", end + 1
+		}
+		out = append(out, v)
+	}
+	return string(out), end + 1
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// isOperator reports whether token is an operator rather than a number operand
+func isOperator(token string) bool {
+	if token == "" {
+		return false
+	}
+	c := token[0]
+	return !(c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'))
+}
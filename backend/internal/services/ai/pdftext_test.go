@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// buildTestPDF wraps content streams in a minimal PDF; compressed streams use FlateDecode
+func buildTestPDF(t *testing.T, compress bool, streams ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	for i, content := range streams {
+		data := []byte(content)
+		dict := fmt.Sprintf("<< /Length %d >>", len(data))
+		if compress {
+			var z bytes.Buffer
+			w := zlib.NewWriter(&z)
+			w.Write(data)
+			w.Close()
+			data = z.Bytes()
+			dict = fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(data))
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nstream\n", i+2, dict)
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+	buf.WriteString("%%EOF")
+	return buf.Bytes()
+}
+
+const testReceiptContent = `BT
+/F1 10 Tf
+72 720 Td
+(PUBLIX SUPER MARKETS) Tj
+0 -12 Td
+[(MLK 2) -20 (%)] TJ
+0 -12 Td
+(ORG BANAN \(LB\)   1.99) Tj
+T*
+<5441582020302E3330> Tj
+ET`
+
+func TestExtractPDFText(t *testing.T) {
+	expected := "PUBLIX SUPER MARKETS\nMLK 2%\nORG BANAN (LB)   1.99\nTAX  0.30"
+
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compressed=%v", compress), func(t *testing.T) {
+			text, err := ExtractPDFText(buildTestPDF(t, compress, testReceiptContent))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if text != expected {
+				t.Errorf("Unexpected text:\n got: %q\nwant: %q", text, expected)
+			}
+		})
+	}
+}
+
+func TestExtractPDFText_KerningSpaces(t *testing.T) {
+	text, err := ExtractPDFText(buildTestPDF(t, false, "BT [(EGGS) -400 (4.99)] TJ ET"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text != "EGGS 4.99" {
+		t.Errorf("Expected wide kerning to become a space, got %q", text)
+	}
+}
+
+func TestExtractPDFText_NoText(t *testing.T) {
+	if _, err := ExtractPDFText(validPDFData); !errors.Is(err, ErrNoExtractableText) {
+		t.Errorf("Expected ErrNoExtractableText, got %v", err)
+	}
+}
+
+func TestExtractPDFText_RejectsNonPDF(t *testing.T) {
+	if _, err := ExtractPDFText(pngData); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
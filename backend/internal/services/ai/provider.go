@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"budget-tracker/internal/config"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ReceiptProvider extracts and categorizes the items of a PDF receipt
+type ReceiptProvider interface {
+	ProcessReceiptDocument(
+		ctx context.Context,
+		base64Data, mimeType string,
+		budgets []string,
+	) (*ReceiptProcessingResult, error)
+}
+
+// CredentialChecker is implemented by providers that can verify their
+// credentials and model with a cheap request, without processing a document
+type CredentialChecker interface {
+	CheckCredentials(ctx context.Context) error
+}
+
+var (
+	_ ReceiptProvider   = (*Client)(nil)
+	_ ReceiptProvider   = (*OpenAICompatibleClient)(nil)
+	_ CredentialChecker = (*Client)(nil)
+	_ CredentialChecker = (*OpenAICompatibleClient)(nil)
+)
+
+// Provider names accepted by AI_PROVIDER
+const (
+	ProviderAnthropic        = "anthropic"
+	ProviderOpenAICompatible = "openai"
+)
+
+// NewProviderFromEnv creates the receipt provider selected by AI_PROVIDER
+// (anthropic by default, or openai for any OpenAI-compatible server)
+func NewProviderFromEnv() (ReceiptProvider, error) {
+	// Return untyped nil on error so callers can compare the provider to nil
+	switch provider := strings.ToLower(config.Get("AI_PROVIDER")); provider {
+	case "", ProviderAnthropic:
+		client, err := NewClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderOpenAICompatible:
+		client, err := NewOpenAICompatibleClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown AI_PROVIDER %q (must be anthropic or openai)", provider)
+	}
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// quotaProbePrompt is the prompt of the quota probes, answered with one token
+const quotaProbePrompt = "Reply with OK."
+
+// Quota is the remaining allowance the provider reported in the rate limit
+// headers of its last response. Fields the provider did not report are nil;
+// self-hosted servers usually report none.
+type Quota struct {
+	RequestsLimit     *int64 `json:"requests_limit,omitempty"`
+	RequestsRemaining *int64 `json:"requests_remaining,omitempty"`
+	TokensLimit       *int64 `json:"tokens_limit,omitempty"`
+	TokensRemaining   *int64 `json:"tokens_remaining,omitempty"`
+}
+
+// QuotaChecker is implemented by providers that can check, with a minimal
+// request, that they still accept work. Unlike CheckCredentials this spends
+// a few tokens, so it also fails once the account is out of credit.
+type QuotaChecker interface {
+	CheckQuota(ctx context.Context) (*Quota, error)
+}
+
+var (
+	_ QuotaChecker = (*Client)(nil)
+	_ QuotaChecker = (*OpenAICompatibleClient)(nil)
+)
+
+// quotaHeaders names the rate limit headers of a provider
+type quotaHeaders struct {
+	requestsLimit, requestsRemaining, tokensLimit, tokensRemaining string
+}
+
+var (
+	anthropicQuotaHeaders = quotaHeaders{
+		requestsLimit:     "anthropic-ratelimit-requests-limit",
+		requestsRemaining: "anthropic-ratelimit-requests-remaining",
+		tokensLimit:       "anthropic-ratelimit-tokens-limit",
+		tokensRemaining:   "anthropic-ratelimit-tokens-remaining",
+	}
+	openAIQuotaHeaders = quotaHeaders{
+		requestsLimit:     "x-ratelimit-limit-requests",
+		requestsRemaining: "x-ratelimit-remaining-requests",
+		tokensLimit:       "x-ratelimit-limit-tokens",
+		tokensRemaining:   "x-ratelimit-remaining-tokens",
+	}
+)
+
+// parse reads the quota from header, or returns nil when none is reported
+func (q quotaHeaders) parse(header http.Header) *Quota {
+	value := func(name string) *int64 {
+		n, err := strconv.ParseInt(header.Get(name), 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &n
+	}
+	quota := &Quota{
+		RequestsLimit:     value(q.requestsLimit),
+		RequestsRemaining: value(q.requestsRemaining),
+		TokensLimit:       value(q.tokensLimit),
+		TokensRemaining:   value(q.tokensRemaining),
+	}
+	if *quota == (Quota{}) {
+		return nil
+	}
+	return quota
+}
+
+// CheckQuota sends a one token request and returns the rate limits of the
+// response. It is not retried, so a failure is reported as it happened.
+func (c *Client) CheckQuota(ctx context.Context) (*Quota, error) {
+	var resp *http.Response
+	_, err := c.newMessage(ctx, anthropic.MessageNewParams{
+		MaxTokens: 1,
+		Messages: []anthropic.MessageParam{
+			{
+				Role: anthropic.MessageParamRoleUser,
+				Content: []anthropic.ContentBlockParamUnion{
+					anthropic.NewTextBlock(quotaProbePrompt),
+				},
+			},
+		},
+	}, option.WithResponseInto(&resp), option.WithMaxRetries(0))
+
+	var quota *Quota
+	if resp != nil {
+		quota = anthropicQuotaHeaders.parse(resp.Header)
+	}
+	return quota, err
+}
+
+// CheckQuota sends a one token chat completion and returns the rate limits
+// of the response
+func (c *OpenAICompatibleClient) CheckQuota(ctx context.Context) (*Quota, error) {
+	_, header, err := c.chatCompletion(ctx, quotaProbePrompt, 1)
+	return openAIQuotaHeaders.parse(header), err
+}
@@ -0,0 +1,264 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validItemTypes are the item_type values ReceiptProcessingPrompt asks for
+var validItemTypes = map[string]bool{"weekly": true, "monthly": true, "misc": true, "tax": true}
+
+// RawResponseError is returned when the model answered but its output could
+// not be used; it keeps the answers for debugging
+type RawResponseError struct {
+	Err          error
+	RawResponses []string
+}
+
+func (e *RawResponseError) Error() string { return e.Err.Error() }
+
+func (e *RawResponseError) Unwrap() error { return e.Err }
+
+// RawResponses returns the model answers carried by err, if any
+func RawResponses(err error) []string {
+	var rawErr *RawResponseError
+	if errors.As(err, &rawErr) {
+		return rawErr.RawResponses
+	}
+	return nil
+}
+
+// textPrompter is implemented by every provider; used for the repair pass
+type textPrompter interface {
+	SendTextPrompt(ctx context.Context, prompt string) (string, error)
+}
+
+// parseReceiptResultWithRepair decodes a model's answer to ReceiptProcessingPrompt.
+// If the answer is not valid JSON or does not match the expected schema, the
+// model is sent one repair prompt listing the problems; ErrParseResponse is
+// returned if the repaired answer is still invalid.
+func parseReceiptResultWithRepair(
+	ctx context.Context,
+	p textPrompter,
+	responseText string,
+) (*ReceiptProcessingResult, error) {
+	result, problems := decodeReceiptResult(responseText)
+	if len(problems) == 0 {
+		result.RawResponses = []string{responseText}
+		return result, nil
+	}
+
+	fmt.Printf("[AI] Response failed validation (%s), attempting repair\n", strings.Join(problems, "; "))
+
+	repaired, err := p.SendTextPrompt(ctx, RepairPrompt(responseText, problems))
+	if err != nil {
+		return nil, &RawResponseError{
+			Err: fmt.Errorf(
+				"%w: %s (repair request failed: %v)\nResponse was: %s",
+				ErrParseResponse,
+				strings.Join(problems, "; "),
+				err,
+				responseText,
+			),
+			RawResponses: []string{responseText},
+		}
+	}
+
+	result, repairProblems := decodeReceiptResult(repaired)
+	if len(repairProblems) > 0 {
+		return nil, &RawResponseError{
+			Err: fmt.Errorf(
+				"%w: %s (after repair)\nResponse was: %s",
+				ErrParseResponse,
+				strings.Join(repairProblems, "; "),
+				repaired,
+			),
+			RawResponses: []string{responseText, repaired},
+		}
+	}
+
+	result.RawResponses = []string{responseText, repaired}
+	return result, nil
+}
+
+// decodeReceiptResult validates responseText against the receipt schema and
+// decodes it. It returns the list of problems found instead of the result
+// when validation fails.
+func decodeReceiptResult(responseText string) (*ReceiptProcessingResult, []string) {
+	// Strip any markdown code block formatting from the response
+	responseText = stripMarkdownCodeBlock(responseText)
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(responseText), &raw); err != nil {
+		return nil, []string{fmt.Sprintf("response is not a JSON object: %v", err)}
+	}
+
+	var problems []string
+	problemf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	checkOptional := func(obj map[string]any, prefix, field, kind string) {
+		if v, ok := obj[field]; ok && v != nil && jsonKind(v) != kind {
+			problemf("%s%s: expected %s, got %s", prefix, field, kind, jsonKind(v))
+		}
+	}
+	checkRequired := func(obj map[string]any, prefix, field, kind string) {
+		v, ok := obj[field]
+		if !ok || v == nil {
+			problemf("%s%s: missing", prefix, field)
+			return
+		}
+		if jsonKind(v) != kind {
+			problemf("%s%s: expected %s, got %s", prefix, field, kind, jsonKind(v))
+		}
+	}
+
+	checkReceipt := func(obj map[string]any, prefix string) {
+		checkOptional(obj, prefix, "source", "string")
+		checkOptional(obj, prefix, "total", "number")
+		checkOptional(obj, prefix, "tax", "number")
+		checkOptional(obj, prefix, "item_count", "number")
+
+		items, ok := obj["items"].([]any)
+		switch {
+		case obj["items"] == nil:
+			problemf("%sitems: missing", prefix)
+		case !ok:
+			problemf("%sitems: expected array, got %s", prefix, jsonKind(obj["items"]))
+		}
+
+		for i, v := range items {
+			prefix := fmt.Sprintf("%sitems[%d].", prefix, i)
+			item, ok := v.(map[string]any)
+			if !ok {
+				problemf("%s: expected object, got %s", strings.TrimSuffix(prefix, "."), jsonKind(v))
+				continue
+			}
+
+			checkRequired(item, prefix, "item_code", "string")
+			checkRequired(item, prefix, "item_price", "number")
+			checkRequired(item, prefix, "item_name", "string")
+			checkRequired(item, prefix, "item_type", "string")
+
+			if name, ok := item["item_name"].(string); ok && strings.TrimSpace(name) == "" {
+				problemf("%sitem_name: must not be empty", prefix)
+			}
+			if itemType, ok := item["item_type"].(string); ok &&
+				!validItemTypes[strings.ToLower(strings.TrimSpace(itemType))] {
+				problemf("%sitem_type: %q is not one of %s", prefix, itemType, itemTypeList())
+			}
+		}
+	}
+
+	// A document with several receipts is answered with one object each
+	if v, ok := raw["receipts"]; ok {
+		receipts, ok := v.([]any)
+		switch {
+		case !ok:
+			problemf("receipts: expected array, got %s", jsonKind(v))
+		case len(receipts) == 0:
+			problemf("receipts: must not be empty")
+		}
+		for i, v := range receipts {
+			receipt, ok := v.(map[string]any)
+			if !ok {
+				problemf("receipts[%d]: expected object, got %s", i, jsonKind(v))
+				continue
+			}
+			checkReceipt(receipt, fmt.Sprintf("receipts[%d].", i))
+		}
+	} else {
+		checkReceipt(raw, "")
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	var result ReceiptProcessingResult
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		return nil, []string{fmt.Sprintf("response does not match the schema: %v", err)}
+	}
+
+	return mergeReceipts(&result), nil
+}
+
+// mergeReceipts fills the fields of a result with several receipts from
+// them: the items in document order, the sums and the first store. A single
+// receipt in Receipts is returned as the result itself.
+func mergeReceipts(result *ReceiptProcessingResult) *ReceiptProcessingResult {
+	switch len(result.Receipts) {
+	case 0:
+		return result
+	case 1:
+		return &result.Receipts[0]
+	}
+
+	merged := &ReceiptProcessingResult{
+		Source:   result.Receipts[0].Source,
+		Items:    []CategorizedItem{},
+		Receipts: result.Receipts,
+	}
+	for _, receipt := range result.Receipts {
+		merged.Items = append(merged.Items, receipt.Items...)
+		merged.Total += receipt.Total
+		merged.Tax += receipt.Tax
+		merged.ItemCount += receipt.ItemCount
+	}
+	return merged
+}
+
+// jsonKind names the JSON type of a value decoded into any
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func itemTypeList() string {
+	types := make([]string, 0, len(validItemTypes))
+	for t := range validItemTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// RepairPrompt asks the model to fix an answer that failed schema validation
+func RepairPrompt(brokenOutput string, problems []string) string {
+	return fmt.Sprintf(
+		`Your previous answer could not be used because it does not match the required JSON format.
+
+=== VALIDATION ERRORS ===
+- %s
+
+=== PREVIOUS ANSWER ===
+%s
+
+=== TASK ===
+Return the corrected JSON object. Keep every item and value from the previous answer; only fix the errors listed above.
+Required format: {"source": string, "item_count": number, "total": number, "tax": number, "items": [{"item_code": string, "item_price": number, "item_name": string, "item_type": "weekly"|"monthly"|"misc"|"tax"}]}
+If the previous answer had several receipts, keep them as {"receipts": [...]} with one object in the required format each.
+Return ONLY the raw JSON object, with NO markdown formatting, code blocks or explanatory text.`,
+		strings.Join(problems, "\n- "),
+		brokenOutput,
+	)
+}
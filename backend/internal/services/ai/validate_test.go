@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validReceiptJSON = `{"source":"Publix","item_count":1,"total":4.29,"tax":0.3,` +
+	`"items":[{"item_code":"MLK 2%","item_price":3.99,"item_name":"2% Milk","item_type":"weekly"},` +
+	`{"item_code":"TAX","item_price":0.3,"item_name":"Tax","item_type":"TAX"}]}`
+
+func TestDecodeReceiptResult_Valid(t *testing.T) {
+	result, problems := decodeReceiptResult("```json\n" + validReceiptJSON + "\n```")
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+	if len(result.Items) != 2 || result.Items[0].ItemPrice != 3.99 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestDecodeReceiptResult_SeveralReceipts(t *testing.T) {
+	other := `{"source":"Target","item_count":1,"total":10,"tax":0,` +
+		`"items":[{"item_code":"TOWEL","item_price":10,"item_name":"Towel","item_type":"misc"}]}`
+
+	result, problems := decodeReceiptResult(`{"receipts":[` + validReceiptJSON + `,` + other + `]}`)
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+	if len(result.Receipts) != 2 || result.Receipts[1].Source != "Target" {
+		t.Fatalf("Expected both receipts, got %+v", result.Receipts)
+	}
+	// The result adds the receipts up for callers expecting one
+	if result.Source != "Publix" || len(result.Items) != 3 || result.Items[2].ItemCode != "TOWEL" {
+		t.Errorf("Expected the items of both receipts, got %+v", result)
+	}
+	if result.ItemCount != 2 || result.Total != 14.29 || result.Tax != 0.3 {
+		t.Errorf("Expected summed totals, got count %d, total %v, tax %v", result.ItemCount, result.Total, result.Tax)
+	}
+
+	single, problems := decodeReceiptResult(`{"receipts":[` + other + `]}`)
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+	if single.Source != "Target" || len(single.Receipts) != 0 || len(single.Items) != 1 {
+		t.Errorf("Expected a single receipt to be returned as is, got %+v", single)
+	}
+}
+
+func TestDecodeReceiptResult_Problems(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response string
+		expected string
+	}{
+		{"not json", "Sure! Here are the items", "not a JSON object"},
+		{"missing items", `{"source":"Publix"}`, "items: missing"},
+		{"items not array", `{"items":{}}`, "items: expected array, got object"},
+		{"price as string", `{"items":[{"item_code":"A","item_price":"1.99","item_name":"A","item_type":"misc"}]}`,
+			"items[0].item_price: expected number, got string"},
+		{"missing item_code", `{"items":[{"item_price":1,"item_name":"A","item_type":"misc"}]}`,
+			"items[0].item_code: missing"},
+		{"unknown type", `{"items":[{"item_code":"A","item_price":1,"item_name":"A","item_type":"food"}]}`,
+			`items[0].item_type: "food" is not one of misc, monthly, tax, weekly`},
+		{"total as string", `{"total":"4.00","items":[]}`, "total: expected number, got string"},
+		{"receipts not array", `{"receipts":{}}`, "receipts: expected array, got object"},
+		{"no receipts", `{"receipts":[]}`, "receipts: must not be empty"},
+		{"receipt without items", `{"receipts":[{"source":"Publix"}]}`, "receipts[0].items: missing"},
+		{"receipt item price as string",
+			`{"receipts":[{"items":[]},{"items":[{"item_code":"A","item_price":"1","item_name":"A","item_type":"misc"}]}]}`,
+			"receipts[1].items[0].item_price: expected number, got string"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, problems := decodeReceiptResult(tc.response)
+			if result != nil {
+				t.Errorf("Expected no result, got %+v", result)
+			}
+			if !strings.Contains(strings.Join(problems, "; "), tc.expected) {
+				t.Errorf("Expected problem %q, got %v", tc.expected, problems)
+			}
+		})
+	}
+}
+
+// fakePrompter returns canned responses and records the prompts it receives
+type fakePrompter struct {
+	responses []string
+	err       error
+	prompts   []string
+}
+
+func (f *fakePrompter) SendTextPrompt(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return "", f.err
+	}
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	return response, nil
+}
+
+func TestParseReceiptResultWithRepair(t *testing.T) {
+	broken := `{"items":[{"item_code":"MLK","item_price":"3.99","item_name":"Milk","item_type":"weekly"}]}`
+
+	t.Run("valid response needs no repair", func(t *testing.T) {
+		p := &fakePrompter{}
+		if _, err := parseReceiptResultWithRepair(context.Background(), p, validReceiptJSON); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(p.prompts) != 0 {
+			t.Errorf("Expected no repair prompt, got %d", len(p.prompts))
+		}
+	})
+
+	t.Run("repair succeeds", func(t *testing.T) {
+		p := &fakePrompter{responses: []string{validReceiptJSON}}
+		result, err := parseReceiptResultWithRepair(context.Background(), p, broken)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Items) != 2 {
+			t.Errorf("Expected repaired result, got %+v", result)
+		}
+		if !reflect.DeepEqual(result.RawResponses, []string{broken, validReceiptJSON}) {
+			t.Errorf("Expected both raw responses, got %v", result.RawResponses)
+		}
+		if len(p.prompts) != 1 ||
+			!strings.Contains(p.prompts[0], "items[0].item_price: expected number, got string") ||
+			!strings.Contains(p.prompts[0], broken) {
+			t.Errorf("Expected repair prompt with errors and broken output, got %v", p.prompts)
+		}
+	})
+
+	t.Run("repair retried only once", func(t *testing.T) {
+		p := &fakePrompter{responses: []string{broken, validReceiptJSON}}
+		_, err := parseReceiptResultWithRepair(context.Background(), p, broken)
+		if !errors.Is(err, ErrParseResponse) {
+			t.Errorf("Expected ErrParseResponse, got %v", err)
+		}
+		if len(p.prompts) != 1 {
+			t.Errorf("Expected exactly one repair attempt, got %d", len(p.prompts))
+		}
+		if raw := RawResponses(err); len(raw) != 2 {
+			t.Errorf("Expected the original and repaired responses on the error, got %v", raw)
+		}
+	})
+
+	t.Run("repair request fails", func(t *testing.T) {
+		p := &fakePrompter{err: ErrTimeout}
+		_, err := parseReceiptResultWithRepair(context.Background(), p, broken)
+		if !errors.Is(err, ErrParseResponse) {
+			t.Errorf("Expected ErrParseResponse, got %v", err)
+		}
+		if raw := RawResponses(err); !reflect.DeepEqual(raw, []string{broken}) {
+			t.Errorf("Expected the original response on the error, got %v", raw)
+		}
+	})
+}
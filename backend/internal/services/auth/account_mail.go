@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"budget-tracker/internal/config"
+	"budget-tracker/internal/services/mail"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Lifetimes of the single use tokens sent by email
+const (
+	VerifyEmailTTL   = 24 * time.Hour
+	PasswordResetTTL = time.Hour
+)
+
+// AccountMailer sends the email verification and password reset emails
+type AccountMailer struct {
+	mailer mail.Mailer
+	// appURL is the address of the web app, e.g. https://budget.example.com.
+	// The emails link to its /verify-email and /reset-password pages; without
+	// it they contain the bare token.
+	appURL string
+}
+
+// NewAccountMailer creates an AccountMailer sending through mailer
+func NewAccountMailer(mailer mail.Mailer, appURL string) *AccountMailer {
+	return &AccountMailer{mailer: mailer, appURL: strings.TrimRight(appURL, "/")}
+}
+
+// NewAccountMailerFromEnv creates an AccountMailer sending through the SMTP
+// server configured by the SMTP_* variables, linking to APP_URL. It returns
+// mail.ErrNotConfigured when SMTP_HOST is not set.
+func NewAccountMailerFromEnv() (*AccountMailer, error) {
+	mailer, err := mail.NewSMTPMailerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	appURL := config.Get("APP_URL")
+	if appURL != "" {
+		if u, err := url.Parse(appURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid APP_URL %q", appURL)
+		}
+	}
+	return NewAccountMailer(mailer, appURL), nil
+}
+
+// ForTenant returns a copy of the mailer linking to the subdomain of tenant
+// under the app URL, e.g. https://smith.budget.example.com
+func (a *AccountMailer) ForTenant(tenant string) *AccountMailer {
+	if a == nil {
+		return nil
+	}
+	scoped := *a
+	if u, err := url.Parse(a.appURL); err == nil && u.Host != "" {
+		u.Host = tenant + "." + u.Host
+		scoped.appURL = u.String()
+	}
+	return &scoped
+}
+
+// SendVerification mails the token confirming the address to email
+func (a *AccountMailer) SendVerification(ctx context.Context, email, token string) error {
+	return a.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: "Confirm the email address of your budget account with the link below.\n\n" +
+			a.action("/verify-email", token) +
+			fmt.Sprintf("\n\nThe link expires in %d hours. If you did not create an account, ignore this email.\n",
+				int(VerifyEmailTTL.Hours())),
+	})
+}
+
+// SendPasswordReset mails the token that sets a new password to email
+func (a *AccountMailer) SendPasswordReset(ctx context.Context, email, token string) error {
+	return a.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: "Choose a new password for your budget account with the link below.\n\n" +
+			a.action("/reset-password", token) +
+			fmt.Sprintf("\n\nThe link expires in %d minutes and signs you out on every device. "+
+				"If you did not ask to reset your password, ignore this email.\n",
+				int(PasswordResetTTL.Minutes())),
+	})
+}
+
+// action returns the link to page carrying token, or the token itself when
+// the app URL is not configured
+func (a *AccountMailer) action(page, token string) string {
+	if a.appURL == "" {
+		return "Token: " + token
+	}
+	return a.appURL + page + "?" + url.Values{"token": {token}}.Encode()
+}
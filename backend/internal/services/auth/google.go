@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"budget-tracker/internal/config"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGoogleNotConfigured   = errors.New("GOOGLE_CLIENT_ID environment variable is not set")
+	ErrGoogleEmailUnverified = errors.New("Google account email is not verified")
+)
+
+// Google OAuth 2.0 endpoints
+const (
+	GoogleAuthURL     = "https://accounts.google.com/o/oauth2/v2/auth"
+	GoogleTokenURL    = "https://oauth2.googleapis.com/token"
+	GoogleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+)
+
+// googleTimeout bounds each request to Google
+const googleTimeout = 10 * time.Second
+
+// GoogleConfig configures sign-in with Google
+type GoogleConfig struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback registered with Google,
+	// e.g. https://budget.example.com/api/auth/google/callback
+	RedirectURL string
+	// SuccessURL is where the browser is sent after signing in, with the
+	// token in the URL fragment. Without it the callback responds with JSON.
+	SuccessURL string
+
+	// The endpoints default to Google's; tests point them at a fake server
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+}
+
+// GoogleProvider runs the OAuth 2.0 authorization code flow against Google
+// and returns the verified email of the account that signed in
+type GoogleProvider struct {
+	cfg    GoogleConfig
+	client *http.Client
+}
+
+// NewGoogleProvider creates a GoogleProvider; the client id, secret and
+// redirect URL are required
+func NewGoogleProvider(cfg GoogleConfig) (*GoogleProvider, error) {
+	if cfg.ClientID == "" {
+		return nil, ErrGoogleNotConfigured
+	}
+	if cfg.ClientSecret == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL are required with GOOGLE_CLIENT_ID")
+	}
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = GoogleAuthURL
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = GoogleTokenURL
+	}
+	if cfg.UserInfoURL == "" {
+		cfg.UserInfoURL = GoogleUserInfoURL
+	}
+	return &GoogleProvider{cfg: cfg, client: &http.Client{Timeout: googleTimeout}}, nil
+}
+
+// NewGoogleProviderFromEnv creates a GoogleProvider from GOOGLE_CLIENT_ID,
+// GOOGLE_CLIENT_SECRET, GOOGLE_REDIRECT_URL and the optional GOOGLE_SUCCESS_URL
+func NewGoogleProviderFromEnv() (*GoogleProvider, error) {
+	return NewGoogleProvider(GoogleConfig{
+		ClientID:     config.Get("GOOGLE_CLIENT_ID"),
+		ClientSecret: config.Get("GOOGLE_CLIENT_SECRET"),
+		RedirectURL:  config.Get("GOOGLE_REDIRECT_URL"),
+		SuccessURL:   config.Get("GOOGLE_SUCCESS_URL"),
+	})
+}
+
+// SuccessURL returns where to send the browser after signing in, or "" to
+// respond with JSON
+func (p *GoogleProvider) SuccessURL() string {
+	return p.cfg.SuccessURL
+}
+
+// AuthCodeURL returns the Google consent page URL to redirect the browser to.
+// state must be unguessable and checked again in the callback.
+func (p *GoogleProvider) AuthCodeURL(state string) string {
+	query := url.Values{
+		"client_id":     {p.cfg.ClientID},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {"openid email"},
+		"state":         {state},
+		"prompt":        {"select_account"},
+	}
+	return p.cfg.AuthURL + "?" + query.Encode()
+}
+
+// Exchange trades the authorization code from the callback for an access
+// token and returns the lowercased email of the Google account. Accounts
+// whose email Google has not verified are rejected.
+func (p *GoogleProvider) Exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"code":          {code},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"grant_type":    {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := p.do(req, &token); err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("Google returned no access token")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.UserInfoURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	var info struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := p.do(req, &info); err != nil {
+		return "", fmt.Errorf("failed to fetch Google account: %w", err)
+	}
+	if info.Email == "" || !info.EmailVerified {
+		return "", ErrGoogleEmailUnverified
+	}
+	return strings.ToLower(info.Email), nil
+}
+
+// do sends req and decodes a JSON response, turning OAuth error responses
+// into errors
+func (p *GoogleProvider) do(req *http.Request, v any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return fmt.Errorf("%s: %s (status %d)", oauthErr.Error, oauthErr.Description, resp.StatusCode)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// NewOAuthState returns a random value for the OAuth state parameter
+func NewOAuthState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
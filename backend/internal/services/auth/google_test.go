@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeGoogle serves the token and userinfo endpoints, accepting only code
+// "good" and returning the given account
+func fakeGoogle(t *testing.T, email string, verified bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good" || r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant", "error_description": "Bad Request"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "access", "token_type": "Bearer"}`))
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		verifiedJSON := "false"
+		if verified {
+			verifiedJSON = "true"
+		}
+		w.Write([]byte(`{"sub": "1", "email": "` + email + `", "email_verified": ` + verifiedJSON + `}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestGoogleProvider(t *testing.T, server *httptest.Server) *GoogleProvider {
+	t.Helper()
+	google, err := NewGoogleProvider(GoogleConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://budget.test/api/auth/google/callback",
+		AuthURL:      server.URL + "/auth",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/userinfo",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return google
+}
+
+func TestGoogleProvider_Exchange(t *testing.T) {
+	google := newTestGoogleProvider(t, fakeGoogle(t, "Me@Example.com", true))
+
+	authURL, err := url.Parse(google.AuthCodeURL("xyz"))
+	if err != nil {
+		t.Fatalf("Invalid auth URL: %v", err)
+	}
+	query := authURL.Query()
+	if query.Get("state") != "xyz" || query.Get("client_id") != "client" ||
+		query.Get("redirect_uri") != "http://budget.test/api/auth/google/callback" {
+		t.Errorf("Unexpected auth URL %s", authURL)
+	}
+
+	email, err := google.Exchange(context.Background(), "good")
+	if err != nil || email != "me@example.com" {
+		t.Errorf("Expected me@example.com, got %q (%v)", email, err)
+	}
+
+	_, err = google.Exchange(context.Background(), "bad")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Expected invalid_grant error, got %v", err)
+	}
+}
+
+func TestGoogleProvider_RejectsUnverifiedEmail(t *testing.T) {
+	google := newTestGoogleProvider(t, fakeGoogle(t, "me@example.com", false))
+
+	if _, err := google.Exchange(context.Background(), "good"); !errors.Is(err, ErrGoogleEmailUnverified) {
+		t.Errorf("Expected ErrGoogleEmailUnverified, got %v", err)
+	}
+}
+
+func TestNewGoogleProvider_RequiresConfig(t *testing.T) {
+	if _, err := NewGoogleProvider(GoogleConfig{}); !errors.Is(err, ErrGoogleNotConfigured) {
+		t.Errorf("Expected ErrGoogleNotConfigured, got %v", err)
+	}
+	if _, err := NewGoogleProvider(GoogleConfig{ClientID: "client"}); err == nil {
+		t.Error("Expected an error without a client secret and redirect URL")
+	}
+}
@@ -0,0 +1,77 @@
+// Package auth hashes passwords and issues and verifies the JWTs used to
+// authenticate API requests.
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PBKDF2 parameters for new hashes; stored hashes record their own iteration
+// count so it can be raised without invalidating existing passwords
+const (
+	passwordScheme     = "pbkdf2-sha256"
+	passwordIterations = 600_000
+	saltLength         = 16
+	keyLength          = 32
+)
+
+// HashPassword returns an encoded PBKDF2-SHA256 hash of password in the form
+// pbkdf2-sha256$<iterations>$<salt>$<key>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, keyLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return strings.Join([]string{
+		passwordScheme,
+		strconv.Itoa(passwordIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// CheckPassword reports whether password matches an encoded hash from
+// HashPassword; it returns ErrPasswordMismatch when it does not
+func CheckPassword(encoded, password string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != passwordScheme {
+		return fmt.Errorf("unsupported password hash format")
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return fmt.Errorf("invalid password hash iterations %q", parts[1])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("invalid password hash salt: %w", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return fmt.Errorf("invalid password hash key: %w", err)
+	}
+
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
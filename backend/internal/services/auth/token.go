@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"budget-tracker/internal/config"
+	"budget-tracker/internal/models"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSecretNotSet   = errors.New("JWT_SECRET environment variable is not set")
+	ErrSecretTooShort = fmt.Errorf("JWT secret must be at least %d bytes", MinSecretLength)
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrTokenExpired   = errors.New("token has expired")
+)
+
+// MinSecretLength is the minimum HMAC key length, the size of a SHA-256 hash
+const MinSecretLength = 32
+
+// DefaultTokenTTL is how long access tokens are valid unless JWT_TTL is set.
+// It is kept short because access tokens cannot be revoked; clients renew
+// them with a refresh token.
+const DefaultTokenTTL = 15 * time.Minute
+
+// DefaultRefreshTTL is how long an unused refresh token stays valid unless
+// JWT_REFRESH_TTL is set
+const DefaultRefreshTTL = 30 * 24 * time.Hour
+
+// ScopeAllowance is the scope of tokens issued to allowance sub-accounts,
+// which may only use the routes that log their own expenses
+const ScopeAllowance = "allowance"
+
+// jwtHeader is the encoded header of every token; only HS256 is accepted
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims identifies the user a token was issued to
+type Claims struct {
+	UserID    int64
+	SessionID int64 // session the token was issued for, see UserRepository.CreateSession
+	Email     string
+	Scope     string // empty for full access, otherwise ScopeAllowance
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// jwtClaims is the JSON payload of a token
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	SessionID int64  `json:"sid,omitempty"`
+	Email     string `json:"email"`
+	Scope     string `json:"scope,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenIssuer signs and verifies HS256 JWTs
+type TokenIssuer struct {
+	secret     []byte
+	ttl        time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+// NewTokenIssuer creates a TokenIssuer; the secret must be at least
+// MinSecretLength bytes
+func NewTokenIssuer(secret []byte, ttl time.Duration) (*TokenIssuer, error) {
+	if len(secret) < MinSecretLength {
+		return nil, ErrSecretTooShort
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &TokenIssuer{secret: secret, ttl: ttl, refreshTTL: DefaultRefreshTTL, now: time.Now}, nil
+}
+
+// NewTokenIssuerFromEnv creates a TokenIssuer from JWT_SECRET and the optional
+// JWT_TTL and JWT_REFRESH_TTL durations
+func NewTokenIssuerFromEnv() (*TokenIssuer, error) {
+	secret := config.Get("JWT_SECRET")
+	if secret == "" {
+		return nil, ErrSecretNotSet
+	}
+
+	ttl := DefaultTokenTTL
+	if v := config.Get("JWT_TTL"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid JWT_TTL %q: expected a duration such as 12h", v)
+		}
+		ttl = parsed
+	}
+
+	tokens, err := NewTokenIssuer([]byte(secret), ttl)
+	if err != nil {
+		return nil, err
+	}
+	if v := config.Get("JWT_REFRESH_TTL"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= ttl {
+			return nil, fmt.Errorf("invalid JWT_REFRESH_TTL %q: expected a duration longer than JWT_TTL such as 720h", v)
+		}
+		tokens.refreshTTL = parsed
+	}
+	return tokens, nil
+}
+
+// ForTenant returns a copy of the issuer whose secret is derived from the
+// secret and tenant, so a token of one household of a shared server is not
+// accepted by another
+func (t *TokenIssuer) ForTenant(tenant string) *TokenIssuer {
+	scoped := *t
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte("tenant:" + tenant))
+	scoped.secret = mac.Sum(nil)
+	return &scoped
+}
+
+// RefreshExpiry returns when a refresh token issued or used now expires
+func (t *TokenIssuer) RefreshExpiry() time.Time {
+	return t.now().UTC().Add(t.refreshTTL)
+}
+
+// NewRefreshToken returns a random refresh token for the client and the hash
+// to store in its place
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the stored form of a refresh token. The token is
+// random, so a plain SHA-256 hash is enough to make a leaked database useless.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue returns a signed access token for user in session sessionID and its
+// expiry. Tokens of allowance sub-accounts get ScopeAllowance.
+func (t *TokenIssuer) Issue(user *models.User, sessionID int64) (string, time.Time, error) {
+	now := t.now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(t.ttl)
+
+	claims := jwtClaims{
+		Subject:   strconv.FormatInt(user.ID, 10),
+		SessionID: sessionID,
+		Email:     user.Email,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+	if user.ParentID != nil {
+		claims.Scope = ScopeAllowance
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to encode token claims: %w", err)
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + t.sign(signingInput), expiresAt, nil
+}
+
+// Verify checks the signature and expiry of token and returns its claims
+func (t *TokenIssuer) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(t.sign(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID < 1 {
+		return nil, ErrInvalidToken
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0).UTC()
+	if !t.now().Before(expiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return &Claims{
+		UserID:    userID,
+		SessionID: claims.SessionID,
+		Email:     claims.Email,
+		Scope:     claims.Scope,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0).UTC(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (t *TokenIssuer) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type claimsKey struct{}
+
+// Restricted reports whether the claims belong to an allowance sub-account
+func (c *Claims) Restricted() bool {
+	return c.Scope == ScopeAllowance
+}
+
+// WithClaims returns a copy of ctx carrying the authenticated user's claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestTokenIssuer_IssueAndVerify(t *testing.T) {
+	issuer, err := NewTokenIssuer(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create issuer: %v", err)
+	}
+	now := time.Date(2025, 10, 15, 10, 0, 0, 0, time.UTC)
+	issuer.now = func() time.Time { return now }
+
+	token, expiresAt, err := issuer.Issue(&models.User{ID: 7, Email: "a@example.com"}, 4)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected expiry %s, got %s", now.Add(time.Hour), expiresAt)
+	}
+
+	claims, err := issuer.Verify(token)
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	if claims.UserID != 7 || claims.SessionID != 4 || claims.Email != "a@example.com" || claims.Restricted() {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+
+	// Allowance sub-accounts get restricted tokens
+	parentID := int64(7)
+	kidToken, _, err := issuer.Issue(&models.User{ID: 8, ParentID: &parentID}, 5)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	if kidClaims, err := issuer.Verify(kidToken); err != nil || !kidClaims.Restricted() {
+		t.Errorf("Expected restricted claims, got %+v, %v", kidClaims, err)
+	}
+
+	// A token signed with another secret is rejected
+	other, _ := NewTokenIssuer([]byte(strings.Repeat("x", MinSecretLength)), time.Hour)
+	if _, err := other.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a foreign signature, got %v", err)
+	}
+
+	// Tampering with the payload breaks the signature
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := issuer.Verify(tampered); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a tampered token, got %v", err)
+	}
+
+	// alg=none tokens are rejected
+	if _, err := issuer.Verify("eyJhbGciOiJub25lIn0." + parts[1] + "."); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for alg none, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := issuer.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+}
+
+func TestTokenIssuer_ForTenant(t *testing.T) {
+	issuer, err := NewTokenIssuer(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create issuer: %v", err)
+	}
+	smith, jones := issuer.ForTenant("smith"), issuer.ForTenant("jones")
+
+	token, _, err := smith.Issue(&models.User{ID: 1, Email: "a@example.com"}, 1)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	if _, err := issuer.ForTenant("smith").Verify(token); err != nil {
+		t.Errorf("Expected the tenant to accept its token, got %v", err)
+	}
+	if _, err := jones.Verify(token); err == nil {
+		t.Error("Expected another tenant to refuse the token")
+	}
+	if _, err := issuer.Verify(token); err == nil {
+		t.Error("Expected the unscoped issuer to refuse the token")
+	}
+}
+
+func TestNewTokenIssuer_ShortSecret(t *testing.T) {
+	if _, err := NewTokenIssuer([]byte("short"), time.Hour); !errors.Is(err, ErrSecretTooShort) {
+		t.Errorf("Expected ErrSecretTooShort, got %v", err)
+	}
+}
+
+func TestPassword_HashAndCheck(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$600000$") {
+		t.Errorf("Unexpected hash format %q", hash)
+	}
+	if err := CheckPassword(hash, "correct horse"); err != nil {
+		t.Errorf("Expected password to match, got %v", err)
+	}
+	if err := CheckPassword(hash, "wrong horse"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected ErrPasswordMismatch, got %v", err)
+	}
+
+	other, _ := HashPassword("correct horse")
+	if other == hash {
+		t.Error("Expected hashes of the same password to use different salts")
+	}
+}
@@ -0,0 +1,166 @@
+// Package demo fills the demo database with a sample household budget, so a
+// public demo instance (DEMO_MODE) has something to show and can be reset to
+// it at any time.
+package demo
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Months is how many months of history the dataset has, the current one
+// included
+const Months = 3
+
+// expectedItem is an expected expense of the dataset
+type expectedItem struct {
+	key         string
+	name        string
+	source      string
+	amount      float64
+	expenseType models.ExpenseType
+}
+
+var expectedItems = []expectedItem{
+	{"rent", "Rent", "Landlord", 1400, models.ExpenseTypeMonthly},
+	{"internet", "Internet", "Comcast", 60, models.ExpenseTypeMonthly},
+	{"gym", "Gym membership", "Planet Fitness", 40, models.ExpenseTypeMonthly},
+	{"groceries", "Groceries", "Costco", 120, models.ExpenseTypeWeekly},
+	{"coffee", "Coffee beans", "Blue Bottle", 20, models.ExpenseTypeWeekly},
+}
+
+// spending is an expense made every month on day; expected links it to an
+// expectedItem by key
+type spending struct {
+	day         int
+	name        string
+	source      string
+	amount      float64
+	expenseType models.ExpenseType
+	expected    string
+}
+
+var monthlySpending = []spending{
+	{1, "Rent", "Landlord", 1400, models.ExpenseTypeMonthly, "rent"},
+	{3, "Groceries", "Costco", 118.42, models.ExpenseTypeWeekly, "groceries"},
+	{5, "Coffee beans", "Blue Bottle", 18.50, models.ExpenseTypeWeekly, "coffee"},
+	{8, "Internet", "Comcast", 59.99, models.ExpenseTypeMonthly, "internet"},
+	{10, "Groceries", "Trader Joe's", 86.30, models.ExpenseTypeWeekly, "groceries"},
+	{12, "Dinner out", "Olive Garden", 64.20, models.ExpenseTypeMisc, ""},
+	{15, "Gym membership", "Planet Fitness", 40, models.ExpenseTypeMonthly, "gym"},
+	{17, "Groceries", "Costco", 132.75, models.ExpenseTypeWeekly, "groceries"},
+	{20, "Movie tickets", "AMC", 31, models.ExpenseTypeMisc, ""},
+	{24, "Groceries", "Whole Foods", 97.10, models.ExpenseTypeWeekly, "groceries"},
+	{27, "Property tax installment", "County Treasurer", 210, models.ExpenseTypeTax, ""},
+}
+
+// variation scales the spending that is not fixed in each month of the
+// history, oldest first, so the months differ
+var variation = [Months]float64{0.94, 1.12, 1}
+
+// Seeder resets the demo database to the dataset
+type Seeder struct {
+	maintenance *repository.MaintenanceRepository
+	budgets     *repository.BudgetRepository
+	expected    *repository.ExpectedExpenseRepository
+	actual      *repository.ActualExpenseRepository
+	goals       *repository.SpendingGoalRepository
+	settings    *repository.SettingsRepository
+}
+
+// NewSeeder creates a Seeder for db, which must be the demo database
+func NewSeeder(db *repository.DB) *Seeder {
+	return &Seeder{
+		maintenance: repository.NewMaintenanceRepository(db),
+		budgets:     repository.NewBudgetRepository(db),
+		expected:    repository.NewExpectedExpenseRepository(db),
+		actual:      repository.NewActualExpenseRepository(db),
+		goals:       repository.NewSpendingGoalRepository(db),
+		settings:    repository.NewSettingsRepository(db),
+	}
+}
+
+// Reset deletes everything in the demo database and seeds the dataset in the
+// shared workspace, with its history ending at now. Expenses of the current
+// month stop at today.
+func (s *Seeder) Reset(now time.Time) error {
+	if err := s.maintenance.ClearData(); err != nil {
+		return err
+	}
+	now = now.UTC()
+
+	if err := s.settings.SetDefaultBudget(&models.DefaultBudget{Amount: 3000, NotificationThreshold: 0.8}); err != nil {
+		return fmt.Errorf("failed to seed default budget: %w", err)
+	}
+
+	expectedIDs := map[string]int64{}
+	for _, item := range expectedItems {
+		expense, err := s.expected.Create(&models.CreateExpectedExpenseRequest{
+			ItemName:       item.name,
+			Source:         item.source,
+			ExpectedAmount: item.amount,
+			ExpenseType:    item.expenseType,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed expected expense %s: %w", item.name, err)
+		}
+		expectedIDs[item.key] = expense.ID
+	}
+
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	first := current.AddDate(0, 1-Months, 0)
+	for i := 0; i < Months; i++ {
+		month := first.AddDate(0, i, 0)
+		if _, err := s.budgets.Create(&models.CreateBudgetLimitRequest{
+			Month:                 int(month.Month()),
+			Year:                  month.Year(),
+			Amount:                2800 + 200*float64(i%2),
+			NotificationThreshold: 0.8,
+		}); err != nil {
+			return fmt.Errorf("failed to seed budget: %w", err)
+		}
+
+		receipt := int64(i * len(monthlySpending))
+		for _, item := range monthlySpending {
+			date := month.AddDate(0, 0, item.day-1)
+			if date.Month() != month.Month() || date.After(now) {
+				continue
+			}
+			amount := item.amount
+			if item.expenseType != models.ExpenseTypeMonthly {
+				amount = math.Round(amount*variation[i]*100) / 100
+			}
+			receipt++
+			req := &models.CreateActualExpenseRequest{
+				ItemName:      item.name,
+				Source:        item.source,
+				ActualAmount:  amount,
+				ExpenseType:   item.expenseType,
+				ReceiptDate:   &date,
+				ReceiptNumber: receipt,
+			}
+			if id, ok := expectedIDs[item.expected]; ok {
+				req.ExpectedExpenseID = &id
+			}
+			if _, err := s.actual.Create(req); err != nil {
+				return fmt.Errorf("failed to seed expense %s: %w", item.name, err)
+			}
+		}
+	}
+
+	eatingOut := models.ExpenseTypeMisc
+	if _, err := s.goals.Create(&models.SpendingGoalRequest{
+		Name:         "Eat out less",
+		MonthlyLimit: 100,
+		ExpenseType:  &eatingOut,
+		StartMonth:   int(first.Month()),
+		StartYear:    first.Year(),
+		Months:       6,
+	}); err != nil {
+		return fmt.Errorf("failed to seed spending goal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package demo
+
+import (
+	"budget-tracker/internal/repository"
+	"testing"
+	"time"
+)
+
+func setupDemoDB(t *testing.T) *repository.DB {
+	t.Helper()
+	db, err := repository.NewDemoDB(repository.Config{
+		Mode:        repository.ModeLocal,
+		LocalPath:   t.TempDir() + "/demo.db",
+		Environment: repository.DemoEnvironment,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open demo database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func TestSeeder_Reset(t *testing.T) {
+	db := setupDemoDB(t)
+	seeder := NewSeeder(db)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	// Resetting twice leaves the same dataset, not two copies
+	for i := 0; i < 2; i++ {
+		if err := seeder.Reset(now); err != nil {
+			t.Fatalf("Reset %d failed: %v", i+1, err)
+		}
+	}
+
+	budgets, err := repository.NewBudgetRepository(db).GetAll()
+	if err != nil {
+		t.Fatalf("Failed to list budgets: %v", err)
+	}
+	if len(budgets) != Months {
+		t.Errorf("Expected %d budgets, got %d", Months, len(budgets))
+	}
+
+	actual := repository.NewActualExpenseRepository(db)
+	august, err := actual.GetByMonthYear(8, 2026)
+	if err != nil {
+		t.Fatalf("Failed to list expenses: %v", err)
+	}
+	if len(august) != len(monthlySpending) {
+		t.Errorf("Expected %d expenses in August, got %d", len(monthlySpending), len(august))
+	}
+	october, err := actual.GetByMonthYear(10, 2026)
+	if err != nil {
+		t.Fatalf("Failed to list expenses: %v", err)
+	}
+	for _, e := range october {
+		if e.ReceiptDate.After(now) {
+			t.Errorf("Expected no expenses after today, got %s on %s", e.ItemName, e.ReceiptDate)
+		}
+	}
+	if len(october) == 0 || len(october) >= len(monthlySpending) {
+		t.Errorf("Expected the first half of October, got %d expenses", len(october))
+	}
+
+	goals, err := repository.NewSpendingGoalRepository(db).List()
+	if err != nil || len(goals) != 1 {
+		t.Fatalf("Expected 1 goal, got %d, %v", len(goals), err)
+	}
+	progress, err := repository.NewSpendingGoalRepository(db).Progress(goals[0].ID, now)
+	if err != nil {
+		t.Fatalf("Failed to get goal progress: %v", err)
+	}
+	if progress.MonthsBroken != 1 {
+		t.Errorf("Expected the goal to be broken in one month, got %+v", progress)
+	}
+}
@@ -0,0 +1,167 @@
+// Package export writes expenses in the plain-text formats of other
+// accounting tools.
+package export
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAccount = errors.New("invalid Beancount account name")
+
+// Beancount defaults
+const (
+	DefaultFundingAccount = "Assets:Checking"
+	DefaultCurrency       = "USD"
+)
+
+// expenseAccounts maps expense types onto Beancount expense accounts
+var expenseAccounts = map[models.ExpenseType]string{
+	models.ExpenseTypeWeekly:  "Expenses:Weekly",
+	models.ExpenseTypeMonthly: "Expenses:Monthly",
+	models.ExpenseTypeMisc:    "Expenses:Misc",
+	models.ExpenseTypeTax:     "Expenses:Tax",
+}
+
+// accountPattern matches Beancount account names such as Assets:Bank:Checking
+var accountPattern = regexp.MustCompile(`^(Assets|Liabilities|Equity|Income|Expenses)(:[A-Z0-9][A-Za-z0-9-]*)+$`)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9'._-]{0,22}[A-Z0-9]$`)
+
+// BeancountOptions configures a Beancount export
+type BeancountOptions struct {
+	// FundingAccount is credited for every receipt, e.g. Assets:Checking or
+	// Liabilities:CreditCard
+	FundingAccount string
+	Currency       string
+	// OpenDate is the date the accounts are opened on, normally the first day
+	// of the exported period
+	OpenDate time.Time
+}
+
+// Validate fills in defaults and checks the account and currency names
+func (o *BeancountOptions) Validate() error {
+	if o.FundingAccount == "" {
+		o.FundingAccount = DefaultFundingAccount
+	}
+	if o.Currency == "" {
+		o.Currency = DefaultCurrency
+	}
+	if !accountPattern.MatchString(o.FundingAccount) {
+		return fmt.Errorf("%w: %q", ErrInvalidAccount, o.FundingAccount)
+	}
+	if !currencyPattern.MatchString(o.Currency) {
+		return fmt.Errorf("invalid currency %q", o.Currency)
+	}
+	return nil
+}
+
+// WriteBeancount writes the expenses as a Beancount ledger. Each receipt
+// becomes one transaction with a posting per item to the expense account of
+// its type, balanced against the funding account.
+func WriteBeancount(w io.Writer, expenses []models.ActualExpense, opts BeancountOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	sorted := append([]models.ActualExpense(nil), expenses...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !a.ReceiptDate.Equal(b.ReceiptDate) {
+			return a.ReceiptDate.Before(b.ReceiptDate)
+		}
+		if a.ReceiptNumber != b.ReceiptNumber {
+			return a.ReceiptNumber < b.ReceiptNumber
+		}
+		return lineNo(a) < lineNo(b)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "option \"operating_currency\" \"%s\"\n\n", opts.Currency)
+
+	openDate := opts.OpenDate.Format("2006-01-02")
+	fmt.Fprintf(&b, "%s open %s\n", openDate, opts.FundingAccount)
+	for _, t := range []models.ExpenseType{
+		models.ExpenseTypeWeekly, models.ExpenseTypeMonthly,
+		models.ExpenseTypeMisc, models.ExpenseTypeTax,
+	} {
+		fmt.Fprintf(&b, "%s open %s %s\n", openDate, expenseAccounts[t], opts.Currency)
+	}
+
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sameReceipt(sorted[start], sorted[end]) {
+			end++
+		}
+		writeReceipt(&b, sorted[start:end], opts)
+		start = end
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeReceipt writes one transaction for the items of a receipt
+func writeReceipt(b *strings.Builder, items []models.ActualExpense, opts BeancountOptions) {
+	first := items[0]
+	fmt.Fprintf(
+		b, "\n%s * %s %s\n",
+		first.ReceiptDate.Format("2006-01-02"),
+		quote(first.Source),
+		quote(fmt.Sprintf("Receipt #%d", first.ReceiptNumber)),
+	)
+	fmt.Fprintf(b, "  receipt_number: %d\n", first.ReceiptNumber)
+
+	// Sum in cents so the funding posting balances the items exactly
+	var totalCents int64
+	for _, item := range items {
+		cents := int64(math.Round(item.ActualAmount * 100))
+		totalCents += cents
+
+		account, ok := expenseAccounts[item.ExpenseType]
+		if !ok {
+			account = expenseAccounts[models.ExpenseTypeMisc]
+		}
+		fmt.Fprintf(b, "  %-24s %s %s ; %s\n", account, formatCents(cents), opts.Currency, comment(item.ItemName))
+	}
+	fmt.Fprintf(b, "  %-24s %s %s\n", opts.FundingAccount, formatCents(-totalCents), opts.Currency)
+}
+
+func sameReceipt(a, b models.ActualExpense) bool {
+	return a.ReceiptNumber == b.ReceiptNumber && a.Source == b.Source &&
+		a.ReceiptDate.Equal(b.ReceiptDate)
+}
+
+func lineNo(e models.ActualExpense) int {
+	if e.LineNo == nil {
+		return math.MaxInt
+	}
+	return *e.LineNo
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// quote renders s as a Beancount string literal
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\r", " ").Replace(s)
+	return `"` + s + `"`
+}
+
+// comment keeps s on a single line
+func comment(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteBeancount(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	line := func(n int) *int { return &n }
+
+	expenses := []models.ActualExpense{
+		{ItemName: "Rent", Source: "City Apartments", ActualAmount: 1200, ExpenseType: models.ExpenseTypeMonthly,
+			ReceiptDate: date("2025-10-01"), ReceiptNumber: 1},
+		{ItemName: "Bread", Source: `Joe's "Corner" Shop`, ActualAmount: 2.5, ExpenseType: models.ExpenseTypeWeekly,
+			ReceiptDate: date("2025-10-03"), ReceiptNumber: 2, LineNo: line(2)},
+		{ItemName: "Milk", Source: `Joe's "Corner" Shop`, ActualAmount: 3.1, ExpenseType: models.ExpenseTypeWeekly,
+			ReceiptDate: date("2025-10-03"), ReceiptNumber: 2, LineNo: line(1)},
+		{ItemName: "Sales tax", Source: `Joe's "Corner" Shop`, ActualAmount: 0.45, ExpenseType: models.ExpenseTypeTax,
+			ReceiptDate: date("2025-10-03"), ReceiptNumber: 2, LineNo: line(3)},
+	}
+
+	var out strings.Builder
+	err := WriteBeancount(&out, expenses, BeancountOptions{
+		FundingAccount: "Liabilities:Visa",
+		OpenDate:       date("2025-01-01"),
+	})
+	if err != nil {
+		t.Fatalf("WriteBeancount failed: %v", err)
+	}
+
+	want := `option "operating_currency" "USD"
+
+2025-01-01 open Liabilities:Visa
+2025-01-01 open Expenses:Weekly USD
+2025-01-01 open Expenses:Monthly USD
+2025-01-01 open Expenses:Misc USD
+2025-01-01 open Expenses:Tax USD
+
+2025-10-01 * "City Apartments" "Receipt #1"
+  receipt_number: 1
+  Expenses:Monthly         1200.00 USD ; Rent
+  Liabilities:Visa         -1200.00 USD
+
+2025-10-03 * "Joe's \"Corner\" Shop" "Receipt #2"
+  receipt_number: 2
+  Expenses:Weekly          3.10 USD ; Milk
+  Expenses:Weekly          2.50 USD ; Bread
+  Expenses:Tax             0.45 USD ; Sales tax
+  Liabilities:Visa         -6.05 USD
+`
+	if got := out.String(); got != want {
+		t.Errorf("Unexpected ledger:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBeancountOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    BeancountOptions
+		wantErr bool
+	}{
+		{"defaults", BeancountOptions{}, false},
+		{"nested account", BeancountOptions{FundingAccount: "Assets:Bank:Checking-2"}, false},
+		{"lowercase component", BeancountOptions{FundingAccount: "Assets:checking"}, true},
+		{"unknown root", BeancountOptions{FundingAccount: "Wallet:Cash"}, true},
+		{"injection", BeancountOptions{FundingAccount: "Assets:Cash\n2025-01-01 close"}, true},
+		{"lowercase currency", BeancountOptions{Currency: "usd"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	opts := BeancountOptions{FundingAccount: "Assets:checking"}
+	if err := opts.Validate(); !errors.Is(err, ErrInvalidAccount) {
+		t.Errorf("Expected ErrInvalidAccount, got %v", err)
+	}
+}
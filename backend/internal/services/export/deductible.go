@@ -0,0 +1,83 @@
+package export
+
+import (
+	"archive/zip"
+	"budget-tracker/internal/models"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteDeductibleZip writes report as a zip for a tax filing: a summary
+// deductible-<year>.csv of the deductible items, one row each followed by a
+// total row, and a receipts/ folder with a CSV of every line of each receipt
+func WriteDeductibleZip(w io.Writer, report *models.DeductibleReport) error {
+	archive := zip.NewWriter(w)
+
+	summary, err := archive.Create(fmt.Sprintf("deductible-%d.csv", report.Year))
+	if err != nil {
+		return err
+	}
+	out := csv.NewWriter(summary)
+	out.Write([]string{"date", "receipt", "source", "item", "type", "amount"})
+	for _, receipt := range report.Receipts {
+		for _, item := range receipt.Items {
+			if item.Deductible && !item.PendingApproval {
+				out.Write(deductibleRecord(receipt, item))
+			}
+		}
+	}
+	out.Write([]string{"total", "", "", "", "", formatAmount(report.Total)})
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return err
+	}
+
+	for _, receipt := range report.Receipts {
+		file, err := archive.Create(receiptFileName(receipt))
+		if err != nil {
+			return err
+		}
+		out := csv.NewWriter(file)
+		out.Write([]string{"date", "receipt", "source", "item", "type", "amount", "deductible"})
+		for _, item := range receipt.Items {
+			out.Write(append(deductibleRecord(receipt, item), strconv.FormatBool(item.Deductible)))
+		}
+		out.Write([]string{"total", "", "", "", "", formatAmount(receipt.Total), formatAmount(receipt.Deductible)})
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// receiptFileName names the CSV of receipt after its date and number, or
+// after its only item for an expense entered without a receipt number
+func receiptFileName(receipt models.DeductibleReceipt) string {
+	date := receipt.ReceiptDate.Format("2006-01-02")
+	if receipt.ReceiptNumber == 0 && len(receipt.Items) > 0 {
+		return fmt.Sprintf("receipts/%s-item-%d.csv", date, receipt.Items[0].ID)
+	}
+	return fmt.Sprintf("receipts/%s-%d.csv", date, receipt.ReceiptNumber)
+}
+
+func deductibleRecord(receipt models.DeductibleReceipt, item models.ActualExpense) []string {
+	number := ""
+	if receipt.ReceiptNumber != 0 {
+		number = strconv.FormatInt(receipt.ReceiptNumber, 10)
+	}
+	return []string{
+		receipt.ReceiptDate.Format("2006-01-02"),
+		number,
+		item.Source,
+		item.ItemName,
+		string(item.ExpenseType),
+		formatAmount(item.ActualAmount),
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
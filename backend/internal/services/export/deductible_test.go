@@ -0,0 +1,65 @@
+package export
+
+import (
+	"archive/zip"
+	"budget-tracker/internal/models"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWriteDeductibleZip(t *testing.T) {
+	date := time.Date(2025, 11, 2, 0, 0, 0, 0, time.UTC)
+	report := &models.DeductibleReport{
+		Year: 2025,
+		Receipts: []models.DeductibleReceipt{{
+			ReceiptNumber: 7,
+			ReceiptDate:   date,
+			Source:        "Red Cross",
+			Items: []models.ActualExpense{
+				{ItemName: "Donation", Source: "Red Cross", ActualAmount: 50, ExpenseType: models.ExpenseTypeMisc, Deductible: true},
+				{ItemName: `T-shirt, "XL"`, Source: "Red Cross", ActualAmount: 15, ExpenseType: models.ExpenseTypeMisc},
+			},
+			Total:      65,
+			Deductible: 50,
+		}},
+		Items: 1,
+		Total: 50,
+	}
+
+	var out bytes.Buffer
+	if err := WriteDeductibleZip(&out, report); err != nil {
+		t.Fatalf("WriteDeductibleZip failed: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+
+	want := map[string]string{
+		"deductible-2025.csv": `date,receipt,source,item,type,amount
+2025-11-02,7,Red Cross,Donation,misc,50.00
+total,,,,,50.00
+`,
+		"receipts/2025-11-02-7.csv": `date,receipt,source,item,type,amount,deductible
+2025-11-02,7,Red Cross,Donation,misc,50.00,true
+2025-11-02,7,Red Cross,"T-shirt, ""XL""",misc,15.00,false
+total,,,,,65.00,50.00
+`,
+	}
+	if len(archive.File) != len(want) {
+		t.Fatalf("Expected %d files, got %d", len(want), len(archive.File))
+	}
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		body, _ := io.ReadAll(f)
+		f.Close()
+		if string(body) != want[file.Name] {
+			t.Errorf("Unexpected %s:\n%s", file.Name, body)
+		}
+	}
+}
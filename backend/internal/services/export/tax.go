@@ -0,0 +1,29 @@
+package export
+
+import (
+	"budget-tracker/internal/models"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteTaxCSV writes report as CSV, one row per month and store with the
+// month as YYYY-MM, followed by a total row
+func WriteTaxCSV(w io.Writer, report *models.TaxReport) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"month", "source", "items", "tax"})
+	for _, row := range report.Rows {
+		out.Write([]string{
+			fmt.Sprintf("%d-%02d", report.Year, row.Month),
+			row.Source,
+			strconv.Itoa(row.Items),
+			strconv.FormatFloat(row.Total, 'f', 2, 64),
+		})
+	}
+	out.Write([]string{
+		"total", "", strconv.Itoa(report.Items), strconv.FormatFloat(report.Total, 'f', 2, 64),
+	})
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,32 @@
+package export
+
+import (
+	"budget-tracker/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestWriteTaxCSV(t *testing.T) {
+	report := &models.TaxReport{
+		Year: 2025,
+		Rows: []models.TaxReportRow{
+			{Month: 3, Source: "Costco", Items: 2, Total: 4.5},
+			{Month: 3, Source: `Joe's "Corner" Shop`, Items: 1, Total: 0.45},
+		},
+		Items: 3,
+		Total: 4.95,
+	}
+
+	var out strings.Builder
+	if err := WriteTaxCSV(&out, report); err != nil {
+		t.Fatalf("WriteTaxCSV failed: %v", err)
+	}
+	want := `month,source,items,tax
+2025-03,Costco,2,4.50
+2025-03,"Joe's ""Corner"" Shop",1,0.45
+total,,3,4.95
+`
+	if out.String() != want {
+		t.Errorf("Unexpected CSV:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
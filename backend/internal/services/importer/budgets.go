@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"budget-tracker/internal/models"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BudgetRow is a budget read from a CSV and the line it is on
+type BudgetRow struct {
+	Line   int
+	Budget models.CreateBudgetLimitRequest
+}
+
+// LineError is the problem with one line of a CSV
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// LinesError lists every invalid line of a CSV
+type LinesError struct {
+	Lines []LineError
+}
+
+func (e *LinesError) Error() string {
+	return fmt.Sprintf("%s: %d invalid lines, first line %d: %s",
+		ErrInvalidCSV, len(e.Lines), e.Lines[0].Line, e.Lines[0].Error)
+}
+
+func (e *LinesError) Unwrap() error {
+	return ErrInvalidCSV
+}
+
+// ParseBudgets reads a CSV with month, year and amount columns and an
+// optional threshold column, a fraction like 0.8 or a percentage like 80%,
+// e.g. to seed the budgets of past years. Every budget is validated, and a
+// month listed twice is an error on its second line. The problems of all
+// lines are returned together in a *LinesError.
+func ParseBudgets(r io.Reader) ([]BudgetRow, error) {
+	reader, columns, err := readHeader(r, "month", "year", "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []BudgetRow{}
+	var invalid []LineError
+	seen := map[[2]int]int{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		// Spreadsheets write the empty rows below a table as commas
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		budget, err := budgetRow(row{columns: columns, record: record})
+		if err == nil {
+			err = budget.Validate()
+		}
+		if err == nil {
+			if first, ok := seen[[2]int{budget.Year, budget.Month}]; ok {
+				err = fmt.Errorf("same month as line %d", first)
+			}
+		}
+		if err != nil {
+			invalid = append(invalid, LineError{Line: line, Error: err.Error()})
+			continue
+		}
+		seen[[2]int{budget.Year, budget.Month}] = line
+		rows = append(rows, BudgetRow{Line: line, Budget: budget})
+	}
+
+	if len(invalid) > 0 {
+		return nil, &LinesError{Lines: invalid}
+	}
+	return rows, nil
+}
+
+// budgetRow reads the budget of a row, leaving the checks of its values to
+// its Validate
+func budgetRow(r row) (models.CreateBudgetLimitRequest, error) {
+	var budget models.CreateBudgetLimitRequest
+	month, err := strconv.Atoi(r.get("month"))
+	if err != nil {
+		return budget, fmt.Errorf("invalid month %q", r.get("month"))
+	}
+	year, err := strconv.Atoi(r.get("year"))
+	if err != nil {
+		return budget, fmt.Errorf("invalid year %q", r.get("year"))
+	}
+	amount, err := parseAmount(r.get("amount"))
+	if err != nil {
+		return budget, err
+	}
+	threshold, err := parseThreshold(r.get("threshold"))
+	if err != nil {
+		return budget, err
+	}
+	return models.CreateBudgetLimitRequest{
+		Month: month, Year: year, Amount: amount, NotificationThreshold: threshold,
+	}, nil
+}
+
+// parseThreshold parses thresholds like "0.8", "80%" or "" (the default)
+func parseThreshold(s string) (float64, error) {
+	percent := strings.HasSuffix(s, "%")
+	value := strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	if value == "" && !percent {
+		return 0, nil
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold %q", s)
+	}
+	if percent {
+		threshold /= 100
+	}
+	return threshold, nil
+}
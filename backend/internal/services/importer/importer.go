@@ -0,0 +1,235 @@
+// Package importer reads transaction exports from other budgeting apps and
+// maps them onto expected and actual expenses.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format (supported: ynab, mint)")
+	ErrInvalidCSV    = errors.New("invalid CSV export")
+)
+
+// Format identifies the app an export comes from
+type Format string
+
+const (
+	FormatYNAB Format = "ynab"
+	FormatMint Format = "mint"
+)
+
+// ParseFormat returns the Format for s (any case) or ErrUnknownFormat
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatYNAB, FormatMint:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Transaction is a single outflow read from an export
+type Transaction struct {
+	Date          time.Time `json:"date"`
+	Payee         string    `json:"payee"`
+	Category      string    `json:"category"`
+	CategoryGroup string    `json:"category_group,omitempty"`
+	Memo          string    `json:"memo,omitempty"`
+	Amount        float64   `json:"amount"`
+}
+
+// ParseResult holds the outflows of an export. Inflows, transfers and
+// zero-amount rows are not spending and are only counted in Skipped.
+type ParseResult struct {
+	Transactions []Transaction `json:"transactions"`
+	Skipped      int           `json:"skipped"`
+}
+
+// Parse reads a CSV export in the given format
+func Parse(format Format, r io.Reader) (*ParseResult, error) {
+	switch format {
+	case FormatYNAB:
+		return parseCSV(r, ynabRow, "Date", "Payee", "Category", "Outflow")
+	case FormatMint:
+		return parseCSV(r, mintRow, "Date", "Description", "Amount", "Transaction Type", "Category")
+	}
+	return nil, ErrUnknownFormat
+}
+
+// row gives access to a CSV record by header name
+type row struct {
+	columns map[string]int
+	record  []string
+}
+
+func (r row) get(name string) string {
+	i, ok := r.columns[strings.ToLower(name)]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// rowParser turns a row into a transaction; ok is false for rows to skip
+type rowParser func(r row) (tx Transaction, ok bool, err error)
+
+func parseCSV(r io.Reader, parse rowParser, required ...string) (*ParseResult, error) {
+	reader, columns, err := readHeader(r, required...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ParseResult{Transactions: []Transaction{}}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+
+		tx, ok, err := parse(row{columns: columns, record: record})
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCSV, line, err)
+		}
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		result.Transactions = append(result.Transactions, tx)
+	}
+
+	return result, nil
+}
+
+// readHeader reads the header row of a CSV and maps its lowercased column
+// names to their index, checking the required columns are there
+func readHeader(r io.Reader, required ...string) (*csv.Reader, map[string]int, error) {
+	// Exports written by Excel and YNAB start with a byte order mark, which
+	// would make the quoted first header invalid CSV
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: missing header row", ErrInvalidCSV)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[strings.ToLower(name)]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing %q column", ErrInvalidCSV, name)
+		}
+	}
+	return reader, columns, nil
+}
+
+// ynabRow reads a YNAB register export row. Outflows are spending; inflows
+// and transfers between accounts are skipped.
+func ynabRow(r row) (Transaction, bool, error) {
+	payee := r.get("Payee")
+	if strings.HasPrefix(payee, "Transfer : ") {
+		return Transaction{}, false, nil
+	}
+
+	outflow, err := parseAmount(r.get("Outflow"))
+	if err != nil {
+		return Transaction{}, false, err
+	}
+	if outflow <= 0 {
+		return Transaction{}, false, nil
+	}
+
+	date, err := parseDate(r.get("Date"))
+	if err != nil {
+		return Transaction{}, false, err
+	}
+
+	return Transaction{
+		Date:          date,
+		Payee:         payee,
+		Category:      r.get("Category"),
+		CategoryGroup: r.get("Category Group"),
+		Memo:          r.get("Memo"),
+		Amount:        outflow,
+	}, true, nil
+}
+
+// mintTransferCategories are Mint categories that move money between accounts
+var mintTransferCategories = map[string]bool{
+	"transfer":            true,
+	"credit card payment": true,
+}
+
+// mintRow reads a Mint transactions export row. Debits are spending; credits
+// and transfers are skipped.
+func mintRow(r row) (Transaction, bool, error) {
+	if !strings.EqualFold(r.get("Transaction Type"), "debit") ||
+		mintTransferCategories[strings.ToLower(r.get("Category"))] {
+		return Transaction{}, false, nil
+	}
+
+	amount, err := parseAmount(r.get("Amount"))
+	if err != nil {
+		return Transaction{}, false, err
+	}
+	if amount <= 0 {
+		return Transaction{}, false, nil
+	}
+
+	date, err := parseDate(r.get("Date"))
+	if err != nil {
+		return Transaction{}, false, err
+	}
+
+	return Transaction{
+		Date:     date,
+		Payee:    r.get("Description"),
+		Category: r.get("Category"),
+		Memo:     r.get("Notes"),
+		Amount:   amount,
+	}, true, nil
+}
+
+// dateLayouts are the date formats written by YNAB (depending on the
+// budget's settings) and Mint
+var dateLayouts = []string{"1/2/2006", "2006-01-02"}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (expected MM/DD/YYYY or YYYY-MM-DD)", s)
+}
+
+// parseAmount parses amounts like "1,234.56", "$12.00" or "" (zero)
+func parseAmount(s string) (float64, error) {
+	s = strings.NewReplacer("$", "", ",", "", " ", "").Replace(s)
+	if s == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
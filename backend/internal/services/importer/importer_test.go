@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"budget-tracker/internal/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const ynabExport = "\ufeff" + `"Account","Flag","Date","Payee","Category Group/Category","Category Group","Category","Memo","Outflow","Inflow","Cleared"
+"Checking","","10/01/2025","City Apartments","Bills: Rent","Bills","Rent","","$1,200.00","$0.00","Cleared"
+"Checking","","10/03/2025","FreshMart","Everyday: Groceries","Everyday","Groceries","Weekly shop","$85.40","$0.00","Cleared"
+"Checking","","10/05/2025","Employer","Inflow: Ready to Assign","Inflow","Ready to Assign","","$0.00","$3,000.00","Cleared"
+"Checking","","10/06/2025","Transfer : Savings","","","","","$500.00","$0.00","Cleared"
+"Checking","","11/01/2025","City Apartments","Bills: Rent","Bills","Rent","","$1,200.00","$0.00","Uncleared"
+`
+
+const mintExport = `"Date","Description","Original Description","Amount","Transaction Type","Category","Account Name","Labels","Notes"
+"10/02/2025","Shell","SHELL OIL 1234","45.10","debit","Gas & Fuel","Visa","",""
+"10/04/2025","Paycheck","ACME PAYROLL","2500.00","credit","Paycheck","Checking","",""
+"10/07/2025","Visa Payment","VISA AUTOPAY","300.00","debit","Credit Card Payment","Checking","",""
+"10/09/2025","Bookshop","BOOKSHOP 55","23.99","debit","Books","Visa","","Birthday gift"
+`
+
+func TestParse_YNAB(t *testing.T) {
+	result, err := Parse(FormatYNAB, strings.NewReader(ynabExport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(result.Transactions) != 3 || result.Skipped != 2 {
+		t.Fatalf("Expected 3 outflows and 2 skipped rows, got %d and %d",
+			len(result.Transactions), result.Skipped)
+	}
+	rent := result.Transactions[0]
+	if rent.Payee != "City Apartments" || rent.Category != "Rent" || rent.CategoryGroup != "Bills" ||
+		rent.Amount != 1200 || rent.Date.Format("2006-01-02") != "2025-10-01" {
+		t.Errorf("Unexpected rent transaction: %+v", rent)
+	}
+	if memo := result.Transactions[1].Memo; memo != "Weekly shop" {
+		t.Errorf("Expected memo %q, got %q", "Weekly shop", memo)
+	}
+}
+
+func TestParse_Mint(t *testing.T) {
+	result, err := Parse(FormatMint, strings.NewReader(mintExport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(result.Transactions) != 2 || result.Skipped != 2 {
+		t.Fatalf("Expected 2 debits and 2 skipped rows, got %d and %d",
+			len(result.Transactions), result.Skipped)
+	}
+	gas := result.Transactions[0]
+	if gas.Payee != "Shell" || gas.Category != "Gas & Fuel" || gas.Amount != 45.10 {
+		t.Errorf("Unexpected gas transaction: %+v", gas)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   error
+	}{
+		{"unknown format", Format("quicken"), ynabExport, ErrUnknownFormat},
+		{"empty file", FormatYNAB, "", ErrInvalidCSV},
+		{"mint file as ynab", FormatYNAB, mintExport, ErrInvalidCSV},
+		{
+			"invalid amount", FormatMint,
+			"Date,Description,Amount,Transaction Type,Category\n10/02/2025,Shell,abc,debit,Gas\n",
+			ErrInvalidCSV,
+		},
+		{
+			"invalid date", FormatMint,
+			"Date,Description,Amount,Transaction Type,Category\nOct 2,Shell,10,debit,Gas\n",
+			ErrInvalidCSV,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.format, strings.NewReader(tt.input))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSummarize_SuggestsMappings(t *testing.T) {
+	result, err := Parse(FormatYNAB, strings.NewReader(ynabExport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	summaries := Summarize(result.Transactions)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 categories, got %+v", summaries)
+	}
+	rent := summaries[0]
+	if rent.Category != "Rent" || rent.Count != 2 || rent.Total != 2400 ||
+		rent.ExpenseType != models.ExpenseTypeMonthly || !rent.CreateExpected {
+		t.Errorf("Unexpected rent summary: %+v", rent)
+	}
+	if groceries := summaries[1]; groceries.ExpenseType != models.ExpenseTypeWeekly || groceries.CreateExpected {
+		t.Errorf("Unexpected groceries summary: %+v", groceries)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	result, err := Parse(FormatYNAB, strings.NewReader(ynabExport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	groups, skipped, err := Plan(FormatYNAB, result.Transactions, []CategoryMapping{
+		{Category: "groceries", Skip: true},
+	})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if skipped != 1 || len(groups) != 1 {
+		t.Fatalf("Expected 1 group and 1 skipped transaction, got %d and %d", len(groups), skipped)
+	}
+
+	rent := groups[0]
+	if len(rent.Expenses) != 2 || rent.Expenses[0].ItemName != "Rent" ||
+		rent.Expenses[0].Source != "City Apartments" {
+		t.Errorf("Unexpected rent expenses: %+v", rent.Expenses)
+	}
+	if rent.Expected == nil || rent.Expected.ExpectedAmount != 1200 ||
+		rent.Expected.ExpenseType != models.ExpenseTypeMonthly {
+		t.Errorf("Expected a monthly expected expense of 1200, got %+v", rent.Expected)
+	}
+
+	_, _, err = Plan(FormatYNAB, result.Transactions, []CategoryMapping{
+		{Category: "Rent", ExpenseType: models.ExpenseTypeMisc, CreateExpected: true},
+	})
+	if !errors.Is(err, ErrInvalidMapping) {
+		t.Errorf("Expected ErrInvalidMapping for a misc expected expense, got %v", err)
+	}
+}
+
+func TestParseBudgets(t *testing.T) {
+	input := "\xef\xbb\xbfMonth,Year,Amount,Threshold\n" +
+		"1,2024,\"$1,200.00\",0.9\n" +
+		"2,2024,1100,75%\n" +
+		",,,\n" +
+		"3,2024,1000,\n"
+	rows, err := ParseBudgets(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseBudgets failed: %v", err)
+	}
+	want := []BudgetRow{
+		{Line: 2, Budget: models.CreateBudgetLimitRequest{Month: 1, Year: 2024, Amount: 1200, NotificationThreshold: 0.9}},
+		{Line: 3, Budget: models.CreateBudgetLimitRequest{Month: 2, Year: 2024, Amount: 1100, NotificationThreshold: 0.75}},
+		{Line: 5, Budget: models.CreateBudgetLimitRequest{
+			Month: 3, Year: 2024, Amount: 1000, NotificationThreshold: models.DefaultNotificationThreshold,
+		}},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d budgets, got %+v", len(want), rows)
+	}
+	for i := range want {
+		if rows[i].Line != want[i].Line || rows[i].Budget.Month != want[i].Budget.Month ||
+			rows[i].Budget.Amount != want[i].Budget.Amount ||
+			rows[i].Budget.NotificationThreshold != want[i].Budget.NotificationThreshold {
+			t.Errorf("Row %d: expected %+v, got %+v", i, want[i], rows[i])
+		}
+	}
+
+	// Every invalid line is reported, not just the first
+	_, err = ParseBudgets(strings.NewReader("month,year,amount\n13,2024,100\n1,2024,abc\n2,2024,100\n2,2024,200\n"))
+	var lines *LinesError
+	if !errors.As(err, &lines) || !errors.Is(err, ErrInvalidCSV) {
+		t.Fatalf("Expected a LinesError, got %v", err)
+	}
+	if got := lines.Lines; len(got) != 3 || got[0].Line != 2 || got[1].Line != 3 ||
+		got[2].Line != 5 || got[2].Error != "same month as line 4" {
+		t.Errorf("Unexpected line errors %+v", got)
+	}
+
+	if _, err := ParseBudgets(strings.NewReader("month,amount\n1,100\n")); !errors.Is(err, ErrInvalidCSV) {
+		t.Errorf("Expected a missing year column to fail, got %v", err)
+	}
+}
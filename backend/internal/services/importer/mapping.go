@@ -0,0 +1,264 @@
+package importer
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidMapping = errors.New("invalid category mapping")
+
+// CategoryMapping says how the transactions of one export category are imported
+type CategoryMapping struct {
+	Category    string             `json:"category"`
+	ExpenseType models.ExpenseType `json:"expense_type"`
+	// CreateExpected also adds an expected expense for the category, sized to
+	// its average spending and linked to the imported expenses
+	CreateExpected bool `json:"create_expected"`
+	Skip           bool `json:"skip,omitempty"`
+}
+
+// CategorySummary describes one export category for the mapping review,
+// with the suggested mapping
+type CategorySummary struct {
+	CategoryMapping
+	CategoryGroup string  `json:"category_group,omitempty"`
+	Count         int     `json:"count"`
+	Total         float64 `json:"total"`
+}
+
+// Keywords used to suggest an expense type from a category or category group
+var (
+	taxKeywords     = []string{"tax"}
+	monthlyKeywords = []string{
+		"rent", "mortgage", "utilit", "bill", "insurance", "subscription",
+		"internet", "phone", "mobile", "electric", "water", "loan",
+	}
+	weeklyKeywords = []string{
+		"grocer", "food", "dining", "restaurant", "coffee", "gas", "fuel",
+		"transport", "household",
+	}
+)
+
+// SuggestMapping proposes a mapping for an export category: taxes map to tax,
+// recurring bills to monthly (with an expected expense), everyday spending to
+// weekly and everything else to misc
+func SuggestMapping(category, group string) CategoryMapping {
+	name := strings.ToLower(category + " " + group)
+	matches := func(keywords []string) bool {
+		for _, k := range keywords {
+			if strings.Contains(name, k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	m := CategoryMapping{Category: category, ExpenseType: models.ExpenseTypeMisc}
+	switch {
+	case matches(taxKeywords):
+		m.ExpenseType = models.ExpenseTypeTax
+	case matches(monthlyKeywords):
+		m.ExpenseType = models.ExpenseTypeMonthly
+		m.CreateExpected = true
+	case matches(weeklyKeywords):
+		m.ExpenseType = models.ExpenseTypeWeekly
+	}
+	return m
+}
+
+// Summarize groups transactions by category, largest total first, with a
+// suggested mapping for each
+func Summarize(txs []Transaction) []CategorySummary {
+	byCategory := make(map[string]*CategorySummary)
+	var order []string
+	for _, tx := range txs {
+		key := strings.ToLower(tx.Category)
+		s, ok := byCategory[key]
+		if !ok {
+			s = &CategorySummary{
+				CategoryMapping: SuggestMapping(tx.Category, tx.CategoryGroup),
+				CategoryGroup:   tx.CategoryGroup,
+			}
+			byCategory[key] = s
+			order = append(order, key)
+		}
+		s.Count++
+		s.Total = roundCents(s.Total + tx.Amount)
+	}
+
+	summaries := make([]CategorySummary, 0, len(order))
+	for _, key := range order {
+		summaries = append(summaries, *byCategory[key])
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Total > summaries[j].Total
+	})
+	return summaries
+}
+
+// Validate checks that the mapping can be applied
+func (m *CategoryMapping) Validate() error {
+	if m.Skip {
+		return nil
+	}
+	if !m.ExpenseType.IsValid() {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidMapping, m.Category, models.ErrInvalidExpenseType)
+	}
+	if m.CreateExpected && m.ExpenseType != models.ExpenseTypeWeekly &&
+		m.ExpenseType != models.ExpenseTypeMonthly {
+		return fmt.Errorf(
+			"%w: %q: expected expenses must be weekly or monthly",
+			ErrInvalidMapping, m.Category,
+		)
+	}
+	return nil
+}
+
+// Plan applies the reviewed mappings to the transactions. Categories without
+// a mapping use SuggestMapping. It returns the groups to import and the number
+// of transactions left out by skipped categories.
+func Plan(format Format, txs []Transaction, mappings []CategoryMapping) ([]repository.ImportGroup, int, error) {
+	byCategory := make(map[string]CategoryMapping, len(mappings))
+	for _, m := range mappings {
+		if err := m.Validate(); err != nil {
+			return nil, 0, err
+		}
+		byCategory[strings.ToLower(m.Category)] = m
+	}
+
+	// One group per category keeps each category's expenses together and
+	// linked to its expected expense
+	groups := make(map[string]*repository.ImportGroup)
+	categoryTxs := make(map[string][]Transaction)
+	var order []string
+	skipped := 0
+	for _, tx := range txs {
+		key := strings.ToLower(tx.Category)
+		m, ok := byCategory[key]
+		if !ok {
+			m = SuggestMapping(tx.Category, tx.CategoryGroup)
+			byCategory[key] = m
+		}
+		if m.Skip {
+			skipped++
+			continue
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &repository.ImportGroup{}
+			groups[key] = g
+			order = append(order, key)
+		}
+
+		req := expenseRequest(format, tx, m.ExpenseType)
+		if err := req.Validate(); err != nil {
+			return nil, 0, fmt.Errorf("transaction on %s at %q: %w", tx.Date.Format("2006-01-02"), tx.Payee, err)
+		}
+		g.Expenses = append(g.Expenses, req)
+		categoryTxs[key] = append(categoryTxs[key], tx)
+	}
+
+	planned := make([]repository.ImportGroup, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		if m := byCategory[key]; m.CreateExpected {
+			g.Expected = expectedRequest(format, categoryTxs[key], m)
+		}
+		planned = append(planned, *g)
+	}
+	return planned, skipped, nil
+}
+
+// expenseRequest maps a transaction onto an actual expense: the payee is the
+// source and the memo (or the category) is the item
+func expenseRequest(format Format, tx Transaction, expenseType models.ExpenseType) models.CreateActualExpenseRequest {
+	itemName := tx.Memo
+	if itemName == "" {
+		itemName = tx.Category
+	}
+	if itemName == "" {
+		itemName = tx.Payee
+	}
+
+	date := tx.Date
+	lineNo := 1
+	return models.CreateActualExpenseRequest{
+		ItemName:     truncate(itemName, models.MaxItemNameLength),
+		Source:       truncate(sourceName(format, tx.Payee), models.MaxSourceLength),
+		ActualAmount: tx.Amount,
+		ExpenseType:  expenseType,
+		ReceiptDate:  &date,
+		LineNo:       &lineNo,
+	}
+}
+
+// expectedRequest sizes an expected expense from a category's average
+// spending per month (or per week for weekly expenses) and uses the most
+// frequent payee as its source
+func expectedRequest(format Format, txs []Transaction, m CategoryMapping) *models.CreateExpectedExpenseRequest {
+	months := make(map[[2]int]bool)
+	payees := make(map[string]int)
+	var total float64
+	topPayee := ""
+	for _, tx := range txs {
+		total += tx.Amount
+		months[[2]int{tx.Date.Year(), int(tx.Date.Month())}] = true
+		payees[tx.Payee]++
+		if payees[tx.Payee] > payees[topPayee] {
+			topPayee = tx.Payee
+		}
+	}
+
+	amount := total / float64(len(months))
+	if m.ExpenseType == models.ExpenseTypeWeekly {
+		amount = amount * 12 / 52
+	}
+
+	name := m.Category
+	if name == "" {
+		name = "Uncategorized"
+	}
+	return &models.CreateExpectedExpenseRequest{
+		ItemName:       truncate(name, models.MaxItemNameLength),
+		Source:         truncate(sourceName(format, topPayee), models.MaxExpectedSourceLength),
+		ExpectedAmount: roundCents(amount),
+		ExpenseType:    m.ExpenseType,
+		Category:       truncate(m.Category, models.MaxCategoryLength),
+	}
+}
+
+// sourceName falls back to the app's name for transactions without a payee
+func sourceName(format Format, payee string) string {
+	if payee != "" {
+		return payee
+	}
+	switch format {
+	case FormatYNAB:
+		return "YNAB import"
+	case FormatMint:
+		return "Mint import"
+	}
+	return "Import"
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	// Cut on a rune boundary
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
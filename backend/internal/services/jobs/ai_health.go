@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// AIHealthSchedule is the job's default cron schedule; every probe spends a
+// few tokens
+const AIHealthSchedule = "*/30 * * * *"
+
+// AIHealthJob probes the AI provider so an exhausted quota or a revoked key
+// is noticed before a receipt fails. The first failure of each kind is
+// reported as a notification to the admins, and every failing run is
+// recorded as the job's last error.
+type AIHealthJob struct {
+	monitor       *ai.HealthMonitor
+	users         *repository.UserRepository
+	notifications *repository.NotificationRepository
+	// notified is the failing status already reported, reset once healthy
+	notified string
+}
+
+// NewAIHealthJob creates a new AIHealthJob
+func NewAIHealthJob(
+	monitor *ai.HealthMonitor,
+	users *repository.UserRepository,
+	notifications *repository.NotificationRepository,
+) *AIHealthJob {
+	return &AIHealthJob{monitor: monitor, users: users, notifications: notifications}
+}
+
+func (j *AIHealthJob) Name() string {
+	return "ai-health"
+}
+
+func (j *AIHealthJob) Run(ctx context.Context, now time.Time) error {
+	health := j.monitor.Check(ctx)
+	if health.Healthy() {
+		j.notified = ""
+		return nil
+	}
+
+	if health.Status != ai.HealthError && health.Status != j.notified {
+		log.Printf("[Jobs] AI provider is %s: %s", health.Status, health.Message)
+		if err := j.notify(health); err != nil {
+			return err
+		}
+		j.notified = health.Status
+	}
+	return fmt.Errorf("AI provider is %s: %s", health.Status, health.Message)
+}
+
+// notify tells the admins receipt processing stopped working, or the shared
+// workspace when there are none, e.g. while authentication is disabled
+func (j *AIHealthJob) notify(health ai.ProviderHealth) error {
+	message := "The AI provider has no quota or credit left. Top it up to process receipts again."
+	if health.Status == ai.HealthUnauthorized {
+		message = "The AI provider rejected the API key. Update the key to process receipts again."
+	}
+	adminIDs, err := j.users.AdminIDs()
+	if err != nil {
+		return err
+	}
+	if len(adminIDs) == 0 {
+		adminIDs = []int64{0}
+	}
+	for _, userID := range adminIDs {
+		if _, err := j.notifications.ForUser(userID).Create(&models.Notification{
+			Kind:    models.NotificationAIUnavailable,
+			Title:   "Receipt processing is unavailable",
+			Message: message,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
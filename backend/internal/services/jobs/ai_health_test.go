@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeQuotaProvider answers quota probes with err
+type fakeQuotaProvider struct {
+	err error
+}
+
+func (p *fakeQuotaProvider) ProcessReceiptDocument(
+	context.Context, string, string, []string,
+) (*ai.ReceiptProcessingResult, error) {
+	return nil, p.err
+}
+
+func (p *fakeQuotaProvider) CheckQuota(context.Context) (*ai.Quota, error) {
+	return nil, p.err
+}
+
+func TestAIHealthJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	users := repository.NewUserRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+	provider := &fakeQuotaProvider{}
+	job := NewAIHealthJob(ai.NewHealthMonitor(provider), users, notifications)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	if err := job.Run(context.Background(), now); err != nil {
+		t.Fatalf("Expected a healthy provider to pass, got %v", err)
+	}
+
+	// Every failing run is an error, but users are notified once; without
+	// admins the shared workspace is
+	provider.err = ai.ErrQuotaExhausted
+	for range 2 {
+		if err := job.Run(context.Background(), now); err == nil {
+			t.Error("Expected an exhausted provider to fail the job")
+		}
+	}
+	stored, err := notifications.List(false)
+	if err != nil {
+		t.Fatalf("Failed to list notifications: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Kind != models.NotificationAIUnavailable {
+		t.Fatalf("Expected one notification, got %+v", stored)
+	}
+
+	// Once the credit is topped up, running out again is reported again
+	provider.err = nil
+	if err := job.Run(context.Background(), now); err != nil {
+		t.Errorf("Expected the provider to recover, got %v", err)
+	}
+	provider.err = ai.ErrQuotaExhausted
+	job.Run(context.Background(), now)
+	if stored, _ := notifications.List(false); len(stored) != 2 {
+		t.Errorf("Expected a second notification, got %d", len(stored))
+	}
+
+	// Once there is an admin, only the admin is notified
+	admin, err := users.Create("admin@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if err := users.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("Failed to grant admin role: %v", err)
+	}
+	provider.err = nil
+	job.Run(context.Background(), now)
+	provider.err = ai.ErrQuotaExhausted
+	job.Run(context.Background(), now)
+	if stored, _ := notifications.ForUser(admin.ID).List(false); len(stored) != 1 {
+		t.Errorf("Expected the admin to be notified once, got %d", len(stored))
+	}
+	if stored, _ := notifications.List(false); len(stored) != 2 {
+		t.Errorf("Expected no new notification in the shared workspace, got %d", len(stored))
+	}
+}
@@ -0,0 +1,261 @@
+package jobs
+
+import (
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// BudgetThresholdSchedule is the job's default cron schedule
+const BudgetThresholdSchedule = "@hourly"
+
+// DefaultHysteresis is how many percentage points spending must fall below a
+// crossed threshold before it counts as back on track
+const DefaultHysteresis = 5
+
+// DangerAlertRepeat is how often the danger alert repeats while spending
+// stays at danger
+const DangerAlertRepeat = 24 * time.Hour
+
+// BudgetThresholdJob alerts when the spending of the current month crosses a
+// notification threshold of its budget, once per crossing. When spending
+// falls back below a crossed threshold, e.g. after a refund, it sends a back
+// on track notification and resets the threshold, so crossing it again
+// alerts again. Past the thresholds alerts escalate: danger, from 90% of the
+// budget, repeats daily and over, past it, alerts at once, in place of a
+// threshold alert. Months without a budget of their own are not tracked.
+type BudgetThresholdJob struct {
+	budgets       *repository.BudgetRepository
+	notifications *repository.NotificationRepository
+	hysteresis    float64
+}
+
+// NewBudgetThresholdJob creates a new BudgetThresholdJob
+func NewBudgetThresholdJob(
+	budgets *repository.BudgetRepository,
+	notifications *repository.NotificationRepository,
+	hysteresis float64,
+) *BudgetThresholdJob {
+	return &BudgetThresholdJob{budgets: budgets, notifications: notifications, hysteresis: hysteresis}
+}
+
+func (j *BudgetThresholdJob) Name() string {
+	return "budget-thresholds"
+}
+
+func (j *BudgetThresholdJob) Run(ctx context.Context, now time.Time) error {
+	userIDs, err := j.budgets.UserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// A failing user must not hold back the alerts of the others
+		if err := j.checkThresholds(userID, now); err != nil {
+			log.Printf("[Jobs] %s failed for user %d: %v", j.Name(), userID, err)
+		}
+	}
+	return nil
+}
+
+// SubscribeTo checks the budget of the current month as soon as it or the
+// month's spending changes on bus, e.g. the budget is lowered below its
+// spending or an expense crosses a threshold, rather than at the next run.
+// The check runs in the background.
+func (j *BudgetThresholdJob) SubscribeTo(bus *events.Bus) {
+	bus.Subscribe(func(e events.Event) {
+		var month, year int
+		switch {
+		case e.Budget != nil:
+			month, year = e.Budget.Month, e.Budget.Year
+		case e.Expense != nil:
+			month, year = e.Expense.Month, e.Expense.Year
+		default:
+			return
+		}
+		now := time.Now()
+		if month != int(now.Month()) || year != now.Year() {
+			return
+		}
+		go func() {
+			if err := j.checkThresholds(e.UserID, now); err != nil {
+				log.Printf("[Jobs] %s failed for user %d after %s: %v", j.Name(), e.UserID, e.Type, err)
+			}
+		}()
+	}, events.BudgetCreated, events.BudgetUpdated, events.ExpenseCreated, events.ExpenseUpdated, events.ExpenseDeleted)
+}
+
+// checkThresholds alerts userID to the thresholds of the month's budget
+// crossed or recovered since the last run
+func (j *BudgetThresholdJob) checkThresholds(userID int64, now time.Time) error {
+	budgets := j.budgets.ForUser(userID)
+	budget, err := budgets.GetByMonthYear(int(now.Month()), now.Year())
+	if errors.Is(err, repository.ErrBudgetNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	percentageUsed, err := budgets.PercentageUsed(budget)
+	if err != nil {
+		return err
+	}
+	fired, err := budgets.FiredThresholds(budget.ID)
+	if err != nil {
+		return err
+	}
+
+	crossed, recovered, stale := budget.ThresholdChanges(percentageUsed, fired, j.hysteresis)
+	for _, threshold := range stale {
+		if _, err := budgets.ClearThresholdAlert(budget.ID, threshold); err != nil {
+			return err
+		}
+	}
+
+	var newlyCrossed []float64
+	for _, threshold := range crossed {
+		recorded, err := budgets.RecordThresholdAlert(budget.ID, threshold)
+		if err != nil {
+			return err
+		}
+		if recorded {
+			newlyCrossed = append(newlyCrossed, threshold)
+		}
+	}
+	level, err := j.escalate(budgets, budget, percentageUsed, now)
+	if err != nil {
+		return err
+	}
+	switch {
+	case level != "":
+		// The escalated alert stands for the thresholds crossed with it
+		if err := j.notifyEscalated(userID, budget, level, percentageUsed); err != nil {
+			return err
+		}
+	case len(newlyCrossed) > 0:
+		// One alert for the highest threshold reached
+		if err := j.notifyCrossed(userID, budget, newlyCrossed[len(newlyCrossed)-1], percentageUsed); err != nil {
+			return err
+		}
+	}
+
+	var newlyRecovered []float64
+	for _, threshold := range recovered {
+		cleared, err := budgets.ClearThresholdAlert(budget.ID, threshold)
+		if err != nil {
+			return err
+		}
+		if cleared {
+			newlyRecovered = append(newlyRecovered, threshold)
+		}
+	}
+	if len(newlyRecovered) > 0 {
+		// Back below the lowest threshold that was recovered
+		return j.notifyRecovered(userID, budget, newlyRecovered[0], percentageUsed)
+	}
+	return nil
+}
+
+// escalate records the escalation level the budget reached at
+// percentageUsed, clearing the levels spending fell back below, and returns
+// the level to alert, or "" when no alert is due. Danger repeats every
+// DangerAlertRepeat, over alerts once per crossing.
+func (j *BudgetThresholdJob) escalate(
+	budgets *repository.BudgetRepository,
+	budget *models.BudgetLimit,
+	percentageUsed float64,
+	now time.Time,
+) (string, error) {
+	for _, level := range []string{models.AlertLevelDanger, models.AlertLevelOver} {
+		if percentageUsed < models.AlertLevelPercentages[level]-j.hysteresis {
+			if _, err := budgets.ClearAlertEscalation(budget.ID, level); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	level := models.AlertLevel(percentageUsed)
+	var repeat time.Duration
+	switch level {
+	case "":
+		return "", nil
+	case models.AlertLevelDanger:
+		repeat = DangerAlertRepeat
+	}
+	due, err := budgets.RecordAlertEscalation(budget.ID, level, now, repeat)
+	if err != nil || !due {
+		return "", err
+	}
+	return level, nil
+}
+
+func (j *BudgetThresholdJob) notifyEscalated(
+	userID int64,
+	budget *models.BudgetLimit,
+	level string,
+	percentageUsed float64,
+) error {
+	month := fmt.Sprintf("%s %d", time.Month(budget.Month), budget.Year)
+	log.Printf("[Jobs] Budget %d at %s with %.0f%% in %s", budget.ID, level, percentageUsed, month)
+
+	n := &models.Notification{
+		Kind:    models.NotificationBudgetDanger,
+		Title:   fmt.Sprintf("The %s budget is almost used up", month),
+		Message: fmt.Sprintf("Spending in %s has used %.0f%% of the budget.", month, percentageUsed),
+		Link:    "/budgets",
+	}
+	if level == models.AlertLevelOver {
+		n.Kind = models.NotificationBudgetOver
+		n.Title = fmt.Sprintf("The %s budget is exceeded", month)
+		n.Message = fmt.Sprintf("Spending in %s has used %.0f%% of the budget, past its limit.", month, percentageUsed)
+	}
+	_, err := j.notifications.ForUser(userID).Create(n)
+	return err
+}
+
+func (j *BudgetThresholdJob) notifyCrossed(
+	userID int64,
+	budget *models.BudgetLimit,
+	threshold, percentageUsed float64,
+) error {
+	month := fmt.Sprintf("%s %d", time.Month(budget.Month), budget.Year)
+	log.Printf("[Jobs] Budget %d crossed %.0f%% in %s", budget.ID, threshold*100, month)
+
+	_, err := j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:  models.NotificationBudgetThreshold,
+		Title: fmt.Sprintf("%.0f%% of the %s budget reached", threshold*100, month),
+		Message: fmt.Sprintf(
+			"Spending in %s has used %.0f%% of the budget, past the %.0f%% threshold.",
+			month, percentageUsed, threshold*100,
+		),
+		Link: "/budgets",
+	})
+	return err
+}
+
+func (j *BudgetThresholdJob) notifyRecovered(
+	userID int64,
+	budget *models.BudgetLimit,
+	threshold, percentageUsed float64,
+) error {
+	month := fmt.Sprintf("%s %d", time.Month(budget.Month), budget.Year)
+	log.Printf("[Jobs] Budget %d back under %.0f%% in %s", budget.ID, threshold*100, month)
+
+	_, err := j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:  models.NotificationBudgetRecovered,
+		Title: fmt.Sprintf("Back on track with the %s budget", month),
+		Message: fmt.Sprintf(
+			"Spending in %s is back to %.0f%% of the budget, under the %.0f%% threshold.",
+			month, percentageUsed, threshold*100,
+		),
+		Link: "/budgets",
+	})
+	return err
+}
@@ -0,0 +1,237 @@
+package jobs
+
+import (
+	"budget-tracker/internal/events"
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBudgetThresholdJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	budgets := repository.NewBudgetRepository(db)
+	expenses := repository.NewActualExpenseRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+
+	if _, err := budgets.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 1000, NotificationThresholds: []float64{0.5, 0.8},
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	spend := func(amount float64) *models.ActualExpense {
+		t.Helper()
+		date := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+		expense, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ActualAmount: amount,
+			ExpenseType: models.ExpenseTypeMonthly, ReceiptDate: &date,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		return expense
+	}
+
+	job := NewBudgetThresholdJob(budgets, notifications, DefaultHysteresis)
+	now := time.Date(2025, 3, 20, 9, 0, 0, 0, time.UTC)
+	run := func() []models.Notification {
+		t.Helper()
+		if err := job.Run(context.Background(), now); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		stored, err := notifications.List(false)
+		if err != nil {
+			t.Fatalf("Failed to list notifications: %v", err)
+		}
+		return stored
+	}
+
+	correct := func(expense *models.ActualExpense, amount float64) {
+		t.Helper()
+		if _, err := expenses.Update(expense.ID, &models.UpdateActualExpenseRequest{ActualAmount: &amount}); err != nil {
+			t.Fatalf("Failed to update expense: %v", err)
+		}
+	}
+
+	// Crossing both thresholds at once alerts once, for the highest
+	spend(500)
+	groceries := spend(350)
+	stored := run()
+	if len(stored) != 1 || stored[0].Kind != models.NotificationBudgetThreshold ||
+		stored[0].Title != "80% of the March 2025 budget reached" {
+		t.Fatalf("Expected one alert at 80%%, got %+v", stored)
+	}
+	if stored = run(); len(stored) != 1 {
+		t.Fatalf("Expected no alert while nothing changed, got %+v", stored)
+	}
+
+	// Falling to 78% stays within the hysteresis of the 80% threshold
+	correct(groceries, 280)
+	if stored = run(); len(stored) != 1 {
+		t.Fatalf("Expected no recovery within the hysteresis, got %+v", stored)
+	}
+
+	// A correction to 60% is back on track for 80%, not for 50%
+	correct(groceries, 100)
+	stored = run()
+	if len(stored) != 2 || stored[0].Kind != models.NotificationBudgetRecovered {
+		t.Fatalf("Expected a back on track notification, got %+v", stored)
+	}
+	budget, _ := budgets.GetByMonthYear(3, 2025)
+	if fired, _ := budgets.FiredThresholds(budget.ID); len(fired) != 1 || fired[0] != 0.5 {
+		t.Errorf("Expected only 50%% to stay fired, got %v", fired)
+	}
+
+	// Crossing 80% again alerts again
+	correct(groceries, 350)
+	if stored = run(); len(stored) != 3 || stored[0].Kind != models.NotificationBudgetThreshold {
+		t.Fatalf("Expected a new alert after the reset, got %+v", stored)
+	}
+}
+
+func TestBudgetThresholdJob_Escalation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	budgets := repository.NewBudgetRepository(db)
+	expenses := repository.NewActualExpenseRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+
+	if _, err := budgets.Create(&models.CreateBudgetLimitRequest{
+		Month: 3, Year: 2025, Amount: 1000, NotificationThreshold: 0.8,
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+	spend := func(amount float64) *models.ActualExpense {
+		t.Helper()
+		date := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+		expense, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ActualAmount: amount,
+			ExpenseType: models.ExpenseTypeMonthly, ReceiptDate: &date,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		return expense
+	}
+
+	job := NewBudgetThresholdJob(budgets, notifications, DefaultHysteresis)
+	run := func(now time.Time) []models.Notification {
+		t.Helper()
+		if err := job.Run(context.Background(), now); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		stored, err := notifications.List(false)
+		if err != nil {
+			t.Fatalf("Failed to list notifications: %v", err)
+		}
+		return stored
+	}
+	now := time.Date(2025, 3, 20, 9, 0, 0, 0, time.UTC)
+
+	// Danger stands for the threshold crossed with it
+	spend(600)
+	groceries := spend(320)
+	stored := run(now)
+	if len(stored) != 1 || stored[0].Kind != models.NotificationBudgetDanger {
+		t.Fatalf("Expected one danger alert, got %+v", stored)
+	}
+
+	// and repeats daily while spending stays there
+	if stored = run(now.Add(time.Hour)); len(stored) != 1 {
+		t.Fatalf("Expected no alert within the day, got %+v", stored)
+	}
+	now = now.Add(DangerAlertRepeat)
+	if stored = run(now); len(stored) != 2 || stored[0].Kind != models.NotificationBudgetDanger {
+		t.Fatalf("Expected the danger alert again the next day, got %+v", stored)
+	}
+
+	// Going over alerts at once, and only once
+	spend(100)
+	if stored = run(now.Add(time.Hour)); len(stored) != 3 || stored[0].Kind != models.NotificationBudgetOver ||
+		stored[0].Title != "The March 2025 budget is exceeded" {
+		t.Fatalf("Expected an over alert, got %+v", stored)
+	}
+	now = now.Add(DangerAlertRepeat)
+	if stored = run(now); len(stored) != 3 {
+		t.Fatalf("Expected no alert while over, got %+v", stored)
+	}
+
+	// Falling back under the hysteresis resets the levels
+	amount := 0.0
+	if _, err := expenses.Update(groceries.ID, &models.UpdateActualExpenseRequest{ActualAmount: &amount}); err != nil {
+		t.Fatalf("Failed to update expense: %v", err)
+	}
+	if stored = run(now.Add(time.Hour)); len(stored) != 4 || stored[0].Kind != models.NotificationBudgetRecovered {
+		t.Fatalf("Expected a back on track notification, got %+v", stored)
+	}
+	spend(420)
+	if stored = run(now.Add(2 * time.Hour)); len(stored) != 5 || stored[0].Kind != models.NotificationBudgetOver {
+		t.Fatalf("Expected an over alert after the reset, got %+v", stored)
+	}
+}
+
+func TestBudgetThresholdJob_SubscribeTo(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	bus := events.NewBus()
+	budgets := repository.NewBudgetRepository(db)
+	budgets.PublishTo(bus)
+	expenses := repository.NewActualExpenseRepository(db)
+	expenses.PublishTo(bus)
+	crossings := make(chan events.Event, 1)
+	bus.Subscribe(func(e events.Event) { crossings <- e }, events.BudgetThresholdCrossed)
+	NewBudgetThresholdJob(budgets, repository.NewNotificationRepository(db), DefaultHysteresis).SubscribeTo(bus)
+
+	now := time.Now()
+	createBudget := func(budgets *repository.BudgetRepository) *models.BudgetLimit {
+		t.Helper()
+		budget, err := budgets.Create(&models.CreateBudgetLimitRequest{
+			Month: int(now.Month()), Year: now.Year(), Amount: 1000, NotificationThreshold: 0.8,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+		return budget
+	}
+	spend := func(userID int64, amount float64) {
+		t.Helper()
+		if _, err := expenses.ForUser(userID).Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ActualAmount: amount,
+			ExpenseType: models.ExpenseTypeMonthly, ReceiptDate: &now,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+	expectCrossing := func(userID int64, budget *models.BudgetLimit) {
+		t.Helper()
+		select {
+		case e := <-crossings:
+			if e.UserID != userID || e.Budget == nil || e.Budget.ID != budget.ID || e.Threshold != 0.8 {
+				t.Errorf("Unexpected crossing %+v", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the threshold crossing of user %d", userID)
+		}
+	}
+
+	// Lowering the budget below the spending alerts without waiting for a run
+	lowered := createBudget(budgets.ForUser(1))
+	spend(1, 500)
+	amount := 550.0
+	if _, err := budgets.ForUser(1).Update(lowered.ID, &models.UpdateBudgetLimitRequest{Amount: &amount}); err != nil {
+		t.Fatalf("Failed to update budget: %v", err)
+	}
+	expectCrossing(1, lowered)
+
+	// So does spending past a threshold. The budget is created unpublished,
+	// so only the expenses can trigger the check.
+	spent := createBudget(repository.NewBudgetRepository(db).ForUser(2))
+	spend(2, 500)
+	spend(2, 350)
+	expectCrossing(2, spent)
+}
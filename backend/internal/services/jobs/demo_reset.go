@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DemoResetSchedule is the job's default cron schedule
+const DemoResetSchedule = "@hourly"
+
+// DemoResetter resets the demo database to its dataset
+type DemoResetter interface {
+	Reset(now time.Time) error
+}
+
+// DemoResetJob resets the demo database, undoing whatever visitors of a
+// public demo instance changed. It only runs in demo mode.
+type DemoResetJob struct {
+	resetter DemoResetter
+}
+
+// NewDemoResetJob creates a new DemoResetJob
+func NewDemoResetJob(resetter DemoResetter) *DemoResetJob {
+	return &DemoResetJob{resetter: resetter}
+}
+
+func (j *DemoResetJob) Name() string {
+	return "demo-reset"
+}
+
+func (j *DemoResetJob) Run(ctx context.Context, now time.Time) error {
+	if err := j.resetter.Reset(now); err != nil {
+		return err
+	}
+	log.Println("[Jobs] Reset the demo data")
+	return nil
+}
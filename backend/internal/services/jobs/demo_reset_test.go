@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeResetter records the time of the last reset and fails with err
+type fakeResetter struct {
+	resetAt time.Time
+	err     error
+}
+
+func (r *fakeResetter) Reset(now time.Time) error {
+	r.resetAt = now
+	return r.err
+}
+
+func TestDemoResetJob(t *testing.T) {
+	resetter := &fakeResetter{}
+	job := NewDemoResetJob(resetter)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	if err := job.Run(context.Background(), now); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resetter.resetAt.Equal(now) {
+		t.Errorf("Expected a reset at %s, got %s", now, resetter.resetAt)
+	}
+
+	resetter.err = errors.New("database is locked")
+	if err := job.Run(context.Background(), now); !errors.Is(err, resetter.err) {
+		t.Errorf("Expected the reset error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ExpenseEnrichmentSchedule is the job's default cron schedule
+const ExpenseEnrichmentSchedule = "@daily"
+
+// Limits of one run, per user
+const (
+	// EnrichmentBatchSize is how many expenses are sent to the AI at most
+	EnrichmentBatchSize = 20
+	// EnrichmentHistorySize is how many recent expenses are sent as context
+	EnrichmentHistorySize = 50
+)
+
+// ExpenseEnrichmentJob asks the AI for a readable name and expense type for
+// the expenses entered tersely, e.g. "AMZN MKTP 43.12", using the user's
+// recent expenses as context. The suggestions are stored for the user to
+// accept or dismiss, and each expense is asked about once.
+type ExpenseEnrichmentJob struct {
+	suggestions   *repository.ExpenseSuggestionRepository
+	notifications *repository.NotificationRepository
+	enricher      ai.ExpenseEnricher
+}
+
+// NewExpenseEnrichmentJob creates a new ExpenseEnrichmentJob
+func NewExpenseEnrichmentJob(
+	suggestions *repository.ExpenseSuggestionRepository,
+	notifications *repository.NotificationRepository,
+	enricher ai.ExpenseEnricher,
+) *ExpenseEnrichmentJob {
+	return &ExpenseEnrichmentJob{suggestions: suggestions, notifications: notifications, enricher: enricher}
+}
+
+func (j *ExpenseEnrichmentJob) Name() string {
+	return "expense-enrichment"
+}
+
+func (j *ExpenseEnrichmentJob) Run(ctx context.Context, now time.Time) error {
+	userIDs, err := j.suggestions.UserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := j.enrich(ctx, userID); err != nil {
+			return fmt.Errorf("user %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+// enrich asks about userID's terse expenses not asked about yet
+func (j *ExpenseEnrichmentJob) enrich(ctx context.Context, userID int64) error {
+	suggestions := j.suggestions.ForUser(userID)
+	candidates, err := suggestions.Candidates(EnrichmentBatchSize)
+	if err != nil || len(candidates) == 0 {
+		return err
+	}
+	history, err := suggestions.History(EnrichmentHistorySize)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]ai.EnrichmentEntry, len(candidates))
+	for i, expense := range candidates {
+		entries[i] = ai.EnrichmentEntry{
+			ID:     expense.ID,
+			Name:   expense.ItemName,
+			Source: expense.Source,
+			Amount: expense.ActualAmount,
+		}
+	}
+	results, err := j.enricher.EnrichExpenses(ctx, entries, history)
+	if err != nil {
+		return err
+	}
+
+	suggested := make(map[int64]ai.EnrichmentSuggestion, len(results))
+	for _, s := range results {
+		suggested[s.ID] = s
+	}
+	added := 0
+	for _, expense := range candidates {
+		s, ok := suggested[expense.ID]
+		if !ok {
+			if _, err := suggestions.Record(expense.ID, "", "", models.SuggestionNone); err != nil {
+				return err
+			}
+			continue
+		}
+		recorded, err := suggestions.Record(
+			expense.ID, s.ItemName, models.NormalizeExpenseType(s.ItemType), models.SuggestionPending,
+		)
+		if err != nil {
+			return err
+		}
+		if recorded {
+			added++
+		}
+	}
+	if added == 0 {
+		return nil
+	}
+	return j.notify(userID, added)
+}
+
+func (j *ExpenseEnrichmentJob) notify(userID int64, added int) error {
+	log.Printf("[Jobs] Suggested names for %d expense(s) of user %d", added, userID)
+
+	_, err := j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:    models.NotificationExpenseSuggestions,
+		Title:   "Suggested names for your expenses",
+		Message: fmt.Sprintf("%d expense(s) entered tersely have a suggested name and type to review.", added),
+		Link:    "/actual-expenses/suggestions",
+	})
+	return err
+}
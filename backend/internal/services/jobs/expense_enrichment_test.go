@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnricher suggests a name for the entries in names and records what it
+// was asked
+type fakeEnricher struct {
+	names   map[string]string
+	asked   [][]ai.EnrichmentEntry
+	history []string
+}
+
+func (f *fakeEnricher) EnrichExpenses(
+	ctx context.Context,
+	entries []ai.EnrichmentEntry,
+	history []string,
+) ([]ai.EnrichmentSuggestion, error) {
+	f.asked = append(f.asked, entries)
+	f.history = history
+	var suggestions []ai.EnrichmentSuggestion
+	for _, entry := range entries {
+		if name, ok := f.names[entry.Name]; ok {
+			suggestions = append(suggestions, ai.EnrichmentSuggestion{ID: entry.ID, ItemName: name, ItemType: "weekly"})
+		}
+	}
+	return suggestions, nil
+}
+
+func TestExpenseEnrichmentJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expenses := repository.NewActualExpenseRepository(db)
+	suggestions := repository.NewExpenseSuggestionRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+
+	add := func(name string, lineNo *int) *models.ActualExpense {
+		t.Helper()
+		expense, err := expenses.Create(&models.CreateActualExpenseRequest{
+			ItemName: name, Source: "Card", ActualAmount: 43.12,
+			ExpenseType: models.ExpenseTypeMisc, LineNo: lineNo,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+		return expense
+	}
+	line := 1
+	add("Oat milk", nil)
+	amazon := add("AMZN MKTP 43.12", nil)
+	add("POS 0042 XQZ", nil)
+	// Receipt items were named by the receipt processing
+	add("BB GARLIC HOT", &line)
+
+	enricher := &fakeEnricher{names: map[string]string{"AMZN MKTP 43.12": "Amazon order"}}
+	job := NewExpenseEnrichmentJob(suggestions, notifications, enricher)
+	if err := job.Run(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(enricher.asked) != 1 || len(enricher.asked[0]) != 2 {
+		t.Fatalf("Expected the two terse manual entries asked about, got %+v", enricher.asked)
+	}
+	if len(enricher.history) != 1 || !strings.HasPrefix(enricher.history[0], "Oat milk at Card") {
+		t.Errorf("Expected the readable expenses as history, got %v", enricher.history)
+	}
+
+	pending, err := suggestions.List()
+	if err != nil {
+		t.Fatalf("Failed to list suggestions: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("Expected one pending suggestion, got %+v", pending)
+	}
+	if s := pending[0]; s.ActualExpenseID != amazon.ID || s.ItemName != "Amazon order" ||
+		s.CurrentName != "AMZN MKTP 43.12" || s.ExpenseType != models.ExpenseTypeWeekly {
+		t.Errorf("Unexpected suggestion: %+v", s)
+	}
+
+	stored, err := notifications.List(false)
+	if err != nil {
+		t.Fatalf("Failed to list notifications: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Kind != models.NotificationExpenseSuggestions {
+		t.Errorf("Expected one suggestions notification, got %+v", stored)
+	}
+
+	// Every expense is asked about once, including the one without a suggestion
+	if err := job.Run(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(enricher.asked) != 1 {
+		t.Errorf("Expected no second request, got %d", len(enricher.asked))
+	}
+}
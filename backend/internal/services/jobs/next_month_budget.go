@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultLeadDays is how many days before the end of the month the next
+// month's budget is created
+const DefaultLeadDays = 3
+
+// NextMonthBudgetSchedule is the job's default cron schedule
+const NextMonthBudgetSchedule = "@hourly"
+
+// NextMonthBudgetJob rolls budgets over from one month to the next. It
+// creates next month's budget leadDays before the end of the month, and on
+// the first of the month creates the month's budget if that was missed, e.g.
+// with leadDays 0 or while the server was down. A budget is copied from the
+// month before or, if there is none, from the default budget setting. It runs
+// for every user. Months that already have a budget are left alone, and
+// nothing is created when there is neither a budget to copy nor a default.
+type NextMonthBudgetJob struct {
+	budgets       *repository.BudgetRepository
+	settings      *repository.SettingsRepository
+	notifications *repository.NotificationRepository
+	leadDays      int
+}
+
+// NewNextMonthBudgetJob creates a new NextMonthBudgetJob; leadDays 0 only
+// creates budgets on the first of the month and a negative leadDays uses
+// DefaultLeadDays
+func NewNextMonthBudgetJob(
+	budgets *repository.BudgetRepository,
+	settings *repository.SettingsRepository,
+	notifications *repository.NotificationRepository,
+	leadDays int,
+) *NextMonthBudgetJob {
+	if leadDays < 0 {
+		leadDays = DefaultLeadDays
+	}
+	return &NextMonthBudgetJob{
+		budgets:       budgets,
+		settings:      settings,
+		notifications: notifications,
+		leadDays:      leadDays,
+	}
+}
+
+func (j *NextMonthBudgetJob) Name() string {
+	return "next-month-budget"
+}
+
+func (j *NextMonthBudgetJob) Run(ctx context.Context, now time.Time) error {
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	next := current.AddDate(0, 1, 0)
+	rollover := now.Day() == 1
+	early := j.leadDays > 0 && !now.AddDate(0, 0, j.leadDays).Before(next)
+	if !rollover && !early {
+		return nil
+	}
+
+	userIDs, err := j.budgets.UserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rollover {
+			if err := j.createBudget(userID, current.AddDate(0, -1, 0), current); err != nil {
+				return fmt.Errorf("user %d: %w", userID, err)
+			}
+		}
+		if early {
+			if err := j.createBudget(userID, current, next); err != nil {
+				return fmt.Errorf("user %d: %w", userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createBudget creates userID's budget for the month starting at target,
+// copied from the month starting at source, and notifies userID
+func (j *NextMonthBudgetJob) createBudget(userID int64, source, target time.Time) error {
+	budgets := j.budgets.ForUser(userID)
+	month, year := int(target.Month()), target.Year()
+
+	if _, err := budgets.GetByMonthYear(month, year); err == nil {
+		return nil
+	} else if !errors.Is(err, repository.ErrBudgetNotFound) {
+		return err
+	}
+
+	req, origin, err := j.template(budgets, source)
+	if err != nil || req == nil {
+		return err
+	}
+	req.Month, req.Year = month, year
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("invalid budget template: %w", err)
+	}
+
+	budget, err := budgets.Create(req)
+	if errors.Is(err, repository.ErrBudgetExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[Jobs] Created budget for %s %d from %s", target.Month(), year, origin)
+
+	_, err = j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:  models.NotificationBudgetCreated,
+		Title: fmt.Sprintf("Budget for %s %d created", target.Month(), year),
+		Message: fmt.Sprintf(
+			"A budget of $%.2f was created from %s. Adjust it if %s needs a different amount.",
+			budget.Amount, origin, target.Month(),
+		),
+		Link: fmt.Sprintf("/budget?month=%d&year=%d", month, year),
+	})
+	return err
+}
+
+// template returns the budget of the month starting at source, or the
+// default budget, to copy and a description of where it came from, or nil
+// when there is nothing to copy
+func (j *NextMonthBudgetJob) template(
+	budgets *repository.BudgetRepository,
+	source time.Time,
+) (*models.CreateBudgetLimitRequest, string, error) {
+	previous, err := budgets.GetByMonthYear(int(source.Month()), source.Year())
+	if err == nil {
+		return &models.CreateBudgetLimitRequest{
+			Amount:                 previous.Amount,
+			NotificationThreshold:  previous.NotificationThreshold,
+			NotificationThresholds: previous.NotificationThresholds,
+			RolloverUnspent:        previous.RolloverUnspent,
+			WeeklyLimit:            previous.WeeklyLimit,
+		}, fmt.Sprintf("the %s %d budget", source.Month(), source.Year()), nil
+	}
+	if !errors.Is(err, repository.ErrBudgetNotFound) {
+		return nil, "", err
+	}
+
+	defaultBudget, err := j.settings.GetDefaultBudget()
+	if errors.Is(err, repository.ErrSettingNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &models.CreateBudgetLimitRequest{
+		Amount:                defaultBudget.Amount,
+		NotificationThreshold: defaultBudget.NotificationThreshold,
+	}, "the default budget", nil
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/tursodatabase/go-libsql"
+)
+
+// setupTestDB creates a migrated in-memory database unique to the test
+func setupTestDB(t *testing.T) *repository.DB {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	sqlDB, err := sql.Open("libsql", fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+
+	db := &repository.DB{DB: sqlDB}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func TestNextMonthBudgetJob(t *testing.T) {
+	endOfMonth := time.Date(2025, 10, 30, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		current       float64 // budget for the month of now, 0 for none
+		next          float64 // existing budget for the next month, 0 for none
+		defaultBudget float64 // default budget setting, 0 for none
+		wantAmount    float64 // expected next month budget, 0 for none
+		wantNotified  bool
+	}{
+		{name: "too early in the month", now: time.Date(2025, 10, 20, 9, 0, 0, 0, time.UTC), current: 1000},
+		{name: "copies current budget", now: endOfMonth, current: 1000, defaultBudget: 500, wantAmount: 1000, wantNotified: true},
+		{name: "falls back to default", now: endOfMonth, defaultBudget: 500, wantAmount: 500, wantNotified: true},
+		{name: "nothing to copy", now: endOfMonth},
+		{name: "next month already set", now: endOfMonth, current: 1000, next: 1200, wantAmount: 1200},
+		{name: "december rolls over", now: time.Date(2025, 12, 31, 9, 0, 0, 0, time.UTC), current: 800, wantAmount: 800, wantNotified: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			budgets := repository.NewBudgetRepository(db)
+			settings := repository.NewSettingsRepository(db)
+			notifications := repository.NewNotificationRepository(db)
+			next := time.Date(tt.now.Year(), tt.now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+			create := func(month, year int, amount float64) {
+				t.Helper()
+				if _, err := budgets.Create(&models.CreateBudgetLimitRequest{
+					Month: month, Year: year, Amount: amount, NotificationThreshold: 0.7,
+				}); err != nil {
+					t.Fatalf("Failed to create budget: %v", err)
+				}
+			}
+			if tt.current > 0 {
+				create(int(tt.now.Month()), tt.now.Year(), tt.current)
+			}
+			if tt.next > 0 {
+				create(int(next.Month()), next.Year(), tt.next)
+			}
+			if tt.defaultBudget > 0 {
+				if err := settings.SetDefaultBudget(&models.DefaultBudget{
+					Amount: tt.defaultBudget, NotificationThreshold: 0.8,
+				}); err != nil {
+					t.Fatalf("Failed to set default budget: %v", err)
+				}
+			}
+
+			job := NewNextMonthBudgetJob(budgets, settings, notifications, DefaultLeadDays)
+			// Running twice must not create a second budget or notification
+			for i := 0; i < 2; i++ {
+				if err := job.Run(context.Background(), tt.now); err != nil {
+					t.Fatalf("Run() error: %v", err)
+				}
+			}
+
+			budget, err := budgets.GetByMonthYear(int(next.Month()), next.Year())
+			if tt.wantAmount == 0 {
+				if err == nil {
+					t.Errorf("Expected no budget for next month, got %+v", budget)
+				}
+			} else if err != nil || budget.Amount != tt.wantAmount {
+				t.Errorf("Expected next month budget %.2f, got %+v (err %v)", tt.wantAmount, budget, err)
+			}
+
+			stored, err := notifications.List(false)
+			if err != nil {
+				t.Fatalf("Failed to list notifications: %v", err)
+			}
+			if !tt.wantNotified {
+				if len(stored) != 0 {
+					t.Errorf("Expected no notifications, got %+v", stored)
+				}
+				return
+			}
+			if len(stored) != 1 {
+				t.Fatalf("Expected 1 notification, got %d", len(stored))
+			}
+			wantLink := fmt.Sprintf("/budget?month=%d&year=%d", next.Month(), next.Year())
+			if stored[0].Kind != models.NotificationBudgetCreated || stored[0].Link != wantLink {
+				t.Errorf("Unexpected notification: %+v", stored[0])
+			}
+		})
+	}
+}
+
+func TestNextMonthBudgetJob_Rollover(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	budgets := repository.NewBudgetRepository(db)
+	settings := repository.NewSettingsRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+	if _, err := budgets.Create(&models.CreateBudgetLimitRequest{
+		Month: 10, Year: 2025, Amount: 1000, NotificationThreshold: 0.7,
+	}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	// Without lead days nothing happens before the month starts
+	job := NewNextMonthBudgetJob(budgets, settings, notifications, 0)
+	if err := job.Run(context.Background(), time.Date(2025, 10, 31, 23, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if _, err := budgets.GetByMonthYear(11, 2025); err == nil {
+		t.Fatal("Expected no November budget before November")
+	}
+
+	// On the first the month's budget is copied from the month before
+	if err := job.Run(context.Background(), time.Date(2025, 11, 1, 0, 5, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	budget, err := budgets.GetByMonthYear(11, 2025)
+	if err != nil || budget.Amount != 1000 || budget.NotificationThreshold != 0.7 {
+		t.Fatalf("Expected the October budget to roll over, got %+v (err %v)", budget, err)
+	}
+	stored, err := notifications.List(false)
+	if err != nil || len(stored) != 1 || stored[0].Link != "/budget?month=11&year=2025" {
+		t.Errorf("Expected a notification for November, got %+v (err %v)", stored, err)
+	}
+
+	// A budget deleted later in the month is not recreated
+	if err := budgets.Delete(budget.ID); err != nil {
+		t.Fatalf("Failed to delete budget: %v", err)
+	}
+	if err := job.Run(context.Background(), time.Date(2025, 11, 2, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if _, err := budgets.GetByMonthYear(11, 2025); err == nil {
+		t.Error("Expected the deleted budget to stay deleted")
+	}
+}
+
+func TestNextMonthBudgetJob_SkipsAllowanceAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	parent, err := repository.NewUserRepository(db).Create("parent@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	child, err := repository.NewAllowanceRepository(db).ForUser(parent.ID).Create(&models.AllowanceRequest{
+		Name: "Kid", MonthlyAmount: 20,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create allowance account: %v", err)
+	}
+
+	budgets := repository.NewBudgetRepository(db)
+	settings := repository.NewSettingsRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+	if err := settings.SetDefaultBudget(&models.DefaultBudget{Amount: 500, NotificationThreshold: 0.8}); err != nil {
+		t.Fatalf("Failed to set default budget: %v", err)
+	}
+
+	job := NewNextMonthBudgetJob(budgets, settings, notifications, DefaultLeadDays)
+	if err := job.Run(context.Background(), time.Date(2025, 10, 30, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if _, err := budgets.ForUser(parent.ID).GetByMonthYear(11, 2025); err != nil {
+		t.Errorf("Expected a November budget for the parent: %v", err)
+	}
+	if budget, err := budgets.ForUser(child.ID).GetByMonthYear(11, 2025); err == nil {
+		t.Errorf("Expected no budget for the allowance account, got %+v", budget)
+	}
+	stored, err := notifications.ForUser(parent.ID).List(false)
+	if err != nil || len(stored) != 1 {
+		t.Errorf("Expected one notification for the parent, got %+v (err %v)", stored, err)
+	}
+	if stored, err := notifications.ForUser(child.ID).List(false); err != nil || len(stored) != 0 {
+		t.Errorf("Expected no notification for the allowance account, got %+v (err %v)", stored, err)
+	}
+}
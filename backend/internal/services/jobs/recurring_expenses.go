@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RecurringExpenseSchedule is the job's default cron schedule
+const RecurringExpenseSchedule = "@hourly"
+
+// RecurringExpenseCatchUpDays is how many days back a run looks for due
+// dates, so the bills due while the server was down are still generated
+const RecurringExpenseCatchUpDays = 7
+
+// RecurringExpenseJob creates the actual expenses of the expected expenses
+// set to auto_generate on their due dates, e.g. the rent, so fixed bills
+// appear without being entered or scanned. Each due date is generated once.
+type RecurringExpenseJob struct {
+	expected      *repository.ExpectedExpenseRepository
+	notifications *repository.NotificationRepository
+}
+
+// NewRecurringExpenseJob creates a new RecurringExpenseJob
+func NewRecurringExpenseJob(
+	expected *repository.ExpectedExpenseRepository,
+	notifications *repository.NotificationRepository,
+) *RecurringExpenseJob {
+	return &RecurringExpenseJob{expected: expected, notifications: notifications}
+}
+
+func (j *RecurringExpenseJob) Name() string {
+	return "recurring-expenses"
+}
+
+func (j *RecurringExpenseJob) Run(ctx context.Context, now time.Time) error {
+	userIDs, err := j.expected.UserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		generated, err := j.expected.ForUser(userID).GenerateDue(now.AddDate(0, 0, -RecurringExpenseCatchUpDays), now)
+		if len(generated) > 0 {
+			if err := j.notify(userID, generated); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("user %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+func (j *RecurringExpenseJob) notify(userID int64, generated []models.ActualExpense) error {
+	log.Printf("[Jobs] Generated %d recurring expense(s) for user %d", len(generated), userID)
+
+	title := fmt.Sprintf("%s added for %s", generated[0].ItemName, generated[0].ReceiptDate.Format("January 2"))
+	if len(generated) > 1 {
+		title = fmt.Sprintf("%d recurring expenses added", len(generated))
+	}
+	_, err := j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:  models.NotificationExpensesGenerated,
+		Title: title,
+		Message: fmt.Sprintf(
+			"%d expense(s) were added from your expected expenses on their due date. Edit them if the amount differed.",
+			len(generated),
+		),
+		Link: "/actual-expenses",
+	})
+	return err
+}
@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecurringExpenseJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expected := repository.NewExpectedExpenseRepository(db).ForUser(1)
+	actual := repository.NewActualExpenseRepository(db).ForUser(1)
+	notifications := repository.NewNotificationRepository(db)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	create := func(name string, expenseType models.ExpenseType, amount float64, dueDay int, auto bool) *models.ExpectedExpense {
+		t.Helper()
+		e, err := expected.Create(&models.CreateExpectedExpenseRequest{
+			ItemName: name, Source: "Bills", ExpectedAmount: amount, ExpenseType: expenseType,
+			DueDay: &dueDay, StartsOn: &start, AutoGenerate: auto,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create expected expense: %v", err)
+		}
+		return e
+	}
+	rent := create("Rent", models.ExpenseTypeMonthly, 1000, 1, true)
+	gym := create("Gym", models.ExpenseTypeWeekly, 20, 1, true)
+	create("Streaming", models.ExpenseTypeMonthly, 12, 2, false)
+
+	// The gym of Monday March 3rd was entered by hand
+	monday := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	if _, err := actual.Create(&models.CreateActualExpenseRequest{
+		ItemName: "Gym", Source: "Bills", ActualAmount: 25, ExpenseType: models.ExpenseTypeWeekly,
+		ExpectedExpenseID: &gym.ID, ReceiptDate: &monday,
+	}); err != nil {
+		t.Fatalf("Failed to create expense: %v", err)
+	}
+
+	job := NewRecurringExpenseJob(repository.NewExpectedExpenseRepository(db), notifications)
+	now := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+	// Running twice must not generate a due date again
+	for i := 0; i < 2; i++ {
+		if err := job.Run(context.Background(), now); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+	}
+
+	autoGenerated := true
+	generated, err := actual.List(repository.ExpenseFilter{AutoGenerated: &autoGenerated})
+	if err != nil {
+		t.Fatalf("Failed to list expenses: %v", err)
+	}
+	if len(generated) != 1 {
+		t.Fatalf("Expected the rent of March 1st only, got %+v", generated)
+	}
+	if e := generated[0]; e.ItemName != "Rent" || e.ActualAmount != 1000 || !e.AutoGenerated ||
+		e.ExpectedExpenseID == nil || *e.ExpectedExpenseID != rent.ID ||
+		!e.ReceiptDate.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) || e.Month != 3 {
+		t.Errorf("Unexpected generated expense %+v", e)
+	}
+
+	stored, err := notifications.ForUser(1).List(false)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected 1 notification, got %+v (err %v)", stored, err)
+	}
+	if stored[0].Kind != models.NotificationExpensesGenerated || stored[0].Title != "Rent added for March 1" {
+		t.Errorf("Unexpected notification %+v", stored[0])
+	}
+
+	// A deleted generated expense stays deleted
+	if err := actual.Delete(generated[0].ID); err != nil {
+		t.Fatalf("Failed to delete expense: %v", err)
+	}
+	if err := job.Run(context.Background(), now); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if generated, _ = actual.List(repository.ExpenseFilter{AutoGenerated: &autoGenerated}); len(generated) != 0 {
+		t.Errorf("Expected no generated expense, got %+v", generated)
+	}
+
+	// The next Monday's gym is generated
+	if err := job.Run(context.Background(), now.AddDate(0, 0, 5)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if generated, _ = actual.List(repository.ExpenseFilter{AutoGenerated: &autoGenerated}); len(generated) != 1 ||
+		generated[0].ItemName != "Gym" || generated[0].ReceiptDate.Day() != 10 {
+		t.Errorf("Expected the gym of March 10th, got %+v", generated)
+	}
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SpendingGoalSchedule is the job's default cron schedule
+const SpendingGoalSchedule = "@hourly"
+
+// SpendingGoalJob notifies when a month of a spending goal goes over its
+// limit and so breaks the goal's streak. It runs for every user with goals,
+// and each broken month is notified once.
+type SpendingGoalJob struct {
+	goals         *repository.SpendingGoalRepository
+	notifications *repository.NotificationRepository
+}
+
+// NewSpendingGoalJob creates a new SpendingGoalJob
+func NewSpendingGoalJob(
+	goals *repository.SpendingGoalRepository,
+	notifications *repository.NotificationRepository,
+) *SpendingGoalJob {
+	return &SpendingGoalJob{goals: goals, notifications: notifications}
+}
+
+func (j *SpendingGoalJob) Name() string {
+	return "spending-goals"
+}
+
+func (j *SpendingGoalJob) Run(ctx context.Context, now time.Time) error {
+	userIDs, err := j.goals.UserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := j.checkGoals(userID, now); err != nil {
+			return fmt.Errorf("user %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+// checkGoals notifies userID of the newly broken months of their goals
+func (j *SpendingGoalJob) checkGoals(userID int64, now time.Time) error {
+	goals := j.goals.ForUser(userID)
+	list, err := goals.List()
+	if err != nil {
+		return err
+	}
+	for _, goal := range list {
+		progress, err := goals.Progress(goal.ID, now)
+		if err != nil {
+			return err
+		}
+		// Every month is checked, not just the current one, as expenses can
+		// be backdated and the server may have been down when a month ended
+		for i, month := range progress.Months {
+			if month.Met {
+				continue
+			}
+			recorded, err := goals.RecordBreak(goal.ID, month.Month, month.Year)
+			if err != nil {
+				return err
+			}
+			if recorded {
+				if err := j.notify(userID, &goal, month, streakBefore(progress.Months[:i])); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// streakBefore returns the number of months met at the end of months
+func streakBefore(months []models.GoalMonth) int {
+	streak := 0
+	for i := len(months) - 1; i >= 0 && months[i].Met; i-- {
+		streak++
+	}
+	return streak
+}
+
+func (j *SpendingGoalJob) notify(userID int64, goal *models.SpendingGoal, month models.GoalMonth, streak int) error {
+	monthName := fmt.Sprintf("%s %d", time.Month(month.Month), month.Year)
+	log.Printf("[Jobs] Spending goal %d broken in %s", goal.ID, monthName)
+
+	message := fmt.Sprintf(
+		"Spending in %s is $%.2f, over the $%.2f monthly limit.",
+		monthName, month.Spent, goal.MonthlyLimit,
+	)
+	if streak > 0 {
+		message += fmt.Sprintf(" This ends a streak of %d month(s) within the limit.", streak)
+	}
+	_, err := j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:    models.NotificationGoalBroken,
+		Title:   fmt.Sprintf("Goal %q broken in %s", goal.Name, monthName),
+		Message: message,
+		Link:    fmt.Sprintf("/goals/%d", goal.ID),
+	})
+	return err
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpendingGoalJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expenses := repository.NewActualExpenseRepository(db)
+	goals := repository.NewSpendingGoalRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+
+	spend := func(userID int64, amount float64, month time.Month) {
+		t.Helper()
+		date := time.Date(2025, month, 10, 12, 0, 0, 0, time.UTC)
+		if _, err := expenses.ForUser(userID).Create(&models.CreateActualExpenseRequest{
+			ItemName: "Dinner", Source: "Bistro", ActualAmount: amount,
+			ExpenseType: models.ExpenseTypeMisc, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+	for _, userID := range []int64{1, 2} {
+		if _, err := goals.ForUser(userID).Create(&models.SpendingGoalRequest{
+			Name: "Eating out", MonthlyLimit: 100, StartMonth: 1, StartYear: 2025, Months: 6,
+		}); err != nil {
+			t.Fatalf("Failed to create goal: %v", err)
+		}
+	}
+	spend(1, 80, time.January)
+	spend(1, 90, time.February)
+	spend(1, 150, time.March)
+	spend(2, 60, time.March)
+
+	job := NewSpendingGoalJob(goals, notifications)
+	now := time.Date(2025, 3, 20, 9, 0, 0, 0, time.UTC)
+	// Running twice must not notify the same month again
+	for i := 0; i < 2; i++ {
+		if err := job.Run(context.Background(), now); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+	}
+
+	// Only the user whose goal broke is notified
+	if stored, err := notifications.ForUser(2).List(false); err != nil || len(stored) != 0 {
+		t.Errorf("Expected no notification for user 2, got %+v (err %v)", stored, err)
+	}
+	stored, err := notifications.ForUser(1).List(false)
+	if err != nil {
+		t.Fatalf("Failed to list notifications: %v", err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("Expected 1 notification, got %+v", stored)
+	}
+	if stored[0].Kind != models.NotificationGoalBroken ||
+		!strings.Contains(stored[0].Title, "March 2025") ||
+		!strings.Contains(stored[0].Message, "streak of 2 month(s)") {
+		t.Errorf("Unexpected notification: %+v", stored[0])
+	}
+
+	// A backdated expense breaking an earlier month is notified too
+	spend(2, 120, time.January)
+	if err := job.Run(context.Background(), now); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if stored, err = notifications.ForUser(2).List(false); err != nil || len(stored) != 1 {
+		t.Fatalf("Expected a notification for user 2, got %+v (err %v)", stored, err)
+	}
+}
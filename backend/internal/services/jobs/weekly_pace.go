@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// WeeklyPaceSchedule is the job's default cron schedule
+const WeeklyPaceSchedule = "@hourly"
+
+// DefaultPacePercent is how far, in percent, weekly spending may run ahead of
+// plan before the nudge is sent
+const DefaultPacePercent = 20
+
+// WeeklyPaceJob nudges during the week when the weekly expenses of the week
+// so far exceed the expected weekly amount, prorated to the days elapsed, by
+// more than a percentage. It runs for every user with weekly expected
+// expenses, and each week is nudged once.
+type WeeklyPaceJob struct {
+	pace          *repository.WeeklyPaceRepository
+	notifications *repository.NotificationRepository
+	percent       float64
+}
+
+// NewWeeklyPaceJob creates a new WeeklyPaceJob
+func NewWeeklyPaceJob(
+	pace *repository.WeeklyPaceRepository,
+	notifications *repository.NotificationRepository,
+	percent float64,
+) *WeeklyPaceJob {
+	return &WeeklyPaceJob{pace: pace, notifications: notifications, percent: percent}
+}
+
+func (j *WeeklyPaceJob) Name() string {
+	return "weekly-pace"
+}
+
+func (j *WeeklyPaceJob) Run(ctx context.Context, now time.Time) error {
+	userIDs, err := j.pace.UserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := j.checkPace(userID, now); err != nil {
+			return fmt.Errorf("user %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+// checkPace nudges userID when their week containing now runs ahead of plan
+// and was not nudged yet
+func (j *WeeklyPaceJob) checkPace(userID int64, now time.Time) error {
+	repo := j.pace.ForUser(userID)
+	pace, err := repo.Pace(now)
+	if err != nil {
+		return err
+	}
+	if !pace.Ahead(j.percent) {
+		return nil
+	}
+	recorded, err := repo.RecordNudge(pace.WeekStart)
+	if err != nil || !recorded {
+		return err
+	}
+	return j.notify(userID, pace)
+}
+
+func (j *WeeklyPaceJob) notify(userID int64, pace *models.WeeklyPace) error {
+	week := pace.WeekStart.Format("Jan 2")
+	log.Printf("[Jobs] Weekly spending ahead of plan in the week of %s", week)
+
+	_, err := j.notifications.ForUser(userID).Create(&models.Notification{
+		Kind:  models.NotificationWeeklyPace,
+		Title: fmt.Sprintf("Weekly spending ahead of plan in the week of %s", week),
+		Message: fmt.Sprintf(
+			"Weekly expenses are $%.2f after %d day(s), over the $%.2f planned so far of the $%.2f expected this week.",
+			pace.Spent, pace.Days, pace.Planned(), pace.Expected,
+		),
+		Link: "/actual-expenses",
+	})
+	return err
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/repository"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeeklyPaceJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	expected := repository.NewExpectedExpenseRepository(db)
+	expenses := repository.NewActualExpenseRepository(db)
+	notifications := repository.NewNotificationRepository(db)
+
+	spend := func(userID int64, amount float64, expenseType models.ExpenseType, day int) {
+		t.Helper()
+		date := time.Date(2025, 3, day, 12, 0, 0, 0, time.UTC)
+		if _, err := expenses.ForUser(userID).Create(&models.CreateActualExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ActualAmount: amount,
+			ExpenseType: expenseType, ReceiptDate: &date,
+		}); err != nil {
+			t.Fatalf("Failed to create expense: %v", err)
+		}
+	}
+	for _, userID := range []int64{1, 2} {
+		if _, err := expected.ForUser(userID).Create(&models.CreateExpectedExpenseRequest{
+			ItemName: "Groceries", Source: "Market", ExpectedAmount: 70,
+			ExpenseType: models.ExpenseTypeWeekly,
+		}); err != nil {
+			t.Fatalf("Failed to create expected expense: %v", err)
+		}
+	}
+	// The week starts on Monday March 10; by Wednesday $30 is planned and
+	// the nudge comes above $36
+	spend(1, 40, models.ExpenseTypeWeekly, 10)
+	spend(2, 30, models.ExpenseTypeWeekly, 11)
+	// Neither the previous week nor other expense types count
+	spend(2, 100, models.ExpenseTypeWeekly, 7)
+	spend(2, 100, models.ExpenseTypeMisc, 11)
+
+	job := NewWeeklyPaceJob(repository.NewWeeklyPaceRepository(db), notifications, DefaultPacePercent)
+	now := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	// Running twice must not nudge the same week again
+	for i := 0; i < 2; i++ {
+		if err := job.Run(context.Background(), now); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+	}
+
+	// Only the user ahead of plan is nudged
+	if stored, err := notifications.ForUser(2).List(false); err != nil || len(stored) != 0 {
+		t.Errorf("Expected no nudge for user 2, got %+v (err %v)", stored, err)
+	}
+	stored, err := notifications.ForUser(1).List(false)
+	if err != nil {
+		t.Fatalf("Failed to list notifications: %v", err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("Expected 1 notification, got %+v", stored)
+	}
+	if stored[0].Kind != models.NotificationWeeklyPace ||
+		!strings.Contains(stored[0].Title, "Mar 10") ||
+		!strings.Contains(stored[0].Message, "$40.00 after 3 day(s)") {
+		t.Errorf("Unexpected notification: %+v", stored[0])
+	}
+
+	// The next week is nudged again
+	spend(1, 50, models.ExpenseTypeWeekly, 17)
+	if err := job.Run(context.Background(), time.Date(2025, 3, 18, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if stored, err = notifications.ForUser(1).List(false); err != nil || len(stored) != 2 {
+		t.Fatalf("Expected 2 notifications, got %+v (err %v)", stored, err)
+	}
+}
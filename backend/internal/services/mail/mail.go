@@ -0,0 +1,183 @@
+// Package mail sends the emails of the account flows (email verification and
+// password reset) through a pluggable Mailer.
+package mail
+
+import (
+	"budget-tracker/internal/config"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("SMTP_HOST environment variable is not set")
+
+// DefaultSMTPPort is the submission port, which upgrades to TLS with STARTTLS
+const DefaultSMTPPort = "587"
+
+// smtpTimeout bounds a whole delivery when the context has no deadline
+const smtpTimeout = 30 * time.Second
+
+// Message is a plain text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. SMTPMailer is the implementation used by the
+// server; tests and other transports provide their own.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPConfig configures an SMTPMailer
+type SMTPConfig struct {
+	Host     string
+	Port     string // default DefaultSMTPPort; 465 connects with TLS directly
+	Username string // optional; credentials are only sent over TLS
+	Password string
+	From     string // sender address, e.g. Budget <budget@example.com>
+}
+
+// SMTPMailer sends messages through an SMTP server
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+// NewSMTPMailer creates an SMTPMailer; the host and sender are required
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, ErrNotConfigured
+	}
+	if cfg.Port == "" {
+		cfg.Port = DefaultSMTPPort
+	}
+	if cfg.From == "" {
+		return nil, errors.New("SMTP_FROM is required with SMTP_HOST")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_FROM %q: %w", cfg.From, err)
+	}
+	return &SMTPMailer{cfg: cfg, from: from}, nil
+}
+
+// NewSMTPMailerFromEnv creates an SMTPMailer from SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM
+func NewSMTPMailerFromEnv() (*SMTPMailer, error) {
+	return NewSMTPMailer(SMTPConfig{
+		Host:     config.Get("SMTP_HOST"),
+		Port:     config.Get("SMTP_PORT"),
+		Username: config.Get("SMTP_USERNAME"),
+		Password: config.Get("SMTP_PASSWORD"),
+		From:     config.Get("SMTP_FROM"),
+	})
+}
+
+// Send delivers msg. The connection is upgraded with STARTTLS when the server
+// offers it; net/smtp refuses to send credentials without TLS.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := m.format(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	var conn net.Conn
+	if m.cfg.Port == "465" {
+		conn, err = (&tls.Dialer{Config: &tls.Config{ServerName: m.cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("SMTP server rejected recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return client.Quit()
+}
+
+// format renders msg as a MIME message with a quoted-printable UTF-8 body
+func (m *SMTPMailer) format(msg Message, now time.Time) ([]byte, error) {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	// The subject is the only header taken from the message as is
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("subject must be a single line")
+	}
+
+	var buf bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", m.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `text/plain; charset="utf-8"`},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	} {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package mail
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSMTPMailer(t *testing.T) {
+	if _, err := NewSMTPMailer(SMTPConfig{}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Expected ErrNotConfigured without a host, got %v", err)
+	}
+	if _, err := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com"}); err == nil {
+		t.Error("Expected a sender to be required")
+	}
+	if _, err := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", From: "not an address"}); err == nil {
+		t.Error("Expected an invalid sender to be rejected")
+	}
+
+	m, err := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", From: "Budget <budget@example.com>"})
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+	if m.cfg.Port != DefaultSMTPPort {
+		t.Errorf("Expected port %s, got %s", DefaultSMTPPort, m.cfg.Port)
+	}
+}
+
+func TestSMTPMailer_Format(t *testing.T) {
+	m, err := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", From: "Budget <budget@example.com>"})
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+
+	data, err := m.format(Message{To: "me@example.com", Subject: "Café receipts", Body: "Line one\nLine two"}, now)
+	if err != nil {
+		t.Fatalf("Failed to format message: %v", err)
+	}
+	headers, body, ok := strings.Cut(string(data), "\r\n\r\n")
+	if !ok {
+		t.Fatalf("Expected headers and a body, got %q", data)
+	}
+	for _, want := range []string{
+		`From: "Budget" <budget@example.com>`,
+		"To: <me@example.com>",
+		"Subject: =?utf-8?q?Caf=C3=A9_receipts?=",
+		"Date: Fri, 16 Oct 2026 09:30:00 +0000",
+		"Content-Transfer-Encoding: quoted-printable",
+	} {
+		if !strings.Contains(headers+"\r\n", want+"\r\n") {
+			t.Errorf("Expected header %q in %q", want, headers)
+		}
+	}
+	if body != "Line one\r\nLine two" {
+		t.Errorf("Expected CRLF line endings, got %q", body)
+	}
+
+	// Header injection through the recipient or subject is rejected
+	for _, msg := range []Message{
+		{To: "me@example.com\r\nBcc: other@example.com", Subject: "Hi"},
+		{To: "me@example.com", Subject: "Hi\r\nBcc: other@example.com"},
+	} {
+		if _, err := m.format(msg, now); err == nil {
+			t.Errorf("Expected %+v to be rejected", msg)
+		}
+	}
+}
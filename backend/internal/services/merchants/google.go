@@ -0,0 +1,121 @@
+package merchants
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// GooglePlacesURL is the Google Places API (New) nearby search endpoint
+const GooglePlacesURL = "https://places.googleapis.com/v1/places:searchNearby"
+
+// googleFieldMask selects the fields of each place in the response, which
+// also decides the billing tier
+const googleFieldMask = "places.displayName,places.location,places.primaryType,places.shortFormattedAddress"
+
+// googlePlaceTypes are the kinds of places searched
+var googlePlaceTypes = []string{"store", "restaurant", "cafe", "bar", "gas_station", "pharmacy"}
+
+// GoogleDirectory finds merchants with the Google Places API
+type GoogleDirectory struct {
+	apiKey string
+	url    string
+	client *http.Client
+}
+
+// NewGoogleDirectory creates a GoogleDirectory; the API key is required and
+// endpoint defaults to GooglePlacesURL
+func NewGoogleDirectory(apiKey, endpoint string) (*GoogleDirectory, error) {
+	if apiKey == "" {
+		return nil, errors.New("MERCHANT_API_KEY is required with MERCHANT_DIRECTORY=google")
+	}
+	if endpoint == "" {
+		endpoint = GooglePlacesURL
+	}
+	return &GoogleDirectory{
+		apiKey: apiKey,
+		url:    endpoint,
+		client: &http.Client{Timeout: directoryTimeout},
+	}, nil
+}
+
+type googleLatLng struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type googleSearchRequest struct {
+	IncludedTypes       []string `json:"includedTypes"`
+	MaxResultCount      int      `json:"maxResultCount"`
+	RankPreference      string   `json:"rankPreference"`
+	LocationRestriction struct {
+		Circle struct {
+			Center googleLatLng `json:"center"`
+			Radius float64      `json:"radius"`
+		} `json:"circle"`
+	} `json:"locationRestriction"`
+}
+
+type googleSearchResponse struct {
+	Places []struct {
+		DisplayName struct {
+			Text string `json:"text"`
+		} `json:"displayName"`
+		Location              googleLatLng `json:"location"`
+		PrimaryType           string       `json:"primaryType"`
+		ShortFormattedAddress string       `json:"shortFormattedAddress"`
+	} `json:"places"`
+}
+
+// Nearby implements Directory
+func (d *GoogleDirectory) Nearby(ctx context.Context, lat, lon float64, radius int) ([]Merchant, error) {
+	search := googleSearchRequest{
+		IncludedTypes:  googlePlaceTypes,
+		MaxResultCount: MaxResults,
+		RankPreference: "DISTANCE",
+	}
+	search.LocationRestriction.Circle.Center = googleLatLng{Latitude: lat, Longitude: lon}
+	search.LocationRestriction.Circle.Radius = float64(radius)
+	payload, err := json.Marshal(search)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", d.apiKey)
+	req.Header.Set("X-Goog-FieldMask", googleFieldMask)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("%w: Google Places responded %s", ErrDirectoryUnavailable, resp.Status)
+	}
+
+	var body googleSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: invalid Google Places response: %v", ErrDirectoryUnavailable, err)
+	}
+
+	merchants := make([]Merchant, 0, len(body.Places))
+	for _, p := range body.Places {
+		merchants = append(merchants, Merchant{
+			Name:     p.DisplayName.Text,
+			Category: p.PrimaryType,
+			Address:  p.ShortFormattedAddress,
+			Distance: distance(lat, lon, p.Location.Latitude, p.Location.Longitude),
+		})
+	}
+	return nearest(merchants, radius), nil
+}
@@ -0,0 +1,116 @@
+// Package merchants looks up the shops near a location, so the mobile client
+// can suggest the source of an expense instead of having it typed.
+package merchants
+
+import (
+	"budget-tracker/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDirectoryNotConfigured = errors.New("MERCHANT_DIRECTORY environment variable is not set")
+	ErrDirectoryUnavailable   = errors.New("merchant directory unavailable")
+)
+
+// Directory names accepted by MERCHANT_DIRECTORY
+const (
+	DirectoryOverpass = "overpass"
+	DirectoryGoogle   = "google"
+)
+
+// Search limits
+const (
+	DefaultRadius = 150  // meters
+	MaxRadius     = 1000 // meters
+	MaxResults    = 10
+)
+
+// directoryTimeout bounds each request to a directory
+const directoryTimeout = 10 * time.Second
+
+// Merchant is a shop, restaurant or other place expenses are made at
+type Merchant struct {
+	Name string `json:"name"`
+	// Category is the directory's kind of place, e.g. supermarket or cafe
+	Category string  `json:"category,omitempty"`
+	Address  string  `json:"address,omitempty"`
+	Distance float64 `json:"distance_m"`
+}
+
+// Directory finds merchants near a location
+type Directory interface {
+	// Nearby returns the merchants within radius meters of lat/lon, nearest
+	// first
+	Nearby(ctx context.Context, lat, lon float64, radius int) ([]Merchant, error)
+}
+
+var (
+	_ Directory = (*OverpassDirectory)(nil)
+	_ Directory = (*GoogleDirectory)(nil)
+)
+
+// NewDirectoryFromEnv creates the directory selected by MERCHANT_DIRECTORY:
+// overpass for OpenStreetMap, which needs no key, or google for Google
+// Places with MERCHANT_API_KEY. MERCHANT_DIRECTORY_URL overrides
+// the endpoint, e.g. for a self-hosted Overpass server.
+func NewDirectoryFromEnv() (Directory, error) {
+	url := config.Get("MERCHANT_DIRECTORY_URL")
+	// Return untyped nil on error so callers can compare the directory to nil
+	switch name := strings.ToLower(config.Get("MERCHANT_DIRECTORY")); name {
+	case "":
+		return nil, ErrDirectoryNotConfigured
+	case DirectoryOverpass:
+		return NewOverpassDirectory(url), nil
+	case DirectoryGoogle:
+		directory, err := NewGoogleDirectory(config.Get("MERCHANT_API_KEY"), url)
+		if err != nil {
+			return nil, err
+		}
+		return directory, nil
+	default:
+		return nil, fmt.Errorf("unknown MERCHANT_DIRECTORY %q (must be overpass or google)", name)
+	}
+}
+
+// distance returns the distance in meters between two coordinates
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000 // meters
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	// Haversine formula
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// nearest sorts merchants by distance, drops the ones outside radius and
+// repeated names, e.g. a shop mapped both as a building and as a point, and
+// keeps the first MaxResults
+func nearest(merchants []Merchant, radius int) []Merchant {
+	sort.SliceStable(merchants, func(i, j int) bool {
+		return merchants[i].Distance < merchants[j].Distance
+	})
+
+	result := []Merchant{}
+	seen := map[string]bool{}
+	for _, m := range merchants {
+		key := strings.ToLower(m.Name)
+		if m.Name == "" || seen[key] || m.Distance > float64(radius) {
+			continue
+		}
+		seen[key] = true
+		m.Distance = math.Round(m.Distance)
+		result = append(result, m)
+		if len(result) == MaxResults {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package merchants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Around Vancouver City Hall; 0.001 degrees of latitude is about 111 meters
+const testLat, testLon = 49.2609, -123.1139
+
+func TestOverpassDirectory_Nearby(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("data")
+		if !strings.Contains(query, "around:150,49.260900,-123.113900") {
+			t.Errorf("query = %q, want the search around the location", query)
+		}
+		w.Write([]byte(`{"elements": [
+			{"type": "node", "lat": 49.2629, "lon": -123.1139, "tags": {"name": "Far Cafe", "amenity": "cafe"}},
+			{"type": "way", "center": {"lat": 49.2610, "lon": -123.1139},
+				"tags": {"name": "Corner Market", "shop": "convenience", "addr:housenumber": "12", "addr:street": "Main St"}},
+			{"type": "node", "lat": 49.2611, "lon": -123.1139, "tags": {"name": "corner market", "shop": "convenience"}},
+			{"type": "node", "lat": 49.2612, "lon": -123.1139, "tags": {"name": "Bakery", "shop": "bakery"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	merchants, err := NewOverpassDirectory(server.URL).Nearby(context.Background(), testLat, testLon, DefaultRadius)
+	if err != nil {
+		t.Fatalf("Nearby failed: %v", err)
+	}
+	if len(merchants) != 2 {
+		t.Fatalf("got %d merchants, want the 2 distinct ones within the radius: %+v", len(merchants), merchants)
+	}
+	want := Merchant{Name: "Corner Market", Category: "convenience", Address: "12 Main St", Distance: 11}
+	if merchants[0] != want {
+		t.Errorf("nearest = %+v, want %+v", merchants[0], want)
+	}
+	if merchants[1].Name != "Bakery" || merchants[1].Category != "bakery" {
+		t.Errorf("second = %+v, want Bakery", merchants[1])
+	}
+}
+
+func TestOverpassDirectory_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := NewOverpassDirectory(server.URL).Nearby(context.Background(), testLat, testLon, DefaultRadius)
+	if !errors.Is(err, ErrDirectoryUnavailable) {
+		t.Errorf("err = %v, want ErrDirectoryUnavailable", err)
+	}
+}
+
+func TestGoogleDirectory_Nearby(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Api-Key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var search googleSearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if c := search.LocationRestriction.Circle; c.Center.Latitude != testLat || c.Radius != 300 {
+			t.Errorf("circle = %+v, want the location with radius 300", c)
+		}
+		w.Write([]byte(`{"places": [
+			{"displayName": {"text": "Pharmacy"}, "location": {"latitude": 49.2612, "longitude": -123.1139},
+				"primaryType": "pharmacy", "shortFormattedAddress": "1 Main St"},
+			{"displayName": {"text": "Gas"}, "location": {"latitude": 49.2610, "longitude": -123.1139},
+				"primaryType": "gas_station"}
+		]}`))
+	}))
+	defer server.Close()
+
+	directory, err := NewGoogleDirectory("key", server.URL)
+	if err != nil {
+		t.Fatalf("NewGoogleDirectory failed: %v", err)
+	}
+	merchants, err := directory.Nearby(context.Background(), testLat, testLon, 300)
+	if err != nil {
+		t.Fatalf("Nearby failed: %v", err)
+	}
+	if len(merchants) != 2 || merchants[0].Name != "Gas" || merchants[1].Address != "1 Main St" {
+		t.Errorf("merchants = %+v, want Gas then Pharmacy", merchants)
+	}
+
+	wrongKey, _ := NewGoogleDirectory("wrong", server.URL)
+	if _, err := wrongKey.Nearby(context.Background(), testLat, testLon, 300); !errors.Is(err, ErrDirectoryUnavailable) {
+		t.Errorf("err = %v, want ErrDirectoryUnavailable", err)
+	}
+}
+
+func TestNewDirectoryFromEnv(t *testing.T) {
+	t.Setenv("MERCHANT_DIRECTORY", "")
+	if _, err := NewDirectoryFromEnv(); !errors.Is(err, ErrDirectoryNotConfigured) {
+		t.Errorf("err = %v, want ErrDirectoryNotConfigured", err)
+	}
+
+	t.Setenv("MERCHANT_DIRECTORY", "Overpass")
+	if d, err := NewDirectoryFromEnv(); err != nil || d == nil {
+		t.Errorf("overpass: got %v, %v", d, err)
+	}
+
+	t.Setenv("MERCHANT_DIRECTORY", "google")
+	t.Setenv("MERCHANT_API_KEY", "")
+	if d, err := NewDirectoryFromEnv(); err == nil || d != nil {
+		t.Errorf("google without key: got %v, %v, want an error", d, err)
+	}
+
+	t.Setenv("MERCHANT_DIRECTORY", "yelp")
+	if _, err := NewDirectoryFromEnv(); err == nil {
+		t.Error("expected an error for an unknown directory")
+	}
+}
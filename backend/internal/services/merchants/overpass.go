@@ -0,0 +1,99 @@
+package merchants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OverpassURL is the public OpenStreetMap Overpass API
+const OverpassURL = "https://overpass-api.de/api/interpreter"
+
+// overpassAmenities are the amenity tags, besides every shop, that expenses
+// are made at
+const overpassAmenities = "restaurant|cafe|fast_food|bar|pub|fuel|pharmacy|ice_cream|food_court"
+
+// OverpassDirectory finds merchants in OpenStreetMap through an Overpass API
+// server. The public server needs no key but is rate limited; set
+// MERCHANT_DIRECTORY_URL to use another one.
+type OverpassDirectory struct {
+	url    string
+	client *http.Client
+}
+
+// NewOverpassDirectory creates an OverpassDirectory for the server at
+// endpoint, or the public one when it is empty
+func NewOverpassDirectory(endpoint string) *OverpassDirectory {
+	if endpoint == "" {
+		endpoint = OverpassURL
+	}
+	return &OverpassDirectory{url: endpoint, client: &http.Client{Timeout: directoryTimeout}}
+}
+
+// overpassResponse is the part of an Overpass JSON response that is read.
+// Nodes have a location, ways and relations a center with "out center".
+type overpassResponse struct {
+	Elements []struct {
+		Lat    float64 `json:"lat"`
+		Lon    float64 `json:"lon"`
+		Center *struct {
+			Lat float64 `json:"lat"`
+			Lon float64 `json:"lon"`
+		} `json:"center"`
+		Tags map[string]string `json:"tags"`
+	} `json:"elements"`
+}
+
+// Nearby implements Directory
+func (d *OverpassDirectory) Nearby(ctx context.Context, lat, lon float64, radius int) ([]Merchant, error) {
+	around := fmt.Sprintf("around:%d,%f,%f", radius, lat, lon)
+	query := fmt.Sprintf(
+		`[out:json][timeout:10];(nwr(%s)[shop][name];nwr(%s)[amenity~"^(%s)$"][name];);out center 50;`,
+		around, around, overpassAmenities,
+	)
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, d.url, strings.NewReader(url.Values{"data": {query}}.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("%w: Overpass responded %s", ErrDirectoryUnavailable, resp.Status)
+	}
+
+	var body overpassResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: invalid Overpass response: %v", ErrDirectoryUnavailable, err)
+	}
+
+	merchants := make([]Merchant, 0, len(body.Elements))
+	for _, e := range body.Elements {
+		at := struct{ lat, lon float64 }{e.Lat, e.Lon}
+		if e.Center != nil {
+			at.lat, at.lon = e.Center.Lat, e.Center.Lon
+		}
+		category := e.Tags["shop"]
+		if category == "" {
+			category = e.Tags["amenity"]
+		}
+		merchants = append(merchants, Merchant{
+			Name:     e.Tags["name"],
+			Category: category,
+			Address:  strings.TrimSpace(e.Tags["addr:housenumber"] + " " + e.Tags["addr:street"]),
+			Distance: distance(lat, lon, at.lat, at.lon),
+		})
+	}
+	return nearest(merchants, radius), nil
+}
@@ -0,0 +1,218 @@
+// Package receipt turns receipt documents into expense items. It is the one
+// supported pipeline, run as stages: validate, preprocess, extract,
+// categorize, reconcile and persist. Each stage is an interface, so another
+// extractor, e.g. a text parser or local OCR, can replace the AI provider
+// without changing the rest. By default the PDF is checked, sent to the AI
+// provider in a single request that extracts and categorizes its items, and
+// the answer is normalized for the API. The older multi-step methods of
+// ai.Client only adapt to it and are removed in ai.LegacyRemovalVersion.
+//
+// A document can hold several receipts, e.g. a scan of a whole shopping trip.
+// The extractor then splits the job into one per receipt, and the stages up
+// to reconcile run on each of them on its own.
+package receipt
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// UnknownSource is the store of receipts whose header could not be read
+const UnknownSource = "Unknown"
+
+// Job is a receipt going through a Pipeline. The caller sets the document
+// and what the stages should know about the user; each stage adds its part.
+type Job struct {
+	FileName string
+	Data     []byte
+	// Budgets are the expected expense categories offered to the extractor,
+	// e.g. "Milk (weekly)"
+	Budgets []string
+	// Rules take precedence over the expense type the extractor picked
+	Rules []models.CategoryRule
+
+	// Document is the preprocessed document
+	Document *ai.ProcessedDocument
+	Source   string
+	Items    []models.ReceiptItem
+	// ItemCount is the number of items printed on the receipt, which may
+	// differ from len(Items)
+	ItemCount int
+	// RawResponses holds the model's answers as received, also when they
+	// could not be used
+	RawResponses []string
+	// Receipts are the receipts found in a document that holds several,
+	// added with AddReceipt. Items and ItemCount then add them up.
+	Receipts []*Job
+	// ProcessingID is the history entry the persist stage recorded, if any
+	ProcessingID int64
+	// Durations is how long each stage that ran took
+	Durations map[string]time.Duration
+}
+
+// AddReceipt records one receipt of a document that holds several and
+// returns it, for an extractor to fill in
+func (j *Job) AddReceipt(source string, itemCount int, items []models.ReceiptItem) *Job {
+	receipt := &Job{
+		FileName:  j.FileName,
+		Budgets:   j.Budgets,
+		Rules:     j.Rules,
+		Source:    source,
+		Items:     items,
+		ItemCount: itemCount,
+	}
+	j.Receipts = append(j.Receipts, receipt)
+	j.collect()
+	return receipt
+}
+
+// Segments returns the receipts of the document: Receipts, or the job
+// itself when it holds one receipt
+func (j *Job) Segments() []*Job {
+	if len(j.Receipts) == 0 {
+		return []*Job{j}
+	}
+	return j.Receipts
+}
+
+// collect sets Items and ItemCount from the receipts of the document, with
+// the store of the first one
+func (j *Job) collect() {
+	j.Source = j.Receipts[0].Source
+	j.Items = []models.ReceiptItem{}
+	j.ItemCount = 0
+	for _, receipt := range j.Receipts {
+		j.Items = append(j.Items, receipt.Items...)
+		j.ItemCount += receipt.ItemCount
+	}
+}
+
+// fileNameNoise are the words of a file name that do not name the store
+var fileNameNoise = map[string]bool{
+	"receipt": true, "receipts": true, "invoice": true, "bill": true, "scan": true,
+	"scanned": true, "document": true, "doc": true, "img": true, "image": true,
+	"photo": true, "pxl": true, "dsc": true, "copy": true, "page": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "may": true, "jun": true,
+	"jul": true, "aug": true, "sep": true, "sept": true, "oct": true, "nov": true,
+	"dec": true, "january": true, "february": true, "march": true, "april": true,
+	"june": true, "july": true, "august": true, "september": true, "october": true,
+	"november": true, "december": true,
+}
+
+// SourceFromFileName guesses the store of a receipt from the name of its
+// file, e.g. "Costco" from costco_receipt_2026-10-03.pdf, for a draft made
+// without the AI provider. Dates, numbers and words like receipt or scan are
+// left out; it returns UnknownSource when nothing is left.
+func SourceFromFileName(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	var words []string
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '&' && r != '\''
+	}) {
+		if !strings.ContainsFunc(word, unicode.IsLetter) || fileNameNoise[strings.ToLower(word)] {
+			continue
+		}
+		// Capitalize words written in lower case, keep CVS or McDonalds as is
+		if word == strings.ToLower(word) {
+			first, size := utf8.DecodeRuneInString(word)
+			word = string(unicode.ToUpper(first)) + word[size:]
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return UnknownSource
+	}
+	return strings.Join(words, " ")
+}
+
+// Metrics is told the outcome of every stage run; code is empty when the
+// stage succeeded
+type Metrics interface {
+	ObserveStage(stage string, duration time.Duration, code string)
+}
+
+// Pipeline processes receipts through its stages
+type Pipeline struct {
+	stages  Stages
+	metrics Metrics
+}
+
+// New creates a Pipeline with the DefaultStages for provider
+func New(provider ai.ReceiptProvider) *Pipeline {
+	return NewPipeline(DefaultStages(provider), nil)
+}
+
+// NewPipeline creates a Pipeline running stages. metrics is optional.
+func NewPipeline(stages Stages, metrics Metrics) *Pipeline {
+	return &Pipeline{stages: stages, metrics: metrics}
+}
+
+// With returns a copy of the pipeline with the stages set in overrides
+// replacing its own, e.g. to persist the receipts of one request
+func (p *Pipeline) With(overrides Stages) *Pipeline {
+	scoped := *p
+	scoped.stages = p.stages.merge(overrides)
+	return &scoped
+}
+
+// Run passes job through the stages in order and stops at the first one
+// that fails, returned as a *StageError that unwraps to the stage's error.
+// The categorize and reconcile stages run once per receipt of the document.
+func (p *Pipeline) Run(ctx context.Context, job *Job) error {
+	if job.Durations == nil {
+		job.Durations = map[string]time.Duration{}
+	}
+	for _, step := range p.stages.steps() {
+		start := time.Now()
+		err := runStep(ctx, step, job)
+		job.Durations[step.stage] = time.Since(start)
+
+		var code string
+		if err != nil {
+			failure := stageError(step.stage, err)
+			code, err = failure.Code, failure
+		}
+		if p.metrics != nil {
+			p.metrics.ObserveStage(step.stage, job.Durations[step.stage], code)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStep runs step on job or, for a stage that handles one receipt, on
+// each receipt of the document
+func runStep(ctx context.Context, step step, job *Job) error {
+	if !step.perReceipt || len(job.Receipts) == 0 {
+		return step.run(ctx, job)
+	}
+	for i, receipt := range job.Receipts {
+		if err := step.run(ctx, receipt); err != nil {
+			return fmt.Errorf("receipt %d: %w", i+1, err)
+		}
+	}
+	job.collect()
+	return nil
+}
+
+// RunFile runs the PDF document at path through the pipeline
+func (p *Pipeline) RunFile(ctx context.Context, path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ai.ErrReadFile, err)
+	}
+	job := &Job{FileName: filepath.Base(path), Data: data}
+	return job, p.Run(ctx, job)
+}
@@ -0,0 +1,201 @@
+package receipt
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/services/ai"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	result  *ai.ReceiptProcessingResult
+	err     error
+	budgets []string
+}
+
+func (f *fakeProvider) ProcessReceiptDocument(
+	ctx context.Context,
+	base64Data, mimeType string,
+	budgets []string,
+) (*ai.ReceiptProcessingResult, error) {
+	f.budgets = budgets
+	return f.result, f.err
+}
+
+func TestPipeline_Run(t *testing.T) {
+	provider := &fakeProvider{result: &ai.ReceiptProcessingResult{
+		ItemCount: 2,
+		Items: []ai.CategorizedItem{
+			{ItemCode: "MLK", ItemPrice: 3.99, ItemName: "Milk", ItemType: "WEEKLY"},
+			{ItemCode: "X1", ItemPrice: 1.5, ItemName: "Gadget", ItemType: "gadgets"},
+		},
+		RawResponses: []string{"{}"},
+	}}
+	pipeline := New(provider)
+
+	job := &Job{Data: []byte("%PDF-1.4\n%%EOF"), Budgets: []string{"Milk (weekly)"}}
+	if err := pipeline.Run(context.Background(), job); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := []models.ReceiptItem{
+		{Source: UnknownSource, Type: "weekly", ItemCode: "MLK", ItemPrice: 3.99, ItemName: "Milk", LineNo: 1},
+		{Source: UnknownSource, Type: "misc", ItemCode: "X1", ItemPrice: 1.5, ItemName: "Gadget", LineNo: 2},
+	}
+	if !reflect.DeepEqual(job.Items, want) || job.ItemCount != 2 || len(job.RawResponses) != 1 {
+		t.Errorf("Unexpected result: %+v", job)
+	}
+	if !reflect.DeepEqual(provider.budgets, []string{"Milk (weekly)"}) {
+		t.Errorf("Expected the budgets to reach the provider, got %v", provider.budgets)
+	}
+	if len(job.Durations) != 5 {
+		t.Errorf("Expected the 5 default stages to be timed, got %v", job.Durations)
+	}
+
+	// Provider errors are returned as they are
+	provider.err = ai.ErrRateLimit
+	err := pipeline.Run(context.Background(), &Job{Data: []byte("%PDF-1.4\n%%EOF")})
+	var failure *StageError
+	if !errors.Is(err, ai.ErrRateLimit) || !errors.As(err, &failure) ||
+		failure.Stage != StageExtract || failure.Code != models.ErrCodeRateLimit {
+		t.Errorf("Expected ErrRateLimit from the extract stage, got %v", err)
+	}
+
+	err = pipeline.Run(context.Background(), &Job{Data: []byte{0xFF, 0xD8, 0xFF, 0xE0}})
+	if !errors.Is(err, ai.ErrUnsupportedFormat) || ErrorCode(err) != models.ErrCodeInvalidDocument {
+		t.Errorf("Expected ErrUnsupportedFormat for a JPEG, got %v", err)
+	}
+	if err := pipeline.Run(context.Background(), &Job{}); !errors.Is(err, ErrEmptyDocument) {
+		t.Errorf("Expected ErrEmptyDocument, got %v", err)
+	}
+}
+
+// textExtractor stands in for an extractor that reads the items without the
+// AI, leaving their types to the categorizer
+type textExtractor struct{}
+
+func (textExtractor) Extract(ctx context.Context, job *Job) error {
+	job.Source = "Corner Shop"
+	job.Items = []models.ReceiptItem{{ItemName: "Bread", ItemPrice: 2.5}, {ItemName: "Batteries", ItemPrice: 6}}
+	return nil
+}
+
+type recordedStage struct {
+	stage, code string
+}
+
+type fakeMetrics struct {
+	stages []recordedStage
+}
+
+func (m *fakeMetrics) ObserveStage(stage string, duration time.Duration, code string) {
+	m.stages = append(m.stages, recordedStage{stage, code})
+}
+
+type failingPersister struct{}
+
+func (failingPersister) Persist(ctx context.Context, job *Job) error {
+	return &StageError{Code: "STORAGE", Err: errors.New("disk full")}
+}
+
+func TestPipeline_Stages(t *testing.T) {
+	metrics := &fakeMetrics{}
+	pipeline := NewPipeline(DefaultStages(nil), metrics).With(Stages{Extract: textExtractor{}})
+
+	job := &Job{
+		Data:  []byte("%PDF-1.4\n%%EOF"),
+		Rules: []models.CategoryRule{{ItemContains: "bread", ExpenseType: models.ExpenseTypeWeekly}},
+	}
+	if err := pipeline.Run(context.Background(), job); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	want := []models.ReceiptItem{
+		{Source: "Corner Shop", Type: "weekly", ItemPrice: 2.5, ItemName: "Bread", LineNo: 1},
+		{Source: "Corner Shop", Type: "misc", ItemPrice: 6, ItemName: "Batteries", LineNo: 2},
+	}
+	if !reflect.DeepEqual(job.Items, want) {
+		t.Errorf("Unexpected items: %+v", job.Items)
+	}
+	wantStages := []recordedStage{
+		{StageValidate, ""}, {StagePreprocess, ""}, {StageExtract, ""}, {StageCategorize, ""}, {StageReconcile, ""},
+	}
+	if !reflect.DeepEqual(metrics.stages, wantStages) {
+		t.Errorf("Unexpected metrics: %+v", metrics.stages)
+	}
+
+	// A stage can pick its own code, and the pipeline stops at it
+	metrics.stages = nil
+	err := pipeline.With(Stages{Persist: failingPersister{}}).Run(context.Background(), &Job{Data: []byte("%PDF-1.4")})
+	var failure *StageError
+	if !errors.As(err, &failure) || failure.Stage != StagePersist || failure.Code != "STORAGE" {
+		t.Errorf("Expected a STORAGE failure of the persist stage, got %v", err)
+	}
+	if last := metrics.stages[len(metrics.stages)-1]; last != (recordedStage{StagePersist, "STORAGE"}) {
+		t.Errorf("Expected the failure to be observed, got %+v", last)
+	}
+}
+
+func TestPipeline_SeveralReceipts(t *testing.T) {
+	provider := &fakeProvider{result: &ai.ReceiptProcessingResult{
+		Receipts: []ai.ReceiptProcessingResult{
+			{Source: "Publix", ItemCount: 2, Items: []ai.CategorizedItem{
+				{ItemCode: "MLK", ItemPrice: 3.99, ItemName: "Milk", ItemType: "weekly"},
+				{ItemCode: "TAX", ItemPrice: 0.3, ItemName: "Tax", ItemType: "tax"},
+			}},
+			{ItemCount: 1, Items: []ai.CategorizedItem{
+				{ItemCode: "TWL", ItemPrice: 10, ItemName: "Towel", ItemType: "misc"},
+			}},
+		},
+	}}
+	rules := []models.CategoryRule{{Enabled: true, ItemContains: "towel", ExpenseType: models.ExpenseTypeMonthly}}
+
+	job := &Job{Data: []byte("%PDF-1.4\n%%EOF"), Rules: rules}
+	if err := New(provider).Run(context.Background(), job); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	segments := job.Segments()
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 receipts, got %d", len(segments))
+	}
+	// Each receipt is reconciled on its own: its store and line numbers
+	towel := segments[1].Items[0]
+	if segments[1].Source != UnknownSource || towel.Source != UnknownSource || towel.LineNo != 1 {
+		t.Errorf("Expected the second receipt numbered on its own, got %+v", segments[1])
+	}
+	if towel.Type != "monthly" {
+		t.Errorf("Expected the rules to reach every receipt, got type %q", towel.Type)
+	}
+	if len(job.Items) != 3 || job.ItemCount != 3 || job.Items[2] != towel || job.Source != "Publix" {
+		t.Errorf("Expected the job to add up its receipts, got %+v", job)
+	}
+
+	// A job with one receipt is its own segment
+	single := &Job{Data: []byte("%PDF-1.4\n%%EOF")}
+	provider.result = &provider.result.Receipts[0]
+	if err := New(provider).Run(context.Background(), single); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if segments := single.Segments(); len(segments) != 1 || segments[0] != single {
+		t.Errorf("Expected the job itself as its only receipt, got %v", segments)
+	}
+}
+
+func TestSourceFromFileName(t *testing.T) {
+	for name, want := range map[string]string{
+		"costco_receipt_2026-10-03.pdf":   "Costco",
+		"Trader-Joes-Oct-2026.jpg":        "Trader Joes",
+		`C:\Users\me\CVS receipt (2).pdf`: "CVS",
+		"scans/h-mart 1003.png":           "H Mart",
+		"IMG_20261003_1412.jpg":           UnknownSource,
+		"receipt.pdf":                     UnknownSource,
+		"":                                UnknownSource,
+	} {
+		if got := SourceFromFileName(name); got != want {
+			t.Errorf("SourceFromFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package receipt
+
+import (
+	"budget-tracker/internal/models"
+	"budget-tracker/internal/services/ai"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyDocument is returned by PDFValidator for an empty upload
+var ErrEmptyDocument = errors.New("empty document")
+
+// Stage names, in the order a Pipeline runs them
+const (
+	StageValidate   = "validate"
+	StagePreprocess = "preprocess"
+	StageExtract    = "extract"
+	StageCategorize = "categorize"
+	StageReconcile  = "reconcile"
+	StagePersist    = "persist"
+)
+
+// Validator rejects documents the pipeline cannot read
+type Validator interface {
+	Validate(ctx context.Context, job *Job) error
+}
+
+// Preprocessor prepares the document for the extractor
+type Preprocessor interface {
+	Preprocess(ctx context.Context, job *Job) error
+}
+
+// Extractor reads the store and the items of the receipt. Types it cannot
+// tell may be left empty for the categorizer. For a document with several
+// receipts it adds each with Job.AddReceipt.
+type Extractor interface {
+	Extract(ctx context.Context, job *Job) error
+}
+
+// Categorizer sets the expense type of the items
+type Categorizer interface {
+	Categorize(ctx context.Context, job *Job) error
+}
+
+// Reconciler makes the items consistent before they are returned
+type Reconciler interface {
+	Reconcile(ctx context.Context, job *Job) error
+}
+
+// Persister stores the processed receipt
+type Persister interface {
+	Persist(ctx context.Context, job *Job) error
+}
+
+// Stages are the steps of a Pipeline; nil stages are skipped
+type Stages struct {
+	Validate   Validator
+	Preprocess Preprocessor
+	Extract    Extractor
+	Categorize Categorizer
+	Reconcile  Reconciler
+	Persist    Persister
+}
+
+// DefaultStages are the stages for PDF receipts read by provider, with
+// nothing persisted
+func DefaultStages(provider ai.ReceiptProvider) Stages {
+	return Stages{
+		Validate:   PDFValidator{},
+		Preprocess: PDFPreprocessor{},
+		Extract:    AIExtractor{Provider: provider},
+		Categorize: RuleCategorizer{},
+		Reconcile:  LineReconciler{},
+	}
+}
+
+// LimitedStages are DefaultStages for the documents limits accepts, which
+// may be images as well as PDFs
+func LimitedStages(provider ai.ReceiptProvider, limits ai.DocumentLimits) Stages {
+	stages := DefaultStages(provider)
+	stages.Validate = DocumentValidator{Limits: limits}
+	stages.Preprocess = DocumentPreprocessor{Limits: limits}
+	return stages
+}
+
+type step struct {
+	stage string
+	run   func(context.Context, *Job) error
+	// perReceipt is set for the stages run on each receipt of a document
+	perReceipt bool
+}
+
+// steps returns the stages that are set, in order
+func (s Stages) steps() []step {
+	var steps []step
+	if s.Validate != nil {
+		steps = append(steps, step{stage: StageValidate, run: s.Validate.Validate})
+	}
+	if s.Preprocess != nil {
+		steps = append(steps, step{stage: StagePreprocess, run: s.Preprocess.Preprocess})
+	}
+	if s.Extract != nil {
+		steps = append(steps, step{stage: StageExtract, run: s.Extract.Extract})
+	}
+	if s.Categorize != nil {
+		steps = append(steps, step{stage: StageCategorize, run: s.Categorize.Categorize, perReceipt: true})
+	}
+	if s.Reconcile != nil {
+		steps = append(steps, step{stage: StageReconcile, run: s.Reconcile.Reconcile, perReceipt: true})
+	}
+	if s.Persist != nil {
+		steps = append(steps, step{stage: StagePersist, run: s.Persist.Persist})
+	}
+	return steps
+}
+
+// merge returns s with the stages set in overrides replaced
+func (s Stages) merge(overrides Stages) Stages {
+	if overrides.Validate != nil {
+		s.Validate = overrides.Validate
+	}
+	if overrides.Preprocess != nil {
+		s.Preprocess = overrides.Preprocess
+	}
+	if overrides.Extract != nil {
+		s.Extract = overrides.Extract
+	}
+	if overrides.Categorize != nil {
+		s.Categorize = overrides.Categorize
+	}
+	if overrides.Reconcile != nil {
+		s.Reconcile = overrides.Reconcile
+	}
+	if overrides.Persist != nil {
+		s.Persist = overrides.Persist
+	}
+	return s
+}
+
+// StageError is the failure of one stage with the receipt error code it is
+// reported under, e.g. models.ErrCodeInvalidDocument
+type StageError struct {
+	Stage string
+	Code  string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// stageError wraps the error of stage. A stage can return a StageError to
+// pick its own code; otherwise the code follows from the stage.
+func stageError(stage string, err error) *StageError {
+	var failure *StageError
+	if errors.As(err, &failure) {
+		if failure.Stage == "" {
+			failure.Stage = stage
+		}
+		if failure.Code == "" {
+			failure.Code = stageCode(stage, failure.Err)
+		}
+		return failure
+	}
+	return &StageError{Stage: stage, Code: stageCode(stage, err), Err: err}
+}
+
+func stageCode(stage string, err error) string {
+	switch stage {
+	case StageValidate, StagePreprocess:
+		return models.ErrCodeInvalidDocument
+	case StageExtract:
+		return AIErrorCode(err)
+	default:
+		return models.ErrCodeInternalError
+	}
+}
+
+// ErrorCode returns the receipt error code of err, a StageError or an error
+// of the AI provider
+func ErrorCode(err error) string {
+	var failure *StageError
+	if errors.As(err, &failure) {
+		return failure.Code
+	}
+	return AIErrorCode(err)
+}
+
+// AIErrorCode maps an error of the AI provider to a receipt error code
+func AIErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ai.ErrInvalidDocument):
+		return models.ErrCodeInvalidDocument
+	case errors.Is(err, ai.ErrTimeout):
+		return models.ErrCodeTimeout
+	case errors.Is(err, ai.ErrRateLimit):
+		return models.ErrCodeRateLimit
+	case errors.Is(err, ai.ErrQuotaExhausted), errors.Is(err, ai.ErrOverloaded):
+		return models.ErrCodeAPIError
+	case errors.Is(err, ai.ErrParseResponse):
+		return models.ErrCodeParseError
+	case errors.Is(err, ai.ErrAPIKeyNotSet):
+		return models.ErrCodeInternalError
+	case errors.Is(err, ai.ErrMaxRetries), errors.Is(err, ai.ErrModelNotFound), errors.Is(err, ai.ErrAPIError):
+		return models.ErrCodeAPIError
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return models.ErrCodeTimeout
+	default:
+		return models.ErrCodeInternalError
+	}
+}
+
+// PDFValidator accepts non-empty PDF documents
+type PDFValidator struct{}
+
+func (PDFValidator) Validate(ctx context.Context, job *Job) error {
+	if len(job.Data) == 0 {
+		return ErrEmptyDocument
+	}
+	_, err := ai.NewPDFProcessor().ValidateFormat(job.Data)
+	return err
+}
+
+// PDFPreprocessor encodes the PDF for the AI provider
+type PDFPreprocessor struct{}
+
+func (PDFPreprocessor) Preprocess(ctx context.Context, job *Job) error {
+	document, err := ai.NewPDFProcessor().ProcessPDF(bytes.NewReader(job.Data))
+	if err != nil {
+		return err
+	}
+	job.Document = document
+	return nil
+}
+
+// DocumentValidator accepts non-empty documents within Limits
+type DocumentValidator struct {
+	Limits ai.DocumentLimits
+}
+
+func (v DocumentValidator) Validate(ctx context.Context, job *Job) error {
+	if len(job.Data) == 0 {
+		return ErrEmptyDocument
+	}
+	_, err := ai.NewDocumentProcessor(v.Limits).Validate(job.Data)
+	return err
+}
+
+// DocumentPreprocessor encodes a document within Limits for the AI provider
+type DocumentPreprocessor struct {
+	Limits ai.DocumentLimits
+}
+
+func (p DocumentPreprocessor) Preprocess(ctx context.Context, job *Job) error {
+	document, err := ai.NewDocumentProcessor(p.Limits).Process(job.Data)
+	if err != nil {
+		return err
+	}
+	job.Document = document
+	return nil
+}
+
+// AIExtractor extracts and categorizes the items with an AI provider in a
+// single request, which also tells the receipts of the document apart. Errors from the provider are kept, so callers can match
+// the ai errors, and the model's answers are kept on the job even when they
+// could not be used.
+type AIExtractor struct {
+	Provider ai.ReceiptProvider
+}
+
+func (e AIExtractor) Extract(ctx context.Context, job *Job) error {
+	if job.Document == nil {
+		return errors.New("document was not preprocessed")
+	}
+	result, err := e.Provider.ProcessReceiptDocument(ctx, job.Document.Base64Data, job.Document.MimeType, job.Budgets)
+	if err != nil {
+		job.RawResponses = ai.RawResponses(err)
+		return err
+	}
+
+	job.RawResponses = result.RawResponses
+	if len(result.Receipts) > 1 {
+		for _, r := range result.Receipts {
+			job.AddReceipt(r.Source, r.ItemCount, receiptItems(r.Items))
+		}
+		return nil
+	}
+	job.Source = result.Source
+	job.ItemCount = result.ItemCount
+	job.Items = receiptItems(result.Items)
+	return nil
+}
+
+// receiptItems converts the items of the AI provider, with their types as
+// the model wrote them
+func receiptItems(items []ai.CategorizedItem) []models.ReceiptItem {
+	result := make([]models.ReceiptItem, len(items))
+	for i, item := range items {
+		result[i] = models.ReceiptItem{
+			Type:      item.ItemType,
+			ItemCode:  item.ItemCode,
+			ItemPrice: item.ItemPrice,
+			ItemName:  item.ItemName,
+		}
+	}
+	return result
+}
+
+// RuleCategorizer normalizes the expense types the extractor picked, with
+// unknown ones as misc, and then applies the job's category rules, which take
+// precedence
+type RuleCategorizer struct{}
+
+func (RuleCategorizer) Categorize(ctx context.Context, job *Job) error {
+	for i := range job.Items {
+		item := &job.Items[i]
+		itemType := models.NormalizeExpenseType(item.Type)
+		if !itemType.IsValid() {
+			itemType = models.ExpenseTypeMisc
+		}
+		item.Type = string(itemType)
+		if rule := models.MatchRule(job.Rules, item.ItemName, source(job), item.ItemPrice); rule != nil {
+			item.Type = string(rule.ExpenseType)
+		}
+	}
+	return nil
+}
+
+// LineReconciler sets the store of every item, UnknownSource when it could
+// not be read, and numbers the items from 1 in receipt order
+type LineReconciler struct{}
+
+func (LineReconciler) Reconcile(ctx context.Context, job *Job) error {
+	job.Source = source(job)
+	for i := range job.Items {
+		job.Items[i].Source = job.Source
+		job.Items[i].LineNo = i + 1
+	}
+	return nil
+}
+
+// source returns the store of the receipt, UnknownSource when it could not
+// be read
+func source(job *Job) string {
+	if job.Source == "" {
+		return UnknownSource
+	}
+	return job.Source
+}
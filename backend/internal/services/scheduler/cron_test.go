@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// 2025-10-15 is a Wednesday
+	from := time.Date(2025, 10, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"* * * * *", "2025-10-15 10:08"},
+		{"*/15 * * * *", "2025-10-15 10:15"},
+		{"@hourly", "2025-10-15 11:00"},
+		{"@daily", "2025-10-16 00:00"},
+		{"30 6 * * mon-fri", "2025-10-16 06:30"},
+		{"0 9 * * sat,sun", "2025-10-18 09:00"},
+		{"0 0 * * 7", "2025-10-19 00:00"},
+		{"@monthly", "2025-11-01 00:00"},
+		{"0 12 1 jan *", "2026-01-01 12:00"},
+		{"0 0 29 2 *", "2028-02-29 00:00"},
+		// Both day fields restricted: either one matches
+		{"0 0 1 * fri", "2025-10-17 00:00"},
+		{"5/20 8-9 * * *", "2025-10-16 08:05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule failed: %v", err)
+			}
+			if got := s.Next(from).Format("2006-01-02 15:04"); got != tt.want {
+				t.Errorf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@fortnightly",
+		"0 0 31 2 *",
+	} {
+		if _, err := ParseSchedule(spec); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("ParseSchedule(%q): expected ErrInvalidSchedule, got %v", spec, err)
+		}
+	}
+}
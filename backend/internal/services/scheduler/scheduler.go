@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is a unit of background work. Jobs are also run once at start to catch
+// up on work missed while the server was down, so Run must be idempotent and
+// decide itself whether there is anything to do at now.
+type Job interface {
+	Name() string
+	Run(ctx context.Context, now time.Time) error
+}
+
+// JobStatus describes a registered job and its schedule
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	NextRun   time.Time  `json:"next_run"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+type entry struct {
+	job      Job
+	spec     string
+	schedule *Schedule
+	next     time.Time
+	lastRun  time.Time
+	lastErr  error
+}
+
+// Scheduler runs every job once at start and then checks on a fixed interval
+// for jobs whose cron schedule is due. Schedules can be changed while it runs.
+type Scheduler struct {
+	interval time.Duration
+	mu       sync.Mutex
+	entries  []*entry
+	wg       sync.WaitGroup
+}
+
+// New creates a scheduler that checks for due jobs every interval; the
+// interval should not exceed a minute for minute-level schedules to be exact
+func New(interval time.Duration) *Scheduler {
+	return &Scheduler{interval: interval}
+}
+
+// Add registers a job with a cron schedule (see ParseSchedule); jobs must be
+// added before Start
+func (s *Scheduler) Add(job Job, spec string) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, &entry{
+		job:      job,
+		spec:     spec,
+		schedule: schedule,
+		next:     schedule.Next(time.Now()),
+	})
+	return nil
+}
+
+// SetSchedule replaces a job's cron schedule; the next run is computed from now
+func (s *Scheduler) SetSchedule(name, spec string) (JobStatus, error) {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.job.Name() == name {
+			e.spec = spec
+			e.schedule = schedule
+			e.next = schedule.Next(time.Now())
+			return e.status(), nil
+		}
+	}
+	return JobStatus{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+}
+
+// Jobs returns the registered jobs in the order they were added
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status())
+	}
+	return statuses
+}
+
+func (e *entry) status() JobStatus {
+	status := JobStatus{Name: e.job.Name(), Schedule: e.spec, NextRun: e.next}
+	if !e.lastRun.IsZero() {
+		lastRun := e.lastRun
+		status.LastRun = &lastRun
+	}
+	if e.lastErr != nil {
+		status.LastError = e.lastErr.Error()
+	}
+	return status
+}
+
+// Start runs the jobs in the background until ctx is canceled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.RunOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.RunDue(ctx, now)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the scheduler has stopped after its context was canceled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// RunOnce runs every job once regardless of its schedule, in the order they
+// were added. A failing job is logged and does not stop the others.
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	s.mu.Lock()
+	entries := append([]*entry(nil), s.entries...)
+	s.mu.Unlock()
+
+	s.run(ctx, entries, time.Now())
+}
+
+// RunDue runs the jobs whose next scheduled run is at or before now
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	var due []*entry
+	for _, e := range s.entries {
+		if !e.next.IsZero() && !now.Before(e.next) {
+			e.next = e.schedule.Next(now)
+			due = append(due, e)
+		}
+	}
+	s.mu.Unlock()
+
+	s.run(ctx, due, now)
+}
+
+func (s *Scheduler) run(ctx context.Context, entries []*entry, now time.Time) {
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		err := e.job.Run(ctx, now)
+		if err != nil {
+			log.Printf("[Scheduler] Job %s failed: %v", e.job.Name(), err)
+		}
+
+		s.mu.Lock()
+		e.lastRun = now
+		e.lastErr = err
+		s.mu.Unlock()
+	}
+}
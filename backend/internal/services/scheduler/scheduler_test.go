@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type recordingJob struct {
+	name string
+	err  error
+	runs *[]string
+}
+
+func (j recordingJob) Name() string { return j.name }
+
+func (j recordingJob) Run(ctx context.Context, now time.Time) error {
+	*j.runs = append(*j.runs, j.name)
+	return j.err
+}
+
+func TestScheduler_RunOnceContinuesAfterFailure(t *testing.T) {
+	var runs []string
+	s := New(time.Hour)
+	s.Add(recordingJob{name: "first", err: errors.New("boom"), runs: &runs}, "@hourly")
+	s.Add(recordingJob{name: "second", runs: &runs}, "@hourly")
+
+	s.RunOnce(context.Background())
+
+	if len(runs) != 2 || runs[0] != "first" || runs[1] != "second" {
+		t.Errorf("Expected both jobs to run in order, got %v", runs)
+	}
+}
+
+func TestScheduler_StartRunsImmediatelyAndStops(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	s := New(time.Hour)
+	s.Add(jobFunc(func() { ran <- struct{}{} }), "@hourly")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the job to run at start")
+	}
+
+	cancel()
+	s.Wait()
+}
+
+type jobFunc func()
+
+func (f jobFunc) Name() string { return "func" }
+
+func (f jobFunc) Run(ctx context.Context, now time.Time) error {
+	f()
+	return nil
+}
+
+func TestScheduler_RunDueAndSetSchedule(t *testing.T) {
+	var runs []string
+	s := New(time.Minute)
+	if err := s.Add(recordingJob{name: "daily", runs: &runs}, "@daily"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Add(recordingJob{name: "broken", runs: &runs}, "not cron"); err == nil {
+		t.Error("Expected an invalid schedule to be rejected")
+	}
+
+	next := s.Jobs()[0].NextRun
+	s.RunDue(context.Background(), next.Add(-time.Minute))
+	if len(runs) != 0 {
+		t.Fatalf("Expected no run before the schedule is due, got %v", runs)
+	}
+	s.RunDue(context.Background(), next)
+	if len(runs) != 1 {
+		t.Fatalf("Expected the job to run when due, got %v", runs)
+	}
+
+	status := s.Jobs()[0]
+	if status.LastRun == nil || !status.NextRun.Equal(next.AddDate(0, 0, 1)) {
+		t.Errorf("Expected last run set and next run a day later, got %+v", status)
+	}
+
+	status, err := s.SetSchedule("daily", "*/5 * * * *")
+	if err != nil {
+		t.Fatalf("SetSchedule failed: %v", err)
+	}
+	if status.Schedule != "*/5 * * * *" || status.NextRun.Minute()%5 != 0 {
+		t.Errorf("Unexpected status after SetSchedule: %+v", status)
+	}
+	if _, err := s.SetSchedule("missing", "@daily"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	if _, err := s.SetSchedule("daily", "61 * * * *"); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected ErrInvalidSchedule, got %v", err)
+	}
+}
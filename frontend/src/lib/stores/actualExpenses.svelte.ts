@@ -5,6 +5,7 @@
 
 import { api } from '$lib/utils/api';
 import { ExpenseTypeEnum, ExpenseFilterTypeEnum } from '$lib/types/enums';
+import type { ExpectedExpense } from '$lib/stores/expectedExpenses.svelte';
 
 export interface ActualExpense {
 	id: number;
@@ -16,10 +17,25 @@ export interface ActualExpense {
 	expected_expense_id?: number;
 	receipt_date: string;
 	receipt_number: number;
+	line_no?: number;
 	month: number;
 	year: number;
+	/** month/year were picked on save to split the receipt, not taken from receipt_date */
+	month_assigned: boolean;
 	created_at: string;
 	updated_at: string;
+	/** Held by the approval rule and left out of totals until approved */
+	pending_approval: boolean;
+	approved_at?: string;
+	approved_by?: number;
+	/** Tagged for the year-end deductible report */
+	deductible: boolean;
+	/** Generated from an expected expense on its due date */
+	auto_generated: boolean;
+	/** Lower-case labels, e.g. work; absent when there are none */
+	tags?: string[];
+	/** Only present when requested with include=expected_expense */
+	expected_expense?: ExpectedExpense;
 }
 
 export interface ActualExpenseInput {
@@ -31,6 +47,12 @@ export interface ActualExpenseInput {
 	expected_expense_id?: number;
 	receipt_date?: string;
 	receipt_number?: number;
+	line_no?: number;
+	deductible?: boolean;
+	tags?: string[];
+	/** Count the item toward the month before the receipt's; set both or neither */
+	budget_month?: number;
+	budget_year?: number;
 }
 
 export interface ActualExpenseSummary {
@@ -40,13 +62,27 @@ export interface ActualExpenseSummary {
 	total_monthly: number;
 	total_misc: number;
 	total_actual: number;
+	allowances?: AllowanceStatus[];
+}
+
+export interface AllowanceStatus {
+	id: number;
+	name: string;
+	monthly_amount: number;
+	spent: number;
+	remaining: number;
 }
 
 export type ActualExpenseFilterType = `${ExpenseFilterTypeEnum}`;
 
 interface ActualExpenseListResponse {
 	expenses: ActualExpense[];
+	count: number;
 	total: number;
+	limit?: number;
+	offset?: number;
+	next?: string;
+	prev?: string;
 }
 
 function createActualExpensesStore() {
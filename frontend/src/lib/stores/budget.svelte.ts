@@ -3,7 +3,7 @@
  * Manages budget data with Svelte 5 runes
  */
 
-import { get, post, put, del } from '$lib/utils/api';
+import { get, post, put, del, ApiError } from '$lib/utils/api';
 
 /**
  * Budget type interface matching backend model
@@ -14,10 +14,28 @@ export interface Budget {
 	year: number;
 	amount: number;
 	notification_threshold: number;
+	notification_thresholds: number[];
+	rollover_unspent: boolean;
+	weekly_limit: number;
+	closed_at?: string;
+	deleted_at?: string;
 	created_at: string;
 	updated_at: string;
 }
 
+/**
+ * Backend response format for the budget list
+ */
+interface BudgetListResponse {
+	budgets: Budget[];
+	count: number;
+	total: number;
+	limit?: number;
+	offset?: number;
+	next?: string;
+	prev?: string;
+}
+
 /**
  * Create budget request payload
  */
@@ -26,6 +44,9 @@ export interface CreateBudgetRequest {
 	year: number;
 	amount: number;
 	notification_threshold: number;
+	notification_thresholds?: number[];
+	rollover_unspent?: boolean;
+	weekly_limit?: number;
 }
 
 /**
@@ -36,6 +57,70 @@ export interface UpdateBudgetRequest {
 	year?: number;
 	amount?: number;
 	notification_threshold?: number;
+	notification_thresholds?: number[];
+	rollover_unspent?: boolean;
+	weekly_limit?: number;
+}
+
+/**
+ * Set-budget-for-month request payload; month and year are part of the URL
+ */
+export interface UpsertBudgetRequest {
+	amount: number;
+	notification_threshold?: number;
+	notification_thresholds?: number[];
+	rollover_unspent?: boolean;
+	weekly_limit?: number;
+}
+
+/**
+ * Copy-previous-month request payload; month and year default to the current month
+ */
+export interface CopyBudgetRequest {
+	month?: number;
+	year?: number;
+	overwrite?: boolean;
+}
+
+/**
+ * One month of a budget year; budget and remaining are null without a budget
+ */
+export interface BudgetYearMonth {
+	month: number;
+	budget: number | null;
+	spent: number;
+	remaining: number | null;
+}
+
+/**
+ * Budget and spending of the twelve months of a year, with the annual sums
+ */
+export interface BudgetYear {
+	year: number;
+	months: BudgetYearMonth[];
+	total_budget: number;
+	total_spent: number;
+}
+
+/**
+ * One change of a budget's amount or notification threshold
+ */
+export interface BudgetChange {
+	id: number;
+	budget_id: number;
+	old_amount: number;
+	new_amount: number;
+	old_threshold: number;
+	new_threshold: number;
+	changed_at: string;
+}
+
+/**
+ * Response of the set-budget-for-month endpoint
+ */
+interface UpsertBudgetResponse {
+	created: boolean;
+	budget: Budget;
 }
 
 /**
@@ -76,9 +161,8 @@ function createBudgetStore() {
 			state.loading = true;
 			state.error = null;
 			try {
-				const budgets = await get<Budget[] | null>('/budgets');
-				// Handle null response - backend may return null for empty list
-				state.budgets = budgets ?? [];
+				const response = await get<BudgetListResponse>('/budgets');
+				state.budgets = response.budgets ?? [];
 			} catch (err) {
 				state.error = err instanceof Error ? err.message : 'Failed to fetch budgets';
 				state.budgets = [];
@@ -107,6 +191,29 @@ function createBudgetStore() {
 			}
 		},
 
+		/**
+		 * Create the budgets of several months at once; none is created when
+		 * any month already has one
+		 */
+		async createBudgets(budgets: CreateBudgetRequest[]): Promise<Budget[] | null> {
+			state.loading = true;
+			state.error = null;
+			try {
+				const created = await post<Budget[], { budgets: CreateBudgetRequest[] }>(
+					'/budgets/bulk',
+					{ budgets }
+				);
+				state.budgets = [...state.budgets, ...created];
+				return created;
+			} catch (err) {
+				state.error = err instanceof Error ? err.message : 'Failed to create budgets';
+				console.error('Error creating budgets:', err);
+				return null;
+			} finally {
+				state.loading = false;
+			}
+		},
+
 		/**
 		 * Update an existing budget
 		 */
@@ -126,6 +233,115 @@ function createBudgetStore() {
 			}
 		},
 
+		/**
+		 * Fetch the budget for a month from the API; null when none is set
+		 */
+		async fetchBudgetForMonth(year: number, month: number): Promise<Budget | null> {
+			try {
+				return await get<Budget>(`/budgets/by-month/${year}/${month}`);
+			} catch (err) {
+				if (err instanceof ApiError && err.status === 404) {
+					return null;
+				}
+				state.error = err instanceof Error ? err.message : 'Failed to fetch budget';
+				console.error('Error fetching budget:', err);
+				return null;
+			}
+		},
+
+		/**
+		 * Fetch the budget for the current month in the browser's time zone; null
+		 * when none is set
+		 */
+		async fetchCurrentBudget(): Promise<Budget | null> {
+			const tz = Intl.DateTimeFormat().resolvedOptions().timeZone;
+			try {
+				return await get<Budget>(`/budgets/current?tz=${encodeURIComponent(tz)}`);
+			} catch (err) {
+				if (err instanceof ApiError && err.status === 404) {
+					return null;
+				}
+				state.error = err instanceof Error ? err.message : 'Failed to fetch budget';
+				console.error('Error fetching budget:', err);
+				return null;
+			}
+		},
+
+		/**
+		 * Fetch the budget and spending of every month of a year
+		 */
+		async fetchBudgetYear(year: number): Promise<BudgetYear | null> {
+			try {
+				return await get<BudgetYear>(`/budgets/year/${year}`);
+			} catch (err) {
+				state.error = err instanceof Error ? err.message : 'Failed to fetch budget year';
+				console.error('Error fetching budget year:', err);
+				return null;
+			}
+		},
+
+		/**
+		 * Fetch the changes of a budget's amount and threshold, oldest first
+		 */
+		async fetchBudgetHistory(id: number): Promise<BudgetChange[]> {
+			try {
+				return await get<BudgetChange[]>(`/budgets/${id}/history`);
+			} catch (err) {
+				state.error = err instanceof Error ? err.message : 'Failed to fetch budget history';
+				console.error('Error fetching budget history:', err);
+				return [];
+			}
+		},
+
+		/**
+		 * Create or update the budget for a month in a single request
+		 */
+		async setBudgetForMonth(
+			year: number,
+			month: number,
+			data: UpsertBudgetRequest
+		): Promise<Budget | null> {
+			state.loading = true;
+			state.error = null;
+			try {
+				const { budget } = await put<UpsertBudgetResponse, UpsertBudgetRequest>(
+					`/budgets/by-month/${year}/${month}`,
+					data
+				);
+				state.budgets = state.budgets.some((b) => b.id === budget.id)
+					? state.budgets.map((b) => (b.id === budget.id ? budget : b))
+					: [...state.budgets, budget];
+				return budget;
+			} catch (err) {
+				state.error = err instanceof Error ? err.message : 'Failed to save budget';
+				console.error('Error saving budget:', err);
+				return null;
+			} finally {
+				state.loading = false;
+			}
+		},
+
+		/**
+		 * Copy the previous month's budget into a month
+		 */
+		async copyPreviousBudget(data: CopyBudgetRequest): Promise<Budget | null> {
+			state.loading = true;
+			state.error = null;
+			try {
+				const budget = await post<Budget, CopyBudgetRequest>('/budgets/copy', data);
+				state.budgets = state.budgets.some((b) => b.id === budget.id)
+					? state.budgets.map((b) => (b.id === budget.id ? budget : b))
+					: [...state.budgets, budget];
+				return budget;
+			} catch (err) {
+				state.error = err instanceof Error ? err.message : 'Failed to copy budget';
+				console.error('Error copying budget:', err);
+				return null;
+			} finally {
+				state.loading = false;
+			}
+		},
+
 		/**
 		 * Delete a budget
 		 */
@@ -20,6 +20,17 @@ export interface ExpectedExpense {
 	source: string;
 	expected_amount: number;
 	expense_type: ExpectedExpenseType;
+	/** e.g. Groceries; hints the receipt categorization */
+	category?: string;
+	/** Day of the month (monthly) or ISO weekday, 1 = Monday (weekly) */
+	due_day?: number;
+	/** RRULE, e.g. FREQ=WEEKLY;INTERVAL=2;BYDAY=FR; wins over due_day */
+	recurrence?: string;
+	starts_on?: string;
+	/** Generate an actual expense on each due date */
+	auto_generate: boolean;
+	/** False once archived; archived items no longer count or come due */
+	is_active: boolean;
 	created_at: string;
 	updated_at: string;
 }
@@ -32,6 +43,12 @@ export interface ExpectedExpenseInput {
 	source: string;
 	expected_amount: number;
 	expense_type: ExpectedExpenseType;
+	/** An empty string clears it on update */
+	category?: string;
+	due_day?: number;
+	recurrence?: string;
+	starts_on?: string;
+	auto_generate?: boolean;
 }
 
 /**
@@ -59,6 +76,11 @@ interface ExpectedExpenseListResponse {
 	expenses: ExpectedExpense[];
 	filter: string;
 	count: number;
+	total: number;
+	limit?: number;
+	offset?: number;
+	next?: string;
+	prev?: string;
 }
 
 /**
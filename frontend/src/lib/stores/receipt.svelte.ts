@@ -16,6 +16,7 @@ export interface ExtractedItem {
 	item_code: string;
 	item_price: number;
 	item_name: string;
+	line_no?: number; // 1-based position on the receipt, unset for rows added by hand
 	expected_expense_id?: number; // Link to matched expected expense
 	selected?: boolean;
 }
@@ -26,7 +27,24 @@ export interface ExtractedItem {
 interface ProcessReceiptResponse {
 	success: boolean;
 	items: Omit<ExtractedItem, 'selected'>[];
+	item_count: number;
 	processing_time_ms: number;
+	processing_id?: number;
+	stage_times_ms?: Record<string, number>;
+	receipts?: ReceiptResult[];
+	/** A draft without items, returned with ?allow_degraded=true while the AI is down */
+	degraded?: boolean;
+	error?: string;
+	code?: string;
+}
+
+/**
+ * One receipt of a processed document; a PDF can hold several
+ */
+interface ReceiptResult {
+	source: string;
+	items: Omit<ExtractedItem, 'selected'>[];
+	item_count: number;
 }
 
 /**